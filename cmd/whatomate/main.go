@@ -215,6 +215,24 @@ func runServer(args []string) {
 	go slaProcessor.Start(slaCtx)
 	lo.Info("SLA processor started")
 
+	// Start shift processor (runs every minute)
+	shiftProcessor := handlers.NewShiftProcessor(app, time.Minute)
+	shiftCtx, shiftCancel := context.WithCancel(context.Background())
+	go shiftProcessor.Start(shiftCtx)
+	lo.Info("Shift processor started")
+
+	// Start retention purger (runs every hour)
+	retentionPurger := handlers.NewRetentionPurger(app, time.Hour)
+	retentionCtx, retentionCancel := context.WithCancel(context.Background())
+	go retentionPurger.Start(retentionCtx)
+	lo.Info("Retention purger started")
+
+	// Start campaign resumer (runs every minute)
+	campaignResumer := handlers.NewCampaignResumer(app, time.Minute)
+	campaignCtx, campaignCancel := context.WithCancel(context.Background())
+	go campaignResumer.Start(campaignCtx)
+	lo.Info("Campaign resumer started")
+
 	// Start embedded workers
 	var workers []*worker.Worker
 	var workerCancel context.CancelFunc
@@ -260,6 +278,24 @@ func runServer(args []string) {
 	slaProcessor.Stop()
 	lo.Info("SLA processor stopped")
 
+	// Stop shift processor
+	lo.Info("Stopping shift processor...")
+	shiftCancel()
+	shiftProcessor.Stop()
+	lo.Info("Shift processor stopped")
+
+	// Stop retention purger
+	lo.Info("Stopping retention purger...")
+	retentionCancel()
+	retentionPurger.Stop()
+	lo.Info("Retention purger stopped")
+
+	// Stop campaign resumer
+	lo.Info("Stopping campaign resumer...")
+	campaignCancel()
+	campaignResumer.Stop()
+	lo.Info("Campaign resumer stopped")
+
 	// Stop workers first
 	if workerCancel != nil {
 		lo.Info("Stopping workers...", "count", len(workers))
@@ -428,6 +464,9 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 		if len(path) >= 28 && path[:28] == "/api/custom-actions/redirect" {
 			return r
 		}
+		if len(path) >= 28 && path[:28] == "/api/custom-actions/download" {
+			return r
+		}
 		// Apply auth for all other /api routes (supports both JWT and API key)
 		if len(path) > 4 && path[:4] == "/api" {
 			return middleware.AuthWithDB(app.Config.JWT.Secret, app.DB)(r)
@@ -462,6 +501,7 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/users/{id}", app.GetUser)
 	g.PUT("/api/users/{id}", app.UpdateUser)
 	g.DELETE("/api/users/{id}", app.DeleteUser)
+	g.PUT("/api/users/{id}/capacity", app.SetUserCapacity)
 
 	// Roles & Permissions (admin only - enforced by middleware)
 	g.GET("/api/roles", app.ListRoles)
@@ -491,12 +531,31 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	// Contacts
 	g.GET("/api/contacts", app.ListContacts)
 	g.POST("/api/contacts", app.CreateContact)
+	g.GET("/api/contacts/duplicates", app.ListDuplicateContacts)
+	g.GET("/api/contacts/blocked", app.ListBlockedContacts)
+	g.POST("/api/contacts/dedupe", app.DedupeContacts)
 	g.GET("/api/contacts/{id}", app.GetContact)
 	g.PUT("/api/contacts/{id}", app.UpdateContact)
 	g.DELETE("/api/contacts/{id}", app.DeleteContact)
 	g.PUT("/api/contacts/{id}/assign", app.AssignContact)
 	g.PUT("/api/contacts/{id}/tags", app.UpdateContactTags)
 	g.GET("/api/contacts/{id}/session-data", app.GetContactSessionData)
+	g.POST("/api/contacts/{id}/merge", app.MergeContact)
+	g.GET("/api/contacts/{id}/consent", app.GetContactConsent)
+	g.POST("/api/contacts/{id}/consent", app.RecordContactConsent)
+	g.GET("/api/contacts/{id}/timeline", app.GetContactTimeline)
+	g.GET("/api/contacts/{id}/transcript", app.GetContactTranscript)
+	g.POST("/api/contacts/{id}/data-export", app.ExportContactData)
+	g.POST("/api/contacts/{id}/erase", app.EraseContact)
+	g.PUT("/api/contacts/{id}/legal-hold", app.SetContactLegalHold)
+	g.POST("/api/contacts/{id}/block", app.BlockContact)
+	g.DELETE("/api/contacts/{id}/block", app.UnblockContact)
+
+	// Data-subject requests (audit log)
+	g.GET("/api/data-requests", app.ListDataRequests)
+
+	// Data retention
+	g.GET("/api/retention/preview", app.GetRetentionPreview)
 
 	// Generic Import/Export
 	g.POST("/api/export", app.ExportData)
@@ -510,6 +569,22 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.PUT("/api/tags/{name}", app.UpdateTag)
 	g.DELETE("/api/tags/{name}", app.DeleteTag)
 
+	// Contact Attributes
+	g.GET("/api/contact-attributes", app.ListContactAttributes)
+	g.POST("/api/contact-attributes", app.CreateContactAttribute)
+	g.PUT("/api/contact-attributes/{id}", app.UpdateContactAttribute)
+	g.DELETE("/api/contact-attributes/{id}", app.DeleteContactAttribute)
+
+	// Segments
+	g.GET("/api/segments", app.ListSegments)
+	g.POST("/api/segments", app.CreateSegment)
+	g.GET("/api/segments/fields", app.GetSegmentFields)
+	g.POST("/api/segments/preview", app.PreviewSegment)
+	g.GET("/api/segments/{id}", app.GetSegment)
+	g.PUT("/api/segments/{id}", app.UpdateSegment)
+	g.DELETE("/api/segments/{id}", app.DeleteSegment)
+	g.GET("/api/segments/{id}/contacts", app.ListSegmentContacts)
+
 	// Messages
 	g.GET("/api/contacts/{id}/messages", app.GetMessages)
 	g.POST("/api/contacts/{id}/messages", app.SendMessage)
@@ -565,6 +640,24 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/chatbot/settings", app.GetChatbotSettings)
 	g.PUT("/api/chatbot/settings", app.UpdateChatbotSettings)
 
+	// Business Hours & Holiday Calendars
+	g.GET("/api/business-hours/status", app.GetBusinessHoursStatus)
+	g.GET("/api/holiday-calendars", app.ListHolidayCalendars)
+	g.POST("/api/holiday-calendars", app.CreateHolidayCalendar)
+	g.GET("/api/holiday-calendars/{id}", app.GetHolidayCalendar)
+	g.PUT("/api/holiday-calendars/{id}", app.UpdateHolidayCalendar)
+	g.DELETE("/api/holiday-calendars/{id}", app.DeleteHolidayCalendar)
+	g.POST("/api/holiday-calendars/{id}/holidays", app.CreateHoliday)
+	g.DELETE("/api/holiday-calendars/{id}/holidays/{holiday_id}", app.DeleteHoliday)
+	g.POST("/api/holiday-calendars/{id}/import", app.ImportHolidayCalendar)
+
+	// SLA Policies
+	g.GET("/api/sla-policies", app.ListSLAPolicies)
+	g.POST("/api/sla-policies", app.CreateSLAPolicy)
+	g.GET("/api/sla-policies/{id}", app.GetSLAPolicy)
+	g.PUT("/api/sla-policies/{id}", app.UpdateSLAPolicy)
+	g.DELETE("/api/sla-policies/{id}", app.DeleteSLAPolicy)
+
 	// Keyword Rules
 	g.GET("/api/chatbot/keywords", app.ListKeywordRules)
 	g.POST("/api/chatbot/keywords", app.CreateKeywordRule)
@@ -578,6 +671,17 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/chatbot/flows/{id}", app.GetChatbotFlow)
 	g.PUT("/api/chatbot/flows/{id}", app.UpdateChatbotFlow)
 	g.DELETE("/api/chatbot/flows/{id}", app.DeleteChatbotFlow)
+	g.POST("/api/chatbot/flows/{id}/publish", app.PublishChatbotFlow)
+	g.GET("/api/chatbot/flows/{id}/diff", app.DiffChatbotFlowVersions)
+	g.GET("/api/chatbot/flows/{id}/versions", app.ListChatbotFlowVersions)
+	g.GET("/api/chatbot/flows/{id}/versions/{version_id}", app.GetChatbotFlowVersion)
+	g.POST("/api/chatbot/flows/{id}/versions/{version_id}/rollback", app.RollbackChatbotFlow)
+	g.POST("/api/chatbot/flows/{id}/simulate", app.SimulateChatbotFlow)
+	g.GET("/api/chatbot/flows/{id}/tests", app.ListChatbotFlowTestCases)
+	g.POST("/api/chatbot/flows/{id}/tests", app.CreateChatbotFlowTestCase)
+	g.POST("/api/chatbot/flows/{id}/tests/run", app.RunChatbotFlowTestCases)
+	g.PUT("/api/chatbot/flows/{id}/tests/{test_id}", app.UpdateChatbotFlowTestCase)
+	g.DELETE("/api/chatbot/flows/{id}/tests/{test_id}", app.DeleteChatbotFlowTestCase)
 
 	// AI Contexts
 	g.GET("/api/chatbot/ai-contexts", app.ListAIContexts)
@@ -592,6 +696,8 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/chatbot/transfers/pick", app.PickNextTransfer)
 	g.PUT("/api/chatbot/transfers/{id}/resume", app.ResumeFromTransfer)
 	g.PUT("/api/chatbot/transfers/{id}/assign", app.AssignAgentTransfer)
+	g.GET("/api/chatbot/transfers/{id}/transcript", app.GetTransferTranscript)
+	g.GET("/api/chatbot/transfers/{id}/hops", app.ListTransferHops)
 
 	// Teams (admin/manager - access control in handler)
 	g.GET("/api/teams", app.ListTeams)
@@ -603,6 +709,23 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.POST("/api/teams/{id}/members", app.AddTeamMember)
 	g.DELETE("/api/teams/{id}/members/{member_user_id}", app.RemoveTeamMember)
 
+	// Skills (skills-based routing)
+	g.GET("/api/skills", app.ListSkills)
+	g.POST("/api/skills", app.CreateSkill)
+	g.PUT("/api/skills/{id}", app.UpdateSkill)
+	g.DELETE("/api/skills/{id}", app.DeleteSkill)
+	g.GET("/api/users/{id}/skills", app.GetUserSkills)
+	g.PUT("/api/users/{id}/skills", app.SetUserSkills)
+
+	// Agent shifts
+	g.GET("/api/shifts", app.ListShiftSchedules)
+	g.GET("/api/users/{id}/shift", app.GetUserShift)
+	g.PUT("/api/users/{id}/shift", app.SetUserShift)
+	g.DELETE("/api/users/{id}/shift", app.DeleteUserShift)
+	g.GET("/api/users/{id}/shift-exceptions", app.ListShiftExceptions)
+	g.POST("/api/users/{id}/shift-exceptions", app.CreateShiftException)
+	g.DELETE("/api/users/{id}/shift-exceptions/{exception_id}", app.DeleteShiftException)
+
 	// Canned Responses
 	g.GET("/api/canned-responses", app.ListCannedResponses)
 	g.POST("/api/canned-responses", app.CreateCannedResponse)
@@ -622,6 +745,8 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.GET("/api/analytics/agents", app.GetAgentAnalytics)
 	g.GET("/api/analytics/agents/{id}", app.GetAgentDetails)
 	g.GET("/api/analytics/agents/comparison", app.GetAgentComparison)
+	g.GET("/api/analytics/sla", app.GetSLAAnalytics)
+	g.GET("/api/analytics/surveys", app.ListSurveyResponses)
 
 	// Meta WhatsApp Analytics
 	g.GET("/api/analytics/meta", app.GetMetaAnalytics)
@@ -673,6 +798,7 @@ func setupRoutes(g *fastglue.Fastglue, app *handlers.App, lo logf.Logger, basePa
 	g.DELETE("/api/custom-actions/{id}", app.DeleteCustomAction)
 	g.POST("/api/custom-actions/{id}/execute", app.ExecuteCustomAction)
 	g.GET("/api/custom-actions/redirect/{token}", app.CustomActionRedirect)
+	g.GET("/api/custom-actions/download/{token}", app.CustomActionDownload)
 
 	// Catalogs
 	g.GET("/api/catalogs", app.ListCatalogs)
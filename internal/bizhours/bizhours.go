@@ -0,0 +1,302 @@
+// Package bizhours evaluates business-hours schedules: weekly opening
+// ranges in an IANA timezone with holiday and closure dates.
+package bizhours
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// DateLayout is the layout of closure dates
+const DateLayout = "2006-01-02"
+
+// searchWeeks bounds how far ahead NextOpen looks for an opening
+const searchWeeks = 53
+
+// Range is an opening period within a day, in minutes since midnight. An End
+// at or before Start means the period runs past midnight.
+type Range struct {
+	Start int
+	End   int
+}
+
+// Closure closes every day from Start to End inclusive (YYYY-MM-DD, in the
+// schedule's timezone).
+type Closure struct {
+	Name  string
+	Start string
+	End   string
+}
+
+// Schedule is a weekly business-hours schedule.
+type Schedule struct {
+	Location *time.Location
+	Days     [7][]Range // Indexed by time.Weekday
+	Closures []Closure
+}
+
+// Interval is an absolute opening period [Start, End).
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// New builds a schedule from the business_hours setting. Each entry is
+// {day, enabled, start_time, end_time} with day 0 = Sunday, or carries a
+// "ranges" list of {start_time, end_time} for several periods on one day.
+func New(hours models.JSONBArray, loc *time.Location, closures []Closure) *Schedule {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Schedule{Location: loc, Days: ParseHours(hours), Closures: closures}
+}
+
+// ParseHours converts the business_hours setting into daily ranges.
+// Disabled days and malformed entries contribute no ranges.
+func ParseHours(hours models.JSONBArray) [7][]Range {
+	var days [7][]Range
+	for _, entry := range hours {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		day, ok := m["day"].(float64)
+		if !ok || day < 0 || day > 6 {
+			continue
+		}
+		if enabled, _ := m["enabled"].(bool); !enabled {
+			continue
+		}
+
+		var periods []map[string]interface{}
+		if list, ok := m["ranges"].([]interface{}); ok && len(list) > 0 {
+			for _, item := range list {
+				if p, ok := item.(map[string]interface{}); ok {
+					periods = append(periods, p)
+				}
+			}
+		} else {
+			periods = append(periods, m)
+		}
+
+		for _, p := range periods {
+			start, okStart := ParseClock(stringValue(p["start_time"]))
+			end, okEnd := ParseClock(stringValue(p["end_time"]))
+			if !okStart || !okEnd {
+				continue
+			}
+			days[int(day)] = append(days[int(day)], Range{Start: start, End: end})
+		}
+	}
+	return days
+}
+
+// ParseClock parses HH:MM into minutes since midnight. "23:59" and "24:00"
+// both mean the end of the day.
+func ParseClock(s string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 {
+		return 0, false
+	}
+	switch {
+	case h == 24 && m == 0, h == 23 && m == 59:
+		return 24 * 60, true
+	case h < 0 || h > 23:
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// ValidDate reports whether s is a YYYY-MM-DD date
+func ValidDate(s string) bool {
+	_, err := time.Parse(DateLayout, s)
+	return err == nil
+}
+
+// IsOpen reports whether t falls within business hours
+func (s *Schedule) IsOpen(t time.Time) bool {
+	for _, iv := range s.Intervals(t, t.Add(time.Minute)) {
+		if !t.Before(iv.Start) && t.Before(iv.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns t when the business is open, otherwise the next time it
+// opens. The zero time is returned when it does not open within a year.
+func (s *Schedule) NextOpen(t time.Time) time.Time {
+	from := t
+	for week := 0; week < searchWeeks; week++ {
+		to := from.AddDate(0, 0, 7)
+		for _, iv := range s.Intervals(from, to) {
+			if iv.End.After(t) {
+				if iv.Start.After(t) {
+					return iv.Start
+				}
+				return t
+			}
+		}
+		from = to
+	}
+	return time.Time{}
+}
+
+// Add returns the time at which d of business time has elapsed from t. The
+// zero time is returned when that is more than a year of openings away.
+func (s *Schedule) Add(t time.Time, d time.Duration) time.Time {
+	from := t
+	for week := 0; week < searchWeeks; week++ {
+		to := from.AddDate(0, 0, 7)
+		for _, iv := range Clip(s.Intervals(from, to), from, to) {
+			open := iv.End.Sub(iv.Start)
+			if d <= open {
+				return iv.Start.Add(d)
+			}
+			d -= open
+		}
+		from = to
+	}
+	return time.Time{}
+}
+
+// Duration returns the business time between from and to
+func (s *Schedule) Duration(from, to time.Time) time.Duration {
+	var total time.Duration
+	for _, iv := range Clip(s.Intervals(from, to), from, to) {
+		total += iv.End.Sub(iv.Start)
+	}
+	return total
+}
+
+// Clip trims intervals to [from, to), dropping those left empty
+func Clip(intervals []Interval, from, to time.Time) []Interval {
+	out := make([]Interval, 0, len(intervals))
+	for _, iv := range intervals {
+		if iv.Start.Before(from) {
+			iv.Start = from
+		}
+		if iv.End.After(to) {
+			iv.End = to
+		}
+		if iv.End.After(iv.Start) {
+			out = append(out, iv)
+		}
+	}
+	return out
+}
+
+// ClosureOn returns the closure covering t's date, if any
+func (s *Schedule) ClosureOn(t time.Time) (Closure, bool) {
+	return s.closure(t.In(s.Location).Format(DateLayout))
+}
+
+func (s *Schedule) closure(date string) (Closure, bool) {
+	for _, c := range s.Closures {
+		end := c.End
+		if end == "" {
+			end = c.Start
+		}
+		if date >= c.Start && date <= end {
+			return c, true
+		}
+	}
+	return Closure{}, false
+}
+
+// Intervals returns the merged opening intervals overlapping [from, to),
+// in order. Closed dates are cut out, including the part of an overnight
+// range that falls on a closed date.
+func (s *Schedule) Intervals(from, to time.Time) []Interval {
+	loc := s.Location
+	lf := from.In(loc)
+	// Start a day early to pick up overnight ranges from the previous day
+	day := time.Date(lf.Year(), lf.Month(), lf.Day()-1, 0, 0, 0, 0, loc)
+
+	var out []Interval
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, r := range s.Days[day.Weekday()] {
+			start := time.Date(day.Year(), day.Month(), day.Day(), r.Start/60, r.Start%60, 0, 0, loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), r.End/60, r.End%60, 0, 0, loc)
+			if r.End <= r.Start {
+				end = time.Date(day.Year(), day.Month(), day.Day()+1, r.End/60, r.End%60, 0, 0, loc)
+			}
+
+			// Split at midnight so each part is checked against its own date
+			midnight := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+			parts := []Interval{{Start: start, End: end}}
+			if end.After(midnight) {
+				parts = []Interval{{Start: start, End: midnight}, {Start: midnight, End: end}}
+			}
+			for _, p := range parts {
+				if _, closed := s.closure(p.Start.Format(DateLayout)); closed {
+					continue
+				}
+				if p.End.After(from) && p.Start.Before(to) {
+					out = append(out, p)
+				}
+			}
+		}
+	}
+	return Merge(out)
+}
+
+// Merge sorts intervals and joins those that overlap or touch, dropping
+// empty ones
+func Merge(intervals []Interval) []Interval {
+	sorted := append([]Interval(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var out []Interval
+	for _, iv := range sorted {
+		if !iv.End.After(iv.Start) {
+			continue
+		}
+		if n := len(out); n > 0 && !iv.Start.After(out[n-1].End) {
+			if iv.End.After(out[n-1].End) {
+				out[n-1].End = iv.End
+			}
+			continue
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// LoadLocation resolves an IANA timezone name, falling back to UTC
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidTimezone reports whether name is empty or a known IANA timezone
+func ValidTimezone(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,260 @@
+package bizhours
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(d int, enabled bool, start, end string) map[string]interface{} {
+	return map[string]interface{}{
+		"day":        float64(d),
+		"enabled":    enabled,
+		"start_time": start,
+		"end_time":   end,
+	}
+}
+
+func weekdays(start, end string) models.JSONBArray {
+	hours := models.JSONBArray{day(0, false, "", ""), day(6, false, "", "")}
+	for d := 1; d <= 5; d++ {
+		hours = append(hours, day(d, true, start, end))
+	}
+	return hours
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestIsOpen_UsesScheduleTimezone(t *testing.T) {
+	kolkata := mustLoad(t, "Asia/Kolkata")
+	s := New(weekdays("09:00", "17:00"), kolkata, nil)
+
+	// Monday 2026-03-02 10:00 in Kolkata is 04:30 UTC
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 2, 4, 30, 0, 0, time.UTC)))
+	// 10:00 UTC is 15:30 in Kolkata
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)))
+	// 12:00 UTC is 17:30 in Kolkata
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)))
+	// Saturday
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 7, 12, 0, 0, 0, kolkata)))
+}
+
+func TestIsOpen_MultipleRanges(t *testing.T) {
+	hours := models.JSONBArray{map[string]interface{}{
+		"day":     float64(1),
+		"enabled": true,
+		"ranges": []interface{}{
+			map[string]interface{}{"start_time": "09:00", "end_time": "12:00"},
+			map[string]interface{}{"start_time": "13:00", "end_time": "17:00"},
+		},
+	}}
+	s := New(hours, time.UTC, nil)
+	monday := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, time.UTC) }
+
+	assert.True(t, s.IsOpen(monday(9, 0)))
+	assert.True(t, s.IsOpen(monday(11, 59)))
+	assert.False(t, s.IsOpen(monday(12, 30)))
+	assert.True(t, s.IsOpen(monday(13, 0)))
+	assert.False(t, s.IsOpen(monday(17, 0)))
+}
+
+func TestIsOpen_OvernightRange(t *testing.T) {
+	s := New(models.JSONBArray{day(5, true, "22:00", "06:00")}, time.UTC, nil)
+
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 6, 23, 0, 0, 0, time.UTC)))  // Friday night
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 7, 5, 59, 0, 0, time.UTC)))  // Saturday morning
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 7, 6, 0, 0, 0, time.UTC)))  // Closed at 06:00
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 6, 5, 0, 0, 0, time.UTC)))  // Thursday's night is not open
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 6, 21, 0, 0, 0, time.UTC))) // Before opening
+}
+
+func TestIsOpen_EndOfDay(t *testing.T) {
+	s := New(models.JSONBArray{day(1, true, "00:00", "23:59")}, time.UTC, nil)
+
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 2, 23, 59, 30, 0, time.UTC)))
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 3, 0, 0, 30, 0, time.UTC)))
+}
+
+func TestIsOpen_Closures(t *testing.T) {
+	closures := []Closure{{Name: "Spring break", Start: "2026-03-03", End: "2026-03-04"}}
+	s := New(weekdays("09:00", "17:00"), time.UTC, closures)
+
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)))
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)))
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)))
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)))
+
+	c, ok := s.ClosureOn(time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, "Spring break", c.Name)
+}
+
+func TestIsOpen_ClosureUsesLocalDate(t *testing.T) {
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	closures := []Closure{{Name: "Holiday", Start: "2026-03-03"}}
+	s := New(weekdays("09:00", "17:00"), tokyo, closures)
+
+	// 01:00 UTC on the 3rd is 10:00 on the 3rd in Tokyo
+	assert.False(t, s.IsOpen(time.Date(2026, 3, 3, 1, 0, 0, 0, time.UTC)))
+	assert.True(t, s.IsOpen(time.Date(2026, 3, 4, 1, 0, 0, 0, time.UTC)))
+}
+
+func TestNextOpen(t *testing.T) {
+	closures := []Closure{{Name: "Holiday", Start: "2026-03-09"}}
+	s := New(weekdays("09:00", "17:00"), time.UTC, closures)
+
+	open := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, open, s.NextOpen(open))
+
+	// Monday evening opens Tuesday morning
+	assert.Equal(t, time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC),
+		s.NextOpen(time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)))
+
+	// Friday evening skips the weekend and the Monday holiday
+	assert.Equal(t, time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+		s.NextOpen(time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC)))
+}
+
+func TestNextOpen_NeverOpen(t *testing.T) {
+	s := New(models.JSONBArray{day(1, false, "09:00", "17:00")}, time.UTC, nil)
+	assert.True(t, s.NextOpen(time.Now()).IsZero())
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"09:30", 570, true},
+		{"00:00", 0, true},
+		{"23:59", 1440, true},
+		{"24:00", 1440, true},
+		{"25:00", 0, false},
+		{"9", 0, false},
+		{"ab:cd", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseClock(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidTimezone(t *testing.T) {
+	assert.True(t, ValidTimezone(""))
+	assert.True(t, ValidTimezone("Europe/Berlin"))
+	assert.False(t, ValidTimezone("Mars/Olympus"))
+	assert.Equal(t, time.UTC, LoadLocation("Mars/Olympus"))
+}
+
+func TestParseICal(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:new-year@example.com",
+		"DTSTART;VALUE=DATE:20270101",
+		"DTEND;VALUE=DATE:20270102",
+		"SUMMARY:New Year\\, Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:retreat@example.com",
+		"DTSTART;VALUE=DATE:20270310",
+		"DTEND;VALUE=DATE:20270313",
+		"SUMMARY:Company",
+		"  retreat",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:offsite@example.com",
+		"DTSTART:20270401T090000Z",
+		"DTEND:20270401T170000Z",
+		"SUMMARY:Offsite",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled@example.com",
+		"DTSTART;VALUE=DATE:20270501",
+		"STATUS:CANCELLED",
+		"SUMMARY:Cancelled",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := ParseICal(strings.NewReader(ics))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, Event{UID: "new-year@example.com", Closure: Closure{Name: "New Year, Day", Start: "2027-01-01", End: "2027-01-01"}}, events[0])
+	assert.Equal(t, Event{UID: "retreat@example.com", Closure: Closure{Name: "Company retreat", Start: "2027-03-10", End: "2027-03-12"}}, events[1])
+	assert.Equal(t, Event{UID: "offsite@example.com", Closure: Closure{Name: "Offsite", Start: "2027-04-01", End: "2027-04-01"}}, events[2])
+}
+
+func TestParseICal_NotCalendar(t *testing.T) {
+	_, err := ParseICal(strings.NewReader("name,date\nNew Year,2027-01-01\n"))
+	assert.ErrorIs(t, err, ErrNotICal)
+}
+
+func TestAdd_SkipsClosedTime(t *testing.T) {
+	closures := []Closure{{Name: "Holiday", Start: "2026-03-09"}}
+	s := New(weekdays("09:00", "19:00"), time.UTC, closures)
+
+	// Friday 18:55 plus 15 minutes: 5 minutes on Friday, 10 on Tuesday after
+	// the weekend and the Monday holiday
+	friday := time.Date(2026, 3, 6, 18, 55, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 10, 9, 10, 0, 0, time.UTC), s.Add(friday, 15*time.Minute))
+
+	// Within a single opening
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday.Add(time.Hour), s.Add(monday, time.Hour))
+
+	// Starting while closed counts from the next opening
+	saturday := time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC), s.Add(saturday, 30*time.Minute))
+
+	// Spanning several days: 10h per day
+	assert.Equal(t, time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC), s.Add(monday, 20*time.Hour))
+}
+
+func TestAdd_NeverOpen(t *testing.T) {
+	s := New(models.JSONBArray{day(1, false, "09:00", "17:00")}, time.UTC, nil)
+	assert.True(t, s.Add(time.Now(), time.Minute).IsZero())
+}
+
+func TestDuration(t *testing.T) {
+	s := New(weekdays("09:00", "17:00"), time.UTC, nil)
+
+	friday := time.Date(2026, 3, 6, 16, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, 2*time.Hour, s.Duration(friday, monday))
+
+	// Entirely outside business hours
+	assert.Equal(t, time.Duration(0), s.Duration(friday.Add(2*time.Hour), monday.Add(-2*time.Hour)))
+
+	// Over two weeks, where openings straddle the weekly search windows
+	assert.Equal(t, 80*time.Hour, s.Duration(monday, monday.AddDate(0, 0, 14)))
+}
+
+func TestClipAndMerge(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 3, 9, h, 0, 0, 0, time.UTC) }
+	intervals := []Interval{{Start: at(14), End: at(18)}, {Start: at(8), End: at(10)}, {Start: at(9), End: at(12)}, {Start: at(20), End: at(22)}}
+
+	clipped := Clip(intervals, at(9), at(17))
+	assert.Equal(t, []Interval{{Start: at(14), End: at(17)}, {Start: at(9), End: at(10)}, {Start: at(9), End: at(12)}}, clipped)
+	// The input is left as it was
+	assert.Equal(t, at(8), intervals[1].Start)
+
+	assert.Equal(t, []Interval{{Start: at(9), End: at(12)}, {Start: at(14), End: at(17)}}, Merge(clipped))
+	assert.Equal(t, []Interval{{Start: at(8), End: at(10)}}, Merge([]Interval{{Start: at(8), End: at(9)}, {Start: at(9), End: at(10)}, {Start: at(11), End: at(11)}}))
+	assert.Nil(t, Merge(nil))
+}
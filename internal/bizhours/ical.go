@@ -0,0 +1,154 @@
+package bizhours
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotICal is returned when the input has no VCALENDAR
+var ErrNotICal = errors.New("not an iCalendar file")
+
+// Event is a closure read from an iCalendar file.
+type Event struct {
+	UID string
+	Closure
+}
+
+// ParseICal reads the VEVENTs of an iCalendar file as whole-day closures.
+// Dates of timed events are taken as written; recurrence rules are not
+// expanded, so only the first occurrence of a recurring event is imported.
+// Cancelled events are skipped.
+func ParseICal(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events     []Event
+		inCalendar bool
+		current    *icalEvent
+	)
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			inCalendar = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &icalEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil {
+				if ev, ok := current.event(); ok {
+					events = append(events, ev)
+				}
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.uid = value
+		case name == "SUMMARY":
+			current.summary = unescape(value)
+		case name == "STATUS":
+			current.cancelled = strings.EqualFold(value, "CANCELLED")
+		case name == "DTSTART":
+			current.start, current.startIsDate = parseICalDate(params, value)
+		case name == "DTEND":
+			current.end, current.endIsDate = parseICalDate(params, value)
+			current.endMidnight = strings.Contains(value, "T000000")
+		}
+	}
+
+	if !inCalendar {
+		return nil, ErrNotICal
+	}
+	return events, nil
+}
+
+type icalEvent struct {
+	uid         string
+	summary     string
+	cancelled   bool
+	start       time.Time
+	startIsDate bool
+	end         time.Time
+	endIsDate   bool
+	endMidnight bool
+}
+
+func (e *icalEvent) event() (Event, bool) {
+	if e.cancelled || e.start.IsZero() {
+		return Event{}, false
+	}
+
+	end := e.start
+	if !e.end.IsZero() {
+		end = e.end
+		// DTEND is exclusive for dates and for timed events ending at midnight
+		if e.endIsDate || e.endMidnight {
+			end = end.AddDate(0, 0, -1)
+		}
+		if end.Before(e.start) {
+			end = e.start
+		}
+	}
+
+	return Event{
+		UID: e.uid,
+		Closure: Closure{
+			Name:  e.summary,
+			Start: e.start.Format(DateLayout),
+			End:   end.Format(DateLayout),
+		},
+	}, true
+}
+
+// unfold joins continuation lines, which start with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits "NAME;PARAM=X:value" into its upper-cased name, its
+// parameters and its value.
+func splitProperty(line string) (string, string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	name, params, _ := strings.Cut(head, ";")
+	return strings.ToUpper(name), strings.ToUpper(params), value
+}
+
+// parseICalDate reads the date part of a DATE or DATE-TIME value
+func parseICalDate(params, value string) (time.Time, bool) {
+	if len(value) < 8 {
+		return time.Time{}, false
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false
+	}
+	isDate := strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME")
+	return date, isDate || len(value) == 8
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,94 @@
+package bizhours
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// ForAccount loads the schedule of a WhatsApp account from the chatbot
+// settings in effect for it. It returns nil when business hours are disabled.
+func ForAccount(db *gorm.DB, orgID uuid.UUID, account string) (*Schedule, error) {
+	var settings models.ChatbotSettings
+	err := db.Where("organization_id = ? AND (whats_app_account = ? OR whats_app_account = '')", orgID, account).
+		Order("CASE WHEN whats_app_account = '' THEN 1 ELSE 0 END"). // Prefer account-specific settings
+		First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ForSettings(db, &settings, account, "")
+}
+
+// ForSettings builds the schedule described by chatbot settings for a
+// WhatsApp account. The timezone is the given one when set (e.g. a team's),
+// else the account's, else the organization's, else UTC. Holidays come from
+// the active calendars of the organization that apply to the account. It
+// returns nil when business hours are disabled.
+func ForSettings(db *gorm.DB, settings *models.ChatbotSettings, account, timezone string) (*Schedule, error) {
+	// Past closures no longer matter
+	return ForSettingsSince(db, settings, account, timezone, time.Now().AddDate(0, 0, -1))
+}
+
+// ForSettingsSince is ForSettings with the holidays ending on or after since,
+// for evaluating past periods.
+func ForSettingsSince(db *gorm.DB, settings *models.ChatbotSettings, account, timezone string, since time.Time) (*Schedule, error) {
+	if !Enabled(settings) {
+		return nil, nil
+	}
+	orgID := settings.OrganizationID
+
+	closures, err := LoadClosures(db, orgID, account, since)
+	if err != nil {
+		return nil, err
+	}
+	return New(settings.BusinessHours.Hours, LoadLocation(ResolveTimezone(db, orgID, account, timezone)), closures), nil
+}
+
+// Enabled reports whether chatbot settings define business hours
+func Enabled(settings *models.ChatbotSettings) bool {
+	return settings != nil && settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0
+}
+
+// ResolveTimezone returns timezone when set, else the account's timezone,
+// else the organization's. It returns "" when none is set, which
+// LoadLocation treats as UTC.
+func ResolveTimezone(db *gorm.DB, orgID uuid.UUID, account, timezone string) string {
+	if timezone == "" && account != "" {
+		var acc models.WhatsAppAccount
+		if err := db.Select("timezone").Where("organization_id = ? AND name = ?", orgID, account).First(&acc).Error; err == nil {
+			timezone = acc.Timezone
+		}
+	}
+	if timezone == "" {
+		var org models.Organization
+		if err := db.Select("settings").Where("id = ?", orgID).First(&org).Error; err == nil {
+			timezone, _ = org.Settings["timezone"].(string)
+		}
+	}
+	return timezone
+}
+
+// LoadClosures loads the holidays ending on or after since from the active
+// calendars of the organization that apply to the account
+func LoadClosures(db *gorm.DB, orgID uuid.UUID, account string, since time.Time) ([]Closure, error) {
+	var holidays []models.Holiday
+	// Holiday dates are local; keep a day of margin for timezones behind UTC
+	if err := db.Joins("JOIN holiday_calendars ON holiday_calendars.id = holidays.calendar_id AND holiday_calendars.deleted_at IS NULL").
+		Where("holidays.organization_id = ? AND holidays.end_date >= ?", orgID, since.UTC().AddDate(0, 0, -1).Format(DateLayout)).
+		Where("holiday_calendars.is_active = ? AND (holiday_calendars.whats_app_account = '' OR holiday_calendars.whats_app_account = ?)", true, account).
+		Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+
+	closures := make([]Closure, len(holidays))
+	for i, h := range holidays {
+		closures[i] = Closure{Name: h.Name, Start: h.StartDate, End: h.EndDate}
+	}
+	return closures, nil
+}
@@ -0,0 +1,123 @@
+package contactutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// AttributeFilterOps lists the operators supported by AttributeCondition.
+var AttributeFilterOps = []string{"eq", "ne", "gt", "gte", "lt", "lte", "contains", "in", "is_empty", "is_not_empty"}
+
+var attributeRangeOps = map[string]string{
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// AttributeSQLExpr returns the SQL expression used to filter and index an
+// attribute stored in the given JSONB column. Numbers are extracted as numeric
+// so range filters compare numerically; dates are stored as ISO strings and
+// compare correctly as text. The key must be valid per IsValidAttributeKey,
+// which makes it safe to inline.
+func AttributeSQLExpr(column string, def models.ContactAttribute) string {
+	if def.Type == models.ContactAttributeTypeNumber {
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%[1]s->'%[2]s') = 'number' THEN (%[1]s->>'%[2]s')::numeric END)", column, def.Key)
+	}
+	return fmt.Sprintf("(%s->>'%s')", column, def.Key)
+}
+
+// AttributeCondition builds a SQL condition and its arguments comparing the
+// attribute def in the given JSONB column using op. Equality uses JSONB
+// containment so it can use the GIN index; range and contains filters use
+// AttributeSQLExpr so they can use the per-attribute expression index.
+// For "in", value may be a list or a comma-separated string.
+func AttributeCondition(column string, def models.ContactAttribute, op string, value interface{}) (string, []interface{}, error) {
+	if !IsValidAttributeKey(def.Key) {
+		return "", nil, fmt.Errorf("invalid attribute key: %s", def.Key)
+	}
+	// Filters shouldn't fail on values that are only invalid for writes (e.g. below min)
+	def.Min, def.Max, def.Pattern = nil, nil, ""
+
+	containment := func(v interface{}) string {
+		data, _ := json.Marshal(map[string]interface{}{def.Key: v})
+		return string(data)
+	}
+
+	switch op {
+	case "eq", "ne":
+		v, err := CoerceAttributeValue(def, value)
+		if err != nil || v == nil {
+			return "", nil, fmt.Errorf("invalid value for attribute %s", def.Key)
+		}
+		if op == "eq" {
+			return column + " @> ?::jsonb", []interface{}{containment(v)}, nil
+		}
+		return "NOT (COALESCE(" + column + ", '{}'::jsonb) @> ?::jsonb)", []interface{}{containment(v)}, nil
+
+	case "in":
+		var raw []interface{}
+		switch val := value.(type) {
+		case []interface{}:
+			raw = val
+		case []string:
+			for _, s := range val {
+				raw = append(raw, s)
+			}
+		case string:
+			for _, s := range strings.Split(val, ",") {
+				raw = append(raw, s)
+			}
+		default:
+			raw = []interface{}{val}
+		}
+		conditions := make([]string, 0, len(raw))
+		args := make([]interface{}, 0, len(raw))
+		for _, r := range raw {
+			v, err := CoerceAttributeValue(def, r)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid value for attribute %s", def.Key)
+			}
+			if v == nil {
+				continue
+			}
+			conditions = append(conditions, column+" @> ?::jsonb")
+			args = append(args, containment(v))
+		}
+		if len(conditions) == 0 {
+			return "", nil, fmt.Errorf("invalid value for attribute %s", def.Key)
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+
+	case "gt", "gte", "lt", "lte":
+		if def.Type != models.ContactAttributeTypeNumber && def.Type != models.ContactAttributeTypeDate {
+			return "", nil, fmt.Errorf("operator %s is only supported for number and date attributes", op)
+		}
+		v, err := CoerceAttributeValue(def, value)
+		if err != nil || v == nil {
+			return "", nil, fmt.Errorf("invalid value for attribute %s", def.Key)
+		}
+		return AttributeSQLExpr(column, def) + " " + attributeRangeOps[op] + " ?", []interface{}{v}, nil
+
+	case "contains":
+		if def.Type != models.ContactAttributeTypeText {
+			return "", nil, fmt.Errorf("operator contains is only supported for text attributes")
+		}
+		s, _ := value.(string)
+		if strings.TrimSpace(s) == "" {
+			return "", nil, fmt.Errorf("invalid value for attribute %s", def.Key)
+		}
+		return AttributeSQLExpr(column, def) + " ILIKE ?", []interface{}{"%" + strings.TrimSpace(s) + "%"}, nil
+
+	case "is_empty":
+		return fmt.Sprintf("(%s->'%s') IS NULL", column, def.Key), nil, nil
+
+	case "is_not_empty":
+		return fmt.Sprintf("(%s->'%s') IS NOT NULL", column, def.Key), nil, nil
+	}
+
+	return "", nil, fmt.Errorf("invalid attribute filter operator: %s", op)
+}
@@ -0,0 +1,253 @@
+package contactutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// AttributeDateLayout is the canonical storage format for date attributes.
+// ISO dates sort lexically, so range filters can compare them as text.
+const AttributeDateLayout = "2006-01-02"
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// IsValidAttributeKey reports whether key can be used as an attribute key.
+// Keys are lowercase snake_case so they are safe to use as WhatsApp named
+// template parameters and inside SQL expression indexes.
+func IsValidAttributeKey(key string) bool {
+	return attributeKeyPattern.MatchString(key)
+}
+
+// ValidateAttributeDefinition checks the type-specific settings of an attribute definition.
+func ValidateAttributeDefinition(def *models.ContactAttribute) error {
+	if !IsValidAttributeKey(def.Key) {
+		return fmt.Errorf("key must start with a letter and contain only lowercase letters, digits and underscores (max 40)")
+	}
+	if strings.TrimSpace(def.Label) == "" {
+		return fmt.Errorf("label is required")
+	}
+	if !def.Type.IsValid() {
+		return fmt.Errorf("invalid type. Valid types: text, number, date, enum, boolean")
+	}
+	if def.Type == models.ContactAttributeTypeEnum && len(def.Options) == 0 {
+		return fmt.Errorf("enum attributes require at least one option")
+	}
+	if def.Min != nil && def.Max != nil && *def.Min > *def.Max {
+		return fmt.Errorf("min cannot be greater than max")
+	}
+	if def.Pattern != "" {
+		if def.Type != models.ContactAttributeTypeText {
+			return fmt.Errorf("pattern is only supported for text attributes")
+		}
+		if _, err := regexp.Compile(def.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	return nil
+}
+
+// CoerceAttributeValue validates v against def and returns it in canonical
+// storage form: string for text, enum and date (YYYY-MM-DD), float64 for
+// number and bool for boolean. Strings are parsed for every type so CSV cells
+// and query parameters can be passed directly. A nil or empty value returns nil.
+func CoerceAttributeValue(def models.ContactAttribute, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok {
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		v = s
+	}
+
+	switch def.Type {
+	case models.ContactAttributeTypeText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be a string", def.Key)
+		}
+		length := float64(len([]rune(s)))
+		if def.Min != nil && length < *def.Min {
+			return nil, fmt.Errorf("%s: must be at least %v characters", def.Key, *def.Min)
+		}
+		if def.Max != nil && length > *def.Max {
+			return nil, fmt.Errorf("%s: must be at most %v characters", def.Key, *def.Max)
+		}
+		if def.Pattern != "" {
+			re, err := regexp.Compile(def.Pattern)
+			if err != nil || !re.MatchString(s) {
+				return nil, fmt.Errorf("%s: does not match the required format", def.Key)
+			}
+		}
+		return s, nil
+
+	case models.ContactAttributeTypeNumber:
+		var n float64
+		switch val := v.(type) {
+		case float64:
+			n = val
+		case int:
+			n = float64(val)
+		case json.Number:
+			f, err := val.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("%s: must be a number", def.Key)
+			}
+			n = f
+		case string:
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: must be a number", def.Key)
+			}
+			n = f
+		default:
+			return nil, fmt.Errorf("%s: must be a number", def.Key)
+		}
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("%s: must be a number", def.Key)
+		}
+		if def.Min != nil && n < *def.Min {
+			return nil, fmt.Errorf("%s: must be at least %v", def.Key, *def.Min)
+		}
+		if def.Max != nil && n > *def.Max {
+			return nil, fmt.Errorf("%s: must be at most %v", def.Key, *def.Max)
+		}
+		return n, nil
+
+	case models.ContactAttributeTypeDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be a date (YYYY-MM-DD)", def.Key)
+		}
+		t, err := time.Parse(AttributeDateLayout, s)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: must be a date (YYYY-MM-DD)", def.Key)
+			}
+		}
+		return t.Format(AttributeDateLayout), nil
+
+	case models.ContactAttributeTypeEnum:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be one of %s", def.Key, strings.Join(def.Options, ", "))
+		}
+		for _, opt := range def.Options {
+			if strings.EqualFold(opt, s) {
+				return opt, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: must be one of %s", def.Key, strings.Join(def.Options, ", "))
+
+	case models.ContactAttributeTypeBoolean:
+		switch val := v.(type) {
+		case bool:
+			return val, nil
+		case string:
+			switch strings.ToLower(val) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: must be true or false", def.Key)
+	}
+
+	return nil, fmt.Errorf("%s: unsupported attribute type %q", def.Key, def.Type)
+}
+
+// ValidateAttributes validates attribute values against the organization's
+// definitions and returns them in canonical form. A nil value in the result
+// means the key should be removed. Unknown keys are rejected, as is clearing a
+// required attribute. When checkRequired is set (contact creation), every
+// required attribute must be present.
+func ValidateAttributes(defs []models.ContactAttribute, values map[string]interface{}, checkRequired bool) (map[string]interface{}, error) {
+	byKey := make(map[string]models.ContactAttribute, len(defs))
+	for _, d := range defs {
+		byKey[d.Key] = d
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key, raw := range values {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown attribute", key)
+		}
+		v, err := CoerceAttributeValue(def, raw)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil && def.Required {
+			return nil, fmt.Errorf("%s: is required", key)
+		}
+		result[key] = v
+	}
+
+	if checkRequired {
+		for _, d := range defs {
+			if d.Required && result[d.Key] == nil {
+				return nil, fmt.Errorf("%s: is required", d.Key)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ApplyAttributes merges validated values into existing attributes,
+// removing keys whose value is nil.
+func ApplyAttributes(existing models.JSONB, values map[string]interface{}) models.JSONB {
+	merged := models.JSONB{}
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range values {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// FormatAttributeValue renders a stored attribute value as text, e.g. for
+// CSV export or template parameters. Whole numbers are rendered without decimals.
+func FormatAttributeValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// AttributeTemplateParams returns a contact's attribute values as template
+// parameters keyed by attribute key. Explicit params take precedence over
+// attribute values; the returned map is a new map.
+func AttributeTemplateParams(attrs models.JSONB, params map[string]string) map[string]string {
+	result := make(map[string]string, len(attrs)+len(params))
+	for k, v := range attrs {
+		result[k] = FormatAttributeValue(v)
+	}
+	for k, v := range params {
+		result[k] = v
+	}
+	return result
+}
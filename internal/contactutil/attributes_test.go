@@ -0,0 +1,177 @@
+package contactutil
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+func TestIsValidAttributeKey(t *testing.T) {
+	assert.True(t, IsValidAttributeKey("city"))
+	assert.True(t, IsValidAttributeKey("order_count_2"))
+	assert.False(t, IsValidAttributeKey(""))
+	assert.False(t, IsValidAttributeKey("2fa"))
+	assert.False(t, IsValidAttributeKey("City"))
+	assert.False(t, IsValidAttributeKey("first-name"))
+	assert.False(t, IsValidAttributeKey("x'; drop table contacts; --"))
+}
+
+func TestValidateAttributeDefinition(t *testing.T) {
+	valid := models.ContactAttribute{Key: "plan", Label: "Plan", Type: models.ContactAttributeTypeEnum, Options: models.StringArray{"free", "pro"}}
+	assert.NoError(t, ValidateAttributeDefinition(&valid))
+
+	noOptions := models.ContactAttribute{Key: "plan", Label: "Plan", Type: models.ContactAttributeTypeEnum}
+	assert.Error(t, ValidateAttributeDefinition(&noOptions))
+
+	badType := models.ContactAttribute{Key: "plan", Label: "Plan", Type: "json"}
+	assert.Error(t, ValidateAttributeDefinition(&badType))
+
+	badRange := models.ContactAttribute{Key: "age", Label: "Age", Type: models.ContactAttributeTypeNumber, Min: floatPtr(10), Max: floatPtr(1)}
+	assert.Error(t, ValidateAttributeDefinition(&badRange))
+
+	badPattern := models.ContactAttribute{Key: "code", Label: "Code", Type: models.ContactAttributeTypeText, Pattern: "("}
+	assert.Error(t, ValidateAttributeDefinition(&badPattern))
+
+	patternOnNumber := models.ContactAttribute{Key: "age", Label: "Age", Type: models.ContactAttributeTypeNumber, Pattern: "^[0-9]+$"}
+	assert.Error(t, ValidateAttributeDefinition(&patternOnNumber))
+}
+
+func TestCoerceAttributeValue(t *testing.T) {
+	text := models.ContactAttribute{Key: "code", Type: models.ContactAttributeTypeText, Pattern: `^[A-Z]{3}$`, Max: floatPtr(3)}
+	number := models.ContactAttribute{Key: "age", Type: models.ContactAttributeTypeNumber, Min: floatPtr(0), Max: floatPtr(150)}
+	date := models.ContactAttribute{Key: "dob", Type: models.ContactAttributeTypeDate}
+	enum := models.ContactAttribute{Key: "plan", Type: models.ContactAttributeTypeEnum, Options: models.StringArray{"Free", "Pro"}}
+	boolean := models.ContactAttribute{Key: "vip", Type: models.ContactAttributeTypeBoolean}
+
+	tests := []struct {
+		name string
+		def  models.ContactAttribute
+		in   interface{}
+		want interface{}
+	}{
+		{"text", text, "ABC", "ABC"},
+		{"number from float", number, float64(42), float64(42)},
+		{"number from string", number, " 42.5 ", 42.5},
+		{"date", date, "2024-02-29", "2024-02-29"},
+		{"date from RFC3339", date, "2024-02-29T10:00:00Z", "2024-02-29"},
+		{"enum case-insensitive", enum, "pro", "Pro"},
+		{"boolean", boolean, true, true},
+		{"boolean from string", boolean, "yes", true},
+		{"empty string clears", text, "  ", nil},
+		{"nil clears", number, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceAttributeValue(tt.def, tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	invalid := []struct {
+		name string
+		def  models.ContactAttribute
+		in   interface{}
+	}{
+		{"text pattern", text, "abc"},
+		{"text too long", text, "ABCD"},
+		{"text wrong type", text, float64(1)},
+		{"number not numeric", number, "many"},
+		{"number below min", number, float64(-1)},
+		{"number above max", number, "151"},
+		{"date invalid", date, "2023-02-29"},
+		{"enum not an option", enum, "enterprise"},
+		{"boolean invalid", boolean, "maybe"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CoerceAttributeValue(tt.def, tt.in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	defs := []models.ContactAttribute{
+		{Key: "city", Type: models.ContactAttributeTypeText, Required: true},
+		{Key: "age", Type: models.ContactAttributeTypeNumber},
+	}
+
+	got, err := ValidateAttributes(defs, map[string]interface{}{"city": "Pune", "age": "30"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"city": "Pune", "age": float64(30)}, got)
+
+	_, err = ValidateAttributes(defs, map[string]interface{}{"age": 30}, true)
+	assert.ErrorContains(t, err, "city")
+
+	// Partial updates don't require all required attributes...
+	_, err = ValidateAttributes(defs, map[string]interface{}{"age": 30}, false)
+	assert.NoError(t, err)
+
+	// ...but can't clear one
+	_, err = ValidateAttributes(defs, map[string]interface{}{"city": nil}, false)
+	assert.ErrorContains(t, err, "required")
+
+	_, err = ValidateAttributes(defs, map[string]interface{}{"unknown": "x"}, false)
+	assert.ErrorContains(t, err, "unknown attribute")
+}
+
+func TestApplyAttributes(t *testing.T) {
+	existing := models.JSONB{"city": "Pune", "age": float64(30)}
+	merged := ApplyAttributes(existing, map[string]interface{}{"age": nil, "vip": true})
+	assert.Equal(t, models.JSONB{"city": "Pune", "vip": true}, merged)
+	// Existing map is not modified
+	assert.Equal(t, float64(30), existing["age"])
+}
+
+func TestAttributeTemplateParams(t *testing.T) {
+	attrs := models.JSONB{"city": "Pune", "orders": float64(3), "vip": true}
+	params := AttributeTemplateParams(attrs, map[string]string{"city": "Mumbai", "1": "Alice"})
+	assert.Equal(t, map[string]string{
+		"city":   "Mumbai",
+		"orders": "3",
+		"vip":    "true",
+		"1":      "Alice",
+	}, params)
+}
+
+func TestAttributeCondition(t *testing.T) {
+	number := models.ContactAttribute{Key: "orders", Type: models.ContactAttributeTypeNumber, Min: floatPtr(10)}
+	text := models.ContactAttribute{Key: "city", Type: models.ContactAttributeTypeText}
+
+	cond, args, err := AttributeCondition("attributes", text, "eq", "Pune")
+	require.NoError(t, err)
+	assert.Equal(t, "attributes @> ?::jsonb", cond)
+	assert.Equal(t, []interface{}{`{"city":"Pune"}`}, args)
+
+	// Min/max only apply to writes
+	cond, args, err = AttributeCondition("c.attributes", number, "gte", "5")
+	require.NoError(t, err)
+	assert.Equal(t, "(CASE WHEN jsonb_typeof(c.attributes->'orders') = 'number' THEN (c.attributes->>'orders')::numeric END) >= ?", cond)
+	assert.Equal(t, []interface{}{float64(5)}, args)
+
+	cond, args, err = AttributeCondition("attributes", text, "in", []interface{}{"Pune", "Mumbai"})
+	require.NoError(t, err)
+	assert.Equal(t, "(attributes @> ?::jsonb OR attributes @> ?::jsonb)", cond)
+	assert.Len(t, args, 2)
+
+	cond, _, err = AttributeCondition("attributes", text, "is_empty", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "(attributes->'city') IS NULL", cond)
+
+	_, _, err = AttributeCondition("attributes", text, "gt", "A")
+	assert.ErrorContains(t, err, "only supported for number and date")
+
+	_, _, err = AttributeCondition("attributes", number, "contains", "1")
+	assert.Error(t, err)
+
+	_, _, err = AttributeCondition("attributes", number, "eq", "many")
+	assert.Error(t, err)
+
+	_, _, err = AttributeCondition("attributes", text, "regex", ".*")
+	assert.ErrorContains(t, err, "invalid attribute filter operator")
+}
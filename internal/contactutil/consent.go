@@ -0,0 +1,175 @@
+package contactutil
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// Default consent keywords, matched case-insensitively against the whole message.
+var (
+	DefaultOptOutKeywords = []string{"STOP", "UNSUBSCRIBE", "STOP ALL", "OPT OUT", "CANCEL"}
+	DefaultOptInKeywords  = []string{"START", "SUBSCRIBE", "UNSTOP", "OPT IN"}
+)
+
+// Default confirmation replies sent after a consent keyword is handled.
+const (
+	DefaultOptOutReply = "You have been unsubscribed and will no longer receive messages from us. Reply START to subscribe again."
+	DefaultOptInReply  = "You have been subscribed to messages from us. Reply STOP to unsubscribe."
+)
+
+// ConsentSettings configures how opt-out and opt-in keywords are handled.
+// Stored in the organization settings under the consent_* keys.
+type ConsentSettings struct {
+	OptOutKeywords []string `json:"opt_out_keywords"`
+	OptInKeywords  []string `json:"opt_in_keywords"`
+	OptOutReply    string   `json:"opt_out_reply"` // Empty disables the confirmation
+	OptInReply     string   `json:"opt_in_reply"`  // Empty disables the confirmation
+}
+
+// DefaultConsentSettings returns the settings used when an organization has
+// not configured consent keywords.
+func DefaultConsentSettings() ConsentSettings {
+	return ConsentSettings{
+		OptOutKeywords: append([]string(nil), DefaultOptOutKeywords...),
+		OptInKeywords:  append([]string(nil), DefaultOptInKeywords...),
+		OptOutReply:    DefaultOptOutReply,
+		OptInReply:     DefaultOptInReply,
+	}
+}
+
+// ParseConsentSettings reads consent settings from organization settings,
+// falling back to the defaults for keys that are not set.
+func ParseConsentSettings(settings models.JSONB) ConsentSettings {
+	cs := DefaultConsentSettings()
+	if v, ok := stringList(settings["consent_opt_out_keywords"]); ok {
+		cs.OptOutKeywords = v
+	}
+	if v, ok := stringList(settings["consent_opt_in_keywords"]); ok {
+		cs.OptInKeywords = v
+	}
+	if v, ok := settings["consent_opt_out_reply"].(string); ok {
+		cs.OptOutReply = v
+	}
+	if v, ok := settings["consent_opt_in_reply"].(string); ok {
+		cs.OptInReply = v
+	}
+	return cs
+}
+
+// GetConsentSettings loads the organization's consent settings.
+func GetConsentSettings(db *gorm.DB, orgID uuid.UUID) ConsentSettings {
+	var org models.Organization
+	if err := db.Select("settings").Where("id = ?", orgID).First(&org).Error; err != nil {
+		return DefaultConsentSettings()
+	}
+	return ParseConsentSettings(org.Settings)
+}
+
+// NormalizeConsentKeywords trims, upper-cases and de-duplicates keywords,
+// dropping empty entries.
+func NormalizeConsentKeywords(keywords []string) []string {
+	seen := make(map[string]bool, len(keywords))
+	out := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		k = normalizeKeyword(k)
+		if k != "" && !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// MatchConsentKeyword reports whether text is an opt-out or opt-in keyword.
+// The whole message must match, ignoring case, surrounding whitespace and
+// trailing punctuation, so "Please stop sending me offers" is not an opt-out.
+func MatchConsentKeyword(cs ConsentSettings, text string) (models.ConsentStatus, bool) {
+	text = normalizeKeyword(text)
+	if text == "" {
+		return "", false
+	}
+	for _, k := range cs.OptOutKeywords {
+		if normalizeKeyword(k) == text {
+			return models.ConsentStatusOptedOut, true
+		}
+	}
+	for _, k := range cs.OptInKeywords {
+		if normalizeKeyword(k) == text {
+			return models.ConsentStatusOptedIn, true
+		}
+	}
+	return "", false
+}
+
+// IsOptedOut reports whether the contact has opted out of messages.
+func IsOptedOut(contact *models.Contact) bool {
+	return contact != nil && contact.ConsentStatus == models.ConsentStatusOptedOut
+}
+
+// RecordConsent stores a consent record for the contact and updates the
+// contact's current consent status in a single transaction.
+func RecordConsent(db *gorm.DB, contact *models.Contact, status models.ConsentStatus, source models.ConsentSource, proof models.JSONB, createdBy *uuid.UUID) (*models.ContactConsent, error) {
+	now := time.Now()
+	if proof == nil {
+		proof = models.JSONB{}
+	}
+	record := &models.ContactConsent{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: contact.OrganizationID,
+		ContactID:      contact.ID,
+		Channel:        "whatsapp",
+		Status:         status,
+		Source:         source,
+		Proof:          proof,
+		RecordedAt:     now,
+		CreatedBy:      createdBy,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Contact{}).Where("id = ?", contact.ID).Updates(map[string]interface{}{
+			"consent_status":     status,
+			"consent_updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contact.ConsentStatus = status
+	contact.ConsentUpdatedAt = &now
+	return record, nil
+}
+
+// normalizeKeyword upper-cases s, collapses inner whitespace and strips
+// surrounding punctuation.
+func normalizeKeyword(s string) string {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
+
+// stringList converts a JSON array value into a string slice.
+func stringList(v interface{}) ([]string, bool) {
+	switch list := v.(type) {
+	case []string:
+		return list, true
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
@@ -0,0 +1,76 @@
+package contactutil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchConsentKeyword(t *testing.T) {
+	cs := DefaultConsentSettings()
+
+	tests := []struct {
+		text    string
+		status  models.ConsentStatus
+		matched bool
+	}{
+		{"STOP", models.ConsentStatusOptedOut, true},
+		{"  stop. ", models.ConsentStatusOptedOut, true},
+		{"Stop  all!", models.ConsentStatusOptedOut, true},
+		{"start", models.ConsentStatusOptedIn, true},
+		{"please stop sending offers", "", false},
+		{"", "", false},
+		{"...", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			status, matched := MatchConsentKeyword(cs, tt.text)
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
+
+func TestParseConsentSettings(t *testing.T) {
+	cs := ParseConsentSettings(models.JSONB{
+		"consent_opt_out_keywords": []interface{}{"BAJA", "ALTO"},
+		"consent_opt_out_reply":    "",
+	})
+	assert.Equal(t, []string{"BAJA", "ALTO"}, cs.OptOutKeywords)
+	assert.Equal(t, DefaultOptInKeywords, cs.OptInKeywords)
+	assert.Empty(t, cs.OptOutReply)
+	assert.Equal(t, DefaultOptInReply, cs.OptInReply)
+
+	status, matched := MatchConsentKeyword(cs, "baja")
+	assert.True(t, matched)
+	assert.Equal(t, models.ConsentStatusOptedOut, status)
+	_, matched = MatchConsentKeyword(cs, "stop")
+	assert.False(t, matched)
+}
+
+func TestNormalizeConsentKeywords(t *testing.T) {
+	assert.Equal(t, []string{"STOP", "OPT OUT"}, NormalizeConsentKeywords([]string{" stop", "STOP", "", "opt   out"}))
+}
+
+func TestRecordConsent(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	uid := uuid.New().String()[:8]
+	org := models.Organization{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "test-" + uid, Slug: "test-" + uid}
+	require.NoError(t, db.Create(&org).Error)
+	contact, _, err := GetOrCreateContact(db, org.ID, "919876543210", "")
+	require.NoError(t, err)
+
+	record, err := RecordConsent(db, contact, models.ConsentStatusOptedOut, models.ConsentSourceKeyword, models.JSONB{"text": "STOP"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, contact.ID, record.ContactID)
+	assert.True(t, IsOptedOut(contact))
+
+	var stored models.Contact
+	require.NoError(t, db.First(&stored, contact.ID).Error)
+	assert.Equal(t, models.ConsentStatusOptedOut, stored.ConsentStatus)
+	assert.NotNil(t, stored.ConsentUpdatedAt)
+}
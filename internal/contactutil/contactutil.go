@@ -1,14 +1,39 @@
 package contactutil
 
 import (
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/phoneutil"
 	"gorm.io/gorm"
 )
 
+// DefaultRegion returns the organization's default phone region
+// (settings key "default_phone_region"), or "" if none is configured.
+func DefaultRegion(db *gorm.DB, orgID uuid.UUID) string {
+	var org models.Organization
+	if err := db.Select("settings").Where("id = ?", orgID).First(&org).Error; err != nil {
+		return ""
+	}
+	if v, ok := org.Settings["default_phone_region"].(string); ok {
+		return strings.ToUpper(v)
+	}
+	return ""
+}
+
+// NormalizePhone normalizes user-entered phone input (API, CSV import, campaign
+// recipients) to E.164 digits using the organization's default region for
+// numbers written without a country code.
+func NormalizePhone(db *gorm.DB, orgID uuid.UUID, phoneNumber string) (string, error) {
+	return phoneutil.Normalize(phoneNumber, DefaultRegion(db, orgID))
+}
+
 // GetOrCreateContact finds or creates a contact for the given phone number.
 // Merges behaviors from both handler and worker implementations:
-//   - Normalizes phone (strips leading "+")
+//   - Normalizes phone to E.164 digits (strips "+" and formatting). The number
+//     is assumed to already include its country code, as WhatsApp sender IDs
+//     do; use NormalizePhone first for user-entered input.
 //   - Tries both normalized and +prefix forms
 //   - Updates profile name if changed
 //   - Handles race conditions on create by re-fetching
@@ -16,10 +41,10 @@ import (
 //
 // Returns the contact, whether it was newly created, and any error.
 func GetOrCreateContact(db *gorm.DB, orgID uuid.UUID, phoneNumber, profileName string) (*models.Contact, bool, error) {
-	// Normalize phone number (remove + prefix if present)
-	normalizedPhone := phoneNumber
-	if len(normalizedPhone) > 0 && normalizedPhone[0] == '+' {
-		normalizedPhone = normalizedPhone[1:]
+	// Normalize phone number (remove + prefix and formatting if present)
+	normalizedPhone, err := phoneutil.Normalize(phoneNumber, "")
+	if err != nil {
+		normalizedPhone = strings.TrimPrefix(strings.TrimSpace(phoneNumber), "+")
 	}
 
 	// Try to find existing contact with normalized phone (including soft-deleted)
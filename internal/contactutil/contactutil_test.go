@@ -2,6 +2,7 @@ package contactutil
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
@@ -105,3 +106,152 @@ func TestGetOrCreateContact_UpdatesProfileName(t *testing.T) {
 	require.NoError(t, db.First(&reloaded, contact.ID).Error)
 	assert.Equal(t, "New Name", reloaded.ProfileName)
 }
+
+func TestGetOrCreateContact_StripsFormatting(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	uid := uuid.New().String()[:8]
+	org := models.Organization{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "test-" + uid, Slug: "test-" + uid}
+	require.NoError(t, db.Create(&org).Error)
+
+	contact, isNew, err := GetOrCreateContact(db, org.ID, "+91 98765-43210", "Dave")
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, "919876543210", contact.PhoneNumber)
+}
+
+func TestNormalizePhone_UsesOrgDefaultRegion(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	uid := uuid.New().String()[:8]
+	org := models.Organization{
+		BaseModel: models.BaseModel{ID: uuid.New()},
+		Name:      "test-" + uid,
+		Slug:      "test-" + uid,
+		Settings:  models.JSONB{"default_phone_region": "IN"},
+	}
+	require.NoError(t, db.Create(&org).Error)
+
+	assert.Equal(t, "IN", DefaultRegion(db, org.ID))
+
+	for _, raw := range []string{"+91 98765 43210", "919876543210", "098765 43210"} {
+		phone, err := NormalizePhone(db, org.ID, raw)
+		require.NoError(t, err)
+		assert.Equal(t, "919876543210", phone, raw)
+	}
+}
+
+func TestFindDuplicateContacts(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	uid := uuid.New().String()[:8]
+	org := models.Organization{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "test-" + uid, Slug: "test-" + uid}
+	require.NoError(t, db.Create(&org).Error)
+
+	for _, phone := range []string{"098765 43210", "919876543210", "+91 98765 43210", "447911123456"} {
+		require.NoError(t, db.Create(&models.Contact{
+			BaseModel:      models.BaseModel{ID: uuid.New()},
+			OrganizationID: org.ID,
+			PhoneNumber:    phone,
+		}).Error)
+	}
+
+	groups, err := FindDuplicateContacts(db, org.ID, "IN")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "919876543210", groups[0].PhoneNumber)
+	assert.Equal(t, "919876543210", groups[0].Survivor.PhoneNumber)
+	assert.Len(t, groups[0].Duplicates, 2)
+}
+
+func TestMergeContacts_MovesDataIntoTarget(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	uid := uuid.New().String()[:8]
+	org := models.Organization{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "test-" + uid, Slug: "test-" + uid}
+	require.NoError(t, db.Create(&org).Error)
+
+	target := models.Contact{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		PhoneNumber:    "+91 98765 43210",
+		Tags:           models.JSONBArray{"vip"},
+		Metadata:       models.JSONB{"city": "Pune"},
+	}
+	source := models.Contact{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		PhoneNumber:    "919876543210",
+		ProfileName:    "Eve",
+		Tags:           models.JSONBArray{"vip", "lead"},
+		Metadata:       models.JSONB{"city": "Mumbai", "plan": "pro"},
+	}
+	require.NoError(t, db.Create(&target).Error)
+	require.NoError(t, db.Create(&source).Error)
+
+	msg := models.Message{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       source.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "hello",
+	}
+	require.NoError(t, db.Create(&msg).Error)
+
+	transfer := models.AgentTransfer{
+		OrganizationID:  org.ID,
+		ContactID:       source.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     source.PhoneNumber,
+	}
+	require.NoError(t, db.Create(&transfer).Error)
+	survey := models.SurveyResponse{
+		OrganizationID: org.ID,
+		TransferID:     transfer.ID,
+		ContactID:      source.ID,
+		Type:           models.SurveyTypeCSAT,
+		Channel:        models.SurveyChannelButtons,
+		SentAt:         time.Now(),
+		ExpiresAt:      time.Now().Add(24 * time.Hour),
+	}
+	require.NoError(t, db.Create(&survey).Error)
+
+	result, err := MergeContacts(db, org.ID, target.ID, []uuid.UUID{source.ID}, "919876543210")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.MessagesMoved)
+
+	var reloaded models.Contact
+	require.NoError(t, db.First(&reloaded, target.ID).Error)
+	assert.Equal(t, "919876543210", reloaded.PhoneNumber)
+	assert.Equal(t, "Eve", reloaded.ProfileName)
+	assert.ElementsMatch(t, []interface{}{"vip", "lead"}, []interface{}(reloaded.Tags))
+	assert.Equal(t, "Pune", reloaded.Metadata["city"])
+	assert.Equal(t, "pro", reloaded.Metadata["plan"])
+
+	var movedMsg models.Message
+	require.NoError(t, db.First(&movedMsg, msg.ID).Error)
+	assert.Equal(t, target.ID, movedMsg.ContactID)
+
+	var movedSurvey models.SurveyResponse
+	require.NoError(t, db.First(&movedSurvey, survey.ID).Error)
+	assert.Equal(t, target.ID, movedSurvey.ContactID)
+
+	var count int64
+	db.Unscoped().Model(&models.Contact{}).Where("id = ?", source.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestMergeContacts_RejectsOtherOrgSource(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	uid := uuid.New().String()[:8]
+	org := models.Organization{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "test-" + uid, Slug: "test-" + uid}
+	other := models.Organization{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "other-" + uid, Slug: "other-" + uid}
+	require.NoError(t, db.Create(&org).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	target := models.Contact{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: org.ID, PhoneNumber: "919876543210"}
+	foreign := models.Contact{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: other.ID, PhoneNumber: "919876543211"}
+	require.NoError(t, db.Create(&target).Error)
+	require.NoError(t, db.Create(&foreign).Error)
+
+	_, err := MergeContacts(db, org.ID, target.ID, []uuid.UUID{foreign.ID}, "")
+	assert.ErrorIs(t, err, ErrContactNotFound)
+}
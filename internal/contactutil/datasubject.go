@@ -0,0 +1,333 @@
+package contactutil
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// DataSubjectReport counts the records held about a contact in each table and
+// lists its media files. It is the dry-run report for erasure and is stored in
+// the DataSubjectRequest audit record.
+type DataSubjectReport struct {
+	Messages           int64    `json:"messages"`
+	ChatbotSessions    int64    `json:"chatbot_sessions"`
+	SessionMessages    int64    `json:"chatbot_session_messages"`
+	AgentTransfers     int64    `json:"agent_transfers"`
+	TransferHops       int64    `json:"transfer_hops"`
+	SurveyResponses    int64    `json:"survey_responses"`
+	CampaignRecipients int64    `json:"campaign_recipients"`
+	ConsentRecords     int64    `json:"consent_records"`
+	ActivityRecords    int64    `json:"activity_records"`
+	MediaFiles         []string `json:"media_files"`
+	MediaFilesMissing  []string `json:"media_files_missing,omitempty"` // Referenced by messages but not found in storage
+}
+
+// ToJSONB converts the report for storage in an audit record.
+func (r *DataSubjectReport) ToJSONB() models.JSONB {
+	data, _ := json.Marshal(r)
+	var out models.JSONB
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+// phoneVariants returns the forms a contact's phone number may be stored in by
+// tables that reference contacts by number, such as campaign recipients.
+func phoneVariants(phoneNumber string) []string {
+	digits := strings.TrimPrefix(phoneNumber, "+")
+	return []string{digits, "+" + digits}
+}
+
+// campaignRecipientsQuery scopes bulk_message_recipients to the contact's
+// number in the organization's campaigns.
+func campaignRecipientsQuery(db *gorm.DB, orgID uuid.UUID, contact *models.Contact) *gorm.DB {
+	return db.Model(&models.BulkMessageRecipient{}).
+		Where("phone_number IN ?", phoneVariants(contact.PhoneNumber)).
+		Where("campaign_id IN (?)", db.Model(&models.BulkMessageCampaign{}).Unscoped().Select("id").Where("organization_id = ?", orgID))
+}
+
+// transferIDsQuery selects the ids of the contact's agent transfers, for
+// tables that reference contacts through their transfers.
+func transferIDsQuery(db *gorm.DB, orgID uuid.UUID, contact *models.Contact) *gorm.DB {
+	return db.Model(&models.AgentTransfer{}).Select("id").Where("organization_id = ? AND contact_id = ?", orgID, contact.ID)
+}
+
+// ResolveMediaPath returns the path of a stored media file, or ""
+// if the stored path would escape mediaRoot.
+func ResolveMediaPath(mediaRoot, mediaURL string) string {
+	if mediaURL == "" || strings.Contains(mediaURL, "..") || filepath.IsAbs(mediaURL) {
+		return ""
+	}
+	return filepath.Join(mediaRoot, mediaURL)
+}
+
+// BuildDataSubjectReport counts everything stored about a contact. Soft-deleted
+// rows are included since erasure removes them too.
+func BuildDataSubjectReport(db *gorm.DB, orgID uuid.UUID, contact *models.Contact, mediaRoot string) (*DataSubjectReport, error) {
+	report := &DataSubjectReport{MediaFiles: []string{}}
+	udb := db.Unscoped().Session(&gorm.Session{})
+
+	counts := []struct {
+		query *gorm.DB
+		dest  *int64
+	}{
+		{udb.Model(&models.Message{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &report.Messages},
+		{udb.Model(&models.ChatbotSession{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &report.ChatbotSessions},
+		{udb.Model(&models.ChatbotSessionMessage{}).Where("session_id IN (?)",
+			udb.Model(&models.ChatbotSession{}).Select("id").Where("organization_id = ? AND contact_id = ?", orgID, contact.ID)), &report.SessionMessages},
+		{udb.Model(&models.AgentTransfer{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &report.AgentTransfers},
+		{udb.Model(&models.TransferHop{}).Where("transfer_id IN (?)", transferIDsQuery(udb, orgID, contact)), &report.TransferHops},
+		{udb.Model(&models.SurveyResponse{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &report.SurveyResponses},
+		{campaignRecipientsQuery(udb, orgID, contact), &report.CampaignRecipients},
+		{udb.Model(&models.ContactConsent{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &report.ConsentRecords},
+		{udb.Model(&models.ContactActivity{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &report.ActivityRecords},
+	}
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var mediaURLs []string
+	if err := udb.Model(&models.Message{}).
+		Where("organization_id = ? AND contact_id = ? AND media_url <> ''", orgID, contact.ID).
+		Distinct().Pluck("media_url", &mediaURLs).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range mediaURLs {
+		p := ResolveMediaPath(mediaRoot, u)
+		if p == "" {
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			report.MediaFilesMissing = append(report.MediaFilesMissing, u)
+			continue
+		}
+		report.MediaFiles = append(report.MediaFiles, u)
+	}
+
+	return report, nil
+}
+
+// ExportContactData writes a ZIP archive of everything stored about a contact
+// to w: one JSON file per table, a manifest with the report, and the
+// contact's media files under media/.
+func ExportContactData(db *gorm.DB, orgID uuid.UUID, contact *models.Contact, mediaRoot string, w io.Writer) (*DataSubjectReport, error) {
+	report, err := BuildDataSubjectReport(db, orgID, contact, mediaRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	udb := db.Unscoped().Session(&gorm.Session{})
+	var (
+		messages        []models.Message
+		sessions        []models.ChatbotSession
+		sessionMessages []models.ChatbotSessionMessage
+		transfers       []models.AgentTransfer
+		hops            []models.TransferHop
+		surveys         []models.SurveyResponse
+		recipients      []models.BulkMessageRecipient
+		consents        []models.ContactConsent
+		activities      []models.ContactActivity
+	)
+	loads := []struct {
+		query *gorm.DB
+		dest  interface{}
+	}{
+		{udb.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID).Order("created_at"), &messages},
+		{udb.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID).Order("started_at"), &sessions},
+		{udb.Where("session_id IN (?)", udb.Model(&models.ChatbotSession{}).Select("id").
+			Where("organization_id = ? AND contact_id = ?", orgID, contact.ID)).Order("created_at"), &sessionMessages},
+		{udb.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID).Order("transferred_at"), &transfers},
+		{udb.Where("transfer_id IN (?)", transferIDsQuery(udb, orgID, contact)).Order("hopped_at"), &hops},
+		{udb.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID).Order("sent_at"), &surveys},
+		{campaignRecipientsQuery(udb, orgID, contact).Order("created_at"), &recipients},
+		{udb.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID).Order("recorded_at"), &consents},
+		{udb.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID).Order("created_at"), &activities},
+	}
+	for _, l := range loads {
+		if err := l.query.Find(l.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"manifest.json", map[string]interface{}{
+			"contact_id":  contact.ID,
+			"exported_at": time.Now().UTC(),
+			"report":      report,
+		}},
+		{"contact.json", contact},
+		{"messages.json", messages},
+		{"chatbot_sessions.json", sessions},
+		{"chatbot_session_messages.json", sessionMessages},
+		{"agent_transfers.json", transfers},
+		{"transfer_hops.json", hops},
+		{"survey_responses.json", surveys},
+		{"campaign_recipients.json", recipients},
+		{"consent_records.json", consents},
+		{"activity.json", activities},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+
+	for _, u := range report.MediaFiles {
+		if err := addFileToZip(zw, path.Join("media", filepath.ToSlash(u)), ResolveMediaPath(mediaRoot, u)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func addFileToZip(zw *zip.Writer, name, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open media file: %w", err)
+	}
+	defer f.Close()
+
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fw, f); err != nil {
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+	return nil
+}
+
+// ErasedPhoneNumber returns the placeholder phone number an anonymized contact
+// is given. It is unique per contact and reveals nothing about the original.
+func ErasedPhoneNumber(contactID uuid.UUID) string {
+	return "erased-" + contactID.String()
+}
+
+// EraseContactData irreversibly erases everything stored about a contact.
+// ErasureModeDelete hard-deletes every row, including the contact.
+// ErasureModeAnonymize keeps rows for reporting but blanks every field that
+// can hold personal data, replaces phone numbers with ErasedPhoneNumber and
+// soft-deletes the contact. In both modes media files are removed from
+// storage once the database changes are committed.
+// With dryRun set nothing is changed and only the report is returned.
+func EraseContactData(db *gorm.DB, orgID uuid.UUID, contact *models.Contact, mode models.ErasureMode, mediaRoot string, dryRun bool) (*DataSubjectReport, error) {
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("invalid erasure mode: %s", mode)
+	}
+
+	report, err := BuildDataSubjectReport(db, orgID, contact, mediaRoot)
+	if err != nil || dryRun {
+		return report, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		utx := tx.Unscoped().Session(&gorm.Session{})
+		sessionIDs := utx.Model(&models.ChatbotSession{}).Select("id").
+			Where("organization_id = ? AND contact_id = ?", orgID, contact.ID)
+		transferIDs := transferIDsQuery(utx, orgID, contact)
+
+		if mode == models.ErasureModeDelete {
+			// Children before parents so foreign keys hold
+			steps := []struct {
+				query *gorm.DB
+				model interface{}
+			}{
+				{utx.Where("session_id IN (?)", sessionIDs), &models.ChatbotSessionMessage{}},
+				{utx.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &models.ChatbotSession{}},
+				{utx.Where("transfer_id IN (?)", transferIDs), &models.TransferHop{}},
+				{utx.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &models.SurveyResponse{}},
+				{utx.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &models.AgentTransfer{}},
+				{campaignRecipientsQuery(utx, orgID, contact), &models.BulkMessageRecipient{}},
+				{utx.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &models.Message{}},
+				{utx.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &models.ContactConsent{}},
+				{utx.Where("organization_id = ? AND contact_id = ?", orgID, contact.ID), &models.ContactActivity{}},
+				{utx.Where("organization_id = ? AND id = ?", orgID, contact.ID), &models.Contact{}},
+			}
+			for _, s := range steps {
+				if err := s.query.Delete(s.model).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		erasedPhone := ErasedPhoneNumber(contact.ID)
+		steps := []struct {
+			query   *gorm.DB
+			updates map[string]interface{}
+		}{
+			{utx.Model(&models.ChatbotSessionMessage{}).Where("session_id IN (?)", sessionIDs),
+				map[string]interface{}{"message": ""}},
+			{utx.Model(&models.ChatbotSession{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID),
+				// call_stack holds a copy of session_data for each flow waiting on a subflow
+				map[string]interface{}{"phone_number": erasedPhone, "session_data": models.JSONB{}, "call_stack": models.JSONBArray{}}},
+			{utx.Model(&models.AgentTransfer{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID),
+				map[string]interface{}{"phone_number": erasedPhone, "notes": ""}},
+			{utx.Model(&models.TransferHop{}).Where("transfer_id IN (?)", transferIDs),
+				map[string]interface{}{"reason": "", "notes": ""}},
+			{utx.Model(&models.SurveyResponse{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID),
+				map[string]interface{}{"comment": ""}},
+			{campaignRecipientsQuery(utx, orgID, contact),
+				map[string]interface{}{"phone_number": erasedPhone, "recipient_name": "", "template_params": models.JSONB{}, "error_message": ""}},
+			{utx.Model(&models.Message{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID),
+				map[string]interface{}{
+					"content": "", "media_url": "", "media_filename": "", "whats_app_message_id": "",
+					"template_params": models.JSONB{}, "interactive_data": models.JSONB{}, "flow_response": models.JSONB{},
+					"error_message": "", "metadata": models.JSONB{},
+				}},
+			{utx.Model(&models.ContactConsent{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID),
+				map[string]interface{}{"proof": models.JSONB{}}},
+			{utx.Model(&models.ContactActivity{}).Where("organization_id = ? AND contact_id = ?", orgID, contact.ID),
+				map[string]interface{}{"data": models.JSONB{}}},
+			{utx.Model(&models.Contact{}).Where("organization_id = ? AND id = ?", orgID, contact.ID),
+				map[string]interface{}{
+					"phone_number": erasedPhone, "profile_name": "", "last_message_preview": "",
+					"tags": models.JSONBArray{}, "metadata": models.JSONB{}, "attributes": models.JSONB{},
+					"block_reason": "", "deleted_at": time.Now(),
+				}},
+		}
+		for _, s := range steps {
+			if err := s.query.UpdateColumns(s.updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range report.MediaFiles {
+		if err := os.Remove(ResolveMediaPath(mediaRoot, u)); err != nil && !os.IsNotExist(err) {
+			return report, fmt.Errorf("database records erased but failed to remove media file %s: %w", u, err)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,221 @@
+package contactutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// seedDataSubject creates a contact with a message carrying media, a chatbot
+// session inside a subflow with one message, a transfer with one hop and a survey response, and
+// a campaign delivery.
+func seedDataSubject(t *testing.T, db *gorm.DB, mediaRoot string) (*models.Organization, *models.Contact) {
+	t.Helper()
+
+	org := testutil.CreateTestOrganization(t, db)
+	contact := testutil.CreateTestContact(t, db, org.ID)
+	now := time.Now()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(mediaRoot, "images"), 0755))
+	mediaPath := filepath.Join("images", uuid.New().String()+".jpg")
+	require.NoError(t, os.WriteFile(filepath.Join(mediaRoot, mediaPath), []byte("jpeg"), 0644))
+
+	require.NoError(t, db.Create(&models.Message{
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeImage,
+		Content:         "my address is 221B Baker Street",
+		MediaURL:        mediaPath,
+	}).Error)
+
+	session := models.ChatbotSession{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     contact.PhoneNumber,
+		SessionData:     models.JSONB{"email": "a@example.com"},
+		CallStack: models.JSONBArray{map[string]interface{}{
+			"flow_id":      uuid.New().String(),
+			"step":         "ask_address",
+			"session_data": map[string]interface{}{"address": "221B Baker Street"},
+		}},
+	}
+	require.NoError(t, db.Create(&session).Error)
+	require.NoError(t, db.Create(&models.ChatbotSessionMessage{SessionID: session.ID, Direction: models.DirectionIncoming, Message: "a@example.com"}).Error)
+
+	transfer := models.AgentTransfer{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     contact.PhoneNumber,
+		Notes:           "VIP",
+	}
+	require.NoError(t, db.Create(&transfer).Error)
+	require.NoError(t, db.Create(&models.TransferHop{
+		OrganizationID: org.ID,
+		TransferID:     transfer.ID,
+		Trigger:        models.TransferHopTriggerManual,
+		Reason:         "billing",
+		Notes:          "asked about a refund for order 1234",
+		StartedAt:      now.Add(-time.Hour),
+		HoppedAt:       now,
+	}).Error)
+
+	score := 2
+	require.NoError(t, db.Create(&models.SurveyResponse{
+		OrganizationID: org.ID,
+		TransferID:     transfer.ID,
+		ContactID:      contact.ID,
+		Type:           models.SurveyTypeCSAT,
+		Channel:        models.SurveyChannelButtons,
+		Status:         models.SurveyStatusCompleted,
+		Score:          &score,
+		Comment:        "the agent shared my card number",
+		SentAt:         now,
+		ExpiresAt:      now.Add(24 * time.Hour),
+	}).Error)
+
+	template := testutil.CreateTestTemplate(t, db, org.ID, "test")
+	user := testutil.CreateTestUser(t, db, org.ID)
+	campaign := models.BulkMessageCampaign{OrganizationID: org.ID, Name: "Promo", WhatsAppAccount: "test", TemplateID: template.ID, CreatedBy: user.ID}
+	require.NoError(t, db.Create(&campaign).Error)
+	require.NoError(t, db.Create(&models.BulkMessageRecipient{CampaignID: campaign.ID, PhoneNumber: contact.PhoneNumber, RecipientName: "Jane", SentAt: &now}).Error)
+
+	return org, contact
+}
+
+func TestEraseContactData_DryRun(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	mediaRoot := t.TempDir()
+	org, contact := seedDataSubject(t, db, mediaRoot)
+
+	report, err := EraseContactData(db, org.ID, contact, models.ErasureModeDelete, mediaRoot, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Messages)
+	assert.Equal(t, int64(1), report.ChatbotSessions)
+	assert.Equal(t, int64(1), report.SessionMessages)
+	assert.Equal(t, int64(1), report.AgentTransfers)
+	assert.Equal(t, int64(1), report.TransferHops)
+	assert.Equal(t, int64(1), report.SurveyResponses)
+	assert.Equal(t, int64(1), report.CampaignRecipients)
+	assert.Len(t, report.MediaFiles, 1)
+
+	// Nothing was changed
+	var count int64
+	db.Model(&models.Contact{}).Where("id = ?", contact.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+	assert.FileExists(t, filepath.Join(mediaRoot, report.MediaFiles[0]))
+}
+
+func TestEraseContactData_Delete(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	mediaRoot := t.TempDir()
+	org, contact := seedDataSubject(t, db, mediaRoot)
+
+	report, err := EraseContactData(db, org.ID, contact, models.ErasureModeDelete, mediaRoot, false)
+	require.NoError(t, err)
+
+	after, err := BuildDataSubjectReport(db, org.ID, contact, mediaRoot)
+	require.NoError(t, err)
+	assert.Equal(t, &DataSubjectReport{MediaFiles: []string{}}, after)
+
+	var count int64
+	db.Unscoped().Model(&models.Contact{}).Where("id = ?", contact.ID).Count(&count)
+	assert.Zero(t, count)
+	assert.NoFileExists(t, filepath.Join(mediaRoot, report.MediaFiles[0]))
+}
+
+func TestEraseContactData_Anonymize(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	mediaRoot := t.TempDir()
+	org, contact := seedDataSubject(t, db, mediaRoot)
+	phone := contact.PhoneNumber
+
+	report, err := EraseContactData(db, org.ID, contact, models.ErasureModeAnonymize, mediaRoot, false)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(mediaRoot, report.MediaFiles[0]))
+
+	var stored models.Contact
+	require.NoError(t, db.Unscoped().First(&stored, contact.ID).Error)
+	assert.Equal(t, ErasedPhoneNumber(contact.ID), stored.PhoneNumber)
+	assert.Empty(t, stored.ProfileName)
+	assert.True(t, stored.DeletedAt.Valid)
+
+	// Records are kept for reporting but hold no personal data
+	var msg models.Message
+	require.NoError(t, db.Where("contact_id = ?", contact.ID).First(&msg).Error)
+	assert.Empty(t, msg.Content)
+	assert.Empty(t, msg.MediaURL)
+
+	var session models.ChatbotSession
+	require.NoError(t, db.Where("contact_id = ?", contact.ID).First(&session).Error)
+	assert.Empty(t, session.SessionData)
+	assert.Empty(t, session.CallStack)
+
+	var hop models.TransferHop
+	require.NoError(t, db.Where("organization_id = ?", org.ID).First(&hop).Error)
+	assert.Empty(t, hop.Reason)
+	assert.Empty(t, hop.Notes)
+
+	var survey models.SurveyResponse
+	require.NoError(t, db.Where("contact_id = ?", contact.ID).First(&survey).Error)
+	assert.Empty(t, survey.Comment)
+	require.NotNil(t, survey.Score)
+	assert.Equal(t, 2, *survey.Score)
+
+	var recipients int64
+	db.Model(&models.BulkMessageRecipient{}).Where("phone_number = ?", phone).Count(&recipients)
+	assert.Zero(t, recipients)
+}
+
+func TestExportContactData(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	mediaRoot := t.TempDir()
+	org, contact := seedDataSubject(t, db, mediaRoot)
+
+	var buf bytes.Buffer
+	report, err := ExportContactData(db, org.ID, contact, mediaRoot, &buf)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+		if f.Name == "chatbot_sessions.json" {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+			// Data collected by the calling flows is exported with the session
+			assert.Contains(t, string(data), "221B Baker Street")
+		}
+	}
+	for _, name := range []string{"manifest.json", "contact.json", "messages.json", "chatbot_sessions.json",
+		"chatbot_session_messages.json", "agent_transfers.json", "transfer_hops.json", "survey_responses.json",
+		"campaign_recipients.json"} {
+		assert.True(t, names[name], "missing %s", name)
+	}
+	assert.True(t, names["media/"+filepath.ToSlash(report.MediaFiles[0])])
+}
+
+func TestResolveMediaPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("/media", "images/a.jpg"), ResolveMediaPath("/media", "images/a.jpg"))
+	assert.Empty(t, ResolveMediaPath("/media", "../etc/passwd"))
+	assert.Empty(t, ResolveMediaPath("/media", "/etc/passwd"))
+	assert.Empty(t, ResolveMediaPath("/media", ""))
+}
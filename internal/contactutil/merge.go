@@ -0,0 +1,267 @@
+package contactutil
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/phoneutil"
+	"gorm.io/gorm"
+)
+
+// ErrContactNotFound is returned when a merge target or source does not exist
+// in the organization.
+var ErrContactNotFound = errors.New("contact not found")
+
+// MergeResult summarizes what was moved into the surviving contact.
+type MergeResult struct {
+	ContactID        uuid.UUID   `json:"contact_id"`
+	MergedContactIDs []uuid.UUID `json:"merged_contact_ids"`
+	MessagesMoved    int64       `json:"messages_moved"`
+	SessionsMoved    int64       `json:"sessions_moved"`
+	TransfersMoved   int64       `json:"transfers_moved"`
+	PhoneNumber      string      `json:"phone_number"`
+}
+
+// DuplicateGroup is a set of contacts whose phone numbers normalize to the
+// same E.164 number. Survivor is the contact the others should be merged into.
+type DuplicateGroup struct {
+	PhoneNumber string           `json:"phone_number"`
+	Survivor    models.Contact   `json:"survivor"`
+	Duplicates  []models.Contact `json:"duplicates"`
+}
+
+// MergeContacts merges the source contacts into the target contact in a single
+// transaction. Messages, chatbot sessions, agent transfers, survey responses,
+// consent records and activity log entries are re-pointed at the target, tags
+// are unioned and metadata and attributes are merged (target keys win). The
+// most recent consent change wins, and the target is blocked or on legal hold
+// if any source was.
+// Sources are hard-deleted so their phone numbers are freed from the unique
+// (organization_id, phone_number) index. If phoneNumber is non-empty the
+// surviving contact's phone number is updated to it.
+func MergeContacts(db *gorm.DB, orgID, targetID uuid.UUID, sourceIDs []uuid.UUID, phoneNumber string) (*MergeResult, error) {
+	ids := make([]uuid.UUID, 0, len(sourceIDs))
+	seen := map[uuid.UUID]bool{targetID: true}
+	for _, id := range sourceIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	result := &MergeResult{ContactID: targetID, MergedContactIDs: ids}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var target models.Contact
+		if err := tx.Where("id = ? AND organization_id = ?", targetID, orgID).First(&target).Error; err != nil {
+			return ErrContactNotFound
+		}
+		if len(ids) == 0 {
+			result.PhoneNumber = target.PhoneNumber
+			return nil
+		}
+
+		var sources []models.Contact
+		if err := tx.Unscoped().Where("id IN ? AND organization_id = ?", ids, orgID).
+			Order("created_at ASC").Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) != len(ids) {
+			return ErrContactNotFound
+		}
+
+		if phoneNumber == "" {
+			phoneNumber = target.PhoneNumber
+		}
+
+		res := tx.Model(&models.Message{}).Where("contact_id IN ?", ids).Update("contact_id", targetID)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.MessagesMoved = res.RowsAffected
+
+		res = tx.Model(&models.ChatbotSession{}).Where("contact_id IN ?", ids).
+			Updates(map[string]interface{}{"contact_id": targetID, "phone_number": phoneNumber})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.SessionsMoved = res.RowsAffected
+
+		res = tx.Model(&models.AgentTransfer{}).Where("contact_id IN ?", ids).
+			Updates(map[string]interface{}{"contact_id": targetID, "phone_number": phoneNumber})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.TransfersMoved = res.RowsAffected
+
+		if err := tx.Model(&models.SurveyResponse{}).Where("contact_id IN ?", ids).
+			Update("contact_id", targetID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.ContactConsent{}).Where("contact_id IN ?", ids).
+			Update("contact_id", targetID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.ContactActivity{}).Where("contact_id IN ?", ids).
+			Update("contact_id", targetID).Error; err != nil {
+			return err
+		}
+
+		mergeContactFields(&target, sources)
+
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Contact{}).Error; err != nil {
+			return err
+		}
+
+		// Sources are gone, so the normalized number can now be taken
+		target.PhoneNumber = phoneNumber
+		if err := tx.Model(&target).Select(
+			"phone_number", "profile_name", "whats_app_account", "assigned_user_id",
+			"last_message_at", "last_message_preview", "is_read", "tags", "metadata", "attributes",
+			"consent_status", "consent_updated_at",
+			"is_blocked", "blocked_at", "blocked_by", "block_reason",
+			"legal_hold", "legal_hold_reason", "legal_hold_at", "legal_hold_by",
+		).Updates(&target).Error; err != nil {
+			return err
+		}
+
+		// Keep denormalized phone numbers in sync with the survivor
+		if err := tx.Model(&models.ChatbotSession{}).Where("contact_id = ?", targetID).
+			Update("phone_number", phoneNumber).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AgentTransfer{}).Where("contact_id = ?", targetID).
+			Update("phone_number", phoneNumber).Error; err != nil {
+			return err
+		}
+
+		result.PhoneNumber = phoneNumber
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeContactFields folds source contact attributes into target.
+// Values already set on the target take precedence.
+func mergeContactFields(target *models.Contact, sources []models.Contact) {
+	tagSet := make(map[string]bool)
+	tags := models.JSONBArray{}
+	addTags := func(list models.JSONBArray) {
+		for _, t := range list {
+			if s, ok := t.(string); ok && !tagSet[s] {
+				tagSet[s] = true
+				tags = append(tags, s)
+			}
+		}
+	}
+	addTags(target.Tags)
+
+	if target.Metadata == nil {
+		target.Metadata = models.JSONB{}
+	}
+	if target.Attributes == nil {
+		target.Attributes = models.JSONB{}
+	}
+
+	for _, src := range sources {
+		addTags(src.Tags)
+		for k, v := range src.Metadata {
+			if _, exists := target.Metadata[k]; !exists {
+				target.Metadata[k] = v
+			}
+		}
+		for k, v := range src.Attributes {
+			if _, exists := target.Attributes[k]; !exists {
+				target.Attributes[k] = v
+			}
+		}
+		if target.ProfileName == "" {
+			target.ProfileName = src.ProfileName
+		}
+		if target.WhatsAppAccount == "" {
+			target.WhatsAppAccount = src.WhatsAppAccount
+		}
+		if target.AssignedUserID == nil {
+			target.AssignedUserID = src.AssignedUserID
+		}
+		if src.LastMessageAt != nil && (target.LastMessageAt == nil || src.LastMessageAt.After(*target.LastMessageAt)) {
+			target.LastMessageAt = src.LastMessageAt
+			target.LastMessagePreview = src.LastMessagePreview
+		}
+		if !src.IsRead {
+			target.IsRead = false
+		}
+		if src.ConsentUpdatedAt != nil && (target.ConsentUpdatedAt == nil || src.ConsentUpdatedAt.After(*target.ConsentUpdatedAt)) {
+			target.ConsentStatus = src.ConsentStatus
+			target.ConsentUpdatedAt = src.ConsentUpdatedAt
+		}
+		if src.IsBlocked && !target.IsBlocked {
+			target.IsBlocked = true
+			target.BlockedAt = src.BlockedAt
+			target.BlockedBy = src.BlockedBy
+			target.BlockReason = src.BlockReason
+		}
+		if src.LegalHold && !target.LegalHold {
+			target.LegalHold = true
+			target.LegalHoldReason = src.LegalHoldReason
+			target.LegalHoldAt = src.LegalHoldAt
+			target.LegalHoldBy = src.LegalHoldBy
+		}
+	}
+
+	target.Tags = tags
+}
+
+// FindDuplicateContacts groups an organization's contacts by normalized phone
+// number and returns the groups containing more than one contact. Numbers that
+// cannot be normalized are skipped. The survivor of each group is the contact
+// already stored in normalized form, or the oldest contact otherwise.
+func FindDuplicateContacts(db *gorm.DB, orgID uuid.UUID, region string) ([]DuplicateGroup, error) {
+	var contacts []models.Contact
+	if err := db.Select("id", "organization_id", "phone_number", "profile_name", "created_at").
+		Where("organization_id = ?", orgID).
+		Order("created_at ASC").
+		Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+
+	byPhone := make(map[string][]models.Contact)
+	for _, c := range contacts {
+		normalized, err := phoneutil.Normalize(c.PhoneNumber, region)
+		if err != nil {
+			continue
+		}
+		byPhone[normalized] = append(byPhone[normalized], c)
+	}
+
+	groups := make([]DuplicateGroup, 0)
+	for phone, list := range byPhone {
+		if len(list) < 2 {
+			continue
+		}
+		survivorIdx := 0
+		for i, c := range list {
+			if c.PhoneNumber == phone {
+				survivorIdx = i
+				break
+			}
+		}
+		group := DuplicateGroup{PhoneNumber: phone, Survivor: list[survivorIdx]}
+		for i, c := range list {
+			if i != survivorIdx {
+				group.Duplicates = append(group.Duplicates, c)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].PhoneNumber < groups[j].PhoneNumber })
+	return groups, nil
+}
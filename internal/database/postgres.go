@@ -63,6 +63,8 @@ func GetMigrationModels() []MigrationModel {
 		{"UserOrganization", &models.UserOrganization{}},
 		{"Team", &models.Team{}},
 		{"TeamMember", &models.TeamMember{}},
+		{"Skill", &models.Skill{}},
+		{"AgentSkill", &models.AgentSkill{}},
 		{"APIKey", &models.APIKey{}},
 		{"SSOProvider", &models.SSOProvider{}},
 		{"Webhook", &models.Webhook{}},
@@ -70,6 +72,11 @@ func GetMigrationModels() []MigrationModel {
 		{"WhatsAppAccount", &models.WhatsAppAccount{}},
 		{"Contact", &models.Contact{}},
 		{"Tag", &models.Tag{}},
+		{"ContactAttribute", &models.ContactAttribute{}},
+		{"Segment", &models.Segment{}},
+		{"ContactConsent", &models.ContactConsent{}},
+		{"ContactActivity", &models.ContactActivity{}},
+		{"DataSubjectRequest", &models.DataSubjectRequest{}},
 		{"Message", &models.Message{}},
 		{"Template", &models.Template{}},
 		{"WhatsAppFlow", &models.WhatsAppFlow{}},
@@ -81,9 +88,16 @@ func GetMigrationModels() []MigrationModel {
 
 		// Chatbot models
 		{"ChatbotSettings", &models.ChatbotSettings{}},
+		{"HolidayCalendar", &models.HolidayCalendar{}},
+		{"Holiday", &models.Holiday{}},
+		{"SLAPolicy", &models.SLAPolicy{}},
+		{"TransferHop", &models.TransferHop{}},
+		{"SurveyResponse", &models.SurveyResponse{}},
 		{"KeywordRule", &models.KeywordRule{}},
 		{"ChatbotFlow", &models.ChatbotFlow{}},
 		{"ChatbotFlowStep", &models.ChatbotFlowStep{}},
+		{"ChatbotFlowVersion", &models.ChatbotFlowVersion{}},
+		{"ChatbotFlowTestCase", &models.ChatbotFlowTestCase{}},
 		{"ChatbotSession", &models.ChatbotSession{}},
 		{"ChatbotSessionMessage", &models.ChatbotSessionMessage{}},
 		{"AIContext", &models.AIContext{}},
@@ -91,6 +105,8 @@ func GetMigrationModels() []MigrationModel {
 
 		// User tracking
 		{"UserAvailabilityLog", &models.UserAvailabilityLog{}},
+		{"ShiftSchedule", &models.ShiftSchedule{}},
+		{"ShiftException", &models.ShiftException{}},
 
 		// Canned responses
 		{"CannedResponse", &models.CannedResponse{}},
@@ -236,6 +252,10 @@ func getIndexes() []string {
 		`CREATE INDEX IF NOT EXISTS idx_bulk_campaigns_account ON bulk_message_campaigns(whats_app_account, status)`,
 		`CREATE INDEX IF NOT EXISTS idx_notification_rules_account ON notification_rules(whats_app_account, is_enabled)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_account ON messages(whats_app_account, created_at DESC)`,
+		// Retention purges
+		`CREATE INDEX IF NOT EXISTS idx_messages_org_created_unpurged ON messages(organization_id, created_at) WHERE body_purged_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_org_created_media ON messages(organization_id, created_at) WHERE media_url <> ''`,
+		`CREATE INDEX IF NOT EXISTS idx_chatbot_session_messages_created ON chatbot_session_messages(created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_contacts_account ON contacts(whats_app_account)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_canned_responses_org_name ON canned_responses(organization_id, name)`,
 		`CREATE INDEX IF NOT EXISTS idx_canned_responses_active ON canned_responses(organization_id, is_active, usage_count DESC)`,
@@ -254,6 +274,29 @@ func getIndexes() []string {
 		`CREATE INDEX IF NOT EXISTS idx_custom_roles_org_default ON custom_roles(organization_id, is_default) WHERE is_default = true`,
 		// GIN index for JSONB tag filtering
 		`CREATE INDEX IF NOT EXISTS idx_contacts_tags ON contacts USING GIN (tags)`,
+		// Custom contact attributes (per-attribute expression indexes are created when attributes are defined)
+		`CREATE INDEX IF NOT EXISTS idx_contacts_attributes ON contacts USING GIN (attributes jsonb_path_ops)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_attributes_org_key ON contact_attributes(organization_id, key) WHERE deleted_at IS NULL`,
+		// Segments
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_segments_org_name ON segments(organization_id, name) WHERE deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_bulk_recipients_phone_status ON bulk_message_recipients(phone_number, status)`,
+		// Consent history
+		`CREATE INDEX IF NOT EXISTS idx_contact_consents_contact_recorded ON contact_consents(contact_id, recorded_at DESC)`,
+		// Contact activity log
+		`CREATE INDEX IF NOT EXISTS idx_contact_activities_contact_type_created ON contact_activities(contact_id, type, created_at DESC)`,
+		// Skills
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_skills_org_name ON skills(organization_id, name) WHERE deleted_at IS NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_skills_user_skill ON agent_skills(user_id, skill_id) WHERE deleted_at IS NULL`,
+		// Holidays
+		`CREATE INDEX IF NOT EXISTS idx_holidays_calendar_dates ON holidays(calendar_id, start_date, end_date) WHERE deleted_at IS NULL`,
+		// SLA policies
+		`CREATE INDEX IF NOT EXISTS idx_sla_policies_org_position ON sla_policies(organization_id, position) WHERE deleted_at IS NULL`,
+		// Surveys
+		`CREATE INDEX IF NOT EXISTS idx_survey_responses_contact_pending ON survey_responses(contact_id, sent_at DESC) WHERE status <> 'completed'`,
+		// Shifts
+		`CREATE INDEX IF NOT EXISTS idx_shift_exceptions_user_period ON shift_exceptions(user_id, starts_at, ends_at) WHERE deleted_at IS NULL`,
+		// Chatbot flow versions
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_chatbot_flow_versions_flow_version ON chatbot_flow_versions(flow_id, version)`,
 		// User organizations
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_org_unique ON user_organizations(user_id, organization_id) WHERE deleted_at IS NULL`,
 	}
@@ -0,0 +1,120 @@
+// Package exprutil implements the expression language of chatbot flows and
+// the message templates built on it. Expressions are used for skip
+// conditions, conditional jumps, loop and keyword conditions, API response
+// mappings and script steps; templates render message bodies, URLs and
+// request bodies.
+//
+// Expressions only read the data they are given and cannot loop, so they
+// always finish quickly. Source length, nesting, string results and
+// template output are capped.
+package exprutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxSourceLength caps the length of an expression
+	maxSourceLength = 4096
+	// maxDepth caps how deeply an expression can nest
+	maxDepth = 64
+	// maxStringLength caps the strings an expression can build
+	maxStringLength = 64 * 1024
+)
+
+// evalFunc evaluates a compiled expression against data
+type evalFunc func(data map[string]interface{}) (interface{}, error)
+
+// Expr is a compiled expression
+type Expr struct {
+	src  string
+	eval evalFunc
+}
+
+// Compile parses an expression
+func Compile(src string) (*Expr, error) {
+	if len(src) > maxSourceLength {
+		return nil, errors.New("expression is too long")
+	}
+	if strings.TrimSpace(src) == "" {
+		return nil, errors.New("expression is empty")
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	eval, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return &Expr{src: src, eval: eval}, nil
+}
+
+// String returns the source of the expression
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression. Missing variables and fields are null
+// rather than errors; errors come from values an operation cannot use, such
+// as a division by zero.
+func (e *Expr) Eval(data map[string]interface{}) (interface{}, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return e.eval(data)
+}
+
+// Test reports whether the expression holds. Expressions that fail to
+// evaluate do not hold.
+func (e *Expr) Test(data map[string]interface{}) bool {
+	v, err := e.Eval(data)
+	return err == nil && Truthy(v)
+}
+
+// Eval compiles and evaluates an expression
+func Eval(src string, data map[string]interface{}) (interface{}, error) {
+	expr, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Eval(data)
+}
+
+// Test compiles and evaluates a condition. Conditions that fail to compile
+// or evaluate do not hold.
+func Test(src string, data map[string]interface{}) bool {
+	expr, err := Compile(src)
+	if err != nil {
+		return false
+	}
+	return expr.Test(data)
+}
+
+// Lookup returns the value at a path such as "order.items[0].price", or nil
+// when there is none or path is not a path
+func Lookup(data map[string]interface{}, path string) interface{} {
+	tokens, err := tokenize(path)
+	if err != nil || len(tokens) == 0 || tokens[0].kind != tokenIdent {
+		return nil
+	}
+	for _, tok := range tokens {
+		switch {
+		case tok.kind != tokenOperator:
+		case tok.text == "." || tok.text == "?." || tok.text == "[" || tok.text == "]":
+		default:
+			return nil
+		}
+	}
+	v, err := Eval(path, data)
+	if err != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,360 @@
+package exprutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eval(t *testing.T, src string, data map[string]interface{}) interface{} {
+	t.Helper()
+	v, err := Eval(src, data)
+	require.NoError(t, err, src)
+	return v
+}
+
+func TestEval_Arithmetic(t *testing.T) {
+	data := map[string]interface{}{"price": 250.0, "quantity": "3"}
+
+	assert.Equal(t, 750.0, eval(t, "price * quantity", data))
+	assert.Equal(t, 7.0, eval(t, "1 + 2 * 3", data))
+	assert.Equal(t, 9.0, eval(t, "(1 + 2) * 3", data))
+	assert.Equal(t, -1.0, eval(t, "2 - -(-3)", data))
+	assert.Equal(t, 1.0, eval(t, "10 % 3", data))
+	assert.Equal(t, 0.5, eval(t, ".5", data))
+	assert.Equal(t, 885.0, eval(t, "round(price * quantity * 1.18)", data))
+	assert.Equal(t, 3.14, eval(t, "round(3.14159, 2)", data))
+	assert.Equal(t, "Order 5", eval(t, "'Order ' + 5", data))
+}
+
+func TestEval_Logic(t *testing.T) {
+	data := map[string]interface{}{"a": "1", "b": "2", "c": "3"}
+
+	assert.Equal(t, true, eval(t, "a == '1' AND b == '2'", data))
+	assert.Equal(t, false, eval(t, "a == '1' and b == '3'", data))
+	assert.Equal(t, true, eval(t, "a == '9' || b == '2'", data))
+	assert.Equal(t, true, eval(t, "(a == '1' OR b == '9') && c == '3'", data))
+	assert.Equal(t, false, eval(t, "not (a == '1')", data))
+	assert.Equal(t, true, eval(t, "!missing", data))
+	assert.Equal(t, true, eval(t, "TRUE or false", data))
+
+	// Stops at the first operand that decides the result
+	assert.Equal(t, false, eval(t, "false and 1 / 0", data))
+	assert.Equal(t, true, eval(t, "true or 1 / 0", data))
+}
+
+func TestEval_Comparisons(t *testing.T) {
+	data := map[string]interface{}{"score": 85, "status": "active", "total": "120", "name": ""}
+
+	assert.Equal(t, true, eval(t, "score > 80", data))
+	assert.Equal(t, true, eval(t, "score >= 85", data))
+	assert.Equal(t, false, eval(t, "score < 85", data))
+	assert.Equal(t, true, eval(t, "score == '85'", data))
+	assert.Equal(t, true, eval(t, "total > 100", data)) // Numeric strings compare as numbers
+	assert.Equal(t, true, eval(t, `status == "active"`, data))
+	assert.Equal(t, true, eval(t, "status != 'inactive'", data))
+	assert.Equal(t, true, eval(t, "'b' > 'a'", data))
+	assert.Equal(t, true, eval(t, "missing == null", data))
+	assert.Equal(t, true, eval(t, "name == null", data))
+	assert.Equal(t, true, eval(t, "missing == ''", data))
+	assert.Equal(t, false, eval(t, "missing > 0", data))
+	assert.Equal(t, false, eval(t, "missing < 0", data))
+	assert.Equal(t, true, eval(t, "[1, 2] == [1, '2']", data))
+}
+
+func TestEval_Membership(t *testing.T) {
+	data := map[string]interface{}{
+		"city":  "Pune",
+		"tags":  models.JSONBArray{"vip", "wholesale"},
+		"attrs": models.JSONB{"plan": "gold"},
+		"note":  "please call me",
+	}
+
+	assert.Equal(t, true, eval(t, "city in ['Pune', 'Mumbai']", data))
+	assert.Equal(t, true, eval(t, "city not in ['Delhi']", data))
+	assert.Equal(t, true, eval(t, "'vip' in tags", data))
+	assert.Equal(t, true, eval(t, "tags contains 'wholesale'", data))
+	assert.Equal(t, true, eval(t, "'plan' in attrs", data))
+	assert.Equal(t, true, eval(t, "note contains 'call'", data))
+	assert.Equal(t, false, eval(t, "'x' in missing", data))
+	assert.Equal(t, true, eval(t, "contains(note, 'call')", data))
+}
+
+func TestEval_Paths(t *testing.T) {
+	data := map[string]interface{}{
+		"order": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"sku": "A1", "price": 10.0},
+				map[string]interface{}{"sku": "B2", "price": 5.5},
+			},
+		},
+		"records": []map[string]interface{}{{"id": 1}},
+		"contact": models.JSONB{"attributes": models.JSONB{"tier": "gold"}},
+	}
+
+	assert.Equal(t, "A1", eval(t, "order.items[0].sku", data))
+	assert.Equal(t, "B2", eval(t, "order.items.1.sku", data))
+	assert.Equal(t, "B2", eval(t, "order.items[-1].sku", data))
+	assert.Equal(t, "A1", eval(t, "order['items'][0]['sku']", data))
+	assert.Equal(t, 1, eval(t, "records[0].id", data))
+	assert.Equal(t, "gold", eval(t, "contact.attributes.tier", data))
+
+	// Missing paths are null rather than errors
+	assert.Nil(t, eval(t, "order.items[5].sku", data))
+	assert.Nil(t, eval(t, "customer.address.city", data))
+	assert.Nil(t, eval(t, "customer?.address?.city", data))
+	assert.Nil(t, eval(t, "order.items[0].sku.more", data))
+	assert.Equal(t, "guest", eval(t, "customer.name ?? 'guest'", data))
+}
+
+func TestEval_Functions(t *testing.T) {
+	data := map[string]interface{}{
+		"first_name": "asha",
+		"items":      []interface{}{3.0, 9.0, 4.0},
+		"note":       "  hi  ",
+	}
+
+	assert.Equal(t, "ASHA rao", eval(t, "upper(first_name) + ' ' + \"rao\"", data))
+	assert.Equal(t, "Asha Rao", eval(t, "title(first_name + ' rao')", data))
+	assert.Equal(t, "hi", eval(t, "trim(note)", data))
+	assert.Equal(t, 3.0, eval(t, "len(items)", data))
+	assert.Equal(t, 4.0, eval(t, "len(first_name)", data))
+	assert.Equal(t, 9.0, eval(t, "max(items)", data))
+	assert.Equal(t, 2.0, eval(t, "min(4, 2, 7)", data))
+	assert.Equal(t, 3.0, eval(t, "abs(-3)", data))
+	assert.Equal(t, 2.0, eval(t, "floor(2.7)", data))
+	assert.Equal(t, 3.0, eval(t, "ceil(2.1)", data))
+	assert.Equal(t, 12.0, eval(t, "number('12')", data))
+	assert.Equal(t, "guest", eval(t, "coalesce(nickname, '', 'guest')", data))
+	assert.Equal(t, "a-b", eval(t, "replace('a b', ' ', '-')", data))
+	assert.Equal(t, []interface{}{"a", "b"}, eval(t, "split('a,b', ',')", data))
+	assert.Equal(t, "3, 9, 4", eval(t, "join(items)", data))
+	assert.Equal(t, "3/9/4", eval(t, "join(items, '/')", data))
+	assert.Equal(t, "sh", eval(t, "substr(first_name, 1, 2)", data))
+	assert.Equal(t, true, eval(t, "starts_with(first_name, 'as')", data))
+	assert.Equal(t, true, eval(t, "ends_with(first_name, 'ha')", data))
+	assert.Equal(t, `[3,9,4]`, eval(t, "json(items)", data))
+	assert.Equal(t, "many", eval(t, "if(len(items) > 2, 'many', 'few')", data))
+
+	// Only the chosen branch is evaluated
+	assert.Equal(t, "ok", eval(t, "if(true, 'ok', 1 / 0)", data))
+}
+
+func TestEval_Dates(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 31, 22, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	assert.Equal(t, "2024-01-31", eval(t, "today()", nil))
+	assert.Equal(t, "2024-02-01", eval(t, "today('Asia/Kolkata')", nil))
+	assert.Equal(t, "2024-01-31T22:30:00Z", eval(t, "now()", nil))
+	assert.Equal(t, "2024-02-29", eval(t, "add_months(today(), 1)", nil))
+	assert.Equal(t, "2024-02-03", eval(t, "add_days(today(), 3)", nil))
+	assert.Equal(t, "2024-02-01T22:30:00Z", eval(t, "add_days(now(), 1)", nil))
+	assert.Equal(t, 10.0, eval(t, "days_between('2024-01-01', '2024-01-11')", nil))
+	assert.Equal(t, "wednesday", eval(t, "weekday(today())", nil))
+	assert.Equal(t, "2024-01-31", eval(t, "date('2024-01-31T10:00:00+05:30')", nil))
+	assert.Equal(t, "31 Jan 2024, 10:30 PM", eval(t, "format_date(now(), 'DD MMM YYYY, h:mm A')", nil))
+	assert.Equal(t, "Wednesday at 22", eval(t, "format_date(now(), 'dddd [at] HH')", nil))
+	assert.Equal(t, true, eval(t, "add_days(today(), 1) > today()", nil))
+
+	_, err := Eval("today('Mars/Base')", nil)
+	assert.EqualError(t, err, `unknown time zone "Mars/Base"`)
+	_, err = Eval("add_days('soon', 1)", nil)
+	assert.EqualError(t, err, `"soon" is not a date`)
+}
+
+func TestEval_Errors(t *testing.T) {
+	_, err := Eval("total / count", map[string]interface{}{"total": 10.0, "count": 0.0})
+	assert.EqualError(t, err, "division by zero")
+	_, err = Eval("total * 2", map[string]interface{}{"total": "ten"})
+	assert.EqualError(t, err, `"ten" is not a number`)
+	_, err = Eval("-name", map[string]interface{}{"name": "asha"})
+	assert.EqualError(t, err, `cannot negate "asha"`)
+	_, err = Eval("text + text", map[string]interface{}{"text": strings.Repeat("x", maxStringLength)})
+	assert.EqualError(t, err, "result is too long")
+	_, err = Eval("replace(text, 'x', text)", map[string]interface{}{"text": strings.Repeat("x", maxStringLength)})
+	assert.EqualError(t, err, "result is too long")
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := map[string]string{
+		"":            "expression is empty",
+		"1 +":         "unexpected end of expression",
+		"(1 + 2":      `expected ")" at end of expression`,
+		"foo(1)":      `unknown function "foo"`,
+		"round()":     "wrong number of arguments to round",
+		"if(a, b)":    "wrong number of arguments to if",
+		"'open":       "unterminated string",
+		"1 $ 2":       `unexpected character '$'`,
+		"a b":         `unexpected "b"`,
+		"a == b == c": `unexpected "=="`,
+		"a.":          `expected a field name after "."`,
+		"and":         `unexpected "and"`,
+	}
+	for src, want := range tests {
+		_, err := Compile(src)
+		assert.EqualError(t, err, want, src)
+	}
+
+	_, err := Compile(strings.Repeat("x", maxSourceLength+1))
+	assert.EqualError(t, err, "expression is too long")
+	_, err = Compile(strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100))
+	assert.EqualError(t, err, "expression is nested too deeply")
+}
+
+func TestExprTest(t *testing.T) {
+	expr, err := Compile("total / count > 2")
+	require.NoError(t, err)
+	assert.True(t, expr.Test(map[string]interface{}{"total": 10, "count": 2}))
+	assert.False(t, expr.Test(map[string]interface{}{"total": 10, "count": 0})) // Errors do not hold
+	assert.Equal(t, "total / count > 2", expr.String())
+
+	assert.False(t, Test("status ==", map[string]interface{}{}))
+	assert.True(t, Test("vip", map[string]interface{}{"vip": "yes"}))
+}
+
+func TestLookup(t *testing.T) {
+	data := map[string]interface{}{
+		"name":  "Alice",
+		"user":  map[string]interface{}{"address": map[string]interface{}{"city": "NYC"}},
+		"items": []interface{}{"a", "b", "c"},
+		"1":     "positional",
+	}
+
+	assert.Equal(t, "Alice", Lookup(data, "name"))
+	assert.Equal(t, "NYC", Lookup(data, "user.address.city"))
+	assert.Equal(t, "b", Lookup(data, "items[1]"))
+	assert.Nil(t, Lookup(data, "items[5]"))
+	assert.Nil(t, Lookup(data, "missing"))
+	assert.Nil(t, Lookup(nil, "name"))
+	assert.Nil(t, Lookup(data, ""))
+
+	// Only paths are looked up, not other expressions
+	assert.Nil(t, Lookup(data, "1"))
+	assert.Nil(t, Lookup(data, "upper(name)"))
+	assert.Nil(t, Lookup(data, "name + name"))
+}
+
+func TestTruthy(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  bool
+	}{
+		{"nil", nil, false},
+		{"empty string", "", false},
+		{"false string", "false", false},
+		{"zero string", "0", false},
+		{"false bool", false, false},
+		{"zero int", int(0), false},
+		{"zero int64", int64(0), false},
+		{"zero float64", float64(0), false},
+		{"empty slice", []interface{}{}, false},
+		{"empty map slice", []map[string]interface{}{}, false},
+		{"empty map", map[string]interface{}{}, false},
+		{"non-empty string", "hello", true},
+		{"true bool", true, true},
+		{"non-zero int", 42, true},
+		{"non-zero int64", int64(42), true},
+		{"non-zero float64", 3.14, true},
+		{"non-empty slice", []interface{}{"a"}, true},
+		{"non-empty map slice", []map[string]interface{}{{"k": "v"}}, true},
+		{"non-empty map", map[string]interface{}{"k": "v"}, true},
+		{"non-empty JSONB", models.JSONB{"k": "v"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Truthy(tt.value))
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{"string match", "hello", "hello", true},
+		{"string mismatch", "hello", "world", false},
+		{"int match", 42, "42", true},
+		{"int mismatch", 42, "43", false},
+		{"int64 match", int64(100), "100", true},
+		{"float64 whole number", float64(5), "5", true},
+		{"float64 decimal", 3.14, "3.14", true},
+		{"float64 and numeric string", 5.0, "5.00", true},
+		{"strings compare as text", "5", "5.00", false},
+		{"bool true", true, "true", true},
+		{"bool false", false, "false", true},
+		{"nil vs empty", nil, "", true},
+		{"nil vs nil", nil, nil, true},
+		{"nil vs non-empty", nil, "hello", false},
+		{"lists", []interface{}{"a", 1.0}, []interface{}{"a", 1}, true},
+		{"maps", map[string]interface{}{"k": "v"}, map[string]interface{}{"k": "v"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, equal(tt.a, tt.b))
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want int
+	}{
+		{"int less", 5, "10", -1},
+		{"int equal", 10, "10", 0},
+		{"int greater", 15, "10", 1},
+		{"int64 less", int64(5), "10", -1},
+		{"float64 less", 5.5, "10.0", -1},
+		{"float64 equal", 10.0, "10", 0},
+		{"string numbers", "20", "10", 1},
+		{"text", "abc", "abd", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := compare(tt.a, tt.b)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := compare(nil, 10)
+	assert.False(t, ok)
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil", nil, ""},
+		{"string", "hello", "hello"},
+		{"empty string", "", ""},
+		{"int", 42, "42"},
+		{"int64", int64(100), "100"},
+		{"float64 whole", float64(5), "5"},
+		{"float64 decimal", 3.14, "3.14"},
+		{"bool true", true, "true"},
+		{"bool false", false, "false"},
+		{"slice", []interface{}{"a", "b"}, `["a","b"]`},
+		{"map", map[string]interface{}{"k": "v"}, `{"k":"v"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Format(tt.value))
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package exprutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// builtin is a function expressions can call
+type builtin struct {
+	minArgs int
+	maxArgs int // -1 for no limit
+	call    func(args []interface{}) (interface{}, error)
+}
+
+// functions lists the functions expressions can call. if(cond, a, b) is
+// handled by the parser so that only one branch is evaluated.
+var functions = map[string]builtin{
+	"upper":    {1, 1, textFunc(strings.ToUpper)},
+	"lower":    {1, 1, textFunc(strings.ToLower)},
+	"trim":     {1, 1, textFunc(strings.TrimSpace)},
+	"title":    {1, 1, textFunc(titleCase)},
+	"text":     {1, 1, textFunc(func(s string) string { return s })},
+	"len":      {1, 1, length},
+	"number":   {1, 1, toNumberArg},
+	"json":     {1, 1, toJSON},
+	"round":    {1, 2, round},
+	"floor":    {1, 1, mathFunc(math.Floor)},
+	"ceil":     {1, 1, mathFunc(math.Ceil)},
+	"abs":      {1, 1, mathFunc(math.Abs)},
+	"min":      {1, -1, extreme(-1)},
+	"max":      {1, -1, extreme(1)},
+	"coalesce": {1, -1, coalesce},
+	"contains": {2, 2, func(args []interface{}) (interface{}, error) { return in(args[1], args[0]), nil }},
+	"starts_with": {2, 2, func(args []interface{}) (interface{}, error) {
+		return strings.HasPrefix(Format(args[0]), Format(args[1])), nil
+	}},
+	"ends_with": {2, 2, func(args []interface{}) (interface{}, error) {
+		return strings.HasSuffix(Format(args[0]), Format(args[1])), nil
+	}},
+	"replace":      {3, 3, replace},
+	"split":        {2, 2, split},
+	"join":         {1, 2, join},
+	"substr":       {2, 3, substr},
+	"today":        {0, 1, today},
+	"now":          {0, 1, current},
+	"date":         {1, 1, toDate},
+	"add_days":     {2, 2, addDays},
+	"add_months":   {2, 2, addMonths},
+	"days_between": {2, 2, daysBetween},
+	"weekday":      {1, 1, weekday},
+	"format_date":  {2, 2, formatDate},
+}
+
+func textFunc(fn func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		return fn(Format(args[0])), nil
+	}
+}
+
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			start = true
+			continue
+		}
+		if start {
+			runes[i] = unicode.ToUpper(r)
+		}
+		start = false
+	}
+	return string(runes)
+}
+
+func length(args []interface{}) (interface{}, error) {
+	if list, ok := toList(args[0]); ok {
+		return float64(len(list)), nil
+	}
+	if m, ok := args[0].(map[string]interface{}); ok {
+		return float64(len(m)), nil
+	}
+	return float64(len([]rune(Format(args[0])))), nil
+}
+
+// numberArg converts an argument to a number
+func numberArg(v interface{}) (float64, error) {
+	n, ok := toNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("%q is not a number", Format(v))
+	}
+	return n, nil
+}
+
+func toNumberArg(args []interface{}) (interface{}, error) {
+	n, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func toJSON(args []interface{}) (interface{}, error) {
+	data, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// round rounds to a whole number, or to the given number of decimals
+func round(args []interface{}) (interface{}, error) {
+	n, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 1 {
+		return math.Round(n), nil
+	}
+	places, err := numberArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow(10, math.Round(places))
+	return math.Round(n*scale) / scale, nil
+}
+
+func mathFunc(fn func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		n, err := numberArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(n), nil
+	}
+}
+
+// extreme returns min (sign -1) or max (sign 1) of its arguments, or of the
+// items of a single list argument
+func extreme(sign float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) == 1 {
+			if list, ok := toList(args[0]); ok {
+				if len(list) == 0 {
+					return nil, nil
+				}
+				args = list
+			}
+		}
+		best, err := numberArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args[1:] {
+			n, err := numberArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			if (n-best)*sign > 0 {
+				best = n
+			}
+		}
+		return best, nil
+	}
+}
+
+// coalesce returns the first argument that is not null or empty
+func coalesce(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if !isEmpty(arg) {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+func replace(args []interface{}) (interface{}, error) {
+	s, old := Format(args[0]), Format(args[1])
+	if old == "" {
+		return s, nil
+	}
+	// Reject long results before building them, as each match can grow s by
+	// up to maxStringLength
+	repl := Format(args[2])
+	if n := len(s) + strings.Count(s, old)*(len(repl)-len(old)); n > maxStringLength {
+		return nil, errTooLong
+	}
+	return strings.ReplaceAll(s, old, repl), nil
+}
+
+func split(args []interface{}) (interface{}, error) {
+	s := Format(args[0])
+	if s == "" {
+		return []interface{}{}, nil
+	}
+	parts := strings.Split(s, Format(args[1]))
+	list := make([]interface{}, len(parts))
+	for i, part := range parts {
+		list[i] = part
+	}
+	return list, nil
+}
+
+// join joins the items of a list, with ", " unless a separator is given
+func join(args []interface{}) (interface{}, error) {
+	list, ok := toList(args[0])
+	if !ok {
+		return Format(args[0]), nil
+	}
+	sep := ", "
+	if len(args) == 2 {
+		sep = Format(args[1])
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = Format(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// substr returns the characters from start, up to length when given
+func substr(args []interface{}) (interface{}, error) {
+	runes := []rune(Format(args[0]))
+	start, err := numberArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	from := clamp(int(start), len(runes))
+	to := len(runes)
+	if len(args) == 3 {
+		n, err := numberArg(args[2])
+		if err != nil {
+			return nil, err
+		}
+		to = clamp(from+int(math.Max(n, 0)), len(runes))
+	}
+	return string(runes[from:to]), nil
+}
+
+func clamp(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return int(math.Max(0, math.Min(float64(i), float64(n))))
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = time.RFC3339
+)
+
+// now returns the current time; tests replace it
+var now = time.Now
+
+// location returns the time zone named by an optional argument, UTC when
+// there is none
+func location(args []interface{}) (*time.Location, error) {
+	if len(args) == 0 || isEmpty(args[0]) {
+		return time.UTC, nil
+	}
+	name := Format(args[0])
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+func today(args []interface{}) (interface{}, error) {
+	loc, err := location(args)
+	if err != nil {
+		return nil, err
+	}
+	return now().In(loc).Format(dateLayout), nil
+}
+
+func current(args []interface{}) (interface{}, error) {
+	loc, err := location(args)
+	if err != nil {
+		return nil, err
+	}
+	return now().In(loc).Format(dateTimeLayout), nil
+}
+
+// parseDate reads a date ("2024-03-01") or a date and time in RFC 3339
+// form, reporting which it was
+func parseDate(v interface{}) (t time.Time, hasTime bool, err error) {
+	if t, ok := v.(time.Time); ok {
+		return t, true, nil
+	}
+	s := strings.TrimSpace(Format(v))
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(dateTimeLayout, s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date", s)
+}
+
+// formatDateValue formats a date the way it was given
+func formatDateValue(t time.Time, hasTime bool) string {
+	if hasTime {
+		return t.Format(dateTimeLayout)
+	}
+	return t.Format(dateLayout)
+}
+
+func toDate(args []interface{}) (interface{}, error) {
+	t, _, err := parseDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(dateLayout), nil
+}
+
+func addDays(args []interface{}) (interface{}, error) {
+	t, hasTime, err := parseDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := numberArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return formatDateValue(t.AddDate(0, 0, int(n)), hasTime), nil
+}
+
+// addMonths adds months, keeping to the last day of shorter months
+func addMonths(args []interface{}) (interface{}, error) {
+	t, hasTime, err := parseDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := numberArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	first = first.AddDate(0, int(n), 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return formatDateValue(first.AddDate(0, 0, day-1), hasTime), nil
+}
+
+// daysBetween returns the whole days from the first date to the second
+func daysBetween(args []interface{}) (interface{}, error) {
+	from, _, err := parseDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	to, _, err := parseDate(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return math.Trunc(to.Sub(from).Hours() / 24), nil
+}
+
+func weekday(args []interface{}) (interface{}, error) {
+	t, _, err := parseDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(t.Weekday().String()), nil
+}
+
+// dateTokens are the parts of a format_date layout, longer ones first
+var dateTokens = []string{
+	"YYYY", "YY", "MMMM", "MMM", "MM", "M", "DD", "D", "dddd", "ddd",
+	"HH", "H", "hh", "h", "mm", "ss", "A",
+}
+
+// formatDate formats a date with a layout such as "DD MMM YYYY, h:mm A".
+// Text in square brackets is copied as it is.
+func formatDate(args []interface{}) (interface{}, error) {
+	t, _, err := parseDate(args[0])
+	if err != nil {
+		return nil, err
+	}
+	layout := Format(args[1])
+
+	var sb strings.Builder
+	for i := 0; i < len(layout); {
+		if layout[i] == '[' {
+			if end := strings.IndexByte(layout[i:], ']'); end > 0 {
+				sb.WriteString(layout[i+1 : i+end])
+				i += end + 1
+				continue
+			}
+		}
+		matched := ""
+		for _, tok := range dateTokens {
+			if strings.HasPrefix(layout[i:], tok) {
+				matched = tok
+				break
+			}
+		}
+		if matched == "" {
+			sb.WriteByte(layout[i])
+			i++
+			continue
+		}
+		sb.WriteString(dateToken(t, matched))
+		i += len(matched)
+	}
+	return checkLength(sb.String())
+}
+
+func dateToken(t time.Time, tok string) string {
+	hour12 := t.Hour() % 12
+	if hour12 == 0 {
+		hour12 = 12
+	}
+	switch tok {
+	case "YYYY":
+		return fmt.Sprintf("%04d", t.Year())
+	case "YY":
+		return fmt.Sprintf("%02d", t.Year()%100)
+	case "MMMM":
+		return t.Month().String()
+	case "MMM":
+		return t.Month().String()[:3]
+	case "MM":
+		return fmt.Sprintf("%02d", int(t.Month()))
+	case "M":
+		return fmt.Sprint(int(t.Month()))
+	case "DD":
+		return fmt.Sprintf("%02d", t.Day())
+	case "D":
+		return fmt.Sprint(t.Day())
+	case "dddd":
+		return t.Weekday().String()
+	case "ddd":
+		return t.Weekday().String()[:3]
+	case "HH":
+		return fmt.Sprintf("%02d", t.Hour())
+	case "H":
+		return fmt.Sprint(t.Hour())
+	case "hh":
+		return fmt.Sprintf("%02d", hour12)
+	case "h":
+		return fmt.Sprint(hour12)
+	case "mm":
+		return fmt.Sprintf("%02d", t.Minute())
+	case "ss":
+		return fmt.Sprintf("%02d", t.Second())
+	case "A":
+		if t.Hour() < 12 {
+			return "AM"
+		}
+		return "PM"
+	}
+	return tok
+}
@@ -0,0 +1,660 @@
+package exprutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+}
+
+// operators lists the operator tokens, longer ones first
+var operators = []string{
+	"?.", "??", "==", "!=", ">=", "<=", "&&", "||",
+	"+", "-", "*", "/", "%", "(", ")", "[", "]", ",", ".", ">", "<", "!",
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+
+		case isDigit(ch) || ch == '.' && i+1 < len(src) && isDigit(src[i+1]) && !endsOperand(tokens):
+			// After a "." the digits are a list index, as in items.0.name
+			field := len(tokens) > 0 && tokens[len(tokens)-1].text == "." && tokens[len(tokens)-1].kind == tokenOperator
+			start := i
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if !field && i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) || start == i {
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], value: n})
+
+		case ch == '\'' || ch == '"':
+			var sb strings.Builder
+			closed := false
+			j := i + 1
+			for j < len(src) {
+				c := src[j]
+				if c == '\\' && j+1 < len(src) {
+					switch src[j+1] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[j+1])
+					}
+					j += 2
+					continue
+				}
+				if c == ch {
+					closed = true
+					break
+				}
+				sb.WriteByte(c)
+				j++
+			}
+			if !closed {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[i : j+1], value: sb.String()})
+			i = j + 1
+
+		case isIdentStart(ch):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i]})
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", ch)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// endsOperand reports whether the last token ends a value, so a following
+// ".5" is a field access rather than a number
+func endsOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind != tokenOperator || last.text == ")" || last.text == "]"
+}
+
+// keywords are identifiers with a meaning of their own, in any case
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "contains": true,
+	"true": true, "false": true, "null": true,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peekOperator(ops ...string) string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator {
+		for _, op := range ops {
+			if p.tokens[p.pos].text == op {
+				return op
+			}
+		}
+	}
+	return ""
+}
+
+func (p *parser) isKeyword(offset int, word string) bool {
+	i := p.pos + offset
+	return i < len(p.tokens) && p.tokens[i].kind == tokenIdent && strings.EqualFold(p.tokens[i].text, word)
+}
+
+func (p *parser) expectOperator(op string) error {
+	if p.peekOperator(op) == "" {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q, got %q", op, p.tokens[p.pos].text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errors.New("expression is nested too deeply")
+	}
+	return nil
+}
+
+// parseOr parses a full expression; "or" and "||" bind weakest
+func (p *parser) parseOr() (evalFunc, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("||") != "" || p.isKeyword(0, "or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, true)
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (evalFunc, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("&&") != "" || p.isKeyword(0, "and") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, false)
+	}
+	return left, nil
+}
+
+// logical joins two conditions with or (stopping at the first true one) or
+// with and (stopping at the first false one)
+func logical(left, right evalFunc, or bool) evalFunc {
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(l) == or {
+			return or, nil
+		}
+		r, err := right(data)
+		if err != nil {
+			return nil, err
+		}
+		return Truthy(r), nil
+	}
+}
+
+func (p *parser) parseNot() (evalFunc, error) {
+	if p.peekOperator("!") != "" || p.isKeyword(0, "not") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(data map[string]interface{}) (interface{}, error) {
+			v, err := operand(data)
+			if err != nil {
+				return nil, err
+			}
+			return !Truthy(v), nil
+		}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (evalFunc, error) {
+	left, err := p.parseCoalesce()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peekOperator("==", "!=", ">=", "<=", ">", "<")
+	switch {
+	case op != "":
+		p.pos++
+	case p.isKeyword(0, "in"):
+		op = "in"
+		p.pos++
+	case p.isKeyword(0, "not") && p.isKeyword(1, "in"):
+		op = "not in"
+		p.pos += 2
+	case p.isKeyword(0, "contains"):
+		op = "contains"
+		p.pos++
+	default:
+		return left, nil
+	}
+
+	right, err := p.parseCoalesce()
+	if err != nil {
+		return nil, err
+	}
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(data)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "==":
+			return equal(l, r), nil
+		case "!=":
+			return !equal(l, r), nil
+		case "in":
+			return in(l, r), nil
+		case "not in":
+			return !in(l, r), nil
+		case "contains":
+			return in(r, l), nil
+		}
+		c, ok := compare(l, r)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case ">":
+			return c > 0, nil
+		case ">=":
+			return c >= 0, nil
+		case "<":
+			return c < 0, nil
+		default:
+			return c <= 0, nil
+		}
+	}, nil
+}
+
+// parseCoalesce parses a ?? b, which is b when a is null or empty
+func (p *parser) parseCoalesce() (evalFunc, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("??") != "" {
+		p.pos++
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(data map[string]interface{}) (interface{}, error) {
+			v, err := l(data)
+			if err != nil {
+				return nil, err
+			}
+			if !isEmpty(v) {
+				return v, nil
+			}
+			return right(data)
+		}
+	}
+	return left, nil
+}
+
+// parseSum parses + and - which bind weaker than * / %
+func (p *parser) parseSum() (evalFunc, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peekOperator("+", "-")
+		if op == "" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic(op, left, right)
+	}
+}
+
+func (p *parser) parseProduct() (evalFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peekOperator("*", "/", "%")
+		if op == "" {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic(op, left, right)
+	}
+}
+
+// arithmetic applies an arithmetic operator. + adds when both sides are
+// numbers, including numeric strings from user input, and concatenates
+// otherwise.
+func arithmetic(op string, left, right evalFunc) evalFunc {
+	return func(data map[string]interface{}) (interface{}, error) {
+		l, err := left(data)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(data)
+		if err != nil {
+			return nil, err
+		}
+
+		ln, lok := toNumber(l)
+		rn, rok := toNumber(r)
+		if op == "+" && (!lok || !rok) {
+			return checkLength(Format(l) + Format(r))
+		}
+		if !lok {
+			return nil, fmt.Errorf("%q is not a number", Format(l))
+		}
+		if !rok {
+			return nil, fmt.Errorf("%q is not a number", Format(r))
+		}
+
+		switch op {
+		case "+":
+			return ln + rn, nil
+		case "-":
+			return ln - rn, nil
+		case "*":
+			return ln * rn, nil
+		case "/":
+			if rn == 0 {
+				return nil, errors.New("division by zero")
+			}
+			return ln / rn, nil
+		default: // %
+			if rn == 0 {
+				return nil, errors.New("division by zero")
+			}
+			return math.Mod(ln, rn), nil
+		}
+	}
+}
+
+func (p *parser) parseUnary() (evalFunc, error) {
+	if p.peekOperator("-") != "" {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(data map[string]interface{}) (interface{}, error) {
+			v, err := operand(data)
+			if err != nil {
+				return nil, err
+			}
+			n, ok := toNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot negate %q", Format(v))
+			}
+			return -n, nil
+		}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses field access (a.b, a?.b) and indexing (a[0], a['b'])
+func (p *parser) parsePostfix() (evalFunc, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peekOperator(".", "?.", "[") {
+		case ".", "?.":
+			p.pos++
+			if p.pos >= len(p.tokens) || (p.tokens[p.pos].kind != tokenIdent && p.tokens[p.pos].kind != tokenNumber) {
+				return nil, errors.New("expected a field name after \".\"")
+			}
+			key := p.tokens[p.pos].text
+			p.pos++
+			base := expr
+			expr = func(data map[string]interface{}) (interface{}, error) {
+				v, err := base(data)
+				if err != nil {
+					return nil, err
+				}
+				return member(v, key), nil
+			}
+
+		case "[":
+			p.pos++
+			idx, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOperator("]"); err != nil {
+				return nil, err
+			}
+			base := expr
+			expr = func(data map[string]interface{}) (interface{}, error) {
+				v, err := base(data)
+				if err != nil {
+					return nil, err
+				}
+				i, err := idx(data)
+				if err != nil {
+					return nil, err
+				}
+				return index(v, i), nil
+			}
+
+		default:
+			return expr, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (evalFunc, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenNumber, tokenString:
+		value := tok.value
+		return func(map[string]interface{}) (interface{}, error) { return value, nil }, nil
+
+	case tokenIdent:
+		if p.peekOperator("(") != "" {
+			return p.parseCall(tok.text)
+		}
+		switch strings.ToLower(tok.text) {
+		case "true", "false":
+			value := strings.EqualFold(tok.text, "true")
+			return func(map[string]interface{}) (interface{}, error) { return value, nil }, nil
+		case "null":
+			return func(map[string]interface{}) (interface{}, error) { return nil, nil }, nil
+		}
+		if keywords[strings.ToLower(tok.text)] {
+			return nil, fmt.Errorf("unexpected %q", tok.text)
+		}
+		name := tok.text
+		return func(data map[string]interface{}) (interface{}, error) {
+			return data[name], nil
+		}, nil
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return func(data map[string]interface{}) (interface{}, error) {
+				return evalAll(items, data)
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+// parseList parses comma separated expressions up to the closing operator
+func (p *parser) parseList(closing string) ([]evalFunc, error) {
+	var items []evalFunc
+	if p.peekOperator(closing) == "" {
+		for {
+			item, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if p.peekOperator(",") == "" {
+				break
+			}
+			p.pos++
+		}
+	}
+	if err := p.expectOperator(closing); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func evalAll(items []evalFunc, data map[string]interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		v, err := item(data)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (p *parser) parseCall(name string) (evalFunc, error) {
+	p.pos++ // (
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+
+	// if(condition, then, else) only evaluates the branch it returns
+	if name == "if" {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("wrong number of arguments to %s", name)
+		}
+		return func(data map[string]interface{}) (interface{}, error) {
+			cond, err := args[0](data)
+			if err != nil {
+				return nil, err
+			}
+			if Truthy(cond) {
+				return args[1](data)
+			}
+			return args[2](data)
+		}, nil
+	}
+
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) < fn.minArgs || fn.maxArgs >= 0 && len(args) > fn.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments to %s", name)
+	}
+	return func(data map[string]interface{}) (interface{}, error) {
+		values, err := evalAll(args, data)
+		if err != nil {
+			return nil, err
+		}
+		v, err := fn.call(values)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := v.(string); ok {
+			return checkLength(s)
+		}
+		return v, nil
+	}, nil
+}
+
+// errTooLong is returned when an expression would build a string longer
+// than maxStringLength
+var errTooLong = errors.New("result is too long")
+
+func checkLength(s string) (interface{}, error) {
+	if len(s) > maxStringLength {
+		return nil, errTooLong
+	}
+	return s, nil
+}
@@ -0,0 +1,381 @@
+package exprutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxLoopIterations caps the items a {{for}} block renders
+	maxLoopIterations = 50
+	// maxOutputLength caps the length of a rendered template
+	maxOutputLength = 64 * 1024
+	// maxRenderPasses caps the {{for}} passes across a whole render, so
+	// nested loops stay cheap even when they render nothing
+	maxRenderPasses = 10000
+)
+
+var (
+	forTagPattern      = regexp.MustCompile(`^for\s+([a-zA-Z_][a-zA-Z0-9_]*)\s+in\s+(.+)$`)
+	placeholderPattern = regexp.MustCompile(`^\d+$`)
+)
+
+type tagKind int
+
+const (
+	tagText tagKind = iota // literal text, including tags left as they are
+	tagExpr
+	tagIf
+	tagElif
+	tagElse
+	tagEndif
+	tagFor
+	tagEndfor
+)
+
+// segment is a piece of template source: text or a {{tag}}
+type segment struct {
+	kind    tagKind
+	raw     string // the source, with braces for tags
+	arg     string // the expression of expr, if, elif and for tags
+	loopVar string // the item variable of a for tag
+	expr    *Expr  // the compiled arg, nil when it is invalid
+}
+
+// node is a part of a parsed template
+type node struct {
+	kind     tagKind // tagText, tagExpr, tagIf or tagFor
+	text     string
+	expr     *Expr // nil when the expression is invalid
+	branches []branch
+	loopVar  string
+	body     []node
+}
+
+// branch is an {{if}}, {{elif}} or {{else}} with the nodes it renders
+type branch struct {
+	cond   *Expr // nil when the condition is invalid
+	isElse bool
+	body   []node
+}
+
+// Template is a parsed message template. {{expression}} tags are replaced
+// with their values; {{if}}, {{elif}}, {{else}} and {{endif}} choose text;
+// {{for item in list}} and {{endfor}} repeat text for each item, with
+// item_index counting from 0. Numeric tags such as {{1}} are WhatsApp
+// template placeholders and are left as they are.
+type Template struct {
+	nodes []node
+}
+
+// ParseTemplate parses a template, reporting invalid expressions and
+// unbalanced blocks
+func ParseTemplate(src string) (*Template, error) {
+	return parseTemplate(src, true)
+}
+
+// Render renders a template leniently: invalid tags are left as they are
+// and expressions that fail to evaluate render as empty text
+func Render(src string, data map[string]interface{}) string {
+	if !strings.Contains(src, "{{") {
+		return src
+	}
+	t, _ := parseTemplate(src, false)
+	return t.Render(data)
+}
+
+// Render renders the template with data
+func (t *Template) Render(data map[string]interface{}) string {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	out := &output{}
+	renderNodes(out, t.nodes, data)
+	return out.sb.String()
+}
+
+func parseTemplate(src string, strict bool) (*Template, error) {
+	segments, err := splitTemplate(src, strict)
+	if err != nil {
+		return nil, err
+	}
+	if err := balance(segments, strict); err != nil {
+		return nil, err
+	}
+	nodes, _ := buildNodes(segments, 0)
+	return &Template{nodes: nodes}, nil
+}
+
+// splitTemplate splits a template into text and tags
+func splitTemplate(src string, strict bool) ([]segment, error) {
+	var segments []segment
+	for src != "" {
+		start := strings.Index(src, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(src[start+2:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+
+		if start > 0 {
+			segments = append(segments, segment{kind: tagText, raw: src[:start]})
+		}
+		seg, err := classifyTag(src[start:end+2], strings.TrimSpace(src[start+2:end]))
+		if err != nil {
+			if strict {
+				return nil, err
+			}
+			// An invalid expression is left as it is, while a block with an
+			// invalid condition keeps its shape and never renders
+			if seg.kind == tagExpr {
+				seg.kind = tagText
+			}
+		}
+		segments = append(segments, seg)
+		src = src[end+2:]
+	}
+	if src != "" {
+		segments = append(segments, segment{kind: tagText, raw: src})
+	}
+	return segments, nil
+}
+
+// classifyTag works out what a {{tag}} is and checks its expression
+func classifyTag(raw, content string) (segment, error) {
+	seg := segment{raw: raw}
+	keyword, rest := content, ""
+	if i := strings.IndexAny(content, " \t\n"); i >= 0 {
+		keyword, rest = content[:i], strings.TrimSpace(content[i+1:])
+	}
+
+	switch {
+	case content == "" || placeholderPattern.MatchString(content):
+		seg.kind = tagText
+		return seg, nil
+	case keyword == "if" && rest != "":
+		seg.kind, seg.arg = tagIf, rest
+	case keyword == "elif" && rest != "":
+		seg.kind, seg.arg = tagElif, rest
+	case keyword == "else" && strings.HasPrefix(rest, "if "):
+		seg.kind, seg.arg = tagElif, strings.TrimSpace(rest[3:])
+	case content == "else":
+		seg.kind = tagElse
+		return seg, nil
+	case content == "endif":
+		seg.kind = tagEndif
+		return seg, nil
+	case content == "endfor":
+		seg.kind = tagEndfor
+		return seg, nil
+	case keyword == "for":
+		m := forTagPattern.FindStringSubmatch(content)
+		if m == nil {
+			return seg, fmt.Errorf("%s: expected {{for <name> in <list>}}", raw)
+		}
+		seg.kind, seg.loopVar, seg.arg = tagFor, m[1], m[2]
+	default:
+		seg.kind, seg.arg = tagExpr, content
+	}
+
+	expr, err := Compile(seg.arg)
+	if err != nil {
+		return seg, fmt.Errorf("%s: %w", raw, err)
+	}
+	seg.expr = expr
+	return seg, nil
+}
+
+// balance checks that blocks open and close in order. Leniently, tags that
+// do not fit are turned into text.
+func balance(segments []segment, strict bool) error {
+	type open struct {
+		index   int
+		hasElse bool
+	}
+	var stack []open
+	top := func(kind tagKind) bool {
+		return len(stack) > 0 && segments[stack[len(stack)-1].index].kind == kind
+	}
+
+	for i := range segments {
+		seg := &segments[i]
+		ok := true
+		switch seg.kind {
+		case tagIf, tagFor:
+			stack = append(stack, open{index: i})
+		case tagElif:
+			ok = top(tagIf) && !stack[len(stack)-1].hasElse
+		case tagElse:
+			ok = top(tagIf) && !stack[len(stack)-1].hasElse
+			if ok {
+				stack[len(stack)-1].hasElse = true
+			}
+		case tagEndif:
+			ok = top(tagIf)
+			if ok {
+				stack = stack[:len(stack)-1]
+			}
+		case tagEndfor:
+			ok = top(tagFor)
+			if ok {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if !ok {
+			if strict {
+				return fmt.Errorf("unexpected %s", seg.raw)
+			}
+			seg.kind = tagText
+		}
+	}
+
+	for _, o := range stack {
+		seg := &segments[o.index]
+		if strict {
+			closing := "{{endif}}"
+			if seg.kind == tagFor {
+				closing = "{{endfor}}"
+			}
+			return fmt.Errorf("%s is missing %s", seg.raw, closing)
+		}
+		// The unclosed block's inner tags stay balanced on their own, so
+		// only its else and elif tags need to become text
+		seg.kind = tagText
+	}
+	if !strict {
+		demoteOrphans(segments)
+	}
+	return nil
+}
+
+// demoteOrphans turns else and elif tags whose if became text into text
+func demoteOrphans(segments []segment) {
+	var stack []tagKind
+	for i := range segments {
+		seg := &segments[i]
+		switch seg.kind {
+		case tagIf, tagFor:
+			stack = append(stack, seg.kind)
+		case tagEndif, tagEndfor:
+			stack = stack[:len(stack)-1]
+		case tagElif, tagElse:
+			if len(stack) == 0 || stack[len(stack)-1] != tagIf {
+				seg.kind = tagText
+			}
+		}
+	}
+}
+
+// buildNodes turns balanced segments into nodes, stopping at the tag that
+// ends the enclosing block
+func buildNodes(segments []segment, i int) ([]node, int) {
+	var nodes []node
+	for i < len(segments) {
+		seg := segments[i]
+		switch seg.kind {
+		case tagText:
+			nodes = append(nodes, node{kind: tagText, text: seg.raw})
+			i++
+		case tagExpr:
+			nodes = append(nodes, node{kind: tagExpr, expr: seg.expr})
+			i++
+		case tagIf:
+			n := node{kind: tagIf}
+			for {
+				b := branch{cond: seg.expr, isElse: seg.kind == tagElse}
+				b.body, i = buildNodes(segments, i+1)
+				n.branches = append(n.branches, b)
+				seg = segments[i]
+				if seg.kind == tagEndif {
+					break
+				}
+			}
+			nodes = append(nodes, n)
+			i++
+		case tagFor:
+			n := node{kind: tagFor, loopVar: seg.loopVar}
+			n.expr = seg.expr
+			n.body, i = buildNodes(segments, i+1)
+			nodes = append(nodes, n)
+			i++
+		default: // the elif, else, endif or endfor ending this block
+			return nodes, i
+		}
+	}
+	return nodes, i
+}
+
+// output collects rendered text up to maxOutputLength
+type output struct {
+	sb     strings.Builder
+	passes int
+	full   bool
+}
+
+func (o *output) write(s string) {
+	if o.full {
+		return
+	}
+	if room := maxOutputLength - o.sb.Len(); len(s) > room {
+		// Cut before the rune the limit falls in
+		for room > 0 && !utf8.RuneStart(s[room]) {
+			room--
+		}
+		s = s[:room]
+		o.full = true
+	}
+	o.sb.WriteString(s)
+}
+
+func renderNodes(out *output, nodes []node, data map[string]interface{}) {
+	for _, n := range nodes {
+		if out.full {
+			return
+		}
+		switch n.kind {
+		case tagText:
+			out.write(n.text)
+		case tagExpr:
+			if v, err := n.expr.Eval(data); err == nil {
+				out.write(Format(v))
+			}
+		case tagIf:
+			for _, b := range n.branches {
+				if b.isElse || b.cond != nil && b.cond.Test(data) {
+					renderNodes(out, b.body, data)
+					break
+				}
+			}
+		case tagFor:
+			if n.expr == nil {
+				continue
+			}
+			v, err := n.expr.Eval(data)
+			if err != nil {
+				continue
+			}
+			items, _ := toList(v)
+			if len(items) > maxLoopIterations {
+				items = items[:maxLoopIterations]
+			}
+			for i, item := range items {
+				if out.passes++; out.passes > maxRenderPasses {
+					out.full = true
+					return
+				}
+				loopData := make(map[string]interface{}, len(data)+2)
+				for k, v := range data {
+					loopData[k] = v
+				}
+				loopData[n.loopVar] = item
+				loopData[n.loopVar+"_index"] = i
+				renderNodes(out, n.body, loopData)
+			}
+		}
+	}
+}
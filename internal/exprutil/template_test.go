@@ -0,0 +1,109 @@
+package exprutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]interface{}{
+		"name":  "Alice",
+		"total": 1250.5,
+		"items": []interface{}{
+			map[string]interface{}{"name": "Tea", "qty": 2},
+			map[string]interface{}{"name": "Cake", "qty": 1},
+		},
+		"tier": "gold",
+	}
+
+	tests := map[string]string{
+		"Hello {{name}}":                                                                    "Hello Alice",
+		"Hello {{ upper(name) }}":                                                           "Hello ALICE",
+		"Total: {{round(total * 1.18, 2)}}":                                                 "Total: 1475.59",
+		"{{len(items)}} items":                                                              "2 items",
+		"{{nickname ?? name}}":                                                              "Alice",
+		"{{if(tier == 'gold', 'Welcome back', 'Hi')}}":                                      "Welcome back",
+		"{{if total > 1000}}Free shipping{{endif}}":                                         "Free shipping",
+		"{{if tier == 'silver'}}S{{elif tier == 'gold'}}G{{else}}B{{endif}}":                "G",
+		"{{if tier == 'silver'}}S{{else if tier == 'bronze'}}B{{else}}-{{endif}}":           "-",
+		"{{for item in items}}{{item_index + 1}}. {{item.qty}} x {{item.name}}\n{{endfor}}": "1. 2 x Tea\n2. 1 x Cake\n",
+		"{{for item in items}}{{if item.qty > 1}}{{item.name}}{{endif}}{{endfor}}":          "Tea",
+		"{{for item in items}}{{for c in split(item.name, '')}}{{c}}.{{endfor}} {{endfor}}": "T.e.a. C.a.k.e. ",
+	}
+	for src, want := range tests {
+		assert.Equal(t, want, Render(src, data), src)
+	}
+}
+
+func TestRender_Lenient(t *testing.T) {
+	data := map[string]interface{}{"name": "Alice", "n": 0}
+
+	// Invalid tags, stray block tags and unclosed braces are left as they are
+	assert.Equal(t, "Hi {{name +}}", Render("Hi {{name +}}", data))
+	assert.Equal(t, "Hi Alice{{endif}}", Render("Hi {{name}}{{endif}}", data))
+	assert.Equal(t, "{{if name}}Alice", Render("{{if name}}{{name}}", data))
+	assert.Equal(t, "Hi {{name", Render("Hi {{name", data))
+	assert.Equal(t, "{{}}", Render("{{}}", data))
+
+	// WhatsApp template placeholders are not expressions
+	assert.Equal(t, "Hello {{1}}", Render("Hello {{1}}", data))
+
+	// Blocks with an invalid condition render nothing
+	assert.Equal(t, "", Render("{{if name ==}}x{{endif}}", data))
+
+	// Evaluation errors render as empty text
+	assert.Equal(t, "[]", Render("[{{10 / n}}]", data))
+	assert.Equal(t, "", Render("", nil))
+	assert.Equal(t, "Hello ", Render("Hello {{name}}", nil))
+}
+
+func TestRender_Limits(t *testing.T) {
+	items := make([]interface{}, 100)
+	for i := range items {
+		items[i] = i
+	}
+	out := Render("{{for i in items}}x{{endfor}}", map[string]interface{}{"items": items})
+	assert.Equal(t, strings.Repeat("x", maxLoopIterations), out)
+
+	// Nested loops stop after maxRenderPasses
+	src := strings.Repeat("{{for i in items}}", 4) + "{{i}}" + strings.Repeat("{{endfor}}", 4)
+	out = Render(src, map[string]interface{}{"items": items})
+	assert.LessOrEqual(t, len(out), maxRenderPasses*2)
+
+	// Output is cut at maxOutputLength without splitting a character
+	big := strings.Repeat("é", maxOutputLength/2)
+	out = Render("{{for i in items}}{{big}}{{endfor}}", map[string]interface{}{"items": items, "big": big[:maxOutputLength-2]})
+	assert.LessOrEqual(t, len(out), maxOutputLength)
+	assert.True(t, strings.HasSuffix(out, "é"))
+}
+
+func TestParseTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate("Hi {{name}}{{if vip}} ⭐{{endif}}")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi Asha ⭐", tmpl.Render(map[string]interface{}{"name": "Asha", "vip": true}))
+	assert.Equal(t, "Hi ", tmpl.Render(nil))
+
+	tests := map[string]string{
+		"Hi {{name +}}":                       "{{name +}}: unexpected end of expression",
+		"{{if vip}}x":                         "{{if vip}} is missing {{endif}}",
+		"{{for i in items}}x":                 "{{for i in items}} is missing {{endfor}}",
+		"x{{endif}}":                          "unexpected {{endif}}",
+		"{{else}}":                            "unexpected {{else}}",
+		"{{if a}}{{else}}{{elif b}}{{endif}}": "unexpected {{elif b}}",
+		"{{for i in items}}{{endif}}":         "unexpected {{endif}}",
+		"{{for items}}{{endfor}}":             "{{for items}}: expected {{for <name> in <list>}}",
+		"{{if a ==}}{{endif}}":                "{{if a ==}}: unexpected end of expression",
+	}
+	for src, want := range tests {
+		_, err := ParseTemplate(src)
+		assert.EqualError(t, err, want, src)
+	}
+
+	for _, src := range []string{"", "plain", "Dear {{1}}", "{{ name }}", "{{for i in [1, 2]}}{{i}}{{endfor}}"} {
+		_, err := ParseTemplate(src)
+		assert.NoError(t, err, src)
+	}
+}
@@ -0,0 +1,288 @@
+package exprutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Truthy reports whether a value counts as true in a condition. Null, false,
+// zero, empty strings, "false", "0" and empty lists and maps do not.
+func Truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false" && v != "0"
+	}
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	}
+	return true
+}
+
+// Format converts a value to text. Null is empty, whole numbers have no
+// decimals, and lists and maps are JSON.
+func Format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatNumber(v)
+	}
+	if n, ok := number(v); ok {
+		return formatNumber(n)
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func formatNumber(n float64) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// number returns the value of a number of any Go numeric type
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toNumber returns the value of a number or a numeric string such as a
+// user's reply
+func toNumber(v interface{}) (float64, bool) {
+	if n, ok := number(v); ok {
+		return n, true
+	}
+	if s, ok := v.(string); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// isEmpty reports whether a value is null or an empty string
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && s == ""
+}
+
+// equal compares two values. Null equals null and the empty string; numbers
+// equal numeric strings with the same value; everything else compares as
+// text.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return isEmpty(a) && isEmpty(b)
+	}
+	if _, ok := number(a); ok {
+		if x, ok := toNumber(b); ok {
+			y, _ := number(a)
+			return x == y
+		}
+	}
+	if _, ok := number(b); ok {
+		if x, ok := toNumber(a); ok {
+			y, _ := number(b)
+			return x == y
+		}
+	}
+	if la, ok := toList(a); ok {
+		lb, ok := toList(b)
+		if !ok || len(la) != len(lb) {
+			return false
+		}
+		for i := range la {
+			if !equal(la[i], lb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if reflect.ValueOf(a).Kind() == reflect.Map || reflect.ValueOf(b).Kind() == reflect.Map {
+		return reflect.DeepEqual(a, b)
+	}
+	return Format(a) == Format(b)
+}
+
+// compare orders two values, numerically when both are numbers and as text
+// otherwise. Null cannot be ordered.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	x, xok := toNumber(a)
+	y, yok := toNumber(b)
+	if xok && yok {
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	return strings.Compare(Format(a), Format(b)), true
+}
+
+// in reports whether a list holds a value, a map has a key, or a string
+// contains a substring
+func in(needle, haystack interface{}) bool {
+	if haystack == nil {
+		return false
+	}
+	if s, ok := haystack.(string); ok {
+		return needle != nil && strings.Contains(s, Format(needle))
+	}
+	if list, ok := toList(haystack); ok {
+		for _, item := range list {
+			if equal(needle, item) {
+				return true
+			}
+		}
+		return false
+	}
+	rv := reflect.ValueOf(haystack)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		return rv.MapIndex(reflect.ValueOf(Format(needle)).Convert(rv.Type().Key())).IsValid()
+	}
+	return false
+}
+
+// toList returns the items of a slice or array
+func toList(v interface{}) ([]interface{}, bool) {
+	if list, ok := v.([]interface{}); ok {
+		return list, true
+	}
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false // []byte is text, not a list
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
+}
+
+// member returns a field of a map, or an item of a list when the key is a
+// number. Anything missing is null.
+func member(v interface{}, key string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		item := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !item.IsValid() {
+			return nil
+		}
+		return item.Interface()
+	case reflect.Slice, reflect.Array:
+		if i, err := strconv.Atoi(key); err == nil {
+			return index(v, i)
+		}
+	}
+	return nil
+}
+
+// index returns a list item, counting from the end for negative indexes, a
+// map field or a character of a string
+func index(v interface{}, i interface{}) interface{} {
+	if list, ok := toList(v); ok {
+		n, ok := toNumber(i)
+		if !ok || n != math.Trunc(n) {
+			return nil
+		}
+		idx := int(n)
+		if idx < 0 {
+			idx += len(list)
+		}
+		if idx < 0 || idx >= len(list) {
+			return nil
+		}
+		return list[idx]
+	}
+	if s, ok := v.(string); ok {
+		runes := []rune(s)
+		n, ok := toNumber(i)
+		if !ok || n != math.Trunc(n) {
+			return nil
+		}
+		idx := int(n)
+		if idx < 0 {
+			idx += len(runes)
+		}
+		if idx < 0 || idx >= len(runes) {
+			return nil
+		}
+		return string(runes[idx])
+	}
+	return member(v, Format(i))
+}
@@ -0,0 +1,238 @@
+// Package flowutil snapshots chatbot flow definitions for versioning, restores
+// them onto flows, and compares two definitions field by field.
+package flowutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// Definition is the versioned part of a chatbot flow. Name, description and
+// whether the flow is enabled are not versioned and always apply live.
+type Definition struct {
+	TriggerKeywords    models.StringArray       `json:"trigger_keywords"`
+	TriggerButtonID    string                   `json:"trigger_button_id"`
+	InitialMessage     string                   `json:"initial_message"`
+	InitialMessageType models.FlowStepType      `json:"initial_message_type"`
+	InitialTemplateID  *uuid.UUID               `json:"initial_template_id,omitempty"`
+	CompletionMessage  string                   `json:"completion_message"`
+	OnCompleteAction   string                   `json:"on_complete_action"`
+	CompletionConfig   models.JSONB             `json:"completion_config"`
+	TimeoutMessage     string                   `json:"timeout_message"`
+	CancelKeywords     models.StringArray       `json:"cancel_keywords"`
+	PanelConfig        models.JSONB             `json:"panel_config"`
+	Steps              []models.ChatbotFlowStep `json:"steps"`
+}
+
+// Snapshot captures the definition of a flow from its fields and steps
+func Snapshot(flow *models.ChatbotFlow) Definition {
+	def := Definition{
+		TriggerKeywords:    flow.TriggerKeywords,
+		TriggerButtonID:    flow.TriggerButtonID,
+		InitialMessage:     flow.InitialMessage,
+		InitialMessageType: flow.InitialMessageType,
+		InitialTemplateID:  flow.InitialTemplateID,
+		CompletionMessage:  flow.CompletionMessage,
+		OnCompleteAction:   flow.OnCompleteAction,
+		CompletionConfig:   flow.CompletionConfig,
+		TimeoutMessage:     flow.TimeoutMessage,
+		CancelKeywords:     flow.CancelKeywords,
+		PanelConfig:        flow.PanelConfig,
+		Steps:              make([]models.ChatbotFlowStep, len(flow.Steps)),
+	}
+	for i, step := range flow.Steps {
+		step.Flow = nil
+		step.Template = nil
+		def.Steps[i] = step
+	}
+	return def
+}
+
+// Apply overwrites the versioned fields and steps of a flow with a definition.
+// Steps keep the IDs they had when the definition was captured.
+func Apply(flow *models.ChatbotFlow, def Definition) {
+	flow.TriggerKeywords = def.TriggerKeywords
+	flow.TriggerButtonID = def.TriggerButtonID
+	flow.InitialMessage = def.InitialMessage
+	flow.InitialMessageType = def.InitialMessageType
+	flow.InitialTemplateID = def.InitialTemplateID
+	flow.CompletionMessage = def.CompletionMessage
+	flow.OnCompleteAction = def.OnCompleteAction
+	flow.CompletionConfig = def.CompletionConfig
+	flow.TimeoutMessage = def.TimeoutMessage
+	flow.CancelKeywords = def.CancelKeywords
+	flow.PanelConfig = def.PanelConfig
+	flow.Steps = make([]models.ChatbotFlowStep, len(def.Steps))
+	for i, step := range def.Steps {
+		step.FlowID = flow.ID
+		flow.Steps[i] = step
+	}
+}
+
+// Encode converts a definition to JSONB for storage on a flow version
+func Encode(def Definition) (models.JSONB, error) {
+	data, err := json.Marshal(def)
+	if err != nil {
+		return nil, err
+	}
+	var out models.JSONB
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Decode reads a definition stored on a flow version
+func Decode(data models.JSONB) (Definition, error) {
+	var def Definition
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return def, err
+	}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return def, fmt.Errorf("invalid flow definition: %w", err)
+	}
+	return def, nil
+}
+
+// Change is a field whose value differs between two definitions
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// StepChange lists the fields that differ on a step present in both definitions
+type StepChange struct {
+	Step    string   `json:"step"`
+	Changes []Change `json:"changes"`
+}
+
+// Diff describes how one definition differs from another. Steps are matched
+// by name, so a renamed step shows up as one removed and one added step.
+type Diff struct {
+	Changes      []Change     `json:"changes"`
+	AddedSteps   []string     `json:"added_steps"`
+	RemovedSteps []string     `json:"removed_steps"`
+	ChangedSteps []StepChange `json:"changed_steps"`
+	Reordered    bool         `json:"reordered"` // Steps in both definitions run in a different order
+}
+
+// Empty reports whether the two definitions are equivalent
+func (d Diff) Empty() bool {
+	return len(d.Changes) == 0 && len(d.AddedSteps) == 0 && len(d.RemovedSteps) == 0 &&
+		len(d.ChangedSteps) == 0 && !d.Reordered
+}
+
+// ignoredStepFields are step fields that change whenever steps are saved
+// without the step itself changing
+var ignoredStepFields = map[string]bool{
+	"id": true, "created_at": true, "updated_at": true, "deleted_at": true,
+	"flow_id": true, "step_order": true, "flow": true, "template": true,
+}
+
+// Compare lists the differences going from one definition to another
+func Compare(from, to Definition) Diff {
+	diff := Diff{
+		Changes:      []Change{},
+		AddedSteps:   []string{},
+		RemovedSteps: []string{},
+		ChangedSteps: []StepChange{},
+	}
+
+	fromFields, toFields := toMap(from), toMap(to)
+	delete(fromFields, "steps")
+	delete(toFields, "steps")
+	diff.Changes = compareFields(fromFields, toFields, nil)
+
+	fromSteps := make(map[string]models.ChatbotFlowStep, len(from.Steps))
+	for _, step := range from.Steps {
+		fromSteps[step.StepName] = step
+	}
+	toSteps := make(map[string]bool, len(to.Steps))
+	var fromOrder, toOrder []string
+	for _, step := range to.Steps {
+		toSteps[step.StepName] = true
+		prev, ok := fromSteps[step.StepName]
+		if !ok {
+			diff.AddedSteps = append(diff.AddedSteps, step.StepName)
+			continue
+		}
+		toOrder = append(toOrder, step.StepName)
+		if changes := compareFields(toMap(prev), toMap(step), ignoredStepFields); len(changes) > 0 {
+			diff.ChangedSteps = append(diff.ChangedSteps, StepChange{Step: step.StepName, Changes: changes})
+		}
+	}
+	for _, step := range from.Steps {
+		if !toSteps[step.StepName] {
+			diff.RemovedSteps = append(diff.RemovedSteps, step.StepName)
+			continue
+		}
+		fromOrder = append(fromOrder, step.StepName)
+	}
+	diff.Reordered = !reflect.DeepEqual(fromOrder, toOrder)
+
+	return diff
+}
+
+// compareFields returns the changed keys of two JSON objects in key order
+func compareFields(from, to map[string]interface{}, ignored map[string]bool) []Change {
+	keys := make(map[string]bool, len(from)+len(to))
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		if !ignored[k] {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+
+	changes := []Change{}
+	for _, k := range sorted {
+		a, b := from[k], to[k]
+		if isEmpty(a) && isEmpty(b) {
+			continue
+		}
+		if !reflect.DeepEqual(a, b) {
+			changes = append(changes, Change{Field: k, From: a, To: b})
+		}
+	}
+	return changes
+}
+
+// isEmpty treats a missing value, null, "" and empty objects or lists alike,
+// since saving a flow can turn one into another
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+// toMap converts a value to its generic JSON object form
+func toMap(v interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(data, &out)
+	return out
+}
@@ -0,0 +1,73 @@
+package flowutil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFlow() *models.ChatbotFlow {
+	flowID := uuid.New()
+	return &models.ChatbotFlow{
+		BaseModel:         models.BaseModel{ID: flowID},
+		Name:              "Orders",
+		TriggerKeywords:   models.StringArray{"order"},
+		InitialMessage:    "Hi!",
+		CompletionMessage: "Done.",
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 1, StepName: "ask_name", Message: "Name?", InputType: models.InputTypeText, StoreAs: "name"},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 2, StepName: "ask_email", Message: "Email?", InputType: models.InputTypeEmail, StoreAs: "email"},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	flow := testFlow()
+	data, err := Encode(Snapshot(flow))
+	require.NoError(t, err)
+
+	def, err := Decode(data)
+	require.NoError(t, err)
+	assert.True(t, Compare(Snapshot(flow), def).Empty())
+
+	// Applying onto another flow keeps step IDs but moves them to that flow
+	other := &models.ChatbotFlow{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "Copy"}
+	Apply(other, def)
+	assert.Equal(t, "Copy", other.Name)
+	assert.Equal(t, "Hi!", other.InitialMessage)
+	require.Len(t, other.Steps, 2)
+	assert.Equal(t, flow.Steps[1].ID, other.Steps[1].ID)
+	assert.Equal(t, other.ID, other.Steps[1].FlowID)
+}
+
+func TestCompare(t *testing.T) {
+	from := testFlow()
+	to := testFlow()
+
+	// Saving steps again with new IDs and an empty config is not a change
+	to.Steps[0].InputConfig = models.JSONB{}
+	assert.True(t, Compare(Snapshot(from), Snapshot(to)).Empty())
+
+	to.InitialMessage = "Hello!"
+	to.Steps[0].Message = "Your name?"
+	to.Steps[1].StepName = "ask_phone"
+	to.Steps = []models.ChatbotFlowStep{to.Steps[1], to.Steps[0]}
+
+	diff := Compare(Snapshot(from), Snapshot(to))
+	assert.False(t, diff.Empty())
+	assert.Equal(t, []Change{{Field: "initial_message", From: "Hi!", To: "Hello!"}}, diff.Changes)
+	assert.Equal(t, []string{"ask_phone"}, diff.AddedSteps)
+	assert.Equal(t, []string{"ask_email"}, diff.RemovedSteps)
+	assert.Equal(t, []StepChange{{Step: "ask_name", Changes: []Change{{Field: "message", From: "Name?", To: "Your name?"}}}}, diff.ChangedSteps)
+	assert.False(t, diff.Reordered) // ask_name is the only step in both
+
+	// Swapping two unchanged steps is a reorder
+	to = testFlow()
+	to.Steps = []models.ChatbotFlowStep{to.Steps[1], to.Steps[0]}
+	diff = Compare(Snapshot(from), Snapshot(to))
+	assert.True(t, diff.Reordered)
+	assert.Empty(t, diff.ChangedSteps)
+}
@@ -0,0 +1,307 @@
+package flowutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// MaxLoopIterations caps how many times a loop step can repeat its steps
+const MaxLoopIterations = 100
+
+// LoopConfig configures a loop step. Each time the loop step is reached it
+// appends the collect variables to the list stored under the step's store_as,
+// then goes back to back_to while condition holds (always, when empty), at
+// most max_iterations times.
+type LoopConfig struct {
+	BackTo        string   `json:"back_to"`
+	Condition     string   `json:"condition"`
+	MaxIterations int      `json:"max_iterations"`
+	Collect       []string `json:"collect"`
+	LimitMessage  string   `json:"limit_message"` // Sent when the limit stops the loop
+}
+
+// ParseLoopConfig reads and checks the loop_config of a loop step
+func ParseLoopConfig(config models.JSONB) (LoopConfig, error) {
+	var cfg LoopConfig
+	data, err := json.Marshal(config)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, errors.New("loop_config is invalid")
+	}
+	if cfg.BackTo == "" {
+		return cfg, errors.New("loop_config needs back_to")
+	}
+	if cfg.MaxIterations < 1 || cfg.MaxIterations > MaxLoopIterations {
+		return cfg, fmt.Errorf("loop_config max_iterations must be between 1 and %d", MaxLoopIterations)
+	}
+	return cfg, nil
+}
+
+// SubflowTarget returns the flow a subflow or goto_flow step continues in
+func SubflowTarget(step *models.ChatbotFlowStep) (uuid.UUID, error) {
+	id, _ := step.SubflowConfig["flow_id"].(string)
+	if id == "" {
+		return uuid.Nil, errors.New("subflow_config needs flow_id")
+	}
+	flowID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, errors.New("subflow_config has an invalid flow_id")
+	}
+	return flowID, nil
+}
+
+// WaitsForReply reports whether the flow stops at a step until the contact answers
+func WaitsForReply(step *models.ChatbotFlowStep) bool {
+	switch step.MessageType {
+	case models.FlowStepTypeScript, models.FlowStepTypeSubflow, models.FlowStepTypeGotoFlow, models.FlowStepTypeLoop:
+		return false
+	}
+	return step.InputType != models.InputTypeNone
+}
+
+// successors lists the steps the flow can move to after the step at index i
+func successors(steps []models.ChatbotFlowStep, i int) []string {
+	step := &steps[i]
+	switch step.MessageType {
+	case models.FlowStepTypeGotoFlow, models.FlowStepTypeTransfer:
+		return nil // The flow ends here
+	}
+
+	var next []string
+	if step.NextStep != "" {
+		next = append(next, step.NextStep)
+	} else if i+1 < len(steps) {
+		next = append(next, steps[i+1].StepName)
+	}
+	// Conditional jumps follow a reply
+	if WaitsForReply(step) {
+		for _, v := range step.ConditionalNext {
+			if name, ok := v.(string); ok && name != "" {
+				next = append(next, name)
+			}
+		}
+		rules, _ := step.ConditionalNext["when"].([]interface{})
+		for _, rule := range rules {
+			if entry, ok := rule.(map[string]interface{}); ok {
+				if name, ok := entry["next"].(string); ok && name != "" {
+					next = append(next, name)
+				}
+			}
+		}
+	}
+	if step.MessageType == models.FlowStepTypeLoop {
+		if cfg, err := ParseLoopConfig(step.LoopConfig); err == nil {
+			next = append(next, cfg.BackTo)
+		}
+	}
+	return next
+}
+
+// CheckSteps checks the subflow, goto and loop steps of a flow and rejects
+// steps that repeat without waiting for a reply, unless a loop step limits them
+func CheckSteps(steps []models.ChatbotFlowStep) error {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.StepName] = i
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		switch step.MessageType {
+		case models.FlowStepTypeSubflow, models.FlowStepTypeGotoFlow:
+			if _, err := SubflowTarget(step); err != nil {
+				return fmt.Errorf("step %q: %w", step.StepName, err)
+			}
+		case models.FlowStepTypeLoop:
+			cfg, err := ParseLoopConfig(step.LoopConfig)
+			if err != nil {
+				return fmt.Errorf("step %q: %w", step.StepName, err)
+			}
+			if _, ok := index[cfg.BackTo]; !ok {
+				return fmt.Errorf("step %q loops back to step %q, which does not exist", step.StepName, cfg.BackTo)
+			}
+			if len(cfg.Collect) > 0 && step.StoreAs == "" {
+				return fmt.Errorf("step %q needs store_as to collect values", step.StepName)
+			}
+		}
+	}
+
+	// Look for a cycle among steps that neither wait for a reply nor limit
+	// the repeats
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(steps))
+	var path []string
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = visiting
+		path = append(path, steps[i].StepName)
+		for _, name := range successors(steps, i) {
+			j, ok := index[name]
+			if !ok || WaitsForReply(&steps[j]) || steps[j].MessageType == models.FlowStepTypeLoop {
+				continue
+			}
+			switch state[j] {
+			case visiting:
+				for k, n := range path {
+					if n == name {
+						return append(append([]string{}, path[k:]...), name)
+					}
+				}
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = done
+		return nil
+	}
+	for i := range steps {
+		if state[i] != unvisited || WaitsForReply(&steps[i]) || steps[i].MessageType == models.FlowStepTypeLoop {
+			continue
+		}
+		if cycle := visit(i); cycle != nil {
+			return fmt.Errorf("steps %s repeat without waiting for a reply; use a loop step to limit them",
+				strings.Join(cycle, " → "))
+		}
+	}
+	return nil
+}
+
+// FlowCall is a subflow call or goto from one flow to another
+type FlowCall struct {
+	FlowID    uuid.UUID
+	Subflow   bool
+	Immediate bool // Made before the flow first waits for a reply
+}
+
+// Calls lists the flows a flow's steps call or go to
+func Calls(steps []models.ChatbotFlowStep) []FlowCall {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		index[step.StepName] = i
+	}
+
+	// Steps reached from the first step without waiting for a reply
+	immediate := make(map[int]bool)
+	if len(steps) > 0 {
+		queue := []int{0}
+		immediate[0] = true
+		for len(queue) > 0 {
+			i := queue[0]
+			queue = queue[1:]
+			if WaitsForReply(&steps[i]) {
+				continue
+			}
+			for _, name := range successors(steps, i) {
+				if j, ok := index[name]; ok && !immediate[j] {
+					immediate[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+
+	var calls []FlowCall
+	for i := range steps {
+		step := &steps[i]
+		if step.MessageType != models.FlowStepTypeSubflow && step.MessageType != models.FlowStepTypeGotoFlow {
+			continue
+		}
+		if target, err := SubflowTarget(step); err == nil {
+			calls = append(calls, FlowCall{
+				FlowID:    target,
+				Subflow:   step.MessageType == models.FlowStepTypeSubflow,
+				Immediate: immediate[i],
+			})
+		}
+	}
+	return calls
+}
+
+// FindCallCycle looks for a way the flow can call back into itself without
+// end and returns the flows along it, starting and ending with the flow.
+// Subflow calls add to the call stack each time round, so any cycle with one
+// is endless. Cycles of gotos are endless when no flow on them waits for a
+// reply first.
+func FindCallCycle(calls map[uuid.UUID][]FlowCall, flowID uuid.UUID) []uuid.UUID {
+	all := func(FlowCall) bool { return true }
+	immediate := func(c FlowCall) bool { return c.Immediate }
+
+	if cycle := callPath(calls, flowID, flowID, immediate); cycle != nil {
+		return cycle
+	}
+
+	// A subflow call from any flow reachable from this one, to a flow that
+	// leads back to it
+	reachable := map[uuid.UUID]bool{flowID: true}
+	queue := []uuid.UUID{flowID}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		for _, call := range calls[from] {
+			if call.Subflow {
+				back := []uuid.UUID{flowID}
+				if call.FlowID != flowID {
+					back = callPath(calls, call.FlowID, flowID, all)
+				}
+				if back != nil {
+					there := []uuid.UUID{flowID}
+					if from != flowID {
+						there = callPath(calls, flowID, from, all)
+					}
+					return append(there, back...)
+				}
+			}
+			if !reachable[call.FlowID] {
+				reachable[call.FlowID] = true
+				queue = append(queue, call.FlowID)
+			}
+		}
+	}
+	return nil
+}
+
+// callPath finds the shortest chain of calls from one flow to another,
+// taking at least one call
+func callPath(calls map[uuid.UUID][]FlowCall, from, to uuid.UUID, follow func(FlowCall) bool) []uuid.UUID {
+	parent := make(map[uuid.UUID]uuid.UUID)
+	seen := make(map[uuid.UUID]bool)
+	queue := []uuid.UUID{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, call := range calls[cur] {
+			if !follow(call) || seen[call.FlowID] {
+				continue
+			}
+			seen[call.FlowID] = true
+			parent[call.FlowID] = cur
+			if call.FlowID == to {
+				path := []uuid.UUID{to}
+				for node := to; ; {
+					prev := parent[node]
+					path = append([]uuid.UUID{prev}, path...)
+					if prev == from {
+						return path
+					}
+					node = prev
+				}
+			}
+			queue = append(queue, call.FlowID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package flowutil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLoopConfig(t *testing.T) {
+	cfg, err := ParseLoopConfig(models.JSONB{"back_to": "ask_item", "max_iterations": 5.0, "collect": []interface{}{"item"}})
+	require.NoError(t, err)
+	assert.Equal(t, LoopConfig{BackTo: "ask_item", MaxIterations: 5, Collect: []string{"item"}}, cfg)
+
+	_, err = ParseLoopConfig(models.JSONB{"max_iterations": 5.0})
+	assert.EqualError(t, err, "loop_config needs back_to")
+	_, err = ParseLoopConfig(models.JSONB{"back_to": "ask_item"})
+	assert.EqualError(t, err, "loop_config max_iterations must be between 1 and 100")
+	_, err = ParseLoopConfig(models.JSONB{"back_to": "ask_item", "max_iterations": "many"})
+	assert.EqualError(t, err, "loop_config is invalid")
+}
+
+func TestCheckSteps(t *testing.T) {
+	ask := func(name string) models.ChatbotFlowStep {
+		return models.ChatbotFlowStep{StepName: name, MessageType: models.FlowStepTypeText, InputType: models.InputTypeText, StoreAs: name}
+	}
+	say := func(name, next string) models.ChatbotFlowStep {
+		return models.ChatbotFlowStep{StepName: name, MessageType: models.FlowStepTypeText, InputType: models.InputTypeNone, NextStep: next}
+	}
+	loop := func(name, backTo string) models.ChatbotFlowStep {
+		return models.ChatbotFlowStep{
+			StepName: name, MessageType: models.FlowStepTypeLoop, StoreAs: "items",
+			LoopConfig: models.JSONB{"back_to": backTo, "condition": "more == 'yes'", "max_iterations": 5.0, "collect": []interface{}{"item"}},
+		}
+	}
+
+	// "Add another item?" loops through a question and a loop step
+	assert.NoError(t, CheckSteps([]models.ChatbotFlowStep{ask("item"), ask("more"), loop("repeat", "item"), say("done", "")}))
+
+	// Jumping back to a question waits for the contact each time
+	back := ask("menu")
+	back.ConditionalNext = models.JSONB{"again": "intro"}
+	assert.NoError(t, CheckSteps([]models.ChatbotFlowStep{say("intro", ""), back}))
+
+	// Messages that lead back to each other never stop
+	err := CheckSteps([]models.ChatbotFlowStep{ask("name"), say("a", "b"), say("b", "a")})
+	assert.EqualError(t, err, "steps a → b → a repeat without waiting for a reply; use a loop step to limit them")
+
+	// A loop step limits a loop of messages
+	assert.NoError(t, CheckSteps([]models.ChatbotFlowStep{say("a", ""), loop("repeat", "a")}))
+
+	err = CheckSteps([]models.ChatbotFlowStep{ask("item"), loop("repeat", "missing")})
+	assert.EqualError(t, err, `step "repeat" loops back to step "missing", which does not exist`)
+
+	noStore := loop("repeat", "item")
+	noStore.StoreAs = ""
+	err = CheckSteps([]models.ChatbotFlowStep{ask("item"), noStore})
+	assert.EqualError(t, err, `step "repeat" needs store_as to collect values`)
+
+	err = CheckSteps([]models.ChatbotFlowStep{{StepName: "address", MessageType: models.FlowStepTypeSubflow}})
+	assert.EqualError(t, err, `step "address": subflow_config needs flow_id`)
+}
+
+func TestFindCallCycle(t *testing.T) {
+	checkout, address, menu := uuid.New(), uuid.New(), uuid.New()
+	callStep := func(stepType models.FlowStepType, target uuid.UUID) models.ChatbotFlowStep {
+		return models.ChatbotFlowStep{StepName: "call", MessageType: stepType, SubflowConfig: models.JSONB{"flow_id": target.String()}}
+	}
+	question := models.ChatbotFlowStep{StepName: "question", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText}
+
+	// Checkout asks a question, then calls the address flow and goes to the menu
+	calls := map[uuid.UUID][]FlowCall{
+		checkout: Calls([]models.ChatbotFlowStep{question, callStep(models.FlowStepTypeSubflow, address), callStep(models.FlowStepTypeGotoFlow, menu)}),
+		address:  Calls([]models.ChatbotFlowStep{question}),
+		menu:     Calls([]models.ChatbotFlowStep{question, callStep(models.FlowStepTypeGotoFlow, checkout)}),
+	}
+	assert.Equal(t, []FlowCall{{FlowID: address, Subflow: true}, {FlowID: menu}}, calls[checkout])
+	// The menu waits for a reply before going back to checkout
+	assert.Nil(t, FindCallCycle(calls, checkout))
+
+	// The address flow calling checkout would nest calls without end
+	calls[address] = Calls([]models.ChatbotFlowStep{question, callStep(models.FlowStepTypeSubflow, checkout)})
+	assert.Equal(t, []uuid.UUID{checkout, address, checkout}, FindCallCycle(calls, checkout))
+	assert.Equal(t, []uuid.UUID{menu, checkout, address, checkout, menu}, FindCallCycle(calls, menu))
+
+	// Gotos taken without waiting for a reply bounce between flows forever
+	calls[address] = Calls([]models.ChatbotFlowStep{question})
+	calls[menu] = Calls([]models.ChatbotFlowStep{callStep(models.FlowStepTypeGotoFlow, checkout)})
+	calls[checkout] = Calls([]models.ChatbotFlowStep{callStep(models.FlowStepTypeGotoFlow, menu)})
+	assert.Equal(t, []uuid.UUID{checkout, menu, checkout}, FindCallCycle(calls, checkout))
+
+	// A flow calling itself
+	calls = map[uuid.UUID][]FlowCall{checkout: Calls([]models.ChatbotFlowStep{question, callStep(models.FlowStepTypeSubflow, checkout)})}
+	assert.Equal(t, []uuid.UUID{checkout, checkout}, FindCallCycle(calls, checkout))
+}
@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -17,17 +19,18 @@ import (
 
 // AccountRequest represents the request body for creating/updating an account
 type AccountRequest struct {
-	Name               string `json:"name" validate:"required"`
-	AppID              string `json:"app_id"`
-	PhoneID            string `json:"phone_id" validate:"required"`
-	BusinessID         string `json:"business_id" validate:"required"`
-	AccessToken        string `json:"access_token" validate:"required"`
-	AppSecret          string `json:"app_secret"` // Meta App Secret for webhook signature verification
-	WebhookVerifyToken string `json:"webhook_verify_token"`
-	APIVersion         string `json:"api_version"`
-	IsDefaultIncoming  bool   `json:"is_default_incoming"`
-	IsDefaultOutgoing  bool   `json:"is_default_outgoing"`
-	AutoReadReceipt    bool   `json:"auto_read_receipt"`
+	Name               string  `json:"name" validate:"required"`
+	AppID              string  `json:"app_id"`
+	PhoneID            string  `json:"phone_id" validate:"required"`
+	BusinessID         string  `json:"business_id" validate:"required"`
+	AccessToken        string  `json:"access_token" validate:"required"`
+	AppSecret          string  `json:"app_secret"` // Meta App Secret for webhook signature verification
+	WebhookVerifyToken string  `json:"webhook_verify_token"`
+	APIVersion         string  `json:"api_version"`
+	IsDefaultIncoming  bool    `json:"is_default_incoming"`
+	IsDefaultOutgoing  bool    `json:"is_default_outgoing"`
+	AutoReadReceipt    bool    `json:"auto_read_receipt"`
+	Timezone           *string `json:"timezone"` // IANA timezone for business hours; empty uses the organization's
 }
 
 // AccountResponse represents the response for an account (without sensitive data)
@@ -42,6 +45,7 @@ type AccountResponse struct {
 	IsDefaultIncoming  bool      `json:"is_default_incoming"`
 	IsDefaultOutgoing  bool      `json:"is_default_outgoing"`
 	AutoReadReceipt    bool      `json:"auto_read_receipt"`
+	Timezone           string    `json:"timezone"`
 	Status             string    `json:"status"`
 	HasAccessToken     bool      `json:"has_access_token"`
 	HasAppSecret       bool      `json:"has_app_secret"`
@@ -98,6 +102,14 @@ func (a *App) CreateAccount(r *fastglue.Request) error {
 		webhookVerifyToken = generateVerifyToken()
 	}
 
+	timezone := ""
+	if req.Timezone != nil {
+		timezone = strings.TrimSpace(*req.Timezone)
+	}
+	if !bizhours.ValidTimezone(timezone) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid timezone", nil, "")
+	}
+
 	// Set default API version
 	apiVersion := req.APIVersion
 	if apiVersion == "" {
@@ -117,6 +129,7 @@ func (a *App) CreateAccount(r *fastglue.Request) error {
 		IsDefaultIncoming:  req.IsDefaultIncoming,
 		IsDefaultOutgoing:  req.IsDefaultOutgoing,
 		AutoReadReceipt:    req.AutoReadReceipt,
+		Timezone:           timezone,
 		Status:             "active",
 	}
 
@@ -137,6 +150,9 @@ func (a *App) CreateAccount(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create account", nil, "")
 	}
 
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
 	return r.SendEnvelope(accountToResponse(account))
 }
 
@@ -208,6 +224,13 @@ func (a *App) UpdateAccount(r *fastglue.Request) error {
 		account.APIVersion = req.APIVersion
 	}
 	account.AutoReadReceipt = req.AutoReadReceipt
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		if !bizhours.ValidTimezone(timezone) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid timezone", nil, "")
+		}
+		account.Timezone = timezone
+	}
 
 	// Handle default flags
 	if req.IsDefaultIncoming && !account.IsDefaultIncoming {
@@ -230,6 +253,7 @@ func (a *App) UpdateAccount(r *fastglue.Request) error {
 
 	// Invalidate cache
 	a.InvalidateWhatsAppAccountCache(account.PhoneID)
+	a.InvalidateBusinessHoursCache(orgID)
 
 	return r.SendEnvelope(accountToResponse(*account))
 }
@@ -361,6 +385,7 @@ func accountToResponse(acc models.WhatsAppAccount) AccountResponse {
 		IsDefaultIncoming:  acc.IsDefaultIncoming,
 		IsDefaultOutgoing:  acc.IsDefaultOutgoing,
 		AutoReadReceipt:    acc.AutoReadReceipt,
+		Timezone:           acc.Timezone,
 		Status:             acc.Status,
 		HasAccessToken:     acc.AccessToken != "",
 		HasAppSecret:       acc.AppSecret != "",
@@ -4,9 +4,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/shiftutil"
+	"github.com/shridarpatil/whatomate/internal/surveyutil"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
 )
 
 // AgentAnalyticsSummary represents overall agent analytics
@@ -19,6 +23,28 @@ type AgentAnalyticsSummary struct {
 	TransfersBySource     map[string]int64 `json:"transfers_by_source"`
 	TotalBreakTimeMins    float64          `json:"total_break_time_mins"`
 	BreakCount            int64            `json:"break_count"`
+	TransfersWithHandoffs int64            `json:"transfers_with_handoffs"` // Passed between agents or teams at least once
+	HandoffRatePct        float64          `json:"handoff_rate_pct"`
+	SurveysSent           int64            `json:"surveys_sent"`
+	SurveyResponseRatePct float64          `json:"survey_response_rate_pct"`
+	SurveyScores
+}
+
+// SurveyScores summarizes the satisfaction surveys answered in a period
+type SurveyScores struct {
+	CSATScore     float64 `json:"csat_score"` // Average from 1 to 5
+	CSATResponses int64   `json:"csat_responses"`
+	NPS           float64 `json:"nps"` // From -100 to 100
+	NPSResponses  int64   `json:"nps_responses"`
+}
+
+// ShiftStats compares an agent's scheduled shift time with the time they were
+// available, up to now
+type ShiftStats struct {
+	ScheduledMins     float64 `json:"scheduled_mins"`
+	OnlineMins        float64 `json:"online_mins"`
+	OnShiftOnlineMins float64 `json:"on_shift_online_mins"`
+	ShiftAdherencePct float64 `json:"shift_adherence_pct"` // Share of scheduled time spent available
 }
 
 // AgentPerformanceStats represents performance metrics for an agent
@@ -29,11 +55,18 @@ type AgentPerformanceStats struct {
 	AvgResolutionMins    float64  `json:"avg_resolution_mins"`
 	TransfersHandled     int64    `json:"transfers_handled"`
 	ActiveTransfers      int64    `json:"active_transfers"`
+	MaxConcurrentChats   int      `json:"max_concurrent_chats"` // 0 = unlimited
+	AtCapacity           bool     `json:"at_capacity"`
 	MessagesSent         int64    `json:"messages_sent"`
 	TotalBreakTimeMins   float64  `json:"total_break_time_mins"`
 	BreakCount           int64    `json:"break_count"`
+	AvgHandleMins        float64  `json:"avg_handle_mins"`  // Per conversation held, until handed off or closed
+	Handoffs             int64    `json:"handoffs"`         // Conversations handed to another agent or the queue
+	HandoffRatePct       float64  `json:"handoff_rate_pct"` // Share of held conversations handed off
 	IsAvailable          bool     `json:"is_available"`
 	CurrentBreakStart    *string  `json:"current_break_start,omitempty"`
+	SurveyScores
+	ShiftStats
 }
 
 // TrendPoint represents a data point for time-series charts
@@ -268,6 +301,22 @@ func (a *App) calculateSummaryStats(orgID uuid.UUID, start, end time.Time, summa
 	for _, sc := range sourceCounts {
 		summary.TransfersBySource[sc.Source] = sc.Count
 	}
+
+	// Transfers handed between agents or teams
+	var totalTransfers int64
+	a.DB.Model(&models.AgentTransfer{}).
+		Where("organization_id = ? AND transferred_at >= ? AND transferred_at <= ?", orgID, start, end).
+		Count(&totalTransfers)
+	a.DB.Model(&models.TransferHop{}).
+		Joins("JOIN agent_transfers ON agent_transfers.id = transfer_hops.transfer_id").
+		Where("transfer_hops.organization_id = ? AND agent_transfers.transferred_at >= ? AND agent_transfers.transferred_at <= ?", orgID, start, end).
+		Where(handoffHopCondition).
+		Distinct("transfer_hops.transfer_id").
+		Count(&summary.TransfersWithHandoffs)
+	summary.HandoffRatePct = percentOf(summary.TransfersWithHandoffs, totalTransfers)
+
+	// Satisfaction surveys
+	a.calculateSurveySummary(orgID, nil, start, end, summary)
 }
 
 func (a *App) calculateAgentSummaryStats(orgID, agentID uuid.UUID, start, end time.Time, summary *AgentAnalyticsSummary) {
@@ -312,6 +361,9 @@ func (a *App) calculateAgentSummaryStats(orgID, agentID uuid.UUID, start, end ti
 
 	// Calculate break time
 	summary.TotalBreakTimeMins, summary.BreakCount = a.calculateBreakTime(agentID, start, end)
+
+	// Satisfaction surveys about this agent
+	a.calculateSurveySummary(orgID, &agentID, start, end, summary)
 }
 
 func (a *App) calculateAgentStats(orgID, agentID uuid.UUID, start, end time.Time) AgentPerformanceStats {
@@ -337,6 +389,11 @@ func (a *App) calculateAgentStats(orgID, agentID uuid.UUID, start, end time.Time
 		Where("organization_id = ? AND agent_id = ? AND status = ?", orgID, agentID, models.TransferStatusActive).
 		Count(&stats.ActiveTransfers)
 
+	// Concurrent-chat limit against current load
+	capacity := a.agentCapacities(orgID, []uuid.UUID{agentID})[agentID]
+	stats.MaxConcurrentChats = capacity.Max
+	stats.AtCapacity = !capacity.hasFree()
+
 	// Messages sent - count outgoing messages to contacts during agent's active transfers
 	// This captures all messages sent while the agent was handling the conversation
 	a.DB.Model(&models.Message{}).
@@ -356,9 +413,18 @@ func (a *App) calculateAgentStats(orgID, agentID uuid.UUID, start, end time.Time
 		Scan(&resolutionTimeResult)
 	stats.AvgResolutionMins = resolutionTimeResult.Avg
 
+	// Handle time and hand-offs from the transfer hop history
+	stats.AvgHandleMins, stats.Handoffs, stats.HandoffRatePct = a.calculateHandleStats(orgID, agentID, start, end)
+
 	// Calculate break time from availability logs
 	stats.TotalBreakTimeMins, stats.BreakCount = a.calculateBreakTime(agentID, start, end)
 
+	// Satisfaction surveys about this agent
+	stats.SurveyScores = a.calculateSurveyScores(orgID, &agentID, start, end)
+
+	// Scheduled against actual online time
+	stats.ShiftStats = a.calculateShiftStats(orgID, agentID, start, end)
+
 	// Check if currently on break and get break start time
 	if !stats.IsAvailable {
 		var currentBreak models.UserAvailabilityLog
@@ -394,6 +460,96 @@ func (a *App) calculateAllAgentStats(orgID uuid.UUID, start, end time.Time) []Ag
 	return stats
 }
 
+// handoffHopCondition matches transfer hops that passed a transfer from one
+// agent or team to another, as models.TransferHop.IsHandoff does
+const handoffHopCondition = "((transfer_hops.from_agent_id IS NOT NULL AND transfer_hops.to_agent_id IS DISTINCT FROM transfer_hops.from_agent_id) OR transfer_hops.from_team_id IS DISTINCT FROM transfer_hops.to_team_id)"
+
+// calculateHandleStats returns an agent's average handle time per conversation
+// held in a period, and how many of those they handed to someone else. A
+// holding runs from when the agent got the transfer to when it was handed
+// off or closed.
+func (a *App) calculateHandleStats(orgID, agentID uuid.UUID, start, end time.Time) (avgHandleMins float64, handoffs int64, handoffRatePct float64) {
+	type holdings struct {
+		Count   int64
+		Seconds float64
+	}
+
+	// Holdings that ended with a hop. Team moves that keep the agent are
+	// part of the same holding.
+	var handedOff holdings
+	a.DB.Model(&models.TransferHop{}).
+		Select("COUNT(*) FILTER (WHERE to_agent_id IS DISTINCT FROM from_agent_id) as count, COALESCE(SUM(handle_seconds), 0) as seconds").
+		Where("organization_id = ? AND from_agent_id = ? AND hopped_at >= ? AND hopped_at <= ?", orgID, agentID, start, end).
+		Scan(&handedOff)
+
+	// Holdings that ended with the transfer being closed
+	var closed holdings
+	a.DB.Model(&models.AgentTransfer{}).
+		Select(`COUNT(*) as count, COALESCE(SUM(EXTRACT(EPOCH FROM (resumed_at - COALESCE(
+			(SELECT MAX(hopped_at) FROM transfer_hops WHERE transfer_hops.transfer_id = agent_transfers.id), transferred_at)))), 0) as seconds`).
+		Where("organization_id = ? AND agent_id = ? AND status <> ? AND resumed_at >= ? AND resumed_at <= ?",
+			orgID, agentID, models.TransferStatusActive, start, end).
+		Scan(&closed)
+
+	held := handedOff.Count + closed.Count
+	if held == 0 {
+		return 0, 0, 0
+	}
+	avgHandleMins = (handedOff.Seconds + closed.Seconds) / float64(held) / 60
+	return avgHandleMins, handedOff.Count, percentOf(handedOff.Count, held)
+}
+
+// calculateSurveyScores returns the CSAT and NPS results of surveys answered
+// in a period, for one agent or, when agentID is nil, the organization
+func (a *App) calculateSurveyScores(orgID uuid.UUID, agentID *uuid.UUID, start, end time.Time) SurveyScores {
+	var result struct {
+		CSATResponses int64
+		CSATScore     float64
+		NPSResponses  int64
+		Promoters     int64
+		Detractors    int64
+	}
+	query := a.DB.Model(&models.SurveyResponse{}).
+		Select(`COUNT(*) FILTER (WHERE type = ?) as csat_responses,
+			COALESCE(AVG(score) FILTER (WHERE type = ?), 0) as csat_score,
+			COUNT(*) FILTER (WHERE type = ?) as nps_responses,
+			COUNT(*) FILTER (WHERE type = ? AND score >= ?) as promoters,
+			COUNT(*) FILTER (WHERE type = ? AND score <= ?) as detractors`,
+			models.SurveyTypeCSAT, models.SurveyTypeCSAT, models.SurveyTypeNPS,
+			models.SurveyTypeNPS, surveyutil.PromoterMin, models.SurveyTypeNPS, surveyutil.DetractorMax).
+		Where("organization_id = ? AND status = ? AND completed_at >= ? AND completed_at <= ?",
+			orgID, models.SurveyStatusCompleted, start, end)
+	if agentID != nil {
+		query = query.Where("agent_id = ?", *agentID)
+	}
+	query.Scan(&result)
+
+	return SurveyScores{
+		CSATScore:     result.CSATScore,
+		CSATResponses: result.CSATResponses,
+		NPS:           surveyutil.NPS(result.Promoters, result.Detractors, result.NPSResponses),
+		NPSResponses:  result.NPSResponses,
+	}
+}
+
+// calculateSurveySummary fills the survey figures of an analytics summary
+func (a *App) calculateSurveySummary(orgID uuid.UUID, agentID *uuid.UUID, start, end time.Time, summary *AgentAnalyticsSummary) {
+	sentInPeriod := func() *gorm.DB {
+		query := a.DB.Model(&models.SurveyResponse{}).
+			Where("organization_id = ? AND sent_at >= ? AND sent_at <= ?", orgID, start, end)
+		if agentID != nil {
+			query = query.Where("agent_id = ?", *agentID)
+		}
+		return query
+	}
+	var answered int64
+	sentInPeriod().Count(&summary.SurveysSent)
+	sentInPeriod().Where("status = ?", models.SurveyStatusCompleted).Count(&answered)
+
+	summary.SurveyResponseRatePct = percentOf(answered, summary.SurveysSent)
+	summary.SurveyScores = a.calculateSurveyScores(orgID, agentID, start, end)
+}
+
 // calculateBreakTime calculates total break time and count for an agent within a time period
 func (a *App) calculateBreakTime(agentID uuid.UUID, start, end time.Time) (totalMins float64, count int64) {
 	// Get all "away" periods that overlap with the time range
@@ -434,6 +590,54 @@ func (a *App) calculateBreakTime(agentID uuid.UUID, start, end time.Time) (total
 	return totalMins, count
 }
 
+// calculateShiftStats works out an agent's scheduled shift time and online
+// time from their availability logs, up to now
+func (a *App) calculateShiftStats(orgID, agentID uuid.UUID, start, end time.Time) ShiftStats {
+	var stats ShiftStats
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	if !end.After(start) {
+		return stats
+	}
+
+	var logs []models.UserAvailabilityLog
+	if err := a.DB.Where("user_id = ? AND is_available = true AND started_at <= ? AND (ended_at >= ? OR ended_at IS NULL)",
+		agentID, end, start).
+		Find(&logs).Error; err != nil {
+		a.Log.Error("Failed to fetch availability logs for online time calculation", "error", err, "agent_id", agentID)
+		return stats
+	}
+	online := make([]bizhours.Interval, 0, len(logs))
+	for _, log := range logs {
+		logEnd := end
+		if log.EndedAt != nil && log.EndedAt.Before(end) {
+			logEnd = *log.EndedAt
+		}
+		online = append(online, bizhours.Interval{Start: log.StartedAt, End: logEnd})
+	}
+	online = bizhours.Merge(bizhours.Clip(online, start, end))
+	stats.OnlineMins = shiftutil.Total(online).Minutes()
+
+	var schedule *models.ShiftSchedule
+	var row models.ShiftSchedule
+	if a.DB.Where("organization_id = ? AND user_id = ?", orgID, agentID).First(&row).Error == nil {
+		schedule = &row
+	}
+	plan, err := a.loadShiftPlan(schedule, orgID, agentID, start, end)
+	if err != nil {
+		a.Log.Error("Failed to load shift plan", "error", err, "agent_id", agentID)
+		return stats
+	}
+	scheduled := plan.Intervals(start, end)
+	stats.ScheduledMins = shiftutil.Total(scheduled).Minutes()
+	stats.OnShiftOnlineMins = shiftutil.Overlap(scheduled, online).Minutes()
+	if stats.ScheduledMins > 0 {
+		stats.ShiftAdherencePct = stats.OnShiftOnlineMins / stats.ScheduledMins * 100
+	}
+	return stats
+}
+
 func (a *App) calculateTrendData(orgID uuid.UUID, start, end time.Time, groupBy string, agentID *uuid.UUID) []TrendPoint {
 	var dateFormat string
 	var dateTrunc string
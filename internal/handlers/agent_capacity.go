@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/skillutil"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// queuedTransferBatch caps how many queued transfers are considered each
+// time an agent frees up
+const queuedTransferBatch = 100
+
+// UserCapacityRequest sets a user's concurrent-chat limit in the organization
+type UserCapacityRequest struct {
+	MaxConcurrentChats int `json:"max_concurrent_chats"` // 0 = use team limit
+}
+
+// agentCapacity is an agent's current load and concurrent-chat limit.
+type agentCapacity struct {
+	Load int
+	Max  int // 0 = unlimited
+}
+
+func (c agentCapacity) hasFree() bool {
+	return c.Max == 0 || c.Load < c.Max
+}
+
+// agentCapacities returns the active transfer load and effective limit of
+// each user. A user's own limit in the organization wins; otherwise the
+// lowest limit of the active teams they are an agent in applies.
+func (a *App) agentCapacities(orgID uuid.UUID, userIDs []uuid.UUID) map[uuid.UUID]agentCapacity {
+	caps := make(map[uuid.UUID]agentCapacity, len(userIDs))
+	if len(userIDs) == 0 {
+		return caps
+	}
+
+	type userLimit struct {
+		UserID uuid.UUID `gorm:"column:user_id"`
+		Limit  int       `gorm:"column:max_concurrent_chats"`
+	}
+	var teamLimits []userLimit
+	a.DB.Model(&models.TeamMember{}).
+		Select("team_members.user_id, MIN(teams.max_concurrent_chats) AS max_concurrent_chats").
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("teams.organization_id = ? AND teams.is_active = ? AND teams.max_concurrent_chats > 0", orgID, true).
+		Where("team_members.role = ? AND team_members.user_id IN ?", models.TeamRoleAgent, userIDs).
+		Group("team_members.user_id").
+		Scan(&teamLimits)
+	for _, l := range teamLimits {
+		caps[l.UserID] = agentCapacity{Max: l.Limit}
+	}
+
+	var ownLimits []userLimit
+	a.DB.Model(&models.UserOrganization{}).
+		Select("user_id, max_concurrent_chats").
+		Where("organization_id = ? AND user_id IN ? AND max_concurrent_chats > 0", orgID, userIDs).
+		Scan(&ownLimits)
+	for _, l := range ownLimits {
+		caps[l.UserID] = agentCapacity{Max: l.Limit}
+	}
+
+	type agentLoad struct {
+		AgentID uuid.UUID `gorm:"column:agent_id"`
+		Count   int       `gorm:"column:count"`
+	}
+	var loads []agentLoad
+	a.DB.Model(&models.AgentTransfer{}).
+		Select("agent_id, COUNT(*) as count").
+		Where("organization_id = ? AND agent_id IN ? AND status = ?", orgID, userIDs, models.TransferStatusActive).
+		Group("agent_id").
+		Scan(&loads)
+	for _, l := range loads {
+		c := caps[l.AgentID]
+		c.Load = l.Count
+		caps[l.AgentID] = c
+	}
+
+	return caps
+}
+
+// hasFreeCapacity reports whether the user can take another conversation
+func (a *App) hasFreeCapacity(orgID, userID uuid.UUID) bool {
+	return a.agentCapacities(orgID, []uuid.UUID{userID})[userID].hasFree()
+}
+
+// assignQueuedTransfers hands queued transfers the agent is eligible for to
+// them, oldest first, until they reach capacity. Eligible transfers are those
+// waiting in the agent's auto-assigning team queues and general-queue
+// transfers whose required skills the agent holds. It runs when an agent
+// becomes available or finishes a conversation and returns the number of
+// transfers assigned.
+func (a *App) assignQueuedTransfers(orgID, agentID uuid.UUID) int {
+	var agent models.User
+	if err := a.DB.Where("id = ? AND is_active = ? AND is_available = ?", agentID, true, true).First(&agent).Error; err != nil {
+		return 0
+	}
+
+	capacity := a.agentCapacities(orgID, []uuid.UUID{agentID})[agentID]
+	if !capacity.hasFree() {
+		return 0
+	}
+
+	var teamIDs []uuid.UUID
+	a.DB.Model(&models.TeamMember{}).
+		Joins("JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("team_members.user_id = ? AND team_members.role = ?", agentID, models.TeamRoleAgent).
+		Where("teams.organization_id = ? AND teams.is_active = ? AND teams.assignment_strategy <> ?", orgID, true, models.AssignmentStrategyManual).
+		Pluck("team_members.team_id", &teamIDs)
+
+	query := a.DB.Where("organization_id = ? AND status = ? AND agent_id IS NULL", orgID, models.TransferStatusActive)
+	skillsQueue := "team_id IS NULL AND required_skills IS NOT NULL AND required_skills <> '[]'::jsonb"
+	if len(teamIDs) > 0 {
+		query = query.Where("team_id IN ? OR ("+skillsQueue+")", teamIDs)
+	} else {
+		query = query.Where(skillsQueue)
+	}
+
+	var queued []models.AgentTransfer
+	if err := query.Preload("Contact").Order("transferred_at ASC").Limit(queuedTransferBatch).Find(&queued).Error; err != nil {
+		a.Log.Error("Failed to load queued transfers", "error", err, "agent_id", agentID)
+		return 0
+	}
+	if len(queued) == 0 {
+		return 0
+	}
+
+	self := []skillutil.Candidate{{UserID: agentID, Skills: a.agentSkillLevels(orgID, agentID)}}
+
+	assigned := 0
+	for i := range queued {
+		if !capacity.hasFree() {
+			break
+		}
+		transfer := &queued[i]
+		if transfer.TeamID == nil && skillutil.Best(transfer.RequiredSkills, self) == nil {
+			continue
+		}
+
+		transfer.AgentID = &agentID
+		a.UpdateSLAOnPickup(transfer)
+		// Only claim the transfer if nobody picked it up in the meantime
+		result := a.DB.Model(&models.AgentTransfer{}).
+			Where("id = ? AND agent_id IS NULL AND status = ?", transfer.ID, models.TransferStatusActive).
+			Updates(map[string]any{
+				"agent_id":        agentID,
+				"picked_up_at":    transfer.SLA.PickedUpAt,
+				"sla_breached":    transfer.SLA.Breached,
+				"sla_breached_at": transfer.SLA.BreachedAt,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		a.DB.Model(&models.Contact{}).Where("id = ?", transfer.ContactID).Update("assigned_user_id", agentID)
+		a.recordAssignmentChange(orgID, transfer.ContactID, nil, nil, &agentID, &transfer.ID)
+		a.recordTransferHop(transfer, nil, transfer.TeamID, models.TransferHopTriggerAutoAssign, nil, "", "")
+		a.broadcastTransferAssigned(transfer)
+
+		contactPhone, contactName := "", ""
+		if transfer.Contact != nil {
+			contactPhone = transfer.Contact.PhoneNumber
+			contactName = transfer.Contact.ProfileName
+		}
+		agentIDStr := agentID.String()
+		a.DispatchWebhook(orgID, models.WebhookEventTransferAssigned, TransferEventData{
+			TransferID:      transfer.ID.String(),
+			ContactID:       transfer.ContactID.String(),
+			ContactPhone:    contactPhone,
+			ContactName:     contactName,
+			Source:          transfer.Source,
+			AgentID:         &agentIDStr,
+			AgentName:       &agent.FullName,
+			WhatsAppAccount: transfer.WhatsAppAccount,
+		})
+
+		capacity.Load++
+		assigned++
+	}
+
+	if assigned > 0 {
+		a.Log.Info("Assigned queued transfers to agent", "agent_id", agentID, "count", assigned, "load", capacity.Load, "max", capacity.Max)
+	}
+	return assigned
+}
+
+// agentSkillLevels returns the agent's proficiency per lower-cased skill name
+func (a *App) agentSkillLevels(orgID, agentID uuid.UUID) map[string]int {
+	type skillRow struct {
+		Name        string `gorm:"column:name"`
+		Proficiency int    `gorm:"column:proficiency"`
+	}
+	var rows []skillRow
+	a.DB.Table("agent_skills").
+		Select("skills.name, agent_skills.proficiency").
+		Joins("JOIN skills ON skills.id = agent_skills.skill_id AND skills.deleted_at IS NULL").
+		Where("agent_skills.organization_id = ? AND agent_skills.user_id = ? AND agent_skills.deleted_at IS NULL", orgID, agentID).
+		Scan(&rows)
+
+	levels := make(map[string]int, len(rows))
+	for _, row := range rows {
+		levels[strings.ToLower(row.Name)] = row.Proficiency
+	}
+	return levels
+}
+
+// SetUserCapacity sets a user's concurrent-chat limit in the organization
+func (a *App) SetUserCapacity(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceUsers, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	var req UserCapacityRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if req.MaxConcurrentChats < 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "max_concurrent_chats cannot be negative", nil, "")
+	}
+
+	result := a.DB.Model(&models.UserOrganization{}).
+		Where("user_id = ? AND organization_id = ?", id, orgID).
+		Update("max_concurrent_chats", req.MaxConcurrentChats)
+	if result.Error != nil {
+		a.Log.Error("Failed to update user capacity", "error", result.Error)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update capacity", nil, "")
+	}
+	if result.RowsAffected == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	// A raised limit may let the agent take queued conversations right away
+	assigned := a.assignQueuedTransfers(orgID, id)
+
+	capacity := a.agentCapacities(orgID, []uuid.UUID{id})[id]
+	return r.SendEnvelope(map[string]any{
+		"max_concurrent_chats": req.MaxConcurrentChats,
+		"effective_limit":      capacity.Max,
+		"active_transfers":     capacity.Load,
+		"transfers_assigned":   assigned,
+	})
+}
@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_CreateAgentTransfer_SkipsAgentsAtCapacity(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	busy := createTestAgent(t, app, org.ID)
+	free := createTestAgent(t, app, org.ID)
+	team := createTestTeam(t, app, org.ID, busy.ID, free.ID)
+	require.NoError(t, app.DB.Model(team).Updates(map[string]any{
+		"assignment_strategy":  models.AssignmentStrategyLoadBalanced,
+		"max_concurrent_chats": 1,
+	}).Error)
+
+	// busy already holds its one allowed conversation
+	createTestTransfer(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, account.Name, models.TransferStatusActive, &busy.ID)
+
+	transfer := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+		"contact_id":       testutil.CreateTestContact(t, app.DB, org.ID).ID.String(),
+		"whatsapp_account": account.Name,
+		"team_id":          team.ID.String(),
+	})
+	require.NotNil(t, transfer.AgentID)
+	assert.Equal(t, free.ID.String(), *transfer.AgentID)
+
+	// Both agents are now full, so the next transfer stays queued
+	transfer = createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+		"contact_id":       testutil.CreateTestContact(t, app.DB, org.ID).ID.String(),
+		"whatsapp_account": account.Name,
+		"team_id":          team.ID.String(),
+	})
+	assert.Nil(t, transfer.AgentID)
+}
+
+func TestApp_UpdateAvailability_AssignsQueuedTransfers(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	agent := createTestAgent(t, app, org.ID)
+	require.NoError(t, app.DB.Model(agent).Update("is_available", false).Error)
+	require.NoError(t, app.DB.Model(&models.UserOrganization{}).
+		Where("user_id = ? AND organization_id = ?", agent.ID, org.ID).
+		Update("max_concurrent_chats", 2).Error)
+
+	team := createTestTeam(t, app, org.ID, agent.ID)
+	var queued []*models.AgentTransfer
+	for i := 0; i < 3; i++ {
+		transfer := createTestTransfer(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, account.Name, models.TransferStatusActive, nil)
+		require.NoError(t, app.DB.Model(transfer).Update("team_id", team.ID).Error)
+		queued = append(queued, transfer)
+	}
+
+	req := testutil.NewJSONRequest(t, map[string]any{"is_available": true})
+	testutil.SetAuthContext(req, org.ID, agent.ID)
+	require.NoError(t, app.UpdateAvailability(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Data struct {
+			TransfersAssigned int `json:"transfers_assigned"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &resp))
+	assert.Equal(t, 2, resp.Data.TransfersAssigned)
+
+	var assigned int64
+	app.DB.Model(&models.AgentTransfer{}).Where("agent_id = ?", agent.ID).Count(&assigned)
+	assert.Equal(t, int64(2), assigned)
+
+	// The newest transfer is still waiting
+	var last models.AgentTransfer
+	require.NoError(t, app.DB.First(&last, "id = ?", queued[2].ID).Error)
+	assert.Nil(t, last.AgentID)
+}
+
+func TestApp_SetUserCapacity(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	agent := createTestAgent(t, app, org.ID)
+
+	setCapacity := func(id uuid.UUID, limit int) *fasthttp.RequestCtx {
+		req := testutil.NewJSONRequest(t, map[string]any{"max_concurrent_chats": limit})
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", id.String())
+		require.NoError(t, app.SetUserCapacity(req))
+		return req.RequestCtx
+	}
+
+	t.Run("own limit overrides team limit", func(t *testing.T) {
+		team := createTestTeam(t, app, org.ID, agent.ID)
+		require.NoError(t, app.DB.Model(team).Update("max_concurrent_chats", 2).Error)
+
+		ctx := setCapacity(agent.ID, 5)
+		require.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+		var resp struct {
+			Data struct {
+				EffectiveLimit int `json:"effective_limit"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(ctx.Response.Body(), &resp))
+		assert.Equal(t, 5, resp.Data.EffectiveLimit)
+
+		ctx = setCapacity(agent.ID, 0)
+		require.NoError(t, json.Unmarshal(ctx.Response.Body(), &resp))
+		assert.Equal(t, 2, resp.Data.EffectiveLimit)
+	})
+
+	t.Run("rejects negative limit", func(t *testing.T) {
+		ctx := setCapacity(agent.ID, -1)
+		assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		ctx := setCapacity(uuid.New(), 3)
+		assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+	})
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/skillutil"
+	"github.com/shridarpatil/whatomate/internal/slautil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -31,6 +33,7 @@ type agentTransferRow struct {
 	TransferredAt         time.Time  `gorm:"column:transferred_at"`
 	ResumedAt             *time.Time `gorm:"column:resumed_at"`
 	ResumedBy             *uuid.UUID `gorm:"column:resumed_by"`
+	RequiredSkills        models.SkillRequirements `gorm:"column:required_skills"`
 	SLAResponseDeadline   *time.Time `gorm:"column:sla_response_deadline"`
 	SLAResolutionDeadline *time.Time `gorm:"column:sla_resolution_deadline"`
 	SLABreached           bool       `gorm:"column:sla_breached"`
@@ -39,6 +42,10 @@ type agentTransferRow struct {
 	EscalatedAt           *time.Time `gorm:"column:escalated_at"`
 	PickedUpAt            *time.Time `gorm:"column:picked_up_at"`
 	ExpiresAt             *time.Time `gorm:"column:expires_at"`
+	SLABusinessTime       bool       `gorm:"column:sla_business_time"`
+	SLAPausedAt           *time.Time `gorm:"column:sla_paused_at"`
+	Priority              models.SLAPriority `gorm:"column:priority"`
+	SLAPolicyID           *uuid.UUID `gorm:"column:sla_policy_id"`
 
 	// Joined fields
 	ContactName       *string `gorm:"column:contact_name"`
@@ -56,12 +63,16 @@ type CreateAgentTransferRequest struct {
 	TeamID          *string              `json:"team_id"` // Optional team queue
 	Notes           string               `json:"notes"`
 	Source          models.TransferSource `json:"source"` // manual, flow, keyword
+	RequiredSkills  models.SkillRequirements `json:"required_skills"` // Optional skills for skills-based routing
+	Priority        string               `json:"priority"` // low, normal (default), high, urgent
 }
 
 // AssignTransferRequest represents the request to assign a transfer to an agent
 type AssignTransferRequest struct {
 	AgentID *string `json:"agent_id"` // null or empty string = unassign, UUID = assign to agent
 	TeamID  *string `json:"team_id"`  // optional: move to different team queue
+	Reason  string  `json:"reason"`   // optional: why the transfer is handed off, kept in its hop history
+	Notes   string  `json:"notes"`    // optional: hand-off notes for the next agent
 }
 
 // AgentTransferResponse represents an agent transfer in API responses
@@ -84,6 +95,7 @@ type AgentTransferResponse struct {
 	ResumedAt         *string              `json:"resumed_at,omitempty"`
 	ResumedBy         *string              `json:"resumed_by,omitempty"`
 	ResumedByName     *string              `json:"resumed_by_name,omitempty"`
+	RequiredSkills    models.SkillRequirements `json:"required_skills"`
 
 	// SLA fields
 	SLAResponseDeadline   *string `json:"sla_response_deadline,omitempty"`
@@ -94,6 +106,10 @@ type AgentTransferResponse struct {
 	EscalatedAt           *string `json:"escalated_at,omitempty"`
 	PickedUpAt            *string `json:"picked_up_at,omitempty"`
 	ExpiresAt             *string `json:"expires_at,omitempty"`
+	SLABusinessTime       bool    `json:"sla_business_time"`
+	SLAPausedAt           *string `json:"sla_paused_at,omitempty"` // Set while business hours are closed
+	Priority              models.SLAPriority `json:"priority"`
+	SLAPolicyID           *string `json:"sla_policy_id,omitempty"` // SLA policy applied (omitted = chatbot settings)
 }
 
 // ListAgentTransfers lists agent transfers for the organization
@@ -304,6 +320,7 @@ func (a *App) ListAgentTransfers(r *fastglue.Request) error {
 			Source:          t.Source,
 			Notes:           t.Notes,
 			TransferredAt:   t.TransferredAt.Format(time.RFC3339),
+			RequiredSkills:  t.RequiredSkills,
 		}
 
 		if t.ContactName != nil {
@@ -370,6 +387,16 @@ func (a *App) ListAgentTransfers(r *fastglue.Request) error {
 			expiresAt := t.ExpiresAt.Format(time.RFC3339)
 			resp.ExpiresAt = &expiresAt
 		}
+		resp.SLABusinessTime = t.SLABusinessTime
+		if t.SLAPausedAt != nil {
+			pausedAt := t.SLAPausedAt.Format(time.RFC3339)
+			resp.SLAPausedAt = &pausedAt
+		}
+		resp.Priority = t.Priority
+		if t.SLAPolicyID != nil {
+			policyID := t.SLAPolicyID.String()
+			resp.SLAPolicyID = &policyID
+		}
 
 		response[i] = resp
 	}
@@ -405,6 +432,11 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid contact_id", nil, "")
 	}
 
+	priority, ok := slautil.ParsePriority(req.Priority)
+	if !ok {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid priority. Valid priorities: low, normal, high, urgent", nil, "")
+	}
+
 	// Get contact
 	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
 	if err != nil {
@@ -441,6 +473,7 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 
 	// Determine agent assignment
 	var agentID *uuid.UUID
+	route := transferRoute{RequiredSkills: skillutil.Merge(req.RequiredSkills)}
 
 	// First, try explicit agent from request
 	if req.AgentID != nil && *req.AgentID != "" {
@@ -459,16 +492,22 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		agentID = &parsedAgentID
 	} else if teamID != nil {
 		// Apply team's assignment strategy
-		agentID = a.assignToTeam(*teamID, orgID)
+		route = a.routeTransfer(orgID, teamID, contact, req.RequiredSkills)
+		agentID = route.AgentID
 	} else if settings != nil && settings.AgentAssignment.AssignToSameAgent && contact.AssignedUserID != nil {
-		// Auto-assign to contact's existing assigned agent (if setting enabled and agent is available)
+		// Auto-assign to contact's existing assigned agent (if setting enabled and agent is available with free capacity)
 		var assignedAgent models.User
-		if a.DB.Where("id = ?", contact.AssignedUserID).First(&assignedAgent).Error == nil && assignedAgent.IsAvailable {
+		if a.DB.Where("id = ?", contact.AssignedUserID).First(&assignedAgent).Error == nil && assignedAgent.IsAvailable &&
+			a.hasFreeCapacity(orgID, assignedAgent.ID) {
 			agentID = contact.AssignedUserID
 		}
-		// If agent is not available, falls through to queue (agentID remains nil)
+		// If agent is not available, falls through to skills routing or the queue
+	}
+	if agentID == nil && teamID == nil {
+		// Route on required skills; without any, agentID remains nil (goes to queue)
+		route = a.routeTransfer(orgID, nil, contact, req.RequiredSkills)
+		agentID = route.AgentID
 	}
-	// Otherwise, agentID remains nil (goes to queue)
 
 	// Determine source
 	source := req.Source
@@ -485,10 +524,12 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		PhoneNumber:         contact.PhoneNumber,
 		Status:              models.TransferStatusActive,
 		Source:              source,
+		Priority:            priority,
 		AgentID:             agentID,
 		TeamID:              teamID,
 		TransferredByUserID: &userID,
-		Notes:               req.Notes,
+		Notes:               withRoutingNote(req.Notes, route.Reason),
+		RequiredSkills:      route.RequiredSkills,
 		TransferredAt:       time.Now(),
 	}
 
@@ -564,6 +605,7 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		Source:          transfer.Source,
 		Notes:           transfer.Notes,
 		TransferredAt:   transfer.TransferredAt.Format(time.RFC3339),
+		RequiredSkills:  transfer.RequiredSkills,
 	}
 
 	if transfer.AgentID != nil {
@@ -609,6 +651,12 @@ func (a *App) CreateAgentTransfer(r *fastglue.Request) error {
 		expiresAt := transfer.SLA.ExpiresAt.Format(time.RFC3339)
 		resp.ExpiresAt = &expiresAt
 	}
+	resp.SLABusinessTime = transfer.SLA.BusinessTime
+	resp.Priority = transfer.Priority
+	if transfer.SLA.PolicyID != nil {
+		policyID := transfer.SLA.PolicyID.String()
+		resp.SLAPolicyID = &policyID
+	}
 
 	return r.SendEnvelope(map[string]any{
 		"transfer": resp,
@@ -650,6 +698,11 @@ func (a *App) ResumeFromTransfer(r *fastglue.Request) error {
 	// Clear chatbot tracking so client inactivity SLA doesn't trigger after transfer is closed
 	a.ClearContactChatbotTracking(transfer.ContactID)
 
+	// The agent has capacity for another queued conversation
+	if transfer.AgentID != nil {
+		a.assignQueuedTransfers(orgID, *transfer.AgentID)
+	}
+
 	// Get chatbot settings to check AssignToSameAgent (use cache)
 	settings, _ := a.getChatbotSettingsCached(orgID, transfer.WhatsAppAccount)
 
@@ -658,6 +711,7 @@ func (a *App) ResumeFromTransfer(r *fastglue.Request) error {
 		a.DB.Model(&models.Contact{}).
 			Where("id = ?", transfer.ContactID).
 			Update("assigned_user_id", nil)
+		a.recordAssignmentChange(orgID, transfer.ContactID, &userID, transfer.AgentID, nil, nil)
 	}
 
 	// Broadcast WebSocket notification
@@ -677,6 +731,9 @@ func (a *App) ResumeFromTransfer(r *fastglue.Request) error {
 		WhatsAppAccount: transfer.WhatsAppAccount,
 	})
 
+	// Ask the contact how the conversation went
+	a.sendTransferSurvey(transfer, settings)
+
 	return r.SendEnvelope(map[string]any{
 		"message": "Transfer resumed, chatbot is now active for this contact",
 	})
@@ -741,6 +798,7 @@ func (a *App) AssignAgentTransfer(r *fastglue.Request) error {
 	}
 
 	// Handle team reassignment (requires write permission)
+	previousTeamID := transfer.TeamID
 	if req.TeamID != nil {
 		if !hasWriteAccess {
 			return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You don't have permission to change team assignment", nil, "")
@@ -765,6 +823,7 @@ func (a *App) AssignAgentTransfer(r *fastglue.Request) error {
 	}
 
 	// Update transfer
+	previousAgentID := transfer.AgentID
 	transfer.AgentID = targetAgentID
 
 	// Update SLA tracking if being assigned
@@ -783,10 +842,22 @@ func (a *App) AssignAgentTransfer(r *fastglue.Request) error {
 		// Clear assignment when unassigning
 		a.DB.Model(transfer.Contact).Update("assigned_user_id", nil)
 	}
+	a.recordAssignmentChange(orgID, transfer.ContactID, &userID, previousAgentID, targetAgentID, &transfer.ID)
+
+	trigger := models.TransferHopTriggerManual
+	if previousAgentID == nil && targetAgentID != nil && *targetAgentID == userID {
+		trigger = models.TransferHopTriggerPickup
+	}
+	a.recordTransferHop(&transfer, previousAgentID, previousTeamID, trigger, &userID, req.Reason, req.Notes)
 
 	// Broadcast WebSocket notification
 	a.broadcastTransferAssigned(&transfer)
 
+	// The previous agent freed a slot
+	if previousAgentID != nil && (targetAgentID == nil || *previousAgentID != *targetAgentID) {
+		a.assignQueuedTransfers(orgID, *previousAgentID)
+	}
+
 	// Dispatch webhook for transfer assigned
 	var agentIDStr *string
 	var agentName *string
@@ -846,6 +917,10 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You don't have permission to pick up transfers", nil, "")
 	}
 
+	if !a.hasFreeCapacity(orgID, userID) {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "You have reached your concurrent chat limit", nil, "")
+	}
+
 	// Get optional team filter
 	teamIDStr := string(r.RequestCtx.QueryArgs().Peek("team_id"))
 
@@ -943,6 +1018,8 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 	if err := tx.Commit().Error; err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to complete pickup", nil, "")
 	}
+	a.recordAssignmentChange(orgID, transfer.ContactID, &userID, nil, &userID, &transfer.ID)
+	a.recordTransferHop(&transfer, nil, transfer.TeamID, models.TransferHopTriggerPickup, &userID, "", "")
 
 	// Load related data for response (outside transaction)
 	a.DB.Where("id = ?", transfer.ContactID).First(&transfer.Contact)
@@ -973,6 +1050,7 @@ func (a *App) PickNextTransfer(r *fastglue.Request) error {
 		Source:          transfer.Source,
 		Notes:           transfer.Notes,
 		TransferredAt:   transfer.TransferredAt.Format(time.RFC3339),
+		RequiredSkills:  transfer.RequiredSkills,
 	}
 
 	if transfer.Contact != nil {
@@ -1057,6 +1135,7 @@ func (a *App) broadcastTransferCreated(transfer *models.AgentTransfer, contact *
 		"status":           transfer.Status,
 		"source":           transfer.Source,
 		"notes":            transfer.Notes,
+		"required_skills":  transfer.RequiredSkills,
 		"transferred_at":   transfer.TransferredAt.Format(time.RFC3339),
 	}
 
@@ -1127,8 +1206,9 @@ func (a *App) broadcastTransferAssigned(transfer *models.AgentTransfer) {
 	})
 }
 
-// createTransferToQueue creates an unassigned agent transfer that goes to the queue
-func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *models.Contact, source models.TransferSource) {
+// createTransferToQueue creates an agent transfer for the general queue. When
+// skills are required it is assigned to the best skilled agent, if any.
+func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *models.Contact, source models.TransferSource, requiredSkills models.SkillRequirements) {
 	// Check for existing active transfer
 	var existingCount int64
 	a.DB.Model(&models.AgentTransfer{}).
@@ -1143,7 +1223,9 @@ func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *mo
 	// Get chatbot settings for SLA (use cache)
 	settings, _ := a.getChatbotSettingsCached(account.OrganizationID, account.Name)
 
-	// Create unassigned transfer (goes to queue)
+	route := a.routeTransfer(account.OrganizationID, nil, contact, requiredSkills)
+
+	// Create transfer (unassigned transfers go to queue)
 	transfer := models.AgentTransfer{
 		BaseModel:       models.BaseModel{ID: uuid.New()},
 		OrganizationID:  account.OrganizationID,
@@ -1152,7 +1234,9 @@ func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *mo
 		PhoneNumber:     contact.PhoneNumber,
 		Status:          models.TransferStatusActive,
 		Source:          source,
-		AgentID:         nil, // Unassigned - goes to queue
+		AgentID:         route.AgentID,
+		Notes:           withRoutingNote("", route.Reason),
+		RequiredSkills:  route.RequiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -1161,11 +1245,21 @@ func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *mo
 		a.SetSLADeadlines(&transfer, settings)
 	}
 
+	// If agent is already assigned, mark as picked up
+	if route.AgentID != nil {
+		a.UpdateSLAOnPickup(&transfer)
+	}
+
 	if err := a.DB.Create(&transfer).Error; err != nil {
 		a.Log.Error("Failed to create transfer to queue", "error", err, "contact_id", contact.ID, "source", string(source))
 		return
 	}
 
+	// Update contact assignment if agent assigned
+	if route.AgentID != nil {
+		a.DB.Model(contact).Update("assigned_user_id", route.AgentID)
+	}
+
 	a.Log.Info("Transfer created to agent queue", "transfer_id", transfer.ID, "contact_id", contact.ID, "source", source)
 
 	// Broadcast to WebSocket
@@ -1173,7 +1267,7 @@ func (a *App) createTransferToQueue(account *models.WhatsAppAccount, contact *mo
 }
 
 // createTransferFromKeyword creates an agent transfer triggered by a keyword rule
-func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact *models.Contact) {
+func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact *models.Contact, requiredSkills models.SkillRequirements) {
 	// Check for existing active transfer
 	var existingCount int64
 	a.DB.Model(&models.AgentTransfer{}).
@@ -1190,7 +1284,7 @@ func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact
 
 	// Check business hours - if outside hours, send out of hours message instead of transfer
 	if settings != nil && settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 {
-		if !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
+		if !a.isWithinBusinessHours(settings, account.Name) {
 			a.Log.Info("Outside business hours, sending out of hours message instead of transfer", "contact_id", contact.ID)
 			if settings.BusinessHours.OutOfHoursMessage != "" {
 				_ = a.sendAndSaveTextMessage(account, contact, settings.BusinessHours.OutOfHoursMessage)
@@ -1204,10 +1298,16 @@ func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact
 	if settings != nil && settings.AgentAssignment.AssignToSameAgent && contact.AssignedUserID != nil {
 		// Check if the assigned agent is available
 		var assignedAgent models.User
-		if a.DB.Where("id = ?", contact.AssignedUserID).First(&assignedAgent).Error == nil && assignedAgent.IsAvailable {
+		if a.DB.Where("id = ?", contact.AssignedUserID).First(&assignedAgent).Error == nil && assignedAgent.IsAvailable &&
+			a.hasFreeCapacity(account.OrganizationID, assignedAgent.ID) {
 			agentID = contact.AssignedUserID
 		}
-		// If agent is not available, falls through to queue (agentID remains nil)
+		// If agent is not available, falls through to skills routing or the queue
+	}
+	route := transferRoute{RequiredSkills: skillutil.Merge(requiredSkills)}
+	if agentID == nil {
+		route = a.routeTransfer(account.OrganizationID, nil, contact, requiredSkills)
+		agentID = route.AgentID
 	}
 
 	// Create transfer
@@ -1220,6 +1320,8 @@ func (a *App) createTransferFromKeyword(account *models.WhatsAppAccount, contact
 		Status:          models.TransferStatusActive,
 		Source:          models.TransferSourceKeyword,
 		AgentID:         agentID,
+		Notes:           withRoutingNote("", route.Reason),
+		RequiredSkills:  route.RequiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -1305,12 +1407,28 @@ func (a *App) assignToTeamRoundRobin(teamID uuid.UUID, orgID uuid.UUID) *uuid.UU
 		return nil
 	}
 
-	// Pick the first agent (least recently assigned)
-	selectedMember := members[0]
+	memberIDs := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		memberIDs[i] = m.UserID
+	}
+	capacities := a.agentCapacities(orgID, memberIDs)
+
+	// Pick the least recently assigned agent with free capacity
+	var selectedMember *models.TeamMember
+	for i := range members {
+		if capacities[members[i].UserID].hasFree() {
+			selectedMember = &members[i]
+			break
+		}
+	}
+	if selectedMember == nil {
+		a.Log.Debug("All available agents in team are at capacity", "team_id", teamID)
+		return nil
+	}
 
 	// Update last_assigned_at
 	now := time.Now()
-	a.DB.Model(&selectedMember).Update("last_assigned_at", now)
+	a.DB.Model(selectedMember).Update("last_assigned_at", now)
 
 	a.Log.Debug("Round-robin assigned to agent", "team_id", teamID, "user_id", selectedMember.UserID)
 	return &selectedMember.UserID
@@ -1337,37 +1455,26 @@ func (a *App) assignToTeamLoadBalanced(teamID uuid.UUID, orgID uuid.UUID) *uuid.
 		memberIDs[i] = m.UserID
 	}
 
-	// Count active transfers for all members in a single query (optimized from N+1)
-	type AgentLoad struct {
-		AgentID uuid.UUID `gorm:"column:agent_id"`
-		Count   int64     `gorm:"column:count"`
-	}
-	var loads []AgentLoad
-	a.DB.Model(&models.AgentTransfer{}).
-		Select("agent_id, COUNT(*) as count").
-		Where("organization_id = ? AND agent_id IN ? AND status = ?", orgID, memberIDs, models.TransferStatusActive).
-		Group("agent_id").
-		Scan(&loads)
-
-	// Build a map of agent loads
-	loadMap := make(map[uuid.UUID]int64)
-	for _, l := range loads {
-		loadMap[l.AgentID] = l.Count
-	}
+	// Load and limits for all members in a single pass (optimized from N+1)
+	capacities := a.agentCapacities(orgID, memberIDs)
 
-	// Find agent with lowest load (agents with 0 transfers won't be in loadMap)
+	// Find agent with lowest load among those with free capacity
 	var lowestUserID *uuid.UUID
-	var lowestCount int64 = -1
+	lowestCount := -1
 	for _, m := range members {
-		count := loadMap[m.UserID] // Will be 0 if not in map (no active transfers)
-		if lowestCount < 0 || count < lowestCount {
-			lowestCount = count
+		capacity := capacities[m.UserID] // Zero value if no active transfers and no limit
+		if !capacity.hasFree() {
+			continue
+		}
+		if lowestCount < 0 || capacity.Load < lowestCount {
+			lowestCount = capacity.Load
 			userID := m.UserID
 			lowestUserID = &userID
 		}
 	}
 
 	if lowestUserID == nil {
+		a.Log.Debug("All available agents in team are at capacity", "team_id", teamID)
 		return nil
 	}
 
@@ -1376,7 +1483,7 @@ func (a *App) assignToTeamLoadBalanced(teamID uuid.UUID, orgID uuid.UUID) *uuid.
 }
 
 // createTransferToTeam creates an agent transfer to a specific team with appropriate assignment
-func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *models.Contact, teamID uuid.UUID, notes string, source models.TransferSource) {
+func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *models.Contact, teamID uuid.UUID, notes string, source models.TransferSource, requiredSkills models.SkillRequirements) {
 	// Check for existing active transfer
 	var existingCount int64
 	a.DB.Model(&models.AgentTransfer{}).
@@ -1392,7 +1499,8 @@ func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *mod
 	settings, _ := a.getChatbotSettingsCached(account.OrganizationID, account.Name)
 
 	// Apply team's assignment strategy
-	agentID := a.assignToTeam(teamID, account.OrganizationID)
+	route := a.routeTransfer(account.OrganizationID, &teamID, contact, requiredSkills)
+	agentID := route.AgentID
 
 	// Create transfer
 	transfer := models.AgentTransfer{
@@ -1405,7 +1513,8 @@ func (a *App) createTransferToTeam(account *models.WhatsAppAccount, contact *mod
 		Source:          source,
 		AgentID:         agentID,
 		TeamID:          &teamID,
-		Notes:           notes,
+		Notes:           withRoutingNote(notes, route.Reason),
+		RequiredSkills:  route.RequiredSkills,
 		TransferredAt:   time.Now(),
 	}
 
@@ -1471,6 +1580,7 @@ func (a *App) ReturnAgentTransfersToQueue(userID, orgID uuid.UUID) int {
 	// Return each transfer to its team queue (or general queue)
 	for i := range transfers {
 		transfer := &transfers[i]
+		previousAgentID := transfer.AgentID
 		transfer.AgentID = nil
 
 		if err := a.DB.Save(transfer).Error; err != nil {
@@ -1482,6 +1592,8 @@ func (a *App) ReturnAgentTransfersToQueue(userID, orgID uuid.UUID) int {
 		if transfer.ContactID != uuid.Nil {
 			a.DB.Model(&models.Contact{}).Where("id = ?", transfer.ContactID).Update("assigned_user_id", nil)
 		}
+		a.recordAssignmentChange(orgID, transfer.ContactID, nil, previousAgentID, nil, &transfer.ID)
+		a.recordTransferHop(transfer, previousAgentID, transfer.TeamID, models.TransferHopTriggerAgentAway, nil, "", "")
 
 		// Broadcast the unassignment
 		a.broadcastTransferAssigned(transfer)
@@ -32,6 +32,8 @@ type App struct {
 	HTTPClient *http.Client
 	// wg tracks background goroutines for graceful shutdown
 	wg sync.WaitGroup
+	// simulation is set on the App running a flow in the simulator
+	simulation *flowSimulation
 }
 
 // WaitForBackgroundTasks blocks until all background goroutines complete.
@@ -149,6 +151,7 @@ func (a *App) StartCampaignStatsSubscriber() error {
 				"delivered_count": update.DeliveredCount,
 				"read_count":      update.ReadCount,
 				"failed_count":    update.FailedCount,
+				"opted_out_count": update.OptedOutCount,
 			},
 		})
 	})
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/flowutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"gorm.io/gorm"
@@ -16,6 +18,7 @@ const (
 	// Cache TTLs - 6 hours since these rarely change (invalidated on update anyway)
 	settingsCacheTTL        = 6 * time.Hour
 	flowsCacheTTL           = 6 * time.Hour
+	flowVersionCacheTTL     = 6 * time.Hour
 	keywordRulesCacheTTL    = 6 * time.Hour
 	whatsappAccountCacheTTL = 6 * time.Hour
 	webhooksCacheTTL        = 6 * time.Hour
@@ -24,10 +27,13 @@ const (
 	userPermissionsCacheTTL = 6 * time.Hour
 	rolePermissionsCacheTTL = 6 * time.Hour
 	tagsCacheTTL            = 6 * time.Hour
+	contactAttrsCacheTTL    = 6 * time.Hour
+	businessHoursCacheTTL   = time.Hour // Shorter since the holidays loaded depend on the date
 
 	// Cache key prefixes
 	settingsCachePrefix        = "chatbot:settings:"
 	flowsCachePrefix           = "chatbot:flows:"
+	flowVersionCachePrefix     = "chatbot:flow_version:"
 	keywordRulesCachePrefix    = "chatbot:keywords:"
 	whatsappAccountCachePrefix = "whatsapp:account:"
 	webhooksCachePrefix        = "webhooks:"
@@ -36,6 +42,8 @@ const (
 	userPermissionsCachePrefix = "permissions:user:"
 	rolePermissionsCachePrefix = "permissions:role:"
 	tagsCachePrefix            = "tags:"
+	contactAttrsCachePrefix    = "contact_attributes:"
+	businessHoursCachePrefix   = "chatbot:business_hours:"
 )
 
 // chatbotSettingsCache is used for caching since AI.APIKey has json:"-" tag
@@ -83,7 +91,8 @@ func (a *App) getChatbotSettingsCached(orgID uuid.UUID, whatsAppAccount string)
 	return &settings, nil
 }
 
-// getChatbotFlowsCached retrieves all enabled flows with steps from cache or database
+// getChatbotFlowsCached retrieves all enabled flows with steps from cache or database.
+// Published flows carry the definition of their published version, not the draft.
 func (a *App) getChatbotFlowsCached(orgID uuid.UUID) ([]models.ChatbotFlow, error) {
 	ctx := context.Background()
 	cacheKey := fmt.Sprintf("%s%s", flowsCachePrefix, orgID.String())
@@ -106,6 +115,9 @@ func (a *App) getChatbotFlowsCached(orgID uuid.UUID) ([]models.ChatbotFlow, erro
 		Find(&flows).Error; err != nil {
 		return nil, err
 	}
+	if err := a.applyPublishedVersions(flows); err != nil {
+		return nil, err
+	}
 
 	// Cache the result
 	if data, err := json.Marshal(flows); err == nil {
@@ -117,6 +129,11 @@ func (a *App) getChatbotFlowsCached(orgID uuid.UUID) ([]models.ChatbotFlow, erro
 
 // getChatbotFlowByIDCached retrieves a specific flow by ID from the cached flows list
 func (a *App) getChatbotFlowByIDCached(orgID uuid.UUID, flowID uuid.UUID) (*models.ChatbotFlow, error) {
+	// The simulator runs drafts of flows that may be disabled
+	if a.simulation != nil {
+		return a.simulation.loadFlow(a.DB, orgID, flowID)
+	}
+
 	flows, err := a.getChatbotFlowsCached(orgID)
 	if err != nil {
 		return nil, err
@@ -131,6 +148,102 @@ func (a *App) getChatbotFlowByIDCached(orgID uuid.UUID, flowID uuid.UUID) (*mode
 	return nil, gorm.ErrRecordNotFound
 }
 
+// applyPublishedVersions replaces the draft definition of published flows with
+// their published version
+func (a *App) applyPublishedVersions(flows []models.ChatbotFlow) error {
+	var versionIDs []uuid.UUID
+	for _, flow := range flows {
+		if flow.PublishedVersionID != nil {
+			versionIDs = append(versionIDs, *flow.PublishedVersionID)
+		}
+	}
+	if len(versionIDs) == 0 {
+		return nil
+	}
+
+	var versions []models.ChatbotFlowVersion
+	if err := a.DB.Where("id IN ?", versionIDs).Find(&versions).Error; err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]models.ChatbotFlowVersion, len(versions))
+	for _, v := range versions {
+		byID[v.ID] = v
+	}
+
+	for i := range flows {
+		if flows[i].PublishedVersionID == nil {
+			continue
+		}
+		version, ok := byID[*flows[i].PublishedVersionID]
+		if !ok {
+			a.Log.Error("Published flow version not found", "flow_id", flows[i].ID, "version_id", *flows[i].PublishedVersionID)
+			continue
+		}
+		def, err := flowutil.Decode(version.Definition)
+		if err != nil {
+			a.Log.Error("Failed to decode flow version", "error", err, "version_id", version.ID)
+			continue
+		}
+		flowutil.Apply(&flows[i], def)
+	}
+	return nil
+}
+
+// getFlowVersionCached retrieves a flow version from cache or database.
+// Versions never change, so they are not invalidated.
+func (a *App) getFlowVersionCached(versionID uuid.UUID) (*models.ChatbotFlowVersion, error) {
+	ctx := context.Background()
+	cacheKey := fmt.Sprintf("%s%s", flowVersionCachePrefix, versionID.String())
+
+	// Try cache first
+	cached, err := a.Redis.Get(ctx, cacheKey).Result()
+	if err == nil && cached != "" {
+		var version models.ChatbotFlowVersion
+		if err := json.Unmarshal([]byte(cached), &version); err == nil {
+			return &version, nil
+		}
+	}
+
+	// Cache miss - fetch from database
+	var version models.ChatbotFlowVersion
+	if err := a.DB.Where("id = ?", versionID).First(&version).Error; err != nil {
+		return nil, err
+	}
+
+	// Cache the result
+	if data, err := json.Marshal(version); err == nil {
+		a.Redis.Set(ctx, cacheKey, data, flowVersionCacheTTL)
+	}
+
+	return &version, nil
+}
+
+// getSessionFlow loads the flow a session is in at the version the session
+// started on, so publishing never moves contacts that are mid-flow
+func (a *App) getSessionFlow(orgID uuid.UUID, session *models.ChatbotSession) (*models.ChatbotFlow, error) {
+	flow, err := a.getChatbotFlowByIDCached(orgID, *session.CurrentFlowID)
+	if err != nil {
+		return nil, err
+	}
+	// Sessions started before the flow was published run the current version
+	if session.FlowVersionID == nil ||
+		(flow.PublishedVersionID != nil && *flow.PublishedVersionID == *session.FlowVersionID) {
+		return flow, nil
+	}
+
+	version, err := a.getFlowVersionCached(*session.FlowVersionID)
+	if err != nil {
+		return nil, err
+	}
+	def, err := flowutil.Decode(version.Definition)
+	if err != nil {
+		return nil, err
+	}
+	pinned := *flow
+	flowutil.Apply(&pinned, def)
+	return &pinned, nil
+}
+
 // getKeywordRulesCached retrieves keyword rules from cache or database
 func (a *App) getKeywordRulesCached(orgID uuid.UUID, whatsAppAccount string) ([]models.KeywordRule, error) {
 	ctx := context.Background()
@@ -680,3 +793,105 @@ func (a *App) InvalidateTagsCache(orgID uuid.UUID) {
 	cacheKey := fmt.Sprintf("%s%s", tagsCachePrefix, orgID.String())
 	a.Redis.Del(ctx, cacheKey)
 }
+
+// getContactAttributesCached retrieves contact attribute definitions for an organization from cache or database
+func (a *App) getContactAttributesCached(orgID uuid.UUID) ([]models.ContactAttribute, error) {
+	ctx := context.Background()
+	cacheKey := fmt.Sprintf("%s%s", contactAttrsCachePrefix, orgID.String())
+
+	// Try cache first
+	cached, err := a.Redis.Get(ctx, cacheKey).Result()
+	if err == nil && cached != "" {
+		var attrs []models.ContactAttribute
+		if err := json.Unmarshal([]byte(cached), &attrs); err == nil {
+			return attrs, nil
+		}
+	}
+
+	// Cache miss - fetch from database
+	var attrs []models.ContactAttribute
+	if err := a.DB.Where("organization_id = ?", orgID).Order("position ASC, label ASC").Find(&attrs).Error; err != nil {
+		return nil, err
+	}
+
+	// Cache the result
+	if data, err := json.Marshal(attrs); err == nil {
+		a.Redis.Set(ctx, cacheKey, data, contactAttrsCacheTTL)
+	}
+
+	return attrs, nil
+}
+
+// InvalidateContactAttributesCache invalidates the contact attributes cache for an organization
+func (a *App) InvalidateContactAttributesCache(orgID uuid.UUID) {
+	ctx := context.Background()
+	cacheKey := fmt.Sprintf("%s%s", contactAttrsCachePrefix, orgID.String())
+	a.Redis.Del(ctx, cacheKey)
+}
+
+// businessHoursCache holds what an account's business-hours schedule needs
+// besides the chatbot settings: its timezone and current holidays
+type businessHoursCache struct {
+	Timezone string             `json:"timezone"`
+	Closures []bizhours.Closure `json:"closures"`
+}
+
+// getBusinessHoursCached retrieves the timezone and current holidays of an
+// account's business hours, in the team's timezone when the team has one,
+// from cache or database
+func (a *App) getBusinessHoursCached(orgID uuid.UUID, whatsAppAccount string, teamID *uuid.UUID) (*businessHoursCache, error) {
+	ctx := context.Background()
+	team := ""
+	if teamID != nil {
+		team = teamID.String()
+	}
+	cacheKey := fmt.Sprintf("%s%s:%s:%s", businessHoursCachePrefix, orgID.String(), whatsAppAccount, team)
+
+	// Try cache first (if Redis is available)
+	if a.Redis != nil {
+		cached, err := a.Redis.Get(ctx, cacheKey).Result()
+		if err == nil && cached != "" {
+			var data businessHoursCache
+			if err := json.Unmarshal([]byte(cached), &data); err == nil {
+				return &data, nil
+			}
+		}
+	}
+
+	// Cache miss - fetch from database
+	timezone := ""
+	if teamID != nil {
+		var t models.Team
+		if a.DB.Select("timezone").Where("id = ?", *teamID).First(&t).Error == nil {
+			timezone = t.Timezone
+		}
+	}
+	// Past closures no longer matter
+	closures, err := bizhours.LoadClosures(a.DB, orgID, whatsAppAccount, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	data := businessHoursCache{
+		Timezone: bizhours.ResolveTimezone(a.DB, orgID, whatsAppAccount, timezone),
+		Closures: closures,
+	}
+
+	// Cache the result (if Redis is available)
+	if a.Redis != nil {
+		if encoded, err := json.Marshal(data); err == nil {
+			a.Redis.Set(ctx, cacheKey, encoded, businessHoursCacheTTL)
+		}
+	}
+
+	return &data, nil
+}
+
+// InvalidateBusinessHoursCache invalidates the business hours cache for an organization
+func (a *App) InvalidateBusinessHoursCache(orgID uuid.UUID) {
+	if a.Redis == nil {
+		return
+	}
+	ctx := context.Background()
+	pattern := fmt.Sprintf("%s%s:*", businessHoursCachePrefix, orgID.String())
+	a.deleteKeysByPattern(ctx, pattern)
+}
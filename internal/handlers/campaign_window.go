@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/queue"
+)
+
+// errNoBusinessHours is returned when a business-hours-only campaign can
+// never be sent
+var errNoBusinessHours = errors.New("business hours do not open within the next year")
+
+// CampaignResumer periodically restarts campaigns that were paused because
+// they were outside their account's business hours
+type CampaignResumer struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewCampaignResumer creates a new campaign resumer
+func NewCampaignResumer(app *App, interval time.Duration) *CampaignResumer {
+	return &CampaignResumer{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the campaign resume loop
+func (p *CampaignResumer) Start(ctx context.Context) {
+	p.app.Log.Info("Campaign resumer started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.app.Log.Info("Campaign resumer stopped by context")
+			return
+		case <-p.stopCh:
+			p.app.Log.Info("Campaign resumer stopped")
+			return
+		case <-ticker.C:
+			p.app.resumeCampaigns(ctx, time.Now())
+		}
+	}
+}
+
+// Stop stops the campaign resumer
+func (p *CampaignResumer) Stop() {
+	close(p.stopCh)
+}
+
+// campaignSendWindow returns when a business-hours-only campaign may next
+// send, or nil when it may send now.
+func (a *App) campaignSendWindow(campaign *models.BulkMessageCampaign, now time.Time) (*time.Time, error) {
+	schedule, err := bizhours.ForAccount(a.DB, campaign.OrganizationID, campaign.WhatsAppAccount)
+	if err != nil {
+		// Don't hold a campaign back because its schedule failed to load
+		a.Log.Error("Failed to load business hours for campaign", "error", err, "campaign_id", campaign.ID)
+		return nil, nil
+	}
+	if schedule == nil || schedule.IsOpen(now) {
+		return nil, nil
+	}
+	next := schedule.NextOpen(now)
+	if next.IsZero() {
+		return nil, errNoBusinessHours
+	}
+	return &next, nil
+}
+
+// resumeCampaigns re-enqueues the pending recipients of campaigns whose
+// business hours have opened
+func (a *App) resumeCampaigns(ctx context.Context, now time.Time) {
+	var campaigns []models.BulkMessageCampaign
+	if err := a.DB.Where("status = ? AND resume_at IS NOT NULL AND resume_at <= ?", models.CampaignStatusPaused, now).
+		Find(&campaigns).Error; err != nil {
+		a.Log.Error("Failed to load campaigns to resume", "error", err)
+		return
+	}
+
+	for i := range campaigns {
+		campaign := &campaigns[i]
+
+		// Holidays may have been added since the campaign was paused
+		resumeAt, err := a.campaignSendWindow(campaign, now)
+		if err != nil || resumeAt != nil {
+			a.DB.Model(campaign).Update("resume_at", resumeAt)
+			continue
+		}
+
+		var recipients []models.BulkMessageRecipient
+		if err := a.DB.Where("campaign_id = ? AND status = ?", campaign.ID, models.MessageStatusPending).Find(&recipients).Error; err != nil {
+			a.Log.Error("Failed to load recipients", "error", err, "campaign_id", campaign.ID)
+			continue
+		}
+
+		// Only resume if the campaign was not cancelled or restarted meanwhile
+		updates := map[string]interface{}{"status": models.CampaignStatusProcessing, "resume_at": nil}
+		if campaign.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		if len(recipients) == 0 {
+			updates["status"] = models.CampaignStatusCompleted
+			updates["completed_at"] = now
+		}
+		result := a.DB.Model(&models.BulkMessageCampaign{}).
+			Where("id = ? AND status = ? AND resume_at IS NOT NULL", campaign.ID, models.CampaignStatusPaused).
+			Updates(updates)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		if len(recipients) > 0 {
+			if err := a.Queue.EnqueueRecipients(ctx, campaignJobs(campaign, recipients)); err != nil {
+				a.Log.Error("Failed to enqueue recipients", "error", err, "campaign_id", campaign.ID)
+				a.DB.Model(campaign).Updates(map[string]interface{}{"status": models.CampaignStatusPaused, "resume_at": now})
+				continue
+			}
+		}
+		a.Log.Info("Campaign resumed within business hours", "campaign_id", campaign.ID, "recipients", len(recipients))
+	}
+}
+
+// campaignJobs builds the queue jobs for a campaign's recipients
+func campaignJobs(campaign *models.BulkMessageCampaign, recipients []models.BulkMessageRecipient) []*queue.RecipientJob {
+	jobs := make([]*queue.RecipientJob, len(recipients))
+	for i, recipient := range recipients {
+		jobs[i] = &queue.RecipientJob{
+			CampaignID:     campaign.ID,
+			RecipientID:    recipient.ID,
+			OrganizationID: campaign.OrganizationID,
+			PhoneNumber:    recipient.PhoneNumber,
+			RecipientName:  recipient.RecipientName,
+			TemplateParams: recipient.TemplateParams,
+		}
+	}
+	return jobs
+}
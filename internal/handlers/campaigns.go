@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/phoneutil"
 	"github.com/shridarpatil/whatomate/internal/queue"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/valyala/fasthttp"
@@ -24,6 +26,8 @@ type CampaignRequest struct {
 	TemplateID      string     `json:"template_id" validate:"required"`
 	HeaderMediaID   string     `json:"header_media_id"`
 	ScheduledAt     *time.Time `json:"scheduled_at"`
+	SegmentID       *string    `json:"segment_id"` // Target a saved segment; empty string clears it on update
+	BusinessHoursOnly bool     `json:"business_hours_only"` // Only send within the account's business hours
 }
 
 // CampaignResponse represents campaign in API responses
@@ -42,9 +46,13 @@ type CampaignResponse struct {
 	DeliveredCount  int                  `json:"delivered_count"`
 	ReadCount       int                  `json:"read_count"`
 	FailedCount     int                  `json:"failed_count"`
+	OptedOutCount   int                  `json:"opted_out_count"`
 	ScheduledAt     *time.Time           `json:"scheduled_at,omitempty"`
 	StartedAt       *time.Time           `json:"started_at,omitempty"`
 	CompletedAt     *time.Time           `json:"completed_at,omitempty"`
+	SegmentID       *uuid.UUID           `json:"segment_id,omitempty"`
+	BusinessHoursOnly bool               `json:"business_hours_only"`
+	ResumeAt          *time.Time         `json:"resume_at,omitempty"`
 	CreatedAt       time.Time            `json:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at"`
 }
@@ -119,9 +127,13 @@ func (a *App) ListCampaigns(r *fastglue.Request) error {
 			DeliveredCount:      c.DeliveredCount,
 			ReadCount:           c.ReadCount,
 			FailedCount:         c.FailedCount,
+			OptedOutCount:       c.OptedOutCount,
 			ScheduledAt:         c.ScheduledAt,
 			StartedAt:           c.StartedAt,
 			CompletedAt:         c.CompletedAt,
+			SegmentID:           c.SegmentID,
+			BusinessHoursOnly:   c.BusinessHoursOnly,
+			ResumeAt:            c.ResumeAt,
 			CreatedAt:           c.CreatedAt,
 			UpdatedAt:           c.UpdatedAt,
 		}
@@ -167,6 +179,11 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
 	}
 
+	segmentID, err := a.parseCampaignSegment(r, orgID, req.SegmentID)
+	if err != nil {
+		return nil
+	}
+
 	campaign := models.BulkMessageCampaign{
 		OrganizationID:  orgID,
 		WhatsAppAccount: req.WhatsAppAccount,
@@ -176,6 +193,8 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		Status:          models.CampaignStatusDraft,
 		ScheduledAt:     req.ScheduledAt,
 		CreatedBy:       userID,
+		SegmentID:       segmentID,
+		BusinessHoursOnly: req.BusinessHoursOnly,
 	}
 
 	if err := a.DB.Create(&campaign).Error; err != nil {
@@ -199,7 +218,11 @@ func (a *App) CreateCampaign(r *fastglue.Request) error {
 		SentCount:           campaign.SentCount,
 		DeliveredCount:      campaign.DeliveredCount,
 		FailedCount:         campaign.FailedCount,
+		OptedOutCount:       campaign.OptedOutCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		SegmentID:           campaign.SegmentID,
+		BusinessHoursOnly:   campaign.BusinessHoursOnly,
+		ResumeAt:            campaign.ResumeAt,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
 	})
@@ -237,9 +260,13 @@ func (a *App) GetCampaign(r *fastglue.Request) error {
 		SentCount:           campaign.SentCount,
 		DeliveredCount:      campaign.DeliveredCount,
 		FailedCount:         campaign.FailedCount,
+		OptedOutCount:       campaign.OptedOutCount,
 		ScheduledAt:         campaign.ScheduledAt,
 		StartedAt:           campaign.StartedAt,
 		CompletedAt:         campaign.CompletedAt,
+		SegmentID:           campaign.SegmentID,
+		BusinessHoursOnly:   campaign.BusinessHoursOnly,
+		ResumeAt:            campaign.ResumeAt,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
 	}
@@ -279,8 +306,9 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 
 	// Update fields
 	updates := map[string]interface{}{
-		"name":         req.Name,
-		"scheduled_at": req.ScheduledAt,
+		"name":                req.Name,
+		"scheduled_at":        req.ScheduledAt,
+		"business_hours_only": req.BusinessHoursOnly,
 	}
 
 	if req.TemplateID != "" {
@@ -295,6 +323,14 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		updates["whats_app_account"] = req.WhatsAppAccount
 	}
 
+	if req.SegmentID != nil {
+		segmentID, err := a.parseCampaignSegment(r, orgID, req.SegmentID)
+		if err != nil {
+			return nil
+		}
+		updates["segment_id"] = segmentID
+	}
+
 	if err := a.DB.Model(campaign).Updates(updates).Error; err != nil {
 		a.Log.Error("Failed to update campaign", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update campaign", nil, "")
@@ -316,7 +352,11 @@ func (a *App) UpdateCampaign(r *fastglue.Request) error {
 		SentCount:           campaign.SentCount,
 		DeliveredCount:      campaign.DeliveredCount,
 		FailedCount:         campaign.FailedCount,
+		OptedOutCount:       campaign.OptedOutCount,
 		ScheduledAt:         campaign.ScheduledAt,
+		SegmentID:           campaign.SegmentID,
+		BusinessHoursOnly:   campaign.BusinessHoursOnly,
+		ResumeAt:            campaign.ResumeAt,
 		CreatedAt:           campaign.CreatedAt,
 		UpdatedAt:           campaign.UpdatedAt,
 	}
@@ -390,6 +430,16 @@ func (a *App) StartCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign cannot be started in current state", nil, "")
 	}
 
+	// Resolve segment membership at send time; resumed campaigns keep their recipients
+	if campaign.SegmentID != nil && campaign.Status != models.CampaignStatusPaused {
+		added, err := a.addSegmentRecipients(campaign)
+		if err != nil {
+			a.Log.Error("Failed to add segment recipients", "error", err, "campaign_id", id, "segment_id", campaign.SegmentID)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to add segment recipients", nil, "")
+		}
+		a.Log.Info("Segment recipients added to campaign", "campaign_id", id, "segment_id", campaign.SegmentID, "count", added)
+	}
+
 	// Get all pending recipients
 	var recipients []models.BulkMessageRecipient
 	if err := a.DB.Where("campaign_id = ? AND status = ?", id, models.MessageStatusPending).Find(&recipients).Error; err != nil {
@@ -401,11 +451,35 @@ func (a *App) StartCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign has no pending recipients", nil, "")
 	}
 
-	// Update status to processing
+	// Outside business hours the campaign waits for the account to open
 	now := time.Now()
+	if campaign.BusinessHoursOnly {
+		resumeAt, err := a.campaignSendWindow(campaign, now)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Business hours do not open within the next year", nil, "")
+		}
+		if resumeAt != nil {
+			if err := a.DB.Model(campaign).Updates(map[string]interface{}{
+				"status":    models.CampaignStatusPaused,
+				"resume_at": resumeAt,
+			}).Error; err != nil {
+				a.Log.Error("Failed to schedule campaign", "error", err)
+				return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to start campaign", nil, "")
+			}
+			a.Log.Info("Campaign waiting for business hours", "campaign_id", id, "resume_at", resumeAt)
+			return r.SendEnvelope(map[string]interface{}{
+				"message":   "Outside business hours, campaign will start when they open",
+				"status":    models.CampaignStatusPaused,
+				"resume_at": resumeAt,
+			})
+		}
+	}
+
+	// Update status to processing
 	updates := map[string]interface{}{
 		"status":     models.CampaignStatusProcessing,
 		"started_at": now,
+		"resume_at":  nil,
 	}
 
 	if err := a.DB.Model(campaign).Updates(updates).Error; err != nil {
@@ -416,18 +490,7 @@ func (a *App) StartCampaign(r *fastglue.Request) error {
 	a.Log.Info("Campaign started", "campaign_id", id, "recipients", len(recipients))
 
 	// Enqueue all recipients as individual jobs for parallel processing
-	jobs := make([]*queue.RecipientJob, len(recipients))
-	for i, recipient := range recipients {
-		jobs[i] = &queue.RecipientJob{
-			CampaignID:     id,
-			RecipientID:    recipient.ID,
-			OrganizationID: orgID,
-			PhoneNumber:    recipient.PhoneNumber,
-			RecipientName:  recipient.RecipientName,
-			TemplateParams: recipient.TemplateParams,
-		}
-	}
-
+	jobs := campaignJobs(campaign, recipients)
 	if err := a.Queue.EnqueueRecipients(r.RequestCtx, jobs); err != nil {
 		a.Log.Error("Failed to enqueue recipients", "error", err)
 		// Revert status on failure
@@ -464,7 +527,11 @@ func (a *App) PauseCampaign(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Campaign is not running", nil, "")
 	}
 
-	if err := a.DB.Model(campaign).Update("status", models.CampaignStatusPaused).Error; err != nil {
+	// A manual pause is not resumed automatically
+	if err := a.DB.Model(campaign).Updates(map[string]interface{}{
+		"status":    models.CampaignStatusPaused,
+		"resume_at": nil,
+	}).Error; err != nil {
 		a.Log.Error("Failed to pause campaign", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to pause campaign", nil, "")
 	}
@@ -631,12 +698,17 @@ func (a *App) ImportRecipients(r *fastglue.Request) error {
 		return nil
 	}
 
-	// Create recipients
+	// Create recipients with phone numbers normalized to E.164
+	region := contactutil.DefaultRegion(a.DB, orgID)
 	recipients := make([]models.BulkMessageRecipient, len(req.Recipients))
 	for i, rec := range req.Recipients {
+		phone, err := phoneutil.Normalize(rec.PhoneNumber, region)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Invalid phone number '%s' at recipient %d", rec.PhoneNumber, i+1), nil, "")
+		}
 		recipients[i] = models.BulkMessageRecipient{
 			CampaignID:     id,
-			PhoneNumber:    rec.PhoneNumber,
+			PhoneNumber:    phone,
 			RecipientName:  rec.RecipientName,
 			TemplateParams: models.JSONB(rec.TemplateParams),
 			Status:         models.MessageStatusPending,
@@ -1000,6 +1072,7 @@ func (a *App) incrementCampaignStat(campaignID string, status string) {
 					"delivered_count": campaign.DeliveredCount,
 					"read_count":      campaign.ReadCount,
 					"failed_count":    campaign.FailedCount,
+					"opted_out_count": campaign.OptedOutCount,
 				},
 			})
 		}
@@ -1038,3 +1111,19 @@ func (a *App) recalculateCampaignStats(campaignID uuid.UUID) {
 	}
 }
 
+// parseCampaignSegment validates an optional segment ID for a campaign.
+// Returns nil for an empty value; sends an error envelope if the segment isn't found.
+func (a *App) parseCampaignSegment(r *fastglue.Request, orgID uuid.UUID, value *string) (*uuid.UUID, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	segmentID, err := uuid.Parse(*value)
+	if err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid segment ID", nil, "")
+		return nil, errEnvelopeSent
+	}
+	if _, err := findByIDAndOrg[models.Segment](a.DB, r, segmentID, orgID, "Segment"); err != nil {
+		return nil, err
+	}
+	return &segmentID, nil
+}
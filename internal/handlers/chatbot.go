@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/flowutil"
+	"github.com/shridarpatil/whatomate/internal/inpututil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/surveyutil"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 	"gorm.io/gorm"
@@ -40,12 +45,24 @@ type ChatbotSettingsResponse struct {
 	SLAAutoCloseMessage    string   `json:"sla_auto_close_message"`
 	SLAWarningMessage      string   `json:"sla_warning_message"`
 	SLAEscalationNotifyIDs []string `json:"sla_escalation_notify_ids"`
+	SLABusinessTime        bool     `json:"sla_business_time"`
 	// Client Inactivity Settings (Chatbot Only)
 	ClientReminderEnabled  bool   `json:"client_reminder_enabled"`
 	ClientReminderMinutes  int    `json:"client_reminder_minutes"`
 	ClientReminderMessage  string `json:"client_reminder_message"`
 	ClientAutoCloseMinutes int    `json:"client_auto_close_minutes"`
 	ClientAutoCloseMessage string `json:"client_auto_close_message"`
+	// Satisfaction Survey Settings
+	SurveyEnabled         bool                 `json:"survey_enabled"`
+	SurveyType            models.SurveyType    `json:"survey_type"`
+	SurveyChannel         models.SurveyChannel `json:"survey_channel"`
+	SurveyQuestion        string               `json:"survey_question"`
+	SurveyCommentPrompt   string               `json:"survey_comment_prompt"`
+	SurveyThankYouMessage string               `json:"survey_thank_you_message"`
+	SurveyFlowID          string               `json:"survey_flow_id"`
+	SurveyFlowCTA         string               `json:"survey_flow_cta"`
+	SurveyOnAutoClose     bool                 `json:"survey_on_auto_close"`
+	SurveyExpiryHours     int                  `json:"survey_expiry_hours"`
 }
 
 // ChatbotStatsResponse represents chatbot statistics
@@ -68,6 +85,7 @@ type KeywordRuleResponse struct {
 	MatchType       models.MatchType   `json:"match_type"`
 	ResponseType    models.ResponseType `json:"response_type"`
 	ResponseContent json.RawMessage    `json:"response_content"`
+	Conditions      string             `json:"conditions"`
 	Priority        int                `json:"priority"`
 	Enabled         bool               `json:"enabled"`
 	CreatedAt       string             `json:"created_at"`
@@ -81,6 +99,7 @@ type ChatbotFlowResponse struct {
 	TriggerKeywords []string `json:"trigger_keywords"`
 	Enabled         bool     `json:"enabled"`
 	StepsCount      int      `json:"steps_count"`
+	HasDraftChanges bool     `json:"has_draft_changes"`
 	CreatedAt       string   `json:"created_at"`
 }
 
@@ -179,12 +198,24 @@ func (a *App) GetChatbotSettings(r *fastglue.Request) error {
 		SLAAutoCloseMessage:    settings.SLA.AutoCloseMessage,
 		SLAWarningMessage:      settings.SLA.WarningMessage,
 		SLAEscalationNotifyIDs: settings.SLA.EscalationNotifyIDs,
+		SLABusinessTime:        settings.SLA.BusinessTime,
 		// Client Inactivity Settings
 		ClientReminderEnabled:  settings.ClientInactivity.ReminderEnabled,
 		ClientReminderMinutes:  settings.ClientInactivity.ReminderMinutes,
 		ClientReminderMessage:  settings.ClientInactivity.ReminderMessage,
 		ClientAutoCloseMinutes: settings.ClientInactivity.AutoCloseMinutes,
 		ClientAutoCloseMessage: settings.ClientInactivity.AutoCloseMessage,
+		// Satisfaction Survey Settings
+		SurveyEnabled:         settings.Survey.Enabled,
+		SurveyType:            settings.Survey.Type,
+		SurveyChannel:         settings.Survey.Channel,
+		SurveyQuestion:        settings.Survey.Question,
+		SurveyCommentPrompt:   settings.Survey.CommentPrompt,
+		SurveyThankYouMessage: settings.Survey.ThankYouMessage,
+		SurveyFlowID:          settings.Survey.FlowID,
+		SurveyFlowCTA:         settings.Survey.FlowCTA,
+		SurveyOnAutoClose:     settings.Survey.OnAutoClose,
+		SurveyExpiryHours:     settings.Survey.ExpiryHours,
 	}
 
 	return r.SendEnvelope(map[string]interface{}{
@@ -229,12 +260,24 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 		SLAAutoCloseMessage    *string   `json:"sla_auto_close_message"`
 		SLAWarningMessage      *string   `json:"sla_warning_message"`
 		SLAEscalationNotifyIDs *[]string `json:"sla_escalation_notify_ids"`
+		SLABusinessTime        *bool     `json:"sla_business_time"`
 		// Client Inactivity Settings
 		ClientReminderEnabled  *bool   `json:"client_reminder_enabled"`
 		ClientReminderMinutes  *int    `json:"client_reminder_minutes"`
 		ClientReminderMessage  *string `json:"client_reminder_message"`
 		ClientAutoCloseMinutes *int    `json:"client_auto_close_minutes"`
 		ClientAutoCloseMessage *string `json:"client_auto_close_message"`
+		// Satisfaction Survey Settings
+		SurveyEnabled         *bool                 `json:"survey_enabled"`
+		SurveyType            *models.SurveyType    `json:"survey_type"`
+		SurveyChannel         *models.SurveyChannel `json:"survey_channel"`
+		SurveyQuestion        *string               `json:"survey_question"`
+		SurveyCommentPrompt   *string               `json:"survey_comment_prompt"`
+		SurveyThankYouMessage *string               `json:"survey_thank_you_message"`
+		SurveyFlowID          *string               `json:"survey_flow_id"`
+		SurveyFlowCTA         *string               `json:"survey_flow_cta"`
+		SurveyOnAutoClose     *bool                 `json:"survey_on_auto_close"`
+		SurveyExpiryHours     *int                  `json:"survey_expiry_hours"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -355,6 +398,9 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 	if req.SLAEscalationNotifyIDs != nil {
 		settings.SLA.EscalationNotifyIDs = *req.SLAEscalationNotifyIDs
 	}
+	if req.SLABusinessTime != nil {
+		settings.SLA.BusinessTime = *req.SLABusinessTime
+	}
 
 	// Client Inactivity Settings
 	if req.ClientReminderEnabled != nil {
@@ -373,6 +419,46 @@ func (a *App) UpdateChatbotSettings(r *fastglue.Request) error {
 		settings.ClientInactivity.AutoCloseMessage = *req.ClientAutoCloseMessage
 	}
 
+	// Satisfaction Survey Settings
+	if isNew {
+		settings.Survey.Type = models.SurveyTypeCSAT
+		settings.Survey.Channel = models.SurveyChannelButtons
+		settings.Survey.ExpiryHours = 24
+	}
+	if req.SurveyEnabled != nil {
+		settings.Survey.Enabled = *req.SurveyEnabled
+	}
+	if req.SurveyType != nil {
+		settings.Survey.Type = *req.SurveyType
+	}
+	if req.SurveyChannel != nil {
+		settings.Survey.Channel = *req.SurveyChannel
+	}
+	if req.SurveyQuestion != nil {
+		settings.Survey.Question = *req.SurveyQuestion
+	}
+	if req.SurveyCommentPrompt != nil {
+		settings.Survey.CommentPrompt = *req.SurveyCommentPrompt
+	}
+	if req.SurveyThankYouMessage != nil {
+		settings.Survey.ThankYouMessage = *req.SurveyThankYouMessage
+	}
+	if req.SurveyFlowID != nil {
+		settings.Survey.FlowID = *req.SurveyFlowID
+	}
+	if req.SurveyFlowCTA != nil {
+		settings.Survey.FlowCTA = *req.SurveyFlowCTA
+	}
+	if req.SurveyOnAutoClose != nil {
+		settings.Survey.OnAutoClose = *req.SurveyOnAutoClose
+	}
+	if req.SurveyExpiryHours != nil {
+		settings.Survey.ExpiryHours = *req.SurveyExpiryHours
+	}
+	if err := surveyutil.Validate(&settings.Survey); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
 	if err := a.DB.Save(&settings).Error; err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save settings", nil, "")
 	}
@@ -446,6 +532,7 @@ func (a *App) ListKeywordRules(r *fastglue.Request) error {
 			MatchType:       rule.MatchType,
 			ResponseType:    rule.ResponseType,
 			ResponseContent: responseContent,
+			Conditions:      rule.Conditions,
 			Priority:        rule.Priority,
 			Enabled:         rule.IsEnabled,
 			CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
@@ -473,6 +560,7 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 		MatchType       models.MatchType       `json:"match_type"`
 		ResponseType    models.ResponseType    `json:"response_type"`
 		ResponseContent map[string]interface{} `json:"response_content"`
+		Conditions      string                 `json:"conditions"`
 		Priority        int                    `json:"priority"`
 		Enabled         bool                   `json:"enabled"`
 	}
@@ -495,6 +583,12 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 	if req.Name == "" {
 		req.Name = req.Keywords[0]
 	}
+	if err := a.validateKeywordResponse(orgID, req.ResponseType, req.ResponseContent); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	if err := validateKeywordConditions(req.Conditions); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid conditions: "+err.Error(), nil, "")
+	}
 
 	rule := models.KeywordRule{
 		BaseModel:       models.BaseModel{ID: uuid.New()},
@@ -504,6 +598,7 @@ func (a *App) CreateKeywordRule(r *fastglue.Request) error {
 		MatchType:       req.MatchType,
 		ResponseType:    req.ResponseType,
 		ResponseContent: models.JSONB(req.ResponseContent),
+		Conditions:      req.Conditions,
 		Priority:        req.Priority,
 		IsEnabled:       req.Enabled,
 	}
@@ -546,6 +641,7 @@ func (a *App) GetKeywordRule(r *fastglue.Request) error {
 		MatchType:       rule.MatchType,
 		ResponseType:    rule.ResponseType,
 		ResponseContent: responseContent,
+		Conditions:      rule.Conditions,
 		Priority:        rule.Priority,
 		Enabled:         rule.IsEnabled,
 		CreatedAt:       rule.CreatedAt.Format(time.RFC3339),
@@ -577,6 +673,7 @@ func (a *App) UpdateKeywordRule(r *fastglue.Request) error {
 		MatchType       *models.MatchType       `json:"match_type"`
 		ResponseType    *models.ResponseType    `json:"response_type"`
 		ResponseContent map[string]interface{}  `json:"response_content"`
+		Conditions      *string                 `json:"conditions"`
 		Priority        *int                    `json:"priority"`
 		Enabled         *bool                   `json:"enabled"`
 	}
@@ -601,12 +698,23 @@ func (a *App) UpdateKeywordRule(r *fastglue.Request) error {
 	if req.ResponseContent != nil {
 		rule.ResponseContent = models.JSONB(req.ResponseContent)
 	}
+	if req.Conditions != nil {
+		if err := validateKeywordConditions(*req.Conditions); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid conditions: "+err.Error(), nil, "")
+		}
+		rule.Conditions = *req.Conditions
+	}
 	if req.Priority != nil {
 		rule.Priority = *req.Priority
 	}
 	if req.Enabled != nil {
 		rule.IsEnabled = *req.Enabled
 	}
+	if req.ResponseType != nil || req.ResponseContent != nil {
+		if err := a.validateKeywordResponse(orgID, rule.ResponseType, rule.ResponseContent); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
 
 	if err := a.DB.Save(rule).Error; err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update keyword rule", nil, "")
@@ -688,6 +796,7 @@ func (a *App) ListChatbotFlows(r *fastglue.Request) error {
 			TriggerKeywords: flow.TriggerKeywords,
 			Enabled:         flow.IsEnabled,
 			StepsCount:      len(flow.Steps),
+			HasDraftChanges: flow.HasDraftChanges,
 			CreatedAt:       flow.CreatedAt.Format(time.RFC3339),
 		}
 	}
@@ -711,6 +820,11 @@ type FlowStepRequest struct {
 	ApiConfig       map[string]interface{}   `json:"api_config"`
 	Buttons         []map[string]interface{} `json:"buttons"`
 	TransferConfig  map[string]interface{}   `json:"transfer_config"`
+	TemplateID      string                   `json:"template_id"`
+	TemplateParams  map[string]interface{}   `json:"template_params"`
+	ScriptConfig    map[string]interface{}   `json:"script_config"`
+	SubflowConfig   map[string]interface{}   `json:"subflow_config"`
+	LoopConfig      map[string]interface{}   `json:"loop_config"`
 	ValidationRegex string                   `json:"validation_regex"`
 	ValidationError string                   `json:"validation_error"`
 	StoreAs         string                   `json:"store_as"`
@@ -721,6 +835,165 @@ type FlowStepRequest struct {
 	MaxRetries      int                      `json:"max_retries"`
 }
 
+// validateFlowSteps checks the configuration of template and script steps,
+// the input validation options and the conditions and templates of each
+// step, and returns the parsed template ID of each step
+func (a *App) validateFlowSteps(orgID uuid.UUID, steps []FlowStepRequest) ([]*uuid.UUID, error) {
+	templateIDs := make([]*uuid.UUID, len(steps))
+	for i, step := range steps {
+		switch step.MessageType {
+		case models.FlowStepTypeTemplate:
+			if step.TemplateID == "" {
+				return nil, fmt.Errorf("step %q needs a template_id", step.StepName)
+			}
+			templateID, err := uuid.Parse(step.TemplateID)
+			if err != nil {
+				return nil, fmt.Errorf("step %q has an invalid template_id", step.StepName)
+			}
+			var count int64
+			a.DB.Model(&models.Template{}).Where("id = ? AND organization_id = ?", templateID, orgID).Count(&count)
+			if count == 0 {
+				return nil, fmt.Errorf("step %q uses a template that was not found", step.StepName)
+			}
+			for name, value := range step.TemplateParams {
+				if _, ok := value.(string); !ok {
+					return nil, fmt.Errorf("step %q template parameter %q must be a string", step.StepName, name)
+				}
+			}
+			templateIDs[i] = &templateID
+
+		case models.FlowStepTypeScript:
+			if _, err := parseScriptAssignments(step.ScriptConfig); err != nil {
+				return nil, fmt.Errorf("step %q: %w", step.StepName, err)
+			}
+		}
+
+		if err := inpututil.ValidateConfig(step.InputType, step.InputConfig); err != nil {
+			return nil, fmt.Errorf("step %q: %w", step.StepName, err)
+		}
+		if err := validateStepExpressions(step); err != nil {
+			return nil, err
+		}
+	}
+	return templateIDs, nil
+}
+
+// buildFlowSteps converts the steps of a flow request to flow steps in the
+// order given, with the template IDs from validateFlowSteps
+func buildFlowSteps(flowID uuid.UUID, stepReqs []FlowStepRequest, templateIDs []*uuid.UUID) []models.ChatbotFlowStep {
+	steps := make([]models.ChatbotFlowStep, len(stepReqs))
+	for i, stepReq := range stepReqs {
+		// Convert buttons to JSONBArray
+		var buttons models.JSONBArray
+		for _, btn := range stepReq.Buttons {
+			buttons = append(buttons, btn)
+		}
+
+		step := models.ChatbotFlowStep{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			FlowID:          flowID,
+			StepName:        stepReq.StepName,
+			StepOrder:       i + 1,
+			Message:         stepReq.Message,
+			MessageType:     stepReq.MessageType,
+			InputType:       stepReq.InputType,
+			InputConfig:     models.JSONB(stepReq.InputConfig),
+			ApiConfig:       models.JSONB(stepReq.ApiConfig),
+			Buttons:         buttons,
+			TransferConfig:  models.JSONB(stepReq.TransferConfig),
+			TemplateID:      templateIDs[i],
+			TemplateParams:  models.JSONB(stepReq.TemplateParams),
+			ScriptConfig:    models.JSONB(stepReq.ScriptConfig),
+			SubflowConfig:   models.JSONB(stepReq.SubflowConfig),
+			LoopConfig:      models.JSONB(stepReq.LoopConfig),
+			ValidationRegex: stepReq.ValidationRegex,
+			ValidationError: stepReq.ValidationError,
+			StoreAs:         stepReq.StoreAs,
+			NextStep:        stepReq.NextStep,
+			ConditionalNext: models.JSONB(stepReq.ConditionalNext),
+			SkipCondition:   stepReq.SkipCondition,
+			RetryOnInvalid:  stepReq.RetryOnInvalid,
+			MaxRetries:      stepReq.MaxRetries,
+		}
+		if step.MessageType == "" {
+			step.MessageType = models.FlowStepTypeText
+		}
+		if step.MaxRetries == 0 {
+			step.MaxRetries = 3
+		}
+		steps[i] = step
+	}
+	return steps
+}
+
+// validateFlowGraph checks the jumps, loops and subflow calls of a flow's
+// steps, and that no flows would call each other without end
+func (a *App) validateFlowGraph(orgID, flowID uuid.UUID, flowName string, steps []models.ChatbotFlowStep) error {
+	if err := flowutil.CheckSteps(steps); err != nil {
+		return err
+	}
+
+	calls := flowutil.Calls(steps)
+	if len(calls) == 0 {
+		return nil
+	}
+
+	// The flows of the organization, as saved and as published
+	var flows []models.ChatbotFlow
+	if err := a.DB.Where("organization_id = ?", orgID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		Find(&flows).Error; err != nil {
+		return err
+	}
+	names := map[uuid.UUID]string{flowID: flowName}
+	graph := map[uuid.UUID][]flowutil.FlowCall{}
+	var versionIDs []uuid.UUID
+	for _, f := range flows {
+		if f.ID == flowID {
+			continue
+		}
+		names[f.ID] = f.Name
+		graph[f.ID] = flowutil.Calls(f.Steps)
+		if f.PublishedVersionID != nil {
+			versionIDs = append(versionIDs, *f.PublishedVersionID)
+		}
+	}
+	if len(versionIDs) > 0 {
+		var versions []models.ChatbotFlowVersion
+		if err := a.DB.Where("id IN ?", versionIDs).Find(&versions).Error; err != nil {
+			return err
+		}
+		for _, v := range versions {
+			if def, err := flowutil.Decode(v.Definition); err == nil {
+				graph[v.FlowID] = append(graph[v.FlowID], flowutil.Calls(def.Steps)...)
+			}
+		}
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		if step.MessageType != models.FlowStepTypeSubflow && step.MessageType != models.FlowStepTypeGotoFlow {
+			continue
+		}
+		target, _ := flowutil.SubflowTarget(step)
+		if _, ok := names[target]; !ok {
+			return fmt.Errorf("step %q uses a flow that was not found", step.StepName)
+		}
+	}
+	graph[flowID] = calls
+
+	if cycle := flowutil.FindCallCycle(graph, flowID); cycle != nil {
+		path := make([]string, len(cycle))
+		for i, id := range cycle {
+			path[i] = names[id]
+		}
+		return fmt.Errorf("flows would call each other without end: %s", strings.Join(path, " → "))
+	}
+	return nil
+}
+
 // CreateChatbotFlow creates a new chatbot flow
 func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 	orgID, userID, err := a.getOrgAndUserID(r)
@@ -753,10 +1026,23 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Name is required", nil, "")
 	}
 
+	if err := validateFlowTemplates(req.InitialMessage, req.CompletionMessage, req.CompletionConfig); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	templateIDs, err := a.validateFlowSteps(orgID, req.Steps)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	flowID := uuid.New()
+	steps := buildFlowSteps(flowID, req.Steps, templateIDs)
+	if err := a.validateFlowGraph(orgID, flowID, req.Name, steps); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
 	// Use transaction for flow + steps
 	tx := a.DB.Begin()
 
-	flowID := uuid.New()
 	flow := models.ChatbotFlow{
 		BaseModel:         models.BaseModel{ID: flowID},
 		OrganizationID:    orgID,
@@ -777,46 +1063,19 @@ func (a *App) CreateChatbotFlow(r *fastglue.Request) error {
 	}
 
 	// Create steps
-	for i, stepReq := range req.Steps {
-		// Convert buttons to JSONBArray
-		var buttons models.JSONBArray
-		for _, btn := range stepReq.Buttons {
-			buttons = append(buttons, btn)
-		}
-
-		step := models.ChatbotFlowStep{
-			BaseModel:       models.BaseModel{ID: uuid.New()},
-			FlowID:          flowID,
-			StepName:        stepReq.StepName,
-			StepOrder:       i + 1,
-			Message:         stepReq.Message,
-			MessageType:     stepReq.MessageType,
-			InputType:       stepReq.InputType,
-			InputConfig:     models.JSONB(stepReq.InputConfig),
-			ApiConfig:       models.JSONB(stepReq.ApiConfig),
-			Buttons:         buttons,
-			TransferConfig:  models.JSONB(stepReq.TransferConfig),
-			ValidationRegex: stepReq.ValidationRegex,
-			ValidationError: stepReq.ValidationError,
-			StoreAs:         stepReq.StoreAs,
-			NextStep:        stepReq.NextStep,
-			ConditionalNext: models.JSONB(stepReq.ConditionalNext),
-			SkipCondition:   stepReq.SkipCondition,
-			RetryOnInvalid:  stepReq.RetryOnInvalid,
-			MaxRetries:      stepReq.MaxRetries,
-		}
-		if step.MessageType == "" {
-			step.MessageType = models.FlowStepTypeText
-		}
-		if step.MaxRetries == 0 {
-			step.MaxRetries = 3
-		}
-		if err := tx.Create(&step).Error; err != nil {
+	for i := range steps {
+		if err := tx.Create(&steps[i]).Error; err != nil {
 			tx.Rollback()
 			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create flow step", nil, "")
 		}
 	}
 
+	// New flows start out published as version 1
+	if _, err := a.publishFlowVersion(tx, &flow, userID, "", 0); err != nil {
+		tx.Rollback()
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to publish flow", nil, "")
+	}
+
 	tx.Commit()
 
 	// Invalidate cache
@@ -856,7 +1115,9 @@ func (a *App) GetChatbotFlow(r *fastglue.Request) error {
 	return r.SendEnvelope(flow)
 }
 
-// UpdateChatbotFlow updates a chatbot flow
+// UpdateChatbotFlow updates the draft of a chatbot flow. Name, description
+// and enabled state apply immediately; everything else reaches contacts once
+// the draft is published.
 func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 	orgID, userID, err := a.getOrgAndUserID(r)
 	if err != nil {
@@ -888,14 +1149,51 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 		PanelConfig       map[string]interface{} `json:"panel_config"`
 		Enabled           *bool                  `json:"enabled"`
 		Steps             []FlowStepRequest      `json:"steps"`
+		Publish           bool                   `json:"publish"` // Publish the draft after saving
+		Notes             string                 `json:"notes"`   // Notes for the published version
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
 	}
 
+	var initialMessage, completionMessage string
+	if req.InitialMessage != nil {
+		initialMessage = *req.InitialMessage
+	}
+	if req.CompletionMessage != nil {
+		completionMessage = *req.CompletionMessage
+	}
+	if err := validateFlowTemplates(initialMessage, completionMessage, req.CompletionConfig); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	templateIDs, err := a.validateFlowSteps(orgID, req.Steps)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	steps := buildFlowSteps(id, req.Steps, templateIDs)
+	if len(steps) > 0 {
+		name := flow.Name
+		if req.Name != nil {
+			name = *req.Name
+		}
+		if err := a.validateFlowGraph(orgID, id, name, steps); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+	}
+
 	tx := a.DB.Begin()
 
+	// Flows from before versioning run as saved. Publish that as the first
+	// version so contacts keep running it while the edits stay in draft.
+	if flow.PublishedVersionID == nil {
+		if _, err := a.publishFlowVersion(tx, flow, userID, "", 0); err != nil {
+			tx.Rollback()
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to publish flow", nil, "")
+		}
+	}
+
 	if req.Name != nil {
 		flow.Name = *req.Name
 	}
@@ -930,7 +1228,7 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 	}
 
 	// Update steps if provided
-	if len(req.Steps) > 0 {
+	if len(steps) > 0 {
 		// Delete existing steps
 		if err := tx.Where("flow_id = ?", id).Delete(&models.ChatbotFlowStep{}).Error; err != nil {
 			tx.Rollback()
@@ -938,54 +1236,32 @@ func (a *App) UpdateChatbotFlow(r *fastglue.Request) error {
 		}
 
 		// Create new steps
-		for i, stepReq := range req.Steps {
-			// Convert buttons to JSONBArray
-			var buttons models.JSONBArray
-			for _, btn := range stepReq.Buttons {
-				buttons = append(buttons, btn)
-			}
-
-			step := models.ChatbotFlowStep{
-				BaseModel:       models.BaseModel{ID: uuid.New()},
-				FlowID:          id,
-				StepName:        stepReq.StepName,
-				StepOrder:       i + 1,
-				Message:         stepReq.Message,
-				MessageType:     stepReq.MessageType,
-				InputType:       stepReq.InputType,
-				InputConfig:     models.JSONB(stepReq.InputConfig),
-				ApiConfig:       models.JSONB(stepReq.ApiConfig),
-				Buttons:         buttons,
-				TransferConfig:  models.JSONB(stepReq.TransferConfig),
-				ValidationRegex: stepReq.ValidationRegex,
-				ValidationError: stepReq.ValidationError,
-				StoreAs:         stepReq.StoreAs,
-				NextStep:        stepReq.NextStep,
-				ConditionalNext: models.JSONB(stepReq.ConditionalNext),
-				SkipCondition:   stepReq.SkipCondition,
-				RetryOnInvalid:  stepReq.RetryOnInvalid,
-				MaxRetries:      stepReq.MaxRetries,
-			}
-			if step.MessageType == "" {
-				step.MessageType = models.FlowStepTypeText
-			}
-			if step.MaxRetries == 0 {
-				step.MaxRetries = 3
-			}
-			if err := tx.Create(&step).Error; err != nil {
+		for i := range steps {
+			if err := tx.Create(&steps[i]).Error; err != nil {
 				tx.Rollback()
 				return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create flow step", nil, "")
 			}
 		}
 	}
 
+	if req.Publish {
+		_, err = a.publishFlowVersion(tx, flow, userID, req.Notes, 0)
+	} else {
+		err = a.refreshFlowDraftChanges(tx, flow)
+	}
+	if err != nil {
+		tx.Rollback()
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update flow", nil, "")
+	}
+
 	tx.Commit()
 
 	// Invalidate cache
 	a.InvalidateChatbotFlowsCache(orgID)
 
 	return r.SendEnvelope(map[string]interface{}{
-		"message": "Flow updated successfully",
+		"message":           "Flow updated successfully",
+		"has_draft_changes": flow.HasDraftChanges,
 	})
 }
 
@@ -1014,6 +1290,18 @@ func (a *App) DeleteChatbotFlow(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete flow steps", nil, "")
 	}
 
+	// Delete the flow's test cases
+	if err := tx.Where("flow_id = ? AND organization_id = ?", id, orgID).Delete(&models.ChatbotFlowTestCase{}).Error; err != nil {
+		tx.Rollback()
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete flow test cases", nil, "")
+	}
+
+	// Delete the flow's versions
+	if err := tx.Where("flow_id = ? AND organization_id = ?", id, orgID).Delete(&models.ChatbotFlowVersion{}).Error; err != nil {
+		tx.Rollback()
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete flow versions", nil, "")
+	}
+
 	// Delete flow
 	result := tx.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.ChatbotFlow{})
 	if result.Error != nil {
@@ -1252,14 +1540,33 @@ func (a *App) ListChatbotSessions(r *fastglue.Request) error {
 	}
 
 	status := string(r.RequestCtx.QueryArgs().Peek("status"))
+	flowID := string(r.RequestCtx.QueryArgs().Peek("flow_id"))
+	flowVersionID := string(r.RequestCtx.QueryArgs().Peek("flow_version_id"))
 
 	query := a.DB.Where("organization_id = ?", orgID).
 		Preload("Contact").
+		Preload("FlowVersion", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "flow_id", "version")
+		}).
 		Order("last_activity_at DESC")
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	if flowID != "" {
+		id, err := uuid.Parse(flowID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid flow_id", nil, "")
+		}
+		query = query.Where("current_flow_id = ?", id)
+	}
+	if flowVersionID != "" {
+		id, err := uuid.Parse(flowVersionID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid flow_version_id", nil, "")
+		}
+		query = query.Where("flow_version_id = ?", id)
+	}
 
 	var sessions []models.ChatbotSession
 	if err := query.Limit(100).Find(&sessions).Error; err != nil {
@@ -1287,6 +1594,9 @@ func (a *App) GetChatbotSession(r *fastglue.Request) error {
 	if err := a.DB.Where("id = ? AND organization_id = ?", id, orgID).
 		Preload("Contact").
 		Preload("Messages").
+		Preload("FlowVersion", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "flow_id", "version")
+		}).
 		First(&session).Error; err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Session not found", nil, "")
 	}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// FlowTestCaseRequest is the body for creating or updating a flow test case
+type FlowTestCaseRequest struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Contact     SimulatorContact   `json:"contact"`
+	Turns       []SimulatorTurn    `json:"turns"`
+	APIMocks    []SimulatorAPIMock `json:"api_mocks"`
+}
+
+// FlowTestCaseResult is the outcome of running a stored test case
+type FlowTestCaseResult struct {
+	TestCaseID uuid.UUID         `json:"test_case_id"`
+	Name       string            `json:"name"`
+	Passed     bool              `json:"passed"`
+	Failures   []string          `json:"failures"`
+	Result     *SimulationResult `json:"result"`
+}
+
+// SimulateChatbotFlow runs a flow against a virtual contact and returns what
+// the bot did on each turn. Nothing is sent or saved.
+func (a *App) SimulateChatbotFlow(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	if _, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow"); err != nil {
+		return nil
+	}
+
+	var req SimulateFlowRequest
+	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+	if err := validateSimulatorInput(req.Turns, req.APIMocks); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	result, err := a.simulateFlow(orgID, flowID, req)
+	if err != nil {
+		a.Log.Error("Failed to simulate flow", "error", err, "flow_id", flowID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to simulate flow", nil, "")
+	}
+
+	return r.SendEnvelope(result)
+}
+
+// ListChatbotFlowTestCases lists the test cases of a flow
+func (a *App) ListChatbotFlowTestCases(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+
+	var testCases []models.ChatbotFlowTestCase
+	if err := a.DB.Where("flow_id = ? AND organization_id = ?", flowID, orgID).
+		Order("created_at ASC").
+		Find(&testCases).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list test cases", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"test_cases": testCases,
+	})
+}
+
+// CreateChatbotFlowTestCase stores a scripted conversation for a flow
+func (a *App) CreateChatbotFlowTestCase(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	if _, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow"); err != nil {
+		return nil
+	}
+
+	var req FlowTestCaseRequest
+	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+	if req.Name == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Name is required", nil, "")
+	}
+	if err := validateSimulatorInput(req.Turns, req.APIMocks); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	testCase := models.ChatbotFlowTestCase{
+		OrganizationID: orgID,
+		FlowID:         flowID,
+		LastFailures:   models.StringArray{},
+	}
+	applyFlowTestCaseRequest(&testCase, req)
+	if err := a.DB.Create(&testCase).Error; err != nil {
+		a.Log.Error("Failed to create flow test case", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create test case", nil, "")
+	}
+
+	return r.SendEnvelope(testCase)
+}
+
+// UpdateChatbotFlowTestCase replaces a flow test case
+func (a *App) UpdateChatbotFlowTestCase(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	testCase, err := a.findFlowTestCase(r, orgID)
+	if err != nil {
+		return nil
+	}
+
+	var req FlowTestCaseRequest
+	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+	if req.Name == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Name is required", nil, "")
+	}
+	if err := validateSimulatorInput(req.Turns, req.APIMocks); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	applyFlowTestCaseRequest(testCase, req)
+	// The last result no longer describes this script
+	testCase.LastRunAt = nil
+	testCase.LastPassed = nil
+	testCase.LastFailures = models.StringArray{}
+	if err := a.DB.Save(testCase).Error; err != nil {
+		a.Log.Error("Failed to update flow test case", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update test case", nil, "")
+	}
+
+	return r.SendEnvelope(testCase)
+}
+
+// DeleteChatbotFlowTestCase deletes a flow test case
+func (a *App) DeleteChatbotFlowTestCase(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	testCase, err := a.findFlowTestCase(r, orgID)
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Delete(testCase).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete test case", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"message": "Test case deleted successfully",
+	})
+}
+
+// RunChatbotFlowTestCases runs the test cases of a flow and records their
+// results. Pass test_id to run a single test case.
+func (a *App) RunChatbotFlowTestCases(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	if _, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow"); err != nil {
+		return nil
+	}
+
+	query := a.DB.Where("flow_id = ? AND organization_id = ?", flowID, orgID)
+	if testID := string(r.RequestCtx.QueryArgs().Peek("test_id")); testID != "" {
+		id, err := uuid.Parse(testID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid test case ID", nil, "")
+		}
+		query = query.Where("id = ?", id)
+	}
+
+	var testCases []models.ChatbotFlowTestCase
+	if err := query.Order("created_at ASC").Find(&testCases).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load test cases", nil, "")
+	}
+
+	results := make([]FlowTestCaseResult, 0, len(testCases))
+	passed := 0
+	for i := range testCases {
+		result, err := a.runFlowTestCase(orgID, &testCases[i])
+		if err != nil {
+			a.Log.Error("Failed to run flow test case", "error", err, "test_case_id", testCases[i].ID)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to run test cases", nil, "")
+		}
+		if result.Passed {
+			passed++
+		}
+		results = append(results, *result)
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"total":   len(results),
+		"passed":  passed,
+		"failed":  len(results) - passed,
+		"results": results,
+	})
+}
+
+// runFlowTestCase simulates a stored test case and saves the outcome on it
+func (a *App) runFlowTestCase(orgID uuid.UUID, testCase *models.ChatbotFlowTestCase) (*FlowTestCaseResult, error) {
+	var req SimulateFlowRequest
+	if err := convertJSON(testCase.Contact, &req.Contact); err != nil {
+		return nil, fmt.Errorf("invalid contact: %w", err)
+	}
+	if err := convertJSON(testCase.Turns, &req.Turns); err != nil {
+		return nil, fmt.Errorf("invalid turns: %w", err)
+	}
+	if err := convertJSON(testCase.APIMocks, &req.APIMocks); err != nil {
+		return nil, fmt.Errorf("invalid api_mocks: %w", err)
+	}
+
+	result, err := a.simulateFlow(orgID, testCase.FlowID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	a.DB.Model(testCase).Updates(map[string]interface{}{
+		"last_run_at":   now,
+		"last_passed":   result.Passed,
+		"last_failures": models.StringArray(result.Failures),
+	})
+
+	return &FlowTestCaseResult{
+		TestCaseID: testCase.ID,
+		Name:       testCase.Name,
+		Passed:     result.Passed,
+		Failures:   result.Failures,
+		Result:     result,
+	}, nil
+}
+
+// findFlowTestCase loads the test case named by the test_id path parameter
+// of the flow named by id
+func (a *App) findFlowTestCase(r *fastglue.Request, orgID uuid.UUID) (*models.ChatbotFlowTestCase, error) {
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil, err
+	}
+	testID, err := parsePathUUID(r, "test_id", "test case")
+	if err != nil {
+		return nil, err
+	}
+
+	var testCase models.ChatbotFlowTestCase
+	if err := a.DB.Where("id = ? AND flow_id = ? AND organization_id = ?", testID, flowID, orgID).First(&testCase).Error; err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusNotFound, "Test case not found", nil, "")
+		return nil, errEnvelopeSent
+	}
+	return &testCase, nil
+}
+
+// validateSimulatorInput checks the turns and API mocks of a simulation
+func validateSimulatorInput(turns []SimulatorTurn, mocks []SimulatorAPIMock) error {
+	if len(turns) > 100 {
+		return fmt.Errorf("at most 100 turns are allowed")
+	}
+	for i, turn := range turns {
+		if turn.Media != nil {
+			switch turn.Media.Type {
+			case models.InputTypeImage, models.InputTypeDocument, models.InputTypeAudio, models.InputTypeVideo:
+			default:
+				return fmt.Errorf("turn %d: media type must be image, document, audio or video", i+1)
+			}
+			if turn.Location != nil {
+				return fmt.Errorf("turn %d: a turn cannot send both media and a location", i+1)
+			}
+		}
+	}
+	for i, mock := range mocks {
+		if mock.URL == "" {
+			return fmt.Errorf("api mock %d: url is required", i+1)
+		}
+		if mock.Status != 0 && (mock.Status < 100 || mock.Status > 599) {
+			return fmt.Errorf("api mock %d: invalid status %d", i+1, mock.Status)
+		}
+	}
+	return nil
+}
+
+func applyFlowTestCaseRequest(testCase *models.ChatbotFlowTestCase, req FlowTestCaseRequest) {
+	testCase.Name = req.Name
+	testCase.Description = req.Description
+	testCase.Contact = models.JSONB{}
+	testCase.Turns = models.JSONBArray{}
+	testCase.APIMocks = models.JSONBArray{}
+	_ = convertJSON(req.Contact, &testCase.Contact)
+	if len(req.Turns) > 0 {
+		_ = convertJSON(req.Turns, &testCase.Turns)
+	}
+	if len(req.APIMocks) > 0 {
+		_ = convertJSON(req.APIMocks, &testCase.APIMocks)
+	}
+}
+
+// convertJSON copies src into dst through their JSON form, e.g. to read a
+// JSONB column into a typed value
+func convertJSON(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/flowutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FlowVersionSessionStats counts the sessions that ran a flow version
+type FlowVersionSessionStats struct {
+	Total     int64 `json:"total"`
+	Active    int64 `json:"active"`
+	Completed int64 `json:"completed"`
+	Cancelled int64 `json:"cancelled"`
+	Timeout   int64 `json:"timeout"`
+}
+
+// FlowVersionResponse summarizes a published flow version
+type FlowVersionResponse struct {
+	ID                  uuid.UUID               `json:"id"`
+	Version             int                     `json:"version"`
+	Notes               string                  `json:"notes"`
+	PublishedByID       *uuid.UUID              `json:"published_by_id,omitempty"`
+	PublishedByName     string                  `json:"published_by_name,omitempty"`
+	RestoredFromVersion int                     `json:"restored_from_version,omitempty"`
+	IsPublished         bool                    `json:"is_published"`
+	Sessions            FlowVersionSessionStats `json:"sessions"`
+	CreatedAt           time.Time               `json:"created_at"`
+}
+
+// FlowVersionRef identifies one side of a flow diff: a version or the draft
+type FlowVersionRef struct {
+	Draft     bool       `json:"draft"`
+	VersionID *uuid.UUID `json:"version_id,omitempty"`
+	Version   int        `json:"version,omitempty"`
+}
+
+// loadFlowDraft loads a flow with its saved steps in order
+func loadFlowDraft(db *gorm.DB, orgID, flowID uuid.UUID) (*models.ChatbotFlow, error) {
+	var flow models.ChatbotFlow
+	if err := db.Where("id = ? AND organization_id = ?", flowID, orgID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		First(&flow).Error; err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
+
+// publishFlowVersion snapshots the saved draft of a flow as its next version
+// and makes it the version new sessions start on
+func (a *App) publishFlowVersion(tx *gorm.DB, flow *models.ChatbotFlow, userID uuid.UUID, notes string, restoredFrom int) (*models.ChatbotFlowVersion, error) {
+	draft, err := loadFlowDraft(tx, flow.OrganizationID, flow.ID)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := flowutil.Encode(flowutil.Snapshot(draft))
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the flow so concurrent publishes get distinct version numbers
+	var locked models.ChatbotFlow
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", flow.ID).Error; err != nil {
+		return nil, err
+	}
+	var latest int
+	if err := tx.Model(&models.ChatbotFlowVersion{}).Where("flow_id = ?", flow.ID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	version := &models.ChatbotFlowVersion{
+		BaseModel:           models.BaseModel{ID: uuid.New()},
+		OrganizationID:      flow.OrganizationID,
+		FlowID:              flow.ID,
+		Version:             latest + 1,
+		Definition:          definition,
+		Notes:               notes,
+		RestoredFromVersion: restoredFrom,
+	}
+	if userID != uuid.Nil {
+		version.PublishedByID = &userID
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return nil, err
+	}
+
+	// Sessions that started before the flow was first published ran the saved
+	// flow, which is exactly what was just captured
+	if flow.PublishedVersionID == nil {
+		if err := tx.Model(&models.ChatbotSession{}).
+			Where("current_flow_id = ? AND flow_version_id IS NULL AND status = ?", flow.ID, models.SessionStatusActive).
+			Update("flow_version_id", version.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Model(&models.ChatbotFlow{}).Where("id = ?", flow.ID).Updates(map[string]interface{}{
+		"published_version_id": version.ID,
+		"has_draft_changes":    false,
+	}).Error; err != nil {
+		return nil, err
+	}
+	flow.PublishedVersionID = &version.ID
+	flow.HasDraftChanges = false
+
+	return version, nil
+}
+
+// flowDraftDiff compares the published version of a flow with its saved draft
+func (a *App) flowDraftDiff(db *gorm.DB, flow *models.ChatbotFlow) (flowutil.Diff, error) {
+	draft, err := loadFlowDraft(db, flow.OrganizationID, flow.ID)
+	if err != nil {
+		return flowutil.Diff{}, err
+	}
+	var published flowutil.Definition
+	if flow.PublishedVersionID != nil {
+		var version models.ChatbotFlowVersion
+		if err := db.Where("id = ?", *flow.PublishedVersionID).First(&version).Error; err != nil {
+			return flowutil.Diff{}, err
+		}
+		if published, err = flowutil.Decode(version.Definition); err != nil {
+			return flowutil.Diff{}, err
+		}
+	}
+	return flowutil.Compare(published, flowutil.Snapshot(draft)), nil
+}
+
+// refreshFlowDraftChanges records whether the draft differs from the published version
+func (a *App) refreshFlowDraftChanges(tx *gorm.DB, flow *models.ChatbotFlow) error {
+	diff, err := a.flowDraftDiff(tx, flow)
+	if err != nil {
+		return err
+	}
+	flow.HasDraftChanges = !diff.Empty()
+	return tx.Model(&models.ChatbotFlow{}).Where("id = ?", flow.ID).
+		Update("has_draft_changes", flow.HasDraftChanges).Error
+}
+
+// restoreFlowDraft replaces the draft of a flow with a version's definition
+func restoreFlowDraft(tx *gorm.DB, flow *models.ChatbotFlow, def flowutil.Definition) error {
+	draft := *flow
+	flowutil.Apply(&draft, def)
+	if err := tx.Omit(clause.Associations).Save(&draft).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("flow_id = ?", flow.ID).Delete(&models.ChatbotFlowStep{}).Error; err != nil {
+		return err
+	}
+	for i, step := range draft.Steps {
+		// Deleted steps keep their IDs, so restored steps need new ones
+		step.BaseModel = models.BaseModel{ID: uuid.New()}
+		step.StepOrder = i + 1
+		retryOnInvalid := step.RetryOnInvalid
+		if err := tx.Create(&step).Error; err != nil {
+			return err
+		}
+		// A false value would otherwise be replaced by the column default
+		if !retryOnInvalid {
+			if err := tx.Model(&step).Update("retry_on_invalid", false).Error; err != nil {
+				return err
+			}
+		}
+	}
+	*flow = draft
+	return nil
+}
+
+// resolveFlowVersionRef reads one side of a flow diff. A ref is "draft",
+// "published", a version number or a version ID.
+func (a *App) resolveFlowVersionRef(flow *models.ChatbotFlow, ref string) (FlowVersionRef, flowutil.Definition, error) {
+	if ref == "draft" {
+		draft, err := loadFlowDraft(a.DB, flow.OrganizationID, flow.ID)
+		if err != nil {
+			return FlowVersionRef{}, flowutil.Definition{}, err
+		}
+		return FlowVersionRef{Draft: true}, flowutil.Snapshot(draft), nil
+	}
+
+	query := a.DB.Where("flow_id = ? AND organization_id = ?", flow.ID, flow.OrganizationID)
+	if ref == "" || ref == "published" {
+		if flow.PublishedVersionID == nil {
+			return FlowVersionRef{}, flowutil.Definition{}, errors.New("the flow has not been published")
+		}
+		query = query.Where("id = ?", *flow.PublishedVersionID)
+	} else if n, err := strconv.Atoi(ref); err == nil {
+		query = query.Where("version = ?", n)
+	} else if id, err := uuid.Parse(ref); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		return FlowVersionRef{}, flowutil.Definition{}, fmt.Errorf("invalid version %q", ref)
+	}
+
+	var version models.ChatbotFlowVersion
+	if err := query.First(&version).Error; err != nil {
+		return FlowVersionRef{}, flowutil.Definition{}, fmt.Errorf("version %q not found", ref)
+	}
+	def, err := flowutil.Decode(version.Definition)
+	if err != nil {
+		return FlowVersionRef{}, flowutil.Definition{}, err
+	}
+	return FlowVersionRef{VersionID: &version.ID, Version: version.Version}, def, nil
+}
+
+// PublishChatbotFlow publishes the draft of a flow as a new version. Sessions
+// already in the flow stay on the version they started on.
+func (a *App) PublishChatbotFlow(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if body := r.RequestCtx.PostBody(); len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+		}
+	}
+
+	if flow.PublishedVersionID != nil {
+		diff, err := a.flowDraftDiff(a.DB, flow)
+		if err != nil {
+			a.Log.Error("Failed to compare flow draft", "error", err, "flow_id", flowID)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to publish flow", nil, "")
+		}
+		if diff.Empty() {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "The flow has no unpublished changes", nil, "")
+		}
+	}
+
+	tx := a.DB.Begin()
+	version, err := a.publishFlowVersion(tx, flow, userID, req.Notes, 0)
+	if err != nil {
+		tx.Rollback()
+		a.Log.Error("Failed to publish flow", "error", err, "flow_id", flowID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to publish flow", nil, "")
+	}
+	tx.Commit()
+
+	a.InvalidateChatbotFlowsCache(orgID)
+
+	return r.SendEnvelope(map[string]interface{}{
+		"id":      version.ID,
+		"version": version.Version,
+		"message": "Flow published successfully",
+	})
+}
+
+// ListChatbotFlowVersions lists the published versions of a flow, newest
+// first, with the sessions that ran each version
+func (a *App) ListChatbotFlowVersions(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	var versions []models.ChatbotFlowVersion
+	if err := a.DB.Where("flow_id = ? AND organization_id = ?", flowID, orgID).
+		Omit("definition").
+		Preload("PublishedBy", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "full_name")
+		}).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list flow versions", nil, "")
+	}
+
+	var counts []struct {
+		FlowVersionID uuid.UUID
+		Status        models.SessionStatus
+		Count         int64
+	}
+	a.DB.Model(&models.ChatbotSession{}).
+		Select("flow_version_id, status, COUNT(*) AS count").
+		Where("organization_id = ? AND flow_version_id IN (?)", orgID,
+			a.DB.Model(&models.ChatbotFlowVersion{}).Select("id").Where("flow_id = ?", flowID)).
+		Group("flow_version_id, status").
+		Scan(&counts)
+	stats := make(map[uuid.UUID]*FlowVersionSessionStats)
+	for _, c := range counts {
+		s := stats[c.FlowVersionID]
+		if s == nil {
+			s = &FlowVersionSessionStats{}
+			stats[c.FlowVersionID] = s
+		}
+		s.Total += c.Count
+		switch c.Status {
+		case models.SessionStatusActive:
+			s.Active += c.Count
+		case models.SessionStatusCompleted:
+			s.Completed += c.Count
+		case models.SessionStatusCancelled:
+			s.Cancelled += c.Count
+		case models.SessionStatusTimeout:
+			s.Timeout += c.Count
+		}
+	}
+
+	response := make([]FlowVersionResponse, len(versions))
+	for i, v := range versions {
+		response[i] = FlowVersionResponse{
+			ID:                  v.ID,
+			Version:             v.Version,
+			Notes:               v.Notes,
+			PublishedByID:       v.PublishedByID,
+			RestoredFromVersion: v.RestoredFromVersion,
+			IsPublished:         flow.PublishedVersionID != nil && *flow.PublishedVersionID == v.ID,
+			CreatedAt:           v.CreatedAt,
+		}
+		if v.PublishedBy != nil {
+			response[i].PublishedByName = v.PublishedBy.FullName
+		}
+		if s := stats[v.ID]; s != nil {
+			response[i].Sessions = *s
+		}
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"versions":          response,
+		"has_draft_changes": flow.HasDraftChanges,
+	})
+}
+
+// GetChatbotFlowVersion gets a published flow version with its definition
+func (a *App) GetChatbotFlowVersion(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	versionID, err := parsePathUUID(r, "version_id", "version")
+	if err != nil {
+		return nil
+	}
+
+	var version models.ChatbotFlowVersion
+	if err := a.DB.Where("id = ? AND flow_id = ? AND organization_id = ?", versionID, flowID, orgID).
+		First(&version).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Version not found", nil, "")
+	}
+
+	return r.SendEnvelope(version)
+}
+
+// DiffChatbotFlowVersions compares two versions of a flow. The from and to
+// query parameters take "draft", "published", a version number or a version
+// ID, and default to the published version and the draft.
+func (a *App) DiffChatbotFlowVersions(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	fromParam := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toParam := string(r.RequestCtx.QueryArgs().Peek("to"))
+	if toParam == "" {
+		toParam = "draft"
+	}
+
+	fromRef, from, err := a.resolveFlowVersionRef(flow, fromParam)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	toRef, to, err := a.resolveFlowVersionRef(flow, toParam)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"from": fromRef,
+		"to":   toRef,
+		"diff": flowutil.Compare(from, to),
+	})
+}
+
+// RollbackChatbotFlow restores a flow to an earlier version by publishing a
+// copy of it as a new version. Rolling back to the published version discards
+// the draft.
+func (a *App) RollbackChatbotFlow(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceFlowsChatbot, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Permission denied", nil, "")
+	}
+
+	flowID, err := parsePathUUID(r, "id", "flow")
+	if err != nil {
+		return nil
+	}
+	versionID, err := parsePathUUID(r, "version_id", "version")
+	if err != nil {
+		return nil
+	}
+	flow, err := findByIDAndOrg[models.ChatbotFlow](a.DB, r, flowID, orgID, "Flow")
+	if err != nil {
+		return nil
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if body := r.RequestCtx.PostBody(); len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+		}
+	}
+
+	var target models.ChatbotFlowVersion
+	if err := a.DB.Where("id = ? AND flow_id = ? AND organization_id = ?", versionID, flowID, orgID).
+		First(&target).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Version not found", nil, "")
+	}
+	def, err := flowutil.Decode(target.Definition)
+	if err != nil {
+		a.Log.Error("Failed to decode flow version", "error", err, "version_id", versionID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to roll back flow", nil, "")
+	}
+	// Other flows may have changed since the version was published
+	if err := a.validateFlowGraph(orgID, flowID, flow.Name, def.Steps); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	tx := a.DB.Begin()
+	if err := restoreFlowDraft(tx, flow, def); err != nil {
+		tx.Rollback()
+		a.Log.Error("Failed to restore flow draft", "error", err, "flow_id", flowID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to roll back flow", nil, "")
+	}
+
+	published := target
+	if flow.PublishedVersionID != nil && *flow.PublishedVersionID == target.ID {
+		err = tx.Model(&models.ChatbotFlow{}).Where("id = ?", flowID).Update("has_draft_changes", false).Error
+	} else {
+		notes := req.Notes
+		if notes == "" {
+			notes = fmt.Sprintf("Rolled back to version %d", target.Version)
+		}
+		var version *models.ChatbotFlowVersion
+		if version, err = a.publishFlowVersion(tx, flow, userID, notes, target.Version); err == nil {
+			published = *version
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		a.Log.Error("Failed to roll back flow", "error", err, "flow_id", flowID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to roll back flow", nil, "")
+	}
+	tx.Commit()
+
+	a.InvalidateChatbotFlowsCache(orgID)
+
+	return r.SendEnvelope(map[string]interface{}{
+		"id":      published.ID,
+		"version": published.Version,
+		"message": fmt.Sprintf("Flow rolled back to version %d", target.Version),
+	})
+}
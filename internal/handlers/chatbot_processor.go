@@ -12,8 +12,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
 	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/exprutil"
+	"github.com/shridarpatil/whatomate/internal/inpututil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/skillutil"
+	"github.com/shridarpatil/whatomate/internal/templateutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
 )
@@ -281,9 +286,20 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	}
 	a.saveIncomingMessage(account, contact, msg.ID, messageType, messageText, mediaInfo, replyToWAMID)
 
+	// Messages from blocked contacts are kept for the record only
+	if contact.IsBlocked {
+		a.Log.Info("Contact is blocked, skipping chatbot processing", "contact_id", contact.ID)
+		return
+	}
+
 	// Clear chatbot tracking since client has replied
 	a.ClearContactChatbotTracking(contact.ID)
 
+	// Opt-out/opt-in keywords are handled before transfers and the chatbot
+	if (messageType == "text" || messageType == "button_reply") && a.handleConsentKeyword(account, contact, msg.ID, messageText) {
+		return
+	}
+
 	// Check for active agent transfer - skip chatbot processing if transferred
 	if a.hasActiveAgentTransfer(account.OrganizationID, contact.ID) {
 		a.Log.Info("Contact has active agent transfer, skipping chatbot processing",
@@ -292,6 +308,11 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 		return
 	}
 
+	// Answers to a satisfaction survey don't reach the chatbot
+	if a.handleSurveyReply(account, contact, messageType, messageText, buttonID, flowResponseData) {
+		return
+	}
+
 	// Check if chatbot is enabled for this account (use cache)
 	settings, err := a.getChatbotSettingsCached(account.OrganizationID, account.Name)
 	if err != nil {
@@ -301,14 +322,14 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	if !settings.IsEnabled {
 		a.Log.Debug("Chatbot not enabled for this account, creating transfer for agent queue", "account", account.Name, "settings_id", settings.ID)
 		// Create transfer to agent queue when chatbot is disabled
-		a.createTransferToQueue(account, contact, models.TransferSourceChatbotDisabled)
+		a.createTransferToQueue(account, contact, models.TransferSourceChatbotDisabled, nil)
 		return
 	}
 	a.Log.Info("Chatbot settings loaded", "settings_id", settings.ID, "is_enabled", settings.IsEnabled, "ai_enabled", settings.AI.Enabled, "ai_provider", settings.AI.Provider, "default_response", settings.DefaultResponse)
 
 	// Check business hours if enabled
 	if settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 {
-		if !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
+		if !a.isWithinBusinessHours(settings, account.Name) {
 			// If automated responses are not allowed outside hours, send out-of-hours message and stop
 			if !settings.BusinessHours.AllowAutomatedOutside {
 				a.Log.Info("Outside business hours, sending out of hours message")
@@ -324,8 +345,19 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 		}
 	}
 
-	// Only process text and interactive messages for chatbot
+	// Media and locations can answer flow steps
+	media := flowMediaFromMessage(msg, mediaInfo, messageText)
+
+	// Only process text and interactive messages for chatbot, except media
+	// sent to a flow step waiting for it
 	if messageText == "" {
+		if media != nil {
+			if session := a.findActiveSession(account.OrganizationID, contact.ID, account.Name, settings.SessionTimeoutMins); session != nil && session.CurrentFlowID != nil {
+				a.logSessionMessage(session.ID, models.DirectionIncoming, "["+msg.Type+"]", session.CurrentStep)
+				a.processFlowResponse(account, session, contact, "", "", nil, media)
+				return
+			}
+		}
 		a.Log.Debug("Skipping message with no text content for chatbot", "type", msg.Type)
 		return
 	}
@@ -339,12 +371,12 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	a.logSessionMessage(session.ID, models.DirectionIncoming, messageText, "keyword_check")
 
 	// Check for transfer keyword BEFORE sending greeting (transfer takes priority)
-	keywordResponse, keywordMatched := a.matchKeywordRules(account.OrganizationID, account.Name, messageText)
+	keywordResponse, keywordMatched := a.matchKeywordRules(account.OrganizationID, account.Name, messageText, keywordConditionData(session, contact))
 	if keywordMatched && keywordResponse.ResponseType == models.ResponseTypeTransfer {
 		a.Log.Info("Transfer keyword matched", "response", keywordResponse.Body)
 		// Check business hours - if outside hours, send out of hours message instead
 		if settings.BusinessHours.Enabled && len(settings.BusinessHours.Hours) > 0 {
-			if !a.isWithinBusinessHours(settings.BusinessHours.Hours) {
+			if !a.isWithinBusinessHours(settings, account.Name) {
 				a.Log.Info("Outside business hours, sending out of hours message instead of transfer")
 				if settings.BusinessHours.OutOfHoursMessage != "" {
 					if err := a.sendAndSaveTextMessage(account, contact, settings.BusinessHours.OutOfHoursMessage); err != nil {
@@ -360,13 +392,13 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 				a.Log.Error("Failed to send transfer message", "error", err, "contact", contact.PhoneNumber)
 			}
 		}
-		a.createTransferFromKeyword(account, contact)
+		a.createTransferFromKeyword(account, contact, keywordResponse.RequiredSkills)
 		return
 	}
 
 	// Check if user is in an active flow
 	if session.CurrentFlowID != nil {
-		a.processFlowResponse(account, session, contact, messageText, buttonID, flowResponseData)
+		a.processFlowResponse(account, session, contact, messageText, buttonID, flowResponseData, media)
 		return
 	}
 
@@ -408,6 +440,17 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 	if keywordMatched && keywordResponse.ResponseType != models.ResponseTypeTransfer {
 		a.Log.Info("Keyword rule matched", "response_type", keywordResponse.ResponseType, "response", keywordResponse.Body)
 
+		if keywordResponse.ResponseType != models.ResponseTypeText && keywordResponse.Content != nil {
+			logged, err := a.sendKeywordResponse(account, session, contact, keywordResponse)
+			if err != nil {
+				a.Log.Error("Failed to send keyword response", "error", err, "response_type", keywordResponse.ResponseType, "contact", contact.PhoneNumber)
+			}
+			if logged != "" {
+				a.logSessionMessage(session.ID, models.DirectionOutgoing, logged, "keyword_response")
+			}
+			return
+		}
+
 		// Handle regular text response
 		if len(keywordResponse.Buttons) > 0 {
 			if err := a.sendAndSaveInteractiveButtons(account, contact, keywordResponse.Body, keywordResponse.Buttons); err != nil {
@@ -479,11 +522,15 @@ func (a *App) processIncomingMessageFull(phoneNumberID string, msg IncomingTextM
 type KeywordResponse struct {
 	Body         string
 	Buttons      []map[string]interface{}
-	ResponseType models.ResponseType // text, transfer
+	ResponseType models.ResponseType // text, transfer, template, media, flow, script
+	RequiredSkills models.SkillRequirements // Skills to route a transfer on
+	Content      models.JSONB // Raw response content for template, media, flow and script responses
 }
 
-// matchKeywordRules checks if the message matches any keyword rules
-func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string) (*KeywordResponse, bool) {
+// matchKeywordRules checks if the message matches any keyword rules. Rules
+// with conditions only match when their conditions hold for data, which can
+// also read the message as message.
+func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string, data map[string]interface{}) (*KeywordResponse, bool) {
 	// Use cached keyword rules (includes both account-specific and global rules)
 	rules, err := a.getKeywordRulesCached(orgID, accountName)
 	if err != nil {
@@ -492,8 +539,13 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 	}
 
 	messageLower := strings.ToLower(messageText)
+	conditionData := copyMap(data)
+	conditionData["message"] = messageText
 
 	for _, rule := range rules {
+		if strings.TrimSpace(rule.Conditions) != "" && !evaluateExpression(rule.Conditions, conditionData) {
+			continue
+		}
 		for _, keyword := range rule.Keywords {
 			keywordLower := strings.ToLower(keyword)
 			matched := false
@@ -537,6 +589,15 @@ func (a *App) matchKeywordRules(orgID uuid.UUID, accountName, messageText string
 					if body, ok := rule.ResponseContent["body"].(string); ok {
 						response.Body = body
 					}
+					response.RequiredSkills = skillutil.ParseRequirements(rule.ResponseContent["required_skills"])
+					return response, true
+				}
+
+				// Other non-text responses are sent from their content
+				switch rule.ResponseType {
+				case models.ResponseTypeTemplate, models.ResponseTypeMedia, models.ResponseTypeFlow, models.ResponseTypeScript:
+					response.Content = rule.ResponseContent
+					response.Body, _ = rule.ResponseContent["body"].(string)
 					return response, true
 				}
 
@@ -662,22 +723,13 @@ func (a *App) sendAndSaveFlowMessage(account *models.WhatsAppAccount, contact *m
 // getOrCreateSession finds an active session or creates a new one
 // Returns the session and a boolean indicating if it's a new session
 func (a *App) getOrCreateSession(orgID, contactID uuid.UUID, accountName, phoneNumber string, timeoutMins int) (*models.ChatbotSession, bool) {
-	now := time.Now()
-
-	// Look for an active session that hasn't timed out
-	var session models.ChatbotSession
-	timeout := now.Add(-time.Duration(timeoutMins) * time.Minute)
-	result := a.DB.Where("organization_id = ? AND contact_id = ? AND whats_app_account = ? AND status = ? AND last_activity_at > ?",
-		orgID, contactID, accountName, models.SessionStatusActive, timeout).First(&session)
-
-	if result.Error == nil {
-		// Update last activity
-		a.DB.Model(&session).Update("last_activity_at", now)
-		return &session, false // existing session
+	if session := a.findActiveSession(orgID, contactID, accountName, timeoutMins); session != nil {
+		return session, false // existing session
 	}
 
 	// Create new session
-	session = models.ChatbotSession{
+	now := time.Now()
+	session := models.ChatbotSession{
 		BaseModel:       models.BaseModel{ID: uuid.New()},
 		OrganizationID:  orgID,
 		ContactID:       contactID,
@@ -694,6 +746,23 @@ func (a *App) getOrCreateSession(orgID, contactID uuid.UUID, accountName, phoneN
 	return &session, true // new session
 }
 
+// findActiveSession returns the contact's session that hasn't timed out,
+// updating its last activity, or nil
+func (a *App) findActiveSession(orgID, contactID uuid.UUID, accountName string, timeoutMins int) *models.ChatbotSession {
+	now := time.Now()
+	var session models.ChatbotSession
+	timeout := now.Add(-time.Duration(timeoutMins) * time.Minute)
+	result := a.DB.Where("organization_id = ? AND contact_id = ? AND whats_app_account = ? AND status = ? AND last_activity_at > ?",
+		orgID, contactID, accountName, models.SessionStatusActive, timeout).First(&session)
+	if result.Error != nil {
+		return nil
+	}
+
+	// Update last activity
+	a.DB.Model(&session).Update("last_activity_at", now)
+	return &session
+}
+
 // logSessionMessage logs a message to the chatbot session
 func (a *App) logSessionMessage(sessionID uuid.UUID, direction models.Direction, message, stepName string) {
 	msg := models.ChatbotSessionMessage{
@@ -740,20 +809,28 @@ func (a *App) startFlow(account *models.WhatsAppAccount, session *models.Chatbot
 
 	// Update session with flow info
 	session.CurrentFlowID = &flow.ID
+	session.FlowVersionID = flow.PublishedVersionID
 	session.CurrentStep = ""
 	session.StepRetries = 0
 	session.SessionData = models.JSONB{
 		"_flow_id":   flow.ID.String(),
 		"_flow_name": flow.Name,
 	}
+	session.CallStack = models.JSONBArray{}
 	a.DB.Save(session)
 
+	a.enterFlow(account, session, contact, flow)
+}
+
+// enterFlow sends a flow's initial message and its first step
+func (a *App) enterFlow(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, flow *models.ChatbotFlow) {
 	// Send initial message if configured
 	if flow.InitialMessage != "" {
-		if err := a.sendAndSaveTextMessage(account, contact, flow.InitialMessage); err != nil {
+		message := processTemplate(flow.InitialMessage, session.SessionData)
+		if err := a.sendAndSaveTextMessage(account, contact, message); err != nil {
 			a.Log.Error("Failed to send flow initial message", "error", err, "contact", contact.PhoneNumber)
 		}
-		a.logSessionMessage(session.ID, models.DirectionOutgoing, flow.InitialMessage, "flow_start")
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, message, "flow_start")
 	}
 
 	// Send first step message (with skip check)
@@ -771,19 +848,19 @@ func (a *App) startFlow(account *models.WhatsAppAccount, session *models.Chatbot
 }
 
 // processFlowResponse handles user response within a flow
-func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, userInput string, buttonID string, flowResponseData map[string]interface{}) {
-	// Load the current flow from cache
-	flow, err := a.getChatbotFlowByIDCached(account.OrganizationID, *session.CurrentFlowID)
+func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, userInput string, buttonID string, flowResponseData map[string]interface{}, media *flowMedia) {
+	// Load the current flow from cache, at the version the session started on
+	flow, err := a.getSessionFlow(account.OrganizationID, session)
 	if err != nil {
 		a.Log.Error("Failed to load flow", "error", err)
 		a.exitFlow(session)
 		return
 	}
 
-	// Check for cancel keywords
+	// Check for cancel keywords (a shared location's text is its JSON)
 	userInputLower := strings.ToLower(userInput)
 	for _, cancelKw := range flow.CancelKeywords {
-		if strings.Contains(userInputLower, strings.ToLower(cancelKw)) {
+		if (media == nil || media.Type != models.InputTypeLocation) && strings.Contains(userInputLower, strings.ToLower(cancelKw)) {
 			if err := a.sendAndSaveTextMessage(account, contact, "Flow cancelled."); err != nil {
 				a.Log.Error("Failed to send flow cancel message", "error", err, "contact", contact.PhoneNumber)
 			}
@@ -810,18 +887,26 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 		return
 	}
 
-	// Validate input if required (skip validation for button/list responses)
-	if currentStep.ValidationRegex != "" && buttonID == "" {
-		re, err := regexp.Compile(currentStep.ValidationRegex)
-		if err == nil && !re.MatchString(userInput) {
+	// Media without a caption only answers steps waiting for media
+	if userInput == "" && !inpututil.IsMediaInput(currentStep.InputType) {
+		a.Log.Debug("Ignoring media for a step expecting text", "step", currentStep.StepName)
+		return
+	}
+
+	// Validate input if required (skip validation for button/list responses).
+	// Typed inputs are stored in their normalized form, media as the saved
+	// file's path and locations as their coordinates.
+	var storedValue interface{} = userInput
+	if buttonID == "" {
+		value, errorMsg := a.validateStepInput(account.OrganizationID, session, currentStep, userInput, media)
+		if errorMsg == "" {
+			storedValue = value
+		} else {
 			// Invalid input
 			session.StepRetries++
 			if currentStep.RetryOnInvalid && session.StepRetries < currentStep.MaxRetries {
 				a.DB.Model(session).Update("step_retries", session.StepRetries)
-				errorMsg := currentStep.ValidationError
-				if errorMsg == "" {
-					errorMsg = "Invalid input. Please try again."
-				}
+				errorMsg += " " + inpututil.AttemptsLeft(a.stepLanguage(session, currentStep), currentStep.MaxRetries-session.StepRetries)
 				if err := a.sendAndSaveTextMessage(account, contact, errorMsg); err != nil {
 					a.Log.Error("Failed to send validation error", "error", err, "contact", contact.PhoneNumber)
 				}
@@ -908,7 +993,15 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 			sessionData[currentStep.StoreAs] = buttonID
 			sessionData[currentStep.StoreAs+"_title"] = userInput
 		} else {
-			sessionData[currentStep.StoreAs] = userInput
+			sessionData[currentStep.StoreAs] = storedValue
+		}
+		// Keep a received file's details alongside its path
+		if media != nil && media.Type == currentStep.InputType && media.MediaURL != "" {
+			for suffix, value := range map[string]string{"_mime_type": media.MimeType, "_filename": media.Filename, "_caption": media.Caption} {
+				if value != "" {
+					sessionData[currentStep.StoreAs+suffix] = value
+				}
+			}
 		}
 		a.DB.Model(session).Update("session_data", sessionData)
 		session.SessionData = sessionData
@@ -938,25 +1031,26 @@ func (a *App) processFlowResponse(account *models.WhatsAppAccount, session *mode
 		nextStepName = flow.Steps[currentStepIndex+1].StepName
 	}
 
-	// Check conditional next - use buttonID first (for button/list responses), then userInput
+	// Check conditional next - use buttonID first (for button/list responses), then
+	// userInput, then the "when" rules in order, then "default"
 	if len(currentStep.ConditionalNext) > 0 {
-		// Try buttonID first (for interactive responses)
-		if buttonID != "" {
-			if next, ok := currentStep.ConditionalNext[buttonID].(string); ok {
-				nextStepName = next
-			} else if next, ok := currentStep.ConditionalNext[userInput].(string); ok {
-				nextStepName = next
-			} else if defaultNext, ok := currentStep.ConditionalNext["default"].(string); ok {
-				nextStepName = defaultNext
-			}
-		} else {
-			// Text input - try matching the text
-			if next, ok := currentStep.ConditionalNext[userInput].(string); ok {
-				nextStepName = next
-			} else if defaultNext, ok := currentStep.ConditionalNext["default"].(string); ok {
-				nextStepName = defaultNext
-			}
-		}
+		matchedKey := ""
+		if next, ok := currentStep.ConditionalNext[buttonID].(string); ok && buttonID != "" {
+			nextStepName, matchedKey = next, buttonID
+		} else if next, ok := currentStep.ConditionalNext[userInput].(string); ok {
+			nextStepName, matchedKey = next, userInput
+		} else if rule, ok := matchConditionalRule(currentStep, session.SessionData, userInput, buttonID); ok {
+			nextStepName, matchedKey = rule.Next, rule.If
+		} else if defaultNext, ok := currentStep.ConditionalNext["default"].(string); ok {
+			nextStepName, matchedKey = defaultNext, "default"
+		}
+		a.recordCondition(SimulatedCondition{
+			Step:       currentStep.StepName,
+			Kind:       "conditional_next",
+			Expression: matchedKey,
+			Result:     matchedKey != "",
+			NextStep:   nextStepName,
+		})
 	}
 
 	// Move to next step or complete flow
@@ -996,7 +1090,7 @@ func (a *App) completeFlow(account *models.WhatsAppAccount, session *models.Chat
 
 	// Send completion message
 	if flow.CompletionMessage != "" {
-		message := a.replaceVariables(flow.CompletionMessage, session.SessionData)
+		message := processTemplate(flow.CompletionMessage, session.SessionData)
 		if err := a.sendAndSaveTextMessage(account, contact, message); err != nil {
 			a.Log.Error("Failed to send flow completion message", "error", err, "contact", contact.PhoneNumber)
 		}
@@ -1005,7 +1099,18 @@ func (a *App) completeFlow(account *models.WhatsAppAccount, session *models.Chat
 
 	// Execute on-complete action
 	if flow.OnCompleteAction == "webhook" && len(flow.CompletionConfig) > 0 {
-		go a.sendFlowCompletionWebhook(flow, session, contact)
+		if a.simulation != nil {
+			// Record the call before the simulated turn ends
+			a.sendFlowCompletionWebhook(flow, session, contact)
+		} else {
+			go a.sendFlowCompletionWebhook(flow, session, contact)
+		}
+	}
+
+	// A completed subflow hands back to the flow that called it
+	if len(session.CallStack) > 0 {
+		a.returnFromSubflow(account, session, contact)
+		return
 	}
 
 	// Update session (keep current_flow_id for panel config reference)
@@ -1032,7 +1137,7 @@ func (a *App) sendFlowCompletionWebhook(flow *models.ChatbotFlow, session *model
 	}
 
 	// Replace variables in URL
-	webhookURL = a.replaceVariables(webhookURL, session.SessionData)
+	webhookURL = processTemplate(webhookURL, session.SessionData)
 
 	// Get HTTP method (default: POST)
 	method := "POST"
@@ -1056,7 +1161,7 @@ func (a *App) sendFlowCompletionWebhook(flow *models.ChatbotFlow, session *model
 	var bodyReader io.Reader
 	if bodyTemplate, ok := config["body"].(string); ok && bodyTemplate != "" {
 		// Replace variables in body template
-		bodyWithVars := a.replaceVariables(bodyTemplate, session.SessionData)
+		bodyWithVars := processTemplate(bodyTemplate, session.SessionData)
 		bodyReader = strings.NewReader(bodyWithVars)
 	} else {
 		// Use default payload
@@ -1083,7 +1188,7 @@ func (a *App) sendFlowCompletionWebhook(flow *models.ChatbotFlow, session *model
 	if headers, ok := config["headers"].(map[string]interface{}); ok {
 		for key, value := range headers {
 			if strVal, ok := value.(string); ok {
-				req.Header.Set(key, a.replaceVariables(strVal, session.SessionData))
+				req.Header.Set(key, processTemplate(strVal, session.SessionData))
 			}
 		}
 	}
@@ -1139,21 +1244,6 @@ func (a *App) closeSession(session *models.ChatbotSession) {
 	a.ClearContactChatbotTracking(session.ContactID)
 }
 
-// replaceVariables replaces {{variable}} placeholders with session data values
-func (a *App) replaceVariables(message string, data models.JSONB) string {
-	if data == nil {
-		return message
-	}
-	result := message
-	for key, value := range data {
-		placeholder := "{{" + key + "}}"
-		if strVal, ok := value.(string); ok {
-			result = strings.ReplaceAll(result, placeholder, strVal)
-		}
-	}
-	return result
-}
-
 // sendStepWithSkipCheck checks if a step should be skipped and sends the appropriate step message
 // It takes the full flow to find next steps when skipping
 func (a *App) sendStepWithSkipCheck(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep, flow *models.ChatbotFlow, skippedSteps map[string]bool) {
@@ -1219,11 +1309,25 @@ func (a *App) sendStepWithSkipCheck(account *models.WhatsAppAccount, session *mo
 		return
 	}
 
+	// Steps that move the session elsewhere send nothing themselves
+	switch step.MessageType {
+	case models.FlowStepTypeSubflow:
+		a.callSubflow(account, session, contact, step, flow)
+		return
+	case models.FlowStepTypeGotoFlow:
+		a.gotoFlow(account, session, contact, step, flow)
+		return
+	case models.FlowStepTypeLoop:
+		a.runLoopStep(account, session, contact, step, flow)
+		return
+	}
+
 	// Not skipping - send the step message normally
 	a.sendStepMessage(account, session, contact, step)
 
-	// If input type is "none", automatically advance to next step without waiting for user input
-	if step.InputType == models.InputTypeNone {
+	// If input type is "none", automatically advance to next step without waiting for user input.
+	// Script steps send nothing, so there is nothing to reply to either.
+	if step.InputType == models.InputTypeNone || step.MessageType == models.FlowStepTypeScript {
 
 		// Find next step
 		nextStepName := step.NextStep
@@ -1386,6 +1490,7 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 		// Get transfer configuration
 		var teamID *uuid.UUID
 		var notes string
+		var requiredSkills models.SkillRequirements
 		if step.TransferConfig != nil {
 			if teamIDStr, ok := step.TransferConfig["team_id"].(string); ok && teamIDStr != "" && teamIDStr != "_general" {
 				if parsedID, err := uuid.Parse(teamIDStr); err == nil {
@@ -1395,14 +1500,17 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 			if n, ok := step.TransferConfig["notes"].(string); ok {
 				notes = processTemplate(n, session.SessionData)
 			}
+			requiredSkills = skillutil.ParseRequirements(step.TransferConfig["required_skills"])
 		}
 
-		// Create the transfer
-		if teamID != nil {
-			a.createTransferToTeam(account, contact, *teamID, notes, models.TransferSourceFlow)
+		// Create the transfer (only recorded when simulating)
+		if a.simulation != nil {
+			a.simulation.recordTransfer(SimulatedTransfer{TeamID: teamID, Notes: notes, RequiredSkills: requiredSkills})
+		} else if teamID != nil {
+			a.createTransferToTeam(account, contact, *teamID, notes, models.TransferSourceFlow, requiredSkills)
 		} else {
 			// General queue transfer
-			a.createTransferToQueue(account, contact, models.TransferSourceFlow)
+			a.createTransferToQueue(account, contact, models.TransferSourceFlow, requiredSkills)
 		}
 
 		// End the flow session (transfer takes over)
@@ -1437,32 +1545,7 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 			}
 		} else {
 			// Look up the WhatsApp Flow to get the first screen name
-			var waFlow models.WhatsAppFlow
-			firstScreen := ""
-			if err := a.DB.Where("meta_flow_id = ?", flowID).First(&waFlow).Error; err != nil {
-				a.Log.Debug("Could not find WhatsApp Flow in database, using default screen", "meta_flow_id", flowID)
-			} else {
-				// Extract first screen name from screens array
-				if len(waFlow.Screens) > 0 {
-					if screenMap, ok := waFlow.Screens[0].(map[string]interface{}); ok {
-						if screenID, ok := screenMap["id"].(string); ok {
-							firstScreen = screenID
-							a.Log.Debug("Found first screen from flow", "first_screen", firstScreen)
-						}
-					}
-				}
-				// If screens array is empty, try to get from flow_json
-				if firstScreen == "" && waFlow.FlowJSON != nil {
-					if screens, ok := waFlow.FlowJSON["screens"].([]interface{}); ok && len(screens) > 0 {
-						if screenMap, ok := screens[0].(map[string]interface{}); ok {
-							if screenID, ok := screenMap["id"].(string); ok {
-								firstScreen = screenID
-								a.Log.Debug("Found first screen from flow_json", "first_screen", firstScreen)
-							}
-						}
-					}
-				}
-			}
+			firstScreen := a.whatsAppFlowFirstScreen(account.OrganizationID, flowID)
 
 			// Generate a unique flow token for tracking
 			flowToken := fmt.Sprintf("chatbot_%s_%s_%d", session.ID.String(), step.StepName, time.Now().UnixNano())
@@ -1474,6 +1557,17 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 		}
 		a.logSessionMessage(session.ID, models.DirectionOutgoing, message, step.StepName)
 
+	case models.FlowStepTypeTemplate:
+		// Send an approved template, which also works outside the 24h window
+		message = a.sendTemplateStep(account, session, contact, step)
+		if message != "" {
+			a.logSessionMessage(session.ID, models.DirectionOutgoing, message, step.StepName)
+		}
+
+	case models.FlowStepTypeScript:
+		// Compute session variables without sending anything
+		a.runScriptStep(session, step)
+
 	default:
 		// Default: use the step message with template processing
 		a.Log.Debug("Unhandled message type, falling back to text", "message_type", step.MessageType, "step", step.StepName)
@@ -1485,6 +1579,95 @@ func (a *App) sendStepMessage(account *models.WhatsAppAccount, session *models.C
 	}
 }
 
+// sendTemplateStep sends the step's template with parameters taken from the
+// step's template_params, then session data, then contact attributes. If the
+// template cannot be sent the step message, if any, is sent as text instead.
+// Returns the content that was sent.
+func (a *App) sendTemplateStep(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep) string {
+	fallback := func(reason string) string {
+		a.Log.Error("Cannot send template step", "reason", reason, "step", step.StepName, "template_id", step.TemplateID)
+		message := processTemplate(step.Message, session.SessionData)
+		if message == "" {
+			return ""
+		}
+		if err := a.sendAndSaveTextMessage(account, contact, message); err != nil {
+			a.Log.Error("Failed to send template fallback message", "error", err, "contact", contact.PhoneNumber)
+		}
+		return message
+	}
+
+	if step.TemplateID == nil {
+		return fallback("no template configured")
+	}
+	var template models.Template
+	if err := a.DB.Where("id = ? AND organization_id = ?", *step.TemplateID, account.OrganizationID).First(&template).Error; err != nil {
+		return fallback("template not found")
+	}
+	if template.Status != string(models.TemplateStatusApproved) {
+		return fallback("template is not approved")
+	}
+
+	params := flowTemplateParams(&template, step, session.SessionData, contact)
+	paramNames := templateutil.ExtParamNames(template.BodyContent)
+	bodyParams := templateutil.ResolveParamsFromMap(paramNames, params)
+	for i, name := range paramNames {
+		if i >= len(bodyParams) || bodyParams[i] == "" {
+			return fallback("missing template parameter " + name)
+		}
+	}
+
+	_, err := a.SendOutgoingMessage(context.Background(), OutgoingMessageRequest{
+		Account:    account,
+		Contact:    contact,
+		Type:       models.MessageTypeTemplate,
+		Template:   &template,
+		BodyParams: params,
+	}, ChatbotSendOptions())
+	if err != nil {
+		a.Log.Error("Failed to send template step", "error", err, "contact", contact.PhoneNumber, "template", template.Name)
+		return ""
+	}
+	return templateutil.ReplaceWithStringParams(template.BodyContent, params)
+}
+
+// flowTemplateParams maps a template's body parameters to values for a flow
+// session. Explicit template_params take precedence over session variables
+// with the same name, which take precedence over contact attributes.
+func flowTemplateParams(template *models.Template, step *models.ChatbotFlowStep, sessionData models.JSONB, contact *models.Contact) map[string]string {
+	params := make(map[string]string)
+	for _, name := range templateutil.ExtParamNames(template.BodyContent) {
+		if value := exprutil.Lookup(sessionData, name); value != nil {
+			params[name] = exprutil.Format(value)
+		}
+	}
+	for name, value := range step.TemplateParams {
+		if s, ok := value.(string); ok {
+			params[name] = processTemplate(s, sessionData)
+		}
+	}
+	if contact != nil && len(contact.Attributes) > 0 {
+		params = contactutil.AttributeTemplateParams(contact.Attributes, params)
+	}
+	return params
+}
+
+// runScriptStep evaluates the step's script and saves the computed variables
+// to the session
+func (a *App) runScriptStep(session *models.ChatbotSession, step *models.ChatbotFlowStep) {
+	assignments, err := parseScriptAssignments(step.ScriptConfig)
+	if err != nil {
+		a.Log.Error("Invalid script step", "error", err, "step", step.StepName)
+		return
+	}
+	if session.SessionData == nil {
+		session.SessionData = models.JSONB{}
+	}
+	for _, err := range runScript(assignments, session.SessionData) {
+		a.Log.Warn("Script assignment failed", "error", err, "step", step.StepName, "session_id", session.ID)
+	}
+	a.DB.Model(session).Update("session_data", session.SessionData)
+}
+
 // ApiResponse represents a response from an external API that may include buttons
 type ApiResponse struct {
 	Message      string
@@ -1723,7 +1906,7 @@ func (a *App) fetchAPIContext(apiConfig models.JSONB, session *models.ChatbotSes
 	}
 
 	// Replace variables in URL
-	apiURL = a.replaceVariables(apiURL, sessionData)
+	apiURL = processTemplate(apiURL, sessionData)
 
 	// Get HTTP method (default: GET)
 	method := "GET"
@@ -1734,7 +1917,7 @@ func (a *App) fetchAPIContext(apiConfig models.JSONB, session *models.ChatbotSes
 	// Prepare request body if configured
 	var bodyReader io.Reader
 	if bodyTemplate, ok := apiConfig["body"].(string); ok && bodyTemplate != "" {
-		bodyWithVars := a.replaceVariables(bodyTemplate, sessionData)
+		bodyWithVars := processTemplate(bodyTemplate, sessionData)
 		bodyReader = strings.NewReader(bodyWithVars)
 	}
 
@@ -1752,7 +1935,7 @@ func (a *App) fetchAPIContext(apiConfig models.JSONB, session *models.ChatbotSes
 	if headers, ok := apiConfig["headers"].(map[string]interface{}); ok {
 		for key, value := range headers {
 			if strVal, ok := value.(string); ok {
-				req.Header.Set(key, a.replaceVariables(strVal, sessionData))
+				req.Header.Set(key, processTemplate(strVal, sessionData))
 			}
 		}
 	}
@@ -1778,8 +1961,8 @@ func (a *App) fetchAPIContext(apiConfig models.JSONB, session *models.ChatbotSes
 	if responsePath, ok := apiConfig["response_path"].(string); ok && responsePath != "" {
 		var jsonResp map[string]interface{}
 		if err := json.Unmarshal(respBody, &jsonResp); err == nil {
-			if value := getNestedValue(jsonResp, responsePath); value != nil {
-				return formatValue(value), nil
+			if value := exprutil.Lookup(jsonResp, responsePath); value != nil {
+				return exprutil.Format(value), nil
 			}
 		}
 	}
@@ -2299,17 +2482,22 @@ func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *mode
 		preview = "[" + msgType + "]"
 	}
 
-	a.DB.Model(contact).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"last_message_at":      now,
 		"last_message_preview": preview,
 		"is_read":              false,
 		"whats_app_account":    account.Name,
-	})
+	}
+	// Blocked contacts don't raise unread badges or notifications
+	if contact.IsBlocked {
+		delete(updates, "is_read")
+	}
+	a.DB.Model(contact).Updates(updates)
 
 	a.Log.Info("Saved incoming message", "message_id", message.ID, "contact_id", contact.ID, "media_url", message.MediaURL)
 
 	// Broadcast new message via WebSocket
-	if a.WSHub != nil {
+	if a.WSHub != nil && !contact.IsBlocked {
 		var assignedUserIDStr string
 		if contact.AssignedUserID != nil {
 			assignedUserIDStr = contact.AssignedUserID.String()
@@ -2351,69 +2539,64 @@ func (a *App) saveIncomingMessage(account *models.WhatsAppAccount, contact *mode
 		})
 	}
 
-	// Dispatch webhook for incoming message
-	a.DispatchWebhook(account.OrganizationID, models.WebhookEventMessageIncoming, MessageEventData{
-		MessageID:       message.ID.String(),
-		ContactID:       contact.ID.String(),
-		ContactPhone:    contact.PhoneNumber,
-		ContactName:     contact.ProfileName,
-		MessageType:     models.MessageType(msgType),
-		Content:         content,
-		WhatsAppAccount: account.Name,
-		Direction:       models.DirectionIncoming,
-	})
+	// Dispatch webhook for incoming message. Integrations relay these as
+	// notifications, so blocked contacts are skipped here too.
+	if !contact.IsBlocked {
+		a.DispatchWebhook(account.OrganizationID, models.WebhookEventMessageIncoming, MessageEventData{
+			MessageID:       message.ID.String(),
+			ContactID:       contact.ID.String(),
+			ContactPhone:    contact.PhoneNumber,
+			ContactName:     contact.ProfileName,
+			MessageType:     models.MessageType(msgType),
+			Content:         content,
+			WhatsAppAccount: account.Name,
+			Direction:       models.DirectionIncoming,
+		})
+	}
 }
 
-// isWithinBusinessHours checks if current time is within configured business hours
-func (a *App) isWithinBusinessHours(businessHours models.JSONBArray) bool {
-	now := time.Now()
-	currentDay := int(now.Weekday()) // 0 = Sunday, 1 = Monday, etc.
-	currentTime := now.Format("15:04")
-
-	for _, bh := range businessHours {
-		bhMap, ok := bh.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		// Get day (0-6, Sunday-Saturday)
-		day, ok := bhMap["day"].(float64)
-		if !ok {
-			continue
-		}
-
-		if int(day) != currentDay {
-			continue
-		}
-
-		// Check if enabled for this day
-		enabled, ok := bhMap["enabled"].(bool)
-		if !ok || !enabled {
-			return false // Day exists but is disabled
-		}
+// isWithinBusinessHours checks if the current time is within the business
+// hours configured for the account, in its timezone and outside holidays
+func (a *App) isWithinBusinessHours(settings *models.ChatbotSettings, account string) bool {
+	schedule := a.businessSchedule(settings, account, nil)
+	return schedule != nil && schedule.IsOpen(time.Now())
+}
 
-		// Get start and end times
-		startTime, ok := bhMap["start_time"].(string)
-		if !ok {
-			continue
-		}
-		endTime, ok := bhMap["end_time"].(string)
-		if !ok {
-			continue
-		}
+// businessSchedule returns the business-hours schedule of a WhatsApp account,
+// in the team's timezone when the team has one. It returns nil when business
+// hours are disabled. The timezone and holidays are cached, as this runs for
+// every incoming message.
+func (a *App) businessSchedule(settings *models.ChatbotSettings, account string, teamID *uuid.UUID) *bizhours.Schedule {
+	if !bizhours.Enabled(settings) {
+		return nil
+	}
+	cached, err := a.getBusinessHoursCached(settings.OrganizationID, account, teamID)
+	if err != nil {
+		a.Log.Error("Failed to load business hours", "error", err, "account", account)
+		return nil
+	}
+	return bizhours.New(settings.BusinessHours.Hours, bizhours.LoadLocation(cached.Timezone), cached.Closures)
+}
 
-		// Compare times (simple string comparison works for HH:MM format)
-		if currentTime >= startTime && currentTime <= endTime {
-			return true
+// businessScheduleSince is businessSchedule with the holidays from since on,
+// for evaluating past periods.
+func (a *App) businessScheduleSince(settings *models.ChatbotSettings, account string, teamID *uuid.UUID, since time.Time) *bizhours.Schedule {
+	timezone := ""
+	if teamID != nil {
+		var team models.Team
+		if a.DB.Select("timezone").Where("id = ?", *teamID).First(&team).Error == nil {
+			timezone = team.Timezone
 		}
-		return false // Found the day but outside hours
 	}
-
-	// If no matching day found, assume outside business hours
-	return false
+	schedule, err := bizhours.ForSettingsSince(a.DB, settings, account, timezone, since)
+	if err != nil {
+		a.Log.Error("Failed to load business hours", "error", err, "account", account)
+	}
+	return schedule
 }
 
-// shouldSkipStep evaluates a text expression like "(status == 'vip' OR amount > 100) AND name != ”"
+// shouldSkipStep evaluates a step's skip condition, such as
+// "(status == 'vip' or amount > 100) and name != ''"
 func (a *App) shouldSkipStep(step *models.ChatbotFlowStep, sessionData map[string]interface{}) bool {
 	if step.SkipCondition == "" {
 		a.Log.Debug("No skip condition for step", "step", step.StepName)
@@ -2422,163 +2605,11 @@ func (a *App) shouldSkipStep(step *models.ChatbotFlowStep, sessionData map[strin
 	a.Log.Info("Evaluating skip condition", "step", step.StepName, "condition", step.SkipCondition, "sessionData", sessionData)
 	result := evaluateExpression(step.SkipCondition, sessionData)
 	a.Log.Info("Skip condition result", "step", step.StepName, "result", result)
+	a.recordCondition(SimulatedCondition{
+		Step:       step.StepName,
+		Kind:       "skip_condition",
+		Expression: step.SkipCondition,
+		Result:     result,
+	})
 	return result
 }
-
-// evaluateExpression handles parentheses, AND, OR, and single conditions
-func evaluateExpression(expr string, data map[string]interface{}) bool {
-	expr = strings.TrimSpace(expr)
-	if expr == "" {
-		return false
-	}
-
-	// Handle parentheses first - find innermost pairs and evaluate recursively
-	for strings.Contains(expr, "(") {
-		start := strings.LastIndex(expr, "(")
-		end := strings.Index(expr[start:], ")")
-		if end == -1 {
-			break // Malformed expression
-		}
-		end = start + end
-		inner := expr[start+1 : end]
-		result := evaluateExpression(inner, data)
-		// Replace (inner) with result
-		replacement := "false"
-		if result {
-			replacement = "true"
-		}
-		expr = expr[:start] + replacement + expr[end+1:]
-		expr = strings.TrimSpace(expr)
-	}
-
-	// Handle boolean literals (from parentheses evaluation)
-	if expr == "true" {
-		return true
-	}
-	if expr == "false" {
-		return false
-	}
-
-	// Split by OR (lower precedence) - case insensitive
-	orParts := splitByLogicOperator(expr, " OR ")
-	if len(orParts) > 1 {
-		for _, part := range orParts {
-			if evaluateExpression(part, data) {
-				return true
-			}
-		}
-		return false
-	}
-
-	// Split by AND (higher precedence) - case insensitive
-	andParts := splitByLogicOperator(expr, " AND ")
-	if len(andParts) > 1 {
-		for _, part := range andParts {
-			if !evaluateExpression(part, data) {
-				return false
-			}
-		}
-		return true
-	}
-
-	// Single condition
-	return evaluateSingleCondition(expr, data)
-}
-
-// splitByLogicOperator splits by AND/OR while preserving case-insensitivity
-func splitByLogicOperator(expr, op string) []string {
-	upperExpr := strings.ToUpper(expr)
-	upperOp := strings.ToUpper(op)
-
-	var parts []string
-	lastIdx := 0
-	for {
-		idx := strings.Index(upperExpr[lastIdx:], upperOp)
-		if idx == -1 {
-			parts = append(parts, strings.TrimSpace(expr[lastIdx:]))
-			break
-		}
-		parts = append(parts, strings.TrimSpace(expr[lastIdx:lastIdx+idx]))
-		lastIdx = lastIdx + idx + len(op)
-	}
-	return parts
-}
-
-// evaluateSingleCondition handles: phone != ” or age > 18 or status == 'confirmed'
-func evaluateSingleCondition(expr string, data map[string]interface{}) bool {
-	expr = strings.TrimSpace(expr)
-
-	// Handle boolean literals
-	if expr == "true" {
-		return true
-	}
-	if expr == "false" {
-		return false
-	}
-
-	operators := []string{"!=", "==", ">=", "<=", ">", "<"}
-
-	for _, op := range operators {
-		if strings.Contains(expr, op) {
-			parts := strings.SplitN(expr, op, 2)
-			if len(parts) == 2 {
-				varName := strings.TrimSpace(parts[0])
-				expectedValue := strings.TrimSpace(parts[1])
-				expectedValue = strings.Trim(expectedValue, "'\"")
-
-				actualValue := ""
-				if val, exists := data[varName]; exists && val != nil {
-					actualValue = fmt.Sprintf("%v", val)
-				}
-
-				return compareValues(actualValue, op, expectedValue)
-			}
-		}
-	}
-	return false
-}
-
-// compareValues compares two values using the specified operator
-func compareValues(actual, operator, expected string) bool {
-	switch operator {
-	case "==":
-		return actual == expected
-	case "!=":
-		return actual != expected
-	case ">", "<", ">=", "<=":
-		// Try numeric comparison first
-		actualNum, err1 := parseNumber(actual)
-		expectedNum, err2 := parseNumber(expected)
-		if err1 == nil && err2 == nil {
-			switch operator {
-			case ">":
-				return actualNum > expectedNum
-			case "<":
-				return actualNum < expectedNum
-			case ">=":
-				return actualNum >= expectedNum
-			case "<=":
-				return actualNum <= expectedNum
-			}
-		}
-		// Fall back to string comparison
-		switch operator {
-		case ">":
-			return actual > expected
-		case "<":
-			return actual < expected
-		case ">=":
-			return actual >= expected
-		case "<=":
-			return actual <= expected
-		}
-	}
-	return false
-}
-
-// parseNumber attempts to parse a string as a float64
-func parseNumber(s string) (float64, error) {
-	var n float64
-	_, err := fmt.Sscanf(s, "%f", &n)
-	return n, err
-}
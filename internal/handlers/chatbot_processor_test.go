@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -73,19 +74,19 @@ func TestMatchKeywordRules_ExactMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hello")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hello", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Hello response", resp.Body)
 
 	// Different case should also match (case insensitive by default)
-	resp2, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELLO")
+	resp2, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELLO", nil)
 	assert.True(t, matched2)
 	require.NotNil(t, resp2)
 	assert.Equal(t, "Hello response", resp2.Body)
 
 	// Partial should NOT match exact
-	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "hello world")
+	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "hello world", nil)
 	assert.False(t, matched3)
 }
 
@@ -108,13 +109,58 @@ func TestMatchKeywordRules_ExactMatch_CaseSensitive(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	_, matched := app.matchKeywordRules(org.ID, account.Name, "Hello")
+	_, matched := app.matchKeywordRules(org.ID, account.Name, "Hello", nil)
 	assert.True(t, matched)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "hello")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "hello", nil)
 	assert.False(t, matched2)
 }
 
+func TestMatchKeywordRules_Conditions(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	vipRule := &models.KeywordRule{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "vip-hello",
+		Keywords:        models.StringArray{"hello"},
+		MatchType:       models.MatchTypeExact,
+		Conditions:      "'vip' in contact.tags",
+		ResponseType:    models.ResponseTypeText,
+		ResponseContent: models.JSONB{"body": "Welcome back"},
+		Priority:        20,
+		IsEnabled:       true,
+	}
+	require.NoError(t, app.DB.Create(vipRule).Error)
+	rule := &models.KeywordRule{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "hello",
+		Keywords:        models.StringArray{"hello"},
+		MatchType:       models.MatchTypeExact,
+		ResponseType:    models.ResponseTypeText,
+		ResponseContent: models.JSONB{"body": "Hello"},
+		Priority:        10,
+		IsEnabled:       true,
+	}
+	require.NoError(t, app.DB.Create(rule).Error)
+
+	vip := keywordConditionData(nil, &models.Contact{Tags: models.JSONBArray{"vip"}})
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hello", vip)
+	assert.True(t, matched)
+	require.NotNil(t, resp)
+	assert.Equal(t, "Welcome back", resp.Body)
+
+	// Rules whose conditions fail are passed over
+	resp, matched = app.matchKeywordRules(org.ID, account.Name, "hello", nil)
+	assert.True(t, matched)
+	require.NotNil(t, resp)
+	assert.Equal(t, "Hello", resp.Body)
+}
+
 func TestMatchKeywordRules_ContainsMatch(t *testing.T) {
 	app := newProcessorTestApp(t)
 	org, account := createProcessorTestOrg(t, app)
@@ -133,15 +179,15 @@ func TestMatchKeywordRules_ContainsMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I need help please")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I need help please", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Help response", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELP ME")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "HELP ME", nil)
 	assert.True(t, matched2)
 
-	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "goodbye")
+	_, matched3 := app.matchKeywordRules(org.ID, account.Name, "goodbye", nil)
 	assert.False(t, matched3)
 }
 
@@ -163,12 +209,12 @@ func TestMatchKeywordRules_StartsWithMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hi there")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "hi there", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Hi response", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "say hi")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "say hi", nil)
 	assert.False(t, matched2)
 }
 
@@ -190,12 +236,12 @@ func TestMatchKeywordRules_RegexMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I have order #12345")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "I have order #12345", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Order lookup", resp.Body)
 
-	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "where is my package")
+	_, matched2 := app.matchKeywordRules(org.ID, account.Name, "where is my package", nil)
 	assert.False(t, matched2)
 }
 
@@ -217,7 +263,7 @@ func TestMatchKeywordRules_NoMatch(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "random message")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "random message", nil)
 	assert.False(t, matched)
 	assert.Nil(t, resp)
 }
@@ -257,7 +303,7 @@ func TestMatchKeywordRules_Priority(t *testing.T) {
 	require.NoError(t, app.DB.Create(highRule).Error)
 
 	// The higher priority rule should be returned (rules are ORDER BY priority DESC)
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "this is a test")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "this is a test", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "High priority", resp.Body)
@@ -283,7 +329,7 @@ func TestMatchKeywordRules_DisabledRuleIgnored(t *testing.T) {
 	// Explicitly disable: GORM skips zero-value bools with default:true on INSERT.
 	require.NoError(t, app.DB.Model(rule).Update("is_enabled", false).Error)
 
-	_, matched := app.matchKeywordRules(org.ID, account.Name, "disabled")
+	_, matched := app.matchKeywordRules(org.ID, account.Name, "disabled", nil)
 	assert.False(t, matched)
 }
 
@@ -305,7 +351,7 @@ func TestMatchKeywordRules_TransferType(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "agent")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "agent", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, models.ResponseTypeTransfer, resp.ResponseType)
@@ -336,7 +382,7 @@ func TestMatchKeywordRules_WithButtons(t *testing.T) {
 	}
 	require.NoError(t, app.DB.Create(rule).Error)
 
-	resp, matched := app.matchKeywordRules(org.ID, account.Name, "menu")
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "menu", nil)
 	assert.True(t, matched)
 	require.NotNil(t, resp)
 	assert.Equal(t, "Choose an option:", resp.Body)
@@ -420,9 +466,15 @@ func TestGetOrCreateSession_ExpiredSession(t *testing.T) {
 // isWithinBusinessHours
 // =============================================================================
 
+func businessHoursSettings(hours models.JSONBArray) *models.ChatbotSettings {
+	return &models.ChatbotSettings{
+		BusinessHours: models.BusinessHoursConfig{Enabled: true, Hours: hours},
+	}
+}
+
 func TestIsWithinBusinessHours_WithinHours(t *testing.T) {
 	app := newProcessorTestApp(t)
-	now := time.Now()
+	now := time.Now().UTC() // No timezone configured, so hours are in UTC
 	dayOfWeek := float64(now.Weekday())
 
 	hours := models.JSONBArray{
@@ -434,13 +486,13 @@ func TestIsWithinBusinessHours_WithinHours(t *testing.T) {
 		},
 	}
 
-	result := app.isWithinBusinessHours(hours)
+	result := app.isWithinBusinessHours(businessHoursSettings(hours), "")
 	assert.True(t, result)
 }
 
 func TestIsWithinBusinessHours_OutsideHours(t *testing.T) {
 	app := newProcessorTestApp(t)
-	now := time.Now()
+	now := time.Now().UTC() // No timezone configured, so hours are in UTC
 	dayOfWeek := float64(now.Weekday())
 
 	// Set hours to a time window that has definitely passed
@@ -457,14 +509,14 @@ func TestIsWithinBusinessHours_OutsideHours(t *testing.T) {
 	// This will only be true if running at midnight; for all practical purposes it tests false
 	currentTime := now.Format("15:04")
 	if currentTime > "00:01" {
-		result := app.isWithinBusinessHours(hours)
+		result := app.isWithinBusinessHours(businessHoursSettings(hours), "")
 		assert.False(t, result)
 	}
 }
 
 func TestIsWithinBusinessHours_DayDisabled(t *testing.T) {
 	app := newProcessorTestApp(t)
-	now := time.Now()
+	now := time.Now().UTC() // No timezone configured, so hours are in UTC
 	dayOfWeek := float64(now.Weekday())
 
 	hours := models.JSONBArray{
@@ -476,13 +528,13 @@ func TestIsWithinBusinessHours_DayDisabled(t *testing.T) {
 		},
 	}
 
-	result := app.isWithinBusinessHours(hours)
+	result := app.isWithinBusinessHours(businessHoursSettings(hours), "")
 	assert.False(t, result)
 }
 
 func TestIsWithinBusinessHours_NoMatchingDay(t *testing.T) {
 	app := newProcessorTestApp(t)
-	now := time.Now()
+	now := time.Now().UTC() // No timezone configured, so hours are in UTC
 	// Use a different day of the week
 	otherDay := float64((int(now.Weekday()) + 1) % 7)
 
@@ -495,14 +547,49 @@ func TestIsWithinBusinessHours_NoMatchingDay(t *testing.T) {
 		},
 	}
 
-	result := app.isWithinBusinessHours(hours)
+	result := app.isWithinBusinessHours(businessHoursSettings(hours), "")
 	assert.False(t, result)
 }
 
+func TestIsWithinBusinessHours_CachesHolidays(t *testing.T) {
+	app := newProcessorTestApp(t)
+	if app.Redis == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping test")
+	}
+	org, account := createProcessorTestOrg(t, app)
+	settings := businessHoursSettings(models.JSONBArray{
+		map[string]interface{}{
+			"day":        float64(time.Now().UTC().Weekday()),
+			"enabled":    true,
+			"start_time": "00:00",
+			"end_time":   "23:59",
+		},
+	})
+	settings.OrganizationID = org.ID
+	assert.True(t, app.isWithinBusinessHours(settings, account.Name))
+
+	calendar := models.HolidayCalendar{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: org.ID, Name: "Holidays", IsActive: true}
+	require.NoError(t, app.DB.Create(&calendar).Error)
+	today := time.Now().UTC().Format("2006-01-02")
+	require.NoError(t, app.DB.Create(&models.Holiday{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		CalendarID:     calendar.ID,
+		Name:           "Closed",
+		StartDate:      today,
+		EndDate:        today,
+	}).Error)
+
+	// The cached holidays are used until they are invalidated
+	assert.True(t, app.isWithinBusinessHours(settings, account.Name))
+	app.InvalidateBusinessHoursCache(org.ID)
+	assert.False(t, app.isWithinBusinessHours(settings, account.Name))
+}
+
 func TestIsWithinBusinessHours_EmptyHours(t *testing.T) {
 	app := newProcessorTestApp(t)
 
-	result := app.isWithinBusinessHours(models.JSONBArray{})
+	result := app.isWithinBusinessHours(businessHoursSettings(models.JSONBArray{}), "")
 	assert.False(t, result)
 }
 
@@ -885,31 +972,108 @@ func TestSaveIncomingMessage_LongContent(t *testing.T) {
 }
 
 // =============================================================================
-// replaceVariables
+// handleConsentKeyword
 // =============================================================================
 
-func TestReplaceVariables_Basic(t *testing.T) {
+func TestHandleConsentKeyword_OptOutAndOptIn(t *testing.T) {
 	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
 
-	result := app.replaceVariables("Hello {{name}}, your order is {{order_id}}", models.JSONB{
-		"name":     "John",
-		"order_id": "12345",
-	})
-	assert.Equal(t, "Hello John, your order is 12345", result)
+	assert.True(t, app.handleConsentKeyword(account, contact, "wamid.stop", "Stop"))
+
+	var dbContact models.Contact
+	require.NoError(t, app.DB.First(&dbContact, contact.ID).Error)
+	assert.Equal(t, models.ConsentStatusOptedOut, dbContact.ConsentStatus)
+
+	var record models.ContactConsent
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&record).Error)
+	assert.Equal(t, models.ConsentSourceKeyword, record.Source)
+	assert.Equal(t, "wamid.stop", record.Proof["message_id"])
+
+	// Confirmation reply was sent
+	var reply models.Message
+	require.NoError(t, app.DB.Where("contact_id = ? AND direction = ?", contact.ID, models.DirectionOutgoing).First(&reply).Error)
+	assert.Contains(t, reply.Content, "unsubscribed")
+
+	assert.True(t, app.handleConsentKeyword(account, contact, "wamid.start", "START"))
+	require.NoError(t, app.DB.First(&dbContact, contact.ID).Error)
+	assert.Equal(t, models.ConsentStatusOptedIn, dbContact.ConsentStatus)
+
+	var count int64
+	app.DB.Model(&models.ContactConsent{}).Where("contact_id = ?", contact.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
 }
 
-func TestReplaceVariables_NilData(t *testing.T) {
+func TestHandleConsentKeyword_NotAKeyword(t *testing.T) {
 	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	assert.False(t, app.handleConsentKeyword(account, contact, "wamid.text", "please stop by the store"))
 
-	result := app.replaceVariables("Hello {{name}}", nil)
-	assert.Equal(t, "Hello {{name}}", result)
+	var dbContact models.Contact
+	require.NoError(t, app.DB.First(&dbContact, contact.ID).Error)
+	assert.Empty(t, dbContact.ConsentStatus)
 }
 
-func TestReplaceVariables_MissingVariable(t *testing.T) {
+func TestSaveIncomingMessage_BlockedContact(t *testing.T) {
 	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(contact).Updates(map[string]interface{}{"is_blocked": true, "is_read": true}).Error)
+	contact.IsBlocked = true
+
+	var webhookCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookCalls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	require.NoError(t, app.DB.Create(&models.Webhook{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		Name:           "relay",
+		URL:            server.URL,
+		Events:         models.StringArray{string(models.WebhookEventMessageIncoming)},
+		IsActive:       true,
+	}).Error)
+
+	waMsgID := "wamid." + uuid.New().String()[:16]
+	app.saveIncomingMessage(account, contact, waMsgID, "text", "spam", nil, "")
+	app.WaitForBackgroundTasks()
+
+	// Message is stored but doesn't mark the conversation unread
+	var msg models.Message
+	require.NoError(t, app.DB.Where("whats_app_message_id = ?", waMsgID).First(&msg).Error)
+	var dbContact models.Contact
+	require.NoError(t, app.DB.First(&dbContact, contact.ID).Error)
+	assert.True(t, dbContact.IsRead)
+	assert.Equal(t, "spam", dbContact.LastMessagePreview)
+
+	// Nor notify integrations
+	assert.Zero(t, webhookCalls.Load())
+}
+
+// =============================================================================
+// processTemplate with session data
+// =============================================================================
 
-	result := app.replaceVariables("Hello {{name}}", models.JSONB{})
-	assert.Equal(t, "Hello {{name}}", result)
+func TestProcessTemplate_SessionData(t *testing.T) {
+	result := processTemplate("Hello {{name}}, your order is {{order_id}}", models.JSONB{
+		"name":     "John",
+		"order_id": 12345.0,
+	})
+	assert.Equal(t, "Hello John, your order is 12345", result)
+}
+
+func TestProcessTemplate_SessionDataMissingVariable(t *testing.T) {
+	result := processTemplate("Hello {{name}}", models.JSONB{})
+	assert.Equal(t, "Hello ", result)
+
+	// Invalid tags and WhatsApp template placeholders are sent as they are
+	result = processTemplate("Hello {{name +}} {{1}}", models.JSONB{"name": "John"})
+	assert.Equal(t, "Hello {{name +}} {{1}}", result)
 }
 
 // =============================================================================
@@ -1007,3 +1171,181 @@ func TestEvaluateExpression_Parentheses(t *testing.T) {
 func TestEvaluateExpression_EmptyExpression(t *testing.T) {
 	assert.False(t, evaluateExpression("", map[string]interface{}{}))
 }
+
+// =============================================================================
+// template and script steps
+// =============================================================================
+
+func TestSendStepWithSkipCheck_ScriptThenTemplate(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	require.NoError(t, app.DB.Model(template).Update("body_content", "Hi {{1}}, your total is {{2}}").Error)
+	template.BodyContent = "Hi {{1}}, your total is {{2}}"
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: flowID},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Order Flow",
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{
+				BaseModel:   models.BaseModel{ID: uuid.New()},
+				FlowID:      flowID,
+				StepName:    "compute",
+				StepOrder:   1,
+				MessageType: models.FlowStepTypeScript,
+				ScriptConfig: models.JSONB{"assignments": []interface{}{
+					map[string]interface{}{"variable": "total", "expression": "price * quantity"},
+				}},
+			},
+			{
+				BaseModel:      models.BaseModel{ID: uuid.New()},
+				FlowID:         flowID,
+				StepName:       "confirm",
+				StepOrder:      2,
+				MessageType:    models.FlowStepTypeTemplate,
+				TemplateID:     &template.ID,
+				TemplateParams: models.JSONB{"1": "{{name}}", "2": "Rs. {{total}}"},
+				InputType:      models.InputTypeNone,
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+		CurrentFlowID:   &flowID,
+		CurrentStep:     "compute",
+		SessionData:     models.JSONB{"name": "Asha", "price": "250", "quantity": "2"},
+		StartedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+
+	app.sendStepWithSkipCheck(account, session, contact, &flow.Steps[0], flow, nil)
+
+	// The script stored its result without sending anything, then the
+	// template was sent and the flow completed
+	var messages []models.Message
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).Find(&messages).Error)
+	require.Len(t, messages, 1)
+	assert.Equal(t, models.MessageTypeTemplate, messages[0].MessageType)
+	assert.Equal(t, template.Name, messages[0].TemplateName)
+	assert.Equal(t, "Hi Asha, your total is Rs. 500", messages[0].Content)
+
+	var dbSession models.ChatbotSession
+	require.NoError(t, app.DB.First(&dbSession, session.ID).Error)
+	assert.Equal(t, 500.0, dbSession.SessionData["total"])
+	assert.Equal(t, models.SessionStatusCompleted, dbSession.Status)
+}
+
+func TestSendTemplateStep_FallsBackWhenNotApproved(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	require.NoError(t, app.DB.Model(template).Update("status", models.TemplateStatusPending).Error)
+
+	session := &models.ChatbotSession{
+		BaseModel:   models.BaseModel{ID: uuid.New()},
+		SessionData: models.JSONB{"name": "Asha"},
+	}
+	step := &models.ChatbotFlowStep{
+		StepName:    "confirm",
+		Message:     "Thanks {{name}}, we'll be in touch.",
+		MessageType: models.FlowStepTypeTemplate,
+		TemplateID:  &template.ID,
+	}
+
+	sent := app.sendTemplateStep(account, session, contact, step)
+	assert.Equal(t, "Thanks Asha, we'll be in touch.", sent)
+
+	var msg models.Message
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&msg).Error)
+	assert.Equal(t, models.MessageTypeText, msg.MessageType)
+}
+
+func TestFlowTemplateParams_Precedence(t *testing.T) {
+	template := &models.Template{BodyContent: "Hi {{name}}, order {{order_id}} ships to {{city}}"}
+	step := &models.ChatbotFlowStep{TemplateParams: models.JSONB{"order_id": "#{{order.id}}"}}
+	sessionData := models.JSONB{"name": "Asha", "order_id": "ignored", "order": map[string]interface{}{"id": 42.0}}
+	contact := &models.Contact{Attributes: models.JSONB{"name": "Contact Name", "city": "Pune"}}
+
+	params := flowTemplateParams(template, step, sessionData, contact)
+	assert.Equal(t, "Asha", params["name"])
+	assert.Equal(t, "#42", params["order_id"])
+	assert.Equal(t, "Pune", params["city"])
+}
+
+func TestProcessFlowResponse_StaysOnPinnedVersion(t *testing.T) {
+	app := newProcessorTestApp(t)
+	if app.Redis == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping test")
+	}
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:       models.BaseModel{ID: flowID},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "Signup",
+		IsEnabled:       true,
+		Steps: []models.ChatbotFlowStep{
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "ask_name", StepOrder: 1, Message: "What is your name?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText, StoreAs: "name"},
+			{BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepName: "ask_email", StepOrder: 2, Message: "What is your email?", MessageType: models.FlowStepTypeText, InputType: models.InputTypeText, StoreAs: "email"},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+	v1, err := app.publishFlowVersion(app.DB, flow, uuid.Nil, "", 0)
+	require.NoError(t, err)
+	app.InvalidateChatbotFlowsCache(org.ID)
+
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+		SessionData:     models.JSONB{},
+		LastActivityAt:  time.Now(),
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+	current, err := app.getChatbotFlowByIDCached(org.ID, flowID)
+	require.NoError(t, err)
+	app.startFlow(account, session, contact, current)
+	require.NotNil(t, session.FlowVersionID)
+	assert.Equal(t, v1.ID, *session.FlowVersionID)
+
+	// Publish a version that changes the next question
+	require.NoError(t, app.DB.Model(&models.ChatbotFlowStep{}).
+		Where("flow_id = ? AND step_name = ?", flowID, "ask_email").
+		Update("message", "Where can we email you?").Error)
+	v2, err := app.publishFlowVersion(app.DB, flow, uuid.Nil, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+	app.InvalidateChatbotFlowsCache(org.ID)
+
+	// The contact already in the flow keeps seeing version 1
+	app.processFlowResponse(account, session, contact, "Asha", "", nil, nil)
+	var msg models.Message
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).Order("created_at DESC").First(&msg).Error)
+	assert.Equal(t, "What is your email?", msg.Content)
+
+	// New sessions start on version 2
+	current, err = app.getChatbotFlowByIDCached(org.ID, flowID)
+	require.NoError(t, err)
+	assert.Equal(t, v2.ID, *current.PublishedVersionID)
+	assert.Equal(t, "Where can we email you?", current.Steps[1].Message)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/flowutil"
 	"github.com/shridarpatil/whatomate/internal/handlers"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/test/testutil"
@@ -2756,3 +2757,418 @@ func TestApp_GetKeywordRule_ResponseFields(t *testing.T) {
 		assert.NotEmpty(t, resp.Data.CreatedAt)
 	})
 }
+
+func TestApp_CreateChatbotFlow_TemplateAndScriptSteps(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+	createFlow := func(steps []map[string]any) *fastglue.Request {
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name":             "Order Flow",
+			"trigger_keywords": []string{"order"},
+			"steps":            steps,
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateChatbotFlow(req))
+		return req
+	}
+
+	t.Run("stores template and script configuration", func(t *testing.T) {
+		req := createFlow([]map[string]any{
+			{
+				"step_name":    "compute",
+				"message_type": "script",
+				"script_config": map[string]any{"assignments": []map[string]any{
+					{"variable": "total", "expression": "price * quantity"},
+				}},
+			},
+			{
+				"step_name":       "confirm",
+				"message_type":    "template",
+				"template_id":     template.ID.String(),
+				"template_params": map[string]any{"1": "{{total}}"},
+				"input_type":      "none",
+			},
+		})
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var result struct {
+			ID uuid.UUID `json:"id"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &result)
+
+		var steps []models.ChatbotFlowStep
+		require.NoError(t, app.DB.Where("flow_id = ?", result.ID).Order("step_order").Find(&steps).Error)
+		require.Len(t, steps, 2)
+		assert.NotNil(t, steps[0].ScriptConfig["assignments"])
+		require.NotNil(t, steps[1].TemplateID)
+		assert.Equal(t, template.ID, *steps[1].TemplateID)
+		assert.Equal(t, "{{total}}", steps[1].TemplateParams["1"])
+	})
+
+	t.Run("rejects a template from another organization", func(t *testing.T) {
+		otherOrg := testutil.CreateTestOrganization(t, app.DB)
+		other := testutil.CreateTestTemplate(t, app.DB, otherOrg.ID, account.Name)
+		req := createFlow([]map[string]any{
+			{"step_name": "confirm", "message_type": "template", "template_id": other.ID.String()},
+		})
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, `step "confirm" uses a template that was not found`)
+	})
+
+	t.Run("rejects an invalid script", func(t *testing.T) {
+		req := createFlow([]map[string]any{
+			{
+				"step_name":    "compute",
+				"message_type": "script",
+				"script_config": map[string]any{"assignments": []map[string]any{
+					{"variable": "total", "expression": "price * (quantity"},
+				}},
+			},
+		})
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, `step "compute": assignment 1: expected ")" at end of expression`)
+	})
+
+	t.Run("rejects invalid input validation options", func(t *testing.T) {
+		req := createFlow([]map[string]any{
+			{
+				"step_name":    "ask_quantity",
+				"message":      "How many?",
+				"input_type":   "number",
+				"input_config": map[string]any{"min": 10, "max": 1},
+			},
+		})
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, `step "ask_quantity": min cannot be greater than max`)
+	})
+}
+
+func TestApp_CreateKeywordRule_ResponseContent(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	flow := createTestChatbotFlow(t, app, org.ID, "Apply")
+	user := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	createRule := func(responseType string, content map[string]any) *fastglue.Request {
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"keywords":         []string{"keyword"},
+			"response_type":    responseType,
+			"response_content": content,
+			"enabled":          true,
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateKeywordRule(req))
+		return req
+	}
+
+	valid := []struct {
+		name         string
+		responseType string
+		content      map[string]any
+	}{
+		{"template by id", "template", map[string]any{"template_id": template.ID.String(), "params": map[string]any{"1": "there"}}},
+		{"template by name", "template", map[string]any{"template_name": template.Name}},
+		{"media by id", "media", map[string]any{"media_type": "document", "media_id": "1234567890", "filename": "brochure.pdf"}},
+		{"whatsapp flow", "flow", map[string]any{"whatsapp_flow_id": "987654321", "body": "Apply in a minute", "cta": "Apply now"}},
+		{"chatbot flow", "script", map[string]any{"chatbot_flow_id": flow.ID.String()}},
+	}
+	for _, tc := range valid {
+		t.Run(tc.name, func(t *testing.T) {
+			req := createRule(tc.responseType, tc.content)
+			assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		})
+	}
+
+	invalid := []struct {
+		name         string
+		responseType string
+		content      map[string]any
+		message      string
+	}{
+		{"unknown type", "video", map[string]any{}, `invalid response_type "video"`},
+		{"template missing", "template", map[string]any{"template_id": uuid.New().String()}, "template not found"},
+		{"template params not strings", "template", map[string]any{"template_name": template.Name, "params": map[string]any{"1": 5}}, `template parameter "1" must be a string`},
+		{"media type", "media", map[string]any{"media_type": "sticker", "media_id": "1"}, "media_type must be image, video, audio or document"},
+		{"media source", "media", map[string]any{"media_type": "image"}, "media responses need either a media_id or a media_path"},
+		{"media traversal", "media", map[string]any{"media_type": "image", "media_path": "../etc/passwd"}, "invalid media_path"},
+		{"media file missing", "media", map[string]any{"media_type": "image", "media_path": "missing/banner.png"}, "media file not found"},
+		{"flow body", "flow", map[string]any{"whatsapp_flow_id": "987654321"}, "flow responses need a body"},
+		{"chatbot flow missing", "script", map[string]any{"chatbot_flow_id": uuid.New().String()}, "chatbot flow not found"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			req := createRule(tc.responseType, tc.content)
+			testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, tc.message)
+		})
+	}
+
+	t.Run("update validates the new content", func(t *testing.T) {
+		rule := createTestKeywordRule(t, app, org.ID, "Brochure", []string{"brochure"})
+		req := testutil.NewJSONRequest(t, map[string]any{"response_type": "media"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", rule.ID.String())
+		require.NoError(t, app.UpdateKeywordRule(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "media_type must be image, video, audio or document")
+	})
+}
+
+func TestApp_ChatbotFlowTestCases(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+	flow := createTestChatbotFlow(t, app, org.ID, "Feedback")
+	require.NoError(t, app.DB.Create(&models.ChatbotFlowStep{
+		FlowID:      flow.ID,
+		StepName:    "ask_rating",
+		StepOrder:   1,
+		Message:     "Rate us from 1 to 5",
+		MessageType: models.FlowStepTypeText,
+		InputType:   models.InputTypeNumber,
+		InputConfig: models.JSONB{"min": 1.0, "max": 5.0},
+		StoreAs:     "rating",
+	}).Error)
+
+	createTestCase := func(rating any) uuid.UUID {
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name": "stores the rating",
+			"turns": []map[string]any{
+				{"text": "feedback", "expect": map[string]any{"step": "ask_rating", "messages": []string{"Rate us"}}},
+				{"text": "4", "expect": map[string]any{"status": "completed", "session_data": map[string]any{"rating": rating}}},
+			},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", flow.ID.String())
+		require.NoError(t, app.CreateChatbotFlowTestCase(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var testCase models.ChatbotFlowTestCase
+		testutil.ParseEnvelopeResponse(t, req, &testCase)
+		return testCase.ID
+	}
+	passing := createTestCase(4)
+	failing := createTestCase(5)
+
+	req := testutil.NewJSONRequest(t, map[string]any{})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", flow.ID.String())
+	require.NoError(t, app.RunChatbotFlowTestCases(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Total   int                           `json:"total"`
+		Passed  int                           `json:"passed"`
+		Failed  int                           `json:"failed"`
+		Results []handlers.FlowTestCaseResult `json:"results"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, 1, result.Failed)
+
+	var saved models.ChatbotFlowTestCase
+	require.NoError(t, app.DB.First(&saved, passing).Error)
+	require.NotNil(t, saved.LastPassed)
+	assert.True(t, *saved.LastPassed)
+	require.NoError(t, app.DB.First(&saved, failing).Error)
+	require.NotNil(t, saved.LastPassed)
+	assert.False(t, *saved.LastPassed)
+	assert.Equal(t, models.StringArray{"turn 2: expected session_data.rating to be 5, got 4"}, saved.LastFailures)
+
+	// The runs left no contacts behind
+	var contacts int64
+	app.DB.Model(&models.Contact{}).Where("organization_id = ?", org.ID).Count(&contacts)
+	assert.Zero(t, contacts)
+}
+
+// =============================================================================
+// Flow versions
+// =============================================================================
+
+func TestApp_ChatbotFlowVersions(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+	step := func(name, message string) map[string]any {
+		return map[string]any{"step_name": name, "message": message, "input_type": "text", "store_as": name}
+	}
+	call := func(handler func(*fastglue.Request) error, body any, params map[string]string, query map[string]string) *fastglue.Request {
+		req := testutil.NewJSONRequest(t, body)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		for k, v := range params {
+			testutil.SetPathParam(req, k, v)
+		}
+		for k, v := range query {
+			testutil.SetQueryParam(req, k, v)
+		}
+		require.NoError(t, handler(req))
+		return req
+	}
+
+	// Creating a flow publishes version 1
+	req := call(app.CreateChatbotFlow, map[string]any{
+		"name":    "Signup",
+		"enabled": true,
+		"steps":   []map[string]any{step("ask_name", "Your name?")},
+	}, nil, nil)
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var created struct {
+		ID string `json:"id"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &created)
+	flowParam := map[string]string{"id": created.ID}
+
+	var flow models.ChatbotFlow
+	require.NoError(t, app.DB.First(&flow, "id = ?", created.ID).Error)
+	require.NotNil(t, flow.PublishedVersionID)
+	v1 := *flow.PublishedVersionID
+	assert.False(t, flow.HasDraftChanges)
+
+	// Nothing to publish yet
+	req = call(app.PublishChatbotFlow, map[string]any{}, flowParam, nil)
+	assert.Equal(t, fasthttp.StatusBadRequest, testutil.GetResponseStatusCode(req))
+
+	// Editing steps changes the draft only
+	req = call(app.UpdateChatbotFlow, map[string]any{
+		"steps": []map[string]any{step("ask_name", "What is your name?"), step("ask_email", "Your email?")},
+	}, flowParam, nil)
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	require.NoError(t, app.DB.First(&flow, "id = ?", created.ID).Error)
+	assert.True(t, flow.HasDraftChanges)
+	assert.Equal(t, v1, *flow.PublishedVersionID)
+
+	req = call(app.DiffChatbotFlowVersions, nil, flowParam, nil)
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var diff struct {
+		From handlers.FlowVersionRef `json:"from"`
+		To   handlers.FlowVersionRef `json:"to"`
+		Diff flowutil.Diff           `json:"diff"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &diff)
+	assert.Equal(t, 1, diff.From.Version)
+	assert.True(t, diff.To.Draft)
+	assert.Equal(t, []string{"ask_email"}, diff.Diff.AddedSteps)
+	require.Len(t, diff.Diff.ChangedSteps, 1)
+	assert.Equal(t, "ask_name", diff.Diff.ChangedSteps[0].Step)
+
+	// Publishing creates version 2
+	req = call(app.PublishChatbotFlow, map[string]any{"notes": "Ask for email"}, flowParam, nil)
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var published struct {
+		Version int `json:"version"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &published)
+	assert.Equal(t, 2, published.Version)
+
+	// Rolling back to version 1 publishes its definition as version 3
+	req = call(app.RollbackChatbotFlow, map[string]any{}, map[string]string{"id": created.ID, "version_id": v1.String()}, nil)
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	testutil.ParseEnvelopeResponse(t, req, &published)
+	assert.Equal(t, 3, published.Version)
+
+	var steps []models.ChatbotFlowStep
+	require.NoError(t, app.DB.Where("flow_id = ?", created.ID).Order("step_order").Find(&steps).Error)
+	require.Len(t, steps, 1)
+	assert.Equal(t, "Your name?", steps[0].Message)
+
+	req = call(app.DiffChatbotFlowVersions, nil, flowParam, map[string]string{"from": "1", "to": "published"})
+	testutil.ParseEnvelopeResponse(t, req, &diff)
+	assert.True(t, diff.Diff.Empty())
+
+	req = call(app.ListChatbotFlowVersions, nil, flowParam, nil)
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var list struct {
+		Versions        []handlers.FlowVersionResponse `json:"versions"`
+		HasDraftChanges bool                           `json:"has_draft_changes"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &list)
+	require.Len(t, list.Versions, 3)
+	assert.Equal(t, 3, list.Versions[0].Version)
+	assert.True(t, list.Versions[0].IsPublished)
+	assert.Equal(t, 1, list.Versions[0].RestoredFromVersion)
+	assert.Equal(t, "Ask for email", list.Versions[1].Notes)
+	assert.False(t, list.HasDraftChanges)
+}
+
+func TestApp_ChatbotFlowSubflowCycles(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	perms := getChatbotFlowPermissions(t, app)
+	role := testutil.CreateTestRole(t, app.DB, org.ID, "flow-admin", perms)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+	question := map[string]any{"step_name": "ask_city", "message": "Your city?", "input_type": "text", "store_as": "city"}
+	callStep := func(stepType string, flowID string) map[string]any {
+		return map[string]any{"step_name": "call", "message_type": stepType, "input_type": "none", "subflow_config": map[string]any{"flow_id": flowID}}
+	}
+	save := func(handler func(*fastglue.Request) error, id string, body map[string]any) *fastglue.Request {
+		req := testutil.NewJSONRequest(t, body)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		if id != "" {
+			testutil.SetPathParam(req, "id", id)
+		}
+		require.NoError(t, handler(req))
+		return req
+	}
+	createdID := func(req *fastglue.Request) string {
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		var created struct {
+			ID string `json:"id"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &created)
+		return created.ID
+	}
+
+	address := createdID(save(app.CreateChatbotFlow, "", map[string]any{
+		"name": "Address", "steps": []map[string]any{question},
+	}))
+	checkout := createdID(save(app.CreateChatbotFlow, "", map[string]any{
+		"name": "Checkout", "steps": []map[string]any{callStep("subflow", address)},
+	}))
+
+	// The address flow calling checkout back would nest calls without end
+	req := save(app.UpdateChatbotFlow, address, map[string]any{
+		"steps": []map[string]any{question, callStep("subflow", checkout)},
+	})
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "flows would call each other without end: Address → Checkout → Address")
+
+	// Going to checkout from a flow checkout never calls is fine
+	req = save(app.CreateChatbotFlow, "", map[string]any{
+		"name": "Menu", "steps": []map[string]any{question, callStep("goto_flow", checkout)},
+	})
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	req = save(app.CreateChatbotFlow, "", map[string]any{
+		"name": "Broken", "steps": []map[string]any{callStep("subflow", uuid.New().String())},
+	})
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, `step "call" uses a flow that was not found`)
+
+	req = save(app.CreateChatbotFlow, "", map[string]any{
+		"name": "Repeat",
+		"steps": []map[string]any{
+			{"step_name": "a", "message": "A", "input_type": "none", "next_step": "b"},
+			{"step_name": "b", "message": "B", "input_type": "none", "next_step": "a"},
+		},
+	})
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "steps a → b → a repeat without waiting for a reply; use a loop step to limit them")
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// ErrContactOptedOut is returned when a template message is sent to a contact
+// who has opted out of messages.
+var ErrContactOptedOut = errors.New("contact has opted out of messages")
+
+// ContactConsentRequest represents a manual consent change
+type ContactConsentRequest struct {
+	Status models.ConsentStatus `json:"status"`
+	Source models.ConsentSource `json:"source"` // manual (default), api or import
+	Proof  models.JSONB         `json:"proof"`
+}
+
+// ContactConsentResponse represents a contact's consent state and history
+type ContactConsentResponse struct {
+	ContactID        uuid.UUID               `json:"contact_id"`
+	ConsentStatus    models.ConsentStatus    `json:"consent_status"`
+	ConsentUpdatedAt *time.Time              `json:"consent_updated_at,omitempty"`
+	History          []models.ContactConsent `json:"history"`
+}
+
+// GetContactConsent returns a contact's current consent status and its history
+func (a *App) GetContactConsent(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionRead); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	var history []models.ContactConsent
+	if err := a.DB.Where("contact_id = ? AND organization_id = ?", contact.ID, orgID).
+		Order("recorded_at DESC").Find(&history).Error; err != nil {
+		a.Log.Error("Failed to load consent history", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load consent history", nil, "")
+	}
+
+	return r.SendEnvelope(ContactConsentResponse{
+		ContactID:        contact.ID,
+		ConsentStatus:    contact.ConsentStatus,
+		ConsentUpdatedAt: contact.ConsentUpdatedAt,
+		History:          history,
+	})
+}
+
+// RecordContactConsent records an opt-in or opt-out captured outside WhatsApp
+// (e.g. a signed form or a phone call)
+func (a *App) RecordContactConsent(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var req ContactConsentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if !req.Status.IsValid() {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "status must be opted_in or opted_out", nil, "")
+	}
+	if req.Source == "" {
+		req.Source = models.ConsentSourceManual
+	}
+	// Keyword records are only created from the contact's own messages
+	if !req.Source.IsValid() || req.Source == models.ConsentSourceKeyword {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "source must be manual, api or import", nil, "")
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	record, err := contactutil.RecordConsent(a.DB, contact, req.Status, req.Source, req.Proof, &userID)
+	if err != nil {
+		a.Log.Error("Failed to record consent", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to record consent", nil, "")
+	}
+
+	return r.SendEnvelope(record)
+}
+
+// handleConsentKeyword records an opt-out or opt-in when an incoming message is
+// one of the organization's consent keywords and sends the confirmation reply.
+// Returns true if the message was a consent keyword and needs no further processing.
+func (a *App) handleConsentKeyword(account *models.WhatsAppAccount, contact *models.Contact, waMessageID, messageText string) bool {
+	settings := contactutil.GetConsentSettings(a.DB, account.OrganizationID)
+	status, ok := contactutil.MatchConsentKeyword(settings, messageText)
+	if !ok {
+		return false
+	}
+
+	// Repeated keywords still get a confirmation but don't add history
+	if contact.ConsentStatus != status {
+		proof := models.JSONB{
+			"message_id":       waMessageID,
+			"text":             messageText,
+			"whatsapp_account": account.Name,
+		}
+		if _, err := contactutil.RecordConsent(a.DB, contact, status, models.ConsentSourceKeyword, proof, nil); err != nil {
+			a.Log.Error("Failed to record consent", "error", err, "contact_id", contact.ID)
+			return false
+		}
+		a.Log.Info("Contact consent updated from keyword", "contact_id", contact.ID, "status", status)
+	}
+
+	reply := settings.OptInReply
+	if status == models.ConsentStatusOptedOut {
+		reply = settings.OptOutReply
+	}
+	if reply != "" {
+		if err := a.sendAndSaveTextMessage(account, contact, reply); err != nil {
+			a.Log.Error("Failed to send consent confirmation", "error", err, "contact", contact.PhoneNumber)
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// --- RecordContactConsent Tests ---
+
+func TestApp_RecordContactConsent(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"status": "opted_in",
+			"proof":  map[string]any{"form_url": "https://example.com/signup"},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.RecordContactConsent(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp models.ContactConsent
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, models.ConsentStatusOptedIn, resp.Status)
+		assert.Equal(t, models.ConsentSourceManual, resp.Source)
+		require.NotNil(t, resp.CreatedBy)
+		assert.Equal(t, user.ID, *resp.CreatedBy)
+
+		req = testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err = app.GetContactConsent(req)
+		require.NoError(t, err)
+
+		var consent handlers.ContactConsentResponse
+		testutil.ParseEnvelopeResponse(t, req, &consent)
+		assert.Equal(t, models.ConsentStatusOptedIn, consent.ConsentStatus)
+		assert.Len(t, consent.History, 1)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"status": "maybe"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.RecordContactConsent(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "status must be")
+	})
+
+	t.Run("keyword source not allowed", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"status": "opted_out", "source": "keyword"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.RecordContactConsent(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "source must be")
+	})
+}
+
+// --- Consent enforcement Tests ---
+
+func TestApp_SendTemplateMessage_OptedOutContact(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(contact).Update("consent_status", models.ConsentStatusOptedOut).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"contact_id":  contact.ID.String(),
+		"template_id": template.ID.String(),
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.SendTemplateMessage(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "opted out")
+}
+
+func TestApp_UpdateOrganizationSettings_ConsentKeywords(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"consent_opt_out_keywords": []string{"stop", "baja"},
+		"consent_opt_in_keywords":  []string{"Baja"},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.UpdateOrganizationSettings(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "cannot be both")
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"consent_opt_out_keywords": []string{"stop", "baja"},
+		"consent_opt_out_reply":    "Listo, no recibirás más mensajes.",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err = app.UpdateOrganizationSettings(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err = app.GetOrganizationSettings(req)
+	require.NoError(t, err)
+
+	var resp struct {
+		Settings handlers.OrganizationSettings `json:"settings"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, []string{"STOP", "BAJA"}, resp.Settings.ConsentOptOutKeywords)
+	assert.Equal(t, "Listo, no recibirás más mensajes.", resp.Settings.ConsentOptOutReply)
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// ContactAttributeRequest represents the request body for creating/updating a contact attribute.
+// Key and Type can only be set on create.
+type ContactAttributeRequest struct {
+	Key         string                      `json:"key"`
+	Label       *string                     `json:"label"`
+	Type        models.ContactAttributeType `json:"type"`
+	Description *string                     `json:"description"`
+	Required    *bool                       `json:"required"`
+	Options     []string                    `json:"options"`
+	Min         *float64                    `json:"min"`
+	Max         *float64                    `json:"max"`
+	Pattern     *string                     `json:"pattern"`
+	Position    *int                        `json:"position"`
+}
+
+// ListContactAttributes returns all contact attribute definitions for the organization
+func (a *App) ListContactAttributes(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContactAttributes, models.ActionRead); err != nil {
+		return nil
+	}
+
+	attrs, err := a.getContactAttributesCached(orgID)
+	if err != nil {
+		a.Log.Error("Failed to list contact attributes", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list contact attributes", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"attributes": attrs,
+		"total":      len(attrs),
+	})
+}
+
+// CreateContactAttribute creates a new contact attribute definition
+func (a *App) CreateContactAttribute(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContactAttributes, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req ContactAttributeRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	attr := models.ContactAttribute{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		Key:            strings.TrimSpace(req.Key),
+		Type:           req.Type,
+		Options:        models.StringArray{},
+	}
+	applyContactAttributeRequest(&attr, &req)
+
+	if err := contactutil.ValidateAttributeDefinition(&attr); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	// Check for duplicate key
+	var existing models.ContactAttribute
+	if err := a.DB.Where("organization_id = ? AND key = ?", orgID, attr.Key).First(&existing).Error; err == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact attribute with this key already exists", nil, "")
+	}
+
+	if err := a.DB.Create(&attr).Error; err != nil {
+		a.Log.Error("Failed to create contact attribute", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact attribute", nil, "")
+	}
+
+	// Building the index can take a while on a large contacts table
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.ensureContactAttributeIndex(attr)
+	}()
+	a.InvalidateContactAttributesCache(orgID)
+
+	return r.SendEnvelope(attr)
+}
+
+// UpdateContactAttribute updates a contact attribute definition. Key and type are immutable.
+func (a *App) UpdateContactAttribute(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContactAttributes, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "contact attribute")
+	if err != nil {
+		return nil
+	}
+
+	var req ContactAttributeRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	attr, err := findByIDAndOrg[models.ContactAttribute](a.DB, r, id, orgID, "Contact attribute")
+	if err != nil {
+		return nil
+	}
+
+	if req.Key != "" && req.Key != attr.Key {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "key cannot be changed", nil, "")
+	}
+	if req.Type != "" && req.Type != attr.Type {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "type cannot be changed", nil, "")
+	}
+
+	applyContactAttributeRequest(attr, &req)
+
+	if err := contactutil.ValidateAttributeDefinition(attr); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	if err := a.DB.Model(attr).Select(
+		"label", "description", "required", "options", "min", "max", "pattern", "position",
+	).Updates(attr).Error; err != nil {
+		a.Log.Error("Failed to update contact attribute", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact attribute", nil, "")
+	}
+
+	a.InvalidateContactAttributesCache(orgID)
+
+	return r.SendEnvelope(attr)
+}
+
+// DeleteContactAttribute deletes a contact attribute definition and removes its values from contacts
+func (a *App) DeleteContactAttribute(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContactAttributes, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "contact attribute")
+	if err != nil {
+		return nil
+	}
+
+	attr, err := findByIDAndOrg[models.ContactAttribute](a.DB, r, id, orgID, "Contact attribute")
+	if err != nil {
+		return nil
+	}
+
+	// Remove the attribute from all contacts that have it
+	if err := a.DB.Exec(`
+		UPDATE contacts
+		SET attributes = attributes - ?
+		WHERE organization_id = ?
+		AND attributes->? IS NOT NULL
+	`, attr.Key, orgID, attr.Key).Error; err != nil {
+		a.Log.Error("Failed to remove attribute from contacts", "error", err)
+		// Continue anyway - unknown keys are ignored when reading
+	}
+
+	if err := a.DB.Delete(attr).Error; err != nil {
+		a.Log.Error("Failed to delete contact attribute", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete contact attribute", nil, "")
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.dropContactAttributeIndex(*attr)
+	}()
+	a.InvalidateContactAttributesCache(orgID)
+
+	return r.SendEnvelope(map[string]string{"message": "Contact attribute deleted"})
+}
+
+// applyContactAttributeRequest copies the mutable fields set in req onto attr
+func applyContactAttributeRequest(attr *models.ContactAttribute, req *ContactAttributeRequest) {
+	if req.Label != nil {
+		attr.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Description != nil {
+		attr.Description = *req.Description
+	}
+	if req.Required != nil {
+		attr.Required = *req.Required
+	}
+	if req.Options != nil {
+		attr.Options = models.StringArray(req.Options)
+	}
+	if req.Min != nil {
+		attr.Min = req.Min
+	}
+	if req.Max != nil {
+		attr.Max = req.Max
+	}
+	if req.Pattern != nil {
+		attr.Pattern = *req.Pattern
+	}
+	if req.Position != nil {
+		attr.Position = *req.Position
+	}
+}
+
+// contactAttributeIndexTypes maps the name suffix of attribute expression indexes to the
+// attribute types that use them
+var contactAttributeIndexTypes = map[string][]models.ContactAttributeType{
+	"num": {models.ContactAttributeTypeNumber},
+	"txt": {models.ContactAttributeTypeDate, models.ContactAttributeTypeText},
+}
+
+// contactAttributeIndex returns the name of the expression index of an attribute and its
+// suffix, or "" when the attribute has none
+func contactAttributeIndex(def models.ContactAttribute) (name, suffix string) {
+	if !contactutil.IsValidAttributeKey(def.Key) {
+		return "", ""
+	}
+	for suffix, types := range contactAttributeIndexTypes {
+		for _, t := range types {
+			if def.Type == t {
+				return fmt.Sprintf("idx_contacts_attr_%s_%s", suffix, def.Key), suffix
+			}
+		}
+	}
+	return "", ""
+}
+
+// ensureContactAttributeIndex creates an expression index for range and text filters on
+// the attribute. Equality filters on every type use the GIN index on contacts.attributes.
+// The index is built concurrently so contact writes are not blocked; this cannot run
+// inside a transaction.
+func (a *App) ensureContactAttributeIndex(def models.ContactAttribute) {
+	name, _ := contactAttributeIndex(def)
+	if name == "" {
+		return
+	}
+
+	sql := fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON contacts (%s)",
+		name, contactutil.AttributeSQLExpr("attributes", def))
+	if err := a.DB.Exec(sql).Error; err != nil {
+		a.Log.Error("Failed to create contact attribute index", "error", err, "key", def.Key)
+		// A failed concurrent build leaves an invalid index that IF NOT EXISTS would keep
+		if err := a.DB.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + name).Error; err != nil {
+			a.Log.Error("Failed to drop invalid contact attribute index", "error", err, "key", def.Key)
+		}
+	}
+}
+
+// dropContactAttributeIndex drops the expression index of a deleted attribute. Indexes are
+// named by key, not organization, so it is kept while any organization still has an
+// attribute using it. Like ensureContactAttributeIndex it runs concurrently, outside any
+// transaction.
+func (a *App) dropContactAttributeIndex(def models.ContactAttribute) {
+	name, suffix := contactAttributeIndex(def)
+	if name == "" {
+		return
+	}
+
+	var users int64
+	if err := a.DB.Model(&models.ContactAttribute{}).
+		Where("key = ? AND type IN ?", def.Key, contactAttributeIndexTypes[suffix]).
+		Count(&users).Error; err != nil {
+		a.Log.Error("Failed to check contact attribute index usage", "error", err, "key", def.Key)
+		return
+	}
+	if users > 0 {
+		return
+	}
+	if err := a.DB.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + name).Error; err != nil {
+		a.Log.Error("Failed to drop contact attribute index", "error", err, "key", def.Key)
+	}
+}
+
+// applyContactAttributeFilters adds ListContacts filters given as query parameters of the form
+// attr.<key>=value or attr.<key>.<op>=value. See contactutil.AttributeFilterOps for operators;
+// "in" takes comma-separated values.
+func (a *App) applyContactAttributeFilters(query *gorm.DB, orgID uuid.UUID, args *fasthttp.Args) (*gorm.DB, error) {
+	type filter struct {
+		key, op, value string
+	}
+	var filters []filter
+	args.VisitAll(func(k, v []byte) {
+		name := string(k)
+		if !strings.HasPrefix(name, "attr.") {
+			return
+		}
+		key, op, _ := strings.Cut(strings.TrimPrefix(name, "attr."), ".")
+		if op == "" {
+			op = "eq"
+		}
+		filters = append(filters, filter{key: key, op: op, value: string(v)})
+	})
+	if len(filters) == 0 {
+		return query, nil
+	}
+
+	defs, err := a.getContactAttributesCached(orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contact attributes")
+	}
+	byKey := make(map[string]models.ContactAttribute, len(defs))
+	for _, d := range defs {
+		byKey[d.Key] = d
+	}
+
+	for _, f := range filters {
+		def, ok := byKey[f.key]
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute filter: %s", f.key)
+		}
+		cond, condArgs, err := contactutil.AttributeCondition("attributes", def, f.op, f.value)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(cond, condArgs...)
+	}
+
+	return query, nil
+}
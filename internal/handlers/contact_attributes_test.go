@@ -0,0 +1,317 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// createTestContactAttribute creates a contact attribute definition directly in the database for testing.
+func createTestContactAttribute(t *testing.T, app *handlers.App, orgID uuid.UUID, attr models.ContactAttribute) *models.ContactAttribute {
+	t.Helper()
+
+	attr.ID = uuid.New()
+	attr.OrganizationID = orgID
+	if attr.Label == "" {
+		attr.Label = attr.Key
+	}
+	if attr.Options == nil {
+		attr.Options = models.StringArray{}
+	}
+	require.NoError(t, app.DB.Create(&attr).Error)
+	app.InvalidateContactAttributesCache(orgID)
+	return &attr
+}
+
+// --- CreateContactAttribute Tests ---
+
+func TestApp_CreateContactAttribute(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"key":     "plan",
+			"label":   "Plan",
+			"type":    "enum",
+			"options": []string{"free", "pro"},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateContactAttribute(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp models.ContactAttribute
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, "plan", resp.Key)
+		assert.Equal(t, models.ContactAttributeTypeEnum, resp.Type)
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"key":   "First Name",
+			"label": "First name",
+			"type":  "text",
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateContactAttribute(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "key must start with a letter")
+	})
+
+	t.Run("duplicate key", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "city", Type: models.ContactAttributeTypeText})
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"key":   "city",
+			"label": "City",
+			"type":  "text",
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateContactAttribute(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusConflict, "already exists")
+	})
+
+	t.Run("forbidden without write permission", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "readonly", []string{
+			"contact_attributes:read",
+		})
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"key":   "city",
+			"label": "City",
+			"type":  "text",
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateContactAttribute(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+	})
+}
+
+// --- UpdateContactAttribute Tests ---
+
+func TestApp_UpdateContactAttribute_TypeImmutable(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	attr := createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "age", Type: models.ContactAttributeTypeNumber})
+
+	req := testutil.NewJSONRequest(t, map[string]any{"type": "text"})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", attr.ID.String())
+
+	err := app.UpdateContactAttribute(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "type cannot be changed")
+}
+
+// --- DeleteContactAttribute Tests ---
+
+func TestApp_DeleteContactAttribute_RemovesValues(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	attr := createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "city", Type: models.ContactAttributeTypeText})
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(contact).Update("attributes", models.JSONB{"city": "Pune"}).Error)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", attr.ID.String())
+
+	err := app.DeleteContactAttribute(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var updated models.Contact
+	require.NoError(t, app.DB.First(&updated, contact.ID).Error)
+	assert.NotContains(t, updated.Attributes, "city")
+}
+
+func TestApp_DeleteContactAttribute_DropsIndex(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	other := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	otherRole := testutil.CreateAdminRole(t, app.DB, other.ID)
+	otherUser := testutil.CreateTestUser(t, app.DB, other.ID, testutil.WithRoleID(&otherRole.ID))
+
+	// Index names are per key, so use a key no other test shares
+	key := "score_" + uuid.New().String()[:8]
+	index := "idx_contacts_attr_num_" + key
+	indexExists := func() bool {
+		var count int64
+		require.NoError(t, app.DB.Raw("SELECT COUNT(*) FROM pg_indexes WHERE indexname = ?", index).Scan(&count).Error)
+		return count > 0
+	}
+	createAttr := func(orgID, userID uuid.UUID) uuid.UUID {
+		req := testutil.NewJSONRequest(t, map[string]any{"key": key, "label": "Score", "type": "number"})
+		testutil.SetAuthContext(req, orgID, userID)
+		require.NoError(t, app.CreateContactAttribute(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		var resp models.ContactAttribute
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		// The index is built in the background
+		app.WaitForBackgroundTasks()
+		return resp.ID
+	}
+	deleteAttr := func(orgID, userID, id uuid.UUID) {
+		req := testutil.NewJSONRequest(t, nil)
+		testutil.SetAuthContext(req, orgID, userID)
+		testutil.SetPathParam(req, "id", id.String())
+		require.NoError(t, app.DeleteContactAttribute(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		app.WaitForBackgroundTasks()
+	}
+
+	id := createAttr(org.ID, user.ID)
+	otherID := createAttr(other.ID, otherUser.ID)
+	require.True(t, indexExists())
+
+	// Kept while another organization's attribute still uses it
+	deleteAttr(org.ID, user.ID, id)
+	assert.True(t, indexExists())
+
+	deleteAttr(other.ID, otherUser.ID, otherID)
+	assert.False(t, indexExists())
+}
+
+// --- Contact attribute values Tests ---
+
+func TestApp_CreateContact_ValidatesAttributes(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "plan", Type: models.ContactAttributeTypeEnum, Required: true, Options: models.StringArray{"free", "pro"}})
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"phone_number": "919876543210",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.CreateContact(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "plan: is required")
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"phone_number": "919876543210",
+		"attributes":   map[string]any{"plan": "PRO"},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err = app.CreateContact(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Attributes map[string]any `json:"attributes"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, "pro", resp.Attributes["plan"])
+}
+
+func TestApp_ListContacts_AttributeFilters(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "orders", Type: models.ContactAttributeTypeNumber})
+	createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "city", Type: models.ContactAttributeTypeText})
+
+	c1 := testutil.CreateTestContact(t, app.DB, org.ID)
+	c2 := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(c1).Update("attributes", models.JSONB{"orders": 12, "city": "Pune"}).Error)
+	require.NoError(t, app.DB.Model(c2).Update("attributes", models.JSONB{"orders": 3, "city": "Mumbai"}).Error)
+
+	tests := []struct {
+		name  string
+		param string
+		value string
+		want  int64
+	}{
+		{"equality", "attr.city", "Pune", 1},
+		{"numeric range", "attr.orders.gte", "5", 1},
+		{"in", "attr.city.in", "Pune,Mumbai", 2},
+		{"contains", "attr.city.contains", "mum", 1},
+		{"not equal", "attr.city.ne", "Pune", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := testutil.NewGETRequest(t)
+			testutil.SetAuthContext(req, org.ID, user.ID)
+			testutil.SetQueryParam(req, tt.param, tt.value)
+
+			err := app.ListContacts(req)
+			require.NoError(t, err)
+			assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+			var resp struct {
+				Total int64 `json:"total"`
+			}
+			testutil.ParseEnvelopeResponse(t, req, &resp)
+			assert.Equal(t, tt.want, resp.Total)
+		})
+	}
+
+	t.Run("unknown attribute", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetQueryParam(req, "attr.missing", "x")
+
+		err := app.ListContacts(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "unknown attribute filter")
+	})
+
+	t.Run("range on text attribute", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetQueryParam(req, "attr.city.gt", "A")
+
+		err := app.ListContacts(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "only supported for number and date")
+	})
+}
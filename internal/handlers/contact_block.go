@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// BlockContactRequest represents a request to block a contact
+type BlockContactRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BlockedContactResponse represents a blocked contact in API responses
+type BlockedContactResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	PhoneNumber     string     `json:"phone_number"`
+	ProfileName     string     `json:"profile_name"`
+	WhatsAppAccount string     `json:"whatsapp_account"`
+	BlockedAt       *time.Time `json:"blocked_at"`
+	BlockedBy       *uuid.UUID `json:"blocked_by,omitempty"`
+	BlockReason     string     `json:"block_reason"`
+}
+
+// ListBlockedContacts returns the organization's blocked contacts
+func (a *App) ListBlockedContacts(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.Contact{}).Where("organization_id = ? AND is_blocked = ?", orgID, true)
+	if search != "" {
+		searchPattern := "%" + search + "%"
+		query = query.Where("phone_number ILIKE ? OR profile_name ILIKE ?", searchPattern, searchPattern)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var contacts []models.Contact
+	if err := pg.Apply(query.Order("blocked_at DESC")).Find(&contacts).Error; err != nil {
+		a.Log.Error("Failed to list blocked contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list blocked contacts", nil, "")
+	}
+
+	shouldMask := a.ShouldMaskPhoneNumbers(orgID)
+	response := make([]BlockedContactResponse, len(contacts))
+	for i, c := range contacts {
+		response[i] = buildBlockedContactResponse(&c, shouldMask)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"contacts": response,
+		"total":    total,
+		"page":     pg.Page,
+		"limit":    pg.Limit,
+	})
+}
+
+// BlockContact blocks a contact on WhatsApp and records the block locally.
+// Messages from blocked contacts are still stored but skip the chatbot,
+// agent transfers and notifications.
+func (a *App) BlockContact(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var req BlockContactRequest
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+	if contact.IsBlocked {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact is already blocked", nil, "")
+	}
+
+	account, err := a.contactBlockAccount(r, contact)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := a.WhatsApp.BlockUser(ctx, a.toWhatsAppAccount(account), contact.PhoneNumber); err != nil {
+		a.Log.Error("Failed to block contact on WhatsApp", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Failed to block contact on WhatsApp: "+err.Error(), nil, "")
+	}
+
+	now := time.Now()
+	reason := strings.TrimSpace(req.Reason)
+	if err := a.DB.Model(contact).Updates(map[string]interface{}{
+		"is_blocked":   true,
+		"blocked_at":   now,
+		"blocked_by":   userID,
+		"block_reason": reason,
+	}).Error; err != nil {
+		a.Log.Error("Failed to record contact block", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to block contact", nil, "")
+	}
+	contact.IsBlocked = true
+	contact.BlockedAt = &now
+	contact.BlockedBy = &userID
+	contact.BlockReason = reason
+
+	a.DispatchWebhook(orgID, models.WebhookEventContactBlocked, ContactBlockedEventData{
+		ContactEventData: ContactEventData{
+			ContactID:       contact.ID.String(),
+			ContactPhone:    contact.PhoneNumber,
+			ContactName:     contact.ProfileName,
+			WhatsAppAccount: account.Name,
+		},
+		Reason:    reason,
+		BlockedBy: userID.String(),
+	})
+
+	return r.SendEnvelope(buildBlockedContactResponse(contact, a.ShouldMaskPhoneNumbers(orgID)))
+}
+
+// UnblockContact unblocks a contact on WhatsApp and clears the local block
+func (a *App) UnblockContact(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+	if !contact.IsBlocked {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Contact is not blocked", nil, "")
+	}
+
+	account, err := a.contactBlockAccount(r, contact)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := a.WhatsApp.UnblockUser(ctx, a.toWhatsAppAccount(account), contact.PhoneNumber); err != nil {
+		a.Log.Error("Failed to unblock contact on WhatsApp", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusBadGateway, "Failed to unblock contact on WhatsApp: "+err.Error(), nil, "")
+	}
+
+	if err := a.DB.Model(contact).Updates(map[string]interface{}{
+		"is_blocked":   false,
+		"blocked_at":   nil,
+		"blocked_by":   nil,
+		"block_reason": "",
+	}).Error; err != nil {
+		a.Log.Error("Failed to clear contact block", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to unblock contact", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"message": "Contact unblocked",
+	})
+}
+
+// contactBlockAccount returns the WhatsApp account the contact messages,
+// falling back to the organization's default outgoing account.
+// Sends an error envelope and returns errEnvelopeSent if none is configured.
+func (a *App) contactBlockAccount(r *fastglue.Request, contact *models.Contact) (*models.WhatsAppAccount, error) {
+	var account models.WhatsAppAccount
+	if contact.WhatsAppAccount != "" {
+		if err := a.DB.Where("name = ? AND organization_id = ?", contact.WhatsAppAccount, contact.OrganizationID).First(&account).Error; err != nil {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "WhatsApp account not found", nil, "")
+			return nil, errEnvelopeSent
+		}
+		return &account, nil
+	}
+	if err := a.DB.Where("organization_id = ? AND is_default_outgoing = ?", contact.OrganizationID, true).First(&account).Error; err != nil {
+		if err := a.DB.Where("organization_id = ?", contact.OrganizationID).First(&account).Error; err != nil {
+			_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No WhatsApp account configured", nil, "")
+			return nil, errEnvelopeSent
+		}
+	}
+	return &account, nil
+}
+
+// buildBlockedContactResponse converts a blocked contact to its API response
+func buildBlockedContactResponse(contact *models.Contact, maskPhone bool) BlockedContactResponse {
+	phoneNumber := contact.PhoneNumber
+	profileName := contact.ProfileName
+	if maskPhone {
+		phoneNumber = MaskPhoneNumber(phoneNumber)
+		profileName = MaskIfPhoneNumber(profileName)
+	}
+	return BlockedContactResponse{
+		ID:              contact.ID,
+		PhoneNumber:     phoneNumber,
+		ProfileName:     profileName,
+		WhatsAppAccount: contact.WhatsAppAccount,
+		BlockedAt:       contact.BlockedAt,
+		BlockedBy:       contact.BlockedBy,
+		BlockReason:     contact.BlockReason,
+	}
+}
@@ -0,0 +1,152 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// newBlockTestApp creates a test App whose WhatsApp client talks to a mock
+// block_users endpoint. The returned counter tracks calls to that endpoint.
+func newBlockTestApp(t *testing.T, status int) (*handlers.App, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/block_users") {
+			atomic.AddInt32(&calls, 1)
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte(`{"error":{"message":"Re-engagement check failed","code":139100}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","block_users":{"added_users":[]}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	waClient := whatsapp.NewWithBaseURL(testutil.NopLogger(), server.URL)
+	return newTestApp(t, withWhatsApp(waClient)), &calls
+}
+
+// --- BlockContact Tests ---
+
+func TestApp_BlockContact(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app, calls := newBlockTestApp(t, http.StatusOK)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"reason": "abusive"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.BlockContact(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+		var updated models.Contact
+		require.NoError(t, app.DB.First(&updated, contact.ID).Error)
+		assert.True(t, updated.IsBlocked)
+		assert.Equal(t, "abusive", updated.BlockReason)
+		require.NotNil(t, updated.BlockedBy)
+		assert.Equal(t, user.ID, *updated.BlockedBy)
+
+		// Blocked contacts are listed
+		req = testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err = app.ListBlockedContacts(req)
+		require.NoError(t, err)
+
+		var resp struct {
+			Contacts []handlers.BlockedContactResponse `json:"contacts"`
+			Total    int64                             `json:"total"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, int64(1), resp.Total)
+		assert.Equal(t, contact.ID, resp.Contacts[0].ID)
+	})
+
+	t.Run("meta error is not recorded", func(t *testing.T) {
+		app, _ := newBlockTestApp(t, http.StatusBadRequest)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, nil)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.BlockContact(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadGateway, "Re-engagement check failed")
+
+		var updated models.Contact
+		require.NoError(t, app.DB.First(&updated, contact.ID).Error)
+		assert.False(t, updated.IsBlocked)
+	})
+
+	t.Run("forbidden without write permission", func(t *testing.T) {
+		app, calls := newBlockTestApp(t, http.StatusOK)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "readonly", []string{"contacts:read"})
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, nil)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.BlockContact(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+		assert.Zero(t, atomic.LoadInt32(calls))
+	})
+}
+
+// --- UnblockContact Tests ---
+
+func TestApp_UnblockContact(t *testing.T) {
+	t.Parallel()
+
+	app, calls := newBlockTestApp(t, http.StatusOK)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(contact).Updates(map[string]any{"is_blocked": true, "block_reason": "spam"}).Error)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	err := app.UnblockContact(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	var updated models.Contact
+	require.NoError(t, app.DB.First(&updated, contact.ID).Error)
+	assert.False(t, updated.IsBlocked)
+	assert.Empty(t, updated.BlockReason)
+}
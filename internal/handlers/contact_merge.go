@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// MergeContactsRequest represents the request body for merging contacts
+type MergeContactsRequest struct {
+	SourceIDs []string `json:"source_ids" validate:"required"`
+}
+
+// DuplicateContactSummary is a lightweight contact entry in a duplicate group
+type DuplicateContactSummary struct {
+	ID          uuid.UUID `json:"id"`
+	PhoneNumber string    `json:"phone_number"`
+	ProfileName string    `json:"profile_name"`
+}
+
+// DuplicateGroupResponse represents contacts that normalize to the same phone number
+type DuplicateGroupResponse struct {
+	PhoneNumber string                    `json:"phone_number"`
+	Survivor    DuplicateContactSummary   `json:"survivor"`
+	Duplicates  []DuplicateContactSummary `json:"duplicates"`
+}
+
+// MergeContact merges the given source contacts into the contact in the path
+func (a *App) MergeContact(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceContacts, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You do not have permission to merge contacts", nil, "")
+	}
+
+	targetID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var req MergeContactsRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if len(req.SourceIDs) == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "source_ids is required", nil, "")
+	}
+
+	sourceIDs := make([]uuid.UUID, 0, len(req.SourceIDs))
+	for _, s := range req.SourceIDs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid source contact ID", nil, "")
+		}
+		if id == targetID {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Cannot merge a contact into itself", nil, "")
+		}
+		sourceIDs = append(sourceIDs, id)
+	}
+
+	target, err := findByIDAndOrg[models.Contact](a.DB, r, targetID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	// Store the survivor in normalized form; keep the current number if it can't be parsed
+	phone, err := contactutil.NormalizePhone(a.DB, orgID, target.PhoneNumber)
+	if err != nil {
+		phone = ""
+	}
+
+	result, err := contactutil.MergeContacts(a.DB, orgID, targetID, sourceIDs, phone)
+	if err != nil {
+		if errors.Is(err, contactutil.ErrContactNotFound) {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Contact not found", nil, "")
+		}
+		a.Log.Error("Failed to merge contacts", "error", err, "contact_id", targetID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to merge contacts", nil, "")
+	}
+
+	a.Log.Info("Contacts merged", "contact_id", targetID, "merged", len(result.MergedContactIDs),
+		"messages_moved", result.MessagesMoved, "user_id", userID)
+
+	if err := a.DB.First(target, targetID).Error; err != nil {
+		a.Log.Error("Failed to reload contact", "error", err)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"message": "Contacts merged successfully",
+		"result":  result,
+		"contact": a.buildContactResponse(target, orgID),
+	})
+}
+
+// ListDuplicateContacts returns groups of contacts whose phone numbers normalize to the same number
+func (a *App) ListDuplicateContacts(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceContacts, models.ActionRead, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You do not have permission to view contacts", nil, "")
+	}
+
+	groups, err := contactutil.FindDuplicateContacts(a.DB, orgID, contactutil.DefaultRegion(a.DB, orgID))
+	if err != nil {
+		a.Log.Error("Failed to find duplicate contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to find duplicate contacts", nil, "")
+	}
+
+	shouldMask := a.ShouldMaskPhoneNumbers(orgID)
+	summarize := func(c models.Contact) DuplicateContactSummary {
+		s := DuplicateContactSummary{ID: c.ID, PhoneNumber: c.PhoneNumber, ProfileName: c.ProfileName}
+		if shouldMask {
+			s.PhoneNumber = MaskPhoneNumber(s.PhoneNumber)
+			s.ProfileName = MaskIfPhoneNumber(s.ProfileName)
+		}
+		return s
+	}
+
+	response := make([]DuplicateGroupResponse, len(groups))
+	duplicateCount := 0
+	for i, g := range groups {
+		phone := g.PhoneNumber
+		if shouldMask {
+			phone = MaskPhoneNumber(phone)
+		}
+		response[i] = DuplicateGroupResponse{
+			PhoneNumber: phone,
+			Survivor:    summarize(g.Survivor),
+			Duplicates:  make([]DuplicateContactSummary, len(g.Duplicates)),
+		}
+		for j, d := range g.Duplicates {
+			response[i].Duplicates[j] = summarize(d)
+		}
+		duplicateCount += len(g.Duplicates)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"groups":          response,
+		"total_groups":    len(response),
+		"duplicate_count": duplicateCount,
+	})
+}
+
+// DedupeContacts merges every group of duplicate contacts into its survivor.
+// Merging runs in the background; the response reports what will be merged.
+func (a *App) DedupeContacts(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if !a.HasPermission(userID, models.ResourceContacts, models.ActionWrite, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You do not have permission to merge contacts", nil, "")
+	}
+
+	region := contactutil.DefaultRegion(a.DB, orgID)
+	groups, err := contactutil.FindDuplicateContacts(a.DB, orgID, region)
+	if err != nil {
+		a.Log.Error("Failed to find duplicate contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to find duplicate contacts", nil, "")
+	}
+
+	duplicateCount := 0
+	for _, g := range groups {
+		duplicateCount += len(g.Duplicates)
+	}
+
+	if len(groups) > 0 {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.runContactDedupe(orgID, groups)
+		}()
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"message":         "Contact deduplication started",
+		"total_groups":    len(groups),
+		"duplicate_count": duplicateCount,
+	})
+}
+
+// runContactDedupe merges each duplicate group, logging failures and continuing
+func (a *App) runContactDedupe(orgID uuid.UUID, groups []contactutil.DuplicateGroup) {
+	merged, failed := 0, 0
+	for _, g := range groups {
+		sourceIDs := make([]uuid.UUID, len(g.Duplicates))
+		for i, d := range g.Duplicates {
+			sourceIDs[i] = d.ID
+		}
+		if _, err := contactutil.MergeContacts(a.DB, orgID, g.Survivor.ID, sourceIDs, g.PhoneNumber); err != nil {
+			a.Log.Error("Failed to merge duplicate contacts", "error", err, "contact_id", g.Survivor.ID, "phone", g.PhoneNumber)
+			failed++
+			continue
+		}
+		merged += len(sourceIDs)
+	}
+	a.Log.Info("Contact deduplication completed", "org_id", orgID, "groups", len(groups), "merged", merged, "failed_groups", failed)
+}
@@ -0,0 +1,198 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// --- MergeContact Tests ---
+
+func TestApp_MergeContact(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success moves messages and tags", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		target := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("+919876543210"))
+		source := testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("919876543210"))
+		require.NoError(t, app.DB.Model(source).Update("tags", models.JSONBArray{"lead"}).Error)
+
+		msg := models.Message{
+			BaseModel:       models.BaseModel{ID: uuid.New()},
+			OrganizationID:  org.ID,
+			WhatsAppAccount: "test",
+			ContactID:       source.ID,
+			Direction:       models.DirectionIncoming,
+			MessageType:     models.MessageTypeText,
+			Content:         "hi",
+		}
+		require.NoError(t, app.DB.Create(&msg).Error)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"source_ids": []string{source.ID.String()},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", target.ID.String())
+
+		err := app.MergeContact(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Result struct {
+				MessagesMoved int64  `json:"messages_moved"`
+				PhoneNumber   string `json:"phone_number"`
+			} `json:"result"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, int64(1), resp.Result.MessagesMoved)
+		assert.Equal(t, "919876543210", resp.Result.PhoneNumber)
+
+		var merged models.Contact
+		require.NoError(t, app.DB.First(&merged, target.ID).Error)
+		assert.Equal(t, "919876543210", merged.PhoneNumber)
+		assert.Contains(t, []interface{}(merged.Tags), "lead")
+
+		var movedMsg models.Message
+		require.NoError(t, app.DB.First(&movedMsg, msg.ID).Error)
+		assert.Equal(t, target.ID, movedMsg.ContactID)
+	})
+
+	t.Run("source from another org", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		otherOrg := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		target := testutil.CreateTestContact(t, app.DB, org.ID)
+		foreign := testutil.CreateTestContact(t, app.DB, otherOrg.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"source_ids": []string{foreign.ID.String()},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", target.ID.String())
+
+		err := app.MergeContact(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusNotFound, "Contact not found")
+	})
+
+	t.Run("cannot merge into itself", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		target := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"source_ids": []string{target.ID.String()},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", target.ID.String())
+
+		err := app.MergeContact(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "into itself")
+	})
+
+	t.Run("forbidden without write permission", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		readOnlyRole := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "readonly", []string{
+			"contacts:read",
+		})
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&readOnlyRole.ID))
+		target := testutil.CreateTestContact(t, app.DB, org.ID)
+		source := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"source_ids": []string{source.ID.String()},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", target.ID.String())
+
+		err := app.MergeContact(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusForbidden, "permission")
+	})
+}
+
+// --- ListDuplicateContacts Tests ---
+
+func TestApp_ListDuplicateContacts(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	require.NoError(t, app.DB.Model(org).Update("settings", models.JSONB{"default_phone_region": "IN"}).Error)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+
+	testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("919876543210"))
+	testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("09876543210"))
+	testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("447911123456"))
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.ListDuplicateContacts(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		TotalGroups    int `json:"total_groups"`
+		DuplicateCount int `json:"duplicate_count"`
+		Groups         []struct {
+			PhoneNumber string `json:"phone_number"`
+			Survivor    struct {
+				PhoneNumber string `json:"phone_number"`
+			} `json:"survivor"`
+		} `json:"groups"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, 1, resp.TotalGroups)
+	assert.Equal(t, 1, resp.DuplicateCount)
+	require.Len(t, resp.Groups, 1)
+	assert.Equal(t, "919876543210", resp.Groups[0].PhoneNumber)
+	assert.Equal(t, "919876543210", resp.Groups[0].Survivor.PhoneNumber)
+}
+
+// --- CreateContact normalization Tests ---
+
+func TestApp_CreateContact_NormalizesWithDefaultRegion(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	require.NoError(t, app.DB.Model(org).Update("settings", models.JSONB{"default_phone_region": "IN"}).Error)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	testutil.CreateTestContactWith(t, app.DB, org.ID, testutil.WithPhoneNumber("919876543210"))
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"phone_number": "098765 43210",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.CreateContact(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusConflict, "already exists")
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"phone_number": "not-a-number",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err = app.CreateContact(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid phone number")
+}
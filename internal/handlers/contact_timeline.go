@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// Timeline event types. Activity log entries use their ContactActivityType.
+const (
+	TimelineEventMessage          = "message"
+	TimelineEventSessionStarted   = "chatbot_session_started"
+	TimelineEventSessionEnded     = "chatbot_session_ended"
+	TimelineEventTransferCreated  = "transfer_created"
+	TimelineEventTransferAssigned = string(models.ContactActivityTransferAssigned)
+	TimelineEventTransferResumed  = "transfer_resumed"
+	TimelineEventSLABreached      = "sla_breached"
+	TimelineEventCampaignDelivery = "campaign_delivery"
+	TimelineEventConsentChanged   = "consent_changed"
+	TimelineEventTagsChanged      = string(models.ContactActivityTagsChanged)
+	TimelineEventAssignment       = string(models.ContactActivityAssignmentChanged)
+	TimelineEventCustomAction     = string(models.ContactActivityCustomAction)
+)
+
+// ContactTimelineEvent is a single entry in a contact's activity timeline
+type ContactTimelineEvent struct {
+	ID        string         `json:"id"` // "<type>:<source_id>", unique across the timeline
+	Type      string         `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	SourceID  uuid.UUID      `json:"source_id"` // ID of the message, session, transfer, etc.
+	ActorID   *uuid.UUID     `json:"actor_id,omitempty"`
+	Data      map[string]any `json:"data"`
+}
+
+// timelineCursor is the position of the last event returned. Events are
+// ordered by timestamp, then type, then source ID, all descending.
+type timelineCursor struct {
+	Timestamp time.Time
+	Type      string
+	SourceID  uuid.UUID
+}
+
+func (c timelineCursor) encode() string {
+	raw := c.Timestamp.UTC().Format(time.RFC3339Nano) + "|" + c.Type + "|" + c.SourceID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTimelineCursor(s string) (*timelineCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid cursor")
+	}
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &timelineCursor{Timestamp: ts, Type: parts[1], SourceID: id}, nil
+}
+
+// timelineQuery holds the parameters shared by all timeline sources
+type timelineQuery struct {
+	orgID   uuid.UUID
+	contact *models.Contact
+	cursor  *timelineCursor
+	limit   int
+}
+
+// scope restricts db to events of type typ that come after the cursor and
+// orders and limits them the way the merged timeline is ordered. Since typ is
+// constant per query, the (timestamp, type, id) keyset reduces to a
+// comparison on timestamp and id.
+func (q timelineQuery) scope(db *gorm.DB, typ, timeCol, idCol string) *gorm.DB {
+	db = db.Where(timeCol + " IS NOT NULL")
+	if c := q.cursor; c != nil {
+		switch {
+		case typ < c.Type:
+			db = db.Where(timeCol+" <= ?", c.Timestamp)
+		case typ > c.Type:
+			db = db.Where(timeCol+" < ?", c.Timestamp)
+		default:
+			db = db.Where("("+timeCol+" < ? OR ("+timeCol+" = ? AND "+idCol+" < ?))", c.Timestamp, c.Timestamp, c.SourceID)
+		}
+	}
+	// Fetch one extra row so the merged page knows whether more events exist
+	return db.Order(timeCol + " DESC").Order(idCol + " DESC").Limit(q.limit + 1)
+}
+
+// timelineSource loads events of a single type for a contact
+type timelineSource struct {
+	Type  string
+	fetch func(a *App, q timelineQuery) ([]ContactTimelineEvent, error)
+}
+
+func newTimelineEvent(typ string, ts time.Time, sourceID uuid.UUID, actorID *uuid.UUID, data map[string]any) ContactTimelineEvent {
+	return ContactTimelineEvent{
+		ID:        typ + ":" + sourceID.String(),
+		Type:      typ,
+		Timestamp: ts,
+		SourceID:  sourceID,
+		ActorID:   actorID,
+		Data:      data,
+	}
+}
+
+// activityTimelineSource returns a source for activity log entries of the given type
+func activityTimelineSource(activityType models.ContactActivityType) timelineSource {
+	typ := string(activityType)
+	return timelineSource{Type: typ, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var activities []models.ContactActivity
+		db := a.DB.Where("organization_id = ? AND contact_id = ? AND type = ?", q.orgID, q.contact.ID, activityType)
+		if err := q.scope(db, typ, "created_at", "id").Find(&activities).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(activities))
+		for _, act := range activities {
+			events = append(events, newTimelineEvent(typ, act.CreatedAt, act.ID, act.ActorID, act.Data))
+		}
+		return events, nil
+	}}
+}
+
+// timelineSources lists every source merged into the contact timeline
+var timelineSources = []timelineSource{
+	{Type: TimelineEventMessage, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var messages []models.Message
+		db := a.DB.Where("organization_id = ? AND contact_id = ?", q.orgID, q.contact.ID)
+		if err := q.scope(db, TimelineEventMessage, "created_at", "id").Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(messages))
+		for _, m := range messages {
+			events = append(events, newTimelineEvent(TimelineEventMessage, m.CreatedAt, m.ID, m.SentByUserID, map[string]any{
+				"direction":     m.Direction,
+				"message_type":  m.MessageType,
+				"content":       truncateString(m.Content, 200),
+				"template_name": m.TemplateName,
+				"status":        m.Status,
+			}))
+		}
+		return events, nil
+	}},
+	{Type: TimelineEventSessionStarted, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var sessions []models.ChatbotSession
+		db := a.DB.Where("organization_id = ? AND contact_id = ?", q.orgID, q.contact.ID)
+		if err := q.scope(db, TimelineEventSessionStarted, "started_at", "id").Find(&sessions).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(sessions))
+		for _, s := range sessions {
+			events = append(events, newTimelineEvent(TimelineEventSessionStarted, s.StartedAt, s.ID, nil, map[string]any{
+				"flow_id":          s.CurrentFlowID,
+				"whatsapp_account": s.WhatsAppAccount,
+			}))
+		}
+		return events, nil
+	}},
+	{Type: TimelineEventSessionEnded, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var sessions []models.ChatbotSession
+		db := a.DB.Where("organization_id = ? AND contact_id = ?", q.orgID, q.contact.ID)
+		if err := q.scope(db, TimelineEventSessionEnded, "completed_at", "id").Find(&sessions).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(sessions))
+		for _, s := range sessions {
+			events = append(events, newTimelineEvent(TimelineEventSessionEnded, *s.CompletedAt, s.ID, nil, map[string]any{
+				"status":       s.Status,
+				"flow_id":      s.CurrentFlowID,
+				"current_step": s.CurrentStep,
+			}))
+		}
+		return events, nil
+	}},
+	{Type: TimelineEventTransferCreated, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var transfers []models.AgentTransfer
+		db := a.DB.Where("organization_id = ? AND contact_id = ?", q.orgID, q.contact.ID)
+		if err := q.scope(db, TimelineEventTransferCreated, "transferred_at", "id").Find(&transfers).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(transfers))
+		for _, t := range transfers {
+			events = append(events, newTimelineEvent(TimelineEventTransferCreated, t.TransferredAt, t.ID, t.TransferredByUserID, map[string]any{
+				"source":   t.Source,
+				"agent_id": t.AgentID,
+				"team_id":  t.TeamID,
+				"notes":    t.Notes,
+			}))
+		}
+		return events, nil
+	}},
+	activityTimelineSource(models.ContactActivityTransferAssigned),
+	{Type: TimelineEventTransferResumed, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var transfers []models.AgentTransfer
+		db := a.DB.Where("organization_id = ? AND contact_id = ?", q.orgID, q.contact.ID)
+		if err := q.scope(db, TimelineEventTransferResumed, "resumed_at", "id").Find(&transfers).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(transfers))
+		for _, t := range transfers {
+			events = append(events, newTimelineEvent(TimelineEventTransferResumed, *t.ResumedAt, t.ID, t.ResumedBy, map[string]any{
+				"agent_id": t.AgentID,
+				"team_id":  t.TeamID,
+			}))
+		}
+		return events, nil
+	}},
+	{Type: TimelineEventSLABreached, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var transfers []models.AgentTransfer
+		db := a.DB.Where("organization_id = ? AND contact_id = ? AND sla_breached = ?", q.orgID, q.contact.ID, true)
+		if err := q.scope(db, TimelineEventSLABreached, "sla_breached_at", "id").Find(&transfers).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(transfers))
+		for _, t := range transfers {
+			events = append(events, newTimelineEvent(TimelineEventSLABreached, *t.SLA.BreachedAt, t.ID, nil, map[string]any{
+				"agent_id":         t.AgentID,
+				"team_id":          t.TeamID,
+				"escalation_level": t.SLA.EscalationLevel,
+			}))
+		}
+		return events, nil
+	}},
+	{Type: TimelineEventCampaignDelivery, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		// Campaign recipients are keyed by phone number rather than contact
+		var rows []struct {
+			ID           uuid.UUID
+			CampaignID   uuid.UUID
+			CampaignName string
+			Status       models.MessageStatus
+			ErrorMessage string
+			SentAt       time.Time
+			DeliveredAt  *time.Time
+			ReadAt       *time.Time
+		}
+		db := a.DB.Table("bulk_message_recipients bmr").
+			Select("bmr.id, bmr.campaign_id, c.name AS campaign_name, bmr.status, bmr.error_message, bmr.sent_at, bmr.delivered_at, bmr.read_at").
+			Joins("JOIN bulk_message_campaigns c ON c.id = bmr.campaign_id AND c.deleted_at IS NULL").
+			Where("c.organization_id = ? AND bmr.phone_number = ? AND bmr.deleted_at IS NULL", q.orgID, q.contact.PhoneNumber)
+		if err := q.scope(db, TimelineEventCampaignDelivery, "bmr.sent_at", "bmr.id").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(rows))
+		for _, row := range rows {
+			events = append(events, newTimelineEvent(TimelineEventCampaignDelivery, row.SentAt, row.ID, nil, map[string]any{
+				"campaign_id":   row.CampaignID,
+				"campaign_name": row.CampaignName,
+				"status":        row.Status,
+				"error_message": row.ErrorMessage,
+				"delivered_at":  row.DeliveredAt,
+				"read_at":       row.ReadAt,
+			}))
+		}
+		return events, nil
+	}},
+	{Type: TimelineEventConsentChanged, fetch: func(a *App, q timelineQuery) ([]ContactTimelineEvent, error) {
+		var records []models.ContactConsent
+		db := a.DB.Where("organization_id = ? AND contact_id = ?", q.orgID, q.contact.ID)
+		if err := q.scope(db, TimelineEventConsentChanged, "recorded_at", "id").Find(&records).Error; err != nil {
+			return nil, err
+		}
+		events := make([]ContactTimelineEvent, 0, len(records))
+		for _, c := range records {
+			events = append(events, newTimelineEvent(TimelineEventConsentChanged, c.RecordedAt, c.ID, c.CreatedBy, map[string]any{
+				"status":  c.Status,
+				"source":  c.Source,
+				"channel": c.Channel,
+			}))
+		}
+		return events, nil
+	}},
+	activityTimelineSource(models.ContactActivityTagsChanged),
+	activityTimelineSource(models.ContactActivityAssignmentChanged),
+	activityTimelineSource(models.ContactActivityCustomAction),
+}
+
+// sortTimelineEvents orders events newest first, breaking ties by type and
+// source ID so the order matches the cursor keyset.
+func sortTimelineEvents(events []ContactTimelineEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.After(b.Timestamp)
+		}
+		if a.Type != b.Type {
+			return a.Type > b.Type
+		}
+		return a.SourceID.String() > b.SourceID.String()
+	})
+}
+
+// GetContactTimeline returns a contact's messages, chatbot sessions, transfers,
+// SLA breaches, campaign deliveries and recorded activity as a single stream,
+// newest first. Pass the returned next_cursor as cursor to load older events.
+func (a *App) GetContactTimeline(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionRead); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	limit, _ := strconv.Atoi(string(r.RequestCtx.QueryArgs().Peek("limit")))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	q := timelineQuery{orgID: orgID, contact: contact, limit: limit}
+	if cursorStr := string(r.RequestCtx.QueryArgs().Peek("cursor")); cursorStr != "" {
+		if q.cursor, err = decodeTimelineCursor(cursorStr); err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid cursor", nil, "")
+		}
+	}
+
+	sources := timelineSources
+	if typesParam := string(r.RequestCtx.QueryArgs().Peek("types")); typesParam != "" {
+		known := make(map[string]timelineSource, len(timelineSources))
+		for _, s := range timelineSources {
+			known[s.Type] = s
+		}
+		sources = nil
+		for _, t := range strings.Split(typesParam, ",") {
+			s, ok := known[strings.TrimSpace(t)]
+			if !ok {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid event type: "+strings.TrimSpace(t), nil, "")
+			}
+			sources = append(sources, s)
+		}
+	}
+
+	events := []ContactTimelineEvent{}
+	for _, s := range sources {
+		sourceEvents, err := s.fetch(a, q)
+		if err != nil {
+			a.Log.Error("Failed to load contact timeline", "error", err, "contact_id", contact.ID, "type", s.Type)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load timeline", nil, "")
+		}
+		events = append(events, sourceEvents...)
+	}
+
+	sortTimelineEvents(events)
+
+	hasMore := len(events) > limit
+	nextCursor := ""
+	if hasMore {
+		events = events[:limit]
+		last := events[limit-1]
+		nextCursor = timelineCursor{Timestamp: last.Timestamp, Type: last.Type, SourceID: last.SourceID}.encode()
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"events":      events,
+		"next_cursor": nextCursor,
+		"has_more":    hasMore,
+	})
+}
+
+// recordContactActivity appends an entry to a contact's activity log. Errors
+// are logged rather than returned so they never fail the change being recorded.
+func (a *App) recordContactActivity(orgID, contactID uuid.UUID, activityType models.ContactActivityType, actorID *uuid.UUID, data models.JSONB) {
+	activity := models.ContactActivity{
+		OrganizationID: orgID,
+		ContactID:      contactID,
+		Type:           activityType,
+		ActorID:        actorID,
+		Data:           data,
+	}
+	if err := a.DB.Create(&activity).Error; err != nil {
+		a.Log.Error("Failed to record contact activity", "error", err, "contact_id", contactID, "type", activityType)
+	}
+}
+
+// recordTagsChange records the tags added to and removed from a contact, if any
+func (a *App) recordTagsChange(orgID, contactID uuid.UUID, actorID *uuid.UUID, oldTags, newTags models.JSONBArray) {
+	toSet := func(tags models.JSONBArray) map[string]bool {
+		set := make(map[string]bool, len(tags))
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				set[s] = true
+			}
+		}
+		return set
+	}
+	oldSet, newSet := toSet(oldTags), toSet(newTags)
+
+	added, removed := []string{}, []string{}
+	for t := range newSet {
+		if !oldSet[t] {
+			added = append(added, t)
+		}
+	}
+	for t := range oldSet {
+		if !newSet[t] {
+			removed = append(removed, t)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	a.recordContactActivity(orgID, contactID, models.ContactActivityTagsChanged, actorID, models.JSONB{
+		"added":   added,
+		"removed": removed,
+	})
+}
+
+// recordAssignmentChange records a change of a contact's assigned agent. When
+// transferID is set the change is recorded as a transfer assignment.
+func (a *App) recordAssignmentChange(orgID, contactID uuid.UUID, actorID, from, to, transferID *uuid.UUID) {
+	if from == to || (from != nil && to != nil && *from == *to) {
+		return
+	}
+	data := models.JSONB{"from_user_id": from, "to_user_id": to}
+	activityType := models.ContactActivityAssignmentChanged
+	if transferID != nil {
+		activityType = models.ContactActivityTransferAssigned
+		data["transfer_id"] = transferID
+	}
+	a.recordContactActivity(orgID, contactID, activityType, actorID, data)
+}
@@ -0,0 +1,178 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+type timelineResponse struct {
+	Events     []handlers.ContactTimelineEvent `json:"events"`
+	NextCursor string                          `json:"next_cursor"`
+	HasMore    bool                            `json:"has_more"`
+}
+
+func getContactTimeline(t *testing.T, app *handlers.App, orgID, userID, contactID uuid.UUID, params map[string]string) timelineResponse {
+	t.Helper()
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, orgID, userID)
+	testutil.SetPathParam(req, "id", contactID.String())
+	for k, v := range params {
+		testutil.SetQueryParam(req, k, v)
+	}
+
+	require.NoError(t, app.GetContactTimeline(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp timelineResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	return resp
+}
+
+// --- GetContactTimeline Tests ---
+
+func TestApp_GetContactTimeline(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+	require.NoError(t, app.DB.Create(&models.Message{
+		BaseModel:       models.BaseModel{ID: uuid.New(), CreatedAt: base},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "hello",
+	}).Error)
+	completedAt := base.Add(2 * time.Minute)
+	require.NoError(t, app.DB.Create(&models.ChatbotSession{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusCompleted,
+		StartedAt:       base.Add(time.Minute),
+		CompletedAt:     &completedAt,
+	}).Error)
+	breachedAt := base.Add(4 * time.Minute)
+	require.NoError(t, app.DB.Create(&models.AgentTransfer{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		TransferredAt:   base.Add(3 * time.Minute),
+		SLA:             models.SLATracking{Breached: true, BreachedAt: &breachedAt},
+	}).Error)
+
+	// Tag change is recorded in the activity log
+	req := testutil.NewJSONRequest(t, map[string]any{"tags": []string{"vip"}})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+	require.NoError(t, app.UpdateContactTags(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	t.Run("merges sources newest first", func(t *testing.T) {
+		resp := getContactTimeline(t, app, org.ID, user.ID, contact.ID, nil)
+		require.Len(t, resp.Events, 6)
+		assert.False(t, resp.HasMore)
+
+		types := make([]string, len(resp.Events))
+		for i, e := range resp.Events {
+			types[i] = e.Type
+		}
+		assert.Equal(t, []string{
+			handlers.TimelineEventTagsChanged,
+			handlers.TimelineEventSLABreached,
+			handlers.TimelineEventTransferCreated,
+			handlers.TimelineEventSessionEnded,
+			handlers.TimelineEventSessionStarted,
+			handlers.TimelineEventMessage,
+		}, types)
+		assert.Equal(t, []any{"vip"}, resp.Events[0].Data["added"])
+		require.NotNil(t, resp.Events[0].ActorID)
+		assert.Equal(t, user.ID, *resp.Events[0].ActorID)
+	})
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		seen := map[string]bool{}
+		params := map[string]string{"limit": "4"}
+		for page := 0; page < 3; page++ {
+			resp := getContactTimeline(t, app, org.ID, user.ID, contact.ID, params)
+			for _, e := range resp.Events {
+				assert.False(t, seen[e.ID], "duplicate event %s", e.ID)
+				seen[e.ID] = true
+			}
+			if !resp.HasMore {
+				break
+			}
+			params["cursor"] = resp.NextCursor
+		}
+		assert.Len(t, seen, 6)
+	})
+
+	t.Run("filter by type", func(t *testing.T) {
+		resp := getContactTimeline(t, app, org.ID, user.ID, contact.ID, map[string]string{"types": "message,sla_breached"})
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, handlers.TimelineEventSLABreached, resp.Events[0].Type)
+		assert.Equal(t, handlers.TimelineEventMessage, resp.Events[1].Type)
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		testutil.SetQueryParam(req, "types", "password_reset")
+
+		require.NoError(t, app.GetContactTimeline(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid event type")
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		testutil.SetQueryParam(req, "cursor", "not-a-cursor")
+
+		require.NoError(t, app.GetContactTimeline(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid cursor")
+	})
+}
+
+func TestApp_AssignContact_RecordsActivity(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	agent := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"user_id": agent.ID})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+	require.NoError(t, app.AssignContact(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var activities []models.ContactActivity
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).Find(&activities).Error)
+	require.Len(t, activities, 1)
+	assert.Equal(t, models.ContactActivityAssignmentChanged, activities[0].Type)
+	assert.Equal(t, agent.ID.String(), activities[0].Data["to_user_id"])
+	assert.Nil(t, activities[0].Data["from_user_id"])
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 	"github.com/shridarpatil/whatomate/pkg/whatsapp"
@@ -31,10 +32,14 @@ type ContactResponse struct {
 	Status             string     `json:"status"`
 	Tags               []string   `json:"tags"`
 	CustomFields       any        `json:"custom_fields"`
+	Attributes         any        `json:"attributes"`
 	LastMessageAt      *time.Time `json:"last_message_at"`
 	LastMessagePreview string     `json:"last_message_preview"`
 	UnreadCount        int        `json:"unread_count"`
 	AssignedUserID     *uuid.UUID `json:"assigned_user_id,omitempty"`
+	ConsentStatus      string     `json:"consent_status,omitempty"`
+	IsBlocked          bool       `json:"is_blocked"`
+	LegalHold          bool       `json:"legal_hold"`
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 }
@@ -123,6 +128,12 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 		}
 	}
 
+	// Filter by custom attributes (attr.<key>[.<op>]=value)
+	query, err = a.applyContactAttributeFilters(query, orgID, r.RequestCtx.QueryArgs())
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
 	// Order by last message time (most recent first)
 	query = query.Order("last_message_at DESC NULLS LAST, created_at DESC")
 
@@ -170,10 +181,14 @@ func (a *App) ListContacts(r *fastglue.Request) error {
 			Status:             "active",
 			Tags:               tags,
 			CustomFields:       c.Metadata,
+			Attributes:         c.Attributes,
 			LastMessageAt:      c.LastMessageAt,
 			LastMessagePreview: c.LastMessagePreview,
 			UnreadCount:        int(unreadCount),
 			AssignedUserID:     c.AssignedUserID,
+			ConsentStatus:      string(c.ConsentStatus),
+			IsBlocked:          c.IsBlocked,
+			LegalHold:          c.LegalHold,
 			CreatedAt:          c.CreatedAt,
 			UpdatedAt:          c.UpdatedAt,
 		}
@@ -242,10 +257,14 @@ func (a *App) GetContact(r *fastglue.Request) error {
 		Status:             "active",
 		Tags:               tags,
 		CustomFields:       contact.Metadata,
+		Attributes:         contact.Attributes,
 		LastMessageAt:      contact.LastMessageAt,
 		LastMessagePreview: contact.LastMessagePreview,
 		UnreadCount:        int(unreadCount),
 		AssignedUserID:     contact.AssignedUserID,
+		ConsentStatus:      string(contact.ConsentStatus),
+		IsBlocked:          contact.IsBlocked,
+		LegalHold:          contact.LegalHold,
 		CreatedAt:          contact.CreatedAt,
 		UpdatedAt:          contact.UpdatedAt,
 	}
@@ -1058,10 +1077,12 @@ func (a *App) AssignContact(r *fastglue.Request) error {
 	}
 
 	// Update contact assignment
+	previousUserID := contact.AssignedUserID
 	if err := a.DB.Model(contact).Update("assigned_user_id", req.UserID).Error; err != nil {
 		a.Log.Error("Failed to assign contact", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to assign contact", nil, "")
 	}
+	a.recordAssignmentChange(orgID, contact.ID, &userID, previousUserID, req.UserID, nil)
 
 	return r.SendEnvelope(map[string]any{
 		"message":          "Contact assigned successfully",
@@ -1211,10 +1232,12 @@ func (a *App) UpdateContactTags(r *fastglue.Request) error {
 	}
 
 	// Update contact tags
+	previousTags := contact.Tags
 	if err := a.DB.Model(contact).Update("tags", tagsArray).Error; err != nil {
 		a.Log.Error("Failed to update contact tags", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact tags", nil, "")
 	}
+	a.recordTagsChange(orgID, contact.ID, &userID, previousTags, tagsArray)
 
 	// Reload contact to get updated tags
 	if err := a.DB.First(contact, contactID).Error; err != nil {
@@ -1244,6 +1267,7 @@ type CreateContactRequest struct {
 	WhatsAppAccount string         `json:"whatsapp_account"`
 	Tags            []string       `json:"tags"`
 	Metadata        map[string]any `json:"metadata"`
+	Attributes      map[string]any `json:"attributes"`
 }
 
 // CreateContact creates a new contact or restores a soft-deleted one
@@ -1267,10 +1291,20 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "phone_number is required", nil, "")
 	}
 
-	// Normalize phone number
-	normalizedPhone := req.PhoneNumber
-	if len(normalizedPhone) > 0 && normalizedPhone[0] == '+' {
-		normalizedPhone = normalizedPhone[1:]
+	// Normalize phone number to E.164 using the org's default region
+	normalizedPhone, err := contactutil.NormalizePhone(a.DB, orgID, req.PhoneNumber)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid phone number", nil, "")
+	}
+
+	attrDefs, err := a.getContactAttributesCached(orgID)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact", nil, "")
+	}
+	attributes, err := contactutil.ValidateAttributes(attrDefs, req.Attributes, true)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
 	}
 
 	// Check if contact exists (including soft-deleted)
@@ -1299,6 +1333,9 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 			if req.Metadata != nil {
 				updates["metadata"] = models.JSONB(req.Metadata)
 			}
+			if len(attributes) > 0 {
+				updates["attributes"] = contactutil.ApplyAttributes(existingContact.Attributes, attributes)
+			}
 			if len(updates) > 0 {
 				a.DB.Model(&existingContact).Updates(updates)
 			}
@@ -1330,6 +1367,8 @@ func (a *App) CreateContact(r *fastglue.Request) error {
 		contact.Metadata = models.JSONB(req.Metadata)
 	}
 
+	contact.Attributes = contactutil.ApplyAttributes(nil, attributes)
+
 	if err := a.DB.Create(&contact).Error; err != nil {
 		a.Log.Error("Failed to create contact", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact", nil, "")
@@ -1344,6 +1383,7 @@ type UpdateContactRequest struct {
 	WhatsAppAccount *string         `json:"whatsapp_account"`
 	Tags            []string        `json:"tags"`
 	Metadata        *map[string]any `json:"metadata"`
+	Attributes      map[string]any  `json:"attributes"` // Partial; null values clear an attribute
 	AssignedUserID  *uuid.UUID      `json:"assigned_user_id"`
 }
 
@@ -1394,6 +1434,18 @@ func (a *App) UpdateContact(r *fastglue.Request) error {
 	if req.Metadata != nil {
 		updates["metadata"] = models.JSONB(*req.Metadata)
 	}
+	if req.Attributes != nil {
+		attrDefs, err := a.getContactAttributesCached(orgID)
+		if err != nil {
+			a.Log.Error("Failed to load contact attributes", "error", err)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact", nil, "")
+		}
+		attributes, err := contactutil.ValidateAttributes(attrDefs, req.Attributes, false)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		updates["attributes"] = contactutil.ApplyAttributes(contact.Attributes, attributes)
+	}
 	if req.AssignedUserID != nil {
 		// Verify user exists in same org
 		var user models.User
@@ -1407,10 +1459,17 @@ func (a *App) UpdateContact(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "No fields to update", nil, "")
 	}
 
+	previousTags, previousUserID := contact.Tags, contact.AssignedUserID
 	if err := a.DB.Model(contact).Updates(updates).Error; err != nil {
 		a.Log.Error("Failed to update contact", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update contact", nil, "")
 	}
+	if tags, ok := updates["tags"].(models.JSONBArray); ok {
+		a.recordTagsChange(orgID, contact.ID, &userID, previousTags, tags)
+	}
+	if req.AssignedUserID != nil {
+		a.recordAssignmentChange(orgID, contact.ID, &userID, previousUserID, req.AssignedUserID, nil)
+	}
 
 	// Reload contact
 	a.DB.First(contact, contactID)
@@ -1485,10 +1544,14 @@ func (a *App) buildContactResponse(contact *models.Contact, orgID uuid.UUID) Con
 		Status:             "active",
 		Tags:               tags,
 		CustomFields:       contact.Metadata,
+		Attributes:         contact.Attributes,
 		LastMessageAt:      contact.LastMessageAt,
 		LastMessagePreview: contact.LastMessagePreview,
 		UnreadCount:        int(unreadCount),
 		AssignedUserID:     contact.AssignedUserID,
+		ConsentStatus:      string(contact.ConsentStatus),
+		IsBlocked:          contact.IsBlocked,
+		LegalHold:          contact.LegalHold,
 		CreatedAt:          contact.CreatedAt,
 		UpdatedAt:          contact.UpdatedAt,
 	}
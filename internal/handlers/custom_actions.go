@@ -5,15 +5,15 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"regexp"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/transcript"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 )
@@ -22,7 +22,7 @@ import (
 type CustomActionRequest struct {
 	Name         string                 `json:"name"`
 	Icon         string                 `json:"icon"`
-	ActionType   models.ActionType      `json:"action_type"` // webhook, url, javascript
+	ActionType   models.ActionType      `json:"action_type"` // webhook, url, javascript, transcript
 	Config       map[string]interface{} `json:"config"`
 	IsActive     bool                   `json:"is_active"`
 	DisplayOrder int                    `json:"display_order"`
@@ -73,6 +73,22 @@ type redirectToken struct {
 	ExpiresAt time.Time
 }
 
+// Download token storage for files produced by actions
+var (
+	downloadTokens     = make(map[string]downloadToken)
+	downloadTokenMutex sync.Mutex
+)
+
+type downloadToken struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+	ExpiresAt   time.Time
+}
+
+// downloadTokenTTL is how long an action's file stays available for download
+const downloadTokenTTL = 5 * time.Minute
+
 // ListCustomActions returns all custom actions for the organization
 func (a *App) ListCustomActions(r *fastglue.Request) error {
 	orgID, err := a.getOrgID(r)
@@ -153,8 +169,8 @@ func (a *App) CreateCustomAction(r *fastglue.Request) error {
 	if req.ActionType == "" {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Action type is required", nil, "")
 	}
-	if req.ActionType != models.ActionTypeWebhook && req.ActionType != models.ActionTypeURL && req.ActionType != models.ActionTypeJavascript {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid action type. Must be webhook, url, or javascript", nil, "")
+	if !isValidActionType(req.ActionType) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid action type. Must be webhook, url, javascript, or transcript", nil, "")
 	}
 
 	// Validate config based on action type
@@ -212,7 +228,7 @@ func (a *App) UpdateCustomAction(r *fastglue.Request) error {
 		updates["icon"] = req.Icon
 	}
 	if req.ActionType != "" {
-		if req.ActionType != models.ActionTypeWebhook && req.ActionType != models.ActionTypeURL && req.ActionType != models.ActionTypeJavascript {
+		if !isValidActionType(req.ActionType) {
 			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid action type", nil, "")
 		}
 		updates["action_type"] = req.ActionType
@@ -301,6 +317,12 @@ func (a *App) ExecuteCustomAction(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid contact ID", nil, "")
 	}
 
+	if action.ActionType == models.ActionTypeTranscript {
+		if err := a.requirePermission(r, userID, models.ResourceTranscripts, models.ActionExport); err != nil {
+			return nil
+		}
+	}
+
 	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
 	if err != nil {
 		return nil
@@ -326,10 +348,23 @@ func (a *App) ExecuteCustomAction(r *fastglue.Request) error {
 		result, err = a.executeURLAction(*action, context)
 	case models.ActionTypeJavascript:
 		result, err = a.executeJavaScriptAction(*action, context)
+	case models.ActionTypeTranscript:
+		result, err = a.executeTranscriptAction(*action, orgID, contact.ID)
 	default:
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Unknown action type", nil, "")
 	}
 
+	activityData := models.JSONB{
+		"action_id":   action.ID,
+		"action_name": action.Name,
+		"action_type": action.ActionType,
+		"success":     err == nil && result != nil && result.Success,
+	}
+	if err != nil {
+		activityData["error"] = err.Error()
+	}
+	a.recordContactActivity(orgID, contact.ID, models.ContactActivityCustomAction, &userID, activityData)
+
 	if err != nil {
 		a.Log.Error("Failed to execute custom action", "error", err, "action_id", actionID)
 		return r.SendEnvelope(ActionResult{
@@ -373,6 +408,47 @@ func (a *App) CustomActionRedirect(r *fastglue.Request) error {
 	return nil
 }
 
+// storeDownloadToken keeps a file for one download and returns its token
+func storeDownloadToken(dt downloadToken) string {
+	tokenBytes := make([]byte, 16)
+	_, _ = rand.Read(tokenBytes)
+	token := hex.EncodeToString(tokenBytes)
+	dt.ExpiresAt = time.Now().Add(downloadTokenTTL)
+
+	downloadTokenMutex.Lock()
+	defer downloadTokenMutex.Unlock()
+	// Drop expired files so abandoned downloads don't accumulate
+	for k, v := range downloadTokens {
+		if time.Now().After(v.ExpiresAt) {
+			delete(downloadTokens, k)
+		}
+	}
+	downloadTokens[token] = dt
+	return token
+}
+
+// CustomActionDownload serves a file produced by an action (one-time token)
+func (a *App) CustomActionDownload(r *fastglue.Request) error {
+	token := r.RequestCtx.UserValue("token").(string)
+
+	downloadTokenMutex.Lock()
+	dt, exists := downloadTokens[token]
+	delete(downloadTokens, token)
+	downloadTokenMutex.Unlock()
+
+	if !exists {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Invalid or expired download token", nil, "")
+	}
+	if time.Now().After(dt.ExpiresAt) {
+		return r.SendErrorEnvelope(fasthttp.StatusGone, "Download token has expired", nil, "")
+	}
+
+	r.RequestCtx.Response.Header.Set("Content-Type", dt.ContentType)
+	r.RequestCtx.Response.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", dt.Filename))
+	r.RequestCtx.SetBody(dt.Data)
+	return nil
+}
+
 // executeWebhookAction executes a webhook action
 func (a *App) executeWebhookAction(action models.CustomAction, context map[string]interface{}) (*ActionResult, error) {
 	// Parse config from JSONB (already a map)
@@ -391,18 +467,18 @@ func (a *App) executeWebhookAction(action models.CustomAction, context map[strin
 	}
 
 	// Replace variables in URL
-	url := replaceVariables(config.URL, context)
+	url := processTemplate(config.URL, context)
 
 	// Replace variables in headers
 	headers := make(map[string]string)
 	for k, v := range config.Headers {
-		headers[k] = replaceVariables(v, context)
+		headers[k] = processTemplate(v, context)
 	}
 
 	// Replace variables in body or use default
 	var body string
 	if config.Body != "" {
-		body = replaceVariables(config.Body, context)
+		body = processTemplate(config.Body, context)
 	} else {
 		// Default body with all context
 		bodyJSON, _ := json.Marshal(context)
@@ -467,7 +543,7 @@ func (a *App) executeURLAction(action models.CustomAction, context map[string]in
 	}
 
 	// Replace variables in URL
-	finalURL := replaceVariables(config.URL, context)
+	finalURL := processTemplate(config.URL, context)
 
 	// Generate a random token
 	tokenBytes := make([]byte, 16)
@@ -543,41 +619,6 @@ func buildActionContext(contact models.Contact, user models.User, org models.Org
 	}
 }
 
-// replaceVariables replaces {{variable}} placeholders with context values
-func replaceVariables(template string, context map[string]interface{}) string {
-	re := regexp.MustCompile(`\{\{([^}]+)\}\}`)
-	return re.ReplaceAllStringFunc(template, func(match string) string {
-		// Extract variable path (e.g., "contact.phone_number")
-		path := strings.TrimSuffix(strings.TrimPrefix(match, "{{"), "}}")
-		path = strings.TrimSpace(path)
-
-		parts := strings.Split(path, ".")
-		var value interface{} = context
-
-		for _, part := range parts {
-			if m, ok := value.(map[string]interface{}); ok {
-				value = m[part]
-			} else {
-				return match // Return original if path not found
-			}
-		}
-
-		if value == nil {
-			return ""
-		}
-
-		switch v := value.(type) {
-		case string:
-			return v
-		case []string:
-			return strings.Join(v, ", ")
-		default:
-			jsonBytes, _ := json.Marshal(v)
-			return string(jsonBytes)
-		}
-	})
-}
-
 // validateActionConfig validates the config based on action type
 func validateActionConfig(actionType models.ActionType, config map[string]interface{}) error {
 	switch actionType {
@@ -593,10 +634,23 @@ func validateActionConfig(actionType models.ActionType, config map[string]interf
 		if _, ok := config["code"]; !ok {
 			return &ValidationError{Field: "config.code", Message: "Code is required for JavaScript actions"}
 		}
+	case models.ActionTypeTranscript:
+		if f, ok := config["format"].(string); ok && !transcript.Format(f).IsValid() {
+			return &ValidationError{Field: "config.format", Message: "Format must be html, pdf, or json"}
+		}
 	}
 	return nil
 }
 
+// isValidActionType reports whether t is a supported custom action type
+func isValidActionType(t models.ActionType) bool {
+	switch t {
+	case models.ActionTypeWebhook, models.ActionTypeURL, models.ActionTypeJavascript, models.ActionTypeTranscript:
+		return true
+	}
+	return false
+}
+
 // customActionToResponse converts a CustomAction model to response
 func customActionToResponse(action models.CustomAction) CustomActionResponse {
 	// Config is already a map[string]interface{}, just use it directly
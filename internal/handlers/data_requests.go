@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// EraseContactRequest represents a request to erase a contact's data
+type EraseContactRequest struct {
+	Mode   models.ErasureMode `json:"mode"` // delete (default) or anonymize
+	Reason string             `json:"reason"`
+	DryRun bool               `json:"dry_run"`
+}
+
+// ExportContactDataRequest represents a request to export a contact's data
+type ExportContactDataRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListDataRequests returns the organization's data-subject request audit log
+func (a *App) ListDataRequests(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceDataRequests, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	query := a.DB.Model(&models.DataSubjectRequest{}).Where("organization_id = ?", orgID)
+	if contactIDStr := string(r.RequestCtx.QueryArgs().Peek("contact_id")); contactIDStr != "" {
+		contactID, err := uuid.Parse(contactIDStr)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid contact ID", nil, "")
+		}
+		query = query.Where("contact_id = ?", contactID)
+	}
+	if reqType := string(r.RequestCtx.QueryArgs().Peek("type")); reqType != "" {
+		query = query.Where("type = ?", reqType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var requests []models.DataSubjectRequest
+	if err := pg.Apply(query.Order("created_at DESC")).Find(&requests).Error; err != nil {
+		a.Log.Error("Failed to list data requests", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list data requests", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"requests": requests,
+		"total":    total,
+		"page":     pg.Page,
+		"limit":    pg.Limit,
+	})
+}
+
+// ExportContactData returns a ZIP archive of everything stored about a
+// contact: its record, messages, chatbot sessions, transfers, campaign
+// deliveries, consent and activity history as JSON, plus its media files.
+func (a *App) ExportContactData(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceDataRequests, models.ActionExport); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	// The body is optional
+	var req ExportContactDataRequest
+	if len(r.RequestCtx.PostBody()) > 0 {
+		if err := a.decodeRequest(r, &req); err != nil {
+			return nil
+		}
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	report, err := contactutil.ExportContactData(a.DB, orgID, contact, a.getMediaStoragePath(), &buf)
+	if err != nil {
+		a.Log.Error("Failed to export contact data", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to export contact data", nil, "")
+	}
+
+	if err := a.recordDataRequest(orgID, userID, contact, models.DataRequestTypeExport, "", req.Reason, report); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to record data request", nil, "")
+	}
+
+	filename := fmt.Sprintf("contact_%s_export_%s.zip", contact.ID, time.Now().Format("20060102_150405"))
+	r.RequestCtx.Response.Header.Set("Content-Type", "application/zip")
+	r.RequestCtx.Response.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	r.RequestCtx.SetBody(buf.Bytes())
+
+	return nil
+}
+
+// EraseContact irreversibly deletes or anonymizes everything stored about a
+// contact, including media files. With dry_run set it only reports what would
+// be affected.
+func (a *App) EraseContact(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceDataRequests, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var req EraseContactRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+	if req.Mode == "" {
+		req.Mode = models.ErasureModeDelete
+	}
+	if !req.Mode.IsValid() {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid mode. Must be 'delete' or 'anonymize'", nil, "")
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+	if contact.LegalHold && !req.DryRun {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Contact is on legal hold and cannot be erased", nil, "")
+	}
+
+	report, err := contactutil.EraseContactData(a.DB, orgID, contact, req.Mode, a.getMediaStoragePath(), req.DryRun)
+	if err != nil && report == nil {
+		a.Log.Error("Failed to erase contact data", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to erase contact data", nil, "")
+	}
+	if req.DryRun {
+		return r.SendEnvelope(map[string]any{
+			"dry_run": true,
+			"mode":    req.Mode,
+			"report":  report,
+		})
+	}
+
+	// The database changes are committed at this point, so record them even
+	// if removing media files failed
+	if auditErr := a.recordDataRequest(orgID, userID, contact, models.DataRequestTypeErasure, req.Mode, req.Reason, report); auditErr != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Contact data erased but failed to record data request", nil, "")
+	}
+	if err != nil {
+		a.Log.Error("Failed to remove contact media", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, err.Error(), nil, "")
+	}
+
+	a.Log.Info("Contact data erased", "contact_id", contact.ID, "mode", req.Mode, "user_id", userID)
+
+	return r.SendEnvelope(map[string]any{
+		"message": "Contact data erased",
+		"mode":    req.Mode,
+		"report":  report,
+	})
+}
+
+// recordDataRequest writes the audit record of a completed data-subject request
+func (a *App) recordDataRequest(orgID, userID uuid.UUID, contact *models.Contact, reqType models.DataRequestType, mode models.ErasureMode, reason string, report *contactutil.DataSubjectReport) error {
+	record := models.DataSubjectRequest{
+		OrganizationID:    orgID,
+		ContactID:         contact.ID,
+		PhoneNumberMasked: MaskPhoneNumber(contact.PhoneNumber),
+		Type:              reqType,
+		Mode:              mode,
+		Reason:            reason,
+		Report:            report.ToJSONB(),
+		RequestedBy:       &userID,
+		CompletedAt:       time.Now(),
+	}
+	if err := a.DB.Create(&record).Error; err != nil {
+		a.Log.Error("Failed to record data request", "error", err, "contact_id", contact.ID, "type", reqType)
+		return err
+	}
+	return nil
+}
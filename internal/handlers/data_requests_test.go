@@ -0,0 +1,164 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// --- EraseContact Tests ---
+
+func TestApp_EraseContact(t *testing.T) {
+	t.Parallel()
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		require.NoError(t, app.DB.Create(&models.Message{
+			OrganizationID:  org.ID,
+			WhatsAppAccount: "test",
+			ContactID:       contact.ID,
+			Direction:       models.DirectionIncoming,
+			MessageType:     models.MessageTypeText,
+			Content:         "hello",
+		}).Error)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"dry_run": true})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.EraseContact(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			DryRun bool `json:"dry_run"`
+			Report struct {
+				Messages int64 `json:"messages"`
+			} `json:"report"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.True(t, resp.DryRun)
+		assert.Equal(t, int64(1), resp.Report.Messages)
+
+		var count int64
+		app.DB.Model(&models.DataSubjectRequest{}).Where("contact_id = ?", contact.ID).Count(&count)
+		assert.Zero(t, count)
+		app.DB.Model(&models.Message{}).Where("contact_id = ?", contact.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("delete records audit", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"reason": "DSR-42"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.EraseContact(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var count int64
+		app.DB.Unscoped().Model(&models.Contact{}).Where("id = ?", contact.ID).Count(&count)
+		assert.Zero(t, count)
+
+		var audit models.DataSubjectRequest
+		require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&audit).Error)
+		assert.Equal(t, models.DataRequestTypeErasure, audit.Type)
+		assert.Equal(t, models.ErasureModeDelete, audit.Mode)
+		assert.Equal(t, "DSR-42", audit.Reason)
+		assert.NotEqual(t, contact.PhoneNumber, audit.PhoneNumberMasked)
+		require.NotNil(t, audit.RequestedBy)
+		assert.Equal(t, user.ID, *audit.RequestedBy)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"mode": "shred"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.EraseContact(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid mode")
+	})
+
+	t.Run("forbidden without delete permission", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "privacy-viewer", []string{"data_requests:read", "contacts:delete"})
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		err := app.EraseContact(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+	})
+}
+
+// --- ExportContactData Tests ---
+
+func TestApp_ExportContactData(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", contact.ID.String())
+
+	err := app.ExportContactData(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	assert.Equal(t, "application/zip", string(req.RequestCtx.Response.Header.ContentType()))
+
+	body := testutil.GetResponseBody(req)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	assert.NotEmpty(t, zr.File)
+
+	var audit models.DataSubjectRequest
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&audit).Error)
+	assert.Equal(t, models.DataRequestTypeExport, audit.Type)
+
+	// The export shows up in the audit log
+	req = testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetQueryParam(req, "contact_id", contact.ID.String())
+
+	err = app.ListDataRequests(req)
+	require.NoError(t, err)
+
+	var resp struct {
+		Total int64 `json:"total"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, int64(1), resp.Total)
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/flowutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// maxCallDepth caps nested subflow calls. Saving a flow rejects endless
+// calls, but flows can still change while a session is in one.
+const maxCallDepth = 10
+
+// flowCallFrame is a flow on a session's call stack, waiting for a subflow
+// it called to return
+type flowCallFrame struct {
+	FlowID        uuid.UUID    `json:"flow_id"`
+	FlowVersionID *uuid.UUID   `json:"flow_version_id,omitempty"`
+	Step          string       `json:"step"` // The subflow step to continue after
+	SessionData   models.JSONB `json:"session_data"`
+}
+
+// pushCallFrame adds a calling flow to the session's call stack
+func pushCallFrame(session *models.ChatbotSession, frame flowCallFrame) {
+	var entry map[string]interface{}
+	data, _ := json.Marshal(frame)
+	_ = json.Unmarshal(data, &entry)
+	session.CallStack = append(session.CallStack, entry)
+}
+
+// popCallFrame removes the most recent calling flow from the session's call stack
+func popCallFrame(session *models.ChatbotSession) (flowCallFrame, bool) {
+	var frame flowCallFrame
+	if len(session.CallStack) == 0 {
+		return frame, false
+	}
+	last := session.CallStack[len(session.CallStack)-1]
+	session.CallStack = session.CallStack[:len(session.CallStack)-1]
+	data, err := json.Marshal(last)
+	if err != nil {
+		return frame, false
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return frame, false
+	}
+	return frame, true
+}
+
+// nextStepAfter returns the name of the step that follows a step when no
+// reply chooses one, or "" at the end of the flow
+func nextStepAfter(flow *models.ChatbotFlow, step *models.ChatbotFlowStep) string {
+	if step.NextStep != "" {
+		return step.NextStep
+	}
+	for i, s := range flow.Steps {
+		if s.StepName == step.StepName && i+1 < len(flow.Steps) {
+			return flow.Steps[i+1].StepName
+		}
+	}
+	return ""
+}
+
+// moveToStep makes the named step current and sends it, completing the flow
+// when there is no such step
+func (a *App) moveToStep(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, flow *models.ChatbotFlow, stepName string) {
+	if stepName == "" {
+		a.completeFlow(account, session, contact, flow)
+		return
+	}
+
+	var next *models.ChatbotFlowStep
+	for i := range flow.Steps {
+		if flow.Steps[i].StepName == stepName {
+			next = &flow.Steps[i]
+			break
+		}
+	}
+	if next == nil {
+		a.Log.Warn("Next step not found, completing flow", "next_step", stepName)
+		a.completeFlow(account, session, contact, flow)
+		return
+	}
+
+	session.CurrentStep = next.StepName
+	session.StepRetries = 0
+	a.DB.Model(session).Updates(map[string]interface{}{
+		"current_step": next.StepName,
+		"step_retries": 0,
+	})
+	a.sendStepWithSkipCheck(account, session, contact, next, flow, nil)
+}
+
+// loadStepTarget loads the flow a subflow or goto_flow step continues in
+func (a *App) loadStepTarget(orgID uuid.UUID, step *models.ChatbotFlowStep) (*models.ChatbotFlow, error) {
+	targetID, err := flowutil.SubflowTarget(step)
+	if err != nil {
+		return nil, err
+	}
+	return a.getChatbotFlowByIDCached(orgID, targetID)
+}
+
+// switchFlow moves the session into another flow, keeping its data, and
+// sends that flow's first messages
+func (a *App) switchFlow(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, target *models.ChatbotFlow) {
+	data := copyMap(session.SessionData)
+	data["_flow_id"] = target.ID.String()
+	data["_flow_name"] = target.Name
+
+	session.CurrentFlowID = &target.ID
+	session.FlowVersionID = target.PublishedVersionID
+	session.CurrentStep = ""
+	session.StepRetries = 0
+	session.SessionData = data
+	a.DB.Save(session)
+
+	a.enterFlow(account, session, contact, target)
+}
+
+// callSubflow runs the flow a subflow step names. The calling flow continues
+// after the step once the subflow completes.
+func (a *App) callSubflow(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep, flow *models.ChatbotFlow) {
+	target, err := a.loadStepTarget(account.OrganizationID, step)
+	if err != nil {
+		a.Log.Error("Failed to load subflow, skipping step", "error", err, "step", step.StepName)
+		a.moveToStep(account, session, contact, flow, nextStepAfter(flow, step))
+		return
+	}
+	if len(session.CallStack) >= maxCallDepth {
+		a.Log.Error("Subflows nested too deeply, ending flow", "step", step.StepName, "depth", len(session.CallStack))
+		a.exitFlow(session)
+		return
+	}
+
+	a.Log.Info("Calling subflow", "flow_id", flow.ID, "subflow_id", target.ID, "step", step.StepName)
+	pushCallFrame(session, flowCallFrame{
+		FlowID:        flow.ID,
+		FlowVersionID: session.FlowVersionID,
+		Step:          step.StepName,
+		SessionData:   copyMap(session.SessionData),
+	})
+	a.switchFlow(account, session, contact, target)
+}
+
+// returnFromSubflow resumes the flow that called the session's completed
+// subflow. Values the subflow collected are stored under the calling step's
+// store_as, or merged into the caller's data when it has none.
+func (a *App) returnFromSubflow(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact) {
+	frame, ok := popCallFrame(session)
+	if !ok {
+		a.Log.Error("Invalid subflow call stack, ending flow", "session_id", session.ID)
+		a.exitFlow(session)
+		return
+	}
+
+	collected := models.JSONB{}
+	for key, value := range session.SessionData {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		if before, ok := frame.SessionData[key]; ok && reflect.DeepEqual(before, value) {
+			continue
+		}
+		collected[key] = value
+	}
+
+	session.CurrentFlowID = &frame.FlowID
+	session.FlowVersionID = frame.FlowVersionID
+	caller, err := a.getSessionFlow(account.OrganizationID, session)
+	if err != nil {
+		a.Log.Error("Failed to load calling flow", "error", err, "flow_id", frame.FlowID)
+		a.exitFlow(session)
+		return
+	}
+	var step *models.ChatbotFlowStep
+	for i := range caller.Steps {
+		if caller.Steps[i].StepName == frame.Step {
+			step = &caller.Steps[i]
+			break
+		}
+	}
+	if step == nil {
+		a.Log.Error("Subflow step not found in calling flow", "step", frame.Step, "flow_id", caller.ID)
+		a.exitFlow(session)
+		return
+	}
+
+	data := frame.SessionData
+	if data == nil {
+		data = models.JSONB{}
+	}
+	if step.StoreAs != "" {
+		data[step.StoreAs] = map[string]interface{}(collected)
+	} else {
+		for key, value := range collected {
+			data[key] = value
+		}
+	}
+
+	a.Log.Info("Returning from subflow", "flow_id", caller.ID, "step", step.StepName)
+	session.SessionData = data
+	session.CurrentStep = step.StepName
+	session.StepRetries = 0
+	a.DB.Save(session)
+
+	a.moveToStep(account, session, contact, caller, nextStepAfter(caller, step))
+}
+
+// gotoFlow continues the session in the flow a goto_flow step names. The
+// current flow ends without its completion message; when it was called as a
+// subflow, the new flow returns to the caller instead.
+func (a *App) gotoFlow(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep, flow *models.ChatbotFlow) {
+	target, err := a.loadStepTarget(account.OrganizationID, step)
+	if err != nil {
+		a.Log.Error("Failed to load goto flow, completing flow", "error", err, "step", step.StepName)
+		a.completeFlow(account, session, contact, flow)
+		return
+	}
+
+	a.Log.Info("Going to flow", "flow_id", flow.ID, "target_flow_id", target.ID, "step", step.StepName)
+	a.switchFlow(account, session, contact, target)
+}
+
+// runLoopStep collects the values of one pass through a loop and goes back
+// for another pass while the loop's condition holds and its limit allows
+func (a *App) runLoopStep(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, step *models.ChatbotFlowStep, flow *models.ChatbotFlow) {
+	cfg, err := flowutil.ParseLoopConfig(step.LoopConfig)
+	if err != nil {
+		a.Log.Error("Invalid loop step, continuing", "error", err, "step", step.StepName)
+		a.moveToStep(account, session, contact, flow, nextStepAfter(flow, step))
+		return
+	}
+
+	data := session.SessionData
+	if data == nil {
+		data = models.JSONB{}
+	}
+	counterKey := "_loop_" + step.StepName
+	iteration := 1
+	switch n := data[counterKey].(type) {
+	case float64:
+		iteration = int(n) + 1
+	case int:
+		iteration = n + 1
+	}
+
+	if len(cfg.Collect) > 0 {
+		var items []interface{}
+		if list, ok := data[step.StoreAs].([]interface{}); ok && iteration > 1 {
+			items = list
+		}
+		item := make(map[string]interface{}, len(cfg.Collect))
+		for _, key := range cfg.Collect {
+			item[key] = data[key]
+		}
+		data[step.StoreAs] = append(items, item)
+	}
+
+	repeat := cfg.Condition == "" || evaluateExpression(cfg.Condition, data)
+	again := repeat && iteration < cfg.MaxIterations
+	nextStep := nextStepAfter(flow, step)
+	if again {
+		nextStep = cfg.BackTo
+		data[counterKey] = iteration
+	} else {
+		delete(data, counterKey)
+	}
+	a.recordCondition(SimulatedCondition{
+		Step:       step.StepName,
+		Kind:       "loop",
+		Expression: cfg.Condition,
+		Result:     again,
+		NextStep:   nextStep,
+	})
+
+	session.SessionData = data
+	a.DB.Model(session).Update("session_data", data)
+
+	if repeat && !again && cfg.LimitMessage != "" {
+		message := processTemplate(cfg.LimitMessage, data)
+		if err := a.sendAndSaveTextMessage(account, contact, message); err != nil {
+			a.Log.Error("Failed to send loop limit message", "error", err, "contact", contact.PhoneNumber)
+		}
+		a.logSessionMessage(session.ID, models.DirectionOutgoing, message, step.StepName)
+	}
+
+	a.moveToStep(account, session, contact, flow, nextStep)
+}
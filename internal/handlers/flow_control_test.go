@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallStackFrames(t *testing.T) {
+	session := &models.ChatbotSession{}
+	_, ok := popCallFrame(session)
+	assert.False(t, ok)
+
+	flowID, versionID := uuid.New(), uuid.New()
+	pushCallFrame(session, flowCallFrame{FlowID: flowID, FlowVersionID: &versionID, Step: "address", SessionData: models.JSONB{"name": "Asha"}})
+	pushCallFrame(session, flowCallFrame{FlowID: uuid.New(), Step: "inner"})
+	require.Len(t, session.CallStack, 2)
+
+	frame, ok := popCallFrame(session)
+	require.True(t, ok)
+	assert.Equal(t, "inner", frame.Step)
+	assert.Nil(t, frame.FlowVersionID)
+
+	frame, ok = popCallFrame(session)
+	require.True(t, ok)
+	assert.Equal(t, flowID, frame.FlowID)
+	assert.Equal(t, versionID, *frame.FlowVersionID)
+	assert.Equal(t, models.JSONB{"name": "Asha"}, frame.SessionData)
+	assert.Empty(t, session.CallStack)
+}
+
+func TestSimulateFlow_SubflowAndLoop(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	addressID := uuid.New()
+	address := &models.ChatbotFlow{
+		BaseModel:         models.BaseModel{ID: addressID},
+		OrganizationID:    org.ID,
+		WhatsAppAccount:   account.Name,
+		Name:              "Address",
+		CompletionMessage: "Address saved.",
+		Steps: []models.ChatbotFlowStep{
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: addressID, StepOrder: 1,
+				StepName: "ask_city", Message: "Which city?", MessageType: models.FlowStepTypeText,
+				InputType: models.InputTypeText, StoreAs: "city",
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(address).Error)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:         models.BaseModel{ID: flowID},
+		OrganizationID:    org.ID,
+		WhatsAppAccount:   account.Name,
+		Name:              "Order",
+		CompletionMessage: "Thanks!",
+		Steps: []models.ChatbotFlowStep{
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 1,
+				StepName: "ask_item", Message: "What would you like?", MessageType: models.FlowStepTypeText,
+				InputType: models.InputTypeText, StoreAs: "item",
+			},
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 2,
+				StepName: "ask_more", Message: "Anything else?", MessageType: models.FlowStepTypeText,
+				InputType: models.InputTypeText, StoreAs: "more",
+			},
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 3,
+				StepName: "repeat", MessageType: models.FlowStepTypeLoop, StoreAs: "items",
+				LoopConfig: models.JSONB{
+					"back_to": "ask_item", "condition": "more == 'yes'", "max_iterations": 2,
+					"collect": []interface{}{"item"}, "limit_message": "That's all we can take.",
+				},
+			},
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 4,
+				StepName: "address", MessageType: models.FlowStepTypeSubflow, StoreAs: "address",
+				SubflowConfig: models.JSONB{"flow_id": addressID.String()},
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+
+	askItem, askCity, ended := "ask_item", "ask_city", ""
+	result, err := app.simulateFlow(org.ID, flowID, SimulateFlowRequest{
+		Turns: []SimulatorTurn{
+			{Expect: &TurnExpectation{Step: &askItem}},
+			{Text: "tea"},
+			{Text: "yes", Expect: &TurnExpectation{Step: &askItem}},
+			{Text: "cake"},
+			{Text: "yes", Expect: &TurnExpectation{Step: &askCity, Messages: []string{"all we can take", "Which city?"}}},
+			{Text: "Pune", Expect: &TurnExpectation{Step: &ended, Status: models.SessionStatusCompleted, Messages: []string{"Address saved.", "Thanks!"}}},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Passed, result.Failures)
+	require.Len(t, result.Turns, 6)
+
+	assert.Contains(t, result.Turns[2].Conditions, SimulatedCondition{Step: "repeat", Kind: "loop", Expression: "more == 'yes'", Result: true, NextStep: "ask_item"})
+	assert.Contains(t, result.Turns[4].Conditions, SimulatedCondition{Step: "repeat", Kind: "loop", Expression: "more == 'yes'", Result: false, NextStep: "address"})
+
+	// The loop collected both items and the subflow's answers came back
+	// under the subflow step's store_as
+	data := result.Turns[5].SessionData
+	assert.Equal(t, []interface{}{map[string]interface{}{"item": "tea"}, map[string]interface{}{"item": "cake"}}, data["items"])
+	assert.Equal(t, map[string]interface{}{"city": "Pune"}, data["address"])
+	assert.NotContains(t, data, "city")
+	assert.NotContains(t, data, "_loop_repeat")
+}
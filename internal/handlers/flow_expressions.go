@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/exprutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// Chatbot conditions and message templates share one expression language,
+// implemented in exprutil. Conditions such as skip conditions are plain
+// expressions ("status == 'vip' and total > 100"); message bodies, URLs and
+// request bodies are templates with {{expression}} tags and {{if}} and
+// {{for}} blocks.
+
+// processTemplate renders a message template with session data. Tags that
+// are not valid are sent as they are.
+func processTemplate(template string, data map[string]interface{}) string {
+	return exprutil.Render(template, data)
+}
+
+// evaluateExpression reports whether a condition holds for session data. An
+// empty or invalid condition does not hold.
+func evaluateExpression(expr string, data map[string]interface{}) bool {
+	if strings.TrimSpace(expr) == "" {
+		return false
+	}
+	return exprutil.Test(expr, data)
+}
+
+// copyMap creates a shallow copy of a map
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+// extractResponseMapping evaluates each mapping expression against an API
+// response and maps the results to session variables. Expressions with no
+// value are left out.
+func extractResponseMapping(responseData map[string]interface{}, mapping map[string]string) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	for varName, expr := range mapping {
+		value, err := exprutil.Eval(expr, responseData)
+		if err == nil && value != nil {
+			result[varName] = value
+		}
+	}
+
+	return result
+}
+
+// conditionalRule is one of the ordered "when" rules in a step's
+// conditional_next, going to next when the expression in if holds
+type conditionalRule struct {
+	If   string `json:"if"`
+	Next string `json:"next"`
+}
+
+// parseConditionalRules reads and checks the "when" rules of a step's
+// conditional_next
+func parseConditionalRules(conditionalNext map[string]interface{}) ([]conditionalRule, error) {
+	raw, ok := conditionalNext["when"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("when must be a list of rules")
+	}
+
+	rules := make([]conditionalRule, 0, len(list))
+	for i, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("when rule %d must be an object", i+1)
+		}
+		rule := conditionalRule{}
+		rule.If, _ = entry["if"].(string)
+		rule.Next, _ = entry["next"].(string)
+		if strings.TrimSpace(rule.If) == "" || rule.Next == "" {
+			return nil, fmt.Errorf("when rule %d needs an if and a next", i+1)
+		}
+		if _, err := exprutil.Compile(rule.If); err != nil {
+			return nil, fmt.Errorf("when rule %d: %w", i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matchConditionalRule returns the first "when" rule of a step that holds
+// for the session. Rules can read the reply as _input and the chosen button
+// or list item as _button_id.
+func matchConditionalRule(step *models.ChatbotFlowStep, sessionData models.JSONB, userInput, buttonID string) (conditionalRule, bool) {
+	rules, err := parseConditionalRules(step.ConditionalNext)
+	if err != nil || len(rules) == 0 {
+		return conditionalRule{}, false
+	}
+
+	data := copyMap(sessionData)
+	data["_input"] = userInput
+	data["_button_id"] = buttonID
+	for _, rule := range rules {
+		if evaluateExpression(rule.If, data) {
+			return rule, true
+		}
+	}
+	return conditionalRule{}, false
+}
+
+// namedSource is a condition or template to check, with the field it is in
+type namedSource struct {
+	field string
+	src   string
+}
+
+// stringFields lists the string values of a JSON object field, in key order
+func stringFields(field string, value interface{}) []namedSource {
+	m, _ := value.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sources []namedSource
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok {
+			sources = append(sources, namedSource{field: field + "." + key, src: s})
+		}
+	}
+	return sources
+}
+
+// checkTemplates parses templates, reporting the first invalid one
+func checkTemplates(templates []namedSource) error {
+	for _, t := range templates {
+		if _, err := exprutil.ParseTemplate(t.src); err != nil {
+			return fmt.Errorf("%s: %w", t.field, err)
+		}
+	}
+	return nil
+}
+
+// checkExpressions compiles the non-empty expressions, reporting the first
+// invalid one
+func checkExpressions(exprs []namedSource) error {
+	for _, e := range exprs {
+		if strings.TrimSpace(e.src) == "" {
+			continue
+		}
+		if _, err := exprutil.Compile(e.src); err != nil {
+			return fmt.Errorf("%s: %w", e.field, err)
+		}
+	}
+	return nil
+}
+
+// validateStepExpressions checks the conditions and templates of a flow step
+// so that mistakes are reported when the flow is saved rather than when a
+// contact reaches the step
+func validateStepExpressions(step FlowStepRequest) error {
+	str := func(config map[string]interface{}, key string) string {
+		s, _ := config[key].(string)
+		return s
+	}
+
+	exprs := []namedSource{
+		{"skip_condition", step.SkipCondition},
+		{"loop_config.condition", str(step.LoopConfig, "condition")},
+	}
+	exprs = append(exprs, stringFields("api_config.response_mapping", step.ApiConfig["response_mapping"])...)
+	if err := checkExpressions(exprs); err != nil {
+		return fmt.Errorf("step %q %w", step.StepName, err)
+	}
+	if _, err := parseConditionalRules(step.ConditionalNext); err != nil {
+		return fmt.Errorf("step %q conditional_next: %w", step.StepName, err)
+	}
+
+	templates := []namedSource{
+		{"message", step.Message},
+		{"api_config.url", str(step.ApiConfig, "url")},
+		{"api_config.body", str(step.ApiConfig, "body")},
+		{"api_config.fallback_message", str(step.ApiConfig, "fallback_message")},
+		{"transfer_config.notes", str(step.TransferConfig, "notes")},
+		{"input_config.flow_header", str(step.InputConfig, "flow_header")},
+		{"loop_config.limit_message", str(step.LoopConfig, "limit_message")},
+	}
+	templates = append(templates, stringFields("api_config.headers", step.ApiConfig["headers"])...)
+	templates = append(templates, stringFields("template_params", step.TemplateParams)...)
+	if err := checkTemplates(templates); err != nil {
+		return fmt.Errorf("step %q %w", step.StepName, err)
+	}
+	return nil
+}
+
+// validateFlowTemplates checks the flow-level message and completion
+// webhook templates of a flow
+func validateFlowTemplates(initialMessage, completionMessage string, completionConfig map[string]interface{}) error {
+	url, _ := completionConfig["url"].(string)
+	body, _ := completionConfig["body"].(string)
+	templates := []namedSource{
+		{"initial_message", initialMessage},
+		{"completion_message", completionMessage},
+		{"completion_config.url", url},
+		{"completion_config.body", body},
+	}
+	templates = append(templates, stringFields("completion_config.headers", completionConfig["headers"])...)
+	return checkTemplates(templates)
+}
+
+// keywordConditionData is the data keyword rule conditions can read: the
+// session's variables, and the contact as contact
+func keywordConditionData(session *models.ChatbotSession, contact *models.Contact) map[string]interface{} {
+	var data map[string]interface{}
+	if session != nil {
+		data = copyMap(session.SessionData)
+	} else {
+		data = map[string]interface{}{}
+	}
+	data["contact"] = map[string]interface{}{
+		"phone_number": contact.PhoneNumber,
+		"name":         contact.ProfileName,
+		"tags":         []interface{}(contact.Tags),
+		"attributes":   map[string]interface{}(contact.Attributes),
+	}
+	return data
+}
+
+// validateKeywordConditions checks the conditions of a keyword rule, which
+// may be empty
+func validateKeywordConditions(conditions string) error {
+	if strings.TrimSpace(conditions) == "" {
+		return nil
+	}
+	_, err := exprutil.Compile(conditions)
+	return err
+}
@@ -0,0 +1,589 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- processTemplate ---
+
+func TestProcessTemplate_EmptyTemplate(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "", processTemplate("", nil))
+}
+
+func TestProcessTemplate_NoPlaceholders(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "Hello, World!", processTemplate("Hello, World!", nil))
+}
+
+func TestProcessTemplate_VariablesOnly(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"name": "Alice", "age": 30}
+	result := processTemplate("Hello {{name}}, age {{age}}", data)
+	assert.Equal(t, "Hello Alice, age 30", result)
+}
+
+func TestProcessTemplate_LoopsOnly(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"items": []interface{}{"a", "b", "c"},
+	}
+	result := processTemplate("{{for item in items}}[{{item}}]{{endfor}}", data)
+	assert.Equal(t, "[a][b][c]", result)
+}
+
+func TestProcessTemplate_ConditionalsOnly(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"show": true}
+	result := processTemplate("{{if show}}visible{{endif}}", data)
+	assert.Equal(t, "visible", result)
+}
+
+func TestProcessTemplate_Mixed(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"name":  "Alice",
+		"show":  true,
+		"items": []interface{}{"x", "y"},
+	}
+	result := processTemplate("Hi {{name}}! {{if show}}Items: {{for item in items}}{{item}} {{endfor}}{{endif}}", data)
+	assert.Equal(t, "Hi Alice! Items: x y ", result)
+}
+
+func TestProcessTemplate_NilData(t *testing.T) {
+	t.Parallel()
+	result := processTemplate("Hello {{name}}", nil)
+	assert.Equal(t, "Hello ", result)
+}
+
+func TestProcessTemplate_NestedLoopsWithConditionals(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"name": "Alice", "active": true},
+			map[string]interface{}{"name": "Bob", "active": false},
+		},
+	}
+	result := processTemplate("{{for user in users}}{{if user.active}}*{{user.name}}*{{else}}({{user.name}}){{endif}} {{endfor}}", data)
+	assert.Equal(t, "*Alice* (Bob) ", result)
+}
+
+// --- {{for}} blocks ---
+
+func TestProcessTemplate_ForEmptyArray(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"items": []interface{}{}}
+	result := processTemplate("{{for item in items}}{{item}}{{endfor}}", data)
+	assert.Equal(t, "", result)
+}
+
+func TestProcessTemplate_ForSingleItem(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"items": []interface{}{"hello"}}
+	result := processTemplate("{{for item in items}}[{{item}}]{{endfor}}", data)
+	assert.Equal(t, "[hello]", result)
+}
+
+func TestProcessTemplate_ForMultipleItems(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"colors": []interface{}{"red", "green", "blue"},
+	}
+	result := processTemplate("{{for color in colors}}{{color}},{{endfor}}", data)
+	assert.Equal(t, "red,green,blue,", result)
+}
+
+func TestProcessTemplate_ForNestedObjectAccess(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"name": "Alice", "email": "alice@test.com"},
+			map[string]interface{}{"name": "Bob", "email": "bob@test.com"},
+		},
+	}
+	result := processTemplate("{{for user in users}}{{user.name}}:{{user.email}} {{endfor}}", data)
+	assert.Equal(t, "Alice:alice@test.com Bob:bob@test.com ", result)
+}
+
+func TestProcessTemplate_ForMissingArray(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	result := processTemplate("before{{for item in missing}}{{item}}{{endfor}}after", data)
+	assert.Equal(t, "beforeafter", result)
+}
+
+func TestProcessTemplate_ForIndexVariable(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"items": []interface{}{"a", "b", "c"},
+	}
+	result := processTemplate("{{for item in items}}{{item_index}}:{{item}} {{endfor}}", data)
+	assert.Equal(t, "0:a 1:b 2:c ", result)
+}
+
+func TestProcessTemplate_ForMapSlice(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"products": []map[string]interface{}{
+			{"name": "Widget", "price": 9.99},
+			{"name": "Gadget", "price": 19.99},
+		},
+	}
+	result := processTemplate("{{for p in products}}{{p.name}} {{endfor}}", data)
+	assert.Equal(t, "Widget Gadget ", result)
+}
+
+func TestProcessTemplate_ForNonArrayValue(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"items": "not-an-array"}
+	result := processTemplate("before{{for item in items}}{{item}}{{endfor}}after", data)
+	assert.Equal(t, "beforeafter", result)
+}
+
+func TestProcessTemplate_ForNestedPath(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{"x", "y"},
+		},
+	}
+	result := processTemplate("{{for item in data.items}}{{item}}{{endfor}}", data)
+	assert.Equal(t, "xy", result)
+}
+
+// --- {{if}} blocks ---
+
+func TestProcessTemplate_IfTruthyCondition(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"visible": true}
+	result := processTemplate("{{if visible}}shown{{endif}}", data)
+	assert.Equal(t, "shown", result)
+}
+
+func TestProcessTemplate_IfFalsyCondition(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"visible": false}
+	result := processTemplate("{{if visible}}shown{{endif}}", data)
+	assert.Equal(t, "", result)
+}
+
+func TestProcessTemplate_IfElseBranch(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"logged_in": false}
+	result := processTemplate("{{if logged_in}}Welcome{{else}}Please login{{endif}}", data)
+	assert.Equal(t, "Please login", result)
+}
+
+func TestProcessTemplate_IfElseBranchTrue(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"logged_in": true}
+	result := processTemplate("{{if logged_in}}Welcome{{else}}Please login{{endif}}", data)
+	assert.Equal(t, "Welcome", result)
+}
+
+func TestProcessTemplate_IfNumericGreaterThan(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"score": 85}
+	result := processTemplate("{{if score > 80}}pass{{else}}fail{{endif}}", data)
+	assert.Equal(t, "pass", result)
+}
+
+func TestProcessTemplate_IfNumericLessThan(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"score": 50}
+	result := processTemplate("{{if score < 60}}fail{{else}}pass{{endif}}", data)
+	assert.Equal(t, "fail", result)
+}
+
+func TestProcessTemplate_IfStringEquality(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"status": "active"}
+	result := processTemplate("{{if status == 'active'}}online{{else}}offline{{endif}}", data)
+	assert.Equal(t, "online", result)
+}
+
+func TestProcessTemplate_IfStringInequality(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"status": "inactive"}
+	result := processTemplate("{{if status != 'active'}}not active{{else}}active{{endif}}", data)
+	assert.Equal(t, "not active", result)
+}
+
+func TestProcessTemplate_IfMissingVariable(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	result := processTemplate("{{if missing}}yes{{else}}no{{endif}}", data)
+	assert.Equal(t, "no", result)
+}
+
+func TestProcessTemplate_IfNestedPath(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"user": map[string]interface{}{"active": true},
+	}
+	result := processTemplate("{{if user.active}}active{{endif}}", data)
+	assert.Equal(t, "active", result)
+}
+
+func TestProcessTemplate_IfGreaterThanOrEqual(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"count": 10}
+	assert.Equal(t, "yes", processTemplate("{{if count >= 10}}yes{{else}}no{{endif}}", data))
+	data["count"] = 9
+	assert.Equal(t, "no", processTemplate("{{if count >= 10}}yes{{else}}no{{endif}}", data))
+}
+
+func TestProcessTemplate_IfLessThanOrEqual(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"count": 10}
+	assert.Equal(t, "yes", processTemplate("{{if count <= 10}}yes{{else}}no{{endif}}", data))
+	data["count"] = 11
+	assert.Equal(t, "no", processTemplate("{{if count <= 10}}yes{{else}}no{{endif}}", data))
+}
+
+// --- {{expression}} tags ---
+
+func TestProcessTemplate_VariableSimple(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"name": "Alice"}
+	assert.Equal(t, "Hello Alice", processTemplate("Hello {{name}}", data))
+}
+
+func TestProcessTemplate_VariableNestedPath(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"user": map[string]interface{}{
+			"profile": map[string]interface{}{"name": "Alice"},
+		},
+	}
+	assert.Equal(t, "Hello Alice", processTemplate("Hello {{user.profile.name}}", data))
+}
+
+func TestProcessTemplate_VariableArrayIndex(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"items": []interface{}{"first", "second", "third"},
+	}
+	assert.Equal(t, "first", processTemplate("{{items[0]}}", data))
+}
+
+func TestProcessTemplate_VariableMissingVariable(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	assert.Equal(t, "Hello ", processTemplate("Hello {{name}}", data))
+}
+
+func TestProcessTemplate_VariableMultipleVariables(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"first": "John", "last": "Doe"}
+	assert.Equal(t, "John Doe", processTemplate("{{first}} {{last}}", data))
+}
+
+func TestProcessTemplate_VariableNoPlaceholders(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "plain text", processTemplate("plain text", map[string]interface{}{}))
+}
+
+// --- evaluateExpression ---
+
+func TestEvaluateExpression_TruthyVariable(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"active": true}
+	assert.True(t, evaluateExpression("active", data))
+}
+
+func TestEvaluateExpression_FalsyVariable(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"active": false}
+	assert.False(t, evaluateExpression("active", data))
+}
+
+func TestEvaluateExpression_MissingVariable(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{}
+	assert.False(t, evaluateExpression("missing", data))
+}
+
+func TestEvaluateExpression_EqualOperator(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"status": "active"}
+	assert.True(t, evaluateExpression("status == 'active'", data))
+	assert.False(t, evaluateExpression("status == 'inactive'", data))
+}
+
+func TestEvaluateExpression_NotEqualOperator(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"status": "active"}
+	assert.True(t, evaluateExpression("status != 'inactive'", data))
+	assert.False(t, evaluateExpression("status != 'active'", data))
+}
+
+func TestEvaluateExpression_GreaterThan(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"score": 85}
+	assert.True(t, evaluateExpression("score > 80", data))
+	assert.False(t, evaluateExpression("score > 90", data))
+}
+
+func TestEvaluateExpression_LessThan(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"score": 50}
+	assert.True(t, evaluateExpression("score < 60", data))
+	assert.False(t, evaluateExpression("score < 40", data))
+}
+
+func TestEvaluateExpression_GreaterThanOrEqual(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"score": 80}
+	assert.True(t, evaluateExpression("score >= 80", data))
+	assert.True(t, evaluateExpression("score >= 79", data))
+	assert.False(t, evaluateExpression("score >= 81", data))
+}
+
+func TestEvaluateExpression_LessThanOrEqual(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"score": 80}
+	assert.True(t, evaluateExpression("score <= 80", data))
+	assert.True(t, evaluateExpression("score <= 81", data))
+	assert.False(t, evaluateExpression("score <= 79", data))
+}
+
+func TestEvaluateExpression_DoubleQuotes(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{"name": "Alice"}
+	assert.True(t, evaluateExpression(`name == "Alice"`, data))
+}
+
+func TestEvaluateExpression_NestedPath(t *testing.T) {
+	t.Parallel()
+	data := map[string]interface{}{
+		"user": map[string]interface{}{"role": "admin"},
+	}
+	assert.True(t, evaluateExpression("user.role == 'admin'", data))
+}
+
+func TestEvaluateExpression_EmptyCondition(t *testing.T) {
+	t.Parallel()
+	assert.False(t, evaluateExpression("", map[string]interface{}{}))
+}
+
+// --- copyMap ---
+
+func TestCopyMap_ShallowCopy(t *testing.T) {
+	t.Parallel()
+
+	original := map[string]interface{}{"a": 1, "b": "two", "c": true}
+	copied := copyMap(original)
+
+	assert.Equal(t, original, copied)
+
+	// Modify copy should not affect original
+	copied["d"] = "new"
+	assert.Nil(t, original["d"])
+}
+
+func TestCopyMap_Empty(t *testing.T) {
+	t.Parallel()
+
+	original := map[string]interface{}{}
+	copied := copyMap(original)
+	assert.Equal(t, 0, len(copied))
+}
+
+func TestCopyMap_NilSafe(t *testing.T) {
+	t.Parallel()
+
+	// copyMap with nil would panic (range nil map is ok but make(nil) not)
+	// The implementation handles nil implicitly via make with len(nil) == 0
+	copied := copyMap(nil)
+	assert.NotNil(t, copied)
+	assert.Equal(t, 0, len(copied))
+}
+
+// --- extractResponseMapping ---
+
+func TestExtractResponseMapping_SimpleKey(t *testing.T) {
+	t.Parallel()
+
+	response := map[string]interface{}{"name": "Alice", "age": 30}
+	mapping := map[string]string{
+		"user_name": "name",
+		"user_age":  "age",
+	}
+	result := extractResponseMapping(response, mapping)
+	assert.Equal(t, "Alice", result["user_name"])
+	assert.Equal(t, 30, result["user_age"])
+}
+
+func TestExtractResponseMapping_NestedPath(t *testing.T) {
+	t.Parallel()
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"user": map[string]interface{}{"id": "u123"},
+		},
+	}
+	mapping := map[string]string{"uid": "data.user.id"}
+	result := extractResponseMapping(response, mapping)
+	assert.Equal(t, "u123", result["uid"])
+}
+
+func TestExtractResponseMapping_MissingKey(t *testing.T) {
+	t.Parallel()
+
+	response := map[string]interface{}{"name": "Alice"}
+	mapping := map[string]string{"email": "email"}
+	result := extractResponseMapping(response, mapping)
+	assert.Nil(t, result["email"])
+}
+
+func TestExtractResponseMapping_EmptyMapping(t *testing.T) {
+	t.Parallel()
+
+	response := map[string]interface{}{"name": "Alice"}
+	result := extractResponseMapping(response, map[string]string{})
+	assert.Equal(t, 0, len(result))
+}
+
+func TestExtractResponseMapping_PartialMatch(t *testing.T) {
+	t.Parallel()
+
+	response := map[string]interface{}{"name": "Alice", "age": 30}
+	mapping := map[string]string{
+		"user_name": "name",
+		"email":     "email", // missing
+	}
+	result := extractResponseMapping(response, mapping)
+	assert.Equal(t, "Alice", result["user_name"])
+	_, hasEmail := result["email"]
+	assert.False(t, hasEmail)
+}
+
+// =============================================================================
+// conditional_next rules
+// =============================================================================
+
+func TestMatchConditionalRule_FirstMatchingRule(t *testing.T) {
+	t.Parallel()
+
+	step := &models.ChatbotFlowStep{
+		ConditionalNext: models.JSONB{
+			"when": []interface{}{
+				map[string]interface{}{"if": "total > 1000", "next": "priority"},
+				map[string]interface{}{"if": "_input in ['yes', 'y']", "next": "confirm"},
+				map[string]interface{}{"if": "true", "next": "fallback"},
+			},
+		},
+	}
+
+	rule, ok := matchConditionalRule(step, models.JSONB{"total": 1500}, "yes", "")
+	require.True(t, ok)
+	assert.Equal(t, "priority", rule.Next)
+
+	rule, ok = matchConditionalRule(step, models.JSONB{"total": 10}, "y", "")
+	require.True(t, ok)
+	assert.Equal(t, "confirm", rule.Next)
+
+	rule, ok = matchConditionalRule(step, nil, "no", "")
+	require.True(t, ok)
+	assert.Equal(t, "fallback", rule.Next)
+}
+
+func TestMatchConditionalRule_NoRules(t *testing.T) {
+	t.Parallel()
+
+	step := &models.ChatbotFlowStep{ConditionalNext: models.JSONB{"yes": "confirm"}}
+	_, ok := matchConditionalRule(step, nil, "yes", "")
+	assert.False(t, ok)
+}
+
+func TestParseConditionalRules_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]models.JSONB{
+		"when must be a list of rules":              {"when": "total > 1"},
+		"when rule 1 must be an object":             {"when": []interface{}{"total > 1"}},
+		"when rule 1 needs an if and a next":        {"when": []interface{}{map[string]interface{}{"if": "total > 1"}}},
+		"when rule 1: unexpected end of expression": {"when": []interface{}{map[string]interface{}{"if": "total >", "next": "a"}}},
+	}
+	for want, conditionalNext := range tests {
+		_, err := parseConditionalRules(conditionalNext)
+		assert.EqualError(t, err, want)
+	}
+}
+
+// =============================================================================
+// Validation on save
+// =============================================================================
+
+func TestValidateStepExpressions(t *testing.T) {
+	t.Parallel()
+
+	valid := FlowStepRequest{
+		StepName:      "greet",
+		Message:       "Hi {{upper(name)}}{{if vip}} ⭐{{endif}}",
+		SkipCondition: "name != null",
+		ApiConfig: map[string]interface{}{
+			"url":              "https://example.com/orders/{{order_id}}",
+			"response_mapping": map[string]interface{}{"total": "data.total ?? 0"},
+		},
+	}
+	assert.NoError(t, validateStepExpressions(valid))
+
+	step := valid
+	step.SkipCondition = "name =="
+	assert.EqualError(t, validateStepExpressions(step), `step "greet" skip_condition: unexpected end of expression`)
+
+	step = valid
+	step.Message = "Hi {{if vip}}⭐"
+	assert.EqualError(t, validateStepExpressions(step), `step "greet" message: {{if vip}} is missing {{endif}}`)
+
+	step = valid
+	step.ApiConfig = map[string]interface{}{"response_mapping": map[string]interface{}{"total": "data.("}}
+	assert.Error(t, validateStepExpressions(step))
+
+	step = valid
+	step.ConditionalNext = map[string]interface{}{"when": []interface{}{map[string]interface{}{"if": "a b", "next": "x"}}}
+	assert.EqualError(t, validateStepExpressions(step), `step "greet" conditional_next: when rule 1: unexpected "b"`)
+}
+
+func TestValidateFlowTemplates(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateFlowTemplates("Welcome {{name}}", "", nil))
+	assert.EqualError(t,
+		validateFlowTemplates("", "Thanks {{name +}}", nil),
+		"completion_message: {{name +}}: unexpected end of expression")
+	assert.Error(t, validateFlowTemplates("", "", map[string]interface{}{"headers": map[string]interface{}{"X-Id": "{{endif}}"}}))
+}
+
+func TestValidateKeywordConditions(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateKeywordConditions(""))
+	assert.NoError(t, validateKeywordConditions("'vip' in contact.tags"))
+	assert.Error(t, validateKeywordConditions("contact.tags contains"))
+}
+
+func TestKeywordConditionData(t *testing.T) {
+	t.Parallel()
+
+	contact := &models.Contact{
+		PhoneNumber: "919876543210",
+		ProfileName: "Asha",
+		Tags:        models.JSONBArray{"vip"},
+		Attributes:  models.JSONB{"plan": "gold"},
+	}
+	session := &models.ChatbotSession{SessionData: models.JSONB{"order_id": "A1"}}
+
+	data := keywordConditionData(session, contact)
+	assert.True(t, evaluateExpression("'vip' in contact.tags and contact.attributes.plan == 'gold'", data))
+	assert.True(t, evaluateExpression("order_id == 'A1'", data))
+	assert.Equal(t, models.JSONB{"order_id": "A1"}, session.SessionData)
+
+	assert.True(t, evaluateExpression("order_id == null", keywordConditionData(nil, contact)))
+}
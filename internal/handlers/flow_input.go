@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/inpututil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// typedInputs are the input types whose answers are normalized before they
+// are stored
+var typedInputs = map[models.InputType]bool{
+	models.InputTypeNumber: true,
+	models.InputTypeEmail:  true,
+	models.InputTypePhone:  true,
+	models.InputTypeDate:   true,
+}
+
+// flowMedia is a media file or location received as the answer to a step
+type flowMedia struct {
+	Type     models.InputType
+	MediaURL string // Path under the media storage directory
+	MimeType string
+	Filename string
+	Caption  string
+	Size     int64 // Set by the simulator; otherwise read from the saved file
+	Location map[string]interface{}
+}
+
+// flowMediaFromMessage describes an incoming media or location message, or
+// returns nil for other messages
+func flowMediaFromMessage(msg IncomingTextMessage, mediaInfo *MediaInfo, caption string) *flowMedia {
+	switch msg.Type {
+	case "image", "document", "audio", "video":
+		if mediaInfo == nil {
+			return nil
+		}
+		return &flowMedia{
+			Type:     models.InputType(msg.Type),
+			MediaURL: mediaInfo.MediaURL,
+			MimeType: mediaInfo.MediaMimeType,
+			Filename: mediaInfo.MediaFilename,
+			Caption:  caption,
+		}
+	case "location":
+		if msg.Location == nil {
+			return nil
+		}
+		location := map[string]interface{}{
+			"latitude":  msg.Location.Latitude,
+			"longitude": msg.Location.Longitude,
+		}
+		if msg.Location.Name != "" {
+			location["name"] = msg.Location.Name
+		}
+		if msg.Location.Address != "" {
+			location["address"] = msg.Location.Address
+		}
+		return &flowMedia{Type: models.InputTypeLocation, Location: location}
+	}
+	return nil
+}
+
+// validateStepInput checks an answer against the step's validation regex and
+// its input type. Returns the value to store, or the error message to send
+// back to the customer.
+func (a *App) validateStepInput(orgID uuid.UUID, session *models.ChatbotSession, step *models.ChatbotFlowStep, input string, media *flowMedia) (interface{}, string) {
+	cfg := inpututil.ParseConfig(step.InputConfig)
+	lang := a.stepLanguage(session, step)
+
+	if inpututil.IsMediaInput(step.InputType) {
+		value, err := a.validateStepMedia(step, cfg, media)
+		if err != nil {
+			return nil, stepErrorMessage(step, cfg, lang, err)
+		}
+		return value, ""
+	}
+
+	if step.ValidationRegex != "" {
+		re, err := regexp.Compile(step.ValidationRegex)
+		if err == nil && !re.MatchString(input) {
+			return nil, stepErrorMessage(step, cfg, lang, &inpututil.Error{Code: inpututil.CodeInvalidFormat})
+		}
+	}
+
+	if !typedInputs[step.InputType] && cfg.MinLength == 0 && cfg.MaxLength == 0 {
+		return input, ""
+	}
+	if step.InputType == models.InputTypePhone && cfg.DefaultRegion == "" {
+		cfg.DefaultRegion = contactutil.DefaultRegion(a.DB, orgID)
+	}
+
+	value, err := inpututil.Validate(step.InputType, input, cfg, time.Now())
+	if err != nil {
+		return nil, stepErrorMessage(step, cfg, lang, err)
+	}
+	if !typedInputs[step.InputType] {
+		// Length limits only; keep the answer as typed
+		return input, ""
+	}
+	return value, ""
+}
+
+// validateStepMedia checks a media or location answer. Returns the saved
+// media path, or the coordinates for locations.
+func (a *App) validateStepMedia(step *models.ChatbotFlowStep, cfg inpututil.Config, media *flowMedia) (interface{}, error) {
+	if media == nil {
+		return nil, inpututil.ValidateMedia(step.InputType, "", "", 0, cfg)
+	}
+	if media.Type == models.InputTypeLocation {
+		if err := inpututil.ValidateMedia(step.InputType, media.Type, "", 0, cfg); err != nil {
+			return nil, err
+		}
+		return media.Location, nil
+	}
+
+	var size int64
+	if media.Type == step.InputType {
+		// The download from Meta failed
+		if media.MediaURL == "" {
+			return nil, &inpututil.Error{Code: inpututil.CodeMediaUnavailable}
+		}
+		size = media.Size
+		if size == 0 {
+			if info, err := os.Stat(filepath.Join(a.getMediaStoragePath(), media.MediaURL)); err == nil {
+				size = info.Size()
+			}
+		}
+	}
+	if err := inpututil.ValidateMedia(step.InputType, media.Type, media.MimeType, size, cfg); err != nil {
+		return nil, err
+	}
+	return media.MediaURL, nil
+}
+
+// stepErrorMessage renders a validation error. A per-code override in
+// input_config wins, then the step's validation_error, then the built-in
+// message.
+func stepErrorMessage(step *models.ChatbotFlowStep, cfg inpututil.Config, lang string, err error) string {
+	var inputErr *inpututil.Error
+	if !errors.As(err, &inputErr) {
+		return err.Error()
+	}
+	if _, ok := cfg.ErrorMessages[inputErr.Code]; !ok && step.ValidationError != "" {
+		return step.ValidationError
+	}
+	return inpututil.Message(inputErr, lang, cfg.ErrorMessages)
+}
+
+// stepLanguage picks the language of validation messages: the step's
+// input_config language, then the session's "language" variable
+func (a *App) stepLanguage(session *models.ChatbotSession, step *models.ChatbotFlowStep) string {
+	if lang, _ := step.InputConfig["language"].(string); lang != "" {
+		return lang
+	}
+	if lang, _ := session.SessionData["language"].(string); lang != "" {
+		return lang
+	}
+	return inpututil.DefaultLanguage
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStepInput(t *testing.T) {
+	app := &App{}
+	session := &models.ChatbotSession{SessionData: models.JSONB{"language": "es"}}
+
+	quantity := &models.ChatbotFlowStep{
+		InputType:   models.InputTypeNumber,
+		InputConfig: models.JSONB{"min": 1.0, "max": 10.0, "decimals": 0.0},
+	}
+	value, msg := app.validateStepInput(uuid.New(), session, quantity, "4", nil)
+	assert.Empty(t, msg)
+	assert.Equal(t, 4.0, value)
+
+	// Built-in message in the session's language
+	_, msg = app.validateStepInput(uuid.New(), session, quantity, "12", nil)
+	assert.Equal(t, "Por favor, introduce un número no mayor que 10.", msg)
+
+	// The step's validation_error replaces built-in messages...
+	quantity.ValidationError = "Choose between 1 and 10."
+	_, msg = app.validateStepInput(uuid.New(), session, quantity, "12", nil)
+	assert.Equal(t, "Choose between 1 and 10.", msg)
+
+	// ...but not per-code overrides
+	quantity.InputConfig["error_messages"] = map[string]interface{}{"too_many_decimals": "Whole numbers only."}
+	_, msg = app.validateStepInput(uuid.New(), session, quantity, "2.5", nil)
+	assert.Equal(t, "Whole numbers only.", msg)
+
+	// Regex failures use the invalid_format message
+	code := &models.ChatbotFlowStep{InputType: models.InputTypeText, ValidationRegex: `^[A-Z]{3}\d{3}$`}
+	_, msg = app.validateStepInput(uuid.New(), &models.ChatbotSession{}, code, "abc", nil)
+	assert.Equal(t, "Invalid input. Please try again.", msg)
+	value, msg = app.validateStepInput(uuid.New(), &models.ChatbotSession{}, code, "ABC123", nil)
+	assert.Empty(t, msg)
+	assert.Equal(t, "ABC123", value)
+
+	phone := &models.ChatbotFlowStep{InputType: models.InputTypePhone, InputConfig: models.JSONB{"default_region": "IN"}}
+	value, msg = app.validateStepInput(uuid.New(), &models.ChatbotSession{}, phone, "98765 43210", nil)
+	assert.Empty(t, msg)
+	assert.Equal(t, "919876543210", value)
+
+	// Text answers are stored as typed
+	name := &models.ChatbotFlowStep{InputType: models.InputTypeText, InputConfig: models.JSONB{"max_length": 20.0}}
+	value, msg = app.validateStepInput(uuid.New(), &models.ChatbotSession{}, name, " Asha ", nil)
+	assert.Empty(t, msg)
+	assert.Equal(t, " Asha ", value)
+}
+
+func TestValidateStepInput_Media(t *testing.T) {
+	app := &App{Config: &config.Config{}}
+	app.Config.Storage.LocalPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(app.Config.Storage.LocalPath, "images"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(app.Config.Storage.LocalPath, "images", "damage.jpg"), make([]byte, 2048), 0644))
+
+	session := &models.ChatbotSession{}
+	photo := &models.ChatbotFlowStep{
+		InputType:   models.InputTypeImage,
+		InputConfig: models.JSONB{"allowed_mime_types": []interface{}{"image/*"}, "max_size_mb": 1.0},
+	}
+	image := &flowMedia{Type: models.InputTypeImage, MediaURL: "images/damage.jpg", MimeType: "image/jpeg"}
+
+	value, msg := app.validateStepInput(uuid.New(), session, photo, "", image)
+	assert.Empty(t, msg)
+	assert.Equal(t, "images/damage.jpg", value)
+
+	// Text instead of a photo
+	_, msg = app.validateStepInput(uuid.New(), session, photo, "here you go", nil)
+	assert.Equal(t, "Please send a photo.", msg)
+
+	// The download from Meta failed
+	_, msg = app.validateStepInput(uuid.New(), session, photo, "", &flowMedia{Type: models.InputTypeImage, MimeType: "image/jpeg"})
+	assert.Equal(t, "We couldn't receive your file. Please send it again.", msg)
+
+	photo.InputConfig["max_size_mb"] = 0.001
+	_, msg = app.validateStepInput(uuid.New(), session, photo, "", image)
+	assert.Equal(t, "This file is too large. Please send a file of at most 0.001 MB.", msg)
+
+	where := &models.ChatbotFlowStep{InputType: models.InputTypeLocation}
+	location := flowMediaFromMessage(IncomingTextMessage{
+		Type: "location",
+		Location: &struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+			Name      string  `json:"name,omitempty"`
+			Address   string  `json:"address,omitempty"`
+		}{Latitude: 12.97, Longitude: 77.59, Address: "MG Road"},
+	}, nil, `{"latitude":12.97}`)
+	value, msg = app.validateStepInput(uuid.New(), session, where, `{"latitude":12.97}`, location)
+	assert.Empty(t, msg)
+	assert.Equal(t, map[string]interface{}{"latitude": 12.97, "longitude": 77.59, "address": "MG Road"}, value)
+
+	_, msg = app.validateStepInput(uuid.New(), session, where, "", image)
+	assert.Equal(t, "Please share your location.", msg)
+}
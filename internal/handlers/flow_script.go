@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/exprutil"
+)
+
+// Script steps compute session variables from expressions such as
+// "price * quantity", "upper(first_name) + ' ' + last_name" or
+// "round(total * 1.18, 2)", written in the same expression language as skip
+// conditions and message templates.
+
+// scriptVariablePattern matches the names script assignments may store to.
+// Names starting with an underscore are reserved for flow metadata.
+var scriptVariablePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// scriptExpr evaluates a compiled script expression against session data
+type scriptExpr func(data map[string]interface{}) (interface{}, error)
+
+// scriptAssignment stores the result of an expression, or of a message
+// template when value is set, in a session variable
+type scriptAssignment struct {
+	Variable   string
+	Expression string
+	Value      string
+	hasValue   bool
+}
+
+// parseScriptAssignments reads the assignments from a step's script_config
+func parseScriptAssignments(config map[string]interface{}) ([]scriptAssignment, error) {
+	raw, ok := config["assignments"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("script_config must have at least one assignment")
+	}
+
+	assignments := make([]scriptAssignment, 0, len(raw))
+	for i, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("assignment %d must be an object", i+1)
+		}
+		variable, _ := entry["variable"].(string)
+		if !scriptVariablePattern.MatchString(variable) {
+			return nil, fmt.Errorf("assignment %d has an invalid variable name %q", i+1, variable)
+		}
+
+		assignment := scriptAssignment{Variable: variable}
+		if value, ok := entry["value"].(string); ok {
+			if _, err := exprutil.ParseTemplate(value); err != nil {
+				return nil, fmt.Errorf("assignment %d: %w", i+1, err)
+			}
+			assignment.Value = value
+			assignment.hasValue = true
+		} else {
+			assignment.Expression, _ = entry["expression"].(string)
+			if strings.TrimSpace(assignment.Expression) == "" {
+				return nil, fmt.Errorf("assignment %d needs an expression or a value", i+1)
+			}
+			if _, err := compileScriptExpression(assignment.Expression); err != nil {
+				return nil, fmt.Errorf("assignment %d: %w", i+1, err)
+			}
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+// runScript evaluates the assignments in order, storing each result in data
+// so later assignments can use it. Failed assignments leave their variable
+// unchanged; their errors are returned together.
+func runScript(assignments []scriptAssignment, data map[string]interface{}) []error {
+	var errs []error
+	for _, assignment := range assignments {
+		if assignment.hasValue {
+			data[assignment.Variable] = processTemplate(assignment.Value, data)
+			continue
+		}
+		expr, err := compileScriptExpression(assignment.Expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", assignment.Variable, err))
+			continue
+		}
+		value, err := expr(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", assignment.Variable, err))
+			continue
+		}
+		data[assignment.Variable] = value
+	}
+	return errs
+}
+
+// compileScriptExpression parses an expression in the flow expression
+// language
+func compileScriptExpression(src string) (scriptExpr, error) {
+	expr, err := exprutil.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Eval, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func evalScript(t *testing.T, expr string, data map[string]interface{}) interface{} {
+	t.Helper()
+	compiled, err := compileScriptExpression(expr)
+	require.NoError(t, err, expr)
+	value, err := compiled(data)
+	require.NoError(t, err, expr)
+	return value
+}
+
+func TestScriptExpression_Arithmetic(t *testing.T) {
+	data := map[string]interface{}{"price": 250.0, "quantity": "3"}
+
+	assert.Equal(t, 750.0, evalScript(t, "price * quantity", data))
+	assert.Equal(t, 7.0, evalScript(t, "1 + 2 * 3", data))
+	assert.Equal(t, 9.0, evalScript(t, "(1 + 2) * 3", data))
+	assert.Equal(t, -1.0, evalScript(t, "2 - -(-3)", data))
+	assert.Equal(t, 1.0, evalScript(t, "10 % 3", data))
+	assert.Equal(t, 885.0, evalScript(t, "round(price * quantity * 1.18)", data))
+}
+
+func TestScriptExpression_Strings(t *testing.T) {
+	data := map[string]interface{}{
+		"first_name": "asha",
+		"order":      map[string]interface{}{"items": []interface{}{map[string]interface{}{"sku": "A1"}}},
+	}
+
+	assert.Equal(t, "ASHA rao", evalScript(t, "upper(first_name) + ' ' + \"rao\"", data))
+	assert.Equal(t, "A1", evalScript(t, "order.items[0].sku", data))
+	assert.Equal(t, 1.0, evalScript(t, "len(order.items)", data))
+	assert.Equal(t, "guest", evalScript(t, "coalesce(nickname, 'guest')", data))
+	assert.Equal(t, "Order 5", evalScript(t, "'Order ' + 5", data))
+}
+
+func TestScriptExpression_Errors(t *testing.T) {
+	for _, expr := range []string{"1 +", "(1 + 2", "foo(1)", "round()", "'open", "1 $ 2"} {
+		_, err := compileScriptExpression(expr)
+		assert.Error(t, err, expr)
+	}
+
+	compiled, err := compileScriptExpression("total / count")
+	require.NoError(t, err)
+	_, err = compiled(map[string]interface{}{"total": 10.0, "count": 0.0})
+	assert.EqualError(t, err, "division by zero")
+	_, err = compiled(map[string]interface{}{"total": "ten", "count": 2.0})
+	assert.EqualError(t, err, `"ten" is not a number`)
+}
+
+func TestParseScriptAssignments(t *testing.T) {
+	_, err := parseScriptAssignments(map[string]interface{}{})
+	assert.EqualError(t, err, "script_config must have at least one assignment")
+
+	_, err = parseScriptAssignments(map[string]interface{}{"assignments": []interface{}{
+		map[string]interface{}{"variable": "_flow_id", "value": "x"},
+	}})
+	assert.EqualError(t, err, `assignment 1 has an invalid variable name "_flow_id"`)
+
+	_, err = parseScriptAssignments(map[string]interface{}{"assignments": []interface{}{
+		map[string]interface{}{"variable": "total", "expression": "price *"},
+	}})
+	assert.EqualError(t, err, "assignment 1: unexpected end of expression")
+}
+
+func TestRunScript(t *testing.T) {
+	assignments, err := parseScriptAssignments(map[string]interface{}{"assignments": []interface{}{
+		map[string]interface{}{"variable": "subtotal", "expression": "price * quantity"},
+		map[string]interface{}{"variable": "total", "expression": "subtotal + shipping"},
+		map[string]interface{}{"variable": "summary", "value": "{{quantity}} items, {{total}} total"},
+		map[string]interface{}{"variable": "broken", "expression": "price / 0"},
+	}})
+	require.NoError(t, err)
+
+	data := map[string]interface{}{"price": "20", "quantity": "2", "shipping": 5.0}
+	errs := runScript(assignments, data)
+
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "broken: division by zero")
+	assert.Equal(t, 40.0, data["subtotal"])
+	assert.Equal(t, 45.0, data["total"])
+	assert.Equal(t, "2 items, 45 total", data["summary"])
+	assert.NotContains(t, data, "broken")
+}
@@ -0,0 +1,543 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"gorm.io/gorm"
+)
+
+// SimulatorContact describes the virtual contact a simulated flow talks to.
+// A phone number that belongs to an existing contact runs the flow as that
+// contact; nothing the simulation changes is saved.
+type SimulatorContact struct {
+	ProfileName string                 `json:"profile_name"`
+	PhoneNumber string                 `json:"phone_number"`
+	Attributes  map[string]interface{} `json:"attributes"`
+}
+
+// SimulatorMedia is a media file sent by the virtual contact
+type SimulatorMedia struct {
+	Type      models.InputType `json:"type"` // image, document, audio or video
+	MediaURL  string           `json:"media_url"`
+	MimeType  string           `json:"mime_type"`
+	Filename  string           `json:"filename"`
+	SizeBytes int64            `json:"size_bytes"`
+}
+
+// TurnExpectation is what a test case expects after a turn. Unset fields are
+// not checked.
+type TurnExpectation struct {
+	Step        *string                `json:"step,omitempty"`   // Current step; "" once the flow has ended
+	Status      models.SessionStatus   `json:"status,omitempty"` // Session status
+	Messages    []string               `json:"messages,omitempty"`
+	SessionData map[string]interface{} `json:"session_data,omitempty"`
+	Transferred *bool                  `json:"transferred,omitempty"`
+}
+
+// SimulatorTurn is one message from the virtual contact. The first turn
+// starts the flow, so its input is only recorded.
+type SimulatorTurn struct {
+	Text         string                 `json:"text,omitempty"`
+	ButtonID     string                 `json:"button_id,omitempty"`
+	FlowResponse map[string]interface{} `json:"flow_response,omitempty"`
+	Media        *SimulatorMedia        `json:"media,omitempty"`
+	Location     map[string]interface{} `json:"location,omitempty"`
+	Expect       *TurnExpectation       `json:"expect,omitempty"`
+}
+
+// SimulatorAPIMock answers HTTP calls made by api_fetch steps and completion
+// webhooks. The first mock whose method and URL prefix match is used.
+type SimulatorAPIMock struct {
+	Method string      `json:"method,omitempty"` // Any method when empty
+	URL    string      `json:"url"`              // Prefix of the requested URL
+	Status int         `json:"status,omitempty"` // Defaults to 200
+	Body   interface{} `json:"body,omitempty"`   // Sent as is when a string, as JSON otherwise
+}
+
+// SimulateFlowRequest is the input of a simulation
+type SimulateFlowRequest struct {
+	Contact  SimulatorContact   `json:"contact"`
+	Turns    []SimulatorTurn    `json:"turns"`
+	APIMocks []SimulatorAPIMock `json:"api_mocks"`
+}
+
+// SimulatedMessage is a message the bot sent
+type SimulatedMessage struct {
+	Type            models.MessageType `json:"type"`
+	Content         string             `json:"content"`
+	InteractiveData models.JSONB       `json:"interactive_data,omitempty"`
+	TemplateName    string             `json:"template_name,omitempty"`
+}
+
+// SimulatedCondition is a skip condition or conditional_next rule evaluated
+// during a turn
+type SimulatedCondition struct {
+	Step       string `json:"step"`
+	Kind       string `json:"kind"`       // skip_condition or conditional_next
+	Expression string `json:"expression"` // The condition, or the matched conditional_next key
+	Result     bool   `json:"result"`
+	NextStep   string `json:"next_step,omitempty"`
+}
+
+// SimulatedAPICall is an HTTP call answered by the simulator
+type SimulatedAPICall struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+	Status int    `json:"status"`
+	Mocked bool   `json:"mocked"`
+}
+
+// SimulatedTransfer is a transfer to agents the flow would have created
+type SimulatedTransfer struct {
+	TeamID         *uuid.UUID               `json:"team_id,omitempty"`
+	Notes          string                   `json:"notes,omitempty"`
+	RequiredSkills models.SkillRequirements `json:"required_skills,omitempty"`
+}
+
+// SimulatedTurn is the outcome of one turn
+type SimulatedTurn struct {
+	Input       *SimulatorTurn       `json:"input"`
+	Messages    []SimulatedMessage   `json:"messages"`
+	CurrentStep string               `json:"current_step"`
+	Status      models.SessionStatus `json:"status"`
+	SessionData models.JSONB         `json:"session_data"`
+	Conditions  []SimulatedCondition `json:"conditions"`
+	APICalls    []SimulatedAPICall   `json:"api_calls"`
+	Transfers   []SimulatedTransfer  `json:"transfers"`
+	Failures    []string             `json:"failures,omitempty"`
+}
+
+// SimulationResult is the outcome of a simulation
+type SimulationResult struct {
+	Turns    []SimulatedTurn `json:"turns"`
+	Passed   bool            `json:"passed"`
+	Failures []string        `json:"failures"`
+}
+
+// flowSimulation collects what a flow does while it runs in the simulator.
+// The App running the flow has it set, which stubs out sends, HTTP calls and
+// transfers.
+type flowSimulation struct {
+	flow  *models.ChatbotFlow
+	mocks []SimulatorAPIMock
+
+	mu         sync.Mutex
+	flows      map[uuid.UUID]*models.ChatbotFlow // Subflows and goto targets, as drafts
+	messages   []SimulatedMessage
+	conditions []SimulatedCondition
+	apiCalls   []SimulatedAPICall
+	transfers  []SimulatedTransfer
+	sent       int
+}
+
+func (s *flowSimulation) recordMessage(msg *models.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, SimulatedMessage{
+		Type:            msg.MessageType,
+		Content:         msg.Content,
+		InteractiveData: msg.InteractiveData,
+		TemplateName:    msg.TemplateName,
+	})
+}
+
+func (s *flowSimulation) recordCondition(c SimulatedCondition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conditions = append(s.conditions, c)
+}
+
+func (s *flowSimulation) recordTransfer(t SimulatedTransfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers = append(s.transfers, t)
+}
+
+// loadFlow returns the draft of a flow the simulated flow calls or goes to
+func (s *flowSimulation) loadFlow(db *gorm.DB, orgID, flowID uuid.UUID) (*models.ChatbotFlow, error) {
+	if flowID == s.flow.ID {
+		return s.flow, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if flow, ok := s.flows[flowID]; ok {
+		return flow, nil
+	}
+	flow, err := loadFlowDraft(db, orgID, flowID)
+	if err != nil {
+		return nil, err
+	}
+	flow.PublishedVersionID = nil
+	if s.flows == nil {
+		s.flows = make(map[uuid.UUID]*models.ChatbotFlow)
+	}
+	s.flows[flowID] = flow
+	return flow, nil
+}
+
+// takeTurn moves everything recorded since the last call into turn
+func (s *flowSimulation) takeTurn(turn *SimulatedTurn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn.Messages = append([]SimulatedMessage{}, s.messages...)
+	turn.Conditions = append([]SimulatedCondition{}, s.conditions...)
+	turn.APICalls = append([]SimulatedAPICall{}, s.apiCalls...)
+	turn.Transfers = append([]SimulatedTransfer{}, s.transfers...)
+	s.messages, s.conditions, s.apiCalls, s.transfers = nil, nil, nil, nil
+}
+
+// RoundTrip answers external API calls from the mocks and records them.
+// Calls without a matching mock fail with 502 so fallbacks can be tested.
+func (s *flowSimulation) RoundTrip(req *http.Request) (*http.Response, error) {
+	call := SimulatedAPICall{Method: req.Method, URL: req.URL.String(), Status: http.StatusBadGateway}
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		call.Body = string(body)
+	}
+
+	body := `{"error":"no mock matches this request"}`
+	if mock := s.matchMock(req.Method, call.URL); mock != nil {
+		call.Mocked = true
+		call.Status = http.StatusOK
+		if mock.Status != 0 {
+			call.Status = mock.Status
+		}
+		switch b := mock.Body.(type) {
+		case nil:
+			body = ""
+		case string:
+			body = b
+		default:
+			encoded, _ := json.Marshal(b)
+			body = string(encoded)
+		}
+	}
+
+	s.mu.Lock()
+	s.apiCalls = append(s.apiCalls, call)
+	s.mu.Unlock()
+
+	return simulatedResponse(req, call.Status, body), nil
+}
+
+func (s *flowSimulation) matchMock(method, url string) *SimulatorAPIMock {
+	for i, mock := range s.mocks {
+		if mock.Method != "" && !strings.EqualFold(mock.Method, method) {
+			continue
+		}
+		if mock.URL != "" && strings.HasPrefix(url, mock.URL) {
+			return &s.mocks[i]
+		}
+	}
+	return nil
+}
+
+// simulatedWhatsApp accepts every message sent to the WhatsApp Cloud API
+type simulatedWhatsApp struct {
+	sim *flowSimulation
+}
+
+func (w simulatedWhatsApp) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	w.sim.mu.Lock()
+	w.sim.sent++
+	id := fmt.Sprintf("wamid.simulated_%d", w.sim.sent)
+	w.sim.mu.Unlock()
+	return simulatedResponse(req, http.StatusOK, `{"messaging_product":"whatsapp","messages":[{"id":"`+id+`"}]}`), nil
+}
+
+func simulatedResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// recordCondition notes an evaluated condition when running in the simulator
+func (a *App) recordCondition(c SimulatedCondition) {
+	if a.simulation != nil {
+		a.simulation.recordCondition(c)
+	}
+}
+
+// simulateFlow runs a flow against a virtual contact inside a transaction
+// that is rolled back, so the simulation leaves no trace
+func (a *App) simulateFlow(orgID, flowID uuid.UUID, req SimulateFlowRequest) (*SimulationResult, error) {
+	tx := a.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	var flow models.ChatbotFlow
+	if err := tx.Where("id = ? AND organization_id = ?", flowID, orgID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		First(&flow).Error; err != nil {
+		return nil, err
+	}
+	// The simulator runs the draft, which no session is pinned to
+	flow.PublishedVersionID = nil
+
+	sim := &flowSimulation{flow: &flow, mocks: req.APIMocks}
+	waClient := whatsapp.New(a.Log)
+	waClient.HTTPClient = &http.Client{Transport: simulatedWhatsApp{sim: sim}}
+	app := &App{
+		Config:     a.Config,
+		DB:         tx,
+		Log:        a.Log,
+		WhatsApp:   waClient,
+		HTTPClient: &http.Client{Transport: sim},
+		simulation: sim,
+	}
+
+	// The account is never used to reach WhatsApp
+	account := &models.WhatsAppAccount{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		Name:           flow.WhatsAppAccount,
+		PhoneID:        "simulator",
+	}
+
+	contact, err := app.simulatorContact(orgID, account.Name, req.Contact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create virtual contact: %w", err)
+	}
+	session := &models.ChatbotSession{
+		OrganizationID:  orgID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+		SessionData:     models.JSONB{},
+		LastActivityAt:  time.Now(),
+	}
+	if err := tx.Create(session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create virtual session: %w", err)
+	}
+
+	turns := req.Turns
+	if len(turns) == 0 {
+		turns = []SimulatorTurn{{}}
+	}
+
+	result := &SimulationResult{Turns: []SimulatedTurn{}, Failures: []string{}}
+	for i := range turns {
+		turn := &turns[i]
+		out := SimulatedTurn{Input: turn}
+
+		if i == 0 {
+			app.startFlow(account, session, contact, &flow)
+		} else {
+			if err := tx.First(session, session.ID).Error; err != nil {
+				return nil, err
+			}
+			if session.Status != models.SessionStatusActive || session.CurrentFlowID == nil || session.CurrentStep == "" {
+				result.Failures = append(result.Failures, fmt.Sprintf("turn %d: the flow has already ended", i+1))
+				break
+			}
+			app.simulateTurn(account, session, contact, &flow, turn)
+		}
+
+		if err := tx.First(session, session.ID).Error; err != nil {
+			return nil, err
+		}
+		sim.takeTurn(&out)
+		out.CurrentStep = session.CurrentStep
+		out.Status = session.Status
+		out.SessionData = session.SessionData
+		out.Failures = checkTurnExpectation(turn.Expect, &out)
+		for _, failure := range out.Failures {
+			result.Failures = append(result.Failures, fmt.Sprintf("turn %d: %s", i+1, failure))
+		}
+		result.Turns = append(result.Turns, out)
+	}
+	result.Passed = len(result.Failures) == 0
+
+	return result, nil
+}
+
+// simulatorContact creates the virtual contact in the simulation's
+// transaction, or updates the existing contact with the same phone number
+func (a *App) simulatorContact(orgID uuid.UUID, accountName string, c SimulatorContact) (*models.Contact, error) {
+	phone := strings.TrimSpace(c.PhoneNumber)
+	if phone == "" {
+		phone = "simulator-" + uuid.NewString()[:8]
+	}
+	name := c.ProfileName
+	if name == "" {
+		name = "Simulator"
+	}
+
+	var contact models.Contact
+	err := a.DB.Where("organization_id = ? AND phone_number = ?", orgID, phone).First(&contact).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		updates := map[string]interface{}{}
+		if c.ProfileName != "" {
+			updates["profile_name"] = c.ProfileName
+		}
+		if c.Attributes != nil {
+			updates["attributes"] = models.JSONB(c.Attributes)
+		}
+		if len(updates) > 0 {
+			if err := a.DB.Model(&contact).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+		}
+		return &contact, nil
+	}
+
+	contact = models.Contact{
+		OrganizationID:  orgID,
+		PhoneNumber:     phone,
+		ProfileName:     name,
+		WhatsAppAccount: accountName,
+		Attributes:      models.JSONB(c.Attributes),
+	}
+	if contact.Attributes == nil {
+		contact.Attributes = models.JSONB{}
+	}
+	if err := a.DB.Create(&contact).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// simulateTurn delivers one message from the virtual contact to the flow
+func (a *App) simulateTurn(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, flow *models.ChatbotFlow, turn *SimulatorTurn) {
+	text := turn.Text
+	var media *flowMedia
+	switch {
+	case turn.Location != nil:
+		// A shared location reaches the flow as its JSON
+		encoded, _ := json.Marshal(turn.Location)
+		text = string(encoded)
+		media = &flowMedia{Type: models.InputTypeLocation, Location: turn.Location}
+	case turn.Media != nil:
+		media = &flowMedia{
+			Type:     turn.Media.Type,
+			MediaURL: turn.Media.MediaURL,
+			MimeType: turn.Media.MimeType,
+			Filename: turn.Media.Filename,
+			Caption:  turn.Text,
+			Size:     turn.Media.SizeBytes,
+		}
+	}
+
+	// A tapped button reaches the flow with its title as the text
+	if turn.ButtonID != "" && text == "" {
+		// The session may be in a subflow by now
+		if current, err := a.getSessionFlow(account.OrganizationID, session); err == nil {
+			flow = current
+		}
+		text = buttonTitle(flow, session.CurrentStep, turn.ButtonID)
+	}
+
+	a.logSessionMessage(session.ID, models.DirectionIncoming, text, session.CurrentStep)
+	a.processFlowResponse(account, session, contact, text, turn.ButtonID, turn.FlowResponse, media)
+}
+
+// buttonTitle finds the title of a button of the named step
+func buttonTitle(flow *models.ChatbotFlow, stepName, buttonID string) string {
+	for _, step := range flow.Steps {
+		if step.StepName != stepName {
+			continue
+		}
+		for i, btn := range step.Buttons {
+			btnMap, ok := btn.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := btnMap["id"].(string)
+			if id == "" {
+				id = fmt.Sprintf("btn_%d", i+1)
+			}
+			if id == buttonID {
+				title, _ := btnMap["title"].(string)
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+// checkTurnExpectation compares a turn's outcome with what a test case
+// expects. Expected messages must appear in order, each matching part of a
+// message's text; session values are compared by their text form.
+func checkTurnExpectation(expect *TurnExpectation, turn *SimulatedTurn) []string {
+	if expect == nil {
+		return nil
+	}
+	var failures []string
+
+	if expect.Step != nil && *expect.Step != turn.CurrentStep {
+		failures = append(failures, fmt.Sprintf("expected step %q, got %q", *expect.Step, turn.CurrentStep))
+	}
+	if expect.Status != "" && expect.Status != turn.Status {
+		failures = append(failures, fmt.Sprintf("expected status %q, got %q", expect.Status, turn.Status))
+	}
+
+	next := 0
+	for _, want := range expect.Messages {
+		found := false
+		for next < len(turn.Messages) {
+			msg := turn.Messages[next]
+			next++
+			if strings.Contains(msg.Content, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("expected a message containing %q", want))
+			break
+		}
+	}
+
+	keys := make([]string, 0, len(expect.SessionData))
+	for key := range expect.SessionData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		want := expect.SessionData[key]
+		got, ok := turn.SessionData[key]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("expected session_data.%s to be %v, but it is not set", key, want))
+		} else if fmt.Sprint(got) != fmt.Sprint(want) {
+			failures = append(failures, fmt.Sprintf("expected session_data.%s to be %v, got %v", key, want, got))
+		}
+	}
+
+	if expect.Transferred != nil && *expect.Transferred != (len(turn.Transfers) > 0) {
+		if *expect.Transferred {
+			failures = append(failures, "expected a transfer to agents")
+		} else {
+			failures = append(failures, "expected no transfer to agents")
+		}
+	}
+	return failures
+}
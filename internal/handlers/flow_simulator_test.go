@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTurnExpectation(t *testing.T) {
+	step := "ask_email"
+	transferred := true
+	turn := &SimulatedTurn{
+		Messages: []SimulatedMessage{
+			{Type: models.MessageTypeText, Content: "Thanks, Asha!"},
+			{Type: models.MessageTypeText, Content: "What is your email?"},
+		},
+		CurrentStep: "ask_email",
+		Status:      models.SessionStatusActive,
+		SessionData: models.JSONB{"name": "Asha", "quantity": 4.0},
+	}
+
+	assert.Empty(t, checkTurnExpectation(nil, turn))
+	assert.Empty(t, checkTurnExpectation(&TurnExpectation{
+		Step:        &step,
+		Status:      models.SessionStatusActive,
+		Messages:    []string{"Asha", "email"},
+		SessionData: map[string]interface{}{"name": "Asha", "quantity": 4.0},
+	}, turn))
+
+	failures := checkTurnExpectation(&TurnExpectation{
+		Status:      models.SessionStatusCompleted,
+		Messages:    []string{"email", "Asha"}, // Out of order
+		SessionData: map[string]interface{}{"quantity": "5", "email": "asha@example.com"},
+		Transferred: &transferred,
+	}, turn)
+	assert.Equal(t, []string{
+		`expected status "completed", got "active"`,
+		`expected a message containing "Asha"`,
+		"expected session_data.email to be asha@example.com, but it is not set",
+		"expected session_data.quantity to be 5, got 4",
+		"expected a transfer to agents",
+	}, failures)
+}
+
+func TestFlowSimulation_RoundTrip(t *testing.T) {
+	sim := &flowSimulation{mocks: []SimulatorAPIMock{
+		{Method: "POST", URL: "https://api.example.com/orders", Status: 201, Body: map[string]interface{}{"id": "ORD-1"}},
+		{URL: "https://api.example.com/orders", Body: `{"status":"shipped"}`},
+	}}
+	client := &http.Client{Transport: sim}
+
+	resp, err := client.Post("https://api.example.com/orders", "application/json", strings.NewReader(`{"qty":2}`))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.JSONEq(t, `{"id":"ORD-1"}`, string(body))
+
+	resp, err = client.Get("https://api.example.com/orders/ORD-1")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, `{"status":"shipped"}`, string(body))
+
+	// Unmocked calls fail so fallbacks run
+	resp, err = client.Get("https://other.example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+	var turn SimulatedTurn
+	sim.takeTurn(&turn)
+	assert.Equal(t, []SimulatedAPICall{
+		{Method: "POST", URL: "https://api.example.com/orders", Body: `{"qty":2}`, Status: 201, Mocked: true},
+		{Method: "GET", URL: "https://api.example.com/orders/ORD-1", Status: 200, Mocked: true},
+		{Method: "GET", URL: "https://other.example.com/", Status: http.StatusBadGateway},
+	}, turn.APICalls)
+}
+
+func TestSimulateFlow(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:         models.BaseModel{ID: flowID},
+		OrganizationID:    org.ID,
+		WhatsAppAccount:   account.Name,
+		Name:              "Order Status",
+		InitialMessage:    "Hi! Let's find your order.",
+		CompletionMessage: "Done, {{name}}.",
+		Steps: []models.ChatbotFlowStep{
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 1,
+				StepName: "ask_name", Message: "What is your name?", MessageType: models.FlowStepTypeText,
+				InputType: models.InputTypeText, StoreAs: "name",
+			},
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 2,
+				StepName: "menu", Message: "How can we help?", MessageType: models.FlowStepTypeButtons,
+				InputType: models.InputTypeButton, StoreAs: "choice",
+				Buttons:         models.JSONBArray{map[string]interface{}{"id": "track", "title": "Track order"}, map[string]interface{}{"id": "agent", "title": "Talk to us"}},
+				ConditionalNext: models.JSONB{"track": "lookup", "agent": "handoff"},
+			},
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 3,
+				StepName: "lookup", Message: "Your order is {{status}}.", MessageType: models.FlowStepTypeAPIFetch,
+				InputType: models.InputTypeNone,
+				ApiConfig: models.JSONB{"url": "https://api.example.com/orders", "response_mapping": map[string]interface{}{"status": "status"}},
+			},
+			{
+				BaseModel: models.BaseModel{ID: uuid.New()}, FlowID: flowID, StepOrder: 4,
+				StepName: "handoff", Message: "Connecting you to an agent.", MessageType: models.FlowStepTypeTransfer,
+				SkipCondition: "choice == 'track'",
+			},
+		},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+	// Disabled flows can still be simulated
+	require.NoError(t, app.DB.Model(flow).Update("is_enabled", false).Error)
+
+	askName, menu, ended := "ask_name", "menu", ""
+	result, err := app.simulateFlow(org.ID, flowID, SimulateFlowRequest{
+		Turns: []SimulatorTurn{
+			{Text: "order status", Expect: &TurnExpectation{Step: &askName, Messages: []string{"find your order", "name"}}},
+			{Text: "Asha", Expect: &TurnExpectation{Step: &menu, SessionData: map[string]interface{}{"name": "Asha"}}},
+			{ButtonID: "track", Expect: &TurnExpectation{Step: &ended, Status: models.SessionStatusCompleted, Messages: []string{"shipped", "Done, Asha."}}},
+		},
+		APIMocks: []SimulatorAPIMock{{URL: "https://api.example.com/orders", Body: map[string]interface{}{"status": "shipped"}}},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Passed, result.Failures)
+	require.Len(t, result.Turns, 3)
+
+	last := result.Turns[2]
+	assert.Equal(t, "Track order", last.SessionData["choice_title"])
+	require.Len(t, last.APICalls, 1)
+	assert.True(t, last.APICalls[0].Mocked)
+	assert.Contains(t, last.Conditions, SimulatedCondition{Step: "menu", Kind: "conditional_next", Expression: "track", Result: true, NextStep: "lookup"})
+	assert.Empty(t, last.Transfers)
+
+	// Asking for an agent transfers without creating a transfer
+	result, err = app.simulateFlow(org.ID, flowID, SimulateFlowRequest{
+		Turns: []SimulatorTurn{{}, {Text: "Asha"}, {Text: "talk to us"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Turns, 3)
+	assert.Len(t, result.Turns[2].Transfers, 1)
+	assert.Contains(t, result.Turns[2].Conditions, SimulatedCondition{Step: "handoff", Kind: "skip_condition", Expression: "choice == 'track'", Result: false})
+
+	// A turn after the flow ended fails the run
+	result, err = app.simulateFlow(org.ID, flowID, SimulateFlowRequest{
+		Turns: []SimulatorTurn{{}, {Text: "Asha"}, {Text: "talk to us"}, {Text: "hello?"}},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.Equal(t, []string{"turn 4: the flow has already ended"}, result.Failures)
+
+	// Nothing was saved
+	var count int64
+	app.DB.Model(&models.Message{}).Where("organization_id = ?", org.ID).Count(&count)
+	assert.Zero(t, count)
+	app.DB.Model(&models.ChatbotSession{}).Where("organization_id = ?", org.ID).Count(&count)
+	assert.Zero(t, count)
+	app.DB.Model(&models.AgentTransfer{}).Where("organization_id = ?", org.ID).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// maxICalSize caps the size of an imported iCalendar file
+const maxICalSize = 5 << 20
+
+// HolidayCalendarRequest represents the request body for creating/updating a
+// holiday calendar
+type HolidayCalendarRequest struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	WhatsAppAccount string `json:"whatsapp_account"` // Empty applies to all accounts
+	IsActive        *bool  `json:"is_active"`
+}
+
+// HolidayRequest represents the request body for adding a holiday
+type HolidayRequest struct {
+	Name      string `json:"name"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"` // Defaults to start_date
+}
+
+// HolidayCalendarResponse represents a holiday calendar in API responses
+type HolidayCalendarResponse struct {
+	ID              uuid.UUID        `json:"id"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	WhatsAppAccount string           `json:"whatsapp_account"`
+	IsActive        bool             `json:"is_active"`
+	HolidayCount    int64            `json:"holiday_count"`
+	Holidays        []models.Holiday `json:"holidays,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// ListHolidayCalendars returns the organization's holiday calendars
+func (a *App) ListHolidayCalendars(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	var calendars []models.HolidayCalendar
+	if err := a.DB.Where("organization_id = ?", orgID).Order("name ASC").Find(&calendars).Error; err != nil {
+		a.Log.Error("Failed to list holiday calendars", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list holiday calendars", nil, "")
+	}
+
+	type holidayCount struct {
+		CalendarID uuid.UUID `gorm:"column:calendar_id"`
+		Count      int64     `gorm:"column:count"`
+	}
+	var counts []holidayCount
+	a.DB.Model(&models.Holiday{}).
+		Select("calendar_id, COUNT(*) as count").
+		Where("organization_id = ?", orgID).
+		Group("calendar_id").
+		Scan(&counts)
+	countMap := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		countMap[c.CalendarID] = c.Count
+	}
+
+	response := make([]HolidayCalendarResponse, len(calendars))
+	for i, cal := range calendars {
+		response[i] = buildHolidayCalendarResponse(&cal, countMap[cal.ID], nil)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"calendars": response,
+	})
+}
+
+// GetHolidayCalendar returns a holiday calendar with its holidays
+func (a *App) GetHolidayCalendar(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	calendar, err := findByIDAndOrg[models.HolidayCalendar](a.DB, r, id, orgID, "Holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	var holidays []models.Holiday
+	if err := a.DB.Where("calendar_id = ?", id).Order("start_date ASC").Find(&holidays).Error; err != nil {
+		a.Log.Error("Failed to load holidays", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load holidays", nil, "")
+	}
+
+	return r.SendEnvelope(buildHolidayCalendarResponse(calendar, int64(len(holidays)), holidays))
+}
+
+// CreateHolidayCalendar creates a holiday calendar
+func (a *App) CreateHolidayCalendar(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req HolidayCalendarRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	calendar := models.HolidayCalendar{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		IsActive:       true,
+	}
+	if msg := a.applyHolidayCalendarRequest(orgID, &calendar, &req); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	if err := a.DB.Create(&calendar).Error; err != nil {
+		a.Log.Error("Failed to create holiday calendar", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create holiday calendar", nil, "")
+	}
+
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+	return r.SendEnvelope(buildHolidayCalendarResponse(&calendar, 0, nil))
+}
+
+// UpdateHolidayCalendar updates a holiday calendar
+func (a *App) UpdateHolidayCalendar(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	var req HolidayCalendarRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	calendar, err := findByIDAndOrg[models.HolidayCalendar](a.DB, r, id, orgID, "Holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	if msg := a.applyHolidayCalendarRequest(orgID, calendar, &req); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	if err := a.DB.Model(calendar).Select("name", "description", "whats_app_account", "is_active").Updates(calendar).Error; err != nil {
+		a.Log.Error("Failed to update holiday calendar", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update holiday calendar", nil, "")
+	}
+
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
+	var count int64
+	a.DB.Model(&models.Holiday{}).Where("calendar_id = ?", id).Count(&count)
+
+	return r.SendEnvelope(buildHolidayCalendarResponse(calendar, count, nil))
+}
+
+// DeleteHolidayCalendar deletes a holiday calendar and its holidays
+func (a *App) DeleteHolidayCalendar(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	calendar, err := findByIDAndOrg[models.HolidayCalendar](a.DB, r, id, orgID, "Holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", id).Delete(&models.Holiday{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(calendar).Error
+	}); err != nil {
+		a.Log.Error("Failed to delete holiday calendar", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete holiday calendar", nil, "")
+	}
+
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
+	return r.SendEnvelope(map[string]string{"message": "Holiday calendar deleted"})
+}
+
+// CreateHoliday adds a holiday to a calendar
+func (a *App) CreateHoliday(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	var req HolidayRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.HolidayCalendar](a.DB, r, id, orgID, "Holiday calendar"); err != nil {
+		return nil
+	}
+
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+	if !bizhours.ValidDate(req.StartDate) || !bizhours.ValidDate(req.EndDate) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Dates must be in YYYY-MM-DD format", nil, "")
+	}
+	if req.EndDate < req.StartDate {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "end_date cannot be before start_date", nil, "")
+	}
+
+	holiday := models.Holiday{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		CalendarID:     id,
+		Name:           strings.TrimSpace(req.Name),
+		StartDate:      req.StartDate,
+		EndDate:        req.EndDate,
+	}
+	if err := a.DB.Create(&holiday).Error; err != nil {
+		a.Log.Error("Failed to create holiday", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create holiday", nil, "")
+	}
+
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
+	return r.SendEnvelope(holiday)
+}
+
+// DeleteHoliday removes a holiday from a calendar
+func (a *App) DeleteHoliday(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	calendarID, err := parsePathUUID(r, "id", "holiday calendar")
+	if err != nil {
+		return nil
+	}
+	holidayID, err := parsePathUUID(r, "holiday_id", "holiday")
+	if err != nil {
+		return nil
+	}
+
+	result := a.DB.Where("id = ? AND calendar_id = ? AND organization_id = ?", holidayID, calendarID, orgID).Delete(&models.Holiday{})
+	if result.Error != nil {
+		a.Log.Error("Failed to delete holiday", "error", result.Error)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete holiday", nil, "")
+	}
+	if result.RowsAffected == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Holiday not found", nil, "")
+	}
+
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
+	return r.SendEnvelope(map[string]string{"message": "Holiday deleted"})
+}
+
+// ImportHolidayCalendar imports the events of an iCalendar (.ics) file into a
+// calendar. The file is sent as multipart field "file" or as the raw request
+// body. Events already imported are matched by UID and updated; with
+// ?replace=true all existing holidays of the calendar are removed first.
+func (a *App) ImportHolidayCalendar(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "holiday calendar")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.HolidayCalendar](a.DB, r, id, orgID, "Holiday calendar"); err != nil {
+		return nil
+	}
+
+	data, err := readICalUpload(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	events, err := bizhours.ParseICal(bytes.NewReader(data))
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid iCalendar file", nil, "")
+	}
+
+	replace := string(r.RequestCtx.QueryArgs().Peek("replace")) == "true"
+
+	imported, updated := 0, 0
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Where("calendar_id = ?", id).Delete(&models.Holiday{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, ev := range events {
+			if ev.UID != "" {
+				var existing models.Holiday
+				if err := tx.Where("calendar_id = ? AND uid = ?", id, ev.UID).First(&existing).Error; err == nil {
+					if err := tx.Model(&existing).Updates(map[string]any{
+						"name":       ev.Name,
+						"start_date": ev.Start,
+						"end_date":   ev.End,
+					}).Error; err != nil {
+						return err
+					}
+					updated++
+					continue
+				}
+			}
+
+			if err := tx.Create(&models.Holiday{
+				BaseModel:      models.BaseModel{ID: uuid.New()},
+				OrganizationID: orgID,
+				CalendarID:     id,
+				Name:           ev.Name,
+				StartDate:      ev.Start,
+				EndDate:        ev.End,
+				UID:            ev.UID,
+			}).Error; err != nil {
+				return err
+			}
+			imported++
+		}
+		return nil
+	}); err != nil {
+		a.Log.Error("Failed to import holidays", "error", err, "calendar_id", id)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to import holidays", nil, "")
+	}
+
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
+	a.Log.Info("Holidays imported", "calendar_id", id, "imported", imported, "updated", updated)
+
+	return r.SendEnvelope(map[string]any{
+		"imported": imported,
+		"updated":  updated,
+	})
+}
+
+// GetBusinessHoursStatus reports whether an account is currently within
+// business hours and when it next opens
+func (a *App) GetBusinessHoursStatus(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	account := string(r.RequestCtx.QueryArgs().Peek("whatsapp_account"))
+	schedule, err := bizhours.ForAccount(a.DB, orgID, account)
+	if err != nil {
+		a.Log.Error("Failed to load business hours", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load business hours", nil, "")
+	}
+	if schedule == nil {
+		return r.SendEnvelope(map[string]any{
+			"enabled": false,
+			"is_open": true,
+		})
+	}
+
+	now := time.Now()
+	resp := map[string]any{
+		"enabled":    true,
+		"timezone":   schedule.Location.String(),
+		"local_time": now.In(schedule.Location).Format(time.RFC3339),
+		"is_open":    schedule.IsOpen(now),
+	}
+	if next := schedule.NextOpen(now); !next.IsZero() {
+		resp["next_open"] = next.In(schedule.Location).Format(time.RFC3339)
+	}
+	if closure, ok := schedule.ClosureOn(now); ok {
+		resp["holiday"] = closure.Name
+	}
+	return r.SendEnvelope(resp)
+}
+
+// applyHolidayCalendarRequest validates the request and copies it onto the
+// calendar. It returns an error message for invalid input.
+func (a *App) applyHolidayCalendarRequest(orgID uuid.UUID, calendar *models.HolidayCalendar, req *HolidayCalendarRequest) string {
+	calendar.Name = strings.TrimSpace(req.Name)
+	if calendar.Name == "" {
+		return "Name is required"
+	}
+	calendar.Description = req.Description
+
+	calendar.WhatsAppAccount = strings.TrimSpace(req.WhatsAppAccount)
+	if calendar.WhatsAppAccount != "" {
+		var count int64
+		a.DB.Model(&models.WhatsAppAccount{}).Where("organization_id = ? AND name = ?", orgID, calendar.WhatsAppAccount).Count(&count)
+		if count == 0 {
+			return "WhatsApp account not found"
+		}
+	}
+
+	if req.IsActive != nil {
+		calendar.IsActive = *req.IsActive
+	}
+	return ""
+}
+
+// readICalUpload returns the iCalendar file from a multipart "file" field or
+// the raw request body
+func readICalUpload(r *fastglue.Request) ([]byte, error) {
+	if fileHeader, err := r.RequestCtx.FormFile("file"); err == nil {
+		if fileHeader.Size > maxICalSize {
+			return nil, errors.New("File too large")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, errors.New("Failed to open file")
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxICalSize))
+		if err != nil {
+			return nil, errors.New("Failed to read file")
+		}
+		return data, nil
+	}
+
+	body := r.RequestCtx.PostBody()
+	if len(body) == 0 {
+		return nil, errors.New("Missing file")
+	}
+	if len(body) > maxICalSize {
+		return nil, errors.New("File too large")
+	}
+	return body, nil
+}
+
+func buildHolidayCalendarResponse(calendar *models.HolidayCalendar, count int64, holidays []models.Holiday) HolidayCalendarResponse {
+	return HolidayCalendarResponse{
+		ID:              calendar.ID,
+		Name:            calendar.Name,
+		Description:     calendar.Description,
+		WhatsAppAccount: calendar.WhatsAppAccount,
+		IsActive:        calendar.IsActive,
+		HolidayCount:    count,
+		Holidays:        holidays,
+		CreatedAt:       calendar.CreatedAt,
+		UpdatedAt:       calendar.UpdatedAt,
+	}
+}
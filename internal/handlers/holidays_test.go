@@ -0,0 +1,123 @@
+package handlers_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func createTestHolidayCalendar(t *testing.T, app *handlers.App, orgID, userID uuid.UUID) handlers.HolidayCalendarResponse {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, map[string]any{"name": "Public holidays"})
+	testutil.SetAuthContext(req, orgID, userID)
+	require.NoError(t, app.CreateHolidayCalendar(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var calendar handlers.HolidayCalendarResponse
+	testutil.ParseEnvelopeResponse(t, req, &calendar)
+	return calendar
+}
+
+func TestApp_CreateHoliday_ValidatesDates(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	calendar := createTestHolidayCalendar(t, app, org.ID, admin.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"name": "Bad", "start_date": "01/05/2027"})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", calendar.ID.String())
+	require.NoError(t, app.CreateHoliday(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "YYYY-MM-DD")
+
+	req = testutil.NewJSONRequest(t, map[string]any{"name": "Backwards", "start_date": "2027-05-02", "end_date": "2027-05-01"})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", calendar.ID.String())
+	require.NoError(t, app.CreateHoliday(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "end_date")
+
+	req = testutil.NewJSONRequest(t, map[string]any{"name": "Labour Day", "start_date": "2027-05-01"})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", calendar.ID.String())
+	require.NoError(t, app.CreateHoliday(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var holiday models.Holiday
+	require.NoError(t, app.DB.Where("calendar_id = ?", calendar.ID).First(&holiday).Error)
+	assert.Equal(t, "2027-05-01", holiday.EndDate)
+}
+
+func TestApp_ImportHolidayCalendar_UpsertsByUID(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	calendar := createTestHolidayCalendar(t, app, org.ID, admin.ID)
+
+	ics := func(summary string) string {
+		return strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"BEGIN:VEVENT",
+			"UID:new-year",
+			"DTSTART;VALUE=DATE:20270101",
+			"SUMMARY:" + summary,
+			"END:VEVENT",
+			"END:VCALENDAR",
+		}, "\r\n")
+	}
+
+	importICal := func(body string) map[string]int {
+		req := testutil.NewRequest(t)
+		req.RequestCtx.Request.Header.SetMethod("POST")
+		req.RequestCtx.Request.Header.SetContentType("text/calendar")
+		req.RequestCtx.Request.SetBodyString(body)
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", calendar.ID.String())
+		require.NoError(t, app.ImportHolidayCalendar(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp map[string]int
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		return resp
+	}
+
+	assert.Equal(t, map[string]int{"imported": 1, "updated": 0}, importICal(ics("New Year")))
+	assert.Equal(t, map[string]int{"imported": 0, "updated": 1}, importICal(ics("New Year's Day")))
+
+	var holidays []models.Holiday
+	require.NoError(t, app.DB.Where("calendar_id = ?", calendar.ID).Find(&holidays).Error)
+	require.Len(t, holidays, 1)
+	assert.Equal(t, "New Year's Day", holidays[0].Name)
+	assert.Equal(t, "2027-01-01", holidays[0].StartDate)
+}
+
+func TestApp_ImportHolidayCalendar_RejectsInvalidFile(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	calendar := createTestHolidayCalendar(t, app, org.ID, admin.ID)
+
+	req := testutil.NewRequest(t)
+	req.RequestCtx.Request.Header.SetMethod("POST")
+	req.RequestCtx.Request.SetBodyString("not a calendar")
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", calendar.ID.String())
+	require.NoError(t, app.ImportHolidayCalendar(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid iCalendar file")
+}
@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/segmentutil"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 	"gorm.io/gorm"
@@ -24,6 +26,7 @@ type ExportConfig struct {
 	DefaultColumns  []string
 	ColumnLabels    map[string]string // Column name -> CSV header label
 	ColumnTransform map[string]func(interface{}) string
+	AttributeColumn string // JSONB column holding org-defined attributes, exported as attr.<key> columns
 }
 
 // ImportConfig defines allowed tables and their importable columns
@@ -34,7 +37,9 @@ type ImportConfig struct {
 	OptionalColumns  []string
 	ColumnTransform  map[string]func(string) (interface{}, error)
 	UniqueColumn     string // Column to check for duplicates (e.g., "phone_number")
+	PrepareRecord    func(db *gorm.DB, orgID uuid.UUID, record map[string]interface{}) error // Runs before the duplicate check
 	BeforeCreate     func(db *gorm.DB, orgID uuid.UUID, record map[string]interface{}) error
+	AttributeColumn  string // JSONB column holding org-defined attributes, imported from attr.<key> columns
 }
 
 // Supported export/import configurations
@@ -104,6 +109,7 @@ var exportConfigs = map[string]ExportConfig{
 				return ""
 			},
 		},
+		AttributeColumn: "attributes",
 	},
 	"tags": {
 		Model:          &models.Tag{},
@@ -128,11 +134,8 @@ var importConfigs = map[string]ImportConfig{
 		UniqueColumn:    "phone_number",
 		ColumnTransform: map[string]func(string) (interface{}, error){
 			"phone_number": func(s string) (interface{}, error) {
-				// Normalize phone number - remove + prefix
+				// Region-aware normalization happens in PrepareRecord
 				phone := strings.TrimSpace(s)
-				if len(phone) > 0 && phone[0] == '+' {
-					phone = phone[1:]
-				}
 				if phone == "" {
 					return nil, fmt.Errorf("phone number is required")
 				}
@@ -153,6 +156,17 @@ var importConfigs = map[string]ImportConfig{
 				return tags, nil
 			},
 		},
+		PrepareRecord: func(db *gorm.DB, orgID uuid.UUID, record map[string]interface{}) error {
+			// Normalize to E.164 so "+91 98xxx", "9198xxx" and "098xxx" dedupe to one contact
+			phone, _ := record["phone_number"].(string)
+			normalized, err := contactutil.NormalizePhone(db, orgID, phone)
+			if err != nil {
+				return fmt.Errorf("invalid phone number '%s'", phone)
+			}
+			record["phone_number"] = normalized
+			return nil
+		},
+		AttributeColumn: "attributes",
 	},
 	"tags": {
 		Model:           &models.Tag{},
@@ -205,8 +219,22 @@ func (a *App) ExportData(r *fastglue.Request) error {
 	for _, col := range config.AllowedColumns {
 		allowedSet[col] = true
 	}
+	// Attribute columns are requested as attr.<key>
+	attrDefs, err := a.getAttributeColumnDefs(orgID, config.AttributeColumn)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to export data", nil, "")
+	}
+	requestedAttrs := make(map[string]bool)
 	requestedCols := make(map[string]bool, len(columns))
 	for _, col := range columns {
+		if key, ok := strings.CutPrefix(col, attributeColumnPrefix); ok && config.AttributeColumn != "" {
+			if !hasAttributeDef(attrDefs, key) {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Column '%s' is not allowed for export", col), nil, "")
+			}
+			requestedAttrs[key] = true
+			continue
+		}
 		if !allowedSet[col] {
 			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, fmt.Sprintf("Column '%s' is not allowed for export", col), nil, "")
 		}
@@ -245,6 +273,30 @@ func (a *App) ExportData(r *fastglue.Request) error {
 		}
 	}
 
+	// Restrict contacts to a saved segment's current members
+	if segmentID, ok := req.Filters["segment_id"]; ok && segmentID != "" && req.Table == "contacts" {
+		if !a.HasPermission(userID, models.ResourceSegments, models.ActionRead, orgID) {
+			return r.SendErrorEnvelope(fasthttp.StatusForbidden, "You do not have permission to view segments", nil, "")
+		}
+		id, err := uuid.Parse(segmentID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid segment ID", nil, "")
+		}
+		segment, err := findByIDAndOrg[models.Segment](a.DB, r, id, orgID, "Segment")
+		if err != nil {
+			return nil
+		}
+		rule, err := segmentutil.ParseRules(segment.Rules)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		sql, args, err := segmentutil.Compile(rule, attrDefs, time.Now())
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid segment rules: "+err.Error(), nil, "")
+		}
+		query = query.Where(sql, args...)
+	}
+
 	// Select only needed columns plus id for scoping.
 	// Build the list from server-controlled AllowedColumns to prevent SQL injection.
 	// This ensures only server-defined strings are passed to GORM, not user input.
@@ -254,7 +306,17 @@ func (a *App) ExportData(r *fastglue.Request) error {
 			safeColumns = append(safeColumns, col)
 		}
 	}
+	// Attribute columns in definition order
+	exportAttrs := make([]models.ContactAttribute, 0, len(requestedAttrs))
+	for _, d := range attrDefs {
+		if requestedAttrs[d.Key] {
+			exportAttrs = append(exportAttrs, d)
+		}
+	}
 	selectCols := append([]string{"id"}, safeColumns...)
+	if len(exportAttrs) > 0 {
+		selectCols = append(selectCols, config.AttributeColumn)
+	}
 	query = query.Select(selectCols)
 
 	// Execute query
@@ -273,7 +335,7 @@ func (a *App) ExportData(r *fastglue.Request) error {
 	writer := csv.NewWriter(&buf)
 
 	// Write header using safe (server-controlled) column names
-	header := make([]string, len(safeColumns))
+	header := make([]string, len(safeColumns), len(safeColumns)+len(exportAttrs))
 	for i, col := range safeColumns {
 		if label, ok := config.ColumnLabels[col]; ok {
 			header[i] = label
@@ -281,6 +343,9 @@ func (a *App) ExportData(r *fastglue.Request) error {
 			header[i] = col
 		}
 	}
+	for _, d := range exportAttrs {
+		header = append(header, d.Label)
+	}
 	_ = writer.Write(header)
 
 	// Write rows
@@ -297,7 +362,7 @@ func (a *App) ExportData(r *fastglue.Request) error {
 		}
 
 		// Convert to CSV row (skip id column which is at index 0)
-		csvRow := make([]string, len(safeColumns))
+		csvRow := make([]string, len(safeColumns), len(safeColumns)+len(exportAttrs))
 		for i, col := range safeColumns {
 			val := values[i+1] // +1 to skip id
 
@@ -308,6 +373,14 @@ func (a *App) ExportData(r *fastglue.Request) error {
 				csvRow[i] = formatExportValue(val, colTypes[i+1])
 			}
 		}
+
+		// Attributes column is last in selectCols
+		if len(exportAttrs) > 0 {
+			attrs := parseAttributesValue(values[len(values)-1])
+			for _, d := range exportAttrs {
+				csvRow = append(csvRow, contactutil.FormatAttributeValue(attrs[d.Key]))
+			}
+		}
 		_ = writer.Write(csvRow)
 	}
 
@@ -435,6 +508,29 @@ func (a *App) ImportData(r *fastglue.Request) error {
 		}
 	}
 
+	// Map attribute columns (attr.<key> or the attribute label) that aren't regular columns
+	attrDefs, err := a.getAttributeColumnDefs(orgID, config.AttributeColumn)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to import data", nil, "")
+	}
+	mappedIdx := make(map[int]bool, len(normalizedIndex))
+	for _, idx := range normalizedIndex {
+		mappedIdx[idx] = true
+	}
+	attrIndex := make(map[string]int)
+	for col, idx := range colIndex {
+		if mappedIdx[idx] {
+			continue
+		}
+		for _, d := range attrDefs {
+			if strings.EqualFold(col, attributeColumnPrefix+d.Key) || strings.EqualFold(col, d.Label) {
+				attrIndex[d.Key] = idx
+				break
+			}
+		}
+	}
+
 	// Process rows
 	var created, updated, skipped, errors int
 	var errorMessages []string
@@ -498,6 +594,33 @@ func (a *App) ImportData(r *fastglue.Request) error {
 			continue
 		}
 
+		// Validate attribute cells; empty cells are ignored rather than clearing values
+		attrValues := models.JSONB{}
+		if len(attrIndex) > 0 {
+			raw := make(map[string]interface{}, len(attrIndex))
+			for key, idx := range attrIndex {
+				if idx < len(record) && strings.TrimSpace(record[idx]) != "" {
+					raw[key] = record[idx]
+				}
+			}
+			values, err := contactutil.ValidateAttributes(attrDefs, raw, false)
+			if err != nil {
+				errors++
+				errorMessages = append(errorMessages, fmt.Sprintf("Row %d: %s", rowNum, err.Error()))
+				continue
+			}
+			attrValues = contactutil.ApplyAttributes(nil, values)
+		}
+
+		// Run PrepareRecord hook if defined (e.g. normalization affecting the unique column)
+		if config.PrepareRecord != nil {
+			if err := config.PrepareRecord(a.DB, orgID, recordMap); err != nil {
+				errors++
+				errorMessages = append(errorMessages, fmt.Sprintf("Row %d: %s", rowNum, err.Error()))
+				continue
+			}
+		}
+
 		// Check for duplicate based on unique column
 		if config.UniqueColumn != "" {
 			uniqueVal := recordMap[config.UniqueColumn]
@@ -514,6 +637,11 @@ func (a *App) ImportData(r *fastglue.Request) error {
 					// Update existing record
 					delete(recordMap, "organization_id")
 					delete(recordMap, config.UniqueColumn)
+					if len(attrValues) > 0 {
+						data, _ := json.Marshal(attrValues)
+						recordMap[config.AttributeColumn] = gorm.Expr(
+							fmt.Sprintf("COALESCE(%s, '{}'::jsonb) || ?::jsonb", config.AttributeColumn), string(data))
+					}
 					if len(recordMap) > 0 {
 						if err := a.DB.Model(existing).Updates(recordMap).Error; err != nil {
 							errors++
@@ -531,6 +659,23 @@ func (a *App) ImportData(r *fastglue.Request) error {
 			}
 		}
 
+		// New records must have every required attribute
+		if config.AttributeColumn != "" {
+			missing := ""
+			for _, d := range attrDefs {
+				if d.Required && attrValues[d.Key] == nil {
+					missing = d.Key
+					break
+				}
+			}
+			if missing != "" {
+				errors++
+				errorMessages = append(errorMessages, fmt.Sprintf("Row %d: missing required attribute '%s'", rowNum, missing))
+				continue
+			}
+			recordMap[config.AttributeColumn] = attrValues
+		}
+
 		// Run BeforeCreate hook if defined
 		if config.BeforeCreate != nil {
 			if err := config.BeforeCreate(a.DB, orgID, recordMap); err != nil {
@@ -618,6 +763,17 @@ func (a *App) GetExportConfig(r *fastglue.Request) error {
 		}
 	}
 
+	attrDefs, err := a.getAttributeColumnDefs(orgID, config.AttributeColumn)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+	}
+	for _, d := range attrDefs {
+		columns = append(columns, map[string]string{
+			"key":   attributeColumnPrefix + d.Key,
+			"label": d.Label,
+		})
+	}
+
 	return r.SendEnvelope(map[string]interface{}{
 		"table":           tableName,
 		"columns":         columns,
@@ -679,6 +835,17 @@ func (a *App) GetImportConfig(r *fastglue.Request) error {
 		}
 	}
 
+	attrDefs, err := a.getAttributeColumnDefs(orgID, config.AttributeColumn)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+	}
+	for _, d := range attrDefs {
+		optionalCols = append(optionalCols, map[string]string{
+			"key":   attributeColumnPrefix + d.Key,
+			"label": d.Label,
+		})
+	}
+
 	return r.SendEnvelope(map[string]interface{}{
 		"table":            tableName,
 		"required_columns": requiredCols,
@@ -687,6 +854,45 @@ func (a *App) GetImportConfig(r *fastglue.Request) error {
 	})
 }
 
+// attributeColumnPrefix marks export/import columns that map to org-defined attributes
+const attributeColumnPrefix = "attr."
+
+// getAttributeColumnDefs returns the organization's attribute definitions when the table has an attribute column
+func (a *App) getAttributeColumnDefs(orgID uuid.UUID, attributeColumn string) ([]models.ContactAttribute, error) {
+	if attributeColumn == "" {
+		return nil, nil
+	}
+	return a.getContactAttributesCached(orgID)
+}
+
+// hasAttributeDef reports whether defs contains an attribute with the given key
+func hasAttributeDef(defs []models.ContactAttribute, key string) bool {
+	for _, d := range defs {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAttributesValue decodes a scanned JSONB attributes column
+func parseAttributesValue(v interface{}) map[string]interface{} {
+	var data []byte
+	switch val := v.(type) {
+	case []byte:
+		data = val
+	case string:
+		data = []byte(val)
+	default:
+		return nil
+	}
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil
+	}
+	return attrs
+}
+
 // Helper function to convert snake_case to PascalCase
 // Handles common acronyms like ID, URL, API, etc.
 func snakeToPascal(s string) string {
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/exprutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/templateutil"
+)
+
+// Keyword rule response_content by response_type:
+//
+//	text:     {body, buttons}
+//	transfer: {body, required_skills}
+//	template: {template_id | template_name, params: {name: value}}
+//	media:    {media_type, media_id | media_path, mime_type, filename, caption}
+//	flow:     {whatsapp_flow_id, body, header, cta}
+//	script:   {chatbot_flow_id}
+
+// keywordMediaTypes are the media types a keyword rule can respond with
+var keywordMediaTypes = map[string]models.MessageType{
+	"image":    models.MessageTypeImage,
+	"video":    models.MessageTypeVideo,
+	"audio":    models.MessageTypeAudio,
+	"document": models.MessageTypeDocument,
+}
+
+// validateKeywordResponse checks that a keyword rule's response content has
+// the shape its response type needs
+func (a *App) validateKeywordResponse(orgID uuid.UUID, responseType models.ResponseType, content map[string]interface{}) error {
+	str := func(key string) string {
+		s, _ := content[key].(string)
+		return strings.TrimSpace(s)
+	}
+
+	switch responseType {
+	case models.ResponseTypeText, models.ResponseTypeTransfer:
+		return nil
+
+	case models.ResponseTypeTemplate:
+		if str("template_id") == "" && str("template_name") == "" {
+			return fmt.Errorf("template responses need a template_id or template_name")
+		}
+		if _, err := a.findKeywordTemplate(orgID, content); err != nil {
+			return err
+		}
+		if params, ok := content["params"]; ok {
+			paramMap, ok := params.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("params must be an object")
+			}
+			for name, value := range paramMap {
+				if _, ok := value.(string); !ok {
+					return fmt.Errorf("template parameter %q must be a string", name)
+				}
+			}
+		}
+		return nil
+
+	case models.ResponseTypeMedia:
+		if _, ok := keywordMediaTypes[str("media_type")]; !ok {
+			return fmt.Errorf("media_type must be image, video, audio or document")
+		}
+		mediaID, mediaPath := str("media_id"), str("media_path")
+		if (mediaID == "") == (mediaPath == "") {
+			return fmt.Errorf("media responses need either a media_id or a media_path")
+		}
+		if mediaPath != "" {
+			if strings.Contains(mediaPath, "..") || filepath.IsAbs(mediaPath) {
+				return fmt.Errorf("invalid media_path")
+			}
+			if _, err := os.Stat(filepath.Join(a.getMediaStoragePath(), mediaPath)); err != nil {
+				return fmt.Errorf("media file not found")
+			}
+		}
+		return nil
+
+	case models.ResponseTypeFlow:
+		if str("whatsapp_flow_id") == "" {
+			return fmt.Errorf("flow responses need a whatsapp_flow_id")
+		}
+		if str("body") == "" {
+			return fmt.Errorf("flow responses need a body")
+		}
+		if len(str("cta")) > 20 {
+			return fmt.Errorf("cta must be at most 20 characters")
+		}
+		return nil
+
+	case models.ResponseTypeScript:
+		flowID, err := uuid.Parse(str("chatbot_flow_id"))
+		if err != nil {
+			return fmt.Errorf("script responses need a valid chatbot_flow_id")
+		}
+		var count int64
+		a.DB.Model(&models.ChatbotFlow{}).Where("id = ? AND organization_id = ?", flowID, orgID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("chatbot flow not found")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("invalid response_type %q", responseType)
+}
+
+// findKeywordTemplate loads the template a keyword rule responds with
+func (a *App) findKeywordTemplate(orgID uuid.UUID, content map[string]interface{}) (*models.Template, error) {
+	var template models.Template
+	query := a.DB.Where("organization_id = ?", orgID)
+	if idStr, _ := content["template_id"].(string); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template_id")
+		}
+		query = query.Where("id = ?", id)
+	} else {
+		name, _ := content["template_name"].(string)
+		query = query.Where("name = ?", name)
+	}
+	if err := query.First(&template).Error; err != nil {
+		return nil, fmt.Errorf("template not found")
+	}
+	return &template, nil
+}
+
+// sendKeywordResponse sends a matched keyword rule's template, media or
+// WhatsApp Flow, or starts its chatbot flow. Returns the content to log to
+// the session, if any.
+func (a *App) sendKeywordResponse(account *models.WhatsAppAccount, session *models.ChatbotSession, contact *models.Contact, response *KeywordResponse) (string, error) {
+	content := response.Content
+	str := func(key string) string {
+		s, _ := content[key].(string)
+		return s
+	}
+
+	switch response.ResponseType {
+	case models.ResponseTypeTemplate:
+		template, err := a.findKeywordTemplate(account.OrganizationID, content)
+		if err != nil {
+			return "", err
+		}
+		if template.Status != string(models.TemplateStatusApproved) {
+			return "", fmt.Errorf("template %s is not approved", template.Name)
+		}
+		params := map[string]string{}
+		if raw, ok := content["params"].(map[string]interface{}); ok {
+			for name, value := range raw {
+				params[name] = exprutil.Format(value)
+			}
+		}
+		if len(contact.Attributes) > 0 {
+			params = contactutil.AttributeTemplateParams(contact.Attributes, params)
+		}
+		paramNames := templateutil.ExtParamNames(template.BodyContent)
+		bodyParams := templateutil.ResolveParamsFromMap(paramNames, params)
+		for i, name := range paramNames {
+			if i >= len(bodyParams) || bodyParams[i] == "" {
+				return "", fmt.Errorf("missing template parameter %s", name)
+			}
+		}
+		_, err = a.SendOutgoingMessage(context.Background(), OutgoingMessageRequest{
+			Account:    account,
+			Contact:    contact,
+			Type:       models.MessageTypeTemplate,
+			Template:   template,
+			BodyParams: params,
+		}, ChatbotSendOptions())
+		if err != nil {
+			return "", err
+		}
+		return templateutil.ReplaceWithStringParams(template.BodyContent, params), nil
+
+	case models.ResponseTypeMedia:
+		req := OutgoingMessageRequest{
+			Account:       account,
+			Contact:       contact,
+			Type:          keywordMediaTypes[str("media_type")],
+			MediaID:       str("media_id"),
+			MediaMimeType: str("mime_type"),
+			MediaFilename: str("filename"),
+			Caption:       str("caption"),
+		}
+		if req.Type == "" {
+			return "", fmt.Errorf("invalid media_type %q", str("media_type"))
+		}
+		if mediaPath := str("media_path"); mediaPath != "" {
+			if strings.Contains(mediaPath, "..") {
+				return "", fmt.Errorf("invalid media_path")
+			}
+			data, err := os.ReadFile(filepath.Join(a.getMediaStoragePath(), mediaPath))
+			if err != nil {
+				return "", fmt.Errorf("failed to read media file: %w", err)
+			}
+			req.MediaData = data
+			req.MediaURL = mediaPath
+			if req.MediaMimeType == "" {
+				req.MediaMimeType = getMimeTypeFromExtension(strings.ToLower(filepath.Ext(mediaPath)))
+			}
+			if req.MediaFilename == "" {
+				req.MediaFilename = filepath.Base(mediaPath)
+			}
+		}
+		if _, err := a.SendOutgoingMessage(context.Background(), req, ChatbotSendOptions()); err != nil {
+			return "", err
+		}
+		if req.Caption != "" {
+			return req.Caption, nil
+		}
+		return "[" + str("media_type") + "]", nil
+
+	case models.ResponseTypeFlow:
+		flowID := str("whatsapp_flow_id")
+		flowToken := fmt.Sprintf("keyword_%s_%d", contact.ID.String(), time.Now().UnixNano())
+		firstScreen := a.whatsAppFlowFirstScreen(account.OrganizationID, flowID)
+		if err := a.sendAndSaveFlowMessage(account, contact, flowID, str("header"), str("body"), str("cta"), flowToken, firstScreen); err != nil {
+			return "", err
+		}
+		return str("body"), nil
+
+	case models.ResponseTypeScript:
+		flowID, err := uuid.Parse(str("chatbot_flow_id"))
+		if err != nil {
+			return "", fmt.Errorf("invalid chatbot_flow_id")
+		}
+		// Only enabled flows are cached
+		flow, err := a.getChatbotFlowByIDCached(account.OrganizationID, flowID)
+		if err != nil {
+			return "", fmt.Errorf("chatbot flow not found or disabled")
+		}
+		a.startFlow(account, session, contact, flow)
+		return "", nil
+	}
+
+	return "", fmt.Errorf("unsupported response type %q", response.ResponseType)
+}
+
+// whatsAppFlowFirstScreen returns the first screen of a synced WhatsApp Flow,
+// or "" to let Meta use the flow's default
+func (a *App) whatsAppFlowFirstScreen(orgID uuid.UUID, metaFlowID string) string {
+	var waFlow models.WhatsAppFlow
+	if err := a.DB.Where("meta_flow_id = ? AND organization_id = ?", metaFlowID, orgID).First(&waFlow).Error; err != nil {
+		a.Log.Debug("Could not find WhatsApp Flow in database, using default screen", "meta_flow_id", metaFlowID)
+		return ""
+	}
+
+	// Extract first screen name from screens array
+	if len(waFlow.Screens) > 0 {
+		if screenMap, ok := waFlow.Screens[0].(map[string]interface{}); ok {
+			if screenID, ok := screenMap["id"].(string); ok {
+				return screenID
+			}
+		}
+	}
+	// If screens array is empty, try to get from flow_json
+	if waFlow.FlowJSON != nil {
+		if screens, ok := waFlow.FlowJSON["screens"].([]interface{}); ok && len(screens) > 0 {
+			if screenMap, ok := screens[0].(map[string]interface{}); ok {
+				if screenID, ok := screenMap["id"].(string); ok {
+					return screenID
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/config"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createKeywordTestSession(t *testing.T, app *App, orgID uuid.UUID, account *models.WhatsAppAccount, contact *models.Contact) *models.ChatbotSession {
+	t.Helper()
+	session := &models.ChatbotSession{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  orgID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.SessionStatusActive,
+		SessionData:     models.JSONB{},
+		StartedAt:       time.Now(),
+		LastActivityAt:  time.Now(),
+	}
+	require.NoError(t, app.DB.Create(session).Error)
+	return session
+}
+
+func TestMatchKeywordRules_MediaResponseWithoutBody(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+
+	rule := &models.KeywordRule{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: account.Name,
+		Name:            "brochure",
+		Keywords:        models.StringArray{"brochure"},
+		MatchType:       models.MatchTypeContains,
+		ResponseType:    models.ResponseTypeMedia,
+		ResponseContent: models.JSONB{"media_type": "document", "media_id": "123"},
+		IsEnabled:       true,
+	}
+	require.NoError(t, app.DB.Create(rule).Error)
+
+	resp, matched := app.matchKeywordRules(org.ID, account.Name, "send me the brochure", nil)
+	require.True(t, matched)
+	assert.Equal(t, models.ResponseTypeMedia, resp.ResponseType)
+	assert.Equal(t, "123", resp.Content["media_id"])
+}
+
+func TestSendKeywordResponse_MediaFromStoredFile(t *testing.T) {
+	app := newProcessorTestApp(t)
+	app.Config = &config.Config{}
+	app.Config.Storage.LocalPath = t.TempDir()
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	session := createKeywordTestSession(t, app, org.ID, account, contact)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(app.Config.Storage.LocalPath, "keywords"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(app.Config.Storage.LocalPath, "keywords", "brochure.pdf"), []byte("%PDF-1.4"), 0644))
+
+	logged, err := app.sendKeywordResponse(account, session, contact, &KeywordResponse{
+		ResponseType: models.ResponseTypeMedia,
+		Content: models.JSONB{
+			"media_type": "document",
+			"media_path": "keywords/brochure.pdf",
+			"caption":    "Our brochure",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Our brochure", logged)
+
+	var msg models.Message
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&msg).Error)
+	assert.Equal(t, models.MessageTypeDocument, msg.MessageType)
+	assert.Equal(t, "keywords/brochure.pdf", msg.MediaURL)
+	assert.Equal(t, "application/pdf", msg.MediaMimeType)
+	assert.Equal(t, "brochure.pdf", msg.MediaFilename)
+}
+
+func TestSendKeywordResponse_Template(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	session := createKeywordTestSession(t, app, org.ID, account, contact)
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	response := &KeywordResponse{
+		ResponseType: models.ResponseTypeTemplate,
+		Content:      models.JSONB{"template_name": template.Name},
+	}
+	_, err := app.sendKeywordResponse(account, session, contact, response)
+	assert.EqualError(t, err, "missing template parameter 1")
+
+	response.Content["params"] = map[string]interface{}{"1": "there"}
+	logged, err := app.sendKeywordResponse(account, session, contact, response)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there", logged)
+
+	var msg models.Message
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&msg).Error)
+	assert.Equal(t, models.MessageTypeTemplate, msg.MessageType)
+	assert.Equal(t, template.Name, msg.TemplateName)
+}
+
+func TestSendKeywordResponse_StartsChatbotFlow(t *testing.T) {
+	app := newProcessorTestApp(t)
+	if app.Redis == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping test")
+	}
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	session := createKeywordTestSession(t, app, org.ID, account, contact)
+
+	flowID := uuid.New()
+	flow := &models.ChatbotFlow{
+		BaseModel:      models.BaseModel{ID: flowID},
+		OrganizationID: org.ID,
+		Name:           "Apply",
+		IsEnabled:      true,
+		Steps: []models.ChatbotFlowStep{{
+			BaseModel:   models.BaseModel{ID: uuid.New()},
+			FlowID:      flowID,
+			StepName:    "ask_name",
+			StepOrder:   1,
+			Message:     "What is your name?",
+			MessageType: models.FlowStepTypeText,
+			InputType:   models.InputTypeText,
+			StoreAs:     "name",
+		}},
+	}
+	require.NoError(t, app.DB.Create(flow).Error)
+	app.InvalidateChatbotFlowsCache(org.ID)
+
+	_, err := app.sendKeywordResponse(account, session, contact, &KeywordResponse{
+		ResponseType: models.ResponseTypeScript,
+		Content:      models.JSONB{"chatbot_flow_id": flowID.String()},
+	})
+	require.NoError(t, err)
+
+	var dbSession models.ChatbotSession
+	require.NoError(t, app.DB.First(&dbSession, session.ID).Error)
+	require.NotNil(t, dbSession.CurrentFlowID)
+	assert.Equal(t, flowID, *dbSession.CurrentFlowID)
+	assert.Equal(t, "ask_name", dbSession.CurrentStep)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/shridarpatil/whatomate/internal/templateutil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
@@ -120,9 +121,24 @@ func SLASendOptions() MessageSendOptions {
 	}
 }
 
+// SurveySendOptions returns options suitable for satisfaction survey messages
+func SurveySendOptions() MessageSendOptions {
+	return MessageSendOptions{
+		BroadcastWebSocket: true,
+		DispatchWebhook:    false,
+		TrackSLA:           false,
+		Async:              true,
+	}
+}
+
 // SendOutgoingMessage is the unified method for sending all types of WhatsApp messages.
 // It handles: text, media (image/video/audio/document), interactive (buttons/list/cta_url), and template messages.
 func (a *App) SendOutgoingMessage(ctx context.Context, req OutgoingMessageRequest, opts MessageSendOptions) (*models.Message, error) {
+	// Business-initiated template messages must not reach opted-out contacts
+	if req.Type == models.MessageTypeTemplate && contactutil.IsOptedOut(req.Contact) {
+		return nil, ErrContactOptedOut
+	}
+
 	// 1. Create message record
 	msg := a.createOutgoingMessage(req, opts)
 
@@ -131,6 +147,9 @@ func (a *App) SendOutgoingMessage(ctx context.Context, req OutgoingMessageReques
 		a.Log.Error("Failed to create message", "error", err)
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
+	if a.simulation != nil {
+		a.simulation.recordMessage(msg)
+	}
 
 	// 2. Define the send function based on message type
 	sendFn := func(sendCtx context.Context) (string, error) {
@@ -560,24 +579,25 @@ func (a *App) SendTemplateMessage(r *fastglue.Request) error {
 		contact = c
 		phoneNumber = c.PhoneNumber
 	} else {
-		// Find or create contact from phone number
-		phoneNumber = req.PhoneNumber
-		var c models.Contact
-		err := a.DB.Where("phone_number = ? AND organization_id = ?", phoneNumber, orgID).First(&c).Error
+		// Find or create contact from the normalized phone number
+		normalized, err := contactutil.NormalizePhone(a.DB, orgID, req.PhoneNumber)
 		if err != nil {
-			// Contact not found, create new one
-			c = models.Contact{
-				BaseModel:      models.BaseModel{ID: uuid.New()},
-				OrganizationID: orgID,
-				PhoneNumber:    phoneNumber,
-			}
-			if err := a.DB.Create(&c).Error; err != nil {
-				a.Log.Error("Failed to create contact", "error", err, "phone", phoneNumber)
-				return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact", nil, "")
-			}
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid phone_number", nil, "")
+		}
+		phoneNumber = normalized
+		c, isNew, err := contactutil.GetOrCreateContact(a.DB, orgID, phoneNumber, "")
+		if err != nil {
+			a.Log.Error("Failed to create contact", "error", err, "phone", phoneNumber)
+			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create contact", nil, "")
+		}
+		if isNew {
 			a.Log.Info("Contact created from API", "contact_id", c.ID, "phone", phoneNumber)
 		}
-		contact = &c
+		contact = c
+	}
+
+	if contactutil.IsOptedOut(contact) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Contact has opted out of messages", nil, "")
 	}
 
 	// Get WhatsApp account
@@ -603,6 +623,11 @@ func (a *App) SendTemplateMessage(r *fastglue.Request) error {
 		}
 	}
 
+	// Contact attributes are available as named parameters; explicit params take precedence
+	if contact != nil && len(contact.Attributes) > 0 {
+		req.TemplateParams = contactutil.AttributeTemplateParams(contact.Attributes, req.TemplateParams)
+	}
+
 	// Extract parameter names and resolve values
 	paramNames := templateutil.ExtParamNames(template.BodyContent)
 	bodyParams := templateutil.ResolveParamsFromMap(paramNames, req.TemplateParams)
@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/database"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/phoneutil"
+	"github.com/shridarpatil/whatomate/internal/retention"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 )
@@ -16,6 +21,18 @@ type OrganizationSettings struct {
 	MaskPhoneNumbers bool   `json:"mask_phone_numbers"`
 	Timezone         string `json:"timezone"`
 	DateFormat       string `json:"date_format"`
+	// DefaultPhoneRegion is the ISO 3166-1 alpha-2 region used to normalize
+	// phone numbers entered without a country code (e.g. "IN")
+	DefaultPhoneRegion string `json:"default_phone_region"`
+	// Consent keywords handled before the chatbot, and their confirmation replies
+	ConsentOptOutKeywords []string `json:"consent_opt_out_keywords"`
+	ConsentOptInKeywords  []string `json:"consent_opt_in_keywords"`
+	ConsentOptOutReply    string   `json:"consent_opt_out_reply"`
+	ConsentOptInReply     string   `json:"consent_opt_in_reply"`
+	// Retention periods in days; zero keeps data forever
+	RetentionMessageBodyDays int `json:"retention_message_body_days"`
+	RetentionMediaDays       int `json:"retention_media_days"`
+	RetentionSessionLogDays  int `json:"retention_session_log_days"`
 }
 
 // GetOrganizationSettings returns the organization settings
@@ -47,8 +64,22 @@ func (a *App) GetOrganizationSettings(r *fastglue.Request) error {
 		if v, ok := org.Settings["date_format"].(string); ok && v != "" {
 			settings.DateFormat = v
 		}
+		if v, ok := org.Settings["default_phone_region"].(string); ok {
+			settings.DefaultPhoneRegion = v
+		}
 	}
 
+	consent := contactutil.ParseConsentSettings(org.Settings)
+	settings.ConsentOptOutKeywords = consent.OptOutKeywords
+	settings.ConsentOptInKeywords = consent.OptInKeywords
+	settings.ConsentOptOutReply = consent.OptOutReply
+	settings.ConsentOptInReply = consent.OptInReply
+
+	policy := retention.ParsePolicy(org.Settings)
+	settings.RetentionMessageBodyDays = policy.MessageBodyDays
+	settings.RetentionMediaDays = policy.MediaDays
+	settings.RetentionSessionLogDays = policy.SessionLogDays
+
 	return r.SendEnvelope(map[string]interface{}{
 		"settings": settings,
 		"name":     org.Name,
@@ -63,10 +94,20 @@ func (a *App) UpdateOrganizationSettings(r *fastglue.Request) error {
 	}
 
 	var req struct {
-		MaskPhoneNumbers *bool   `json:"mask_phone_numbers"`
-		Timezone         *string `json:"timezone"`
-		DateFormat       *string `json:"date_format"`
-		Name             *string `json:"name"`
+		MaskPhoneNumbers   *bool   `json:"mask_phone_numbers"`
+		Timezone           *string `json:"timezone"`
+		DateFormat         *string `json:"date_format"`
+		DefaultPhoneRegion *string `json:"default_phone_region"`
+		Name               *string `json:"name"`
+
+		ConsentOptOutKeywords *[]string `json:"consent_opt_out_keywords"`
+		ConsentOptInKeywords  *[]string `json:"consent_opt_in_keywords"`
+		ConsentOptOutReply    *string   `json:"consent_opt_out_reply"`
+		ConsentOptInReply     *string   `json:"consent_opt_in_reply"`
+
+		RetentionMessageBodyDays *int `json:"retention_message_body_days"`
+		RetentionMediaDays       *int `json:"retention_media_days"`
+		RetentionSessionLogDays  *int `json:"retention_session_log_days"`
 	}
 
 	if err := json.Unmarshal(r.RequestCtx.PostBody(), &req); err != nil {
@@ -87,11 +128,62 @@ func (a *App) UpdateOrganizationSettings(r *fastglue.Request) error {
 		org.Settings["mask_phone_numbers"] = *req.MaskPhoneNumbers
 	}
 	if req.Timezone != nil {
+		if !bizhours.ValidTimezone(*req.Timezone) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid timezone", nil, "")
+		}
 		org.Settings["timezone"] = *req.Timezone
 	}
 	if req.DateFormat != nil {
 		org.Settings["date_format"] = *req.DateFormat
 	}
+	if req.DefaultPhoneRegion != nil {
+		region := strings.ToUpper(strings.TrimSpace(*req.DefaultPhoneRegion))
+		if region != "" && !phoneutil.IsValidRegion(region) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Unsupported phone region", nil, "")
+		}
+		org.Settings["default_phone_region"] = region
+	}
+	if req.ConsentOptOutKeywords != nil || req.ConsentOptInKeywords != nil {
+		current := contactutil.ParseConsentSettings(org.Settings)
+		optOut, optIn := current.OptOutKeywords, current.OptInKeywords
+		if req.ConsentOptOutKeywords != nil {
+			optOut = contactutil.NormalizeConsentKeywords(*req.ConsentOptOutKeywords)
+		}
+		if req.ConsentOptInKeywords != nil {
+			optIn = contactutil.NormalizeConsentKeywords(*req.ConsentOptInKeywords)
+		}
+		if len(optOut) == 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "At least one opt-out keyword is required", nil, "")
+		}
+		for _, k := range optIn {
+			for _, o := range optOut {
+				if k == o {
+					return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Keyword "+k+" cannot be both an opt-out and opt-in keyword", nil, "")
+				}
+			}
+		}
+		org.Settings["consent_opt_out_keywords"] = optOut
+		org.Settings["consent_opt_in_keywords"] = optIn
+	}
+	if req.ConsentOptOutReply != nil {
+		org.Settings["consent_opt_out_reply"] = strings.TrimSpace(*req.ConsentOptOutReply)
+	}
+	if req.ConsentOptInReply != nil {
+		org.Settings["consent_opt_in_reply"] = strings.TrimSpace(*req.ConsentOptInReply)
+	}
+	for key, days := range map[string]*int{
+		retention.SettingMessageBodyDays: req.RetentionMessageBodyDays,
+		retention.SettingMediaDays:       req.RetentionMediaDays,
+		retention.SettingSessionLogDays:  req.RetentionSessionLogDays,
+	} {
+		if days == nil {
+			continue
+		}
+		if *days < 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Retention periods cannot be negative", nil, "")
+		}
+		org.Settings[key] = *days
+	}
 	if req.Name != nil && *req.Name != "" {
 		org.Name = *req.Name
 	}
@@ -100,6 +192,9 @@ func (a *App) UpdateOrganizationSettings(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update settings", nil, "")
 	}
 
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(org.ID)
+
 	return r.SendEnvelope(map[string]interface{}{
 		"message": "Settings updated successfully",
 	})
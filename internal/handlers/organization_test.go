@@ -139,6 +139,36 @@ func TestApp_UpdateOrganizationSettings_Success(t *testing.T) {
 	assert.Equal(t, "MM/DD/YYYY", updatedOrg.Settings["date_format"])
 }
 
+func TestApp_UpdateOrganizationSettings_DefaultPhoneRegion(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("phone-region")))
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"default_phone_region": "in",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.UpdateOrganizationSettings(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var updatedOrg models.Organization
+	require.NoError(t, app.DB.Where("id = ?", org.ID).First(&updatedOrg).Error)
+	assert.Equal(t, "IN", updatedOrg.Settings["default_phone_region"])
+
+	req = testutil.NewJSONRequest(t, map[string]any{
+		"default_phone_region": "XX",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err = app.UpdateOrganizationSettings(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Unsupported phone region")
+}
+
 func TestApp_UpdateOrganizationSettings_PartialUpdate(t *testing.T) {
 	t.Parallel()
 
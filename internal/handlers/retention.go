@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/retention"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// RetentionPurger periodically applies each organization's retention policy
+type RetentionPurger struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewRetentionPurger creates a new retention purger
+func NewRetentionPurger(app *App, interval time.Duration) *RetentionPurger {
+	return &RetentionPurger{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the retention purge loop
+func (p *RetentionPurger) Start(ctx context.Context) {
+	p.app.Log.Info("Retention purger started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.app.Log.Info("Retention purger stopped by context")
+			return
+		case <-p.stopCh:
+			p.app.Log.Info("Retention purger stopped")
+			return
+		case <-ticker.C:
+			p.purgeAll()
+		}
+	}
+}
+
+// Stop stops the retention purger
+func (p *RetentionPurger) Stop() {
+	close(p.stopCh)
+}
+
+// purgeAll applies the retention policy of every organization that has one
+func (p *RetentionPurger) purgeAll() {
+	var orgs []models.Organization
+	if err := p.app.DB.Select("id, settings").Find(&orgs).Error; err != nil {
+		p.app.Log.Error("Failed to load organizations for retention", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, org := range orgs {
+		policy := retention.ParsePolicy(org.Settings)
+		if !policy.IsEnabled() {
+			continue
+		}
+
+		report, err := p.app.newRetentionPurger(org.ID, policy, now).Purge()
+		if err != nil {
+			p.app.Log.Error("Retention purge failed", "error", err, "org_id", org.ID)
+		}
+		if report != nil && (report.MessageBodies > 0 || report.MediaFiles > 0 || report.SessionMessages > 0) {
+			p.app.Log.Info("Retention purge completed",
+				"org_id", org.ID,
+				"message_bodies", report.MessageBodies,
+				"media_files", report.MediaFiles,
+				"session_messages", report.SessionMessages,
+			)
+		}
+	}
+}
+
+func (a *App) newRetentionPurger(orgID uuid.UUID, policy retention.Policy, now time.Time) *retention.Purger {
+	return &retention.Purger{
+		DB:        a.DB,
+		OrgID:     orgID,
+		Policy:    policy,
+		MediaRoot: a.getMediaStoragePath(),
+		BatchSize: retention.DefaultBatchSize,
+		Now:       now,
+	}
+}
+
+// GetRetentionPreview returns the organization's retention policy and what
+// the next purge would remove
+func (a *App) GetRetentionPreview(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsGeneral, models.ActionRead); err != nil {
+		return nil
+	}
+
+	var org models.Organization
+	if err := a.DB.Select("id, settings").Where("id = ?", orgID).First(&org).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Organization not found", nil, "")
+	}
+
+	policy := retention.ParsePolicy(org.Settings)
+	report, err := a.newRetentionPurger(orgID, policy, time.Now()).Preview()
+	if err != nil {
+		a.Log.Error("Failed to preview retention purge", "error", err, "org_id", orgID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to preview retention purge", nil, "")
+	}
+
+	var heldContacts int64
+	a.DB.Model(&models.Contact{}).Where("organization_id = ? AND legal_hold = ?", orgID, true).Count(&heldContacts)
+
+	return r.SendEnvelope(map[string]any{
+		"policy":        policy,
+		"preview":       report,
+		"held_contacts": heldContacts,
+	})
+}
+
+// LegalHoldRequest represents a request to place or release a legal hold
+type LegalHoldRequest struct {
+	LegalHold bool   `json:"legal_hold"`
+	Reason    string `json:"reason"`
+}
+
+// SetContactLegalHold places a contact on legal hold, exempting it from
+// retention purges and erasure, or releases the hold
+func (a *App) SetContactLegalHold(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceDataRequests, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	var req LegalHoldRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	contact, err := findByIDAndOrg[models.Contact](a.DB, r, contactID, orgID, "Contact")
+	if err != nil {
+		return nil
+	}
+
+	updates := map[string]any{
+		"legal_hold":        false,
+		"legal_hold_reason": "",
+		"legal_hold_at":     nil,
+		"legal_hold_by":     nil,
+	}
+	if req.LegalHold {
+		updates["legal_hold"] = true
+		updates["legal_hold_reason"] = req.Reason
+		updates["legal_hold_at"] = time.Now()
+		updates["legal_hold_by"] = userID
+	}
+
+	if err := a.DB.Model(contact).Updates(updates).Error; err != nil {
+		a.Log.Error("Failed to update legal hold", "error", err, "contact_id", contact.ID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update legal hold", nil, "")
+	}
+
+	a.Log.Info("Contact legal hold updated", "contact_id", contact.ID, "legal_hold", req.LegalHold, "user_id", userID)
+
+	return r.SendEnvelope(map[string]any{
+		"contact_id":        contact.ID,
+		"legal_hold":        req.LegalHold,
+		"legal_hold_reason": updates["legal_hold_reason"],
+	})
+}
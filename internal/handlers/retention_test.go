@@ -0,0 +1,118 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// --- SetContactLegalHold Tests ---
+
+func TestApp_SetContactLegalHold(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hold blocks erasure until released", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"legal_hold": true, "reason": "Litigation 2026-17"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		require.NoError(t, app.SetContactLegalHold(req))
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var got models.Contact
+		require.NoError(t, app.DB.First(&got, contact.ID).Error)
+		assert.True(t, got.LegalHold)
+		assert.Equal(t, "Litigation 2026-17", got.LegalHoldReason)
+		require.NotNil(t, got.LegalHoldBy)
+		assert.Equal(t, user.ID, *got.LegalHoldBy)
+
+		req = testutil.NewJSONRequest(t, map[string]any{})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		require.NoError(t, app.EraseContact(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusConflict, "legal hold")
+
+		req = testutil.NewJSONRequest(t, map[string]any{"legal_hold": false})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		require.NoError(t, app.SetContactLegalHold(req))
+		require.NoError(t, app.DB.First(&got, contact.ID).Error)
+		assert.False(t, got.LegalHold)
+		assert.Nil(t, got.LegalHoldAt)
+
+		req = testutil.NewJSONRequest(t, map[string]any{})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		require.NoError(t, app.EraseContact(req))
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	})
+
+	t.Run("requires permission", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "privacy-viewer", []string{"data_requests:read"})
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{"legal_hold": true})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		require.NoError(t, app.SetContactLegalHold(req))
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+	})
+}
+
+// --- GetRetentionPreview Tests ---
+
+func TestApp_GetRetentionPreview(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(&models.Organization{}).Where("id = ?", org.ID).
+		Update("settings", models.JSONB{"retention_message_body_days": 30}).Error)
+	require.NoError(t, app.DB.Create(&models.Message{
+		BaseModel:       models.BaseModel{CreatedAt: time.Now().AddDate(0, 0, -31)},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "old",
+	}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.GetRetentionPreview(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Policy struct {
+			MessageBodyDays int `json:"message_body_days"`
+		} `json:"policy"`
+		Preview struct {
+			MessageBodies int64 `json:"message_bodies"`
+		} `json:"preview"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, 30, resp.Policy.MessageBodyDays)
+	assert.Equal(t, int64(1), resp.Preview.MessageBodies)
+
+	// Preview leaves the message untouched
+	var msg models.Message
+	require.NoError(t, app.DB.Where("contact_id = ?", contact.ID).First(&msg).Error)
+	assert.Equal(t, "old", msg.Content)
+}
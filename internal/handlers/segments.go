@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/segmentutil"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// segmentPreviewSize is the number of sample contacts returned by PreviewSegment
+const segmentPreviewSize = 10
+
+// SegmentRequest represents the request body for creating/updating a segment
+type SegmentRequest struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Rules       segmentutil.Rule `json:"rules"`
+}
+
+// SegmentPreviewRequest represents the request body for previewing unsaved segment rules
+type SegmentPreviewRequest struct {
+	Rules segmentutil.Rule `json:"rules"`
+}
+
+// ListSegments returns the organization's segments
+func (a *App) ListSegments(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionRead); err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+	search := string(r.RequestCtx.QueryArgs().Peek("search"))
+
+	query := a.DB.Model(&models.Segment{}).Where("organization_id = ?", orgID)
+	if search != "" {
+		query = query.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var segments []models.Segment
+	if err := pg.Apply(query.Order("name ASC")).Find(&segments).Error; err != nil {
+		a.Log.Error("Failed to list segments", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list segments", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"segments": segments,
+		"total":    total,
+		"page":     pg.Page,
+		"limit":    pg.Limit,
+	})
+}
+
+// GetSegmentFields returns the fields and operators available for segment rules
+func (a *App) GetSegmentFields(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionRead); err != nil {
+		return nil
+	}
+
+	defs, err := a.getContactAttributesCached(orgID)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load segment fields", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"fields": segmentutil.Fields(defs),
+	})
+}
+
+// PreviewSegment returns the member count and a sample of contacts for unsaved rules
+func (a *App) PreviewSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionRead); err != nil {
+		return nil
+	}
+
+	var req SegmentPreviewRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	query, err := a.segmentContactsQuery(r, orgID, req.Rules)
+	if err != nil {
+		return nil
+	}
+
+	var count int64
+	if err := query.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		a.Log.Error("Failed to count segment contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to preview segment", nil, "")
+	}
+
+	var contacts []models.Contact
+	if err := query.Order("contacts.last_message_at DESC NULLS LAST").Limit(segmentPreviewSize).Find(&contacts).Error; err != nil {
+		a.Log.Error("Failed to preview segment contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to preview segment", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"count":    count,
+		"contacts": a.buildContactResponses(contacts, orgID),
+	})
+}
+
+// CreateSegment creates a new segment
+func (a *App) CreateSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req SegmentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "name is required", nil, "")
+	}
+
+	query, err := a.segmentContactsQuery(r, orgID, req.Rules)
+	if err != nil {
+		return nil
+	}
+
+	// Check for duplicate name
+	var existing models.Segment
+	if err := a.DB.Where("organization_id = ? AND name = ?", orgID, req.Name).First(&existing).Error; err == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Segment with this name already exists", nil, "")
+	}
+
+	segment := models.Segment{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Rules:          req.Rules.ToJSONB(),
+		CreatedBy:      &userID,
+	}
+	a.evaluateSegmentCount(&segment, query)
+
+	if err := a.DB.Create(&segment).Error; err != nil {
+		a.Log.Error("Failed to create segment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create segment", nil, "")
+	}
+
+	return r.SendEnvelope(segment)
+}
+
+// GetSegment returns a segment with a freshly evaluated member count
+func (a *App) GetSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.Segment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := segmentutil.ParseRules(segment.Rules)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	query, err := a.segmentContactsQuery(r, orgID, rule)
+	if err != nil {
+		return nil
+	}
+
+	a.evaluateSegmentCount(segment, query)
+	a.DB.Model(segment).Select("contact_count", "last_evaluated_at").Updates(segment)
+
+	return r.SendEnvelope(segment)
+}
+
+// UpdateSegment updates a segment's name, description and rules
+func (a *App) UpdateSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	var req SegmentRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.Segment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "name is required", nil, "")
+	}
+
+	query, err := a.segmentContactsQuery(r, orgID, req.Rules)
+	if err != nil {
+		return nil
+	}
+
+	if req.Name != segment.Name {
+		var existing models.Segment
+		if err := a.DB.Where("organization_id = ? AND name = ? AND id != ?", orgID, req.Name, id).First(&existing).Error; err == nil {
+			return r.SendErrorEnvelope(fasthttp.StatusConflict, "Segment with this name already exists", nil, "")
+		}
+	}
+
+	segment.Name = req.Name
+	segment.Description = req.Description
+	segment.Rules = req.Rules.ToJSONB()
+	a.evaluateSegmentCount(segment, query)
+
+	if err := a.DB.Model(segment).Select(
+		"name", "description", "rules", "contact_count", "last_evaluated_at",
+	).Updates(segment).Error; err != nil {
+		a.Log.Error("Failed to update segment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update segment", nil, "")
+	}
+
+	return r.SendEnvelope(segment)
+}
+
+// DeleteSegment deletes a segment that isn't targeted by an unfinished campaign
+func (a *App) DeleteSegment(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.Segment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	var inUse int64
+	a.DB.Model(&models.BulkMessageCampaign{}).
+		Where("organization_id = ? AND segment_id = ? AND status IN ?", orgID, id, []models.CampaignStatus{
+			models.CampaignStatusDraft, models.CampaignStatusScheduled, models.CampaignStatusPaused,
+		}).
+		Count(&inUse)
+	if inUse > 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Segment is used by a campaign that hasn't been sent", nil, "")
+	}
+
+	if err := a.DB.Delete(segment).Error; err != nil {
+		a.Log.Error("Failed to delete segment", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete segment", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Segment deleted"})
+}
+
+// ListSegmentContacts returns a page of the contacts currently matching a segment
+func (a *App) ListSegmentContacts(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSegments, models.ActionRead); err != nil {
+		return nil
+	}
+	if err := a.requirePermission(r, userID, models.ResourceContacts, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "segment")
+	if err != nil {
+		return nil
+	}
+
+	segment, err := findByIDAndOrg[models.Segment](a.DB, r, id, orgID, "Segment")
+	if err != nil {
+		return nil
+	}
+
+	rule, err := segmentutil.ParseRules(segment.Rules)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+	query, err := a.segmentContactsQuery(r, orgID, rule)
+	if err != nil {
+		return nil
+	}
+
+	pg := parsePagination(r)
+
+	var total int64
+	query.Session(&gorm.Session{}).Count(&total)
+
+	var contacts []models.Contact
+	if err := pg.Apply(query.Order("contacts.last_message_at DESC NULLS LAST, contacts.created_at DESC")).
+		Find(&contacts).Error; err != nil {
+		a.Log.Error("Failed to list segment contacts", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list segment contacts", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"contacts": a.buildContactResponses(contacts, orgID),
+		"total":    total,
+		"page":     pg.Page,
+		"limit":    pg.Limit,
+	})
+}
+
+// segmentContactsQuery compiles rule into a query for the organization's matching contacts.
+// Sends an error envelope and returns errEnvelopeSent if the rules are invalid.
+func (a *App) segmentContactsQuery(r *fastglue.Request, orgID uuid.UUID, rule segmentutil.Rule) (*gorm.DB, error) {
+	defs, err := a.getContactAttributesCached(orgID)
+	if err != nil {
+		a.Log.Error("Failed to load contact attributes", "error", err)
+		_ = r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load contact attributes", nil, "")
+		return nil, errEnvelopeSent
+	}
+
+	sql, args, err := segmentutil.Compile(rule, defs, time.Now())
+	if err != nil {
+		_ = r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid segment rules: "+err.Error(), nil, "")
+		return nil, errEnvelopeSent
+	}
+
+	return a.DB.Model(&models.Contact{}).
+		Where("contacts.organization_id = ?", orgID).
+		Where(sql, args...), nil
+}
+
+// evaluateSegmentCount sets the segment's member count from query. Failures are
+// logged and leave the previous count in place.
+func (a *App) evaluateSegmentCount(segment *models.Segment, query *gorm.DB) {
+	var count int64
+	if err := query.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		a.Log.Error("Failed to count segment contacts", "error", err, "segment_id", segment.ID)
+		return
+	}
+	now := time.Now()
+	segment.ContactCount = count
+	segment.LastEvaluatedAt = &now
+}
+
+// buildContactResponses converts contacts to API responses
+func (a *App) buildContactResponses(contacts []models.Contact, orgID uuid.UUID) []ContactResponse {
+	response := make([]ContactResponse, len(contacts))
+	for i := range contacts {
+		response[i] = a.buildContactResponse(&contacts[i], orgID)
+	}
+	return response
+}
+
+// addSegmentRecipients adds the segment's current members to a campaign,
+// skipping numbers that are already recipients. Group chats are excluded since
+// templates can't be broadcast to them. It returns the number of recipients added.
+func (a *App) addSegmentRecipients(campaign *models.BulkMessageCampaign) (int, error) {
+	var segment models.Segment
+	if err := a.DB.Where("id = ? AND organization_id = ?", campaign.SegmentID, campaign.OrganizationID).First(&segment).Error; err != nil {
+		return 0, err
+	}
+	rule, err := segmentutil.ParseRules(segment.Rules)
+	if err != nil {
+		return 0, err
+	}
+	defs, err := a.getContactAttributesCached(campaign.OrganizationID)
+	if err != nil {
+		return 0, err
+	}
+	sql, args, err := segmentutil.Compile(rule, defs, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	var batch []models.Contact
+	err = a.DB.Model(&models.Contact{}).
+		Select("contacts.id", "contacts.phone_number", "contacts.profile_name").
+		Where("contacts.organization_id = ?", campaign.OrganizationID).
+		Where(sql, args...).
+		Where("contacts.phone_number NOT LIKE ?", "%@%").
+		Where("NOT EXISTS (SELECT 1 FROM bulk_message_recipients bmr WHERE bmr.campaign_id = ? AND bmr.phone_number = contacts.phone_number AND bmr.deleted_at IS NULL)", campaign.ID).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			recipients := make([]models.BulkMessageRecipient, len(batch))
+			for i, c := range batch {
+				recipients[i] = models.BulkMessageRecipient{
+					CampaignID:     campaign.ID,
+					PhoneNumber:    c.PhoneNumber,
+					RecipientName:  c.ProfileName,
+					TemplateParams: models.JSONB{},
+					Status:         models.MessageStatusPending,
+				}
+			}
+			if err := a.DB.Create(&recipients).Error; err != nil {
+				return err
+			}
+			added += len(recipients)
+			return nil
+		}).Error
+	if err != nil {
+		return added, err
+	}
+
+	var totalCount int64
+	a.DB.Model(&models.BulkMessageRecipient{}).Where("campaign_id = ?", campaign.ID).Count(&totalCount)
+	a.DB.Model(campaign).Update("total_recipients", totalCount)
+
+	return added, nil
+}
@@ -0,0 +1,262 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// vipRules matches contacts tagged "vip".
+var vipRules = map[string]any{
+	"match": "all",
+	"rules": []map[string]any{{"field": "tags", "op": "has", "value": "vip"}},
+}
+
+// createTestSegment creates a segment directly in the database for testing.
+func createTestSegment(t *testing.T, app *handlers.App, orgID uuid.UUID, name string, rules models.JSONB) *models.Segment {
+	t.Helper()
+
+	segment := &models.Segment{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		Name:           name,
+		Rules:          rules,
+	}
+	require.NoError(t, app.DB.Create(segment).Error)
+	return segment
+}
+
+// tagContact sets a single tag on a test contact.
+func tagContact(t *testing.T, app *handlers.App, contact *models.Contact, tag string) {
+	t.Helper()
+	require.NoError(t, app.DB.Model(contact).Update("tags", models.JSONBArray{tag}).Error)
+}
+
+// --- CreateSegment Tests ---
+
+func TestApp_CreateSegment(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		tagContact(t, app, testutil.CreateTestContact(t, app.DB, org.ID), "vip")
+		testutil.CreateTestContact(t, app.DB, org.ID)
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name":  "VIPs",
+			"rules": vipRules,
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateSegment(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp models.Segment
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		assert.Equal(t, "VIPs", resp.Name)
+		assert.Equal(t, int64(1), resp.ContactCount)
+		assert.NotNil(t, resp.LastEvaluatedAt)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name": "Broken",
+			"rules": map[string]any{
+				"match": "all",
+				"rules": []map[string]any{{"field": "attr.missing", "op": "eq", "value": "x"}},
+			},
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateSegment(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid segment rules")
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		createTestSegment(t, app, org.ID, "VIPs", models.JSONB{})
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name":  "VIPs",
+			"rules": vipRules,
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateSegment(req)
+		require.NoError(t, err)
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusConflict, "already exists")
+	})
+
+	t.Run("forbidden without write permission", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "readonly", []string{
+			"segments:read",
+		})
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name":  "VIPs",
+			"rules": vipRules,
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+
+		err := app.CreateSegment(req)
+		require.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+	})
+}
+
+// --- PreviewSegment Tests ---
+
+func TestApp_PreviewSegment(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	createTestContactAttribute(t, app, org.ID, models.ContactAttribute{Key: "orders", Type: models.ContactAttributeTypeNumber})
+
+	c1 := testutil.CreateTestContact(t, app.DB, org.ID)
+	c2 := testutil.CreateTestContact(t, app.DB, org.ID)
+	testutil.CreateTestContact(t, app.DB, org.ID)
+	tagContact(t, app, c1, "vip")
+	require.NoError(t, app.DB.Model(c2).Update("attributes", models.JSONB{"orders": 8}).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"rules": map[string]any{
+			"match": "any",
+			"rules": []map[string]any{
+				{"field": "tags", "op": "has", "value": "vip"},
+				{"field": "attr.orders", "op": "gte", "value": 5},
+			},
+		},
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.PreviewSegment(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Count    int64 `json:"count"`
+		Contacts []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"contacts"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, int64(2), resp.Count)
+	assert.Len(t, resp.Contacts, 2)
+}
+
+// --- ListSegmentContacts Tests ---
+
+func TestApp_ListSegmentContacts(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	for i := 0; i < 3; i++ {
+		tagContact(t, app, testutil.CreateTestContact(t, app.DB, org.ID), "vip")
+	}
+	testutil.CreateTestContact(t, app.DB, org.ID)
+	segment := createTestSegment(t, app, org.ID, "VIPs", models.JSONB(vipRules))
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", segment.ID.String())
+	testutil.SetQueryParam(req, "limit", "2")
+
+	err := app.ListSegmentContacts(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp struct {
+		Contacts []map[string]any `json:"contacts"`
+		Total    int64            `json:"total"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+	assert.Equal(t, int64(3), resp.Total)
+	assert.Len(t, resp.Contacts, 2)
+}
+
+// --- Campaign targeting Tests ---
+
+func TestApp_StartCampaign_SegmentRecipients(t *testing.T) {
+	t.Parallel()
+
+	mockQueue := testutil.NewMockQueue()
+	app := newTestApp(t, withQueue(mockQueue))
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithEmail(testutil.UniqueEmail("segment-campaign")), testutil.WithPassword("password"))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("segment-campaign-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	vip := testutil.CreateTestContact(t, app.DB, org.ID)
+	tagContact(t, app, vip, "vip")
+	testutil.CreateTestContact(t, app.DB, org.ID)
+	segment := createTestSegment(t, app, org.ID, "VIPs", models.JSONB(vipRules))
+
+	campaign := createTestCampaign(t, app, org.ID, template.ID, user.ID, account.Name, models.CampaignStatusDraft)
+	require.NoError(t, app.DB.Model(campaign).Update("segment_id", segment.ID).Error)
+	// Existing recipients for segment members aren't duplicated
+	createTestRecipient(t, app, campaign.ID, vip.PhoneNumber, models.MessageStatusPending)
+
+	req := testutil.NewJSONRequest(t, nil)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", campaign.ID.String())
+
+	err := app.StartCampaign(req)
+	require.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	assert.Len(t, mockQueue.Jobs, 1)
+
+	var updated models.BulkMessageCampaign
+	require.NoError(t, app.DB.First(&updated, campaign.ID).Error)
+	assert.Equal(t, 1, updated.TotalRecipients)
+}
+
+func TestApp_CreateCampaign_SegmentNotFound(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	account := testutil.CreateTestWhatsAppAccountWith(t, app.DB, org.ID, testutil.WithAccountName("segment-missing-account"))
+	template := testutil.CreateTestTemplate(t, app.DB, org.ID, account.Name)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":             "Segment campaign",
+		"whatsapp_account": account.Name,
+		"template_id":      template.ID.String(),
+		"segment_id":       uuid.New().String(),
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+
+	err := app.CreateCampaign(req)
+	require.NoError(t, err)
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusNotFound, "Segment not found")
+}
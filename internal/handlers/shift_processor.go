@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+)
+
+// ShiftProcessor makes agents available when their shifts start and away
+// when they end
+type ShiftProcessor struct {
+	app      *App
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// NewShiftProcessor creates a new shift processor
+func NewShiftProcessor(app *App, interval time.Duration) *ShiftProcessor {
+	return &ShiftProcessor{
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the shift processing loop
+func (p *ShiftProcessor) Start(ctx context.Context) {
+	p.app.Log.Info("Shift processor started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.app.Log.Info("Shift processor stopped by context")
+			return
+		case <-p.stopCh:
+			p.app.Log.Info("Shift processor stopped")
+			return
+		case <-ticker.C:
+			p.processShiftBoundaries(time.Now())
+		}
+	}
+}
+
+// Stop stops the shift processor
+func (p *ShiftProcessor) Stop() {
+	close(p.stopCh)
+}
+
+// processShiftBoundaries applies the shifts that started or ended since the
+// last check
+func (p *ShiftProcessor) processShiftBoundaries(now time.Time) {
+	var schedules []models.ShiftSchedule
+	if err := p.app.DB.Where("is_active = ?", true).Find(&schedules).Error; err != nil {
+		p.app.Log.Error("Failed to load shift schedules", "error", err)
+		return
+	}
+
+	for i := range schedules {
+		p.applyShift(&schedules[i], now)
+	}
+}
+
+// applyShift changes an agent's availability when they have crossed a shift
+// boundary. Between boundaries agents can still take breaks or stay on.
+func (p *ShiftProcessor) applyShift(schedule *models.ShiftSchedule, now time.Time) {
+	orgID, userID := schedule.OrganizationID, schedule.UserID
+
+	plan, err := p.app.loadShiftPlan(schedule, orgID, userID, now, now.Add(time.Second))
+	if err != nil {
+		p.app.Log.Error("Failed to load shift plan", "error", err, "user_id", userID)
+		return
+	}
+	onShift := plan.OnShift(now)
+	if schedule.OnShift != nil && *schedule.OnShift == onShift {
+		return
+	}
+
+	// Claim the boundary so it is applied once
+	result := p.app.DB.Model(&models.ShiftSchedule{}).
+		Where("id = ? AND (on_shift IS NULL OR on_shift <> ?)", schedule.ID, onShift).
+		Update("on_shift", onShift)
+	if result.Error != nil {
+		p.app.Log.Error("Failed to update shift state", "error", result.Error, "user_id", userID)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	var user models.User
+	if err := p.app.DB.Where("id = ? AND is_active = ?", userID, true).First(&user).Error; err != nil {
+		return
+	}
+	if user.IsAvailable == onShift {
+		return
+	}
+	if err := p.app.setAvailability(&user, orgID, onShift, models.AvailabilitySourceShift); err != nil {
+		p.app.Log.Error("Failed to update availability at shift boundary", "error", err, "user_id", userID)
+		return
+	}
+
+	transfersReturned, transfersAssigned := 0, 0
+	if onShift {
+		transfersAssigned = p.app.assignQueuedTransfers(orgID, userID)
+	} else {
+		transfersReturned = p.app.ReturnAgentTransfersToQueue(userID, orgID)
+	}
+
+	if p.app.WSHub != nil {
+		p.app.WSHub.BroadcastToOrg(orgID, websocket.WSMessage{
+			Type: websocket.TypeAvailabilityUpdate,
+			Payload: map[string]any{
+				"user_id":      userID.String(),
+				"is_available": onShift,
+				"source":       models.AvailabilitySourceShift,
+			},
+		})
+	}
+
+	p.app.Log.Info("Applied shift boundary",
+		"user_id", userID,
+		"on_shift", onShift,
+		"transfers_to_queue", transfersReturned,
+		"transfers_assigned", transfersAssigned,
+	)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// everyDayNineToFive works every day, 09:00-17:00 UTC
+func everyDayNineToFive() models.JSONBArray {
+	hours := models.JSONBArray{}
+	for day := 0; day <= 6; day++ {
+		hours = append(hours, map[string]interface{}{
+			"day": float64(day), "enabled": true, "start_time": "09:00", "end_time": "17:00",
+		})
+	}
+	return hours
+}
+
+func TestShiftProcessor_AppliesBoundaries(t *testing.T) {
+	app := newProcessorTestApp(t)
+	app.WSHub = websocket.NewHub(app.Log)
+	org, account := createProcessorTestOrg(t, app)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	schedule := &models.ShiftSchedule{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		UserID:         agent.ID,
+		Timezone:       "UTC",
+		Hours:          everyDayNineToFive(),
+		IsActive:       true,
+	}
+	require.NoError(t, app.DB.Create(schedule).Error)
+
+	transfer := &models.AgentTransfer{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		Source:          models.TransferSourceManual,
+		AgentID:         &agent.ID,
+		TransferredAt:   time.Now(),
+	}
+	require.NoError(t, app.DB.Create(transfer).Error)
+
+	processor := NewShiftProcessor(app, time.Minute)
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	// Shift over: the agent goes away and their conversations return to the queue
+	processor.processShiftBoundaries(day.Add(17*time.Hour + 5*time.Minute))
+
+	var user models.User
+	require.NoError(t, app.DB.First(&user, "id = ?", agent.ID).Error)
+	assert.False(t, user.IsAvailable)
+	require.NoError(t, app.DB.First(transfer, "id = ?", transfer.ID).Error)
+	assert.Nil(t, transfer.AgentID)
+
+	var log models.UserAvailabilityLog
+	require.NoError(t, app.DB.Where("user_id = ? AND ended_at IS NULL", agent.ID).First(&log).Error)
+	assert.False(t, log.IsAvailable)
+	assert.Equal(t, models.AvailabilitySourceShift, log.Source)
+
+	// Staying on after the shift is not undone until the next boundary
+	require.NoError(t, app.DB.Model(&models.User{}).Where("id = ?", agent.ID).Update("is_available", true).Error)
+	processor.processShiftBoundaries(day.Add(18 * time.Hour))
+	require.NoError(t, app.DB.First(&user, "id = ?", agent.ID).Error)
+	assert.True(t, user.IsAvailable)
+
+	// Leave during the next shift makes the agent away again
+	require.NoError(t, app.DB.Create(&models.ShiftException{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		UserID:         agent.ID,
+		Type:           models.ShiftExceptionLeave,
+		StartsAt:       day.AddDate(0, 0, 1),
+		EndsAt:         day.AddDate(0, 0, 2),
+	}).Error)
+	processor.processShiftBoundaries(day.AddDate(0, 0, 1).Add(10 * time.Hour))
+	require.NoError(t, app.DB.First(&user, "id = ?", agent.ID).Error)
+	assert.False(t, user.IsAvailable)
+
+	// The following shift starts as usual
+	processor.processShiftBoundaries(day.AddDate(0, 0, 2).Add(9 * time.Hour))
+	require.NoError(t, app.DB.First(&user, "id = ?", agent.ID).Error)
+	assert.True(t, user.IsAvailable)
+	require.NoError(t, app.DB.First(schedule, "id = ?", schedule.ID).Error)
+	require.NotNil(t, schedule.OnShift)
+	assert.True(t, *schedule.OnShift)
+}
+
+func TestShiftProcessor_SkipsInactiveSchedules(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, _ := createProcessorTestOrg(t, app)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	schedule := &models.ShiftSchedule{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		UserID:         agent.ID,
+		Hours:          everyDayNineToFive(),
+		IsActive:       true,
+	}
+	require.NoError(t, app.DB.Create(schedule).Error)
+	require.NoError(t, app.DB.Model(schedule).Update("is_active", false).Error)
+
+	NewShiftProcessor(app, time.Minute).processShiftBoundaries(time.Date(2025, 3, 3, 20, 0, 0, 0, time.UTC))
+
+	var user models.User
+	require.NoError(t, app.DB.First(&user, "id = ?", agent.ID).Error)
+	assert.True(t, user.IsAvailable)
+}
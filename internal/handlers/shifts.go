@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/shiftutil"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// ShiftScheduleRequest represents the request body for setting an agent's
+// weekly shifts
+type ShiftScheduleRequest struct {
+	Timezone string                   `json:"timezone"` // Empty uses the organization's
+	Hours    []map[string]interface{} `json:"hours"`    // [{day, enabled, start_time, end_time}] or with "ranges"
+	IsActive *bool                    `json:"is_active"`
+}
+
+// ShiftExceptionRequest represents the request body for adding leave or
+// overtime
+type ShiftExceptionRequest struct {
+	Type     models.ShiftExceptionType `json:"type"`
+	StartsAt time.Time                 `json:"starts_at"`
+	EndsAt   time.Time                 `json:"ends_at"`
+	Reason   string                    `json:"reason"`
+}
+
+// ShiftScheduleResponse represents an agent's shift schedule in API responses
+type ShiftScheduleResponse struct {
+	UserID      uuid.UUID         `json:"user_id"`
+	UserName    string            `json:"user_name"`
+	Timezone    string            `json:"timezone"`
+	Hours       models.JSONBArray `json:"hours"`
+	IsActive    bool              `json:"is_active"`
+	OnShiftNow  bool              `json:"on_shift_now"`
+	IsAvailable bool              `json:"is_available"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// ListShiftSchedules returns the shift schedules of the organization's agents
+func (a *App) ListShiftSchedules(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionRead); err != nil {
+		return nil
+	}
+
+	var schedules []models.ShiftSchedule
+	if err := a.DB.Preload("User").Where("organization_id = ?", orgID).Find(&schedules).Error; err != nil {
+		a.Log.Error("Failed to list shift schedules", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list shift schedules", nil, "")
+	}
+
+	now := time.Now()
+	response := make([]ShiftScheduleResponse, len(schedules))
+	for i := range schedules {
+		response[i] = a.buildShiftScheduleResponse(&schedules[i], now)
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"schedules": response,
+	})
+}
+
+// GetUserShift returns a user's shift schedule and their current and
+// upcoming leave and overtime
+func (a *App) GetUserShift(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	// Users can always see their own shifts
+	if id != userID {
+		if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionRead); err != nil {
+			return nil
+		}
+	}
+
+	if !a.isOrgMember(id, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	now := time.Now()
+	var schedule *ShiftScheduleResponse
+	var row models.ShiftSchedule
+	err = a.DB.Preload("User").Where("organization_id = ? AND user_id = ?", orgID, id).First(&row).Error
+	switch {
+	case err == nil:
+		resp := a.buildShiftScheduleResponse(&row, now)
+		schedule = &resp
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		a.Log.Error("Failed to load shift schedule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load shift schedule", nil, "")
+	}
+
+	var exceptions []models.ShiftException
+	if err := a.DB.Where("organization_id = ? AND user_id = ? AND ends_at > ?", orgID, id, now).
+		Order("starts_at").Find(&exceptions).Error; err != nil {
+		a.Log.Error("Failed to load shift exceptions", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load shift exceptions", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"schedule":   schedule,
+		"exceptions": exceptions,
+	})
+}
+
+// SetUserShift creates or replaces a user's weekly shift schedule
+func (a *App) SetUserShift(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	var req ShiftScheduleRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if !a.isOrgMember(id, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	if !bizhours.ValidTimezone(req.Timezone) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid timezone", nil, "")
+	}
+	hours := make(models.JSONBArray, len(req.Hours))
+	for i, h := range req.Hours {
+		hours[i] = h
+	}
+	if err := shiftutil.ValidateHours(hours); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	var schedule models.ShiftSchedule
+	err = a.DB.Where("organization_id = ? AND user_id = ?", orgID, id).First(&schedule).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		a.Log.Error("Failed to load shift schedule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save shift schedule", nil, "")
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		schedule = models.ShiftSchedule{
+			BaseModel:      models.BaseModel{ID: uuid.New()},
+			OrganizationID: orgID,
+			UserID:         id,
+			IsActive:       true,
+		}
+	}
+	schedule.Timezone = req.Timezone
+	schedule.Hours = hours
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+	// Apply the new shifts on the next check
+	schedule.OnShift = nil
+
+	if err := a.DB.Save(&schedule).Error; err != nil {
+		a.Log.Error("Failed to save shift schedule", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to save shift schedule", nil, "")
+	}
+
+	if err := a.DB.Preload("User").First(&schedule, "id = ?", schedule.ID).Error; err != nil {
+		a.Log.Error("Failed to reload shift schedule", "error", err)
+	}
+	return r.SendEnvelope(a.buildShiftScheduleResponse(&schedule, time.Now()))
+}
+
+// DeleteUserShift removes a user's shift schedule. Their availability is no
+// longer changed automatically.
+func (a *App) DeleteUserShift(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	// Hard delete so the (organization, user) unique index allows a new schedule
+	result := a.DB.Unscoped().Where("organization_id = ? AND user_id = ?", orgID, id).Delete(&models.ShiftSchedule{})
+	if result.Error != nil {
+		a.Log.Error("Failed to delete shift schedule", "error", result.Error)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete shift schedule", nil, "")
+	}
+	if result.RowsAffected == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Shift schedule not found", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Shift schedule deleted"})
+}
+
+// ListShiftExceptions returns a user's leave and overtime. With from/to
+// (YYYY-MM-DD) it returns those overlapping the period, otherwise those not
+// yet over.
+func (a *App) ListShiftExceptions(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	if id != userID {
+		if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionRead); err != nil {
+			return nil
+		}
+	}
+
+	query := a.DB.Where("organization_id = ? AND user_id = ?", orgID, id)
+	from := string(r.RequestCtx.QueryArgs().Peek("from"))
+	to := string(r.RequestCtx.QueryArgs().Peek("to"))
+	if from != "" || to != "" {
+		start, end, errMsg := parseDateRange(from, to)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+		query = query.Where("starts_at <= ? AND ends_at > ?", end, start)
+	} else {
+		query = query.Where("ends_at > ?", time.Now())
+	}
+
+	var exceptions []models.ShiftException
+	if err := query.Order("starts_at").Find(&exceptions).Error; err != nil {
+		a.Log.Error("Failed to list shift exceptions", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list shift exceptions", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"exceptions": exceptions,
+	})
+}
+
+// CreateShiftException adds leave or overtime for a user
+func (a *App) CreateShiftException(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	var req ShiftExceptionRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if !a.isOrgMember(id, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	if err := shiftutil.ValidateException(req.Type, req.StartsAt, req.EndsAt); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+	}
+
+	exception := models.ShiftException{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		UserID:         id,
+		Type:           req.Type,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Reason:         strings.TrimSpace(req.Reason),
+		CreatedByID:    &userID,
+	}
+	if err := a.DB.Create(&exception).Error; err != nil {
+		a.Log.Error("Failed to create shift exception", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create shift exception", nil, "")
+	}
+
+	return r.SendEnvelope(exception)
+}
+
+// DeleteShiftException removes leave or overtime from a user
+func (a *App) DeleteShiftException(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceShifts, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+	exceptionID, err := parsePathUUID(r, "exception_id", "shift exception")
+	if err != nil {
+		return nil
+	}
+
+	result := a.DB.Where("id = ? AND user_id = ? AND organization_id = ?", exceptionID, id, orgID).Delete(&models.ShiftException{})
+	if result.Error != nil {
+		a.Log.Error("Failed to delete shift exception", "error", result.Error)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete shift exception", nil, "")
+	}
+	if result.RowsAffected == 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Shift exception not found", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Shift exception deleted"})
+}
+
+func (a *App) buildShiftScheduleResponse(schedule *models.ShiftSchedule, now time.Time) ShiftScheduleResponse {
+	resp := ShiftScheduleResponse{
+		UserID:    schedule.UserID,
+		Timezone:  schedule.Timezone,
+		Hours:     schedule.Hours,
+		IsActive:  schedule.IsActive,
+		UpdatedAt: schedule.UpdatedAt,
+	}
+	if resp.Hours == nil {
+		resp.Hours = models.JSONBArray{}
+	}
+	if schedule.User != nil {
+		resp.UserName = schedule.User.FullName
+		resp.IsAvailable = schedule.User.IsAvailable
+	}
+	if plan, err := a.loadShiftPlan(schedule, schedule.OrganizationID, schedule.UserID, now, now.Add(time.Second)); err == nil {
+		resp.OnShiftNow = plan.OnShift(now)
+	}
+	return resp
+}
+
+// loadShiftPlan builds a user's working plan with the leave and overtime
+// overlapping [from, to). The schedule may be nil.
+func (a *App) loadShiftPlan(schedule *models.ShiftSchedule, orgID, userID uuid.UUID, from, to time.Time) (*shiftutil.Plan, error) {
+	var exceptions []models.ShiftException
+	if err := a.DB.Where("organization_id = ? AND user_id = ? AND starts_at < ? AND ends_at > ?", orgID, userID, to, from).
+		Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+
+	timezone := ""
+	if schedule != nil {
+		timezone = schedule.Timezone
+	}
+	if timezone == "" {
+		var org models.Organization
+		if err := a.DB.Select("settings").Where("id = ?", orgID).First(&org).Error; err == nil {
+			timezone, _ = org.Settings["timezone"].(string)
+		}
+	}
+	return shiftutil.New(schedule, bizhours.LoadLocation(timezone), exceptions), nil
+}
@@ -0,0 +1,206 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func weekdayShiftHours() []map[string]any {
+	hours := []map[string]any{}
+	for day := 1; day <= 5; day++ {
+		hours = append(hours, map[string]any{"day": day, "enabled": true, "start_time": "09:00", "end_time": "17:00"})
+	}
+	return hours
+}
+
+func TestApp_SetUserShift(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	agent := createTestAgent(t, app, org.ID)
+
+	setShift := func(userID uuid.UUID, body map[string]any) *fasthttp.RequestCtx {
+		req := testutil.NewJSONRequest(t, body)
+		testutil.SetAuthContext(req, org.ID, userID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.SetUserShift(req))
+		return req.RequestCtx
+	}
+
+	t.Run("creates and replaces the schedule", func(t *testing.T) {
+		ctx := setShift(admin.ID, map[string]any{"timezone": "Europe/Berlin", "hours": weekdayShiftHours()})
+		require.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+		ctx = setShift(admin.ID, map[string]any{"timezone": "Asia/Kolkata", "hours": weekdayShiftHours()[:2]})
+		require.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+		var schedules []models.ShiftSchedule
+		require.NoError(t, app.DB.Where("user_id = ?", agent.ID).Find(&schedules).Error)
+		require.Len(t, schedules, 1)
+		assert.Equal(t, "Asia/Kolkata", schedules[0].Timezone)
+		assert.Len(t, schedules[0].Hours, 2)
+		assert.True(t, schedules[0].IsActive)
+		assert.Nil(t, schedules[0].OnShift)
+	})
+
+	t.Run("rejects invalid hours", func(t *testing.T) {
+		req := testutil.NewJSONRequest(t, map[string]any{"hours": []map[string]any{
+			{"day": 1, "enabled": true, "start_time": "9am", "end_time": "17:00"},
+		}})
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.SetUserShift(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "day 1 has an invalid start_time, use HH:MM")
+	})
+
+	t.Run("rejects invalid timezone", func(t *testing.T) {
+		ctx := setShift(admin.ID, map[string]any{"timezone": "Mars/Olympus", "hours": weekdayShiftHours()})
+		assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	})
+
+	t.Run("agents cannot set shifts", func(t *testing.T) {
+		ctx := setShift(agent.ID, map[string]any{"hours": weekdayShiftHours()})
+		assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	})
+
+	t.Run("agents can see their own shift", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, agent.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.GetUserShift(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var result struct {
+			Schedule *handlers.ShiftScheduleResponse `json:"schedule"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &result)
+		require.NotNil(t, result.Schedule)
+		assert.Equal(t, agent.ID, result.Schedule.UserID)
+		assert.Equal(t, "Test Agent", result.Schedule.UserName)
+	})
+}
+
+func TestApp_ShiftExceptions(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	agent := createTestAgent(t, app, org.ID)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"type":      "leave",
+		"starts_at": start,
+		"ends_at":   start.Add(8 * time.Hour),
+		"reason":    "Doctor's appointment",
+	})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", agent.ID.String())
+	require.NoError(t, app.CreateShiftException(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var created models.ShiftException
+	testutil.ParseEnvelopeResponse(t, req, &created)
+	assert.Equal(t, models.ShiftExceptionLeave, created.Type)
+
+	t.Run("rejects an end before the start", func(t *testing.T) {
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"type":      "overtime",
+			"starts_at": start,
+			"ends_at":   start.Add(-time.Hour),
+		})
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.CreateShiftException(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "ends_at must be after starts_at")
+	})
+
+	t.Run("lists upcoming exceptions", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, agent.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.ListShiftExceptions(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var result struct {
+			Exceptions []models.ShiftException `json:"exceptions"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &result)
+		require.Len(t, result.Exceptions, 1)
+		assert.Equal(t, "Doctor's appointment", result.Exceptions[0].Reason)
+	})
+
+	t.Run("deletes an exception", func(t *testing.T) {
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		testutil.SetPathParam(req, "exception_id", created.ID.String())
+		require.NoError(t, app.DeleteShiftException(req))
+		require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		req = testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		testutil.SetPathParam(req, "exception_id", created.ID.String())
+		require.NoError(t, app.DeleteShiftException(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusNotFound, "Shift exception not found")
+	})
+}
+
+func TestApp_GetAgentDetails_ShiftStats(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	agent := createTestAgent(t, app, org.ID)
+
+	// Four hours of overtime yesterday, one of them spent available
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(time.Hour)
+	require.NoError(t, app.DB.Create(&models.ShiftException{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		UserID:         agent.ID,
+		Type:           models.ShiftExceptionOvertime,
+		StartsAt:       yesterday,
+		EndsAt:         yesterday.Add(4 * time.Hour),
+	}).Error)
+	onlineFrom, onlineTo := yesterday.Add(3*time.Hour), yesterday.Add(6*time.Hour)
+	require.NoError(t, app.DB.Create(&models.UserAvailabilityLog{
+		UserID:         agent.ID,
+		OrganizationID: org.ID,
+		IsAvailable:    true,
+		StartedAt:      onlineFrom,
+		EndedAt:        &onlineTo,
+		Source:         models.AvailabilitySourceShift,
+	}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", agent.ID.String())
+	testutil.SetQueryParam(req, "from", yesterday.AddDate(0, 0, -1).Format("2006-01-02"))
+	testutil.SetQueryParam(req, "to", time.Now().Format("2006-01-02"))
+	require.NoError(t, app.GetAgentDetails(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Agent handlers.AgentPerformanceStats `json:"agent"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	assert.InDelta(t, 240, result.Agent.ScheduledMins, 0.1)
+	assert.InDelta(t, 180, result.Agent.OnlineMins, 0.1)
+	assert.InDelta(t, 60, result.Agent.OnShiftOnlineMins, 0.1)
+	assert.InDelta(t, 25, result.Agent.ShiftAdherencePct, 0.1)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/skillutil"
+)
+
+// routingNotePrefix marks the routing explanation appended to transfer notes
+const routingNotePrefix = "[Routing] "
+
+// transferRoute is the outcome of routing a new transfer.
+type transferRoute struct {
+	AgentID        *uuid.UUID
+	RequiredSkills models.SkillRequirements
+	Reason         string // Empty when the team's own strategy decided
+}
+
+// routeTransfer selects an agent for a new transfer. Required skills are the
+// explicit requirements merged with those derived from the contact.
+//
+// Teams using a non-skills strategy keep their own behaviour. For a
+// skills_based team, or for a transfer without a team that requires skills,
+// the best skilled available agent across the organization is chosen; when
+// nobody matches, a skills_based team falls back to its least-loaded agent
+// and anything else stays in its queue.
+func (a *App) routeTransfer(orgID uuid.UUID, teamID *uuid.UUID, contact *models.Contact, explicit models.SkillRequirements) transferRoute {
+	route := transferRoute{
+		RequiredSkills: skillutil.Merge(explicit, a.contactSkillRequirements(orgID, contact)),
+	}
+
+	var team *models.Team
+	if teamID != nil {
+		team = &models.Team{}
+		if err := a.DB.Where("id = ? AND organization_id = ? AND is_active = ?", *teamID, orgID, true).First(team).Error; err != nil {
+			a.Log.Error("Failed to get team for routing", "error", err, "team_id", *teamID)
+			return route
+		}
+		if team.AssignmentStrategy != models.AssignmentStrategySkillsBased {
+			route.AgentID = a.assignToTeam(*teamID, orgID)
+			return route
+		}
+	}
+
+	if len(route.RequiredSkills) == 0 {
+		if team == nil {
+			return route
+		}
+		route.AgentID = a.assignToTeamLoadBalanced(team.ID, orgID)
+		route.Reason = fallbackReason("No skills required", team, route.AgentID != nil)
+		return route
+	}
+
+	skills := skillutil.Describe(route.RequiredSkills)
+	match := a.bestSkilledAgent(orgID, route.RequiredSkills)
+	if match != nil {
+		agentID := match.Candidate.UserID
+		route.AgentID = &agentID
+		route.Reason = fmt.Sprintf("Assigned to %s, the best available match for %s (proficiency score %d, %d active transfers)",
+			match.Candidate.Name, skills, match.Score, match.Candidate.ActiveLoad)
+		return route
+	}
+
+	prefix := "No available agent with free capacity has " + skills
+	if team != nil {
+		route.AgentID = a.assignToTeamLoadBalanced(team.ID, orgID)
+	}
+	route.Reason = fallbackReason(prefix, team, route.AgentID != nil)
+	return route
+}
+
+func fallbackReason(prefix string, team *models.Team, assigned bool) string {
+	switch {
+	case team == nil:
+		return prefix + "; left in the general queue"
+	case assigned:
+		return prefix + "; assigned to the least-loaded agent in team " + team.Name
+	default:
+		return prefix + "; left in the " + team.Name + " team queue"
+	}
+}
+
+// contactSkillRequirements returns the skills implied by the contact's tags
+// and attributes.
+func (a *App) contactSkillRequirements(orgID uuid.UUID, contact *models.Contact) models.SkillRequirements {
+	if contact == nil {
+		return nil
+	}
+	var skills []models.Skill
+	if err := a.DB.Where("organization_id = ?", orgID).Find(&skills).Error; err != nil {
+		a.Log.Error("Failed to load skills for routing", "error", err)
+		return nil
+	}
+	return skillutil.ContactRequirements(skills, contact)
+}
+
+// bestSkilledAgent ranks available agents of the organization that hold all
+// required skills and have free capacity.
+func (a *App) bestSkilledAgent(orgID uuid.UUID, reqs models.SkillRequirements) *skillutil.Match {
+	names := make([]string, len(reqs))
+	for i, req := range reqs {
+		names[i] = strings.ToLower(req.Skill)
+	}
+
+	type skillRow struct {
+		UserID      uuid.UUID `gorm:"column:user_id"`
+		FullName    string    `gorm:"column:full_name"`
+		SkillName   string    `gorm:"column:skill_name"`
+		Proficiency int       `gorm:"column:proficiency"`
+	}
+	var rows []skillRow
+	if err := a.DB.Table("agent_skills").
+		Select("agent_skills.user_id, users.full_name, skills.name AS skill_name, agent_skills.proficiency").
+		Joins("JOIN skills ON skills.id = agent_skills.skill_id AND skills.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = agent_skills.user_id").
+		Where("agent_skills.organization_id = ? AND agent_skills.deleted_at IS NULL", orgID).
+		Where("LOWER(skills.name) IN ? AND users.is_available = ? AND users.is_active = ?", names, true, true).
+		Scan(&rows).Error; err != nil {
+		a.Log.Error("Failed to load agent skills for routing", "error", err)
+		return nil
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	byUser := map[uuid.UUID]*skillutil.Candidate{}
+	var userIDs []uuid.UUID
+	for _, row := range rows {
+		c, ok := byUser[row.UserID]
+		if !ok {
+			c = &skillutil.Candidate{UserID: row.UserID, Name: row.FullName, Skills: map[string]int{}}
+			byUser[row.UserID] = c
+			userIDs = append(userIDs, row.UserID)
+		}
+		c.Skills[strings.ToLower(row.SkillName)] = row.Proficiency
+	}
+
+	// Agents at their concurrent-chat limit are not considered
+	capacities := a.agentCapacities(orgID, userIDs)
+	candidates := make([]skillutil.Candidate, 0, len(userIDs))
+	for _, id := range userIDs {
+		capacity := capacities[id]
+		if !capacity.hasFree() {
+			continue
+		}
+		c := byUser[id]
+		c.ActiveLoad = capacity.Load
+		candidates = append(candidates, *c)
+	}
+	return skillutil.Best(reqs, candidates)
+}
+
+// withRoutingNote appends the routing explanation to transfer notes.
+func withRoutingNote(notes, reason string) string {
+	if reason == "" {
+		return notes
+	}
+	if notes == "" {
+		return routingNotePrefix + reason
+	}
+	return notes + "\n\n" + routingNotePrefix + reason
+}
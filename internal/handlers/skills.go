@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// SkillRequest represents the request body for creating/updating a skill
+type SkillRequest struct {
+	Name                   string               `json:"name"`
+	Category               models.SkillCategory `json:"category"`
+	Description            string               `json:"description"`
+	ContactTags            []string             `json:"contact_tags"`
+	ContactAttribute       string               `json:"contact_attribute"`
+	ContactAttributeValues []string             `json:"contact_attribute_values"`
+	MinProficiency         int                  `json:"min_proficiency"`
+}
+
+// AgentSkillInput is one skill assignment in SetUserSkills
+type AgentSkillInput struct {
+	SkillID     string `json:"skill_id"`
+	Proficiency int    `json:"proficiency"`
+}
+
+// SetUserSkillsRequest replaces all skills held by a user
+type SetUserSkillsRequest struct {
+	Skills []AgentSkillInput `json:"skills"`
+}
+
+// AgentSkillResponse represents a user's skill in API responses
+type AgentSkillResponse struct {
+	SkillID     uuid.UUID            `json:"skill_id"`
+	Name        string               `json:"name"`
+	Category    models.SkillCategory `json:"category"`
+	Proficiency int                  `json:"proficiency"`
+}
+
+// ListSkills returns the organization's skills
+func (a *App) ListSkills(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSkills, models.ActionRead); err != nil {
+		return nil
+	}
+
+	query := a.DB.Where("organization_id = ?", orgID)
+	if category := string(r.RequestCtx.QueryArgs().Peek("category")); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var skills []models.Skill
+	if err := query.Order("category ASC, name ASC").Find(&skills).Error; err != nil {
+		a.Log.Error("Failed to list skills", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list skills", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"skills": skills,
+	})
+}
+
+// CreateSkill creates a new skill
+func (a *App) CreateSkill(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSkills, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req SkillRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	skill := models.Skill{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+	}
+	if msg := applySkillRequest(&skill, &req); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	var existing models.Skill
+	if err := a.DB.Where("organization_id = ? AND LOWER(name) = LOWER(?)", orgID, skill.Name).First(&existing).Error; err == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Skill with this name already exists", nil, "")
+	}
+
+	if err := a.DB.Create(&skill).Error; err != nil {
+		a.Log.Error("Failed to create skill", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create skill", nil, "")
+	}
+
+	return r.SendEnvelope(skill)
+}
+
+// UpdateSkill updates a skill
+func (a *App) UpdateSkill(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSkills, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "skill")
+	if err != nil {
+		return nil
+	}
+
+	var req SkillRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	skill, err := findByIDAndOrg[models.Skill](a.DB, r, id, orgID, "Skill")
+	if err != nil {
+		return nil
+	}
+
+	if msg := applySkillRequest(skill, &req); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	var existing models.Skill
+	if err := a.DB.Where("organization_id = ? AND LOWER(name) = LOWER(?) AND id != ?", orgID, skill.Name, id).First(&existing).Error; err == nil {
+		return r.SendErrorEnvelope(fasthttp.StatusConflict, "Skill with this name already exists", nil, "")
+	}
+
+	if err := a.DB.Model(skill).Select(
+		"name", "category", "description", "contact_tags", "contact_attribute", "contact_attribute_values", "min_proficiency",
+	).Updates(skill).Error; err != nil {
+		a.Log.Error("Failed to update skill", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update skill", nil, "")
+	}
+
+	return r.SendEnvelope(skill)
+}
+
+// DeleteSkill deletes a skill and removes it from all agents
+func (a *App) DeleteSkill(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSkills, models.ActionDelete); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "skill")
+	if err != nil {
+		return nil
+	}
+
+	skill, err := findByIDAndOrg[models.Skill](a.DB, r, id, orgID, "Skill")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("skill_id = ?", id).Delete(&models.AgentSkill{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(skill).Error
+	}); err != nil {
+		a.Log.Error("Failed to delete skill", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete skill", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "Skill deleted"})
+}
+
+// GetUserSkills returns the skills held by a user
+func (a *App) GetUserSkills(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	// Users can always see their own skills
+	if id != userID {
+		if err := a.requirePermission(r, userID, models.ResourceSkills, models.ActionRead); err != nil {
+			return nil
+		}
+	}
+
+	if !a.isOrgMember(id, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	skills, err := a.loadUserSkills(id, orgID)
+	if err != nil {
+		a.Log.Error("Failed to load user skills", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load skills", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"skills": skills,
+	})
+}
+
+// SetUserSkills replaces the skills held by a user
+func (a *App) SetUserSkills(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSkills, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "user")
+	if err != nil {
+		return nil
+	}
+
+	var req SetUserSkillsRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	if !a.isOrgMember(id, orgID) {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	skillIDs := make([]uuid.UUID, 0, len(req.Skills))
+	seen := map[uuid.UUID]bool{}
+	assignments := make([]models.AgentSkill, 0, len(req.Skills))
+	for _, in := range req.Skills {
+		skillID, err := uuid.Parse(in.SkillID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid skill_id", nil, "")
+		}
+		if seen[skillID] {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Duplicate skill_id", nil, "")
+		}
+		if in.Proficiency < models.MinSkillProficiency || in.Proficiency > models.MaxSkillProficiency {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "proficiency must be between 1 and 5", nil, "")
+		}
+		seen[skillID] = true
+		skillIDs = append(skillIDs, skillID)
+		assignments = append(assignments, models.AgentSkill{
+			BaseModel:      models.BaseModel{ID: uuid.New()},
+			OrganizationID: orgID,
+			UserID:         id,
+			SkillID:        skillID,
+			Proficiency:    in.Proficiency,
+		})
+	}
+
+	if len(skillIDs) > 0 {
+		var count int64
+		a.DB.Model(&models.Skill{}).Where("organization_id = ? AND id IN ?", orgID, skillIDs).Count(&count)
+		if int(count) != len(skillIDs) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Skill not found", nil, "")
+		}
+	}
+
+	if err := a.DB.Transaction(func(tx *gorm.DB) error {
+		// Hard delete so the (user, skill) unique index allows re-adding
+		if err := tx.Unscoped().Where("organization_id = ? AND user_id = ?", orgID, id).Delete(&models.AgentSkill{}).Error; err != nil {
+			return err
+		}
+		if len(assignments) == 0 {
+			return nil
+		}
+		return tx.Create(&assignments).Error
+	}); err != nil {
+		a.Log.Error("Failed to set user skills", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update skills", nil, "")
+	}
+
+	skills, err := a.loadUserSkills(id, orgID)
+	if err != nil {
+		a.Log.Error("Failed to load user skills", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load skills", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"skills": skills,
+	})
+}
+
+// applySkillRequest validates req and copies it onto skill. It returns a
+// validation message, or "" when the request is valid.
+func applySkillRequest(skill *models.Skill, req *SkillRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "name is required"
+	}
+	if len(req.Name) > 100 {
+		return "name must be at most 100 characters"
+	}
+
+	category := req.Category
+	if category == "" {
+		category = models.SkillCategoryOther
+	}
+	switch category {
+	case models.SkillCategoryLanguage, models.SkillCategoryProduct, models.SkillCategoryTier, models.SkillCategoryOther:
+	default:
+		return "invalid category. Valid categories: language, product, tier, other"
+	}
+
+	minProficiency := req.MinProficiency
+	if minProficiency == 0 {
+		minProficiency = models.MinSkillProficiency
+	}
+	if minProficiency < models.MinSkillProficiency || minProficiency > models.MaxSkillProficiency {
+		return "min_proficiency must be between 1 and 5"
+	}
+
+	attribute := strings.TrimSpace(req.ContactAttribute)
+	if attribute == "" && len(req.ContactAttributeValues) > 0 {
+		return "contact_attribute is required when contact_attribute_values are set"
+	}
+
+	skill.Name = req.Name
+	skill.Category = category
+	skill.Description = req.Description
+	skill.ContactTags = models.StringArray(nonEmptyStrings(req.ContactTags))
+	skill.ContactAttribute = attribute
+	skill.ContactAttributeValues = models.StringArray(nonEmptyStrings(req.ContactAttributeValues))
+	skill.MinProficiency = minProficiency
+	return ""
+}
+
+func nonEmptyStrings(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// isOrgMember reports whether the user belongs to the organization
+func (a *App) isOrgMember(userID, orgID uuid.UUID) bool {
+	var count int64
+	a.DB.Model(&models.UserOrganization{}).
+		Where("user_id = ? AND organization_id = ?", userID, orgID).
+		Count(&count)
+	return count > 0
+}
+
+func (a *App) loadUserSkills(userID, orgID uuid.UUID) ([]AgentSkillResponse, error) {
+	var rows []models.AgentSkill
+	if err := a.DB.Preload("Skill").
+		Joins("JOIN skills ON skills.id = agent_skills.skill_id AND skills.deleted_at IS NULL").
+		Where("agent_skills.organization_id = ? AND agent_skills.user_id = ?", orgID, userID).
+		Order("skills.name ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	skills := make([]AgentSkillResponse, 0, len(rows))
+	for _, row := range rows {
+		resp := AgentSkillResponse{SkillID: row.SkillID, Proficiency: row.Proficiency}
+		if row.Skill != nil {
+			resp.Name = row.Skill.Name
+			resp.Category = row.Skill.Category
+		}
+		skills = append(skills, resp)
+	}
+	return skills, nil
+}
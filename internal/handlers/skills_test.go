@@ -0,0 +1,272 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// createTestSkill creates a skill and grants it to the given users at the
+// given proficiency.
+func createTestSkill(t *testing.T, app *handlers.App, orgID uuid.UUID, name string, holders map[uuid.UUID]int) *models.Skill {
+	t.Helper()
+
+	skill := &models.Skill{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		Name:           name,
+		Category:       models.SkillCategoryLanguage,
+		MinProficiency: 1,
+	}
+	require.NoError(t, app.DB.Create(skill).Error)
+
+	for userID, proficiency := range holders {
+		require.NoError(t, app.DB.Create(&models.AgentSkill{
+			BaseModel:      models.BaseModel{ID: uuid.New()},
+			OrganizationID: orgID,
+			UserID:         userID,
+			SkillID:        skill.ID,
+			Proficiency:    proficiency,
+		}).Error)
+	}
+	return skill
+}
+
+func createTransferViaAPI(t *testing.T, app *handlers.App, orgID, userID uuid.UUID, body map[string]any) handlers.AgentTransferResponse {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, body)
+	testutil.SetAuthContext(req, orgID, userID)
+	require.NoError(t, app.CreateAgentTransfer(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req), string(testutil.GetResponseBody(req)))
+
+	var result struct {
+		Data struct {
+			Transfer handlers.AgentTransferResponse `json:"transfer"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &result))
+	return result.Data.Transfer
+}
+
+// --- Skills CRUD Tests ---
+
+func TestApp_CreateSkill(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+	t.Run("creates skill", func(t *testing.T) {
+		req := testutil.NewJSONRequest(t, map[string]any{
+			"name":                     "Spanish",
+			"category":                 "language",
+			"contact_attribute":        "language",
+			"contact_attribute_values": []string{"es", " "},
+			"min_proficiency":          3,
+		})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateSkill(req))
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var skill models.Skill
+		require.NoError(t, app.DB.Where("organization_id = ? AND name = ?", org.ID, "Spanish").First(&skill).Error)
+		assert.Equal(t, models.SkillCategoryLanguage, skill.Category)
+		assert.Equal(t, models.StringArray{"es"}, skill.ContactAttributeValues)
+		assert.Equal(t, 3, skill.MinProficiency)
+	})
+
+	t.Run("rejects duplicate name", func(t *testing.T) {
+		req := testutil.NewJSONRequest(t, map[string]any{"name": "spanish"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateSkill(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusConflict, "already exists")
+	})
+
+	t.Run("rejects invalid category", func(t *testing.T) {
+		req := testutil.NewJSONRequest(t, map[string]any{"name": "Billing", "category": "department"})
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		require.NoError(t, app.CreateSkill(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "invalid category")
+	})
+
+	t.Run("requires permission", func(t *testing.T) {
+		viewerRole := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "skills-viewer", []string{"skills:read"})
+		viewer := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&viewerRole.ID))
+
+		req := testutil.NewJSONRequest(t, map[string]any{"name": "French"})
+		testutil.SetAuthContext(req, org.ID, viewer.ID)
+		require.NoError(t, app.CreateSkill(req))
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+	})
+}
+
+func TestApp_SetUserSkills(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	agent := createTestAgent(t, app, org.ID)
+	spanish := createTestSkill(t, app, org.ID, "spanish", nil)
+	billing := createTestSkill(t, app, org.ID, "billing", nil)
+
+	setSkills := func(skills []map[string]any) *fasthttp.RequestCtx {
+		req := testutil.NewJSONRequest(t, map[string]any{"skills": skills})
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		testutil.SetPathParam(req, "id", agent.ID.String())
+		require.NoError(t, app.SetUserSkills(req))
+		return req.RequestCtx
+	}
+
+	ctx := setSkills([]map[string]any{
+		{"skill_id": spanish.ID.String(), "proficiency": 4},
+		{"skill_id": billing.ID.String(), "proficiency": 2},
+	})
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	// Replacing drops skills that are no longer listed
+	ctx = setSkills([]map[string]any{{"skill_id": spanish.ID.String(), "proficiency": 5}})
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, agent.ID)
+	testutil.SetPathParam(req, "id", agent.ID.String())
+	require.NoError(t, app.GetUserSkills(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Data struct {
+			Skills []handlers.AgentSkillResponse `json:"skills"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(testutil.GetResponseBody(req), &result))
+	require.Len(t, result.Data.Skills, 1)
+	assert.Equal(t, "spanish", result.Data.Skills[0].Name)
+	assert.Equal(t, 5, result.Data.Skills[0].Proficiency)
+
+	ctx = setSkills([]map[string]any{{"skill_id": spanish.ID.String(), "proficiency": 6}})
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+
+	ctx = setSkills([]map[string]any{{"skill_id": uuid.New().String(), "proficiency": 3}})
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+}
+
+// --- Skills-based routing Tests ---
+
+func TestApp_CreateAgentTransfer_SkillsRouting(t *testing.T) {
+	t.Parallel()
+
+	t.Run("picks best skilled agent across teams", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+		agentRole := testutil.CreateAgentRole(t, app.DB, org.ID)
+		novice := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&agentRole.ID), testutil.WithFullName("Novice"))
+		expert := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&agentRole.ID), testutil.WithFullName("Expert"))
+		createTestSkill(t, app, org.ID, "spanish", map[uuid.UUID]int{novice.ID: 2, expert.ID: 5})
+
+		// The expert is not in the target team, but skills routing looks org-wide
+		team := createTestTeam(t, app, org.ID, novice.ID)
+		require.NoError(t, app.DB.Model(team).Update("assignment_strategy", models.AssignmentStrategySkillsBased).Error)
+
+		transfer := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+			"contact_id":       contact.ID.String(),
+			"whatsapp_account": account.Name,
+			"team_id":          team.ID.String(),
+			"notes":            "Customer asked for Spanish",
+			"required_skills":  []map[string]any{{"skill": "Spanish", "min_proficiency": 3}},
+		})
+
+		require.NotNil(t, transfer.AgentID)
+		assert.Equal(t, expert.ID.String(), *transfer.AgentID)
+		assert.Equal(t, models.SkillRequirements{{Skill: "Spanish", MinProficiency: 3}}, transfer.RequiredSkills)
+		assert.Contains(t, transfer.Notes, "Customer asked for Spanish")
+		assert.Contains(t, transfer.Notes, "[Routing] Assigned to Expert")
+	})
+
+	t.Run("falls back to team when nobody matches", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		agent := createTestAgent(t, app, org.ID)
+		createTestSkill(t, app, org.ID, "spanish", map[uuid.UUID]int{agent.ID: 2})
+
+		team := createTestTeam(t, app, org.ID, agent.ID)
+		require.NoError(t, app.DB.Model(team).Update("assignment_strategy", models.AssignmentStrategySkillsBased).Error)
+
+		transfer := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+			"contact_id":       contact.ID.String(),
+			"whatsapp_account": account.Name,
+			"team_id":          team.ID.String(),
+			"required_skills":  []string{"french"},
+		})
+
+		require.NotNil(t, transfer.AgentID)
+		assert.Equal(t, agent.ID.String(), *transfer.AgentID)
+		assert.Contains(t, transfer.Notes, "No available agent with free capacity has french; assigned to the least-loaded agent in team")
+	})
+
+	t.Run("derives requirements from contact attributes", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		require.NoError(t, app.DB.Model(contact).Update("attributes", models.JSONB{"language": "es"}).Error)
+
+		agent := createTestAgent(t, app, org.ID)
+		skill := createTestSkill(t, app, org.ID, "spanish", map[uuid.UUID]int{agent.ID: 4})
+		require.NoError(t, app.DB.Model(skill).Updates(map[string]any{
+			"contact_attribute":        "language",
+			"contact_attribute_values": models.StringArray{"es"},
+		}).Error)
+
+		transfer := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+			"contact_id":       contact.ID.String(),
+			"whatsapp_account": account.Name,
+		})
+
+		require.NotNil(t, transfer.AgentID)
+		assert.Equal(t, agent.ID.String(), *transfer.AgentID)
+		assert.Equal(t, models.SkillRequirements{{Skill: "spanish", MinProficiency: 1}}, transfer.RequiredSkills)
+	})
+
+	t.Run("no requirements keeps general queue", func(t *testing.T) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+		admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+		account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		agent := createTestAgent(t, app, org.ID)
+		createTestSkill(t, app, org.ID, "spanish", map[uuid.UUID]int{agent.ID: 4})
+
+		transfer := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+			"contact_id":       contact.ID.String(),
+			"whatsapp_account": account.Name,
+			"notes":            "Plain transfer",
+		})
+
+		assert.Nil(t, transfer.AgentID)
+		assert.Equal(t, "Plain transfer", transfer.Notes)
+	})
+}
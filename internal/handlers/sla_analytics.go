@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// SLATimeStats holds response and resolution figures measured on one clock
+type SLATimeStats struct {
+	AvgResponseMins         float64 `json:"avg_response_mins"`   // Transfer to pickup
+	AvgResolutionMins       float64 `json:"avg_resolution_mins"` // Transfer to resume
+	ResponseWithinTarget    int64   `json:"response_within_target"`
+	ResponseCompliancePct   float64 `json:"response_compliance_pct"`
+	ResolutionWithinTarget  int64   `json:"resolution_within_target"`
+	ResolutionCompliancePct float64 `json:"resolution_compliance_pct"`
+}
+
+// SLAAnalyticsResponse reports SLA performance for a period on both the
+// wall clock and the business-hours clock
+type SLAAnalyticsResponse struct {
+	TotalTransfers    int64        `json:"total_transfers"`
+	PickedUp          int64        `json:"picked_up"`
+	Resolved          int64        `json:"resolved"`
+	Breached          int64        `json:"breached"`
+	BreachRatePct     float64      `json:"breach_rate_pct"`
+	Escalated         int64        `json:"escalated"`
+	BusinessTimeCount int64        `json:"business_time_count"` // Transfers whose deadlines count business hours only
+	PausedNow         int64        `json:"paused_now"`          // Active transfers paused outside business hours
+	WallClock         SLATimeStats `json:"wall_clock"`
+	BusinessTime      SLATimeStats `json:"business_time"` // Equals wall clock where no business hours are configured
+}
+
+// slaTransferRow holds the columns needed for SLA analytics
+type slaTransferRow struct {
+	WhatsAppAccount string     `gorm:"column:whats_app_account"`
+	TeamID          *uuid.UUID `gorm:"column:team_id"`
+	Status          models.TransferStatus
+	TransferredAt   time.Time
+	ResumedAt       *time.Time
+	PickedUpAt      *time.Time `gorm:"column:picked_up_at"`
+	SLABreached     bool       `gorm:"column:sla_breached"`
+	EscalationLevel int        `gorm:"column:escalation_level"`
+	SLABusinessTime bool       `gorm:"column:sla_business_time"`
+	SLAPausedAt     *time.Time `gorm:"column:sla_paused_at"`
+}
+
+// GetSLAAnalytics returns SLA compliance for transfers created in a period.
+// Response and resolution times are reported both in wall-clock minutes and
+// in business minutes, which leave out nights, weekends and holidays.
+func (a *App) GetSLAAnalytics(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceAnalytics, models.ActionRead); err != nil {
+		return nil
+	}
+
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+
+	now := time.Now()
+	var periodStart, periodEnd time.Time
+	if fromStr != "" && toStr != "" {
+		var errMsg string
+		periodStart, periodEnd, errMsg = parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+	} else {
+		// Default to current month
+		periodStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		periodEnd = now
+	}
+
+	var rows []slaTransferRow
+	if err := a.DB.Model(&models.AgentTransfer{}).
+		Select("whats_app_account, team_id, status, transferred_at, resumed_at, picked_up_at, sla_breached, escalation_level, sla_business_time, sla_paused_at").
+		Where("organization_id = ? AND transferred_at >= ? AND transferred_at <= ?", orgID, periodStart, periodEnd).
+		Scan(&rows).Error; err != nil {
+		a.Log.Error("Failed to load transfers for SLA analytics", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load SLA analytics", nil, "")
+	}
+
+	return r.SendEnvelope(a.calculateSLAAnalytics(orgID, rows, periodStart))
+}
+
+// calculateSLAAnalytics aggregates transfers against the SLA targets of their
+// WhatsApp account
+func (a *App) calculateSLAAnalytics(orgID uuid.UUID, rows []slaTransferRow, since time.Time) SLAAnalyticsResponse {
+	type clock struct {
+		responseMins, resolutionMins float64
+	}
+	var (
+		resp             SLAAnalyticsResponse
+		wall, business   clock
+		settingsByAcc    = map[string]*models.ChatbotSettings{}
+		schedulesByQueue = map[string]*bizhours.Schedule{}
+	)
+
+	for _, row := range rows {
+		resp.TotalTransfers++
+		if row.SLABreached {
+			resp.Breached++
+		}
+		if row.EscalationLevel > 0 {
+			resp.Escalated++
+		}
+		if row.SLABusinessTime {
+			resp.BusinessTimeCount++
+		}
+		if row.Status == models.TransferStatusActive && row.SLAPausedAt != nil {
+			resp.PausedNow++
+		}
+
+		settings, ok := settingsByAcc[row.WhatsAppAccount]
+		if !ok {
+			settings, _ = a.getChatbotSettingsCached(orgID, row.WhatsAppAccount)
+			settingsByAcc[row.WhatsAppAccount] = settings
+		}
+		key := row.WhatsAppAccount
+		if row.TeamID != nil {
+			key += "/" + row.TeamID.String()
+		}
+		schedule, ok := schedulesByQueue[key]
+		if !ok && settings != nil {
+			schedule = a.businessScheduleSince(settings, row.WhatsAppAccount, row.TeamID, since)
+			schedulesByQueue[key] = schedule
+		}
+
+		businessMins := func(end time.Time) float64 {
+			if schedule == nil {
+				return end.Sub(row.TransferredAt).Minutes()
+			}
+			return schedule.Duration(row.TransferredAt, end).Minutes()
+		}
+		var responseTarget, resolutionTarget float64
+		if settings != nil {
+			responseTarget = float64(settings.SLA.ResponseMinutes)
+			resolutionTarget = float64(settings.SLA.ResolutionMinutes)
+		}
+
+		if row.PickedUpAt != nil {
+			resp.PickedUp++
+			w, b := row.PickedUpAt.Sub(row.TransferredAt).Minutes(), businessMins(*row.PickedUpAt)
+			wall.responseMins += w
+			business.responseMins += b
+			if responseTarget > 0 && w <= responseTarget {
+				resp.WallClock.ResponseWithinTarget++
+			}
+			if responseTarget > 0 && b <= responseTarget {
+				resp.BusinessTime.ResponseWithinTarget++
+			}
+		}
+
+		if row.Status == models.TransferStatusResumed && row.ResumedAt != nil {
+			resp.Resolved++
+			w, b := row.ResumedAt.Sub(row.TransferredAt).Minutes(), businessMins(*row.ResumedAt)
+			wall.resolutionMins += w
+			business.resolutionMins += b
+			if resolutionTarget > 0 && w <= resolutionTarget {
+				resp.WallClock.ResolutionWithinTarget++
+			}
+			if resolutionTarget > 0 && b <= resolutionTarget {
+				resp.BusinessTime.ResolutionWithinTarget++
+			}
+		}
+	}
+
+	resp.BreachRatePct = percentOf(resp.Breached, resp.TotalTransfers)
+	for _, c := range []struct {
+		stats *SLATimeStats
+		clock clock
+	}{{&resp.WallClock, wall}, {&resp.BusinessTime, business}} {
+		if resp.PickedUp > 0 {
+			c.stats.AvgResponseMins = c.clock.responseMins / float64(resp.PickedUp)
+		}
+		if resp.Resolved > 0 {
+			c.stats.AvgResolutionMins = c.clock.resolutionMins / float64(resp.Resolved)
+		}
+		c.stats.ResponseCompliancePct = percentOf(c.stats.ResponseWithinTarget, resp.PickedUp)
+		c.stats.ResolutionCompliancePct = percentOf(c.stats.ResolutionWithinTarget, resp.Resolved)
+	}
+
+	return resp
+}
+
+// percentOf returns part as a percentage of total, 0 when total is 0
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
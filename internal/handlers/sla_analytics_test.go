@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_GetSLAAnalytics_WallAndBusinessTime(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	var hours models.JSONBArray
+	for day := 1; day <= 5; day++ {
+		hours = append(hours, map[string]any{"day": float64(day), "enabled": true, "start_time": "09:00", "end_time": "17:00"})
+	}
+	require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		BusinessHours:  models.BusinessHoursConfig{Enabled: true, Hours: hours},
+		SLA:            models.SLAConfig{Enabled: true, ResponseMinutes: 30, ResolutionMinutes: 120},
+	}).Error)
+
+	// Transferred Friday 16:50, picked up Monday 09:10 and resolved Monday 10:00
+	transferredAt := time.Date(2026, 3, 6, 16, 50, 0, 0, time.UTC)
+	pickedUpAt := time.Date(2026, 3, 9, 9, 10, 0, 0, time.UTC)
+	resumedAt := time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC)
+	transfer := createTestTransfer(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, account.Name, models.TransferStatusResumed, nil)
+	require.NoError(t, app.DB.Model(transfer).Updates(map[string]any{
+		"transferred_at": transferredAt,
+		"picked_up_at":   pickedUpAt,
+		"resumed_at":     resumedAt,
+	}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetQueryParam(req, "from", "2026-03-01")
+	testutil.SetQueryParam(req, "to", "2026-03-31")
+	require.NoError(t, app.GetSLAAnalytics(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var resp handlers.SLAAnalyticsResponse
+	testutil.ParseEnvelopeResponse(t, req, &resp)
+
+	assert.Equal(t, int64(1), resp.TotalTransfers)
+	assert.Equal(t, int64(1), resp.PickedUp)
+	assert.Equal(t, int64(1), resp.Resolved)
+
+	// Wall clock counts the weekend
+	assert.InDelta(t, pickedUpAt.Sub(transferredAt).Minutes(), resp.WallClock.AvgResponseMins, 0.01)
+	assert.Equal(t, float64(0), resp.WallClock.ResponseCompliancePct)
+
+	// Business time: 10 minutes on Friday and 10 on Monday
+	assert.InDelta(t, 20, resp.BusinessTime.AvgResponseMins, 0.01)
+	assert.InDelta(t, 70, resp.BusinessTime.AvgResolutionMins, 0.01)
+	assert.Equal(t, float64(100), resp.BusinessTime.ResponseCompliancePct)
+	assert.Equal(t, float64(100), resp.BusinessTime.ResolutionCompliancePct)
+}
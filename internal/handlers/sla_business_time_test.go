@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// weekdayHours returns Monday to Friday business hours
+func weekdayHours(start, end string) models.JSONBArray {
+	var hours models.JSONBArray
+	for day := 1; day <= 5; day++ {
+		hours = append(hours, map[string]interface{}{
+			"day":        float64(day),
+			"enabled":    true,
+			"start_time": start,
+			"end_time":   end,
+		})
+	}
+	return hours
+}
+
+func TestApplySLATargets_CountsBusinessMinutes(t *testing.T) {
+	settings := &models.ChatbotSettings{
+		SLA: models.SLAConfig{
+			Enabled:           true,
+			BusinessTime:      true,
+			ResponseMinutes:   15,
+			ResolutionMinutes: 120,
+			EscalationMinutes: 0,
+		},
+	}
+	schedule := bizhours.New(weekdayHours("09:00", "19:00"), time.UTC, nil)
+
+	// Friday 18:55: the timers run out on Monday morning, not over the weekend
+	start := time.Date(2026, 3, 6, 18, 55, 0, 0, time.UTC)
+	transfer := &models.AgentTransfer{}
+	applySLATargets(transfer, slaTargetsFor(settings, nil, transfer), slaClock{start: start, schedule: schedule})
+
+	require.NotNil(t, transfer.SLA.ResponseDeadline)
+	assert.Equal(t, time.Date(2026, 3, 9, 9, 10, 0, 0, time.UTC), *transfer.SLA.ResponseDeadline)
+	require.NotNil(t, transfer.SLA.ResolutionDeadline)
+	assert.Equal(t, time.Date(2026, 3, 9, 10, 55, 0, 0, time.UTC), *transfer.SLA.ResolutionDeadline)
+	assert.Nil(t, transfer.SLA.EscalationAt)
+}
+
+func TestPauseResumeSLATimers(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	settings := models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		BusinessHours:  models.BusinessHoursConfig{Enabled: true, Hours: weekdayHours("09:00", "17:00")},
+		SLA:            models.SLAConfig{Enabled: true, BusinessTime: true, ResponseMinutes: 30},
+	}
+	require.NoError(t, app.DB.Create(&settings).Error)
+
+	// Transferred Friday 16:50 with a deadline of Monday 09:20
+	transferredAt := time.Date(2026, 3, 6, 16, 50, 0, 0, time.UTC)
+	deadline := time.Date(2026, 3, 9, 9, 20, 0, 0, time.UTC)
+	transfer := models.AgentTransfer{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		Source:          models.TransferSourceManual,
+		TransferredAt:   transferredAt,
+		SLA:             models.SLATracking{ResponseDeadline: &deadline, BusinessTime: true},
+	}
+	require.NoError(t, app.DB.Create(&transfer).Error)
+
+	// The business closes for Monday after the transfer was created
+	calendar := models.HolidayCalendar{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: org.ID, Name: "Holidays", IsActive: true}
+	require.NoError(t, app.DB.Create(&calendar).Error)
+	require.NoError(t, app.DB.Create(&models.Holiday{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		CalendarID:     calendar.ID,
+		Name:           "Closed",
+		StartDate:      "2026-03-09",
+		EndDate:        "2026-03-09",
+	}).Error)
+
+	p := NewSLAProcessor(app, time.Minute)
+
+	// Saturday: the timer is paused and no breach is recorded
+	p.pauseResumeSLATimers(org.ID, settings, time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC))
+	var stored models.AgentTransfer
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	require.NotNil(t, stored.SLA.PausedAt)
+
+	// Tuesday morning: the timer resumes with the Monday holiday left out
+	p.pauseResumeSLATimers(org.ID, settings, time.Date(2026, 3, 10, 9, 5, 0, 0, time.UTC))
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.Nil(t, stored.SLA.PausedAt)
+	require.NotNil(t, stored.SLA.ResponseDeadline)
+	assert.True(t, stored.SLA.ResponseDeadline.Equal(time.Date(2026, 3, 10, 9, 20, 0, 0, time.UTC)))
+}
+
+func TestPauseResumeSLATimers_PastHoliday(t *testing.T) {
+	app := newProcessorTestApp(t)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	settings := models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		BusinessHours:  models.BusinessHoursConfig{Enabled: true, Hours: weekdayHours("09:00", "17:00")},
+		SLA:            models.SLAConfig{Enabled: true, BusinessTime: true, ResponseMinutes: 30},
+	}
+	require.NoError(t, app.DB.Create(&settings).Error)
+
+	// Transferred Friday 16:50, long before today, ahead of a Monday and
+	// Tuesday closure
+	transferredAt := time.Date(2024, 3, 1, 16, 50, 0, 0, time.UTC)
+	deadline := time.Date(2024, 3, 6, 9, 20, 0, 0, time.UTC)
+	transfer := models.AgentTransfer{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		Source:          models.TransferSourceManual,
+		TransferredAt:   transferredAt,
+		SLA:             models.SLATracking{StartedAt: &transferredAt, ResponseDeadline: &deadline, BusinessTime: true},
+	}
+	require.NoError(t, app.DB.Create(&transfer).Error)
+
+	calendar := models.HolidayCalendar{BaseModel: models.BaseModel{ID: uuid.New()}, OrganizationID: org.ID, Name: "Holidays", IsActive: true}
+	require.NoError(t, app.DB.Create(&calendar).Error)
+	require.NoError(t, app.DB.Create(&models.Holiday{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		CalendarID:     calendar.ID,
+		Name:           "Closed",
+		StartDate:      "2024-03-04",
+		EndDate:        "2024-03-05",
+	}).Error)
+
+	p := NewSLAProcessor(app, time.Minute)
+	p.pauseResumeSLATimers(org.ID, settings, time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC))
+
+	// Wednesday morning: the closure is still left out although it ended
+	// well before today
+	p.pauseResumeSLATimers(org.ID, settings, time.Date(2024, 3, 6, 9, 5, 0, 0, time.UTC))
+	var stored models.AgentTransfer
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.Nil(t, stored.SLA.PausedAt)
+	require.NotNil(t, stored.SLA.ResponseDeadline)
+	assert.True(t, stored.SLA.ResponseDeadline.Equal(deadline))
+
+	// Escalation deadlines use the same schedule
+	clock := app.transferSLAClock(&stored, &settings)
+	require.NotNil(t, clock.schedule)
+	assert.False(t, clock.schedule.IsOpen(time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/slautil"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// SLAPolicyRequest represents the request body for creating/updating an SLA
+// policy
+type SLAPolicyRequest struct {
+	Name           string                     `json:"name"`
+	Description    string                     `json:"description"`
+	IsActive       *bool                      `json:"is_active"`
+	Position       int                        `json:"position"`
+	Conditions     models.SLAPolicyConditions `json:"conditions"`
+	Targets        models.SLATargetSet        `json:"targets"`
+	WarningPercent int                        `json:"warning_percent"`
+	Escalations    models.SLAEscalationChain  `json:"escalations"`
+}
+
+// ListSLAPolicies returns the organization's SLA policies in evaluation order
+func (a *App) ListSLAPolicies(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	var policies []models.SLAPolicy
+	if err := a.DB.Where("organization_id = ?", orgID).Order("position ASC, created_at ASC").Find(&policies).Error; err != nil {
+		a.Log.Error("Failed to list SLA policies", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list SLA policies", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"policies": policies,
+	})
+}
+
+// GetSLAPolicy returns an SLA policy
+func (a *App) GetSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionRead); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	policy, err := findByIDAndOrg[models.SLAPolicy](a.DB, r, id, orgID, "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	return r.SendEnvelope(policy)
+}
+
+// CreateSLAPolicy creates an SLA policy
+func (a *App) CreateSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	var req SLAPolicyRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	policy := models.SLAPolicy{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: orgID,
+		IsActive:       true,
+	}
+	if msg := applySLAPolicyRequest(&policy, &req); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	if err := a.DB.Create(&policy).Error; err != nil {
+		a.Log.Error("Failed to create SLA policy", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create SLA policy", nil, "")
+	}
+
+	return r.SendEnvelope(policy)
+}
+
+// UpdateSLAPolicy updates an SLA policy. Transfers already under the policy
+// keep their deadlines; the new escalation chain applies from their next
+// escalation on.
+func (a *App) UpdateSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	var req SLAPolicyRequest
+	if err := a.decodeRequest(r, &req); err != nil {
+		return nil
+	}
+
+	policy, err := findByIDAndOrg[models.SLAPolicy](a.DB, r, id, orgID, "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	if msg := applySLAPolicyRequest(policy, &req); msg != "" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, msg, nil, "")
+	}
+
+	if err := a.DB.Model(policy).Select(
+		"name", "description", "is_active", "position", "conditions", "targets", "warning_percent", "escalations",
+	).Updates(policy).Error; err != nil {
+		a.Log.Error("Failed to update SLA policy", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update SLA policy", nil, "")
+	}
+
+	return r.SendEnvelope(policy)
+}
+
+// DeleteSLAPolicy deletes an SLA policy. New transfers stop matching it;
+// transfers already under it finish with its targets.
+func (a *App) DeleteSLAPolicy(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceSettingsChatbot, models.ActionWrite); err != nil {
+		return nil
+	}
+
+	id, err := parsePathUUID(r, "id", "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	policy, err := findByIDAndOrg[models.SLAPolicy](a.DB, r, id, orgID, "SLA policy")
+	if err != nil {
+		return nil
+	}
+
+	if err := a.DB.Delete(policy).Error; err != nil {
+		a.Log.Error("Failed to delete SLA policy", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to delete SLA policy", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]string{"message": "SLA policy deleted"})
+}
+
+// applySLAPolicyRequest validates req and copies it onto policy. It returns a
+// validation message, or "" when the request is valid.
+func applySLAPolicyRequest(policy *models.SLAPolicy, req *SLAPolicyRequest) string {
+	if len(strings.TrimSpace(req.Name)) > 100 {
+		return "name must be at most 100 characters"
+	}
+
+	conditions := req.Conditions
+	conditions.TeamIDs = nonEmptyStrings(conditions.TeamIDs)
+	conditions.WhatsAppAccounts = nonEmptyStrings(conditions.WhatsAppAccounts)
+	conditions.ContactTags = nonEmptyStrings(conditions.ContactTags)
+	conditions.ContactAttribute = strings.TrimSpace(conditions.ContactAttribute)
+	conditions.ContactAttributeValues = nonEmptyStrings(conditions.ContactAttributeValues)
+
+	escalations := models.SLAEscalationChain{}
+	for _, level := range req.Escalations {
+		level.NotifyIDs = nonEmptyStrings(level.NotifyIDs)
+		escalations = append(escalations, level)
+	}
+
+	candidate := models.SLAPolicy{
+		Name:           strings.TrimSpace(req.Name),
+		Description:    req.Description,
+		IsActive:       policy.IsActive,
+		Position:       req.Position,
+		Conditions:     conditions,
+		Targets:        req.Targets,
+		WarningPercent: req.WarningPercent,
+		Escalations:    escalations,
+	}
+	if req.IsActive != nil {
+		candidate.IsActive = *req.IsActive
+	}
+	if err := slautil.Validate(&candidate); err != nil {
+		return err.Error()
+	}
+
+	policy.Name = candidate.Name
+	policy.Description = candidate.Description
+	policy.IsActive = candidate.IsActive
+	policy.Position = candidate.Position
+	policy.Conditions = candidate.Conditions
+	policy.Targets = candidate.Targets
+	policy.WarningPercent = candidate.WarningPercent
+	policy.Escalations = candidate.Escalations
+	return ""
+}
@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestApp_CreateSLAPolicy_Validates(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+
+	cases := map[string]map[string]any{
+		"at least one priority": {"name": "VIP"},
+		"invalid priority": {
+			"name":    "VIP",
+			"targets": map[string]any{"critical": map[string]any{"response_minutes": 5}},
+		},
+		"after the previous level": {
+			"name":        "VIP",
+			"targets":     map[string]any{"normal": map[string]any{"response_minutes": 5}},
+			"escalations": []map[string]any{{"after_minutes": 10}, {"after_minutes": 5}},
+		},
+	}
+	for want, body := range cases {
+		req := testutil.NewJSONRequest(t, body)
+		testutil.SetAuthContext(req, org.ID, admin.ID)
+		require.NoError(t, app.CreateSLAPolicy(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, want)
+	}
+}
+
+func TestApp_CreateAgentTransfer_AppliesMatchingSLAPolicy(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	require.NoError(t, app.DB.Create(&models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		SLA:            models.SLAConfig{Enabled: true, ResponseMinutes: 60},
+	}).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"name":       "VIP customers",
+		"conditions": map[string]any{"contact_tags": []string{"vip"}},
+		"targets": map[string]any{
+			"normal": map[string]any{"response_minutes": 15},
+			"urgent": map[string]any{"response_minutes": 5},
+		},
+		"warning_percent": 80,
+	})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	require.NoError(t, app.CreateSLAPolicy(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+	var policy models.SLAPolicy
+	testutil.ParseEnvelopeResponse(t, req, &policy)
+
+	vip := testutil.CreateTestContact(t, app.DB, org.ID)
+	require.NoError(t, app.DB.Model(vip).Update("tags", models.JSONBArray{"VIP"}).Error)
+
+	before := time.Now()
+	transfer := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+		"contact_id":       vip.ID.String(),
+		"whatsapp_account": account.Name,
+		"priority":         "urgent",
+	})
+	assert.Equal(t, models.SLAPriorityUrgent, transfer.Priority)
+	require.NotNil(t, transfer.SLAPolicyID)
+	assert.Equal(t, policy.ID.String(), *transfer.SLAPolicyID)
+	require.NotNil(t, transfer.SLAResponseDeadline)
+	deadline, err := time.Parse(time.RFC3339, *transfer.SLAResponseDeadline)
+	require.NoError(t, err)
+	assert.WithinDuration(t, before.Add(5*time.Minute), deadline, 5*time.Second)
+
+	var stored models.AgentTransfer
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	require.NotNil(t, stored.SLA.WarningAt)
+	assert.WithinDuration(t, before.Add(4*time.Minute), *stored.SLA.WarningAt, 5*time.Second)
+
+	// Contacts the policy doesn't match use the chatbot settings
+	other := createTransferViaAPI(t, app, org.ID, admin.ID, map[string]any{
+		"contact_id":       testutil.CreateTestContact(t, app.DB, org.ID).ID.String(),
+		"whatsapp_account": account.Name,
+	})
+	assert.Equal(t, models.SLAPriorityNormal, other.Priority)
+	assert.Nil(t, other.SLAPolicyID)
+}
+
+func TestApp_CreateAgentTransfer_RejectsInvalidPriority(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"contact_id":       contact.ID.String(),
+		"whatsapp_account": account.Name,
+		"priority":         "critical",
+	})
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	require.NoError(t, app.CreateAgentTransfer(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid priority")
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLATargetsFor_PolicyPriorityAndChain(t *testing.T) {
+	settings := &models.ChatbotSettings{
+		SLA: models.SLAConfig{ResponseMinutes: 60, ResolutionMinutes: 240, EscalationMinutes: 90},
+	}
+	policy := &models.SLAPolicy{
+		Targets: models.SLATargetSet{
+			models.SLAPriorityNormal: {ResponseMinutes: 20, ResolutionMinutes: 120},
+			models.SLAPriorityUrgent: {ResponseMinutes: 5, ResolutionMinutes: 30},
+		},
+		WarningPercent: 80,
+		Escalations: models.SLAEscalationChain{
+			{AfterMinutes: 10},
+			{AfterMinutes: 25},
+		},
+	}
+
+	urgent := &models.AgentTransfer{Priority: models.SLAPriorityUrgent}
+	assert.Equal(t, slaTargets{ResponseMinutes: 5, ResolutionMinutes: 30, EscalationMinutes: 10, WarningMinutes: 4},
+		slaTargetsFor(settings, policy, urgent))
+
+	// Unlisted priorities use the normal targets; the escalation follows the level reached
+	high := &models.AgentTransfer{Priority: models.SLAPriorityHigh}
+	high.SLA.EscalationLevel = 1
+	assert.Equal(t, slaTargets{ResponseMinutes: 20, ResolutionMinutes: 120, EscalationMinutes: 25, WarningMinutes: 16},
+		slaTargetsFor(settings, policy, high))
+	high.SLA.EscalationLevel = 2
+	assert.Equal(t, 0, slaTargetsFor(settings, policy, high).EscalationMinutes)
+
+	// Without a policy the chatbot settings apply
+	assert.Equal(t, slaTargets{ResponseMinutes: 60, ResolutionMinutes: 240, EscalationMinutes: 90},
+		slaTargetsFor(settings, nil, urgent))
+}
+
+func TestEscalateTransfers_FollowsPolicyChain(t *testing.T) {
+	app := newProcessorTestApp(t)
+	app.WSHub = websocket.NewHub(app.Log)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	settings := models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		SLA:            models.SLAConfig{Enabled: true},
+	}
+	require.NoError(t, app.DB.Create(&settings).Error)
+
+	policy := models.SLAPolicy{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		Name:           "VIP",
+		IsActive:       true,
+		Targets:        models.SLATargetSet{models.SLAPriorityNormal: {ResponseMinutes: 5}},
+		Escalations: models.SLAEscalationChain{
+			{AfterMinutes: 5, NotifyIDs: []string{uuid.NewString()}},
+			{AfterMinutes: 15, NotifyIDs: []string{uuid.NewString()}},
+			{AfterMinutes: 30, NotifyIDs: []string{uuid.NewString()}},
+		},
+	}
+	require.NoError(t, app.DB.Create(&policy).Error)
+
+	start := time.Now().Add(-20 * time.Minute)
+	escalationAt := start.Add(5 * time.Minute)
+	transfer := models.AgentTransfer{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		Source:          models.TransferSourceManual,
+		TransferredAt:   start,
+		SLA:             models.SLATracking{PolicyID: &policy.ID, StartedAt: &start, EscalationAt: &escalationAt},
+	}
+	require.NoError(t, app.DB.Create(&transfer).Error)
+
+	p := NewSLAProcessor(app, time.Minute)
+	var stored models.AgentTransfer
+
+	// Level 1 schedules level 2 at 15 minutes, which is already due
+	p.escalateTransfers(org.ID, settings, time.Now())
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.Equal(t, 1, stored.SLA.EscalationLevel)
+	require.NotNil(t, stored.SLA.EscalationAt)
+	assert.WithinDuration(t, start.Add(15*time.Minute), *stored.SLA.EscalationAt, time.Second)
+
+	// Level 2 schedules level 3 at 30 minutes, in the future
+	p.escalateTransfers(org.ID, settings, time.Now())
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.Equal(t, 2, stored.SLA.EscalationLevel)
+	require.NotNil(t, stored.SLA.EscalationAt)
+	assert.WithinDuration(t, start.Add(30*time.Minute), *stored.SLA.EscalationAt, time.Second)
+
+	p.escalateTransfers(org.ID, settings, time.Now())
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.Equal(t, 2, stored.SLA.EscalationLevel)
+
+	// Past the last level the chain ends
+	p.escalateTransfers(org.ID, settings, start.Add(31*time.Minute))
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.Equal(t, 3, stored.SLA.EscalationLevel)
+	assert.Nil(t, stored.SLA.EscalationAt)
+}
+
+func TestWarnApproachingSLA_WarnsOnce(t *testing.T) {
+	app := newProcessorTestApp(t)
+	app.WSHub = websocket.NewHub(app.Log)
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	warningAt := time.Now().Add(-time.Minute)
+	transfer := models.AgentTransfer{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusActive,
+		Source:          models.TransferSourceManual,
+		TransferredAt:   time.Now().Add(-10 * time.Minute),
+		SLA:             models.SLATracking{WarningAt: &warningAt},
+	}
+	require.NoError(t, app.DB.Create(&transfer).Error)
+
+	p := NewSLAProcessor(app, time.Minute)
+	p.warnApproachingSLA(org.ID, time.Now())
+
+	var stored models.AgentTransfer
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	require.NotNil(t, stored.SLA.WarnedAt)
+	warnedAt := *stored.SLA.WarnedAt
+
+	p.warnApproachingSLA(org.ID, time.Now().Add(time.Minute))
+	require.NoError(t, app.DB.First(&stored, "id = ?", transfer.ID).Error)
+	assert.True(t, stored.SLA.WarnedAt.Equal(warnedAt))
+}
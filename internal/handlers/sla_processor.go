@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/slautil"
 	"github.com/shridarpatil/whatomate/internal/websocket"
 )
 
@@ -71,22 +73,27 @@ func (p *SLAProcessor) processStaleTransfers() {
 func (p *SLAProcessor) processOrganizationSLA(settings models.ChatbotSettings, now time.Time) {
 	orgID := settings.OrganizationID
 
+	// 0. Pause or resume business-time SLA timers
+	if settings.SLA.BusinessTime {
+		p.pauseResumeSLATimers(orgID, settings, now)
+	}
+
 	// 1. Auto-close expired transfers
 	if settings.SLA.AutoCloseHours > 0 {
 		p.autoCloseExpiredTransfers(orgID, settings, now)
 	}
 
-	// 2. Escalate transfers past escalation deadline
-	if settings.SLA.EscalationMinutes > 0 {
-		p.escalateTransfers(orgID, settings, now)
-	}
+	// 2. Warn about transfers approaching their response deadline
+	p.warnApproachingSLA(orgID, now)
 
-	// 3. Mark SLA breached for transfers past response deadline
-	if settings.SLA.ResponseMinutes > 0 {
-		p.markSLABreached(orgID, settings, now)
-	}
+	// 3. Escalate transfers past escalation deadline. SLA policies may
+	// escalate even when the chatbot settings don't, so this always runs.
+	p.escalateTransfers(orgID, settings, now)
 
-	// 4. Handle client inactivity (reminders and auto-close)
+	// 4. Mark SLA breached for transfers past response deadline
+	p.markSLABreached(orgID, settings, now)
+
+	// 5. Handle client inactivity (reminders and auto-close)
 	if settings.ClientInactivity.ReminderEnabled {
 		p.processClientInactivity(orgID, settings, now)
 	}
@@ -103,6 +110,7 @@ func (p *SLAProcessor) autoCloseExpiredTransfers(orgID uuid.UUID, settings model
 		return
 	}
 
+	freedAgents := map[uuid.UUID]bool{}
 	for _, transfer := range transfers {
 		// Send auto-close message to customer if configured
 		if settings.SLA.AutoCloseMessage != "" {
@@ -127,23 +135,109 @@ func (p *SLAProcessor) autoCloseExpiredTransfers(orgID uuid.UUID, settings model
 
 		// Broadcast update
 		p.broadcastTransferUpdate(transfer, string(models.TransferStatusExpired))
+
+		if settings.Survey.OnAutoClose {
+			p.app.sendTransferSurvey(&transfer, &settings)
+		}
+
+		if transfer.AgentID != nil {
+			freedAgents[*transfer.AgentID] = true
+		}
 	}
 
 	if len(transfers) > 0 {
 		p.app.Log.Info("Auto-closed expired transfers", "count", len(transfers), "org_id", orgID)
 	}
+
+	// Agents whose conversations closed can take queued ones
+	for agentID := range freedAgents {
+		p.app.assignQueuedTransfers(orgID, agentID)
+	}
 }
 
-// escalateTransfers escalates transfers past their escalation deadline
+// pauseResumeSLATimers pauses the SLA timers of business-time transfers while
+// business hours are closed. When they reopen the deadlines are counted again
+// from the transfer time with the current business hours, so holidays added
+// in the meantime are taken into account.
+func (p *SLAProcessor) pauseResumeSLATimers(orgID uuid.UUID, settings models.ChatbotSettings, now time.Time) {
+	var transfers []models.AgentTransfer
+	if err := p.app.DB.Where(
+		"organization_id = ? AND status = ? AND sla_business_time = ?",
+		orgID, models.TransferStatusActive, true,
+	).Find(&transfers).Error; err != nil {
+		p.app.Log.Error("Failed to find business-time transfers", "error", err, "org_id", orgID)
+		return
+	}
+	policies := p.app.loadSLAPolicies(transfers)
+
+	// Transfers of the same account and team share a schedule, with the
+	// holidays since the earliest of their SLA clocks started
+	scheduleKey := func(transfer *models.AgentTransfer) string {
+		key := transfer.WhatsAppAccount
+		if transfer.TeamID != nil {
+			key += "/" + transfer.TeamID.String()
+		}
+		return key
+	}
+	since := map[string]time.Time{}
+	for i := range transfers {
+		key, start := scheduleKey(&transfers[i]), slaStart(&transfers[i])
+		if earliest, ok := since[key]; !ok || start.Before(earliest) {
+			since[key] = start
+		}
+	}
+	schedules := map[string]*bizhours.Schedule{}
+	for _, transfer := range transfers {
+		key := scheduleKey(&transfer)
+		schedule, ok := schedules[key]
+		if !ok {
+			schedule = p.app.businessScheduleSince(&settings, transfer.WhatsAppAccount, transfer.TeamID, since[key])
+			schedules[key] = schedule
+		}
+
+		open := schedule == nil || schedule.IsOpen(now)
+		switch {
+		case !open && transfer.SLA.PausedAt == nil:
+			if err := p.app.DB.Model(&transfer).Update("sla_paused_at", now).Error; err != nil {
+				p.app.Log.Error("Failed to pause SLA timer", "error", err, "transfer_id", transfer.ID)
+				continue
+			}
+			p.app.Log.Debug("SLA timer paused outside business hours", "transfer_id", transfer.ID)
+
+		case open && transfer.SLA.PausedAt != nil:
+			updates := map[string]interface{}{"sla_paused_at": nil}
+			// Without business hours the deadlines are kept as they are
+			if schedule != nil {
+				targets := slaTargetsFor(&settings, transferSLAPolicy(&transfer, policies), &transfer)
+				applySLATargets(&transfer, targets, slaClock{start: slaStart(&transfer), schedule: schedule})
+				updates["sla_response_deadline"] = transfer.SLA.ResponseDeadline
+				updates["sla_resolution_deadline"] = transfer.SLA.ResolutionDeadline
+				updates["sla_escalation_at"] = transfer.SLA.EscalationAt
+				updates["sla_warning_at"] = transfer.SLA.WarningAt
+			}
+			if err := p.app.DB.Model(&transfer).Updates(updates).Error; err != nil {
+				p.app.Log.Error("Failed to resume SLA timer", "error", err, "transfer_id", transfer.ID)
+				continue
+			}
+			p.app.Log.Debug("SLA timer resumed", "transfer_id", transfer.ID, "response_deadline", transfer.SLA.ResponseDeadline)
+		}
+	}
+}
+
+// escalateTransfers escalates transfers past their escalation deadline.
+// Transfers under an SLA policy step through its escalation chain, each level
+// with its own notify list and customer message; others escalate twice using
+// the chatbot settings.
 func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.ChatbotSettings, now time.Time) {
 	var transfers []models.AgentTransfer
 	if err := p.app.DB.Where(
-		"organization_id = ? AND status = ? AND sla_escalation_at IS NOT NULL AND sla_escalation_at < ? AND escalation_level < 2",
+		"organization_id = ? AND status = ? AND sla_escalation_at IS NOT NULL AND sla_escalation_at < ? AND (sla_policy_id IS NOT NULL OR escalation_level < 2) AND sla_paused_at IS NULL",
 		orgID, models.TransferStatusActive, now,
 	).Find(&transfers).Error; err != nil {
 		p.app.Log.Error("Failed to find transfers for escalation", "error", err, "org_id", orgID)
 		return
 	}
+	policies := p.app.loadSLAPolicies(transfers)
 
 	for _, transfer := range transfers {
 		newLevel := transfer.SLA.EscalationLevel + 1
@@ -154,6 +248,27 @@ func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.Chatbo
 			"escalated_at":     now,
 		}
 
+		notifyIDs := []string(settings.SLA.EscalationNotifyIDs)
+		customerMessage := ""
+		if newLevel == 1 {
+			customerMessage = settings.SLA.WarningMessage
+		}
+		if transfer.SLA.PolicyID != nil {
+			policy := transferSLAPolicy(&transfer, policies)
+			if policy == nil || transfer.SLA.EscalationLevel >= len(policy.Escalations) {
+				// The chain was shortened or its policy removed since the deadline was set
+				p.app.DB.Model(&transfer).Update("sla_escalation_at", nil)
+				continue
+			}
+			level := policy.Escalations[transfer.SLA.EscalationLevel]
+			notifyIDs, customerMessage = level.NotifyIDs, level.CustomerMessage
+
+			// Schedule the next level of the chain, if any
+			transfer.SLA.EscalationLevel = newLevel
+			clock := p.app.transferSLAClock(&transfer, &settings)
+			updates["sla_escalation_at"] = clock.after(slaTargetsFor(&settings, policy, &transfer).EscalationMinutes)
+		}
+
 		// If not yet breached and past response deadline, mark as breached
 		if !transfer.SLA.Breached && transfer.SLA.ResponseDeadline != nil && now.After(*transfer.SLA.ResponseDeadline) {
 			updates["sla_breached"] = true
@@ -173,14 +288,14 @@ func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.Chatbo
 		)
 
 		// Send notification to escalation contacts
-		p.notifyEscalation(transfer, settings, newLevel)
+		p.notifyEscalation(transfer, notifyIDs, newLevel)
 
 		// Broadcast update
 		p.broadcastTransferUpdate(transfer, "escalated")
 
 		// Send warning message to customer if configured
-		if newLevel == 1 && settings.SLA.WarningMessage != "" {
-			p.sendSLAWarningToCustomer(transfer, settings.SLA.WarningMessage)
+		if customerMessage != "" {
+			p.sendSLAWarningToCustomer(transfer, customerMessage)
 		}
 	}
 
@@ -189,10 +304,36 @@ func (p *SLAProcessor) escalateTransfers(orgID uuid.UUID, settings models.Chatbo
 	}
 }
 
+// warnApproachingSLA flags transfers not yet picked up that have used the
+// warning share of their SLA policy's response time and notifies the
+// organization once per transfer
+func (p *SLAProcessor) warnApproachingSLA(orgID uuid.UUID, now time.Time) {
+	var transfers []models.AgentTransfer
+	if err := p.app.DB.Where(
+		"organization_id = ? AND status = ? AND sla_warning_at IS NOT NULL AND sla_warning_at < ? AND sla_warned_at IS NULL AND picked_up_at IS NULL AND sla_paused_at IS NULL",
+		orgID, models.TransferStatusActive, now,
+	).Find(&transfers).Error; err != nil {
+		p.app.Log.Error("Failed to find transfers for SLA warning", "error", err, "org_id", orgID)
+		return
+	}
+
+	for _, transfer := range transfers {
+		if err := p.app.DB.Model(&transfer).Update("sla_warned_at", now).Error; err != nil {
+			p.app.Log.Error("Failed to mark SLA warning", "error", err, "transfer_id", transfer.ID)
+			continue
+		}
+		p.broadcastTransferUpdate(transfer, "sla_warning")
+	}
+
+	if len(transfers) > 0 {
+		p.app.Log.Info("Warned about transfers approaching SLA breach", "count", len(transfers), "org_id", orgID)
+	}
+}
+
 // markSLABreached marks transfers as SLA breached when past response deadline
 func (p *SLAProcessor) markSLABreached(orgID uuid.UUID, settings models.ChatbotSettings, now time.Time) {
 	result := p.app.DB.Model(&models.AgentTransfer{}).Where(
-		"organization_id = ? AND status = ? AND sla_breached = ? AND sla_response_deadline IS NOT NULL AND sla_response_deadline < ? AND agent_id IS NULL",
+		"organization_id = ? AND status = ? AND sla_breached = ? AND sla_response_deadline IS NOT NULL AND sla_response_deadline < ? AND agent_id IS NULL AND sla_paused_at IS NULL",
 		orgID, models.TransferStatusActive, false, now,
 	).Updates(map[string]interface{}{
 		"sla_breached":    true,
@@ -210,8 +351,8 @@ func (p *SLAProcessor) markSLABreached(orgID uuid.UUID, settings models.ChatbotS
 }
 
 // notifyEscalation sends notifications to escalation contacts via WebSocket broadcast
-func (p *SLAProcessor) notifyEscalation(transfer models.AgentTransfer, settings models.ChatbotSettings, level int) {
-	if len(settings.SLA.EscalationNotifyIDs) == 0 {
+func (p *SLAProcessor) notifyEscalation(transfer models.AgentTransfer, notifyIDs []string, level int) {
+	if len(notifyIDs) == 0 {
 		return
 	}
 
@@ -241,14 +382,14 @@ func (p *SLAProcessor) notifyEscalation(transfer models.AgentTransfer, settings
 			"level_name":            levelName,
 			"waiting_since":         transfer.TransferredAt.Format(time.RFC3339),
 			"team_id":               transfer.TeamID,
-			"escalation_notify_ids": settings.SLA.EscalationNotifyIDs,
+			"escalation_notify_ids": notifyIDs,
 		},
 	})
 
 	p.app.Log.Info("Escalation notification sent",
 		"transfer_id", transfer.ID,
 		"level", level,
-		"notify_count", len(settings.SLA.EscalationNotifyIDs),
+		"notify_count", len(notifyIDs),
 	)
 }
 
@@ -342,7 +483,9 @@ func (p *SLAProcessor) broadcastTransferUpdate(transfer models.AgentTransfer, ev
 	})
 }
 
-// SetSLADeadlines sets SLA deadlines on a new transfer based on settings
+// SetSLADeadlines sets SLA deadlines on a new transfer. The targets come from
+// the first SLA policy matching the transfer, or from the chatbot settings
+// when none does.
 func (a *App) SetSLADeadlines(transfer *models.AgentTransfer, settings *models.ChatbotSettings) {
 	if !settings.SLA.Enabled {
 		return
@@ -350,23 +493,26 @@ func (a *App) SetSLADeadlines(transfer *models.AgentTransfer, settings *models.C
 
 	now := time.Now()
 
-	// Response deadline (time to pick up)
-	if settings.SLA.ResponseMinutes > 0 {
-		deadline := now.Add(time.Duration(settings.SLA.ResponseMinutes) * time.Minute)
-		transfer.SLA.ResponseDeadline = &deadline
+	// Outside business hours the SLA clock starts when the business next opens
+	schedule := a.businessSchedule(settings, transfer.WhatsAppAccount, transfer.TeamID)
+	if schedule != nil {
+		if open := schedule.NextOpen(now); !open.IsZero() {
+			now = open
+		}
 	}
+	transfer.SLA.StartedAt = &now
 
-	// Resolution deadline
-	if settings.SLA.ResolutionMinutes > 0 {
-		deadline := now.Add(time.Duration(settings.SLA.ResolutionMinutes) * time.Minute)
-		transfer.SLA.ResolutionDeadline = &deadline
+	clock := slaClock{start: now}
+	if settings.SLA.BusinessTime && schedule != nil {
+		transfer.SLA.BusinessTime = true
+		clock.schedule = schedule
 	}
 
-	// Escalation deadline
-	if settings.SLA.EscalationMinutes > 0 {
-		deadline := now.Add(time.Duration(settings.SLA.EscalationMinutes) * time.Minute)
-		transfer.SLA.EscalationAt = &deadline
+	policy := a.selectSLAPolicy(transfer)
+	if policy != nil {
+		transfer.SLA.PolicyID = &policy.ID
 	}
+	applySLATargets(transfer, slaTargetsFor(settings, policy, transfer), clock)
 
 	// Expiry deadline (auto-close)
 	if settings.SLA.AutoCloseHours > 0 {
@@ -376,12 +522,159 @@ func (a *App) SetSLADeadlines(transfer *models.AgentTransfer, settings *models.C
 
 	a.Log.Debug("SLA deadlines set",
 		"transfer_id", transfer.ID,
+		"policy_id", transfer.SLA.PolicyID,
+		"priority", transfer.Priority,
 		"response_deadline", transfer.SLA.ResponseDeadline,
 		"escalation_at", transfer.SLA.EscalationAt,
 		"expires_at", transfer.SLA.ExpiresAt,
+		"business_time", transfer.SLA.BusinessTime,
 	)
 }
 
+// selectSLAPolicy returns the active SLA policy that applies to a transfer,
+// or nil when none matches
+func (a *App) selectSLAPolicy(transfer *models.AgentTransfer) *models.SLAPolicy {
+	var policies []models.SLAPolicy
+	if err := a.DB.Where("organization_id = ? AND is_active = ?", transfer.OrganizationID, true).
+		Order("position ASC, created_at ASC").
+		Find(&policies).Error; err != nil {
+		a.Log.Error("Failed to load SLA policies", "error", err, "org_id", transfer.OrganizationID)
+		return nil
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	var contact *models.Contact
+	var c models.Contact
+	if a.DB.Where("id = ?", transfer.ContactID).First(&c).Error == nil {
+		contact = &c
+	}
+	return slautil.Select(policies, transfer, contact)
+}
+
+// loadSLAPolicies returns the SLA policies applied to the given transfers by
+// ID. Deleted policies are included, as a transfer keeps the policy it
+// started with.
+func (a *App) loadSLAPolicies(transfers []models.AgentTransfer) map[uuid.UUID]*models.SLAPolicy {
+	policies := map[uuid.UUID]*models.SLAPolicy{}
+	var ids []uuid.UUID
+	for _, t := range transfers {
+		if t.SLA.PolicyID != nil {
+			ids = append(ids, *t.SLA.PolicyID)
+		}
+	}
+	if len(ids) == 0 {
+		return policies
+	}
+
+	var list []models.SLAPolicy
+	if err := a.DB.Unscoped().Where("id IN ?", ids).Find(&list).Error; err != nil {
+		a.Log.Error("Failed to load SLA policies", "error", err)
+		return policies
+	}
+	for i := range list {
+		policies[list[i].ID] = &list[i]
+	}
+	return policies
+}
+
+// transferSLAPolicy returns the policy applied to a transfer from policies,
+// nil when it has none
+func transferSLAPolicy(transfer *models.AgentTransfer, policies map[uuid.UUID]*models.SLAPolicy) *models.SLAPolicy {
+	if transfer.SLA.PolicyID == nil {
+		return nil
+	}
+	return policies[*transfer.SLA.PolicyID]
+}
+
+// slaTargets are the SLA minutes applied to a transfer, counted from the
+// start of its SLA clock
+type slaTargets struct {
+	ResponseMinutes   int
+	ResolutionMinutes int
+	EscalationMinutes int // Next escalation
+	WarningMinutes    int
+}
+
+// slaTargetsFor returns a transfer's targets for its priority under policy,
+// or the chatbot settings' targets when policy is nil. The escalation target
+// is that of the transfer's next escalation level.
+func slaTargetsFor(settings *models.ChatbotSettings, policy *models.SLAPolicy, transfer *models.AgentTransfer) slaTargets {
+	if policy == nil {
+		targets := slaTargets{
+			ResponseMinutes:   settings.SLA.ResponseMinutes,
+			ResolutionMinutes: settings.SLA.ResolutionMinutes,
+		}
+		if transfer.SLA.EscalationLevel < 2 {
+			targets.EscalationMinutes = settings.SLA.EscalationMinutes
+		}
+		return targets
+	}
+
+	forPriority := policy.Targets.For(transfer.Priority)
+	targets := slaTargets{
+		ResponseMinutes:   forPriority.ResponseMinutes,
+		ResolutionMinutes: forPriority.ResolutionMinutes,
+		WarningMinutes:    forPriority.ResponseMinutes * policy.WarningPercent / 100,
+	}
+	if level := transfer.SLA.EscalationLevel; level < len(policy.Escalations) {
+		targets.EscalationMinutes = policy.Escalations[level].AfterMinutes
+	}
+	return targets
+}
+
+// slaClock counts SLA minutes from start, within business hours only when
+// it has a schedule
+type slaClock struct {
+	start    time.Time
+	schedule *bizhours.Schedule
+}
+
+// after returns when the given minutes run out. It returns nil for minutes
+// that are not positive or more than a year of business openings away.
+func (c slaClock) after(minutes int) *time.Time {
+	if minutes <= 0 {
+		return nil
+	}
+	d := time.Duration(minutes) * time.Minute
+	if c.schedule == nil {
+		t := c.start.Add(d)
+		return &t
+	}
+	t := c.schedule.Add(c.start, d)
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// slaStart returns when a transfer's SLA clock started
+func slaStart(transfer *models.AgentTransfer) time.Time {
+	if transfer.SLA.StartedAt != nil {
+		return *transfer.SLA.StartedAt
+	}
+	return transfer.TransferredAt
+}
+
+// transferSLAClock returns the SLA clock of a stored transfer
+func (a *App) transferSLAClock(transfer *models.AgentTransfer, settings *models.ChatbotSettings) slaClock {
+	clock := slaClock{start: slaStart(transfer)}
+	if transfer.SLA.BusinessTime {
+		clock.schedule = a.businessScheduleSince(settings, transfer.WhatsAppAccount, transfer.TeamID, clock.start)
+	}
+	return clock
+}
+
+// applySLATargets sets the response, resolution, escalation and warning
+// deadlines of a transfer from its targets
+func applySLATargets(transfer *models.AgentTransfer, targets slaTargets, clock slaClock) {
+	transfer.SLA.ResponseDeadline = clock.after(targets.ResponseMinutes)
+	transfer.SLA.ResolutionDeadline = clock.after(targets.ResolutionMinutes)
+	transfer.SLA.EscalationAt = clock.after(targets.EscalationMinutes)
+	transfer.SLA.WarningAt = clock.after(targets.WarningMinutes)
+}
+
 // UpdateSLAOnPickup updates SLA tracking when a transfer is picked up
 func (a *App) UpdateSLAOnPickup(transfer *models.AgentTransfer) {
 	now := time.Now()
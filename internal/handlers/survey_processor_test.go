@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSurveyTest creates an organization with surveys enabled and a closed
+// transfer handled by an agent
+func setupSurveyTest(t *testing.T, survey models.SurveyConfig) (*App, *models.WhatsAppAccount, *models.Contact, *models.AgentTransfer, *models.ChatbotSettings) {
+	t.Helper()
+
+	app := newProcessorTestApp(t)
+	if app.Redis == nil {
+		t.Skip("TEST_REDIS_URL not set, skipping test")
+	}
+	org, account := createProcessorTestOrg(t, app)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	agent := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	survey.Enabled = true
+	survey.ExpiryHours = 24
+	settings := &models.ChatbotSettings{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		Survey:         survey,
+	}
+	require.NoError(t, app.DB.Create(settings).Error)
+
+	now := time.Now()
+	transfer := &models.AgentTransfer{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: account.Name,
+		PhoneNumber:     contact.PhoneNumber,
+		Status:          models.TransferStatusResumed,
+		Source:          models.TransferSourceManual,
+		AgentID:         &agent.ID,
+		TransferredAt:   now.Add(-time.Hour),
+		ResumedAt:       &now,
+	}
+	require.NoError(t, app.DB.Create(transfer).Error)
+
+	return app, account, contact, transfer, settings
+}
+
+func TestHandleSurveyReply_ScoreThenComment(t *testing.T) {
+	app, account, contact, transfer, settings := setupSurveyTest(t, models.SurveyConfig{
+		Type:            models.SurveyTypeCSAT,
+		Channel:         models.SurveyChannelButtons,
+		Question:        "How did we do?",
+		CommentPrompt:   "Anything we could do better?",
+		ThankYouMessage: "Thanks for your feedback!",
+	})
+
+	app.sendTransferSurvey(transfer, settings)
+	// A transfer is surveyed once
+	app.sendTransferSurvey(transfer, settings)
+
+	var surveys []models.SurveyResponse
+	require.NoError(t, app.DB.Where("transfer_id = ?", transfer.ID).Find(&surveys).Error)
+	require.Len(t, surveys, 1)
+	assert.Equal(t, models.SurveyStatusSent, surveys[0].Status)
+	assert.Equal(t, transfer.AgentID, surveys[0].AgentID)
+
+	// Messages that aren't scores pass through
+	assert.False(t, app.handleSurveyReply(account, contact, "text", "hello", "", nil))
+
+	assert.True(t, app.handleSurveyReply(account, contact, "button_reply", "Good", "survey_score_4", nil))
+	var stored models.SurveyResponse
+	require.NoError(t, app.DB.First(&stored, "id = ?", surveys[0].ID).Error)
+	assert.Equal(t, models.SurveyStatusScored, stored.Status)
+	require.NotNil(t, stored.Score)
+	assert.Equal(t, 4, *stored.Score)
+
+	assert.True(t, app.handleSurveyReply(account, contact, "text", "Quick and friendly", "", nil))
+	require.NoError(t, app.DB.First(&stored, "id = ?", surveys[0].ID).Error)
+	assert.Equal(t, models.SurveyStatusCompleted, stored.Status)
+	assert.Equal(t, 4, *stored.Score)
+	assert.Equal(t, "Quick and friendly", stored.Comment)
+	assert.NotNil(t, stored.CompletedAt)
+
+	// Once answered, messages reach the chatbot again
+	assert.False(t, app.handleSurveyReply(account, contact, "text", "5", "", nil))
+}
+
+func TestHandleSurveyReply_CommentWindowPassed(t *testing.T) {
+	app, account, contact, transfer, settings := setupSurveyTest(t, models.SurveyConfig{
+		Type:          models.SurveyTypeCSAT,
+		Channel:       models.SurveyChannelButtons,
+		Question:      "How did we do?",
+		CommentPrompt: "Anything we could do better?",
+	})
+
+	app.sendTransferSurvey(transfer, settings)
+	assert.True(t, app.handleSurveyReply(account, contact, "button_reply", "Good", "survey_score_4", nil))
+
+	var survey models.SurveyResponse
+	require.NoError(t, app.DB.First(&survey, "transfer_id = ?", transfer.ID).Error)
+	require.Equal(t, models.SurveyStatusScored, survey.Status)
+	assert.True(t, survey.ExpiresAt.Before(time.Now().Add(time.Hour)))
+
+	// The customer comes back hours later with a new question
+	require.NoError(t, app.DB.Model(&survey).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.False(t, app.handleSurveyReply(account, contact, "text", "Where is my order?", "", nil))
+
+	require.NoError(t, app.DB.First(&survey, "id = ?", survey.ID).Error)
+	assert.Equal(t, models.SurveyStatusCompleted, survey.Status)
+	assert.Equal(t, 4, *survey.Score)
+	assert.Empty(t, survey.Comment)
+}
+
+func TestHandleSurveyReply_FlowAnswer(t *testing.T) {
+	app, account, contact, transfer, settings := setupSurveyTest(t, models.SurveyConfig{
+		Type:     models.SurveyTypeNPS,
+		Channel:  models.SurveyChannelFlow,
+		Question: "How likely are you to recommend us?",
+		FlowID:   "1234567890",
+	})
+
+	app.sendTransferSurvey(transfer, settings)
+	var survey models.SurveyResponse
+	require.NoError(t, app.DB.First(&survey, "transfer_id = ?", transfer.ID).Error)
+
+	// Responses from other flows are not survey answers
+	assert.False(t, app.handleSurveyReply(account, contact, "nfm_reply", "Sent", "", map[string]any{
+		"flow_token": "other", "score": "9",
+	}))
+
+	assert.True(t, app.handleSurveyReply(account, contact, "nfm_reply", "Sent", "", map[string]any{
+		"flow_token": "survey_" + survey.ID.String(), "score": "9", "comment": "Great support",
+	}))
+	require.NoError(t, app.DB.First(&survey, "id = ?", survey.ID).Error)
+	assert.Equal(t, models.SurveyStatusCompleted, survey.Status)
+	require.NotNil(t, survey.Score)
+	assert.Equal(t, 9, *survey.Score)
+	assert.Equal(t, "Great support", survey.Comment)
+}
+
+func TestSendTransferSurvey_SkipsUnhandledTransfers(t *testing.T) {
+	app, _, _, transfer, settings := setupSurveyTest(t, models.SurveyConfig{
+		Type:     models.SurveyTypeCSAT,
+		Channel:  models.SurveyChannelButtons,
+		Question: "How did we do?",
+	})
+
+	transfer.AgentID = nil
+	app.sendTransferSurvey(transfer, settings)
+
+	var count int64
+	app.DB.Model(&models.SurveyResponse{}).Where("transfer_id = ?", transfer.ID).Count(&count)
+	assert.Zero(t, count)
+}
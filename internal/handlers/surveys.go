@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/surveyutil"
+	"github.com/shridarpatil/whatomate/pkg/whatsapp"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// SurveyResponseItem represents a survey response in API responses
+type SurveyResponseItem struct {
+	ID              uuid.UUID            `json:"id"`
+	TransferID      uuid.UUID            `json:"transfer_id"`
+	ContactID       uuid.UUID            `json:"contact_id"`
+	ContactName     string               `json:"contact_name"`
+	ContactPhone    string               `json:"contact_phone"`
+	AgentID         *uuid.UUID           `json:"agent_id,omitempty"`
+	AgentName       *string              `json:"agent_name,omitempty"`
+	TeamID          *uuid.UUID           `json:"team_id,omitempty"`
+	TeamName        *string              `json:"team_name,omitempty"`
+	WhatsAppAccount string               `json:"whatsapp_account"`
+	Type            models.SurveyType    `json:"type"`
+	Channel         models.SurveyChannel `json:"channel"`
+	Status          models.SurveyStatus  `json:"status"`
+	Score           *int                 `json:"score,omitempty"`
+	Comment         string               `json:"comment"`
+	SentAt          time.Time            `json:"sent_at"`
+	CompletedAt     *time.Time           `json:"completed_at,omitempty"`
+}
+
+// surveyCommentWindow is how long after a button score the next message is
+// taken as the survey comment
+const surveyCommentWindow = 10 * time.Minute
+
+// csatScoreLabels are the options of a CSAT survey sent as buttons, best first
+var csatScoreLabels = map[int]string{
+	5: "Excellent",
+	4: "Good",
+	3: "Okay",
+	2: "Poor",
+	1: "Very poor",
+}
+
+// ListSurveyResponses returns survey responses sent in a period, newest first.
+// Results can be filtered by agent, team, type and status.
+func (a *App) ListSurveyResponses(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceAnalytics, models.ActionRead); err != nil {
+		return nil
+	}
+
+	args := r.RequestCtx.QueryArgs()
+	fromStr := string(args.Peek("from"))
+	toStr := string(args.Peek("to"))
+
+	now := time.Now()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := now
+	if fromStr != "" && toStr != "" {
+		var errMsg string
+		periodStart, periodEnd, errMsg = parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+	}
+
+	query := a.DB.Model(&models.SurveyResponse{}).
+		Where("organization_id = ? AND sent_at >= ? AND sent_at <= ?", orgID, periodStart, periodEnd)
+	for _, param := range []string{"agent_id", "team_id"} {
+		if s := string(args.Peek(param)); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid "+param, nil, "")
+			}
+			query = query.Where(param+" = ?", id)
+		}
+	}
+	if s := string(args.Peek("type")); s != "" {
+		query = query.Where("type = ?", s)
+	}
+	if s := string(args.Peek("status")); s != "" {
+		query = query.Where("status = ?", s)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	pg := parsePagination(r)
+	var surveys []models.SurveyResponse
+	if err := pg.Apply(query.Preload("Contact").Preload("Agent").Preload("Team").Order("sent_at DESC")).
+		Find(&surveys).Error; err != nil {
+		a.Log.Error("Failed to list survey responses", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list survey responses", nil, "")
+	}
+
+	items := make([]SurveyResponseItem, len(surveys))
+	for i := range surveys {
+		items[i] = buildSurveyResponseItem(&surveys[i])
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"surveys": items,
+		"total":   total,
+		"page":    pg.Page,
+		"limit":   pg.Limit,
+	})
+}
+
+// sendTransferSurvey sends the satisfaction survey for a closed transfer when
+// surveys are enabled. Transfers no agent handled are not surveyed, and a
+// transfer is surveyed at most once.
+func (a *App) sendTransferSurvey(transfer *models.AgentTransfer, settings *models.ChatbotSettings) {
+	if settings == nil || !settings.Survey.Enabled || transfer.AgentID == nil {
+		return
+	}
+	cfg := settings.Survey
+
+	var account models.WhatsAppAccount
+	if err := a.DB.Where("name = ? AND organization_id = ?", transfer.WhatsAppAccount, transfer.OrganizationID).
+		First(&account).Error; err != nil {
+		a.Log.Error("Failed to load WhatsApp account for survey", "error", err, "transfer_id", transfer.ID)
+		return
+	}
+
+	var contact models.Contact
+	if err := a.DB.Where("id = ?", transfer.ContactID).First(&contact).Error; err != nil {
+		a.Log.Error("Failed to load contact for survey", "error", err, "transfer_id", transfer.ID)
+		return
+	}
+	if contact.IsBlocked {
+		return
+	}
+
+	now := time.Now()
+	expiryHours := cfg.ExpiryHours
+	if expiryHours < 1 {
+		expiryHours = 24
+	}
+	survey := models.SurveyResponse{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  transfer.OrganizationID,
+		TransferID:      transfer.ID,
+		ContactID:       transfer.ContactID,
+		AgentID:         transfer.AgentID,
+		TeamID:          transfer.TeamID,
+		WhatsAppAccount: transfer.WhatsAppAccount,
+		Type:            cfg.Type,
+		Channel:         cfg.Channel,
+		Status:          models.SurveyStatusSent,
+		SentAt:          now,
+		ExpiresAt:       now.Add(time.Duration(expiryHours) * time.Hour),
+	}
+	if err := a.DB.Create(&survey).Error; err != nil {
+		a.Log.Error("Failed to create survey", "error", err, "transfer_id", transfer.ID)
+		return
+	}
+
+	req := OutgoingMessageRequest{
+		Account: &account,
+		Contact: &contact,
+	}
+	switch {
+	case cfg.Channel == models.SurveyChannelFlow:
+		cta := cfg.FlowCTA
+		if cta == "" {
+			cta = "Rate us"
+		}
+		req.Type = models.MessageTypeFlow
+		req.FlowID = cfg.FlowID
+		req.BodyText = cfg.Question
+		req.FlowCTA = cta
+		req.FlowToken = surveyutil.FlowTokenPrefix + survey.ID.String()
+	case cfg.Type == models.SurveyTypeCSAT:
+		req.Type = models.MessageTypeInteractive
+		req.InteractiveType = "list"
+		req.BodyText = cfg.Question
+		for score := 5; score >= 1; score-- {
+			req.Buttons = append(req.Buttons, whatsapp.Button{
+				ID:    surveyutil.ButtonID(score),
+				Title: csatScoreLabels[score],
+			})
+		}
+	default:
+		// WhatsApp allows at most 10 options, so NPS scores are typed
+		req.Type = models.MessageTypeText
+		req.Content = cfg.Question
+	}
+
+	if _, err := a.SendOutgoingMessage(context.Background(), req, SurveySendOptions()); err != nil {
+		a.Log.Error("Failed to send survey", "error", err, "transfer_id", transfer.ID)
+	}
+}
+
+// handleSurveyReply records a contact's answer to their pending survey. It
+// returns true when the message was taken as an answer and should not be
+// processed further.
+func (a *App) handleSurveyReply(account *models.WhatsAppAccount, contact *models.Contact, messageType, messageText, buttonID string, flowData map[string]any) bool {
+	// Scored surveys are loaded after their comment window too, so the next
+	// message completes them
+	var survey models.SurveyResponse
+	if err := a.DB.Where("organization_id = ? AND contact_id = ? AND status <> ? AND (expires_at > ? OR status = ?)",
+		account.OrganizationID, contact.ID, models.SurveyStatusCompleted, time.Now(), models.SurveyStatusScored).
+		Order("sent_at DESC").First(&survey).Error; err != nil {
+		return false
+	}
+
+	settings, err := a.getChatbotSettingsCached(account.OrganizationID, survey.WhatsAppAccount)
+	if err != nil {
+		settings = &models.ChatbotSettings{}
+	}
+
+	switch survey.Status {
+	case models.SurveyStatusSent:
+		var score int
+		var ok bool
+		if messageType == "nfm_reply" {
+			if token, _ := flowData["flow_token"].(string); token != surveyutil.FlowTokenPrefix+survey.ID.String() {
+				return false
+			}
+			score, survey.Comment, ok = surveyutil.FlowAnswer(survey.Type, flowData)
+		} else {
+			score, ok = surveyutil.ParseScore(survey.Type, buttonID, messageText)
+		}
+		if !ok {
+			return false
+		}
+		survey.Score = &score
+
+		if messageType != "nfm_reply" && settings.Survey.CommentPrompt != "" {
+			survey.Status = models.SurveyStatusScored
+			if err := a.DB.Model(&survey).Updates(map[string]any{
+				"score":      score,
+				"status":     survey.Status,
+				"expires_at": time.Now().Add(surveyCommentWindow),
+			}).Error; err != nil {
+				a.Log.Error("Failed to save survey score", "error", err, "survey_id", survey.ID)
+				return true
+			}
+			a.sendSurveyMessage(account, contact, settings.Survey.CommentPrompt)
+			return true
+		}
+
+	case models.SurveyStatusScored:
+		// Only the next message within the comment window is the comment.
+		// Anything else completes the survey and is processed as usual.
+		if messageText == "" || !time.Now().Before(survey.ExpiresAt) {
+			a.completeSurvey(account, contact, &survey, "")
+			return false
+		}
+		survey.Comment = messageText
+	}
+
+	a.completeSurvey(account, contact, &survey, settings.Survey.ThankYouMessage)
+	return true
+}
+
+// completeSurvey saves a survey's answer, thanks the contact and dispatches
+// the survey.completed webhook
+func (a *App) completeSurvey(account *models.WhatsAppAccount, contact *models.Contact, survey *models.SurveyResponse, thankYou string) {
+	now := time.Now()
+	survey.Status = models.SurveyStatusCompleted
+	survey.CompletedAt = &now
+	if err := a.DB.Model(survey).Updates(map[string]any{
+		"score":        survey.Score,
+		"comment":      survey.Comment,
+		"status":       survey.Status,
+		"completed_at": now,
+	}).Error; err != nil {
+		a.Log.Error("Failed to complete survey", "error", err, "survey_id", survey.ID)
+		return
+	}
+
+	if thankYou != "" {
+		a.sendSurveyMessage(account, contact, thankYou)
+	}
+
+	data := SurveyEventData{
+		SurveyID:        survey.ID.String(),
+		TransferID:      survey.TransferID.String(),
+		ContactID:       contact.ID.String(),
+		ContactPhone:    contact.PhoneNumber,
+		ContactName:     contact.ProfileName,
+		Type:            survey.Type,
+		Comment:         survey.Comment,
+		WhatsAppAccount: survey.WhatsAppAccount,
+	}
+	if survey.Score != nil {
+		data.Score = *survey.Score
+	}
+	if survey.AgentID != nil {
+		agentID := survey.AgentID.String()
+		data.AgentID = &agentID
+	}
+	if survey.TeamID != nil {
+		teamID := survey.TeamID.String()
+		data.TeamID = &teamID
+	}
+	a.DispatchWebhook(survey.OrganizationID, models.WebhookEventSurveyCompleted, data)
+}
+
+func (a *App) sendSurveyMessage(account *models.WhatsAppAccount, contact *models.Contact, text string) {
+	if _, err := a.SendOutgoingMessage(context.Background(), OutgoingMessageRequest{
+		Account: account,
+		Contact: contact,
+		Type:    models.MessageTypeText,
+		Content: text,
+	}, SurveySendOptions()); err != nil {
+		a.Log.Error("Failed to send survey message", "error", err, "contact_id", contact.ID)
+	}
+}
+
+func buildSurveyResponseItem(survey *models.SurveyResponse) SurveyResponseItem {
+	item := SurveyResponseItem{
+		ID:              survey.ID,
+		TransferID:      survey.TransferID,
+		ContactID:       survey.ContactID,
+		AgentID:         survey.AgentID,
+		TeamID:          survey.TeamID,
+		WhatsAppAccount: survey.WhatsAppAccount,
+		Type:            survey.Type,
+		Channel:         survey.Channel,
+		Status:          survey.Status,
+		Score:           survey.Score,
+		Comment:         survey.Comment,
+		SentAt:          survey.SentAt,
+		CompletedAt:     survey.CompletedAt,
+	}
+	if survey.Contact != nil {
+		item.ContactName = survey.Contact.ProfileName
+		item.ContactPhone = survey.Contact.PhoneNumber
+	}
+	if survey.Agent != nil {
+		item.AgentName = &survey.Agent.FullName
+	}
+	if survey.Team != nil {
+		item.TeamName = &survey.Team.Name
+	}
+	return item
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func createTestSurvey(t *testing.T, app *handlers.App, orgID, contactID uuid.UUID, agentID *uuid.UUID, surveyType models.SurveyType, score *int) *models.SurveyResponse {
+	t.Helper()
+
+	transfer := createTestTransfer(t, app, orgID, contactID, "test-account", models.TransferStatusResumed, agentID)
+	now := time.Now()
+	survey := &models.SurveyResponse{
+		BaseModel:       models.BaseModel{ID: uuid.New()},
+		OrganizationID:  orgID,
+		TransferID:      transfer.ID,
+		ContactID:       contactID,
+		AgentID:         agentID,
+		WhatsAppAccount: "test-account",
+		Type:            surveyType,
+		Channel:         models.SurveyChannelButtons,
+		Status:          models.SurveyStatusSent,
+		SentAt:          now.Add(-time.Hour),
+		ExpiresAt:       now.Add(23 * time.Hour),
+	}
+	if score != nil {
+		survey.Status = models.SurveyStatusCompleted
+		survey.Score = score
+		survey.CompletedAt = &now
+	}
+	require.NoError(t, app.DB.Create(survey).Error)
+	return survey
+}
+
+func TestApp_UpdateChatbotSettings_SurveyValidation(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	user := testutil.CreateTestUser(t, app.DB, org.ID)
+
+	req := testutil.NewJSONRequest(t, map[string]any{
+		"survey_enabled":  true,
+		"survey_type":     "nps",
+		"survey_channel":  "flow",
+		"survey_question": "How likely are you to recommend us?",
+	})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	require.NoError(t, app.UpdateChatbotSettings(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "survey flow ID is required for the flow channel")
+}
+
+func TestApp_ListSurveyResponses(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	agent := createTestAgent(t, app, org.ID)
+
+	five, nine := 5, 9
+	answered := createTestSurvey(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, &agent.ID, models.SurveyTypeCSAT, &five)
+	createTestSurvey(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, &agent.ID, models.SurveyTypeCSAT, nil)
+	createTestSurvey(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, &agent.ID, models.SurveyTypeNPS, &nine)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetQueryParam(req, "type", "csat")
+	testutil.SetQueryParam(req, "status", "completed")
+	require.NoError(t, app.ListSurveyResponses(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Surveys []handlers.SurveyResponseItem `json:"surveys"`
+		Total   int64                         `json:"total"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	assert.Equal(t, int64(1), result.Total)
+	require.Len(t, result.Surveys, 1)
+	assert.Equal(t, answered.ID, result.Surveys[0].ID)
+	require.NotNil(t, result.Surveys[0].Score)
+	assert.Equal(t, 5, *result.Surveys[0].Score)
+}
+
+func TestApp_GetAgentDetails_SurveyScores(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	agent := createTestAgent(t, app, org.ID)
+
+	for _, score := range []int{4, 5} {
+		createTestSurvey(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, &agent.ID, models.SurveyTypeCSAT, &score)
+	}
+	// One promoter, one passive and one detractor
+	for _, score := range []int{10, 7, 3} {
+		createTestSurvey(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, &agent.ID, models.SurveyTypeNPS, &score)
+	}
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", agent.ID.String())
+	require.NoError(t, app.GetAgentDetails(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Agent handlers.AgentPerformanceStats `json:"agent"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	assert.InDelta(t, 4.5, result.Agent.CSATScore, 0.01)
+	assert.Equal(t, int64(2), result.Agent.CSATResponses)
+	assert.InDelta(t, 0, result.Agent.NPS, 0.01)
+	assert.Equal(t, int64(3), result.Agent.NPSResponses)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
@@ -13,8 +14,10 @@ import (
 type TeamRequest struct {
 	Name               string                   `json:"name" validate:"required"`
 	Description        string                   `json:"description"`
-	AssignmentStrategy models.AssignmentStrategy `json:"assignment_strategy"` // round_robin, load_balanced, manual
+	AssignmentStrategy models.AssignmentStrategy `json:"assignment_strategy"` // round_robin, load_balanced, manual, skills_based
 	IsActive           bool                     `json:"is_active"`
+	MaxConcurrentChats *int                     `json:"max_concurrent_chats"` // Per-agent limit; 0 = unlimited
+	Timezone           *string                  `json:"timezone"`             // IANA name for SLA business hours; empty = account/org timezone
 }
 
 // TeamMemberRequest represents add member request
@@ -30,6 +33,8 @@ type TeamResponse struct {
 	Description        string                    `json:"description"`
 	AssignmentStrategy models.AssignmentStrategy `json:"assignment_strategy"`
 	IsActive           bool                      `json:"is_active"`
+	MaxConcurrentChats int                       `json:"max_concurrent_chats"`
+	Timezone           string                    `json:"timezone"`
 	MemberCount        int                       `json:"member_count"`
 	Members            []TeamMemberResponse      `json:"members,omitempty"`
 	CreatedAt          time.Time                 `json:"created_at"`
@@ -161,9 +166,15 @@ func (a *App) CreateTeam(r *fastglue.Request) error {
 	if strategy == "" {
 		strategy = models.AssignmentStrategyRoundRobin
 	}
-	if strategy != models.AssignmentStrategyRoundRobin && strategy != models.AssignmentStrategyLoadBalanced && strategy != models.AssignmentStrategyManual {
+	if strategy != models.AssignmentStrategyRoundRobin && strategy != models.AssignmentStrategyLoadBalanced && strategy != models.AssignmentStrategyManual && strategy != models.AssignmentStrategySkillsBased {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid assignment strategy", nil, "")
 	}
+	if req.MaxConcurrentChats != nil && *req.MaxConcurrentChats < 0 {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "max_concurrent_chats cannot be negative", nil, "")
+	}
+	if req.Timezone != nil && !bizhours.ValidTimezone(*req.Timezone) {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid timezone", nil, "")
+	}
 
 	team := models.Team{
 		OrganizationID:     orgID,
@@ -172,6 +183,12 @@ func (a *App) CreateTeam(r *fastglue.Request) error {
 		AssignmentStrategy: strategy,
 		IsActive:           true,
 	}
+	if req.MaxConcurrentChats != nil {
+		team.MaxConcurrentChats = *req.MaxConcurrentChats
+	}
+	if req.Timezone != nil {
+		team.Timezone = *req.Timezone
+	}
 
 	if err := a.DB.Create(&team).Error; err != nil {
 		a.Log.Error("Failed to create team", "error", err)
@@ -226,17 +243,34 @@ func (a *App) UpdateTeam(r *fastglue.Request) error {
 	team.IsActive = req.IsActive
 
 	if req.AssignmentStrategy != "" {
-		if req.AssignmentStrategy != models.AssignmentStrategyRoundRobin && req.AssignmentStrategy != models.AssignmentStrategyLoadBalanced && req.AssignmentStrategy != models.AssignmentStrategyManual {
+		if req.AssignmentStrategy != models.AssignmentStrategyRoundRobin && req.AssignmentStrategy != models.AssignmentStrategyLoadBalanced && req.AssignmentStrategy != models.AssignmentStrategyManual && req.AssignmentStrategy != models.AssignmentStrategySkillsBased {
 			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid assignment strategy", nil, "")
 		}
 		team.AssignmentStrategy = req.AssignmentStrategy
 	}
 
+	if req.MaxConcurrentChats != nil {
+		if *req.MaxConcurrentChats < 0 {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "max_concurrent_chats cannot be negative", nil, "")
+		}
+		team.MaxConcurrentChats = *req.MaxConcurrentChats
+	}
+
+	if req.Timezone != nil {
+		if !bizhours.ValidTimezone(*req.Timezone) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid timezone", nil, "")
+		}
+		team.Timezone = *req.Timezone
+	}
+
 	if err := a.DB.Save(&team).Error; err != nil {
 		a.Log.Error("Failed to update team", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update team", nil, "")
 	}
 
+	// Invalidate cache
+	a.InvalidateBusinessHoursCache(orgID)
+
 	return r.SendEnvelope(map[string]interface{}{"team": buildTeamResponse(&team, false)})
 }
 
@@ -486,6 +520,8 @@ func buildTeamResponse(team *models.Team, includeMembers bool) TeamResponse {
 		Description:        team.Description,
 		AssignmentStrategy: team.AssignmentStrategy,
 		IsActive:           team.IsActive,
+		MaxConcurrentChats: team.MaxConcurrentChats,
+		Timezone:           team.Timezone,
 		MemberCount:        len(team.Members),
 		CreatedAt:          team.CreatedAt,
 		UpdatedAt:          team.UpdatedAt,
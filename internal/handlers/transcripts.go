@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/transcript"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
+)
+
+// defaultTranscriptDays is the range of a contact transcript when no dates are given
+const defaultTranscriptDays = 30
+
+// GetContactTranscript renders a contact's conversation over a date range
+// (from/to as YYYY-MM-DD, default last 30 days) as HTML, PDF or JSON
+func (a *App) GetContactTranscript(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceTranscripts, models.ActionExport); err != nil {
+		return nil
+	}
+
+	contactID, err := parsePathUUID(r, "id", "contact")
+	if err != nil {
+		return nil
+	}
+
+	format, ok := parseTranscriptFormat(r)
+	if !ok {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid format. Must be 'html', 'pdf' or 'json'", nil, "")
+	}
+
+	fromStr := string(r.RequestCtx.QueryArgs().Peek("from"))
+	toStr := string(r.RequestCtx.QueryArgs().Peek("to"))
+	to := time.Now()
+	from := to.AddDate(0, 0, -defaultTranscriptDays)
+	if fromStr != "" || toStr != "" {
+		if fromStr == "" || toStr == "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Both from and to are required", nil, "")
+		}
+		var errMsg string
+		from, to, errMsg = parseDateRange(fromStr, toStr)
+		if errMsg != "" {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, errMsg, nil, "")
+		}
+	}
+
+	return a.sendTranscript(r, transcript.Options{
+		OrgID:     orgID,
+		ContactID: contactID,
+		From:      from,
+		To:        to,
+	}, format)
+}
+
+// GetTransferTranscript renders the conversation of a single agent transfer,
+// from when it was created until it was resumed
+func (a *App) GetTransferTranscript(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceTranscripts, models.ActionExport); err != nil {
+		return nil
+	}
+
+	transferID, err := parsePathUUID(r, "id", "transfer")
+	if err != nil {
+		return nil
+	}
+
+	format, ok := parseTranscriptFormat(r)
+	if !ok {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid format. Must be 'html', 'pdf' or 'json'", nil, "")
+	}
+
+	return a.sendTranscript(r, transcript.Options{
+		OrgID:      orgID,
+		TransferID: &transferID,
+	}, format)
+}
+
+// parseTranscriptFormat reads the format query parameter, defaulting to HTML
+func parseTranscriptFormat(r *fastglue.Request) (transcript.Format, bool) {
+	format := transcript.Format(r.RequestCtx.QueryArgs().Peek("format"))
+	if format == "" {
+		format = transcript.FormatHTML
+	}
+	return format, format.IsValid()
+}
+
+// sendTranscript builds the transcript and sends it as a file download, or
+// in the response envelope for JSON
+func (a *App) sendTranscript(r *fastglue.Request, opts transcript.Options, format transcript.Format) error {
+	t, err := a.buildTranscript(opts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Conversation not found", nil, "")
+		}
+		if errors.Is(err, transcript.ErrTooManyMessages) {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, err.Error(), nil, "")
+		}
+		a.Log.Error("Failed to build transcript", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to build transcript", nil, "")
+	}
+
+	if format == transcript.FormatJSON {
+		return r.SendEnvelope(t)
+	}
+
+	data, err := renderTranscript(t, format)
+	if err != nil {
+		a.Log.Error("Failed to render transcript", "error", err, "format", format)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to render transcript", nil, "")
+	}
+
+	r.RequestCtx.Response.Header.Set("Content-Type", format.ContentType())
+	r.RequestCtx.Response.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", transcriptFilename(t, format)))
+	r.RequestCtx.SetBody(data)
+	return nil
+}
+
+// buildTranscript loads a transcript using the organization's media storage
+// and timezone
+func (a *App) buildTranscript(opts transcript.Options) (*transcript.Transcript, error) {
+	var org models.Organization
+	if err := a.DB.Select("id, settings").Where("id = ?", opts.OrgID).First(&org).Error; err != nil {
+		return nil, err
+	}
+	opts.MediaRoot = a.getMediaStoragePath()
+	opts.Location = orgLocation(org.Settings)
+	return transcript.Build(a.DB, opts)
+}
+
+func renderTranscript(t *transcript.Transcript, format transcript.Format) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := transcript.Render(&buf, t, format); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func transcriptFilename(t *transcript.Transcript, format transcript.Format) string {
+	return fmt.Sprintf("transcript_%s_%s.%s", t.Contact.ID, t.GeneratedAt.Format("20060102_150405"), format)
+}
+
+// orgLocation returns the organization's configured timezone, or UTC
+func orgLocation(settings models.JSONB) *time.Location {
+	if tz, ok := settings["timezone"].(string); ok && tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// executeTranscriptAction renders the contact's recent conversation and
+// returns a one-time download link for it
+func (a *App) executeTranscriptAction(action models.CustomAction, orgID, contactID uuid.UUID) (*ActionResult, error) {
+	format := transcript.FormatPDF
+	if f, ok := action.Config["format"].(string); ok && f != "" {
+		format = transcript.Format(f)
+	}
+	if !format.IsValid() {
+		return nil, fmt.Errorf("invalid transcript format %q", format)
+	}
+	days := defaultTranscriptDays
+	if d, ok := action.Config["days"].(float64); ok && d > 0 {
+		days = int(d)
+	}
+
+	to := time.Now()
+	t, err := a.buildTranscript(transcript.Options{
+		OrgID:     orgID,
+		ContactID: contactID,
+		From:      to.AddDate(0, 0, -days),
+		To:        to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := renderTranscript(t, format)
+	if err != nil {
+		return nil, err
+	}
+
+	token := storeDownloadToken(downloadToken{
+		Data:        data,
+		ContentType: format.ContentType(),
+		Filename:    transcriptFilename(t, format),
+	})
+
+	return &ActionResult{
+		Success:     true,
+		Message:     "Transcript ready",
+		RedirectURL: "/api/custom-actions/download/" + token,
+		Data: map[string]interface{}{
+			"format":  format,
+			"entries": len(t.Entries),
+		},
+		Toast: &ToastConfig{Message: "Transcript ready", Type: "success"},
+	}, nil
+}
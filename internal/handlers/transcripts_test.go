@@ -0,0 +1,196 @@
+package handlers_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+// --- GetContactTranscript Tests ---
+
+func TestApp_GetContactTranscript(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) (*handlers.App, *models.Organization, *models.User, *models.Contact) {
+		app := newTestApp(t)
+		org := testutil.CreateTestOrganization(t, app.DB)
+		role := testutil.CreateAdminRole(t, app.DB, org.ID)
+		user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+		contact := testutil.CreateTestContact(t, app.DB, org.ID)
+		require.NoError(t, app.DB.Create(&models.Message{
+			OrganizationID:  org.ID,
+			WhatsAppAccount: "test",
+			ContactID:       contact.ID,
+			Direction:       models.DirectionIncoming,
+			MessageType:     models.MessageTypeText,
+			Content:         "where is my order",
+		}).Error)
+		return app, org, user, contact
+	}
+
+	t.Run("json", func(t *testing.T) {
+		app, org, user, contact := setup(t)
+
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		testutil.SetQueryParam(req, "format", "json")
+
+		require.NoError(t, app.GetContactTranscript(req))
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+		var resp struct {
+			Entries []struct {
+				Text string `json:"text"`
+			} `json:"entries"`
+		}
+		testutil.ParseEnvelopeResponse(t, req, &resp)
+		require.Len(t, resp.Entries, 1)
+		assert.Equal(t, "where is my order", resp.Entries[0].Text)
+	})
+
+	t.Run("pdf download", func(t *testing.T) {
+		app, org, user, contact := setup(t)
+
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		testutil.SetQueryParam(req, "format", "pdf")
+
+		require.NoError(t, app.GetContactTranscript(req))
+		assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+		assert.Equal(t, "application/pdf", string(req.RequestCtx.Response.Header.ContentType()))
+		assert.True(t, bytes.HasPrefix(testutil.GetResponseBody(req), []byte("%PDF-")))
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		app, org, user, contact := setup(t)
+
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, user.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+		testutil.SetQueryParam(req, "format", "docx")
+
+		require.NoError(t, app.GetContactTranscript(req))
+		testutil.AssertErrorResponse(t, req, fasthttp.StatusBadRequest, "Invalid format")
+	})
+
+	t.Run("requires permission", func(t *testing.T) {
+		app, org, _, contact := setup(t)
+		role := testutil.CreateTestRoleWithKeys(t, app.DB, org.ID, "viewer", []string{"contacts:read"})
+		viewer := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+
+		req := testutil.NewGETRequest(t)
+		testutil.SetAuthContext(req, org.ID, viewer.ID)
+		testutil.SetPathParam(req, "id", contact.ID.String())
+
+		require.NoError(t, app.GetContactTranscript(req))
+		assert.Equal(t, fasthttp.StatusForbidden, testutil.GetResponseStatusCode(req))
+	})
+}
+
+// --- GetTransferTranscript Tests ---
+
+func TestApp_GetTransferTranscript(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	require.NoError(t, app.DB.Create(&models.Message{
+		BaseModel:       models.BaseModel{CreatedAt: time.Now().Add(-time.Hour)},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "before the transfer",
+	}).Error)
+	transfer := models.AgentTransfer{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     contact.PhoneNumber,
+		Notes:           "Angry customer",
+	}
+	require.NoError(t, app.DB.Create(&transfer).Error)
+	require.NoError(t, app.DB.Create(&models.Message{
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "during the transfer",
+	}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", transfer.ID.String())
+
+	require.NoError(t, app.GetTransferTranscript(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	body := string(testutil.GetResponseBody(req))
+	assert.Contains(t, body, "during the transfer")
+	assert.Contains(t, body, "Angry customer")
+	assert.NotContains(t, body, "before the transfer")
+}
+
+// --- Transcript custom action Tests ---
+
+func TestApp_ExecuteCustomAction_Transcript(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	role := testutil.CreateAdminRole(t, app.DB, org.ID)
+	user := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&role.ID))
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+
+	action := models.CustomAction{
+		OrganizationID: org.ID,
+		Name:           "Transcript",
+		ActionType:     models.ActionTypeTranscript,
+		Config:         models.JSONB{"format": "html", "days": 7},
+		IsActive:       true,
+	}
+	require.NoError(t, app.DB.Create(&action).Error)
+
+	req := testutil.NewJSONRequest(t, map[string]any{"contact_id": contact.ID.String()})
+	testutil.SetAuthContext(req, org.ID, user.ID)
+	testutil.SetPathParam(req, "id", action.ID.String())
+
+	require.NoError(t, app.ExecuteCustomAction(req))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Success     bool   `json:"success"`
+		RedirectURL string `json:"redirect_url"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	require.True(t, result.Success)
+	require.Contains(t, result.RedirectURL, "/api/custom-actions/download/")
+
+	token := result.RedirectURL[len("/api/custom-actions/download/"):]
+	download := testutil.NewGETRequest(t)
+	testutil.SetPathParam(download, "token", token)
+	require.NoError(t, app.CustomActionDownload(download))
+	assert.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(download))
+	assert.Contains(t, string(testutil.GetResponseBody(download)), "Conversation transcript")
+
+	// Tokens are single use
+	again := testutil.NewGETRequest(t)
+	testutil.SetPathParam(again, "token", token)
+	require.NoError(t, app.CustomActionDownload(again))
+	assert.Equal(t, fasthttp.StatusNotFound, testutil.GetResponseStatusCode(again))
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/websocket"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// TransferHopResponse represents a transfer hop in API responses
+type TransferHopResponse struct {
+	ID            uuid.UUID                 `json:"id"`
+	FromAgentID   *uuid.UUID                `json:"from_agent_id,omitempty"`
+	FromAgentName *string                   `json:"from_agent_name,omitempty"`
+	ToAgentID     *uuid.UUID                `json:"to_agent_id,omitempty"`
+	ToAgentName   *string                   `json:"to_agent_name,omitempty"`
+	FromTeamID    *uuid.UUID                `json:"from_team_id,omitempty"`
+	FromTeamName  *string                   `json:"from_team_name,omitempty"`
+	ToTeamID      *uuid.UUID                `json:"to_team_id,omitempty"`
+	ToTeamName    *string                   `json:"to_team_name,omitempty"`
+	Trigger       models.TransferHopTrigger `json:"trigger"`
+	Reason        string                    `json:"reason"`
+	Notes         string                    `json:"notes"`
+	ByUserID      *uuid.UUID                `json:"by_user_id,omitempty"`
+	ByUserName    *string                   `json:"by_user_name,omitempty"`
+	StartedAt     time.Time                 `json:"started_at"`
+	HoppedAt      time.Time                 `json:"hopped_at"`
+	HandleSeconds int64                     `json:"handle_seconds"`
+	IsHandoff     bool                      `json:"is_handoff"`
+}
+
+// ListTransferHops returns the hand-off history of a transfer, oldest first
+func (a *App) ListTransferHops(r *fastglue.Request) error {
+	orgID, userID, err := a.getOrgAndUserID(r)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusUnauthorized, "Unauthorized", nil, "")
+	}
+
+	if err := a.requirePermission(r, userID, models.ResourceTransfers, models.ActionRead); err != nil {
+		return nil
+	}
+
+	transferID, err := parsePathUUID(r, "id", "transfer")
+	if err != nil {
+		return nil
+	}
+
+	if _, err := findByIDAndOrg[models.AgentTransfer](a.DB, r, transferID, orgID, "Transfer"); err != nil {
+		return nil
+	}
+
+	var hops []models.TransferHop
+	if err := a.DB.Where("transfer_id = ? AND organization_id = ?", transferID, orgID).
+		Preload("FromAgent").Preload("ToAgent").Preload("FromTeam").Preload("ToTeam").Preload("ByUser").
+		Order("hopped_at ASC").
+		Find(&hops).Error; err != nil {
+		a.Log.Error("Failed to list transfer hops", "error", err, "transfer_id", transferID)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list transfer hops", nil, "")
+	}
+
+	response := make([]TransferHopResponse, len(hops))
+	for i := range hops {
+		response[i] = buildTransferHopResponse(&hops[i])
+	}
+
+	return r.SendEnvelope(map[string]any{
+		"hops": response,
+	})
+}
+
+// recordTransferHop records that a transfer moved from the given agent and
+// team to its current ones, and broadcasts the hop. The previous holder's
+// handle time runs from the last hop, or from the transfer if there is none.
+// Nothing is recorded when neither the agent nor the team changed.
+func (a *App) recordTransferHop(transfer *models.AgentTransfer, fromAgentID, fromTeamID *uuid.UUID, trigger models.TransferHopTrigger, byUserID *uuid.UUID, reason, notes string) {
+	now := time.Now()
+	hop := models.TransferHop{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: transfer.OrganizationID,
+		TransferID:     transfer.ID,
+		FromAgentID:    fromAgentID,
+		ToAgentID:      transfer.AgentID,
+		FromTeamID:     fromTeamID,
+		ToTeamID:       transfer.TeamID,
+		Trigger:        trigger,
+		Reason:         reason,
+		Notes:          notes,
+		ByUserID:       byUserID,
+		StartedAt:      transfer.TransferredAt,
+		HoppedAt:       now,
+	}
+	if !hop.Moved() {
+		return
+	}
+
+	var last models.TransferHop
+	if a.DB.Where("transfer_id = ?", transfer.ID).Order("hopped_at DESC").First(&last).Error == nil {
+		hop.StartedAt = last.HoppedAt
+	}
+	hop.HandleSeconds = int64(now.Sub(hop.StartedAt).Seconds())
+
+	if err := a.DB.Create(&hop).Error; err != nil {
+		a.Log.Error("Failed to record transfer hop", "error", err, "transfer_id", transfer.ID)
+		return
+	}
+
+	a.broadcastTransferHop(transfer, &hop)
+}
+
+func (a *App) broadcastTransferHop(transfer *models.AgentTransfer, hop *models.TransferHop) {
+	if a.WSHub == nil {
+		return
+	}
+
+	a.WSHub.BroadcastToOrg(transfer.OrganizationID, websocket.WSMessage{
+		Type: websocket.TypeAgentTransferHop,
+		Payload: map[string]any{
+			"id":             hop.ID.String(),
+			"transfer_id":    transfer.ID.String(),
+			"contact_id":     transfer.ContactID.String(),
+			"from_agent_id":  hop.FromAgentID,
+			"to_agent_id":    hop.ToAgentID,
+			"from_team_id":   hop.FromTeamID,
+			"to_team_id":     hop.ToTeamID,
+			"trigger":        hop.Trigger,
+			"reason":         hop.Reason,
+			"hopped_at":      hop.HoppedAt.Format(time.RFC3339),
+			"handle_seconds": hop.HandleSeconds,
+			"is_handoff":     hop.IsHandoff(),
+		},
+	})
+}
+
+func buildTransferHopResponse(hop *models.TransferHop) TransferHopResponse {
+	resp := TransferHopResponse{
+		ID:            hop.ID,
+		FromAgentID:   hop.FromAgentID,
+		ToAgentID:     hop.ToAgentID,
+		FromTeamID:    hop.FromTeamID,
+		ToTeamID:      hop.ToTeamID,
+		Trigger:       hop.Trigger,
+		Reason:        hop.Reason,
+		Notes:         hop.Notes,
+		ByUserID:      hop.ByUserID,
+		StartedAt:     hop.StartedAt,
+		HoppedAt:      hop.HoppedAt,
+		HandleSeconds: hop.HandleSeconds,
+		IsHandoff:     hop.IsHandoff(),
+	}
+	if hop.FromAgent != nil {
+		resp.FromAgentName = &hop.FromAgent.FullName
+	}
+	if hop.ToAgent != nil {
+		resp.ToAgentName = &hop.ToAgent.FullName
+	}
+	if hop.FromTeam != nil {
+		resp.FromTeamName = &hop.FromTeam.Name
+	}
+	if hop.ToTeam != nil {
+		resp.ToTeamName = &hop.ToTeam.Name
+	}
+	if hop.ByUser != nil {
+		resp.ByUserName = &hop.ByUser.FullName
+	}
+	return resp
+}
@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/handlers"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func assignTransferViaAPI(t *testing.T, app *handlers.App, orgID, userID, transferID uuid.UUID, body map[string]any) {
+	t.Helper()
+
+	req := testutil.NewJSONRequest(t, body)
+	testutil.SetAuthContext(req, orgID, userID)
+	testutil.SetPathParam(req, "id", transferID.String())
+	require.NoError(t, app.AssignAgentTransfer(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+}
+
+func TestApp_ListTransferHops_RecordsReassignments(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	first := createTestAgent(t, app, org.ID)
+	second := createTestAgent(t, app, org.ID)
+	transfer := createTestTransfer(t, app, org.ID, contact.ID, account.Name, models.TransferStatusActive, nil)
+
+	assignTransferViaAPI(t, app, org.ID, admin.ID, transfer.ID, map[string]any{
+		"agent_id": first.ID.String(),
+	})
+	// Re-assigning to the same agent is not a hop
+	assignTransferViaAPI(t, app, org.ID, admin.ID, transfer.ID, map[string]any{
+		"agent_id": first.ID.String(),
+	})
+	assignTransferViaAPI(t, app, org.ID, admin.ID, transfer.ID, map[string]any{
+		"agent_id": second.ID.String(),
+		"reason":   "Billing question",
+		"notes":    "Customer wants a refund for the last invoice",
+	})
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", transfer.ID.String())
+	require.NoError(t, app.ListTransferHops(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Hops []handlers.TransferHopResponse `json:"hops"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	require.Len(t, result.Hops, 2)
+
+	// Taking a transfer from the queue is not a hand-off
+	assert.Nil(t, result.Hops[0].FromAgentID)
+	assert.Equal(t, first.ID, *result.Hops[0].ToAgentID)
+	assert.Equal(t, models.TransferHopTriggerManual, result.Hops[0].Trigger)
+	assert.False(t, result.Hops[0].IsHandoff)
+	assert.WithinDuration(t, transfer.TransferredAt, result.Hops[0].StartedAt, time.Second)
+
+	hop := result.Hops[1]
+	assert.Equal(t, first.ID, *hop.FromAgentID)
+	assert.Equal(t, second.ID, *hop.ToAgentID)
+	assert.Equal(t, "Billing question", hop.Reason)
+	assert.Equal(t, "Customer wants a refund for the last invoice", hop.Notes)
+	assert.Equal(t, admin.ID, *hop.ByUserID)
+	assert.True(t, hop.IsHandoff)
+	assert.True(t, hop.StartedAt.Equal(result.Hops[0].HoppedAt))
+}
+
+func TestApp_ListTransferHops_OtherOrganization(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	other := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, other.ID)
+	admin := testutil.CreateTestUser(t, app.DB, other.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	contact := testutil.CreateTestContact(t, app.DB, org.ID)
+	transfer := createTestTransfer(t, app, org.ID, contact.ID, account.Name, models.TransferStatusActive, nil)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, other.ID, admin.ID)
+	testutil.SetPathParam(req, "id", transfer.ID.String())
+	require.NoError(t, app.ListTransferHops(req))
+	testutil.AssertErrorResponse(t, req, fasthttp.StatusNotFound, "Transfer not found")
+}
+
+func TestApp_GetAgentDetails_HandoffStats(t *testing.T) {
+	app := newTestApp(t)
+	org := testutil.CreateTestOrganization(t, app.DB)
+	adminRole := testutil.CreateAdminRole(t, app.DB, org.ID)
+	admin := testutil.CreateTestUser(t, app.DB, org.ID, testutil.WithRoleID(&adminRole.ID))
+	account := testutil.CreateTestWhatsAppAccount(t, app.DB, org.ID)
+	agent := createTestAgent(t, app, org.ID)
+	colleague := createTestAgent(t, app, org.ID)
+
+	// One conversation handed to a colleague after 10 minutes
+	handedOff := createTestTransfer(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, account.Name, models.TransferStatusActive, &colleague.ID)
+	require.NoError(t, app.DB.Create(&models.TransferHop{
+		BaseModel:      models.BaseModel{ID: uuid.New()},
+		OrganizationID: org.ID,
+		TransferID:     handedOff.ID,
+		FromAgentID:    &agent.ID,
+		ToAgentID:      &colleague.ID,
+		Trigger:        models.TransferHopTriggerManual,
+		StartedAt:      time.Now().Add(-10 * time.Minute),
+		HoppedAt:       time.Now(),
+		HandleSeconds:  600,
+	}).Error)
+
+	// One conversation closed after 20 minutes
+	closed := createTestTransfer(t, app, org.ID, testutil.CreateTestContact(t, app.DB, org.ID).ID, account.Name, models.TransferStatusResumed, &agent.ID)
+	resumedAt := time.Now()
+	require.NoError(t, app.DB.Model(closed).Updates(map[string]any{
+		"transferred_at": resumedAt.Add(-20 * time.Minute),
+		"resumed_at":     resumedAt,
+	}).Error)
+
+	req := testutil.NewGETRequest(t)
+	testutil.SetAuthContext(req, org.ID, admin.ID)
+	testutil.SetPathParam(req, "id", agent.ID.String())
+	require.NoError(t, app.GetAgentDetails(req))
+	require.Equal(t, fasthttp.StatusOK, testutil.GetResponseStatusCode(req))
+
+	var result struct {
+		Agent handlers.AgentPerformanceStats `json:"agent"`
+	}
+	testutil.ParseEnvelopeResponse(t, req, &result)
+	assert.Equal(t, int64(1), result.Agent.Handoffs)
+	assert.InDelta(t, 50, result.Agent.HandoffRatePct, 0.1)
+	assert.InDelta(t, 15, result.Agent.AvgHandleMins, 0.1)
+}
@@ -832,41 +832,21 @@ func (a *App) UpdateAvailability(r *fastglue.Request) error {
 		return nil
 	}
 
-	// Only log if status is actually changing
-	if user.IsAvailable != req.IsAvailable {
-		now := time.Now()
-
-		// End the previous availability log (if exists)
-		a.DB.Model(&models.UserAvailabilityLog{}).
-			Where("user_id = ? AND ended_at IS NULL", userID).
-			Update("ended_at", now)
-
-		// Create new availability log
-		log := models.UserAvailabilityLog{
-			UserID:         userID,
-			OrganizationID: orgID,
-			IsAvailable:    req.IsAvailable,
-			StartedAt:      now,
-		}
-		if err := a.DB.Create(&log).Error; err != nil {
-			a.Log.Error("Failed to create availability log", "error", err)
-			// Continue anyway - logging failure shouldn't block availability update
-		}
-	}
-
-	user.IsAvailable = req.IsAvailable
-
-	if err := a.DB.Save(&user).Error; err != nil {
+	if err := a.setAvailability(&user, orgID, req.IsAvailable, models.AvailabilitySourceManual); err != nil {
 		a.Log.Error("Failed to update availability", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update availability", nil, "")
 	}
 
 	status := "available"
 	transfersReturned := 0
+	transfersAssigned := 0
 	if !req.IsAvailable {
 		status = "away"
 		// Return agent's active transfers to queue when going away
 		transfersReturned = a.ReturnAgentTransfersToQueue(userID, orgID)
+	} else {
+		// Hand the agent queued conversations up to their capacity
+		transfersAssigned = a.assignQueuedTransfers(orgID, userID)
 	}
 
 	// Get the current break start time if away
@@ -885,5 +865,36 @@ func (a *App) UpdateAvailability(r *fastglue.Request) error {
 		"status":              status,
 		"break_started_at":    breakStartedAt,
 		"transfers_to_queue":  transfersReturned,
+		"transfers_assigned":  transfersAssigned,
 	})
 }
+
+// setAvailability marks a user available or away and logs the change for
+// break and online time
+func (a *App) setAvailability(user *models.User, orgID uuid.UUID, available bool, source string) error {
+	// Only log if status is actually changing
+	if user.IsAvailable != available {
+		now := time.Now()
+
+		// End the previous availability log (if exists)
+		a.DB.Model(&models.UserAvailabilityLog{}).
+			Where("user_id = ? AND ended_at IS NULL", user.ID).
+			Update("ended_at", now)
+
+		// Create new availability log
+		log := models.UserAvailabilityLog{
+			UserID:         user.ID,
+			OrganizationID: orgID,
+			IsAvailable:    available,
+			StartedAt:      now,
+			Source:         source,
+		}
+		if err := a.DB.Create(&log).Error; err != nil {
+			a.Log.Error("Failed to create availability log", "error", err)
+			// Continue anyway - logging failure shouldn't block availability update
+		}
+	}
+
+	user.IsAvailable = available
+	return a.DB.Model(user).Update("is_available", available).Error
+}
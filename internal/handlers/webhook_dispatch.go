@@ -44,6 +44,13 @@ type ContactEventData struct {
 	WhatsAppAccount string `json:"whatsapp_account"`
 }
 
+// ContactBlockedEventData represents data for contact block events
+type ContactBlockedEventData struct {
+	ContactEventData
+	Reason    string `json:"reason,omitempty"`
+	BlockedBy string `json:"blocked_by"`
+}
+
 // TransferEventData represents data for transfer events
 type TransferEventData struct {
 	TransferID      string                `json:"transfer_id"`
@@ -57,6 +64,21 @@ type TransferEventData struct {
 	WhatsAppAccount string                `json:"whatsapp_account"`
 }
 
+// SurveyEventData represents data for survey events
+type SurveyEventData struct {
+	SurveyID        string            `json:"survey_id"`
+	TransferID      string            `json:"transfer_id"`
+	ContactID       string            `json:"contact_id"`
+	ContactPhone    string            `json:"contact_phone"`
+	ContactName     string            `json:"contact_name"`
+	Type            models.SurveyType `json:"type"`
+	Score           int               `json:"score"`
+	Comment         string            `json:"comment,omitempty"`
+	AgentID         *string           `json:"agent_id,omitempty"`
+	TeamID          *string           `json:"team_id,omitempty"`
+	WhatsAppAccount string            `json:"whatsapp_account"`
+}
+
 // maxConcurrentWebhooks limits the number of concurrent webhook deliveries per dispatch
 const maxConcurrentWebhooks = 10
 
@@ -39,9 +39,11 @@ var AvailableWebhookEvents = []map[string]string{
 	{"value": string(models.WebhookEventMessageIncoming), "label": "Message Incoming", "description": "When a new message is received from a contact"},
 	{"value": string(models.WebhookEventMessageSent), "label": "Message Sent", "description": "When an agent sends a message"},
 	{"value": string(models.WebhookEventContactCreated), "label": "Contact Created", "description": "When a new contact is created"},
+	{"value": string(models.WebhookEventContactBlocked), "label": "Contact Blocked", "description": "When a contact is blocked"},
 	{"value": string(models.WebhookEventTransferCreated), "label": "Transfer Created", "description": "When a transfer to human agent is requested"},
 	{"value": string(models.WebhookEventTransferAssigned), "label": "Transfer Assigned", "description": "When a transfer is assigned to an agent"},
 	{"value": string(models.WebhookEventTransferResumed), "label": "Transfer Resumed", "description": "When chatbot is resumed (transfer closed)"},
+	{"value": string(models.WebhookEventSurveyCompleted), "label": "Survey Completed", "description": "When a contact answers a satisfaction survey"},
 }
 
 // ListWebhooks returns all webhooks for the organization
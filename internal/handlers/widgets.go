@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/surveyutil"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
 	"gorm.io/gorm"
@@ -16,7 +17,7 @@ import (
 type WidgetRequest struct {
 	Name        string        `json:"name"`
 	Description string        `json:"description"`
-	DataSource  string        `json:"data_source"`  // messages, contacts, campaigns, transfers, sessions
+	DataSource  string        `json:"data_source"`  // messages, contacts, campaigns, transfers, sessions, surveys
 	Metric      string        `json:"metric"`       // count, sum, avg
 	Field       string        `json:"field"`        // Field for sum/avg
 	Filters     []FilterInput `json:"filters"`      // Filter conditions
@@ -124,6 +125,7 @@ var widgetDataSources = map[string][]string{
 	"campaigns": {"status", "message_status"},
 	"transfers": {"status", "source"},
 	"sessions":  {"status"},
+	"surveys":   {"type", "status", "channel", "score"},
 }
 
 // Available metrics
@@ -820,6 +822,10 @@ func (a *App) executeWidgetQuery(orgID uuid.UUID, widget models.Widget, fromStr,
 	case "sessions":
 		currentValue = a.querySessions(orgID, widget.Metric, filters, periodStart, periodEnd)
 		previousValue = a.querySessions(orgID, widget.Metric, filters, previousPeriodStart, previousPeriodEnd)
+
+	case "surveys":
+		currentValue = a.querySurveys(orgID, widget.Metric, widget.Field, filters, periodStart, periodEnd)
+		previousValue = a.querySurveys(orgID, widget.Metric, widget.Field, filters, previousPeriodStart, previousPeriodEnd)
 	}
 
 	response.Value = currentValue
@@ -937,6 +943,43 @@ func (a *App) querySessions(orgID uuid.UUID, _ string, filters []FilterInput, st
 	return float64(count)
 }
 
+// querySurveys counts surveys sent in a period, or averages the answered
+// ones: field "score" gives the average score and "nps" the net promoter
+// score of NPS surveys
+func (a *App) querySurveys(orgID uuid.UUID, metric, field string, filters []FilterInput, start, end time.Time) float64 {
+	query := a.DB.Model(&models.SurveyResponse{}).Where("organization_id = ? AND sent_at >= ? AND sent_at <= ?", orgID, start, end)
+
+	for _, f := range filters {
+		query = applyFilter(query, f)
+	}
+
+	var result float64
+	switch metric {
+	case "count":
+		var count int64
+		query.Count(&count)
+		result = float64(count)
+	case "avg":
+		query = query.Where("status = ?", models.SurveyStatusCompleted)
+		switch field {
+		case "score":
+			query.Select("COALESCE(AVG(score), 0)").Scan(&result)
+		case "nps":
+			var bands struct {
+				Total      int64
+				Promoters  int64
+				Detractors int64
+			}
+			query.Where("type = ?", models.SurveyTypeNPS).
+				Select("COUNT(*) as total, COUNT(*) FILTER (WHERE score >= ?) as promoters, COUNT(*) FILTER (WHERE score <= ?) as detractors",
+					surveyutil.PromoterMin, surveyutil.DetractorMax).
+				Scan(&bands)
+			result = surveyutil.NPS(bands.Promoters, bands.Detractors, bands.Total)
+		}
+	}
+	return result
+}
+
 func (a *App) getChartData(orgID uuid.UUID, widget models.Widget, filters []FilterInput, start, end time.Time) []ChartPoint {
 	chartData := make([]ChartPoint, 0)
 
@@ -988,6 +1031,8 @@ func resolveDataSourceTable(dataSource string) (tableName, dateField string, ok
 		return "agent_transfers", "transferred_at", true
 	case "sessions":
 		return "chatbot_sessions", "created_at", true
+	case "surveys":
+		return "survey_responses", "sent_at", true
 	default:
 		return "", "", false
 	}
@@ -1298,6 +1343,14 @@ var tableQuerySQL = map[string]struct{ base, orderBy string }{
 			WHERE s.organization_id = ? AND s.created_at >= ? AND s.created_at <= ?`,
 		orderBy: " ORDER BY s.created_at DESC LIMIT 10",
 	},
+	"surveys": {
+		base: `SELECT sr.id, COALESCE(c.profile_name, c.phone_number) as label,
+			COALESCE(sr.score::text || ' ', '') || COALESCE(LEFT(sr.comment, 60), '') as sub_label,
+			sr.status, '' as direction, sr.sent_at as created_at
+			FROM survey_responses sr LEFT JOIN contacts c ON c.id = sr.contact_id
+			WHERE sr.organization_id = ? AND sr.sent_at >= ? AND sr.sent_at <= ?`,
+		orderBy: " ORDER BY sr.sent_at DESC LIMIT 10",
+	},
 }
 
 // getTableRows returns the last 10 rows for a table widget based on the data source.
@@ -0,0 +1,121 @@
+package inpututil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+)
+
+// relativeDays maps words for nearby days to their offset from today
+var relativeDays = map[string]int{
+	"today": 0, "tomorrow": 1, "yesterday": -1, "day after tomorrow": 2,
+	"hoy": 0, "mañana": 1, "manana": 1, "ayer": -1, "pasado mañana": 2, "pasado manana": 2,
+	"hoje": 0, "amanhã": 1, "amanha": 1, "ontem": -1, "depois de amanhã": 2, "depois de amanha": 2,
+	"aujourd'hui": 0, "demain": 1, "hier": -1, "après-demain": 2, "apres-demain": 2,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// inDaysPattern matches "in 3 days" and "in 2 weeks"
+var inDaysPattern = regexp.MustCompile(`^in (\d{1,3}) (day|days|week|weeks)$`)
+
+// numericDatePattern matches 04/03/2025, 4-3-25 and 04.03.2025
+var numericDatePattern = regexp.MustCompile(`^(\d{1,2})[/.-](\d{1,2})[/.-](\d{2}|\d{4})$`)
+
+// textDateLayouts are tried in order for dates with month names
+var textDateLayouts = []string{
+	DateLayout,
+	"2006/01/02",
+	"2 Jan 2006", "2 January 2006", "2 Jan, 2006", "2 January, 2006",
+	"Jan 2 2006", "January 2 2006", "Jan 2, 2006", "January 2, 2006",
+	"2 Jan", "2 January", "Jan 2", "January 2",
+}
+
+// ParseDate reads a date typed by a customer, in the step's timezone. It
+// accepts ISO dates, numeric dates in the configured day/month order, dates
+// with English month names, and relative words such as "today", "tomorrow",
+// "next friday" or "in 3 days". Dates without a year are taken to be the next
+// occurrence.
+func ParseDate(input string, cfg Config, now time.Time) (time.Time, bool) {
+	loc := bizhours.LoadLocation(cfg.Timezone)
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	s := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	s = strings.TrimSuffix(s, ".")
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	if offset, ok := relativeDays[s]; ok {
+		return today.AddDate(0, 0, offset), true
+	}
+	if m := inDaysPattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if strings.HasPrefix(m[2], "week") {
+			n *= 7
+		}
+		return today.AddDate(0, 0, n), true
+	}
+	if day, ok := weekdays[strings.TrimPrefix(s, "next ")]; ok {
+		// The coming weekday, never today
+		ahead := (int(day) - int(today.Weekday()) + 7) % 7
+		if ahead == 0 {
+			ahead = 7
+		}
+		return today.AddDate(0, 0, ahead), true
+	}
+
+	if m := numericDatePattern.FindStringSubmatch(s); m != nil {
+		first, _ := strconv.Atoi(m[1])
+		second, _ := strconv.Atoi(m[2])
+		year, _ := strconv.Atoi(m[3])
+		if len(m[3]) == 2 {
+			year += 2000
+		}
+		day, month := first, second
+		if cfg.DateOrder == "mdy" {
+			day, month = second, first
+		}
+		return validDate(year, month, day, loc)
+	}
+
+	// Strip ordinal suffixes so "March 3rd" reads as "March 3"
+	s = ordinalPattern.ReplaceAllString(s, "$1")
+	for _, layout := range textDateLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") {
+			t = time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+			if t.Before(today) {
+				t = t.AddDate(1, 0, 0)
+			}
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+var ordinalPattern = regexp.MustCompile(`\b(\d{1,2})(st|nd|rd|th)\b`)
+
+// validDate builds a date, rejecting overflow such as 31/02
+func validDate(year, month, day int, loc *time.Location) (time.Time, bool) {
+	if month < 1 || month > 12 || day < 1 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,287 @@
+// Package inpututil validates and normalizes the answers customers give to
+// chatbot flow steps: numbers, email addresses, phone numbers, dates, text
+// length limits, and media files and locations.
+package inpututil
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/phoneutil"
+)
+
+// Error codes returned in *Error
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeInvalidNumber = "invalid_number"
+	CodeNumberTooLow  = "number_too_low"
+	CodeNumberTooHigh = "number_too_high"
+	CodeTooManyDigits = "too_many_decimals"
+	CodeInvalidEmail  = "invalid_email"
+	CodeInvalidPhone  = "invalid_phone"
+	CodeInvalidDate   = "invalid_date"
+	CodeDateTooEarly  = "date_too_early"
+	CodeDateTooLate   = "date_too_late"
+	CodeInvalidFormat = "invalid_format"
+
+	CodeImageRequired      = "image_required"
+	CodeDocumentRequired   = "document_required"
+	CodeAudioRequired      = "audio_required"
+	CodeVideoRequired      = "video_required"
+	CodeLocationRequired   = "location_required"
+	CodeFileTypeNotAllowed = "file_type_not_allowed"
+	CodeFileTooLarge       = "file_too_large"
+	CodeMediaUnavailable   = "media_unavailable"
+)
+
+// DateLayout is the ISO form dates are stored in
+const DateLayout = "2006-01-02"
+
+// Error describes why an answer was rejected. Params fill the {placeholders}
+// of the message.
+type Error struct {
+	Code   string
+	Params map[string]string
+}
+
+func (e *Error) Error() string {
+	return Message(e, "", nil)
+}
+
+func newError(code string, params ...string) *Error {
+	e := &Error{Code: code, Params: map[string]string{}}
+	for i := 0; i+1 < len(params); i += 2 {
+		e.Params[params[i]] = params[i+1]
+	}
+	return e
+}
+
+// Config holds the validation options of a step, read from its input_config
+type Config struct {
+	MinLength int
+	MaxLength int
+
+	// Numbers
+	Min      *float64
+	Max      *float64
+	Decimals *int // Maximum decimal places; 0 allows whole numbers only
+
+	// Phone numbers without a country code are read in this ISO region
+	DefaultRegion string
+
+	// Dates
+	Timezone  string // IANA name used for "today", "tomorrow", ...
+	DateOrder string // "dmy" (default) or "mdy" for numeric dates like 04/03/2025
+	MinDate   string // ISO date or relative word, e.g. "today"
+	MaxDate   string
+
+	// Media files
+	AllowedMimeTypes []string // e.g. "image/jpeg" or "image/*"
+	MaxSizeMB        float64
+
+	// Error messages
+	Language      string            // en, es, pt or fr
+	ErrorMessages map[string]string // Overrides by error code
+}
+
+// ParseConfig reads a Config from a step's input_config
+func ParseConfig(m map[string]interface{}) Config {
+	cfg := Config{ErrorMessages: map[string]string{}}
+	str := func(key string) string {
+		s, _ := m[key].(string)
+		return strings.TrimSpace(s)
+	}
+	num := func(key string) *float64 {
+		if f, ok := m[key].(float64); ok {
+			return &f
+		}
+		return nil
+	}
+
+	if n := num("min_length"); n != nil {
+		cfg.MinLength = int(*n)
+	}
+	if n := num("max_length"); n != nil {
+		cfg.MaxLength = int(*n)
+	}
+	cfg.Min = num("min")
+	cfg.Max = num("max")
+	if n := num("decimals"); n != nil {
+		d := int(*n)
+		cfg.Decimals = &d
+	}
+	cfg.DefaultRegion = strings.ToUpper(str("default_region"))
+	cfg.Timezone = str("timezone")
+	cfg.DateOrder = strings.ToLower(str("date_order"))
+	cfg.MinDate = str("min_date")
+	cfg.MaxDate = str("max_date")
+	cfg.Language = strings.ToLower(str("language"))
+	if types, ok := m["allowed_mime_types"].([]interface{}); ok {
+		for _, t := range types {
+			if s, ok := t.(string); ok && strings.TrimSpace(s) != "" {
+				cfg.AllowedMimeTypes = append(cfg.AllowedMimeTypes, strings.ToLower(strings.TrimSpace(s)))
+			}
+		}
+	}
+	if n := num("max_size_mb"); n != nil {
+		cfg.MaxSizeMB = *n
+	}
+	if msgs, ok := m["error_messages"].(map[string]interface{}); ok {
+		for code, msg := range msgs {
+			if s, ok := msg.(string); ok && s != "" {
+				cfg.ErrorMessages[code] = s
+			}
+		}
+	}
+	return cfg
+}
+
+// ValidateConfig checks the validation options of a step when it is saved
+func ValidateConfig(inputType models.InputType, m map[string]interface{}) error {
+	cfg := ParseConfig(m)
+	if cfg.MinLength < 0 || cfg.MaxLength < 0 {
+		return fmt.Errorf("min_length and max_length cannot be negative")
+	}
+	if cfg.MaxLength > 0 && cfg.MinLength > cfg.MaxLength {
+		return fmt.Errorf("min_length cannot be greater than max_length")
+	}
+	if cfg.Min != nil && cfg.Max != nil && *cfg.Min > *cfg.Max {
+		return fmt.Errorf("min cannot be greater than max")
+	}
+	if cfg.Decimals != nil && (*cfg.Decimals < 0 || *cfg.Decimals > 10) {
+		return fmt.Errorf("decimals must be between 0 and 10")
+	}
+	if cfg.DefaultRegion != "" && !phoneutil.IsValidRegion(cfg.DefaultRegion) {
+		return fmt.Errorf("invalid default_region %q", cfg.DefaultRegion)
+	}
+	if cfg.Timezone != "" && !bizhours.ValidTimezone(cfg.Timezone) {
+		return fmt.Errorf("invalid timezone %q", cfg.Timezone)
+	}
+	if cfg.DateOrder != "" && cfg.DateOrder != "dmy" && cfg.DateOrder != "mdy" {
+		return fmt.Errorf("date_order must be dmy or mdy")
+	}
+	if inputType == models.InputTypeDate {
+		now := time.Now()
+		for _, bound := range []string{cfg.MinDate, cfg.MaxDate} {
+			if bound == "" {
+				continue
+			}
+			if _, ok := ParseDate(bound, cfg, now); !ok {
+				return fmt.Errorf("invalid date bound %q", bound)
+			}
+		}
+	}
+	if err := validateMediaConfig(inputType, cfg); err != nil {
+		return err
+	}
+	if cfg.Language != "" && !IsSupportedLanguage(cfg.Language) {
+		return fmt.Errorf("unsupported language %q", cfg.Language)
+	}
+	return nil
+}
+
+// Validate checks an answer for a step's input type and returns the value to
+// store: a float64 for numbers, the lower-cased address for emails, E.164
+// digits for phone numbers, an ISO date for dates and the trimmed text
+// otherwise.
+func Validate(inputType models.InputType, input string, cfg Config, now time.Time) (interface{}, error) {
+	input = strings.TrimSpace(input)
+
+	length := utf8.RuneCountInString(input)
+	if cfg.MinLength > 0 && length < cfg.MinLength {
+		return nil, newError(CodeTooShort, "min", strconv.Itoa(cfg.MinLength))
+	}
+	if cfg.MaxLength > 0 && length > cfg.MaxLength {
+		return nil, newError(CodeTooLong, "max", strconv.Itoa(cfg.MaxLength))
+	}
+
+	switch inputType {
+	case models.InputTypeNumber:
+		return validateNumber(input, cfg)
+	case models.InputTypeEmail:
+		return validateEmail(input)
+	case models.InputTypePhone:
+		normalized, err := phoneutil.Normalize(input, cfg.DefaultRegion)
+		if err != nil || strings.Contains(normalized, "@") {
+			return nil, newError(CodeInvalidPhone)
+		}
+		return normalized, nil
+	case models.InputTypeDate:
+		return validateDate(input, cfg, now)
+	}
+	return input, nil
+}
+
+// thousandsPattern matches numbers grouped with commas, like 1,250,000.50
+var thousandsPattern = regexp.MustCompile(`^-?\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+func validateNumber(input string, cfg Config) (interface{}, error) {
+	s := strings.ReplaceAll(input, " ", "")
+	switch {
+	case thousandsPattern.MatchString(s):
+		s = strings.ReplaceAll(s, ",", "")
+	case strings.Count(s, ",") == 1 && !strings.Contains(s, "."):
+		// Decimal comma, as in 12,5
+		s = strings.Replace(s, ",", ".", 1)
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+		return nil, newError(CodeInvalidNumber)
+	}
+	if cfg.Decimals != nil {
+		if _, frac, ok := strings.Cut(s, "."); ok && len(strings.TrimRight(frac, "0")) > *cfg.Decimals {
+			return nil, newError(CodeTooManyDigits, "decimals", strconv.Itoa(*cfg.Decimals))
+		}
+	}
+	if cfg.Min != nil && n < *cfg.Min {
+		return nil, newError(CodeNumberTooLow, "min", formatNumber(*cfg.Min))
+	}
+	if cfg.Max != nil && n > *cfg.Max {
+		return nil, newError(CodeNumberTooHigh, "max", formatNumber(*cfg.Max))
+	}
+	return n, nil
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func validateEmail(input string) (interface{}, error) {
+	addr, err := mail.ParseAddress(input)
+	if err != nil || addr.Address != input && "<"+addr.Address+">" != input {
+		return nil, newError(CodeInvalidEmail)
+	}
+	_, domain, _ := strings.Cut(addr.Address, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return nil, newError(CodeInvalidEmail)
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+func validateDate(input string, cfg Config, now time.Time) (interface{}, error) {
+	date, ok := ParseDate(input, cfg, now)
+	if !ok {
+		return nil, newError(CodeInvalidDate)
+	}
+	if cfg.MinDate != "" {
+		if min, ok := ParseDate(cfg.MinDate, cfg, now); ok && date.Before(min) {
+			return nil, newError(CodeDateTooEarly, "min", min.Format(DateLayout))
+		}
+	}
+	if cfg.MaxDate != "" {
+		if max, ok := ParseDate(cfg.MaxDate, cfg, now); ok && date.After(max) {
+			return nil, newError(CodeDateTooLate, "max", max.Format(DateLayout))
+		}
+	}
+	return date.Format(DateLayout), nil
+}
@@ -0,0 +1,229 @@
+package inpututil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Wednesday
+var now = time.Date(2025, 3, 5, 22, 30, 0, 0, time.UTC)
+
+func errorCode(err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return ""
+}
+
+func TestValidate_Number(t *testing.T) {
+	cfg := ParseConfig(map[string]interface{}{"min": 1.0, "max": 5000.0, "decimals": 2.0})
+	tests := []struct {
+		input    string
+		want     float64
+		wantCode string
+	}{
+		{"42", 42, ""},
+		{" 12.50 ", 12.5, ""},
+		{"12,5", 12.5, ""},
+		{"1,250.75", 1250.75, ""},
+		{"1,250", 1250, ""},
+		{"abc", 0, CodeInvalidNumber},
+		{"0.5", 0, CodeNumberTooLow},
+		{"5000.01", 0, CodeNumberTooHigh},
+		{"3.125", 0, CodeTooManyDigits},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Validate(models.InputTypeNumber, tt.input, cfg, now)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, errorCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := Validate(models.InputTypeNumber, "2.5", ParseConfig(map[string]interface{}{"decimals": 0.0}), now)
+	assert.Equal(t, CodeTooManyDigits, errorCode(err))
+}
+
+func TestValidate_Email(t *testing.T) {
+	got, err := Validate(models.InputTypeEmail, " Asha.Rao@Example.COM ", Config{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "asha.rao@example.com", got)
+
+	for _, input := range []string{"asha", "asha@", "asha@localhost", "Asha <asha@example.com>", "a b@example.com"} {
+		_, err := Validate(models.InputTypeEmail, input, Config{}, now)
+		assert.Equal(t, CodeInvalidEmail, errorCode(err), input)
+	}
+}
+
+func TestValidate_Phone(t *testing.T) {
+	cfg := Config{DefaultRegion: "IN"}
+	got, err := Validate(models.InputTypePhone, "098765 43210", cfg, now)
+	require.NoError(t, err)
+	assert.Equal(t, "919876543210", got)
+
+	got, err = Validate(models.InputTypePhone, "+44 20 7946 0958", cfg, now)
+	require.NoError(t, err)
+	assert.Equal(t, "442079460958", got)
+
+	for _, input := range []string{"call me", "123", "group@g.us"} {
+		_, err := Validate(models.InputTypePhone, input, cfg, now)
+		assert.Equal(t, CodeInvalidPhone, errorCode(err), input)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		cfg   Config
+		want  string
+	}{
+		{"2025-12-25", Config{}, "2025-12-25"},
+		{"04/03/2025", Config{}, "2025-03-04"},
+		{"04/03/2025", Config{DateOrder: "mdy"}, "2025-04-03"},
+		{"4-3-25", Config{}, "2025-03-04"},
+		{"25.12.2025", Config{}, "2025-12-25"},
+		{"25 Dec 2025", Config{}, "2025-12-25"},
+		{"December 25, 2025", Config{}, "2025-12-25"},
+		{"March 3rd", Config{}, "2026-03-03"},
+		{"10 March", Config{}, "2025-03-10"},
+		{"today", Config{}, "2025-03-05"},
+		{"Tomorrow", Config{}, "2025-03-06"},
+		{"day after tomorrow", Config{}, "2025-03-07"},
+		{"yesterday", Config{}, "2025-03-04"},
+		{"in 3 days", Config{}, "2025-03-08"},
+		{"in 2 weeks", Config{}, "2025-03-19"},
+		{"friday", Config{}, "2025-03-07"},
+		{"next wednesday", Config{}, "2025-03-12"},
+		{"mañana", Config{}, "2025-03-06"},
+		{"demain", Config{}, "2025-03-06"},
+		// 22:30 UTC is already the next day in Kolkata
+		{"today", Config{Timezone: "Asia/Kolkata"}, "2025-03-06"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseDate(tt.input, tt.cfg, now)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got.Format(DateLayout))
+		})
+	}
+
+	for _, input := range []string{"31/02/2025", "13/13/2025", "someday", ""} {
+		_, ok := ParseDate(input, Config{}, now)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestValidate_DateBounds(t *testing.T) {
+	cfg := ParseConfig(map[string]interface{}{"min_date": "today", "max_date": "2025-03-31"})
+
+	got, err := Validate(models.InputTypeDate, "tomorrow", cfg, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-03-06", got)
+
+	_, err = Validate(models.InputTypeDate, "yesterday", cfg, now)
+	assert.Equal(t, CodeDateTooEarly, errorCode(err))
+	assert.Equal(t, "Please enter a date on or after 2025-03-05.", err.Error())
+
+	_, err = Validate(models.InputTypeDate, "01/04/2025", cfg, now)
+	assert.Equal(t, CodeDateTooLate, errorCode(err))
+
+	_, err = Validate(models.InputTypeDate, "soon", cfg, now)
+	assert.Equal(t, CodeInvalidDate, errorCode(err))
+}
+
+func TestValidate_Length(t *testing.T) {
+	cfg := ParseConfig(map[string]interface{}{"min_length": 2.0, "max_length": 5.0})
+
+	got, err := Validate(models.InputTypeText, " José ", cfg, now)
+	require.NoError(t, err)
+	assert.Equal(t, "José", got)
+
+	_, err = Validate(models.InputTypeText, "J", cfg, now)
+	assert.Equal(t, CodeTooShort, errorCode(err))
+	_, err = Validate(models.InputTypeText, "Joséphine", cfg, now)
+	assert.Equal(t, CodeTooLong, errorCode(err))
+}
+
+func TestMessage(t *testing.T) {
+	err := &Error{Code: CodeNumberTooHigh, Params: map[string]string{"max": "10"}}
+
+	assert.Equal(t, "Please enter a number no greater than 10.", Message(err, "", nil))
+	assert.Equal(t, "Por favor, introduce un número no mayor que 10.", Message(err, "es", nil))
+	assert.Equal(t, "Por favor, digite um número não maior que 10.", Message(err, "pt-BR", nil))
+	assert.Equal(t, "Please enter a number no greater than 10.", Message(err, "de", nil))
+	assert.Equal(t, "Max is 10!", Message(err, "es", map[string]string{CodeNumberTooHigh: "Max is {max}!"}))
+
+	assert.Equal(t, "(2 attempts left)", AttemptsLeft("en", 2))
+	assert.Equal(t, "(2 tentatives restantes)", AttemptsLeft("fr", 2))
+}
+
+func TestValidateConfig(t *testing.T) {
+	assert.NoError(t, ValidateConfig(models.InputTypeDate, map[string]interface{}{
+		"min_date": "today", "timezone": "Asia/Kolkata", "date_order": "mdy", "language": "es",
+	}))
+	assert.NoError(t, ValidateConfig(models.InputTypeText, nil))
+
+	tests := []struct {
+		inputType models.InputType
+		config    map[string]interface{}
+		wantErr   string
+	}{
+		{models.InputTypeNumber, map[string]interface{}{"min": 10.0, "max": 1.0}, "min cannot be greater than max"},
+		{models.InputTypeNumber, map[string]interface{}{"decimals": -1.0}, "decimals must be between 0 and 10"},
+		{models.InputTypeText, map[string]interface{}{"min_length": 5.0, "max_length": 2.0}, "min_length cannot be greater than max_length"},
+		{models.InputTypePhone, map[string]interface{}{"default_region": "XX"}, `invalid default_region "XX"`},
+		{models.InputTypeDate, map[string]interface{}{"timezone": "Mars/Base"}, `invalid timezone "Mars/Base"`},
+		{models.InputTypeDate, map[string]interface{}{"date_order": "ymd"}, "date_order must be dmy or mdy"},
+		{models.InputTypeDate, map[string]interface{}{"max_date": "whenever"}, `invalid date bound "whenever"`},
+		{models.InputTypeText, map[string]interface{}{"language": "xx"}, `unsupported language "xx"`},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, ValidateConfig(tt.inputType, tt.config), tt.wantErr)
+	}
+}
+
+func TestValidateMedia(t *testing.T) {
+	cfg := ParseConfig(map[string]interface{}{
+		"allowed_mime_types": []interface{}{"image/jpeg", "image/png", "application/*"},
+		"max_size_mb":        1.5,
+	})
+
+	assert.NoError(t, ValidateMedia(models.InputTypeImage, models.InputTypeImage, "image/jpeg", 1024, cfg))
+	assert.NoError(t, ValidateMedia(models.InputTypeDocument, models.InputTypeDocument, "application/pdf", 1024, cfg))
+	assert.NoError(t, ValidateMedia(models.InputTypeAudio, models.InputTypeAudio, "audio/ogg; codecs=opus", 1024, Config{AllowedMimeTypes: []string{"audio/ogg"}}))
+	assert.NoError(t, ValidateMedia(models.InputTypeLocation, models.InputTypeLocation, "", 0, cfg))
+
+	err := ValidateMedia(models.InputTypeImage, models.InputTypeVideo, "video/mp4", 1024, cfg)
+	assert.Equal(t, CodeImageRequired, errorCode(err))
+	err = ValidateMedia(models.InputTypeLocation, "", "", 0, cfg)
+	assert.Equal(t, "Please share your location.", err.Error())
+
+	err = ValidateMedia(models.InputTypeImage, models.InputTypeImage, "image/gif", 1024, cfg)
+	assert.Equal(t, "This file type is not accepted. Please send one of: image/jpeg, image/png, application/*.", err.Error())
+
+	err = ValidateMedia(models.InputTypeImage, models.InputTypeImage, "image/png", 2*1024*1024, cfg)
+	assert.Equal(t, "This file is too large. Please send a file of at most 1.5 MB.", err.Error())
+}
+
+func TestValidateConfig_Media(t *testing.T) {
+	assert.NoError(t, ValidateConfig(models.InputTypeDocument, map[string]interface{}{
+		"allowed_mime_types": []interface{}{"application/pdf", "image/*"}, "max_size_mb": 10.0,
+	}))
+
+	assert.EqualError(t, ValidateConfig(models.InputTypeImage, map[string]interface{}{"allowed_mime_types": []interface{}{"jpeg"}}),
+		`invalid MIME type "jpeg"`)
+	assert.EqualError(t, ValidateConfig(models.InputTypeVideo, map[string]interface{}{"max_size_mb": 500.0}),
+		"max_size_mb must be between 0 and 100")
+	assert.EqualError(t, ValidateConfig(models.InputTypeText, map[string]interface{}{"max_size_mb": 5.0}),
+		"allowed_mime_types and max_size_mb only apply to image, document, audio and video inputs")
+	assert.EqualError(t, ValidateConfig(models.InputTypeLocation, map[string]interface{}{"allowed_mime_types": []interface{}{"image/*"}}),
+		"allowed_mime_types and max_size_mb only apply to image, document, audio and video inputs")
+}
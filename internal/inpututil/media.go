@@ -0,0 +1,83 @@
+package inpututil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// MaxSizeMB is the largest max_size_mb a step can set. WhatsApp itself caps
+// documents at 100 MB.
+const MaxSizeMB = 100
+
+// requiredCodes maps the input types answered with media or a location to the
+// error sent when something else arrives
+var requiredCodes = map[models.InputType]string{
+	models.InputTypeImage:    CodeImageRequired,
+	models.InputTypeDocument: CodeDocumentRequired,
+	models.InputTypeAudio:    CodeAudioRequired,
+	models.InputTypeVideo:    CodeVideoRequired,
+	models.InputTypeLocation: CodeLocationRequired,
+}
+
+// IsMediaInput reports whether a step is answered with media or a location
+// rather than text
+func IsMediaInput(inputType models.InputType) bool {
+	_, ok := requiredCodes[inputType]
+	return ok
+}
+
+// ValidateMedia checks that a received message of type received answers a step
+// of type inputType, and that a file's MIME type and size (in bytes) meet the
+// step's constraints
+func ValidateMedia(inputType, received models.InputType, mimeType string, size int64, cfg Config) error {
+	if received != inputType {
+		return newError(requiredCodes[inputType])
+	}
+	if inputType == models.InputTypeLocation {
+		return nil
+	}
+	if len(cfg.AllowedMimeTypes) > 0 && !mimeTypeAllowed(mimeType, cfg.AllowedMimeTypes) {
+		return newError(CodeFileTypeNotAllowed, "types", strings.Join(cfg.AllowedMimeTypes, ", "))
+	}
+	if cfg.MaxSizeMB > 0 && float64(size) > cfg.MaxSizeMB*1024*1024 {
+		return newError(CodeFileTooLarge, "max", formatNumber(cfg.MaxSizeMB))
+	}
+	return nil
+}
+
+// mimeTypeAllowed matches a MIME type against patterns like "image/jpeg" and
+// "image/*". Parameters such as "; codecs=opus" are ignored.
+func mimeTypeAllowed(mimeType string, allowed []string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mimeType, prefix+"/") {
+				return true
+			}
+		} else if mimeType == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMediaConfig checks allowed_mime_types and max_size_mb
+func validateMediaConfig(inputType models.InputType, cfg Config) error {
+	if (len(cfg.AllowedMimeTypes) > 0 || cfg.MaxSizeMB != 0) &&
+		(!IsMediaInput(inputType) || inputType == models.InputTypeLocation) {
+		return fmt.Errorf("allowed_mime_types and max_size_mb only apply to image, document, audio and video inputs")
+	}
+	for _, pattern := range cfg.AllowedMimeTypes {
+		kind, sub, ok := strings.Cut(pattern, "/")
+		if !ok || kind == "" || sub == "" || strings.Contains(sub, "/") {
+			return fmt.Errorf("invalid MIME type %q", pattern)
+		}
+	}
+	if cfg.MaxSizeMB < 0 || cfg.MaxSizeMB > MaxSizeMB {
+		return fmt.Errorf("max_size_mb must be between 0 and %d", MaxSizeMB)
+	}
+	return nil
+}
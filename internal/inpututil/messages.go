@@ -0,0 +1,148 @@
+package inpututil
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when a step and session name no supported language
+const DefaultLanguage = "en"
+
+// messages holds the built-in error messages by language and error code
+var messages = map[string]map[string]string{
+	"en": {
+		CodeTooShort:           "Please enter at least {min} characters.",
+		CodeTooLong:            "Please enter at most {max} characters.",
+		CodeInvalidNumber:      "Please enter a valid number.",
+		CodeNumberTooLow:       "Please enter a number of at least {min}.",
+		CodeNumberTooHigh:      "Please enter a number no greater than {max}.",
+		CodeTooManyDigits:      "Please enter a number with at most {decimals} decimal places.",
+		CodeInvalidEmail:       "Please enter a valid email address.",
+		CodeInvalidPhone:       "Please enter a valid phone number.",
+		CodeInvalidDate:        "Please enter a valid date, for example 25/12/2025 or tomorrow.",
+		CodeDateTooEarly:       "Please enter a date on or after {min}.",
+		CodeDateTooLate:        "Please enter a date on or before {max}.",
+		CodeInvalidFormat:      "Invalid input. Please try again.",
+		CodeImageRequired:      "Please send a photo.",
+		CodeDocumentRequired:   "Please send a document.",
+		CodeAudioRequired:      "Please send an audio message.",
+		CodeVideoRequired:      "Please send a video.",
+		CodeLocationRequired:   "Please share your location.",
+		CodeFileTypeNotAllowed: "This file type is not accepted. Please send one of: {types}.",
+		CodeFileTooLarge:       "This file is too large. Please send a file of at most {max} MB.",
+		CodeMediaUnavailable:   "We couldn't receive your file. Please send it again.",
+		"attempts_left":        "({attempts} attempts left)",
+	},
+	"es": {
+		CodeTooShort:           "Por favor, introduce al menos {min} caracteres.",
+		CodeTooLong:            "Por favor, introduce como máximo {max} caracteres.",
+		CodeInvalidNumber:      "Por favor, introduce un número válido.",
+		CodeNumberTooLow:       "Por favor, introduce un número de al menos {min}.",
+		CodeNumberTooHigh:      "Por favor, introduce un número no mayor que {max}.",
+		CodeTooManyDigits:      "Por favor, introduce un número con como máximo {decimals} decimales.",
+		CodeInvalidEmail:       "Por favor, introduce un correo electrónico válido.",
+		CodeInvalidPhone:       "Por favor, introduce un número de teléfono válido.",
+		CodeInvalidDate:        "Por favor, introduce una fecha válida, por ejemplo 25/12/2025 o mañana.",
+		CodeDateTooEarly:       "Por favor, introduce una fecha a partir del {min}.",
+		CodeDateTooLate:        "Por favor, introduce una fecha hasta el {max}.",
+		CodeInvalidFormat:      "Entrada no válida. Inténtalo de nuevo.",
+		CodeImageRequired:      "Por favor, envía una foto.",
+		CodeDocumentRequired:   "Por favor, envía un documento.",
+		CodeAudioRequired:      "Por favor, envía un mensaje de audio.",
+		CodeVideoRequired:      "Por favor, envía un vídeo.",
+		CodeLocationRequired:   "Por favor, comparte tu ubicación.",
+		CodeFileTypeNotAllowed: "Este tipo de archivo no se acepta. Envía uno de: {types}.",
+		CodeFileTooLarge:       "El archivo es demasiado grande. Envía un archivo de como máximo {max} MB.",
+		CodeMediaUnavailable:   "No pudimos recibir tu archivo. Por favor, envíalo de nuevo.",
+		"attempts_left":        "({attempts} intentos restantes)",
+	},
+	"pt": {
+		CodeTooShort:           "Por favor, digite pelo menos {min} caracteres.",
+		CodeTooLong:            "Por favor, digite no máximo {max} caracteres.",
+		CodeInvalidNumber:      "Por favor, digite um número válido.",
+		CodeNumberTooLow:       "Por favor, digite um número de pelo menos {min}.",
+		CodeNumberTooHigh:      "Por favor, digite um número não maior que {max}.",
+		CodeTooManyDigits:      "Por favor, digite um número com no máximo {decimals} casas decimais.",
+		CodeInvalidEmail:       "Por favor, digite um e-mail válido.",
+		CodeInvalidPhone:       "Por favor, digite um número de telefone válido.",
+		CodeInvalidDate:        "Por favor, digite uma data válida, por exemplo 25/12/2025 ou amanhã.",
+		CodeDateTooEarly:       "Por favor, digite uma data a partir de {min}.",
+		CodeDateTooLate:        "Por favor, digite uma data até {max}.",
+		CodeInvalidFormat:      "Entrada inválida. Tente novamente.",
+		CodeImageRequired:      "Por favor, envie uma foto.",
+		CodeDocumentRequired:   "Por favor, envie um documento.",
+		CodeAudioRequired:      "Por favor, envie uma mensagem de áudio.",
+		CodeVideoRequired:      "Por favor, envie um vídeo.",
+		CodeLocationRequired:   "Por favor, compartilhe sua localização.",
+		CodeFileTypeNotAllowed: "Este tipo de arquivo não é aceito. Envie um destes: {types}.",
+		CodeFileTooLarge:       "O arquivo é muito grande. Envie um arquivo de no máximo {max} MB.",
+		CodeMediaUnavailable:   "Não conseguimos receber seu arquivo. Por favor, envie novamente.",
+		"attempts_left":        "({attempts} tentativas restantes)",
+	},
+	"fr": {
+		CodeTooShort:           "Veuillez saisir au moins {min} caractères.",
+		CodeTooLong:            "Veuillez saisir au plus {max} caractères.",
+		CodeInvalidNumber:      "Veuillez saisir un nombre valide.",
+		CodeNumberTooLow:       "Veuillez saisir un nombre d'au moins {min}.",
+		CodeNumberTooHigh:      "Veuillez saisir un nombre ne dépassant pas {max}.",
+		CodeTooManyDigits:      "Veuillez saisir un nombre avec au plus {decimals} décimales.",
+		CodeInvalidEmail:       "Veuillez saisir une adresse e-mail valide.",
+		CodeInvalidPhone:       "Veuillez saisir un numéro de téléphone valide.",
+		CodeInvalidDate:        "Veuillez saisir une date valide, par exemple 25/12/2025 ou demain.",
+		CodeDateTooEarly:       "Veuillez saisir une date à partir du {min}.",
+		CodeDateTooLate:        "Veuillez saisir une date jusqu'au {max}.",
+		CodeInvalidFormat:      "Saisie invalide. Veuillez réessayer.",
+		CodeImageRequired:      "Veuillez envoyer une photo.",
+		CodeDocumentRequired:   "Veuillez envoyer un document.",
+		CodeAudioRequired:      "Veuillez envoyer un message audio.",
+		CodeVideoRequired:      "Veuillez envoyer une vidéo.",
+		CodeLocationRequired:   "Veuillez partager votre position.",
+		CodeFileTypeNotAllowed: "Ce type de fichier n'est pas accepté. Veuillez envoyer l'un de : {types}.",
+		CodeFileTooLarge:       "Ce fichier est trop volumineux. Veuillez envoyer un fichier de {max} Mo maximum.",
+		CodeMediaUnavailable:   "Nous n'avons pas pu recevoir votre fichier. Veuillez le renvoyer.",
+		"attempts_left":        "({attempts} tentatives restantes)",
+	},
+}
+
+// IsSupportedLanguage reports whether there are built-in messages for lang
+func IsSupportedLanguage(lang string) bool {
+	_, ok := messages[baseLanguage(lang)]
+	return ok
+}
+
+// baseLanguage reduces a tag like "pt-BR" to "pt"
+func baseLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// Message renders the error message for err in lang. An override for the
+// error's code wins over the built-in text; unknown languages fall back to
+// English.
+func Message(err *Error, lang string, overrides map[string]string) string {
+	text, ok := overrides[err.Code]
+	if !ok {
+		text = lookup(lang, err.Code)
+	}
+	for name, value := range err.Params {
+		text = strings.ReplaceAll(text, "{"+name+"}", value)
+	}
+	return text
+}
+
+// AttemptsLeft renders the "(N attempts left)" suffix shown after an error
+func AttemptsLeft(lang string, attempts int) string {
+	return strings.ReplaceAll(lookup(lang, "attempts_left"), "{attempts}", strconv.Itoa(attempts))
+}
+
+func lookup(lang, key string) string {
+	if msgs, ok := messages[baseLanguage(lang)]; ok {
+		if text, ok := msgs[key]; ok {
+			return text
+		}
+	}
+	return messages[DefaultLanguage][key]
+}
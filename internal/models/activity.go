@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ContactActivity records contact changes that aren't otherwise persisted,
+// such as tag and assignment changes, for the contact timeline.
+type ContactActivity struct {
+	BaseModel
+	OrganizationID uuid.UUID           `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID      uuid.UUID           `gorm:"type:uuid;index;not null" json:"contact_id"`
+	Type           ContactActivityType `gorm:"size:50;not null" json:"type"`
+	ActorID        *uuid.UUID          `gorm:"type:uuid" json:"actor_id,omitempty"` // User who made the change, nil for system changes
+	Data           JSONB               `gorm:"type:jsonb;default:'{}'" json:"data"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Contact      *Contact      `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+}
+
+func (ContactActivity) TableName() string {
+	return "contact_activities"
+}
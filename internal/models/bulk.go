@@ -23,14 +23,19 @@ type BulkMessageCampaign struct {
 	DeliveredCount  int        `gorm:"default:0" json:"delivered_count"`
 	ReadCount       int        `gorm:"default:0" json:"read_count"`
 	FailedCount     int        `gorm:"default:0" json:"failed_count"`
+	OptedOutCount   int        `gorm:"default:0" json:"opted_out_count"` // Recipients skipped because they opted out
 	ScheduledAt     *time.Time `json:"scheduled_at,omitempty"`
 	StartedAt       *time.Time `json:"started_at,omitempty"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
 	CreatedBy       uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	SegmentID       *uuid.UUID `gorm:"type:uuid;index" json:"segment_id,omitempty"` // Recipients are added from this segment when the campaign starts
+	BusinessHoursOnly bool     `gorm:"default:false" json:"business_hours_only"`       // Only send while the account is within business hours
+	ResumeAt          *time.Time `json:"resume_at,omitempty"`                          // When a campaign paused outside business hours resumes
 
 	// Relations
 	Organization *Organization          `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 	Template     *Template              `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+	Segment      *Segment               `gorm:"foreignKey:SegmentID" json:"segment,omitempty"`
 	Creator      *User                  `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
 	Recipients   []BulkMessageRecipient `gorm:"foreignKey:CampaignID" json:"recipients,omitempty"`
 }
@@ -46,7 +51,7 @@ type BulkMessageRecipient struct {
 	PhoneNumber        string     `gorm:"size:50;not null" json:"phone_number"`
 	RecipientName      string     `gorm:"size:255" json:"recipient_name"`
 	TemplateParams     JSONB      `gorm:"type:jsonb;default:'{}'" json:"template_params"`
-	Status             MessageStatus `gorm:"size:20;default:'pending'" json:"status"` // pending, sent, delivered, read, failed
+	Status             MessageStatus `gorm:"size:20;default:'pending'" json:"status"` // pending, sent, delivered, read, failed, opted_out
 	WhatsAppMessageID  string     `gorm:"column:whats_app_message_id;size:100;index" json:"whatsapp_message_id,omitempty"`
 	MessageID          *uuid.UUID `gorm:"type:uuid" json:"message_id,omitempty"`
 	ErrorMessage       string     `gorm:"type:text" json:"error_message"`
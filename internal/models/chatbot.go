@@ -31,6 +31,7 @@ type SLAConfig struct {
 	AutoCloseMessage    string      `gorm:"column:sla_auto_close_message;type:text" json:"sla_auto_close_message"`          // Message to customer when chat is auto-closed
 	WarningMessage      string      `gorm:"column:sla_warning_message;type:text" json:"sla_warning_message"`                // Message to customer when SLA breached
 	EscalationNotifyIDs StringArray `gorm:"column:sla_escalation_notify_ids;type:jsonb;default:'[]'" json:"sla_escalation_notify_ids"` // User IDs to notify on escalation
+	BusinessTime        bool        `gorm:"column:sla_business_time;default:false" json:"sla_business_time"`                // Count response/resolution/escalation minutes within business hours only
 }
 
 // ClientInactivityConfig holds client inactivity and reminder settings
@@ -100,6 +101,7 @@ type ChatbotSettings struct {
 	SLA              SLAConfig              `gorm:"embedded"`
 	ClientInactivity ClientInactivityConfig `gorm:"embedded"`
 	AI               AIConfig               `gorm:"embedded"`
+	Survey           SurveyConfig           `gorm:"embedded"`
 
 	// Session settings
 	SessionTimeoutMins int        `gorm:"default:30" json:"session_timeout_minutes"`
@@ -158,6 +160,11 @@ type ChatbotFlow struct {
 	CancelKeywords     StringArray `gorm:"type:jsonb" json:"cancel_keywords"`
 	PanelConfig        JSONB       `gorm:"type:jsonb;default:'{}'" json:"panel_config"` // Contact info panel configuration
 
+	// The fields above and the steps are the draft; contacts run the published
+	// version. Flows that were never published run as saved.
+	PublishedVersionID *uuid.UUID `gorm:"type:uuid" json:"published_version_id,omitempty"`
+	HasDraftChanges    bool       `gorm:"default:false" json:"has_draft_changes"`
+
 	// Relations
 	Organization    *Organization     `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 	InitialTemplate *Template         `gorm:"foreignKey:InitialTemplateID" json:"initial_template,omitempty"`
@@ -180,7 +187,11 @@ type ChatbotFlowStep struct {
 	ApiConfig       JSONB      `gorm:"type:jsonb" json:"api_config"`      // {url, method, headers, body, response_path, fallback_message}
 	Buttons         JSONBArray `gorm:"type:jsonb" json:"buttons"`         // [{id, title}] - max 10 options (3=buttons, 4-10=list)
 	TransferConfig  JSONB      `gorm:"type:jsonb" json:"transfer_config"` // {team_id: uuid, notes: string} - for transfer message type
-	InputType       InputType  `gorm:"size:20" json:"input_type"`         // none, text, number, email, phone, date, select, button, whatsapp_flow
+	TemplateParams  JSONB      `gorm:"type:jsonb" json:"template_params"` // {"1": "{{name}}", "order_id": "{{order.id}}"} - for template message type
+	ScriptConfig    JSONB      `gorm:"type:jsonb" json:"script_config"`   // {assignments: [{variable, expression|value}]} - for script message type
+	SubflowConfig   JSONB      `gorm:"type:jsonb" json:"subflow_config"`  // {flow_id} - for subflow and goto_flow message types
+	LoopConfig      JSONB      `gorm:"type:jsonb" json:"loop_config"`     // {back_to, condition, max_iterations, collect, limit_message} - for loop message type
+	InputType       InputType  `gorm:"size:20" json:"input_type"`         // none, text, number, email, phone, date, select, button, whatsapp_flow, image, document, audio, video, location
 	InputConfig     JSONB      `gorm:"type:jsonb" json:"input_config"`
 	ValidationRegex string     `gorm:"size:255" json:"validation_regex"`
 	ValidationError string     `gorm:"type:text" json:"validation_error"`
@@ -200,6 +211,50 @@ func (ChatbotFlowStep) TableName() string {
 	return "chatbot_flow_steps"
 }
 
+// ChatbotFlowVersion is an immutable published snapshot of a flow's
+// triggers, messages, completion settings and steps
+type ChatbotFlowVersion struct {
+	BaseModel
+	OrganizationID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	FlowID              uuid.UUID  `gorm:"type:uuid;index;not null" json:"flow_id"`
+	Version             int        `gorm:"not null" json:"version"`
+	Definition          JSONB      `gorm:"type:jsonb;not null" json:"definition"`
+	Notes               string     `gorm:"type:text" json:"notes"`
+	PublishedByID       *uuid.UUID `gorm:"type:uuid" json:"published_by_id,omitempty"`
+	RestoredFromVersion int        `gorm:"default:0" json:"restored_from_version,omitempty"` // Set when the version is a rollback
+
+	// Relations
+	Flow        *ChatbotFlow `gorm:"foreignKey:FlowID" json:"flow,omitempty"`
+	PublishedBy *User        `gorm:"foreignKey:PublishedByID" json:"published_by,omitempty"`
+}
+
+func (ChatbotFlowVersion) TableName() string {
+	return "chatbot_flow_versions"
+}
+
+// ChatbotFlowTestCase is a scripted conversation run against a flow in the
+// simulator, so flows can be regression-tested without a real phone
+type ChatbotFlowTestCase struct {
+	BaseModel
+	OrganizationID uuid.UUID   `gorm:"type:uuid;index;not null" json:"organization_id"`
+	FlowID         uuid.UUID   `gorm:"type:uuid;index;not null" json:"flow_id"`
+	Name           string      `gorm:"size:255;not null" json:"name"`
+	Description    string      `gorm:"type:text" json:"description"`
+	Contact        JSONB       `gorm:"type:jsonb;default:'{}'" json:"contact"`   // {profile_name, phone_number, attributes} of the virtual contact
+	Turns          JSONBArray  `gorm:"type:jsonb;default:'[]'" json:"turns"`     // [{text, button_id, flow_response, media, location, expect}]
+	APIMocks       JSONBArray  `gorm:"type:jsonb;default:'[]'" json:"api_mocks"` // [{method, url, status, body}] answering api_fetch steps and webhooks
+	LastRunAt      *time.Time  `json:"last_run_at,omitempty"`
+	LastPassed     *bool       `json:"last_passed,omitempty"`
+	LastFailures   StringArray `gorm:"type:jsonb;default:'[]'" json:"last_failures"`
+
+	// Relations
+	Flow *ChatbotFlow `gorm:"foreignKey:FlowID" json:"flow,omitempty"`
+}
+
+func (ChatbotFlowTestCase) TableName() string {
+	return "chatbot_flow_test_cases"
+}
+
 // ChatbotSession tracks active conversation sessions
 type ChatbotSession struct {
 	BaseModel
@@ -209,9 +264,11 @@ type ChatbotSession struct {
 	PhoneNumber     string     `gorm:"size:50;not null" json:"phone_number"`
 	Status          SessionStatus `gorm:"size:20;default:'active'" json:"status"` // active, completed, cancelled, timeout
 	CurrentFlowID   *uuid.UUID `gorm:"type:uuid" json:"current_flow_id,omitempty"`
+	FlowVersionID   *uuid.UUID `gorm:"type:uuid;index" json:"flow_version_id,omitempty"` // Version of the current flow the session is pinned to
 	CurrentStep     string     `gorm:"size:100" json:"current_step"`
 	StepRetries     int        `gorm:"default:0" json:"step_retries"`
 	SessionData     JSONB      `gorm:"type:jsonb;default:'{}'" json:"session_data"`
+	CallStack       JSONBArray `gorm:"type:jsonb;default:'[]'" json:"call_stack"` // [{flow_id, flow_version_id, step, session_data}] - flows waiting for a subflow to return
 	StartedAt       time.Time  `gorm:"autoCreateTime" json:"started_at"`
 	LastActivityAt  time.Time  `json:"last_activity_at"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
@@ -220,6 +277,7 @@ type ChatbotSession struct {
 	Organization *Organization           `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
 	Contact      *Contact                `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
 	CurrentFlow  *ChatbotFlow            `gorm:"foreignKey:CurrentFlowID" json:"current_flow,omitempty"`
+	FlowVersion  *ChatbotFlowVersion     `gorm:"foreignKey:FlowVersionID" json:"flow_version,omitempty"`
 	Messages     []ChatbotSessionMessage `gorm:"foreignKey:SessionID" json:"messages,omitempty"`
 }
 
@@ -276,6 +334,12 @@ type SLATracking struct {
 	EscalatedAt        *time.Time `gorm:"column:escalated_at" json:"escalated_at,omitempty"`                            // When escalation occurred
 	Breached           bool       `gorm:"column:sla_breached;default:false" json:"sla_breached"`                        // Whether SLA was breached
 	BreachedAt         *time.Time `gorm:"column:sla_breached_at" json:"sla_breached_at,omitempty"`                      // When SLA was breached
+	BusinessTime       bool       `gorm:"column:sla_business_time;default:false" json:"sla_business_time"`              // Deadlines are counted in business hours
+	PausedAt           *time.Time `gorm:"column:sla_paused_at" json:"sla_paused_at,omitempty"`                          // Set while business hours are closed
+	PolicyID           *uuid.UUID `gorm:"column:sla_policy_id;type:uuid;index" json:"sla_policy_id,omitempty"`          // SLA policy applied (null = chatbot settings)
+	StartedAt          *time.Time `gorm:"column:sla_started_at" json:"sla_started_at,omitempty"`                        // When the SLA clock started
+	WarningAt          *time.Time `gorm:"column:sla_warning_at" json:"sla_warning_at,omitempty"`                        // When the approaching-breach warning is due
+	WarnedAt           *time.Time `gorm:"column:sla_warned_at" json:"sla_warned_at,omitempty"`                          // When the warning was sent
 }
 
 // AgentTransfer tracks when conversations are transferred to human agents
@@ -287,6 +351,7 @@ type AgentTransfer struct {
 	PhoneNumber         string     `gorm:"size:50;not null" json:"phone_number"`
 	Status              TransferStatus `gorm:"size:20;default:'active'" json:"status"` // active, resumed
 	Source              TransferSource `gorm:"size:20;default:'manual'" json:"source"` // manual, flow, keyword, chatbot_disabled
+	Priority            SLAPriority    `gorm:"size:20;default:'normal'" json:"priority"` // low, normal, high, urgent
 	AgentID             *uuid.UUID `gorm:"type:uuid" json:"agent_id,omitempty"`
 	TeamID              *uuid.UUID `gorm:"type:uuid;index" json:"team_id,omitempty"` // Team queue (null = general queue)
 	TransferredByUserID *uuid.UUID `gorm:"type:uuid" json:"transferred_by_user_id,omitempty"` // User who initiated the transfer (null for system)
@@ -294,6 +359,7 @@ type AgentTransfer struct {
 	TransferredAt       time.Time  `gorm:"autoCreateTime" json:"transferred_at"`
 	ResumedAt           *time.Time `json:"resumed_at,omitempty"`
 	ResumedBy           *uuid.UUID `gorm:"type:uuid" json:"resumed_by,omitempty"`
+	RequiredSkills      SkillRequirements `gorm:"type:jsonb;default:'[]'" json:"required_skills"` // Skills the routing strategy matched against
 
 	// SLA Tracking (embedded - all fields stored in same table)
 	SLA SLATracking `gorm:"embedded"`
@@ -310,3 +376,56 @@ type AgentTransfer struct {
 func (AgentTransfer) TableName() string {
 	return "agent_transfers"
 }
+
+// TransferHop records one hand-off of an agent transfer between agents,
+// teams or the queue. HandleSeconds is how long the previous holder had the
+// transfer, from the previous hop (or the transfer) to this one.
+type TransferHop struct {
+	BaseModel
+	OrganizationID uuid.UUID          `gorm:"type:uuid;index;not null" json:"organization_id"`
+	TransferID     uuid.UUID          `gorm:"type:uuid;index;not null" json:"transfer_id"`
+	FromAgentID    *uuid.UUID         `gorm:"type:uuid;index" json:"from_agent_id,omitempty"` // null = queue
+	ToAgentID      *uuid.UUID         `gorm:"type:uuid;index" json:"to_agent_id,omitempty"`   // null = queue
+	FromTeamID     *uuid.UUID         `gorm:"type:uuid" json:"from_team_id,omitempty"`        // null = general queue
+	ToTeamID       *uuid.UUID         `gorm:"type:uuid" json:"to_team_id,omitempty"`          // null = general queue
+	Trigger        TransferHopTrigger `gorm:"size:20;not null" json:"trigger"`
+	Reason         string             `gorm:"size:255" json:"reason"`
+	Notes          string             `gorm:"type:text" json:"notes"`
+	ByUserID       *uuid.UUID         `gorm:"type:uuid" json:"by_user_id,omitempty"` // null for system hand-offs
+	StartedAt      time.Time          `gorm:"not null" json:"started_at"`            // When the previous holder got the transfer
+	HoppedAt       time.Time          `gorm:"not null;index" json:"hopped_at"`
+	HandleSeconds  int64              `gorm:"default:0" json:"handle_seconds"`
+
+	// Relations
+	Transfer  *AgentTransfer `gorm:"foreignKey:TransferID" json:"transfer,omitempty"`
+	FromAgent *User          `gorm:"foreignKey:FromAgentID" json:"from_agent,omitempty"`
+	ToAgent   *User          `gorm:"foreignKey:ToAgentID" json:"to_agent,omitempty"`
+	FromTeam  *Team          `gorm:"foreignKey:FromTeamID" json:"from_team,omitempty"`
+	ToTeam    *Team          `gorm:"foreignKey:ToTeamID" json:"to_team,omitempty"`
+	ByUser    *User          `gorm:"foreignKey:ByUserID" json:"by_user,omitempty"`
+}
+
+func (TransferHop) TableName() string {
+	return "transfer_hops"
+}
+
+// Moved reports whether the hop changed the transfer's agent or team
+func (h *TransferHop) Moved() bool {
+	return !sameUUID(h.FromAgentID, h.ToAgentID) || !sameUUID(h.FromTeamID, h.ToTeamID)
+}
+
+// IsHandoff reports whether the hop passed the transfer from one agent or
+// team to another, as opposed to a pickup from the queue
+func (h *TransferHop) IsHandoff() bool {
+	if h.FromAgentID != nil && (h.ToAgentID == nil || *h.ToAgentID != *h.FromAgentID) {
+		return true
+	}
+	return !sameUUID(h.FromTeamID, h.ToTeamID)
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
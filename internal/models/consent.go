@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContactConsent is an immutable record of a contact opting in to or out of
+// messages. The latest record's status is mirrored on Contact.ConsentStatus.
+type ContactConsent struct {
+	BaseModel
+	OrganizationID uuid.UUID     `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID      uuid.UUID     `gorm:"type:uuid;index;not null" json:"contact_id"`
+	Channel        string        `gorm:"size:20;not null;default:'whatsapp'" json:"channel"`
+	Status         ConsentStatus `gorm:"size:20;not null" json:"status"`       // opted_in, opted_out
+	Source         ConsentSource `gorm:"size:20;not null" json:"source"`       // keyword, manual, api, import
+	Proof          JSONB         `gorm:"type:jsonb;default:'{}'" json:"proof"` // e.g. the keyword message ID and text, or a form URL
+	RecordedAt     time.Time     `gorm:"not null" json:"recorded_at"`
+	CreatedBy      *uuid.UUID    `gorm:"type:uuid" json:"created_by,omitempty"` // User who recorded it, nil for keyword replies
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Contact      *Contact      `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+}
+
+func (ContactConsent) TableName() string {
+	return "contact_consents"
+}
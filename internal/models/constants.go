@@ -43,6 +43,7 @@ const (
 	MessageStatusRead      MessageStatus = "read"
 	MessageStatusFailed    MessageStatus = "failed"
 	MessageStatusReceived  MessageStatus = "received"
+	MessageStatusOptedOut  MessageStatus = "opted_out" // Campaign recipient skipped because the contact opted out
 )
 
 // AIProvider represents supported AI providers
@@ -87,6 +88,9 @@ const (
 	FlowStepTypeButtons      FlowStepType = "buttons"
 	FlowStepTypeTransfer     FlowStepType = "transfer"
 	FlowStepTypeWhatsAppFlow FlowStepType = "whatsapp_flow"
+	FlowStepTypeSubflow      FlowStepType = "subflow"   // Runs another flow, then continues with its data
+	FlowStepTypeGotoFlow     FlowStepType = "goto_flow" // Continues in another flow
+	FlowStepTypeLoop         FlowStepType = "loop"      // Repeats earlier steps up to a limit
 )
 
 // SessionStatus represents chatbot session states
@@ -118,6 +122,16 @@ const (
 	TransferSourceChatbotDisabled TransferSource = "chatbot_disabled"
 )
 
+// TransferHopTrigger represents what moved a transfer to a new agent or team
+type TransferHopTrigger string
+
+const (
+	TransferHopTriggerManual     TransferHopTrigger = "manual"      // Assigned or moved by a user
+	TransferHopTriggerPickup     TransferHopTrigger = "pickup"      // Picked from the queue by an agent
+	TransferHopTriggerAutoAssign TransferHopTrigger = "auto_assign" // Assigned from the queue when an agent had capacity
+	TransferHopTriggerAgentAway  TransferHopTrigger = "agent_away"  // Returned to the queue when the agent went away
+)
+
 // CampaignStatus represents bulk message campaign states
 type CampaignStatus string
 
@@ -171,6 +185,11 @@ const (
 	InputTypeSelect       InputType = "select"
 	InputTypeButton       InputType = "button"
 	InputTypeWhatsAppFlow InputType = "whatsapp_flow"
+	InputTypeImage        InputType = "image"
+	InputTypeDocument     InputType = "document"
+	InputTypeAudio        InputType = "audio"
+	InputTypeVideo        InputType = "video"
+	InputTypeLocation     InputType = "location"
 )
 
 // AssignmentStrategy represents team assignment strategies
@@ -180,6 +199,7 @@ const (
 	AssignmentStrategyRoundRobin   AssignmentStrategy = "round_robin"
 	AssignmentStrategyLoadBalanced AssignmentStrategy = "load_balanced"
 	AssignmentStrategyManual       AssignmentStrategy = "manual"
+	AssignmentStrategySkillsBased  AssignmentStrategy = "skills_based"
 )
 
 // SSOProviderType represents supported SSO providers
@@ -201,9 +221,11 @@ const (
 	WebhookEventMessageOutgoing  WebhookEvent = "message.outgoing"
 	WebhookEventMessageSent      WebhookEvent = "message.sent"
 	WebhookEventContactCreated   WebhookEvent = "contact.created"
+	WebhookEventContactBlocked   WebhookEvent = "contact.blocked"
 	WebhookEventTransferCreated  WebhookEvent = "transfer.created"
 	WebhookEventTransferResumed  WebhookEvent = "transfer.resumed"
 	WebhookEventTransferAssigned WebhookEvent = "transfer.assigned"
+	WebhookEventSurveyCompleted  WebhookEvent = "survey.completed"
 )
 
 // ActionType represents custom action types
@@ -213,4 +235,89 @@ const (
 	ActionTypeWebhook    ActionType = "webhook"
 	ActionTypeURL        ActionType = "url"
 	ActionTypeJavascript ActionType = "javascript"
+	ActionTypeTranscript ActionType = "transcript" // Renders the conversation transcript for download
+)
+
+// ContactAttributeType represents the data type of a custom contact attribute
+type ContactAttributeType string
+
+const (
+	ContactAttributeTypeText    ContactAttributeType = "text"
+	ContactAttributeTypeNumber  ContactAttributeType = "number"
+	ContactAttributeTypeDate    ContactAttributeType = "date"
+	ContactAttributeTypeEnum    ContactAttributeType = "enum"
+	ContactAttributeTypeBoolean ContactAttributeType = "boolean"
+)
+
+// IsValid reports whether t is a supported attribute type
+func (t ContactAttributeType) IsValid() bool {
+	switch t {
+	case ContactAttributeTypeText, ContactAttributeTypeNumber, ContactAttributeTypeDate,
+		ContactAttributeTypeEnum, ContactAttributeTypeBoolean:
+		return true
+	}
+	return false
+}
+
+// ConsentStatus represents a contact's messaging consent
+type ConsentStatus string
+
+const (
+	ConsentStatusOptedIn  ConsentStatus = "opted_in"
+	ConsentStatusOptedOut ConsentStatus = "opted_out"
+)
+
+// IsValid reports whether s is a supported consent status
+func (s ConsentStatus) IsValid() bool {
+	return s == ConsentStatusOptedIn || s == ConsentStatusOptedOut
+}
+
+// ConsentSource represents how a consent change was captured
+type ConsentSource string
+
+const (
+	ConsentSourceKeyword ConsentSource = "keyword" // Contact replied with an opt-in/opt-out keyword
+	ConsentSourceManual  ConsentSource = "manual"  // Recorded by an agent in the UI
+	ConsentSourceAPI     ConsentSource = "api"     // Recorded through the API (e.g. from a website form)
+	ConsentSourceImport  ConsentSource = "import"  // Recorded during contact import
+)
+
+// IsValid reports whether s is a supported consent source
+func (s ConsentSource) IsValid() bool {
+	switch s {
+	case ConsentSourceKeyword, ConsentSourceManual, ConsentSourceAPI, ConsentSourceImport:
+		return true
+	}
+	return false
+}
+
+// ContactActivityType represents a contact change recorded in the activity log
+type ContactActivityType string
+
+const (
+	ContactActivityTagsChanged       ContactActivityType = "tags_changed"
+	ContactActivityAssignmentChanged ContactActivityType = "assignment_changed"
+	ContactActivityTransferAssigned  ContactActivityType = "transfer_assigned"
+	ContactActivityCustomAction      ContactActivityType = "custom_action_executed"
+)
+
+// DataRequestType represents the kind of data-subject request
+type DataRequestType string
+
+const (
+	DataRequestTypeExport  DataRequestType = "export"
+	DataRequestTypeErasure DataRequestType = "erasure"
+)
+
+// ErasureMode represents how a contact's data is erased
+type ErasureMode string
+
+const (
+	ErasureModeDelete    ErasureMode = "delete"    // Hard-delete every record
+	ErasureModeAnonymize ErasureMode = "anonymize" // Strip personal data but keep records for reporting
 )
+
+// IsValid reports whether m is a supported erasure mode
+func (m ErasureMode) IsValid() bool {
+	return m == ErasureModeDelete || m == ErasureModeAnonymize
+}
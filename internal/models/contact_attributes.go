@@ -0,0 +1,29 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ContactAttribute defines an org-level typed custom field for contacts.
+// Values are stored in Contact.Attributes keyed by Key.
+type ContactAttribute struct {
+	BaseModel
+	OrganizationID uuid.UUID            `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Key            string               `gorm:"size:50;not null" json:"key"` // snake_case identifier, immutable
+	Label          string               `gorm:"size:100;not null" json:"label"`
+	Type           ContactAttributeType `gorm:"size:20;not null" json:"type"` // text, number, date, enum, boolean (immutable)
+	Description    string               `gorm:"type:text" json:"description"`
+	Required       bool                 `gorm:"default:false" json:"required"`
+	Options        StringArray          `gorm:"type:jsonb;default:'[]'" json:"options"` // Allowed values for enum
+	Min            *float64             `json:"min,omitempty"`                          // Minimum value (number) or length (text)
+	Max            *float64             `json:"max,omitempty"`                          // Maximum value (number) or length (text)
+	Pattern        string               `gorm:"size:255" json:"pattern"`                // Regex a text value must match
+	Position       int                  `gorm:"default:0" json:"position"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (ContactAttribute) TableName() string {
+	return "contact_attributes"
+}
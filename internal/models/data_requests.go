@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataSubjectRequest is the audit record of a contact data export or erasure.
+// It deliberately holds no personal data so it survives the erasure it records.
+type DataSubjectRequest struct {
+	BaseModel
+	OrganizationID    uuid.UUID       `gorm:"type:uuid;index;not null" json:"organization_id"`
+	ContactID         uuid.UUID       `gorm:"type:uuid;index;not null" json:"contact_id"` // Not a foreign key, erased contacts no longer exist
+	PhoneNumberMasked string          `gorm:"size:50" json:"phone_number_masked"`
+	Type              DataRequestType `gorm:"size:20;not null" json:"type"`  // export, erasure
+	Mode              ErasureMode     `gorm:"size:20" json:"mode,omitempty"` // delete, anonymize (erasure only)
+	Reason            string          `gorm:"type:text" json:"reason"`       // e.g. the legal request reference
+	Report            JSONB           `gorm:"type:jsonb;default:'{}'" json:"report"`
+	RequestedBy       *uuid.UUID      `gorm:"type:uuid" json:"requested_by,omitempty"`
+	CompletedAt       time.Time       `json:"completed_at"`
+
+	// Relations
+	Organization    *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	RequestedByUser *User         `gorm:"foreignKey:RequestedBy" json:"requested_by_user,omitempty"`
+}
+
+func (DataSubjectRequest) TableName() string {
+	return "data_subject_requests"
+}
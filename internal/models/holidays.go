@@ -0,0 +1,42 @@
+package models
+
+import "github.com/google/uuid"
+
+// HolidayCalendar groups closure dates, such as a country's public holidays,
+// that suspend business hours. A calendar without a WhatsApp account applies
+// to every account of the organization.
+type HolidayCalendar struct {
+	BaseModel
+	OrganizationID  uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name            string    `gorm:"size:255;not null" json:"name"`
+	Description     string    `gorm:"type:text" json:"description"`
+	WhatsAppAccount string    `gorm:"size:100;index" json:"whatsapp_account"` // References WhatsAppAccount.Name (empty for all accounts)
+	IsActive        bool      `gorm:"default:true" json:"is_active"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Holidays     []Holiday     `gorm:"foreignKey:CalendarID" json:"holidays,omitempty"`
+}
+
+func (HolidayCalendar) TableName() string {
+	return "holiday_calendars"
+}
+
+// Holiday closes business hours for whole days, from StartDate to EndDate
+// inclusive, in the business-hours timezone.
+type Holiday struct {
+	BaseModel
+	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	CalendarID     uuid.UUID `gorm:"type:uuid;index;not null" json:"calendar_id"`
+	Name           string    `gorm:"size:255" json:"name"`
+	StartDate      string    `gorm:"size:10;not null" json:"start_date"` // YYYY-MM-DD
+	EndDate        string    `gorm:"size:10;not null" json:"end_date"`   // YYYY-MM-DD
+	UID            string    `gorm:"size:255" json:"uid,omitempty"`      // iCalendar UID, used to update events on re-import
+
+	// Relations
+	Calendar *HolidayCalendar `gorm:"foreignKey:CalendarID" json:"calendar,omitempty"`
+}
+
+func (Holiday) TableName() string {
+	return "holidays"
+}
@@ -135,6 +135,7 @@ type UserOrganization struct {
 	OrganizationID uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_user_org;not null" json:"organization_id"`
 	RoleID         *uuid.UUID `gorm:"type:uuid;index" json:"role_id,omitempty"`
 	IsDefault      bool       `gorm:"default:false" json:"is_default"`
+	MaxConcurrentChats int    `gorm:"default:0" json:"max_concurrent_chats"` // Active transfer ceiling in this org; 0 = use team limit
 
 	// Relations
 	User         *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -154,6 +155,7 @@ type UserAvailabilityLog struct {
 	IsAvailable    bool       `gorm:"not null" json:"is_available"`
 	StartedAt      time.Time  `gorm:"not null" json:"started_at"`
 	EndedAt        *time.Time `json:"ended_at,omitempty"` // null means current status
+	Source         string     `gorm:"size:20;default:'manual'" json:"source"` // manual or shift
 
 	// Relations
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -169,8 +171,10 @@ type Team struct {
 	OrganizationID     uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
 	Name               string    `gorm:"size:100;not null" json:"name"`
 	Description        string    `gorm:"size:500" json:"description"`
-	AssignmentStrategy AssignmentStrategy `gorm:"size:50;default:'round_robin'" json:"assignment_strategy"` // round_robin, load_balanced, manual
+	AssignmentStrategy AssignmentStrategy `gorm:"size:50;default:'round_robin'" json:"assignment_strategy"` // round_robin, load_balanced, manual, skills_based
 	IsActive           bool      `gorm:"default:true" json:"is_active"`
+	MaxConcurrentChats int       `gorm:"default:0" json:"max_concurrent_chats"` // Per-agent ceiling for members without their own; 0 = unlimited
+	Timezone           string    `gorm:"size:64" json:"timezone"`                // IANA timezone for business hours (empty for account/org timezone)
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -269,7 +273,7 @@ type CustomAction struct {
 	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
 	Name           string    `gorm:"size:100;not null" json:"name"`
 	Icon           string    `gorm:"size:50" json:"icon"`                      // lucide icon name
-	ActionType     ActionType `gorm:"size:20;not null" json:"action_type"`     // webhook, url, javascript
+	ActionType     ActionType `gorm:"size:20;not null" json:"action_type"`     // webhook, url, javascript, transcript
 	Config         JSONB     `gorm:"type:jsonb;default:'{}'" json:"config"`    // Type-specific configuration
 	IsActive       bool      `gorm:"default:true" json:"is_active"`
 	DisplayOrder   int       `gorm:"default:0" json:"display_order"`
@@ -298,6 +302,7 @@ type WhatsAppAccount struct {
 	IsDefaultOutgoing  bool      `gorm:"default:false" json:"is_default_outgoing"`
 	AutoReadReceipt    bool      `gorm:"default:false" json:"auto_read_receipt"`
 	Status             string    `gorm:"size:20;default:'active'" json:"status"`
+	Timezone           string    `gorm:"size:64" json:"timezone"` // IANA timezone for business hours (empty for org timezone)
 
 	// Relations
 	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -320,6 +325,23 @@ type Contact struct {
 	IsRead             bool       `gorm:"default:true" json:"is_read"`
 	Tags               JSONBArray `gorm:"type:jsonb;default:'[]'" json:"tags"`
 	Metadata           JSONB      `gorm:"type:jsonb;default:'{}'" json:"metadata"`
+	Attributes         JSONB      `gorm:"type:jsonb;default:'{}'" json:"attributes"` // Typed values for org-defined ContactAttributes
+
+	// Latest consent state; the full history is kept in ContactConsent
+	ConsentStatus    ConsentStatus `gorm:"size:20;index" json:"consent_status,omitempty"` // opted_in, opted_out, or empty if unknown
+	ConsentUpdatedAt *time.Time    `json:"consent_updated_at,omitempty"`
+
+	// Block list; blocked contacts are also blocked on WhatsApp
+	IsBlocked   bool       `gorm:"default:false;index" json:"is_blocked"`
+	BlockedAt   *time.Time `json:"blocked_at,omitempty"`
+	BlockedBy   *uuid.UUID `gorm:"type:uuid" json:"blocked_by,omitempty"`
+	BlockReason string     `gorm:"type:text" json:"block_reason,omitempty"`
+
+	// Legal hold exempts the contact from retention purges and erasure
+	LegalHold       bool       `gorm:"default:false" json:"legal_hold"`
+	LegalHoldReason string     `gorm:"type:text" json:"legal_hold_reason,omitempty"`
+	LegalHoldAt     *time.Time `json:"legal_hold_at,omitempty"`
+	LegalHoldBy     *uuid.UUID `gorm:"type:uuid" json:"legal_hold_by,omitempty"`
 
 	// Chatbot SLA tracking
 	ChatbotLastMessageAt *time.Time `json:"chatbot_last_message_at,omitempty"` // When chatbot last sent a message
@@ -359,6 +381,7 @@ type Message struct {
 	ReplyToMessageID  *uuid.UUID `gorm:"type:uuid" json:"reply_to_message_id,omitempty"`
 	SentByUserID      *uuid.UUID `gorm:"type:uuid;index" json:"sent_by_user_id,omitempty"` // User who sent outgoing message
 	Metadata          JSONB      `gorm:"type:jsonb;default:'{}'" json:"metadata"`
+	BodyPurgedAt      *time.Time `json:"body_purged_at,omitempty"` // When the retention policy removed the message body
 
 	// Relations
 	Organization   *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
@@ -3,6 +3,7 @@ package models_test
 import (
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -318,3 +319,57 @@ func TestJSONBArray_Scan(t *testing.T) {
 		})
 	}
 }
+
+func TestTransferHop_IsHandoff(t *testing.T) {
+	t.Parallel()
+
+	agentA, agentB := uuid.New(), uuid.New()
+	teamA, teamB := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		hop         models.TransferHop
+		wantMoved   bool
+		wantHandoff bool
+	}{
+		{
+			name:        "picked up from the queue",
+			hop:         models.TransferHop{ToAgentID: &agentA},
+			wantMoved:   true,
+			wantHandoff: false,
+		},
+		{
+			name:        "handed to another agent",
+			hop:         models.TransferHop{FromAgentID: &agentA, ToAgentID: &agentB},
+			wantMoved:   true,
+			wantHandoff: true,
+		},
+		{
+			name:        "returned to the queue",
+			hop:         models.TransferHop{FromAgentID: &agentA},
+			wantMoved:   true,
+			wantHandoff: true,
+		},
+		{
+			name:        "moved to another team",
+			hop:         models.TransferHop{FromAgentID: &agentA, ToAgentID: &agentA, FromTeamID: &teamA, ToTeamID: &teamB},
+			wantMoved:   true,
+			wantHandoff: true,
+		},
+		{
+			name:        "nothing changed",
+			hop:         models.TransferHop{FromAgentID: &agentA, ToAgentID: &agentA, FromTeamID: &teamA, ToTeamID: &teamA},
+			wantMoved:   false,
+			wantHandoff: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.wantMoved, tt.hop.Moved())
+			assert.Equal(t, tt.wantHandoff, tt.hop.IsHandoff())
+		})
+	}
+}
@@ -46,31 +46,37 @@ func (RolePermission) TableName() string {
 
 // PermissionResource constants for available resources
 const (
-	ResourceUsers           = "users"
-	ResourceTeams           = "teams"
-	ResourceRoles           = "roles"
-	ResourceSettingsGeneral = "settings.general"
-	ResourceSettingsChatbot = "settings.chatbot"
-	ResourceSettingsSSO     = "settings.sso"
-	ResourceAccounts        = "accounts"
-	ResourceTemplates       = "templates"
-	ResourceFlowsWhatsApp   = "flows.whatsapp"
-	ResourceFlowsChatbot    = "flows.chatbot"
-	ResourceCampaigns       = "campaigns"
-	ResourceChatbotKeywords = "chatbot.keywords"
-	ResourceChatbotAI       = "chatbot.ai"
-	ResourceChat            = "chat"
-	ResourceChatAssign      = "chat.assign"
-	ResourceContacts        = "contacts"
-	ResourceTags            = "tags"
-	ResourceAnalytics       = "analytics"
-	ResourceAnalyticsAgents = "analytics.agents"
-	ResourceTransfers       = "transfers"
-	ResourceWebhooks        = "webhooks"
-	ResourceAPIKeys         = "api_keys"
-	ResourceCannedResponses = "canned_responses"
-	ResourceCustomActions   = "custom_actions"
-	ResourceOrganizations   = "organizations"
+	ResourceUsers             = "users"
+	ResourceTeams             = "teams"
+	ResourceSkills            = "skills"
+	ResourceShifts            = "shifts"
+	ResourceRoles             = "roles"
+	ResourceSettingsGeneral   = "settings.general"
+	ResourceSettingsChatbot   = "settings.chatbot"
+	ResourceSettingsSSO       = "settings.sso"
+	ResourceAccounts          = "accounts"
+	ResourceTemplates         = "templates"
+	ResourceFlowsWhatsApp     = "flows.whatsapp"
+	ResourceFlowsChatbot      = "flows.chatbot"
+	ResourceCampaigns         = "campaigns"
+	ResourceChatbotKeywords   = "chatbot.keywords"
+	ResourceChatbotAI         = "chatbot.ai"
+	ResourceChat              = "chat"
+	ResourceChatAssign        = "chat.assign"
+	ResourceContacts          = "contacts"
+	ResourceTags              = "tags"
+	ResourceContactAttributes = "contact_attributes"
+	ResourceSegments          = "segments"
+	ResourceDataRequests      = "data_requests"
+	ResourceAnalytics         = "analytics"
+	ResourceAnalyticsAgents   = "analytics.agents"
+	ResourceTransfers         = "transfers"
+	ResourceTranscripts       = "transcripts"
+	ResourceWebhooks          = "webhooks"
+	ResourceAPIKeys           = "api_keys"
+	ResourceCannedResponses   = "canned_responses"
+	ResourceCustomActions     = "custom_actions"
+	ResourceOrganizations     = "organizations"
 )
 
 // PermissionAction constants for available actions
@@ -99,6 +105,16 @@ func DefaultPermissions() []Permission {
 		{Resource: ResourceTeams, Action: ActionWrite, Description: "Create and edit teams"},
 		{Resource: ResourceTeams, Action: ActionDelete, Description: "Delete teams"},
 
+		// Skills
+		{Resource: ResourceSkills, Action: ActionRead, Description: "View skills and agent proficiencies"},
+		{Resource: ResourceSkills, Action: ActionWrite, Description: "Create and edit skills and assign them to agents"},
+		{Resource: ResourceSkills, Action: ActionDelete, Description: "Delete skills"},
+
+		// Shifts
+		{Resource: ResourceShifts, Action: ActionRead, Description: "View agent shift schedules"},
+		{Resource: ResourceShifts, Action: ActionWrite, Description: "Edit agent shifts, leave and overtime"},
+		{Resource: ResourceShifts, Action: ActionDelete, Description: "Delete agent shifts, leave and overtime"},
+
 		// Roles
 		{Resource: ResourceRoles, Action: ActionRead, Description: "View roles"},
 		{Resource: ResourceRoles, Action: ActionWrite, Description: "Create and edit roles"},
@@ -165,6 +181,22 @@ func DefaultPermissions() []Permission {
 		{Resource: ResourceTags, Action: ActionWrite, Description: "Create and edit tags"},
 		{Resource: ResourceTags, Action: ActionDelete, Description: "Delete tags"},
 
+		// Contact Attributes
+		{Resource: ResourceContactAttributes, Action: ActionRead, Description: "View contact attribute definitions"},
+		{Resource: ResourceContactAttributes, Action: ActionWrite, Description: "Create and edit contact attribute definitions"},
+		{Resource: ResourceContactAttributes, Action: ActionDelete, Description: "Delete contact attribute definitions"},
+
+		// Segments
+		{Resource: ResourceSegments, Action: ActionRead, Description: "View contact segments"},
+		{Resource: ResourceSegments, Action: ActionWrite, Description: "Create and edit contact segments"},
+		{Resource: ResourceSegments, Action: ActionDelete, Description: "Delete contact segments"},
+
+		// Data-subject requests
+		{Resource: ResourceDataRequests, Action: ActionRead, Description: "View data-subject request history"},
+		{Resource: ResourceDataRequests, Action: ActionWrite, Description: "Place contacts on legal hold"},
+		{Resource: ResourceDataRequests, Action: ActionExport, Description: "Export all data held about a contact"},
+		{Resource: ResourceDataRequests, Action: ActionDelete, Description: "Erase all data held about a contact"},
+
 		// Analytics
 		{Resource: ResourceAnalytics, Action: ActionRead, Description: "View analytics dashboard"},
 		{Resource: ResourceAnalytics, Action: ActionWrite, Description: "Create and edit dashboard widgets"},
@@ -175,6 +207,7 @@ func DefaultPermissions() []Permission {
 		{Resource: ResourceTransfers, Action: ActionRead, Description: "View agent transfers"},
 		{Resource: ResourceTransfers, Action: ActionWrite, Description: "Create transfers"},
 		{Resource: ResourceTransfers, Action: ActionPickup, Description: "Pickup transfers from queue"},
+		{Resource: ResourceTranscripts, Action: ActionExport, Description: "Export conversation transcripts"},
 
 		// Webhooks
 		{Resource: ResourceWebhooks, Action: ActionRead, Description: "View webhooks"},
@@ -215,6 +248,10 @@ func SystemRolePermissions() map[string][]string {
 	managerPermissions := []string{
 		// Teams (read only)
 		"teams:read",
+		// Skills (read only)
+		"skills:read",
+		// Shifts
+		"shifts:read", "shifts:write", "shifts:delete",
 		// Settings
 		"settings.general:read", "settings.general:write",
 		"settings.chatbot:read", "settings.chatbot:write",
@@ -236,10 +273,15 @@ func SystemRolePermissions() map[string][]string {
 		"contacts:read", "contacts:write", "contacts:delete", "contacts:import", "contacts:export",
 		// Tags
 		"tags:read", "tags:write", "tags:delete",
+		// Contact Attributes
+		"contact_attributes:read", "contact_attributes:write", "contact_attributes:delete",
+		// Segments
+		"segments:read", "segments:write", "segments:delete",
 		// Analytics
 		"analytics:read", "analytics.agents:read",
 		// Transfers
 		"transfers:read", "transfers:write", "transfers:pickup",
+		"transcripts:export",
 		// Webhooks
 		"webhooks:read", "webhooks:write", "webhooks:delete",
 		// Canned Responses
@@ -257,6 +299,8 @@ func SystemRolePermissions() map[string][]string {
 		"contacts:read",
 		// Tags (read only - agents can see tags on contacts)
 		"tags:read",
+		// Contact Attributes (read only - agents can see attribute definitions)
+		"contact_attributes:read",
 		// Analytics (own)
 		"analytics.agents:read",
 		// Transfers
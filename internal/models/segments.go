@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Segment is a saved audience of contacts defined by a rule tree.
+// Membership is evaluated dynamically each time the segment is used.
+type Segment struct {
+	BaseModel
+	OrganizationID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name            string     `gorm:"size:255;not null" json:"name"`
+	Description     string     `gorm:"type:text" json:"description"`
+	Rules           JSONB      `gorm:"type:jsonb;default:'{}'" json:"rules"` // segmentutil.Rule tree
+	ContactCount    int64      `gorm:"default:0" json:"contact_count"`       // Member count as of LastEvaluatedAt
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	CreatedBy       *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (Segment) TableName() string {
+	return "segments"
+}
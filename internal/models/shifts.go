@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Availability log sources
+const (
+	AvailabilitySourceManual = "manual" // Toggled by the agent
+	AvailabilitySourceShift  = "shift"  // Set at a shift boundary
+)
+
+// ShiftSchedule is an agent's weekly working hours. While it is active, the
+// agent is made available when a shift starts and away when it ends.
+type ShiftSchedule struct {
+	BaseModel
+	OrganizationID uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_shift_schedule_user;not null" json:"organization_id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_shift_schedule_user;not null" json:"user_id"`
+	Timezone       string     `gorm:"size:100" json:"timezone"`             // IANA name; empty = organization timezone
+	Hours          JSONBArray `gorm:"type:jsonb;default:'[]'" json:"hours"` // Same format as business_hours
+	IsActive       bool       `gorm:"default:true" json:"is_active"`
+	OnShift        *bool      `json:"on_shift,omitempty"` // State applied at the last boundary; nil = apply on the next check
+
+	// Relations
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (ShiftSchedule) TableName() string {
+	return "shift_schedules"
+}
+
+// ShiftExceptionType marks a one-off change to an agent's shifts
+type ShiftExceptionType string
+
+const (
+	ShiftExceptionLeave    ShiftExceptionType = "leave"    // Off, even during a scheduled shift
+	ShiftExceptionOvertime ShiftExceptionType = "overtime" // Working outside the scheduled shifts
+)
+
+// ShiftException is leave or overtime from StartsAt to EndsAt
+type ShiftException struct {
+	BaseModel
+	OrganizationID uuid.UUID          `gorm:"type:uuid;index;not null" json:"organization_id"`
+	UserID         uuid.UUID          `gorm:"type:uuid;index;not null" json:"user_id"`
+	Type           ShiftExceptionType `gorm:"size:20;not null" json:"type"`
+	StartsAt       time.Time          `gorm:"not null" json:"starts_at"`
+	EndsAt         time.Time          `gorm:"index;not null" json:"ends_at"`
+	Reason         string             `gorm:"type:text" json:"reason"`
+	CreatedByID    *uuid.UUID         `gorm:"type:uuid" json:"created_by_id,omitempty"`
+
+	// Relations
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (ShiftException) TableName() string {
+	return "shift_exceptions"
+}
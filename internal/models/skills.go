@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// SkillCategory groups skills for display and routing explanations
+type SkillCategory string
+
+const (
+	SkillCategoryLanguage SkillCategory = "language"
+	SkillCategoryProduct  SkillCategory = "product"
+	SkillCategoryTier     SkillCategory = "tier"
+	SkillCategoryOther    SkillCategory = "other"
+)
+
+// Skill is an organization-defined capability agents can hold, such as a
+// language, a product line or a support tier. A contact requires the skill
+// when it carries one of ContactTags or when its ContactAttribute holds one
+// of ContactAttributeValues.
+type Skill struct {
+	BaseModel
+	OrganizationID         uuid.UUID     `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name                   string        `gorm:"size:100;not null" json:"name"`
+	Category               SkillCategory `gorm:"size:50;default:'other'" json:"category"`
+	Description            string        `gorm:"type:text" json:"description"`
+	ContactTags            StringArray   `gorm:"type:jsonb;default:'[]'" json:"contact_tags"`
+	ContactAttribute       string        `gorm:"size:100" json:"contact_attribute"`
+	ContactAttributeValues StringArray   `gorm:"type:jsonb;default:'[]'" json:"contact_attribute_values"`
+	MinProficiency         int           `gorm:"default:1" json:"min_proficiency"` // Proficiency required when derived from a contact
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (Skill) TableName() string {
+	return "skills"
+}
+
+// Proficiency bounds for AgentSkill.Proficiency
+const (
+	MinSkillProficiency = 1
+	MaxSkillProficiency = 5
+)
+
+// AgentSkill records how proficient a user is at a skill (1-5).
+type AgentSkill struct {
+	BaseModel
+	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null" json:"organization_id"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	SkillID        uuid.UUID `gorm:"type:uuid;index;not null" json:"skill_id"`
+	Proficiency    int       `gorm:"default:1" json:"proficiency"`
+
+	// Relations
+	User  *User  `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Skill *Skill `gorm:"foreignKey:SkillID" json:"skill,omitempty"`
+}
+
+func (AgentSkill) TableName() string {
+	return "agent_skills"
+}
+
+// SkillRequirement names a skill a transfer needs and the minimum proficiency
+// an agent must have in it.
+type SkillRequirement struct {
+	Skill          string `json:"skill"`
+	MinProficiency int    `json:"min_proficiency,omitempty"`
+}
+
+// SkillRequirements is a JSONB list of skill requirements
+type SkillRequirements []SkillRequirement
+
+func (s SkillRequirements) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *SkillRequirements) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, s)
+}
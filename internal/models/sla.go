@@ -0,0 +1,140 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// SLAPriority is the urgency of an agent transfer
+type SLAPriority string
+
+const (
+	SLAPriorityLow    SLAPriority = "low"
+	SLAPriorityNormal SLAPriority = "normal"
+	SLAPriorityHigh   SLAPriority = "high"
+	SLAPriorityUrgent SLAPriority = "urgent"
+)
+
+// SLAPriorities lists the valid priorities, from least to most urgent
+var SLAPriorities = []SLAPriority{SLAPriorityLow, SLAPriorityNormal, SLAPriorityHigh, SLAPriorityUrgent}
+
+// SLAPolicy sets SLA targets for the transfers matching its conditions. The
+// active policy with the lowest Position whose conditions all match a
+// transfer is applied to it; transfers no policy matches use the SLA
+// settings of their chatbot settings.
+type SLAPolicy struct {
+	BaseModel
+	OrganizationID uuid.UUID           `gorm:"type:uuid;index;not null" json:"organization_id"`
+	Name           string              `gorm:"size:100;not null" json:"name"`
+	Description    string              `gorm:"type:text" json:"description"`
+	IsActive       bool                `gorm:"default:true" json:"is_active"`
+	Position       int                 `gorm:"default:0" json:"position"` // Evaluation order, lowest first
+	Conditions     SLAPolicyConditions `gorm:"type:jsonb;default:'{}'" json:"conditions"`
+	Targets        SLATargetSet        `gorm:"type:jsonb;default:'{}'" json:"targets"`     // Per priority
+	WarningPercent int                 `gorm:"default:0" json:"warning_percent"`           // Warn when this much of the response time is used (0 = off)
+	Escalations    SLAEscalationChain  `gorm:"type:jsonb;default:'[]'" json:"escalations"` // Escalation levels in order
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+func (SLAPolicy) TableName() string {
+	return "sla_policies"
+}
+
+// SLAPolicyConditions select the transfers a policy applies to. Each
+// non-empty condition must match; within a condition any listed value
+// matches. A policy without conditions matches every transfer.
+type SLAPolicyConditions struct {
+	TeamIDs                []string         `json:"team_ids,omitempty"`
+	WhatsAppAccounts       []string         `json:"whatsapp_accounts,omitempty"`
+	Sources                []TransferSource `json:"sources,omitempty"`
+	ContactTags            []string         `json:"contact_tags,omitempty"`
+	ContactAttribute       string           `json:"contact_attribute,omitempty"`
+	ContactAttributeValues []string         `json:"contact_attribute_values,omitempty"`
+}
+
+func (c SLAPolicyConditions) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *SLAPolicyConditions) Scan(value interface{}) error {
+	if value == nil {
+		*c = SLAPolicyConditions{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+// SLATargets are the SLA minutes for one priority. Zero disables a target.
+type SLATargets struct {
+	ResponseMinutes   int `json:"response_minutes"`
+	ResolutionMinutes int `json:"resolution_minutes"`
+}
+
+// SLATargetSet maps priorities to their targets
+type SLATargetSet map[SLAPriority]SLATargets
+
+// For returns the targets of a priority, falling back to the normal
+// priority's targets
+func (s SLATargetSet) For(priority SLAPriority) SLATargets {
+	if t, ok := s[priority]; ok {
+		return t
+	}
+	return s[SLAPriorityNormal]
+}
+
+func (s SLATargetSet) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *SLATargetSet) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// SLAEscalationLevel is one step of an escalation chain
+type SLAEscalationLevel struct {
+	AfterMinutes    int      `json:"after_minutes"`              // From the start of the SLA clock
+	NotifyIDs       []string `json:"notify_ids"`                 // Users notified at this level
+	CustomerMessage string   `json:"customer_message,omitempty"` // Optional message sent to the customer
+}
+
+// SLAEscalationChain is a JSONB list of escalation levels
+type SLAEscalationChain []SLAEscalationLevel
+
+func (c SLAEscalationChain) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *SLAEscalationChain) Scan(value interface{}) error {
+	if value == nil {
+		*c = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, c)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SurveyType is the kind of satisfaction survey sent when a conversation ends
+type SurveyType string
+
+const (
+	SurveyTypeCSAT SurveyType = "csat" // Satisfaction from 1 to 5
+	SurveyTypeNPS  SurveyType = "nps"  // Likelihood to recommend from 0 to 10
+)
+
+// SurveyChannel is how a survey is presented to the customer
+type SurveyChannel string
+
+const (
+	SurveyChannelButtons SurveyChannel = "buttons" // Interactive score options (typed scores are accepted too)
+	SurveyChannelFlow    SurveyChannel = "flow"    // WhatsApp Flow returning "score" and "comment"
+)
+
+// SurveyStatus tracks a survey from sending to the customer's answer
+type SurveyStatus string
+
+const (
+	SurveyStatusSent      SurveyStatus = "sent"      // Waiting for a score
+	SurveyStatusScored    SurveyStatus = "scored"    // Waiting for a comment
+	SurveyStatusCompleted SurveyStatus = "completed" // Answered
+)
+
+// SurveyConfig holds the post-resolution survey settings
+type SurveyConfig struct {
+	Enabled         bool          `gorm:"column:survey_enabled;default:false" json:"survey_enabled"`
+	Type            SurveyType    `gorm:"column:survey_type;size:10;default:'csat'" json:"survey_type"`
+	Channel         SurveyChannel `gorm:"column:survey_channel;size:10;default:'buttons'" json:"survey_channel"`
+	Question        string        `gorm:"column:survey_question;type:text" json:"survey_question"`
+	CommentPrompt   string        `gorm:"column:survey_comment_prompt;type:text" json:"survey_comment_prompt"`       // Asked after a button score; empty skips the comment
+	ThankYouMessage string        `gorm:"column:survey_thank_you_message;type:text" json:"survey_thank_you_message"` // Sent once the survey is answered
+	FlowID          string        `gorm:"column:survey_flow_id;size:100" json:"survey_flow_id"`                      // Meta Flow ID for the flow channel
+	FlowCTA         string        `gorm:"column:survey_flow_cta;size:20" json:"survey_flow_cta"`
+	OnAutoClose     bool          `gorm:"column:survey_on_auto_close;default:false" json:"survey_on_auto_close"` // Also survey transfers closed by the SLA auto-close
+	ExpiryHours     int           `gorm:"column:survey_expiry_hours;default:24" json:"survey_expiry_hours"`      // Replies after this are not taken as answers
+}
+
+// SurveyResponse is a survey sent for a closed agent transfer and the
+// customer's answer
+type SurveyResponse struct {
+	BaseModel
+	OrganizationID  uuid.UUID     `gorm:"type:uuid;index;not null" json:"organization_id"`
+	TransferID      uuid.UUID     `gorm:"type:uuid;uniqueIndex;not null" json:"transfer_id"`
+	ContactID       uuid.UUID     `gorm:"type:uuid;index;not null" json:"contact_id"`
+	AgentID         *uuid.UUID    `gorm:"type:uuid;index" json:"agent_id,omitempty"`
+	TeamID          *uuid.UUID    `gorm:"type:uuid;index" json:"team_id,omitempty"`
+	WhatsAppAccount string        `gorm:"size:100" json:"whatsapp_account"`
+	Type            SurveyType    `gorm:"size:10;not null" json:"type"`
+	Channel         SurveyChannel `gorm:"size:10;not null" json:"channel"`
+	Status          SurveyStatus  `gorm:"size:20;default:'sent';index" json:"status"`
+	Score           *int          `json:"score,omitempty"`
+	Comment         string        `gorm:"type:text" json:"comment"`
+	SentAt          time.Time     `gorm:"index;not null" json:"sent_at"`
+	ExpiresAt       time.Time     `gorm:"not null" json:"expires_at"`
+	CompletedAt     *time.Time    `gorm:"index" json:"completed_at,omitempty"`
+
+	// Relations
+	Organization *Organization  `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Transfer     *AgentTransfer `gorm:"foreignKey:TransferID" json:"transfer,omitempty"`
+	Contact      *Contact       `gorm:"foreignKey:ContactID" json:"contact,omitempty"`
+	Agent        *User          `gorm:"foreignKey:AgentID" json:"agent,omitempty"`
+	Team         *Team          `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+}
+
+func (SurveyResponse) TableName() string {
+	return "survey_responses"
+}
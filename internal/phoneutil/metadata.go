@@ -0,0 +1,77 @@
+package phoneutil
+
+// regionMeta describes the numbering plan details needed to turn a
+// nationally formatted number into E.164 for a single region.
+type regionMeta struct {
+	CallingCode         string // Country calling code without "+"
+	NationalPrefix      string // Trunk prefix dialled before national numbers (e.g. "0")
+	InternationalPrefix string // Exit code dialled before international numbers (e.g. "00")
+	MinLength           int    // Minimum national significant number length
+	MaxLength           int    // Maximum national significant number length
+}
+
+// regions holds numbering plan metadata keyed by ISO 3166-1 alpha-2 code.
+// Lengths are national significant number lengths (without trunk prefix).
+var regions = map[string]regionMeta{
+	// Americas
+	"US": {CallingCode: "1", NationalPrefix: "1", InternationalPrefix: "011", MinLength: 10, MaxLength: 10},
+	"CA": {CallingCode: "1", NationalPrefix: "1", InternationalPrefix: "011", MinLength: 10, MaxLength: 10},
+	"MX": {CallingCode: "52", NationalPrefix: "01", InternationalPrefix: "00", MinLength: 10, MaxLength: 10},
+	"BR": {CallingCode: "55", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 11},
+	"AR": {CallingCode: "54", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 11},
+	"CO": {CallingCode: "57", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 10},
+
+	// Europe
+	"GB": {CallingCode: "44", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 10},
+	"IE": {CallingCode: "353", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 7, MaxLength: 9},
+	"DE": {CallingCode: "49", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 6, MaxLength: 13},
+	"FR": {CallingCode: "33", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"ES": {CallingCode: "34", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"IT": {CallingCode: "39", InternationalPrefix: "00", MinLength: 6, MaxLength: 11},
+	"NL": {CallingCode: "31", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"BE": {CallingCode: "32", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 9},
+	"CH": {CallingCode: "41", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"AT": {CallingCode: "43", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 4, MaxLength: 13},
+	"SE": {CallingCode: "46", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 7, MaxLength: 9},
+	"NO": {CallingCode: "47", InternationalPrefix: "00", MinLength: 8, MaxLength: 8},
+	"DK": {CallingCode: "45", InternationalPrefix: "00", MinLength: 8, MaxLength: 8},
+	"PL": {CallingCode: "48", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"PT": {CallingCode: "351", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"RU": {CallingCode: "7", NationalPrefix: "8", InternationalPrefix: "810", MinLength: 10, MaxLength: 10},
+	"TR": {CallingCode: "90", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 10},
+
+	// Middle East
+	"AE": {CallingCode: "971", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 9},
+	"SA": {CallingCode: "966", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 9},
+	"QA": {CallingCode: "974", InternationalPrefix: "00", MinLength: 7, MaxLength: 8},
+	"KW": {CallingCode: "965", InternationalPrefix: "00", MinLength: 7, MaxLength: 8},
+	"OM": {CallingCode: "968", InternationalPrefix: "00", MinLength: 7, MaxLength: 8},
+	"BH": {CallingCode: "973", InternationalPrefix: "00", MinLength: 8, MaxLength: 8},
+	"IL": {CallingCode: "972", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 9},
+	"EG": {CallingCode: "20", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+
+	// South Asia
+	"IN": {CallingCode: "91", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 10},
+	"PK": {CallingCode: "92", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 10},
+	"BD": {CallingCode: "880", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+	"LK": {CallingCode: "94", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+	"NP": {CallingCode: "977", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+
+	// East and South-East Asia, Oceania
+	"SG": {CallingCode: "65", InternationalPrefix: "000", MinLength: 8, MaxLength: 8},
+	"MY": {CallingCode: "60", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+	"ID": {CallingCode: "62", NationalPrefix: "0", InternationalPrefix: "001", MinLength: 8, MaxLength: 12},
+	"PH": {CallingCode: "63", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+	"TH": {CallingCode: "66", NationalPrefix: "0", InternationalPrefix: "001", MinLength: 8, MaxLength: 9},
+	"VN": {CallingCode: "84", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 10},
+	"CN": {CallingCode: "86", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 10, MaxLength: 11},
+	"JP": {CallingCode: "81", NationalPrefix: "0", InternationalPrefix: "010", MinLength: 9, MaxLength: 10},
+	"KR": {CallingCode: "82", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+	"AU": {CallingCode: "61", NationalPrefix: "0", InternationalPrefix: "0011", MinLength: 9, MaxLength: 9},
+	"NZ": {CallingCode: "64", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 8, MaxLength: 10},
+
+	// Africa
+	"NG": {CallingCode: "234", NationalPrefix: "0", InternationalPrefix: "009", MinLength: 8, MaxLength: 10},
+	"KE": {CallingCode: "254", NationalPrefix: "0", InternationalPrefix: "000", MinLength: 9, MaxLength: 9},
+	"ZA": {CallingCode: "27", NationalPrefix: "0", InternationalPrefix: "00", MinLength: 9, MaxLength: 9},
+}
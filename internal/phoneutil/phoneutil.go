@@ -0,0 +1,117 @@
+package phoneutil
+
+import (
+	"errors"
+	"strings"
+)
+
+// E.164 allows at most 15 digits including the country calling code.
+// Anything shorter than 7 digits cannot be a routable subscriber number.
+const (
+	minE164Length = 7
+	maxE164Length = 15
+)
+
+// ErrInvalidNumber is returned when a phone number cannot be normalized.
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// IsValidRegion reports whether region is a supported ISO 3166-1 alpha-2 code.
+func IsValidRegion(region string) bool {
+	_, ok := regions[strings.ToUpper(strings.TrimSpace(region))]
+	return ok
+}
+
+// Normalize converts a phone number into E.164 digits without the leading "+",
+// which is how phone numbers are stored throughout the application.
+//
+// Numbers written with a "+" are treated as international. Otherwise region
+// (an ISO 3166-1 alpha-2 code, e.g. "IN") is used to strip international and
+// national trunk prefixes and to prepend the country calling code, so
+// "+91 98765 43210", "919876543210" and "098765 43210" all normalize to
+// "919876543210" for region IN. With an empty or unknown region only
+// formatting characters are removed.
+//
+// WhatsApp group JIDs (containing "@") are returned unchanged.
+func Normalize(raw, region string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.Contains(raw, "@") {
+		return raw, nil
+	}
+
+	international := strings.HasPrefix(raw, "+")
+	digits, ok := extractDigits(strings.TrimPrefix(raw, "+"))
+	if !ok || digits == "" {
+		return "", ErrInvalidNumber
+	}
+
+	meta, hasRegion := regions[strings.ToUpper(strings.TrimSpace(region))]
+	if international || !hasRegion {
+		return validE164(digits)
+	}
+
+	// Dialled with the region's exit code, e.g. "0044 20 ..." from the UK
+	if meta.InternationalPrefix != "" && strings.HasPrefix(digits, meta.InternationalPrefix) {
+		if rest := digits[len(meta.InternationalPrefix):]; validLength(rest) {
+			return rest, nil
+		}
+	}
+
+	// National format with trunk prefix, e.g. "098765 43210" in India
+	if meta.NationalPrefix != "" && strings.HasPrefix(digits, meta.NationalPrefix) {
+		if rest := digits[len(meta.NationalPrefix):]; meta.fitsNational(rest) {
+			return meta.CallingCode + rest, nil
+		}
+	}
+
+	// Already carries the region's calling code. Where national numbers are
+	// written with a trunk prefix this wins over the bare national form, or
+	// regions with variable-length numbers such as DE would get the code
+	// added twice. Where there is no trunk prefix, as in IT where mobiles
+	// start with 3, national numbers are written bare, so the code is only
+	// taken as such when the digits are too long to be national.
+	if meta.NationalPrefix != "" && strings.HasPrefix(digits, meta.CallingCode) && meta.fitsNational(digits[len(meta.CallingCode):]) {
+		return digits, nil
+	}
+
+	// National significant number without trunk prefix
+	if meta.fitsNational(digits) {
+		return meta.CallingCode + digits, nil
+	}
+
+	// Assume an international number from another region written without "+"
+	return validE164(digits)
+}
+
+// fitsNational reports whether n has a valid national number length for the region.
+func (m regionMeta) fitsNational(n string) bool {
+	return len(n) >= m.MinLength && len(n) <= m.MaxLength
+}
+
+// extractDigits strips common formatting characters from s.
+// Returns false if s contains anything other than digits and separators.
+func extractDigits(s string) (string, bool) {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+			// formatting separator
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+func validLength(digits string) bool {
+	return len(digits) >= minE164Length && len(digits) <= maxE164Length
+}
+
+func validE164(digits string) (string, error) {
+	if !validLength(digits) {
+		return "", ErrInvalidNumber
+	}
+	return digits, nil
+}
@@ -0,0 +1,119 @@
+package phoneutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize_IndiaVariantsConverge(t *testing.T) {
+	inputs := []string{
+		"+91 98765 43210",
+		"919876543210",
+		"098765 43210",
+		"9876543210",
+		"0091 98765-43210",
+		"+91 (98765) 43210",
+	}
+	for _, in := range inputs {
+		t.Run(in, func(t *testing.T) {
+			got, err := Normalize(in, "IN")
+			require.NoError(t, err)
+			assert.Equal(t, "919876543210", got)
+		})
+	}
+}
+
+func TestNormalize_RegionSpecific(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   string
+	}{
+		{"US national", "(415) 555-2671", "US", "14155552671"},
+		{"US with trunk 1", "1 415 555 2671", "US", "14155552671"},
+		{"US exit code", "011 44 20 7946 0958", "US", "442079460958"},
+		{"UK national", "020 7946 0958", "GB", "442079460958"},
+		{"UK lowercase region", "07911 123456", "gb", "447911123456"},
+		{"Germany national", "030 1234567", "DE", "49301234567"},
+		{"Germany with calling code", "49 30 1234567", "DE", "49301234567"},
+		{"Germany mobile with calling code", "4915112345678", "DE", "4915112345678"},
+		{"Germany mobile national", "01511 2345678", "DE", "4915112345678"},
+		{"Austria national", "0664 1234567", "AT", "436641234567"},
+		{"Austria with calling code", "43 664 1234567", "AT", "436641234567"},
+		{"Austria landline with calling code", "43 1 5055000", "AT", "4315055000"},
+		{"Italy with calling code", "39 06 1234 5678", "IT", "390612345678"},
+		{"Italy mobile with calling code", "39 333 123 4567", "IT", "393331234567"},
+		{"Italy mobile national starting with 39", "393 123 4567", "IT", "393931234567"},
+		{"Italy mobile national starting with 34", "347 123 4567", "IT", "393471234567"},
+		{"Italy 34x mobile with calling code", "39 347 123 4567", "IT", "393471234567"},
+		{"Indonesia with calling code", "62 812 3456 7890", "ID", "6281234567890"},
+		{"Australia exit code", "0011 91 98765 43210", "AU", "919876543210"},
+		{"Singapore national", "8123 4567", "SG", "6581234567"},
+		{"foreign number without plus", "447911123456", "IN", "447911123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.region)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalize_NoRegion(t *testing.T) {
+	got, err := Normalize("+1 (234) 567-890", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1234567890", got)
+
+	got, err = Normalize("1234567890", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1234567890", got)
+}
+
+func TestNormalize_UnknownRegionFallsBackToFormatOnly(t *testing.T) {
+	got, err := Normalize("98765 43210", "XX")
+	require.NoError(t, err)
+	assert.Equal(t, "9876543210", got)
+}
+
+func TestNormalize_PlusIgnoresRegion(t *testing.T) {
+	got, err := Normalize("+44 7911 123456", "IN")
+	require.NoError(t, err)
+	assert.Equal(t, "447911123456", got)
+}
+
+func TestNormalize_GroupJIDUnchanged(t *testing.T) {
+	got, err := Normalize("120363025246125486@g.us", "IN")
+	require.NoError(t, err)
+	assert.Equal(t, "120363025246125486@g.us", got)
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	inputs := []string{"", "   ", "+", "abc", "98765x43210", "12345", "+1234567890123456"}
+	for _, in := range inputs {
+		t.Run(in, func(t *testing.T) {
+			_, err := Normalize(in, "IN")
+			assert.ErrorIs(t, err, ErrInvalidNumber)
+		})
+	}
+}
+
+func TestIsValidRegion(t *testing.T) {
+	assert.True(t, IsValidRegion("IN"))
+	assert.True(t, IsValidRegion("us"))
+	assert.False(t, IsValidRegion(""))
+	assert.False(t, IsValidRegion("XX"))
+}
+
+func TestRegionsMetadataConsistent(t *testing.T) {
+	for code, meta := range regions {
+		assert.Len(t, code, 2, "region %s", code)
+		assert.NotEmpty(t, meta.CallingCode, "region %s", code)
+		assert.Positive(t, meta.MinLength, "region %s", code)
+		assert.GreaterOrEqual(t, meta.MaxLength, meta.MinLength, "region %s", code)
+		assert.LessOrEqual(t, len(meta.CallingCode)+meta.MaxLength, maxE164Length, "region %s", code)
+	}
+}
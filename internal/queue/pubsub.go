@@ -24,6 +24,7 @@ type CampaignStatsUpdate struct {
 	DeliveredCount int                  `json:"delivered_count"`
 	ReadCount      int                  `json:"read_count"`
 	FailedCount    int                  `json:"failed_count"`
+	OptedOutCount  int                  `json:"opted_out_count"`
 }
 
 // Publisher publishes messages to Redis pub/sub channels
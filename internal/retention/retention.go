@@ -0,0 +1,266 @@
+// Package retention enforces per-organization data retention policies by
+// purging old message bodies, media files and chatbot session logs.
+package retention
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultBatchSize is the number of rows purged per statement.
+const DefaultBatchSize = 500
+
+// Organization settings keys holding the retention periods in days.
+const (
+	SettingMessageBodyDays = "retention_message_body_days"
+	SettingMediaDays       = "retention_media_days"
+	SettingSessionLogDays  = "retention_session_log_days"
+)
+
+// Policy holds an organization's retention periods in days. Zero keeps data
+// forever.
+type Policy struct {
+	MessageBodyDays int `json:"message_body_days"` // Message text and payloads are blanked
+	MediaDays       int `json:"media_days"`        // Media files are removed from storage
+	SessionLogDays  int `json:"session_log_days"`  // Chatbot session messages are deleted
+}
+
+// IsEnabled reports whether any retention period is set.
+func (p Policy) IsEnabled() bool {
+	return p.MessageBodyDays > 0 || p.MediaDays > 0 || p.SessionLogDays > 0
+}
+
+// ParsePolicy reads the retention policy from organization settings.
+func ParsePolicy(settings models.JSONB) Policy {
+	return Policy{
+		MessageBodyDays: intSetting(settings, SettingMessageBodyDays),
+		MediaDays:       intSetting(settings, SettingMediaDays),
+		SessionLogDays:  intSetting(settings, SettingSessionLogDays),
+	}
+}
+
+func intSetting(settings models.JSONB, key string) int {
+	switch v := settings[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// Report counts what a purge removed, or would remove for a preview. Cutoffs
+// are nil for periods that are not set.
+type Report struct {
+	MessageBodies    int64      `json:"message_bodies"`
+	MediaFiles       int64      `json:"media_files"`
+	SessionMessages  int64      `json:"session_messages"`
+	MessageCutoff    *time.Time `json:"message_body_cutoff,omitempty"`
+	MediaCutoff      *time.Time `json:"media_cutoff,omitempty"`
+	SessionLogCutoff *time.Time `json:"session_log_cutoff,omitempty"`
+}
+
+func cutoff(days int, now time.Time) *time.Time {
+	if days <= 0 {
+		return nil
+	}
+	t := now.AddDate(0, 0, -days)
+	return &t
+}
+
+// Purger applies retention policies for one organization.
+type Purger struct {
+	DB        *gorm.DB
+	OrgID     uuid.UUID
+	Policy    Policy
+	MediaRoot string
+	BatchSize int
+	Now       time.Time
+}
+
+// heldContacts selects the organization's contacts on legal hold.
+func (p *Purger) heldContacts() *gorm.DB {
+	return p.DB.Model(&models.Contact{}).Unscoped().Select("id").
+		Where("organization_id = ? AND legal_hold = ?", p.OrgID, true)
+}
+
+func (p *Purger) messageBodies(before time.Time) *gorm.DB {
+	return p.DB.Unscoped().Model(&models.Message{}).
+		Where("organization_id = ? AND created_at < ? AND body_purged_at IS NULL", p.OrgID, before).
+		Where("contact_id NOT IN (?)", p.heldContacts())
+}
+
+func (p *Purger) mediaMessages(before time.Time) *gorm.DB {
+	return p.DB.Unscoped().Model(&models.Message{}).
+		Where("organization_id = ? AND created_at < ? AND media_url <> ''", p.OrgID, before).
+		Where("contact_id NOT IN (?)", p.heldContacts())
+}
+
+func (p *Purger) sessionMessages(before time.Time) *gorm.DB {
+	return p.DB.Unscoped().Model(&models.ChatbotSessionMessage{}).
+		Where("created_at < ?", before).
+		Where("session_id IN (?)", p.DB.Model(&models.ChatbotSession{}).Unscoped().Select("id").
+			Where("organization_id = ? AND contact_id NOT IN (?)", p.OrgID, p.heldContacts()))
+}
+
+// Preview counts what Purge would remove without changing anything.
+func (p *Purger) Preview() (*Report, error) {
+	report := &Report{
+		MessageCutoff:    cutoff(p.Policy.MessageBodyDays, p.Now),
+		MediaCutoff:      cutoff(p.Policy.MediaDays, p.Now),
+		SessionLogCutoff: cutoff(p.Policy.SessionLogDays, p.Now),
+	}
+	if report.MessageCutoff != nil {
+		if err := p.messageBodies(*report.MessageCutoff).Count(&report.MessageBodies).Error; err != nil {
+			return nil, err
+		}
+	}
+	if report.MediaCutoff != nil {
+		if err := p.mediaMessages(*report.MediaCutoff).Count(&report.MediaFiles).Error; err != nil {
+			return nil, err
+		}
+	}
+	if report.SessionLogCutoff != nil {
+		if err := p.sessionMessages(*report.SessionLogCutoff).Count(&report.SessionMessages).Error; err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Purge removes data older than the policy allows, in batches of BatchSize
+// rows. Contacts on legal hold are skipped; soft-deleted rows are purged too.
+// Media rows are only cleared once their file has been removed, so files that
+// fail to delete are retried on the next run.
+func (p *Purger) Purge() (*Report, error) {
+	if p.BatchSize <= 0 {
+		p.BatchSize = DefaultBatchSize
+	}
+	report := &Report{
+		MessageCutoff:    cutoff(p.Policy.MessageBodyDays, p.Now),
+		MediaCutoff:      cutoff(p.Policy.MediaDays, p.Now),
+		SessionLogCutoff: cutoff(p.Policy.SessionLogDays, p.Now),
+	}
+
+	if report.MediaCutoff != nil {
+		n, err := p.purgeMedia(*report.MediaCutoff)
+		report.MediaFiles = n
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if report.MessageCutoff != nil {
+		n, err := p.purgeMessageBodies(*report.MessageCutoff)
+		report.MessageBodies = n
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if report.SessionLogCutoff != nil {
+		n, err := p.inBatches(func() *gorm.DB {
+			return p.DB.Unscoped().Where("id IN (?)", p.sessionMessages(*report.SessionLogCutoff).Select("id").Limit(p.BatchSize)).
+				Delete(&models.ChatbotSessionMessage{})
+		})
+		report.SessionMessages = n
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// inBatches runs op until it affects fewer than BatchSize rows, returning the
+// total number of rows affected.
+func (p *Purger) inBatches(op func() *gorm.DB) (int64, error) {
+	var total int64
+	for {
+		res := op()
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if res.RowsAffected < int64(p.BatchSize) {
+			return total, nil
+		}
+	}
+}
+
+func (p *Purger) purgeMessageBodies(before time.Time) (int64, error) {
+	n, err := p.inBatches(func() *gorm.DB {
+		return p.DB.Unscoped().Model(&models.Message{}).
+			Where("id IN (?)", p.messageBodies(before).Select("id").Limit(p.BatchSize)).
+			UpdateColumns(map[string]interface{}{
+				"content":          "",
+				"template_params":  models.JSONB{},
+				"interactive_data": models.JSONB{},
+				"flow_response":    models.JSONB{},
+				"body_purged_at":   p.Now,
+			})
+	})
+	if err != nil {
+		return n, err
+	}
+
+	// The contact list keeps a copy of the last message text
+	err = p.DB.Model(&models.Contact{}).
+		Where("organization_id = ? AND last_message_at < ? AND last_message_preview <> '' AND legal_hold = ?", p.OrgID, before, false).
+		UpdateColumn("last_message_preview", "").Error
+	return n, err
+}
+
+func (p *Purger) purgeMedia(before time.Time) (int64, error) {
+	var total int64
+	for {
+		var rows []struct {
+			ID       uuid.UUID
+			MediaURL string
+		}
+		if err := p.mediaMessages(before).Select("id, media_url").Order("created_at").
+			Limit(p.BatchSize).Scan(&rows).Error; err != nil {
+			return total, err
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(rows))
+		var firstErr error
+		for _, row := range rows {
+			if path := contactutil.ResolveMediaPath(p.MediaRoot, row.MediaURL); path != "" {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to remove media file %s: %w", row.MediaURL, err)
+					}
+					continue
+				}
+			}
+			ids = append(ids, row.ID)
+		}
+
+		if len(ids) > 0 {
+			res := p.DB.Unscoped().Model(&models.Message{}).Where("id IN ?", ids).
+				UpdateColumns(map[string]interface{}{"media_url": "", "media_filename": ""})
+			if res.Error != nil {
+				return total, res.Error
+			}
+			total += res.RowsAffected
+		}
+
+		// Stop on file errors rather than spinning on rows that can't be cleared
+		if firstErr != nil {
+			return total, firstErr
+		}
+		if len(rows) < p.BatchSize {
+			return total, nil
+		}
+	}
+}
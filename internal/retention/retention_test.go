@@ -0,0 +1,138 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy := ParsePolicy(models.JSONB{
+		SettingMessageBodyDays: float64(30),
+		SettingMediaDays:       7,
+		SettingSessionLogDays:  "bogus",
+	})
+	assert.Equal(t, Policy{MessageBodyDays: 30, MediaDays: 7}, policy)
+	assert.True(t, policy.IsEnabled())
+
+	assert.False(t, ParsePolicy(nil).IsEnabled())
+}
+
+// createMessage stores a message for the contact created at the given time.
+func createMessage(t *testing.T, db *gorm.DB, orgID, contactID uuid.UUID, createdAt time.Time, mediaURL string) *models.Message {
+	t.Helper()
+	msg := &models.Message{
+		BaseModel:       models.BaseModel{CreatedAt: createdAt},
+		OrganizationID:  orgID,
+		WhatsAppAccount: "test",
+		ContactID:       contactID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "secret",
+		MediaURL:        mediaURL,
+	}
+	require.NoError(t, db.Create(msg).Error)
+	return msg
+}
+
+func TestPurger_Purge(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	mediaRoot := t.TempDir()
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+
+	org := testutil.CreateTestOrganization(t, db)
+	contact := testutil.CreateTestContact(t, db, org.ID)
+	held := testutil.CreateTestContact(t, db, org.ID)
+	require.NoError(t, db.Model(held).Update("legal_hold", true).Error)
+
+	mediaPath := filepath.Join("images", uuid.New().String()+".jpg")
+	require.NoError(t, os.MkdirAll(filepath.Join(mediaRoot, "images"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(mediaRoot, mediaPath), []byte("jpeg"), 0644))
+
+	oldMsg := createMessage(t, db, org.ID, contact.ID, old, mediaPath)
+	newMsg := createMessage(t, db, org.ID, contact.ID, now, "")
+	heldMsg := createMessage(t, db, org.ID, held.ID, old, "")
+
+	session := models.ChatbotSession{
+		OrganizationID:  org.ID,
+		ContactID:       contact.ID,
+		WhatsAppAccount: "test",
+		PhoneNumber:     contact.PhoneNumber,
+	}
+	require.NoError(t, db.Create(&session).Error)
+	require.NoError(t, db.Create(&models.ChatbotSessionMessage{
+		BaseModel: models.BaseModel{CreatedAt: old},
+		SessionID: session.ID,
+		Direction: models.DirectionIncoming,
+		Message:   "a@example.com",
+	}).Error)
+
+	purger := &Purger{
+		DB:        db,
+		OrgID:     org.ID,
+		Policy:    Policy{MessageBodyDays: 30, MediaDays: 30, SessionLogDays: 30},
+		MediaRoot: mediaRoot,
+		BatchSize: 1,
+		Now:       now,
+	}
+
+	preview, err := purger.Preview()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), preview.MessageBodies)
+	assert.Equal(t, int64(1), preview.MediaFiles)
+	assert.Equal(t, int64(1), preview.SessionMessages)
+
+	report, err := purger.Purge()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.MessageBodies)
+	assert.Equal(t, int64(1), report.MediaFiles)
+	assert.Equal(t, int64(1), report.SessionMessages)
+
+	var got models.Message
+	require.NoError(t, db.First(&got, oldMsg.ID).Error)
+	assert.Empty(t, got.Content)
+	assert.Empty(t, got.MediaURL)
+	assert.NotNil(t, got.BodyPurgedAt)
+	_, statErr := os.Stat(filepath.Join(mediaRoot, mediaPath))
+	assert.True(t, os.IsNotExist(statErr))
+
+	require.NoError(t, db.First(&got, newMsg.ID).Error)
+	assert.Equal(t, "secret", got.Content)
+
+	require.NoError(t, db.First(&got, heldMsg.ID).Error)
+	assert.Equal(t, "secret", got.Content, "legal hold must exempt the contact")
+
+	var count int64
+	db.Model(&models.ChatbotSessionMessage{}).Where("session_id = ?", session.ID).Count(&count)
+	assert.Zero(t, count)
+
+	// A second run has nothing left to do
+	report, err = purger.Purge()
+	require.NoError(t, err)
+	assert.Zero(t, report.MessageBodies+report.MediaFiles+report.SessionMessages)
+}
+
+func TestPurger_DisabledPeriods(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	org := testutil.CreateTestOrganization(t, db)
+	contact := testutil.CreateTestContact(t, db, org.ID)
+	msg := createMessage(t, db, org.ID, contact.ID, time.Now().AddDate(-1, 0, 0), "")
+
+	report, err := (&Purger{DB: db, OrgID: org.ID, Now: time.Now()}).Purge()
+	require.NoError(t, err)
+	assert.Nil(t, report.MessageCutoff)
+	assert.Zero(t, report.MessageBodies)
+
+	var got models.Message
+	require.NoError(t, db.First(&got, msg.ID).Error)
+	assert.Equal(t, "secret", got.Content)
+}
@@ -0,0 +1,477 @@
+// Package segmentutil compiles saved contact segment rule trees to SQL.
+package segmentutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+const (
+	// MatchAll requires every child rule to match
+	MatchAll = "all"
+	// MatchAny requires at least one child rule to match
+	MatchAny = "any"
+
+	// MaxDepth limits how deeply rule groups can be nested
+	MaxDepth = 5
+	// MaxConditions limits the number of conditions in a rule tree
+	MaxConditions = 50
+
+	// AttributeFieldPrefix marks fields that refer to org-defined contact attributes
+	AttributeFieldPrefix = "attr."
+)
+
+// Rule is a node in a segment rule tree. A node is either a group (Match and
+// Rules) or a condition (Field, Op and Value).
+type Rule struct {
+	Match string      `json:"match,omitempty"`
+	Rules []Rule      `json:"rules,omitempty"`
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// IsGroup reports whether the rule is a group of child rules
+func (r Rule) IsGroup() bool {
+	return r.Field == ""
+}
+
+// ToJSONB converts the rule tree for storage in a JSONB column
+func (r Rule) ToJSONB() models.JSONB {
+	data, _ := json.Marshal(r)
+	var result models.JSONB
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+// ParseRules decodes a rule tree stored in a JSONB column
+func ParseRules(data models.JSONB) (Rule, error) {
+	var rule Rule
+	if len(data) == 0 {
+		return Rule{Match: MatchAll}, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return rule, err
+	}
+	if err := json.Unmarshal(raw, &rule); err != nil {
+		return rule, fmt.Errorf("invalid rules: %w", err)
+	}
+	return rule, nil
+}
+
+// Field kinds determine which operators a field supports
+const (
+	kindText     = "text"
+	kindUUID     = "uuid"
+	kindDate     = "date"
+	kindNumber   = "number"
+	kindTags     = "tags"
+	kindCampaign = "campaign"
+)
+
+var kindOps = map[string][]string{
+	kindText:     {"eq", "ne", "contains", "starts_with", "is_empty", "is_not_empty"},
+	kindUUID:     {"eq", "ne", "is_empty", "is_not_empty"},
+	kindDate:     {"before", "after", "within_days", "older_than_days", "is_empty", "is_not_empty"},
+	kindNumber:   {"eq", "ne", "gt", "gte", "lt", "lte"},
+	kindTags:     {"has", "not_has", "has_any", "is_empty", "is_not_empty"},
+	kindCampaign: {"received", "delivered", "read", "failed", "not_received"},
+}
+
+type builtinField struct {
+	label string
+	kind  string
+	expr  string
+}
+
+// builtinFields are server-defined, so their expressions are safe to inline.
+// Contact columns are qualified because message and campaign subqueries
+// have columns with the same names.
+var builtinFields = map[string]builtinField{
+	"phone_number":     {"Phone Number", kindText, "contacts.phone_number"},
+	"profile_name":     {"Name", kindText, "contacts.profile_name"},
+	"whatsapp_account": {"WhatsApp Account", kindText, "contacts.whats_app_account"},
+	"consent_status":   {"Consent Status", kindText, "contacts.consent_status"},
+	"assigned_user_id": {"Assigned User", kindUUID, "contacts.assigned_user_id"},
+	"created_at":       {"Created At", kindDate, "contacts.created_at"},
+	"last_message_at":  {"Last Message At", kindDate, "contacts.last_message_at"},
+	"tags":             {"Tags", kindTags, "contacts.tags"},
+	"messages.last_incoming_at": {"Last Incoming Message At", kindDate, fmt.Sprintf(
+		"(SELECT MAX(m.created_at) FROM messages m WHERE m.contact_id = contacts.id AND m.direction = '%s' AND m.deleted_at IS NULL)",
+		models.DirectionIncoming)},
+	"messages.incoming_count": {"Incoming Messages", kindNumber, fmt.Sprintf(
+		"(SELECT COUNT(*) FROM messages m WHERE m.contact_id = contacts.id AND m.direction = '%s' AND m.deleted_at IS NULL)",
+		models.DirectionIncoming)},
+	"messages.outgoing_count": {"Outgoing Messages", kindNumber, fmt.Sprintf(
+		"(SELECT COUNT(*) FROM messages m WHERE m.contact_id = contacts.id AND m.direction = '%s' AND m.deleted_at IS NULL)",
+		models.DirectionOutgoing)},
+	"campaign": {"Campaign Engagement", kindCampaign, ""},
+}
+
+// builtinFieldOrder is the order fields are listed in Fields
+var builtinFieldOrder = []string{
+	"phone_number", "profile_name", "whatsapp_account", "consent_status", "assigned_user_id", "created_at",
+	"last_message_at", "tags", "messages.last_incoming_at", "messages.incoming_count",
+	"messages.outgoing_count", "campaign",
+}
+
+// campaignStatuses maps campaign engagement operators to recipient statuses
+var campaignStatuses = map[string][]models.MessageStatus{
+	"received":     {models.MessageStatusSent, models.MessageStatusDelivered, models.MessageStatusRead},
+	"delivered":    {models.MessageStatusDelivered, models.MessageStatusRead},
+	"read":         {models.MessageStatusRead},
+	"failed":       {models.MessageStatusFailed},
+	"not_received": {models.MessageStatusSent, models.MessageStatusDelivered, models.MessageStatusRead},
+}
+
+// Field describes a field that can be used in segment rules
+type Field struct {
+	Field   string   `json:"field"`
+	Label   string   `json:"label"`
+	Type    string   `json:"type"`
+	Ops     []string `json:"ops"`
+	Options []string `json:"options,omitempty"`
+}
+
+// Fields returns the fields available for segment rules, including the
+// organization's contact attributes.
+func Fields(defs []models.ContactAttribute) []Field {
+	fields := make([]Field, 0, len(builtinFieldOrder)+len(defs))
+	for _, name := range builtinFieldOrder {
+		f := builtinFields[name]
+		fields = append(fields, Field{Field: name, Label: f.label, Type: f.kind, Ops: kindOps[f.kind]})
+	}
+	for _, d := range defs {
+		fields = append(fields, Field{
+			Field:   AttributeFieldPrefix + d.Key,
+			Label:   d.Label,
+			Type:    string(d.Type),
+			Ops:     attributeOps(d.Type),
+			Options: d.Options,
+		})
+	}
+	return fields
+}
+
+// attributeOps returns the operators supported for an attribute type
+func attributeOps(t models.ContactAttributeType) []string {
+	switch t {
+	case models.ContactAttributeTypeNumber, models.ContactAttributeTypeDate:
+		return []string{"eq", "ne", "gt", "gte", "lt", "lte", "in", "is_empty", "is_not_empty"}
+	case models.ContactAttributeTypeText:
+		return []string{"eq", "ne", "contains", "in", "is_empty", "is_not_empty"}
+	default:
+		return []string{"eq", "ne", "in", "is_empty", "is_not_empty"}
+	}
+}
+
+// compiler holds state while compiling a rule tree
+type compiler struct {
+	attrs      map[string]models.ContactAttribute
+	now        time.Time
+	conditions int
+}
+
+// Compile compiles a rule tree into a SQL condition on the contacts table and
+// its arguments. defs are the organization's contact attribute definitions and
+// now is the reference time for relative date operators. The caller is
+// responsible for scoping the query to the organization.
+func Compile(root Rule, defs []models.ContactAttribute, now time.Time) (string, []interface{}, error) {
+	c := &compiler{attrs: make(map[string]models.ContactAttribute, len(defs)), now: now}
+	for _, d := range defs {
+		c.attrs[d.Key] = d
+	}
+	return c.compile(root, 1)
+}
+
+// Validate checks that a rule tree compiles against the attribute definitions
+func Validate(root Rule, defs []models.ContactAttribute) error {
+	_, _, err := Compile(root, defs, time.Now())
+	return err
+}
+
+func (c *compiler) compile(r Rule, depth int) (string, []interface{}, error) {
+	if !r.IsGroup() {
+		c.conditions++
+		if c.conditions > MaxConditions {
+			return "", nil, fmt.Errorf("segments can have at most %d conditions", MaxConditions)
+		}
+		return c.condition(r)
+	}
+
+	if depth > MaxDepth {
+		return "", nil, fmt.Errorf("rule groups can be nested at most %d levels deep", MaxDepth)
+	}
+
+	joiner := " AND "
+	switch r.Match {
+	case MatchAll, "":
+	case MatchAny:
+		joiner = " OR "
+	default:
+		return "", nil, fmt.Errorf("invalid match %q, must be all or any", r.Match)
+	}
+
+	if len(r.Rules) == 0 {
+		// An empty "all" group matches every contact
+		return "TRUE", nil, nil
+	}
+
+	parts := make([]string, 0, len(r.Rules))
+	var args []interface{}
+	for _, child := range r.Rules {
+		sql, childArgs, err := c.compile(child, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "("+sql+")")
+		args = append(args, childArgs...)
+	}
+	return strings.Join(parts, joiner), args, nil
+}
+
+func (c *compiler) condition(r Rule) (string, []interface{}, error) {
+	if key, ok := strings.CutPrefix(r.Field, AttributeFieldPrefix); ok {
+		def, exists := c.attrs[key]
+		if !exists {
+			return "", nil, fmt.Errorf("unknown attribute: %s", key)
+		}
+		return contactutil.AttributeCondition("contacts.attributes", def, r.Op, r.Value)
+	}
+
+	field, ok := builtinFields[r.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown field: %s", r.Field)
+	}
+	if !containsOp(kindOps[field.kind], r.Op) {
+		return "", nil, fmt.Errorf("operator %s is not supported for field %s", r.Op, r.Field)
+	}
+
+	switch field.kind {
+	case kindText:
+		return textCondition(field.expr, r)
+	case kindUUID:
+		return uuidCondition(field.expr, r)
+	case kindDate:
+		return c.dateCondition(field.expr, r)
+	case kindNumber:
+		return numberCondition(field.expr, r)
+	case kindTags:
+		return tagsCondition(field.expr, r)
+	case kindCampaign:
+		return campaignCondition(r)
+	}
+	return "", nil, fmt.Errorf("unknown field: %s", r.Field)
+}
+
+func textCondition(expr string, r Rule) (string, []interface{}, error) {
+	switch r.Op {
+	case "is_empty":
+		return "COALESCE(" + expr + ", '') = ''", nil, nil
+	case "is_not_empty":
+		return "COALESCE(" + expr + ", '') <> ''", nil, nil
+	}
+
+	s, ok := r.Value.(string)
+	if !ok || s == "" {
+		return "", nil, fmt.Errorf("%s: value must be a non-empty string", r.Field)
+	}
+	switch r.Op {
+	case "eq":
+		return expr + " = ?", []interface{}{s}, nil
+	case "ne":
+		return "COALESCE(" + expr + ", '') <> ?", []interface{}{s}, nil
+	case "contains":
+		return expr + " ILIKE ?", []interface{}{"%" + s + "%"}, nil
+	default: // starts_with
+		return expr + " LIKE ?", []interface{}{s + "%"}, nil
+	}
+}
+
+func uuidCondition(expr string, r Rule) (string, []interface{}, error) {
+	switch r.Op {
+	case "is_empty":
+		return expr + " IS NULL", nil, nil
+	case "is_not_empty":
+		return expr + " IS NOT NULL", nil, nil
+	}
+
+	s, _ := r.Value.(string)
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: value must be a valid ID", r.Field)
+	}
+	if r.Op == "eq" {
+		return expr + " = ?", []interface{}{id}, nil
+	}
+	return "(" + expr + " IS NULL OR " + expr + " <> ?)", []interface{}{id}, nil
+}
+
+func (c *compiler) dateCondition(expr string, r Rule) (string, []interface{}, error) {
+	switch r.Op {
+	case "is_empty":
+		return expr + " IS NULL", nil, nil
+	case "is_not_empty":
+		return expr + " IS NOT NULL", nil, nil
+	case "within_days", "older_than_days":
+		days, err := toFloat(r.Value)
+		if err != nil || days < 0 {
+			return "", nil, fmt.Errorf("%s: value must be a non-negative number of days", r.Field)
+		}
+		cutoff := c.now.Add(-time.Duration(days * float64(24*time.Hour)))
+		if r.Op == "within_days" {
+			return expr + " >= ?", []interface{}{cutoff}, nil
+		}
+		return expr + " < ?", []interface{}{cutoff}, nil
+	}
+
+	s, _ := r.Value.(string)
+	t, err := parseTime(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: value must be a date (YYYY-MM-DD) or RFC3339 timestamp", r.Field)
+	}
+	if r.Op == "before" {
+		return expr + " < ?", []interface{}{t}, nil
+	}
+	return expr + " > ?", []interface{}{t}, nil
+}
+
+var numberOps = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+func numberCondition(expr string, r Rule) (string, []interface{}, error) {
+	n, err := toFloat(r.Value)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: value must be a number", r.Field)
+	}
+	return expr + " " + numberOps[r.Op] + " ?", []interface{}{n}, nil
+}
+
+func tagsCondition(expr string, r Rule) (string, []interface{}, error) {
+	switch r.Op {
+	case "is_empty":
+		return "COALESCE(jsonb_array_length(" + expr + "), 0) = 0", nil, nil
+	case "is_not_empty":
+		return "COALESCE(jsonb_array_length(" + expr + "), 0) > 0", nil, nil
+	}
+
+	tags := toStrings(r.Value)
+	if len(tags) == 0 {
+		return "", nil, fmt.Errorf("%s: value must be a tag or list of tags", r.Field)
+	}
+	containment := func(tag string) string {
+		data, _ := json.Marshal([]string{tag})
+		return string(data)
+	}
+
+	switch r.Op {
+	case "has":
+		if len(tags) != 1 {
+			return "", nil, fmt.Errorf("%s: has takes a single tag, use has_any for a list", r.Field)
+		}
+		return expr + " @> ?::jsonb", []interface{}{containment(tags[0])}, nil
+	case "not_has":
+		if len(tags) != 1 {
+			return "", nil, fmt.Errorf("%s: not_has takes a single tag", r.Field)
+		}
+		return "NOT (COALESCE(" + expr + ", '[]'::jsonb) @> ?::jsonb)", []interface{}{containment(tags[0])}, nil
+	default: // has_any
+		conditions := make([]string, len(tags))
+		args := make([]interface{}, len(tags))
+		for i, tag := range tags {
+			conditions[i] = expr + " @> ?::jsonb"
+			args[i] = containment(tag)
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+	}
+}
+
+func campaignCondition(r Rule) (string, []interface{}, error) {
+	sql := "EXISTS (SELECT 1 FROM bulk_message_recipients bmr JOIN bulk_message_campaigns bmc ON bmc.id = bmr.campaign_id" +
+		" WHERE bmc.organization_id = contacts.organization_id AND bmr.phone_number = contacts.phone_number" +
+		" AND bmr.deleted_at IS NULL AND bmr.status IN ?"
+	args := []interface{}{campaignStatuses[r.Op]}
+
+	// An empty value matches any campaign
+	if r.Value != nil {
+		s, ok := r.Value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%s: value must be a campaign ID", r.Field)
+		}
+		if s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: value must be a campaign ID", r.Field)
+			}
+			sql += " AND bmr.campaign_id = ?"
+			args = append(args, id)
+		}
+	}
+	sql += ")"
+
+	if r.Op == "not_received" {
+		sql = "NOT " + sql
+	}
+	return sql, args, nil
+}
+
+func containsOp(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case float64:
+		return val, nil
+	case int:
+		return float64(val), nil
+	case json.Number:
+		return val.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(val), 64)
+	}
+	return 0, fmt.Errorf("not a number")
+}
+
+func toStrings(v interface{}) []string {
+	var result []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		add(val)
+	case []string:
+		for _, s := range val {
+			add(s)
+		}
+	case []interface{}:
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				add(s)
+			}
+		}
+	}
+	return result
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(contactutil.AttributeDateLayout, s)
+}
@@ -0,0 +1,144 @@
+package segmentutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+var testDefs = []models.ContactAttribute{
+	{Key: "city", Label: "City", Type: models.ContactAttributeTypeText},
+	{Key: "orders", Label: "Orders", Type: models.ContactAttributeTypeNumber},
+}
+
+func TestCompile_Groups(t *testing.T) {
+	rule := Rule{
+		Match: MatchAll,
+		Rules: []Rule{
+			{Field: "tags", Op: "has", Value: "vip"},
+			{Field: "last_message_at", Op: "older_than_days", Value: float64(30)},
+			{Match: MatchAny, Rules: []Rule{
+				{Field: "attr.city", Op: "eq", Value: "Pune"},
+				{Field: "attr.orders", Op: "gte", Value: "5"},
+			}},
+		},
+	}
+
+	sql, args, err := Compile(rule, testDefs, testNow)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"(contacts.tags @> ?::jsonb) AND (contacts.last_message_at < ?) AND "+
+			"((contacts.attributes @> ?::jsonb) OR "+
+			"((CASE WHEN jsonb_typeof(contacts.attributes->'orders') = 'number' THEN (contacts.attributes->>'orders')::numeric END) >= ?))",
+		sql)
+	require.Len(t, args, 4)
+	assert.Equal(t, `["vip"]`, args[0])
+	assert.Equal(t, testNow.AddDate(0, 0, -30), args[1])
+	assert.Equal(t, `{"city":"Pune"}`, args[2])
+	assert.Equal(t, float64(5), args[3])
+}
+
+func TestCompile_EmptyRootMatchesAll(t *testing.T) {
+	sql, args, err := Compile(Rule{}, nil, testNow)
+	require.NoError(t, err)
+	assert.Equal(t, "TRUE", sql)
+	assert.Empty(t, args)
+}
+
+func TestCompile_Conditions(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		sql  string
+	}{
+		{"text contains", Rule{Field: "profile_name", Op: "contains", Value: "ali"}, "contacts.profile_name ILIKE ?"},
+		{"uuid empty", Rule{Field: "assigned_user_id", Op: "is_empty"}, "contacts.assigned_user_id IS NULL"},
+		{"date after", Rule{Field: "created_at", Op: "after", Value: "2024-01-01"}, "contacts.created_at > ?"},
+		{"tags any", Rule{Field: "tags", Op: "has_any", Value: []interface{}{"a", "b"}}, "(contacts.tags @> ?::jsonb OR contacts.tags @> ?::jsonb)"},
+		{"incoming count", Rule{Field: "messages.incoming_count", Op: "gt", Value: float64(3)},
+			"(SELECT COUNT(*) FROM messages m WHERE m.contact_id = contacts.id AND m.direction = 'incoming' AND m.deleted_at IS NULL) > ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, _, err := Compile(tt.rule, testDefs, testNow)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sql, sql)
+		})
+	}
+}
+
+func TestCompile_Campaign(t *testing.T) {
+	sql, args, err := Compile(Rule{Field: "campaign", Op: "not_received", Value: "7f3c1a9e-3c52-4d3f-9d35-2f3b0f0a4b11"}, nil, testNow)
+	require.NoError(t, err)
+	assert.Contains(t, sql, "NOT EXISTS (SELECT 1 FROM bulk_message_recipients bmr")
+	assert.Contains(t, sql, "AND bmr.campaign_id = ?)")
+	require.Len(t, args, 2)
+	assert.Equal(t, []models.MessageStatus{models.MessageStatusSent, models.MessageStatusDelivered, models.MessageStatusRead}, args[0])
+
+	// Empty value matches any campaign
+	sql, args, err = Compile(Rule{Field: "campaign", Op: "read"}, nil, testNow)
+	require.NoError(t, err)
+	assert.NotContains(t, sql, "campaign_id = ?")
+	assert.Len(t, args, 1)
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		err  string
+	}{
+		{"unknown field", Rule{Field: "password", Op: "eq", Value: "x"}, "unknown field"},
+		{"unknown attribute", Rule{Field: "attr.missing", Op: "eq", Value: "x"}, "unknown attribute"},
+		{"unsupported op", Rule{Field: "tags", Op: "gt", Value: "x"}, "not supported"},
+		{"invalid match", Rule{Match: "none", Rules: []Rule{{Field: "tags", Op: "is_empty"}}}, "invalid match"},
+		{"bad date", Rule{Field: "created_at", Op: "before", Value: "yesterday"}, "must be a date"},
+		{"bad uuid", Rule{Field: "assigned_user_id", Op: "eq", Value: "me"}, "valid ID"},
+		{"bad campaign", Rule{Field: "campaign", Op: "read", Value: float64(1)}, "campaign ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Compile(tt.rule, testDefs, testNow)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestCompile_Limits(t *testing.T) {
+	deep := Rule{Field: "tags", Op: "is_empty"}
+	for i := 0; i <= MaxDepth; i++ {
+		deep = Rule{Match: MatchAll, Rules: []Rule{deep}}
+	}
+	_, _, err := Compile(deep, nil, testNow)
+	assert.ErrorContains(t, err, "nested")
+
+	wide := Rule{Match: MatchAny}
+	for i := 0; i <= MaxConditions; i++ {
+		wide.Rules = append(wide.Rules, Rule{Field: "tags", Op: "is_empty"})
+	}
+	_, _, err = Compile(wide, nil, testNow)
+	assert.ErrorContains(t, err, "at most")
+}
+
+func TestParseRules_RoundTrip(t *testing.T) {
+	rule := Rule{Match: MatchAny, Rules: []Rule{{Field: "tags", Op: "has", Value: "vip"}}}
+	parsed, err := ParseRules(rule.ToJSONB())
+	require.NoError(t, err)
+	assert.Equal(t, rule, parsed)
+
+	empty, err := ParseRules(nil)
+	require.NoError(t, err)
+	assert.Equal(t, MatchAll, empty.Match)
+}
+
+func TestFields_IncludesAttributes(t *testing.T) {
+	fields := Fields(testDefs)
+	last := fields[len(fields)-1]
+	assert.Equal(t, "attr.orders", last.Field)
+	assert.Contains(t, last.Ops, "gte")
+}
@@ -0,0 +1,174 @@
+// Package shiftutil works out when agents are scheduled to work from their
+// weekly shifts, leave and overtime, and validates shift definitions.
+package shiftutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// MaxExceptionDays caps the length of a single leave or overtime period
+const MaxExceptionDays = 366
+
+// Plan is an agent's working time: the weekly shifts, less leave, plus
+// overtime.
+type Plan struct {
+	Weekly   *bizhours.Schedule
+	Leave    []bizhours.Interval
+	Overtime []bizhours.Interval
+}
+
+// New builds the plan of a shift schedule with its exceptions. A nil or
+// inactive schedule has no weekly shifts, so only overtime counts.
+func New(schedule *models.ShiftSchedule, loc *time.Location, exceptions []models.ShiftException) *Plan {
+	var hours models.JSONBArray
+	if schedule != nil && schedule.IsActive {
+		hours = schedule.Hours
+	}
+	p := &Plan{Weekly: bizhours.New(hours, loc, nil)}
+	for _, e := range exceptions {
+		iv := bizhours.Interval{Start: e.StartsAt, End: e.EndsAt}
+		switch e.Type {
+		case models.ShiftExceptionLeave:
+			p.Leave = append(p.Leave, iv)
+		case models.ShiftExceptionOvertime:
+			p.Overtime = append(p.Overtime, iv)
+		}
+	}
+	return p
+}
+
+// Intervals returns the working intervals within [from, to), merged and in
+// order. Overtime takes precedence over leave.
+func (p *Plan) Intervals(from, to time.Time) []bizhours.Interval {
+	working := Subtract(bizhours.Clip(p.Weekly.Intervals(from, to), from, to), p.Leave)
+	return bizhours.Merge(append(working, bizhours.Clip(p.Overtime, from, to)...))
+}
+
+// OnShift reports whether t falls within working time
+func (p *Plan) OnShift(t time.Time) bool {
+	return len(p.Intervals(t, t.Add(time.Second))) > 0
+}
+
+// Duration returns the working time within [from, to)
+func (p *Plan) Duration(from, to time.Time) time.Duration {
+	return Total(p.Intervals(from, to))
+}
+
+// Subtract removes the parts of intervals covered by cut
+func Subtract(intervals, cut []bizhours.Interval) []bizhours.Interval {
+	out := intervals
+	for _, c := range cut {
+		next := make([]bizhours.Interval, 0, len(out))
+		for _, iv := range out {
+			if !c.Start.Before(iv.End) || !c.End.After(iv.Start) {
+				next = append(next, iv)
+				continue
+			}
+			if c.Start.After(iv.Start) {
+				next = append(next, bizhours.Interval{Start: iv.Start, End: c.Start})
+			}
+			if c.End.Before(iv.End) {
+				next = append(next, bizhours.Interval{Start: c.End, End: iv.End})
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+// Overlap returns the time covered by both sets of intervals
+func Overlap(a, b []bizhours.Interval) time.Duration {
+	a, b = bizhours.Merge(a), bizhours.Merge(b)
+	var total time.Duration
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := a[i].Start, a[i].End
+		if b[j].Start.After(start) {
+			start = b[j].Start
+		}
+		if b[j].End.Before(end) {
+			end = b[j].End
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+		if a[i].End.Before(b[j].End) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return total
+}
+
+// Total returns the combined length of intervals
+func Total(intervals []bizhours.Interval) time.Duration {
+	var total time.Duration
+	for _, iv := range intervals {
+		total += iv.End.Sub(iv.Start)
+	}
+	return total
+}
+
+// ValidateHours checks weekly shift hours, given in the business_hours
+// format, and returns a message suitable for API clients
+func ValidateHours(hours models.JSONBArray) error {
+	seen := map[int]bool{}
+	for i, entry := range hours {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("hours entry %d must be an object", i+1)
+		}
+		day, ok := m["day"].(float64)
+		if !ok || day != float64(int(day)) || day < 0 || day > 6 {
+			return fmt.Errorf("hours entry %d must have a day from 0 (Sunday) to 6", i+1)
+		}
+		if seen[int(day)] {
+			return fmt.Errorf("day %d is listed more than once", int(day))
+		}
+		seen[int(day)] = true
+		if enabled, _ := m["enabled"].(bool); !enabled {
+			continue
+		}
+
+		periods := []interface{}{m}
+		if ranges, ok := m["ranges"].([]interface{}); ok && len(ranges) > 0 {
+			periods = ranges
+		}
+		for _, item := range periods {
+			p, _ := item.(map[string]interface{})
+			start, _ := p["start_time"].(string)
+			end, _ := p["end_time"].(string)
+			if _, ok := bizhours.ParseClock(start); !ok {
+				return fmt.Errorf("day %d has an invalid start_time, use HH:MM", int(day))
+			}
+			if _, ok := bizhours.ParseClock(end); !ok {
+				return fmt.Errorf("day %d has an invalid end_time, use HH:MM", int(day))
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateException checks a leave or overtime period
+func ValidateException(t models.ShiftExceptionType, start, end time.Time) error {
+	switch t {
+	case models.ShiftExceptionLeave, models.ShiftExceptionOvertime:
+	default:
+		return fmt.Errorf("invalid exception type %q", t)
+	}
+	if start.IsZero() || end.IsZero() {
+		return errors.New("starts_at and ends_at are required")
+	}
+	if !end.After(start) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if end.Sub(start) > MaxExceptionDays*24*time.Hour {
+		return fmt.Errorf("an exception can last at most %d days", MaxExceptionDays)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package shiftutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shridarpatil/whatomate/internal/bizhours"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nineToFive works Monday to Friday, 09:00-17:00
+func nineToFive() *models.ShiftSchedule {
+	hours := models.JSONBArray{}
+	for day := 1; day <= 5; day++ {
+		hours = append(hours, map[string]interface{}{
+			"day": float64(day), "enabled": true, "start_time": "09:00", "end_time": "17:00",
+		})
+	}
+	return &models.ShiftSchedule{Hours: hours, IsActive: true}
+}
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPlan_OnShift(t *testing.T) {
+	// 2025-03-03 is a Monday
+	plan := New(nineToFive(), time.UTC, []models.ShiftException{
+		{Type: models.ShiftExceptionLeave, StartsAt: at("2025-03-04 00:00"), EndsAt: at("2025-03-05 00:00")},
+		{Type: models.ShiftExceptionOvertime, StartsAt: at("2025-03-08 10:00"), EndsAt: at("2025-03-08 14:00")},
+	})
+
+	assert.True(t, plan.OnShift(at("2025-03-03 09:00")))
+	assert.False(t, plan.OnShift(at("2025-03-03 17:00")))
+	assert.False(t, plan.OnShift(at("2025-03-04 12:00")), "on leave")
+	assert.True(t, plan.OnShift(at("2025-03-08 11:00")), "overtime on a Saturday")
+	assert.False(t, plan.OnShift(at("2025-03-09 11:00")))
+}
+
+func TestPlan_Timezone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	plan := New(nineToFive(), loc, nil)
+
+	// 09:00 IST is 03:30 UTC
+	assert.False(t, plan.OnShift(at("2025-03-03 03:29")))
+	assert.True(t, plan.OnShift(at("2025-03-03 03:30")))
+}
+
+func TestPlan_Duration(t *testing.T) {
+	plan := New(nineToFive(), time.UTC, []models.ShiftException{
+		// Half-day leave on Tuesday
+		{Type: models.ShiftExceptionLeave, StartsAt: at("2025-03-04 13:00"), EndsAt: at("2025-03-04 17:00")},
+		// Overtime running past Wednesday's shift
+		{Type: models.ShiftExceptionOvertime, StartsAt: at("2025-03-05 16:00"), EndsAt: at("2025-03-05 19:00")},
+	})
+
+	week := plan.Duration(at("2025-03-03 00:00"), at("2025-03-10 00:00"))
+	assert.Equal(t, 40*time.Hour-4*time.Hour+2*time.Hour, week)
+
+	// Partial periods are clipped
+	assert.Equal(t, 3*time.Hour, plan.Duration(at("2025-03-03 14:00"), at("2025-03-03 20:00")))
+}
+
+func TestPlan_InactiveScheduleKeepsOvertime(t *testing.T) {
+	schedule := nineToFive()
+	schedule.IsActive = false
+	plan := New(schedule, time.UTC, []models.ShiftException{
+		{Type: models.ShiftExceptionOvertime, StartsAt: at("2025-03-03 18:00"), EndsAt: at("2025-03-03 20:00")},
+	})
+
+	assert.False(t, plan.OnShift(at("2025-03-03 10:00")))
+	assert.True(t, plan.OnShift(at("2025-03-03 19:00")))
+}
+
+func TestOverlap(t *testing.T) {
+	a := []bizhours.Interval{
+		{Start: at("2025-03-03 09:00"), End: at("2025-03-03 12:00")},
+		{Start: at("2025-03-03 13:00"), End: at("2025-03-03 17:00")},
+	}
+	b := []bizhours.Interval{
+		{Start: at("2025-03-03 08:00"), End: at("2025-03-03 10:00")},
+		{Start: at("2025-03-03 11:00"), End: at("2025-03-03 14:00")},
+	}
+	assert.Equal(t, 3*time.Hour, Overlap(a, b))
+	assert.Zero(t, Overlap(a, nil))
+}
+
+func TestValidateHours(t *testing.T) {
+	assert.NoError(t, ValidateHours(nineToFive().Hours))
+	assert.NoError(t, ValidateHours(models.JSONBArray{
+		map[string]interface{}{"day": float64(6), "enabled": false},
+		map[string]interface{}{"day": float64(0), "enabled": true, "ranges": []interface{}{
+			map[string]interface{}{"start_time": "22:00", "end_time": "06:00"},
+		}},
+	}))
+
+	assert.EqualError(t, ValidateHours(models.JSONBArray{
+		map[string]interface{}{"day": float64(7), "enabled": true, "start_time": "09:00", "end_time": "17:00"},
+	}), "hours entry 1 must have a day from 0 (Sunday) to 6")
+	assert.EqualError(t, ValidateHours(models.JSONBArray{
+		map[string]interface{}{"day": float64(1), "enabled": true, "start_time": "9am", "end_time": "17:00"},
+	}), "day 1 has an invalid start_time, use HH:MM")
+	assert.EqualError(t, ValidateHours(models.JSONBArray{
+		map[string]interface{}{"day": float64(1), "enabled": false},
+		map[string]interface{}{"day": float64(1), "enabled": false},
+	}), "day 1 is listed more than once")
+}
+
+func TestValidateException(t *testing.T) {
+	start := at("2025-03-03 09:00")
+	assert.NoError(t, ValidateException(models.ShiftExceptionLeave, start, start.Add(time.Hour)))
+	assert.EqualError(t, ValidateException("sick", start, start.Add(time.Hour)), `invalid exception type "sick"`)
+	assert.EqualError(t, ValidateException(models.ShiftExceptionOvertime, start, start), "ends_at must be after starts_at")
+	assert.Error(t, ValidateException(models.ShiftExceptionLeave, start, start.AddDate(2, 0, 0)))
+}
@@ -0,0 +1,188 @@
+// Package skillutil derives the skills a conversation needs and ranks agents
+// against them for skills-based routing.
+package skillutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// ParseRequirements reads skill requirements from a JSON-decoded value as
+// stored in flow transfer configs and keyword rule responses. It accepts a
+// list of skill names, a list of {"skill", "min_proficiency"} objects, or a
+// mix of both. Unknown shapes are ignored.
+func ParseRequirements(v interface{}) models.SkillRequirements {
+	var reqs models.SkillRequirements
+	switch list := v.(type) {
+	case []string:
+		for _, name := range list {
+			reqs = append(reqs, models.SkillRequirement{Skill: name})
+		}
+	case []interface{}:
+		for _, item := range list {
+			switch it := item.(type) {
+			case string:
+				reqs = append(reqs, models.SkillRequirement{Skill: it})
+			case map[string]interface{}:
+				name, _ := it["skill"].(string)
+				req := models.SkillRequirement{Skill: name}
+				if n, ok := it["min_proficiency"].(float64); ok {
+					req.MinProficiency = int(n)
+				}
+				reqs = append(reqs, req)
+			}
+		}
+	case models.SkillRequirements:
+		reqs = append(reqs, list...)
+	}
+	return Merge(reqs)
+}
+
+// Merge combines requirement lists. Skill names are trimmed and compared
+// case-insensitively; when a skill appears more than once the highest
+// minimum proficiency wins. Order of first appearance is kept.
+func Merge(lists ...models.SkillRequirements) models.SkillRequirements {
+	merged := models.SkillRequirements{}
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, req := range list {
+			name := strings.TrimSpace(req.Skill)
+			if name == "" {
+				continue
+			}
+			min := clampProficiency(req.MinProficiency)
+			key := strings.ToLower(name)
+			if i, ok := index[key]; ok {
+				if min > merged[i].MinProficiency {
+					merged[i].MinProficiency = min
+				}
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, models.SkillRequirement{Skill: name, MinProficiency: min})
+		}
+	}
+	return merged
+}
+
+// ContactRequirements returns the skills a contact needs based on each
+// skill's contact tag and attribute mappings.
+func ContactRequirements(skills []models.Skill, contact *models.Contact) models.SkillRequirements {
+	if contact == nil {
+		return models.SkillRequirements{}
+	}
+
+	tags := map[string]bool{}
+	for _, t := range contact.Tags {
+		if s, ok := t.(string); ok {
+			tags[strings.ToLower(s)] = true
+		}
+	}
+
+	var reqs models.SkillRequirements
+	for _, skill := range skills {
+		if skillMatchesContact(skill, tags, contact.Attributes) {
+			reqs = append(reqs, models.SkillRequirement{Skill: skill.Name, MinProficiency: skill.MinProficiency})
+		}
+	}
+	return Merge(reqs)
+}
+
+func skillMatchesContact(skill models.Skill, tags map[string]bool, attrs models.JSONB) bool {
+	for _, t := range skill.ContactTags {
+		if tags[strings.ToLower(t)] {
+			return true
+		}
+	}
+	if skill.ContactAttribute == "" || len(skill.ContactAttributeValues) == 0 {
+		return false
+	}
+	value, ok := attrs[skill.ContactAttribute]
+	if !ok || value == nil {
+		return false
+	}
+	str := strings.ToLower(fmt.Sprint(value))
+	for _, want := range skill.ContactAttributeValues {
+		if strings.ToLower(want) == str {
+			return true
+		}
+	}
+	return false
+}
+
+// Candidate is an available agent considered for a transfer.
+type Candidate struct {
+	UserID     uuid.UUID
+	Name       string
+	Skills     map[string]int // Lower-cased skill name -> proficiency
+	ActiveLoad int            // Active transfers currently assigned
+}
+
+// Match is the result of ranking candidates.
+type Match struct {
+	Candidate Candidate
+	Score     int // Sum of proficiencies in the required skills
+}
+
+// Best returns the candidate that holds every required skill at or above its
+// minimum proficiency, preferring the highest total proficiency and then the
+// lowest active load. It returns nil when nobody qualifies.
+func Best(reqs models.SkillRequirements, candidates []Candidate) *Match {
+	var matches []Match
+	for _, c := range candidates {
+		score, ok := qualify(reqs, c)
+		if ok {
+			matches = append(matches, Match{Candidate: c, Score: score})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Candidate.ActiveLoad < matches[j].Candidate.ActiveLoad
+	})
+	return &matches[0]
+}
+
+func qualify(reqs models.SkillRequirements, c Candidate) (int, bool) {
+	score := 0
+	for _, req := range reqs {
+		level, ok := c.Skills[strings.ToLower(req.Skill)]
+		if !ok || level < clampProficiency(req.MinProficiency) {
+			return 0, false
+		}
+		score += level
+	}
+	return score, true
+}
+
+// Describe renders requirements for routing notes, e.g. "spanish (3+), billing".
+func Describe(reqs models.SkillRequirements) string {
+	parts := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		if req.MinProficiency > models.MinSkillProficiency {
+			parts = append(parts, fmt.Sprintf("%s (%d+)", req.Skill, req.MinProficiency))
+		} else {
+			parts = append(parts, req.Skill)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func clampProficiency(p int) int {
+	if p < models.MinSkillProficiency {
+		return models.MinSkillProficiency
+	}
+	if p > models.MaxSkillProficiency {
+		return models.MaxSkillProficiency
+	}
+	return p
+}
@@ -0,0 +1,100 @@
+package skillutil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequirements(t *testing.T) {
+	raw := []interface{}{
+		"Spanish",
+		map[string]interface{}{"skill": "billing", "min_proficiency": float64(3)},
+		map[string]interface{}{"skill": "spanish", "min_proficiency": float64(4)},
+		map[string]interface{}{"min_proficiency": float64(2)},
+		float64(7),
+	}
+
+	reqs := ParseRequirements(raw)
+	assert.Equal(t, models.SkillRequirements{
+		{Skill: "Spanish", MinProficiency: 4},
+		{Skill: "billing", MinProficiency: 3},
+	}, reqs)
+
+	assert.Empty(t, ParseRequirements(nil))
+	assert.Empty(t, ParseRequirements("spanish"))
+}
+
+func TestMerge_ClampsProficiency(t *testing.T) {
+	reqs := Merge(
+		models.SkillRequirements{{Skill: "tier2", MinProficiency: 9}},
+		models.SkillRequirements{{Skill: " vip ", MinProficiency: 0}},
+	)
+	assert.Equal(t, models.SkillRequirements{
+		{Skill: "tier2", MinProficiency: models.MaxSkillProficiency},
+		{Skill: "vip", MinProficiency: models.MinSkillProficiency},
+	}, reqs)
+}
+
+func TestContactRequirements(t *testing.T) {
+	skills := []models.Skill{
+		{Name: "spanish", ContactAttribute: "language", ContactAttributeValues: models.StringArray{"es", "Spanish"}, MinProficiency: 3},
+		{Name: "enterprise", ContactTags: models.StringArray{"Enterprise"}, MinProficiency: 2},
+		{Name: "billing", ContactTags: models.StringArray{"billing"}},
+	}
+	contact := &models.Contact{
+		Tags:       models.JSONBArray{"enterprise", "new"},
+		Attributes: models.JSONB{"language": "ES"},
+	}
+
+	reqs := ContactRequirements(skills, contact)
+	assert.Equal(t, models.SkillRequirements{
+		{Skill: "spanish", MinProficiency: 3},
+		{Skill: "enterprise", MinProficiency: 2},
+	}, reqs)
+
+	assert.Empty(t, ContactRequirements(skills, &models.Contact{}))
+	assert.Empty(t, ContactRequirements(skills, nil))
+}
+
+func TestBest(t *testing.T) {
+	alice := Candidate{UserID: uuid.New(), Name: "Alice", Skills: map[string]int{"spanish": 5, "billing": 2}, ActiveLoad: 4}
+	bob := Candidate{UserID: uuid.New(), Name: "Bob", Skills: map[string]int{"spanish": 3, "billing": 4}, ActiveLoad: 1}
+	carol := Candidate{UserID: uuid.New(), Name: "Carol", Skills: map[string]int{"spanish": 5}, ActiveLoad: 0}
+	candidates := []Candidate{alice, bob, carol}
+
+	t.Run("highest total proficiency wins", func(t *testing.T) {
+		m := Best(models.SkillRequirements{{Skill: "Spanish"}, {Skill: "billing"}}, candidates)
+		require.NotNil(t, m)
+		assert.Equal(t, bob.UserID, m.Candidate.UserID)
+		assert.Equal(t, 7, m.Score)
+	})
+
+	t.Run("minimum proficiency filters", func(t *testing.T) {
+		m := Best(models.SkillRequirements{{Skill: "spanish", MinProficiency: 4}, {Skill: "billing"}}, candidates)
+		require.NotNil(t, m)
+		assert.Equal(t, alice.UserID, m.Candidate.UserID)
+	})
+
+	t.Run("ties go to lowest load", func(t *testing.T) {
+		m := Best(models.SkillRequirements{{Skill: "spanish", MinProficiency: 5}}, candidates)
+		require.NotNil(t, m)
+		assert.Equal(t, carol.UserID, m.Candidate.UserID)
+	})
+
+	t.Run("nobody qualifies", func(t *testing.T) {
+		assert.Nil(t, Best(models.SkillRequirements{{Skill: "french"}}, candidates))
+		assert.Nil(t, Best(models.SkillRequirements{{Skill: "spanish"}}, nil))
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	assert.Equal(t, "spanish (3+), billing", Describe(models.SkillRequirements{
+		{Skill: "spanish", MinProficiency: 3},
+		{Skill: "billing", MinProficiency: 1},
+	}))
+	assert.Equal(t, "", Describe(nil))
+}
@@ -0,0 +1,170 @@
+// Package slautil selects the SLA policy that applies to an agent transfer
+// and validates SLA policy definitions.
+package slautil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// MaxEscalationLevels caps the length of an escalation chain
+const MaxEscalationLevels = 5
+
+// ParsePriority returns the priority named by s. An empty string is the
+// normal priority.
+func ParsePriority(s string) (models.SLAPriority, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return models.SLAPriorityNormal, true
+	}
+	for _, p := range models.SLAPriorities {
+		if string(p) == s {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Select returns the first active policy whose conditions match the
+// transfer, or nil when none does. Policies must be sorted by position.
+// The contact may be nil, in which case tag and attribute conditions never
+// match.
+func Select(policies []models.SLAPolicy, transfer *models.AgentTransfer, contact *models.Contact) *models.SLAPolicy {
+	for i := range policies {
+		if policies[i].IsActive && Matches(policies[i].Conditions, transfer, contact) {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a transfer meets every non-empty condition
+func Matches(c models.SLAPolicyConditions, transfer *models.AgentTransfer, contact *models.Contact) bool {
+	if len(c.TeamIDs) > 0 {
+		if transfer.TeamID == nil || !containsFold(c.TeamIDs, transfer.TeamID.String()) {
+			return false
+		}
+	}
+	if len(c.WhatsAppAccounts) > 0 && !containsFold(c.WhatsAppAccounts, transfer.WhatsAppAccount) {
+		return false
+	}
+	if len(c.Sources) > 0 {
+		found := false
+		for _, s := range c.Sources {
+			if s == transfer.Source {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(c.ContactTags) > 0 && !contactHasTag(contact, c.ContactTags) {
+		return false
+	}
+	if c.ContactAttribute != "" && !contactAttributeMatches(contact, c.ContactAttribute, c.ContactAttributeValues) {
+		return false
+	}
+	return true
+}
+
+func contactHasTag(contact *models.Contact, tags []string) bool {
+	if contact == nil {
+		return false
+	}
+	for _, t := range contact.Tags {
+		if s, ok := t.(string); ok && containsFold(tags, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// contactAttributeMatches reports whether the contact has the attribute set
+// to one of values, or set at all when values is empty
+func contactAttributeMatches(contact *models.Contact, key string, values []string) bool {
+	if contact == nil {
+		return false
+	}
+	value, ok := contact.Attributes[key]
+	if !ok || value == nil {
+		return false
+	}
+	if len(values) == 0 {
+		return true
+	}
+	return containsFold(values, fmt.Sprint(value))
+}
+
+func containsFold(list []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks a policy's targets, warning threshold and escalation
+// chain and returns a message suitable for API clients
+func Validate(policy *models.SLAPolicy) error {
+	if strings.TrimSpace(policy.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	if len(policy.Targets) == 0 {
+		return errors.New("at least one priority target is required")
+	}
+	for priority, t := range policy.Targets {
+		if p, ok := ParsePriority(string(priority)); !ok || p != priority {
+			return fmt.Errorf("invalid priority %q", priority)
+		}
+		if t.ResponseMinutes < 0 || t.ResolutionMinutes < 0 {
+			return fmt.Errorf("targets for %s cannot be negative", priority)
+		}
+	}
+
+	if policy.WarningPercent < 0 || policy.WarningPercent > 99 {
+		return errors.New("warning_percent must be between 0 and 99")
+	}
+
+	if len(policy.Escalations) > MaxEscalationLevels {
+		return fmt.Errorf("at most %d escalation levels are allowed", MaxEscalationLevels)
+	}
+	prev := 0
+	for i, level := range policy.Escalations {
+		if level.AfterMinutes <= prev {
+			return fmt.Errorf("escalation level %d must come after the previous level", i+1)
+		}
+		prev = level.AfterMinutes
+		for _, id := range level.NotifyIDs {
+			if _, err := uuid.Parse(id); err != nil {
+				return fmt.Errorf("escalation level %d has an invalid user ID", i+1)
+			}
+		}
+	}
+
+	c := policy.Conditions
+	for _, id := range c.TeamIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return errors.New("invalid team ID in conditions")
+		}
+	}
+	for _, s := range c.Sources {
+		switch s {
+		case models.TransferSourceManual, models.TransferSourceFlow, models.TransferSourceKeyword, models.TransferSourceChatbotDisabled:
+		default:
+			return fmt.Errorf("invalid source %q in conditions", s)
+		}
+	}
+	if c.ContactAttribute == "" && len(c.ContactAttributeValues) > 0 {
+		return errors.New("contact_attribute is required with contact_attribute_values")
+	}
+	return nil
+}
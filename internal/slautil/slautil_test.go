@@ -0,0 +1,107 @@
+package slautil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriority(t *testing.T) {
+	p, ok := ParsePriority("")
+	assert.True(t, ok)
+	assert.Equal(t, models.SLAPriorityNormal, p)
+
+	p, ok = ParsePriority(" Urgent ")
+	assert.True(t, ok)
+	assert.Equal(t, models.SLAPriorityUrgent, p)
+
+	_, ok = ParsePriority("critical")
+	assert.False(t, ok)
+}
+
+func TestSelect_FirstMatchWins(t *testing.T) {
+	billing := uuid.New()
+	policies := []models.SLAPolicy{
+		{Name: "inactive", IsActive: false},
+		{Name: "vip", IsActive: true, Conditions: models.SLAPolicyConditions{ContactTags: []string{"VIP"}}},
+		{Name: "billing", IsActive: true, Conditions: models.SLAPolicyConditions{TeamIDs: []string{billing.String()}}},
+		{Name: "default", IsActive: true},
+	}
+	vip := &models.Contact{Tags: models.JSONBArray{"vip"}}
+
+	transfer := &models.AgentTransfer{TeamID: &billing}
+	assert.Equal(t, "vip", Select(policies, transfer, vip).Name)
+	assert.Equal(t, "billing", Select(policies, transfer, &models.Contact{}).Name)
+	assert.Equal(t, "default", Select(policies, &models.AgentTransfer{}, nil).Name)
+	assert.Nil(t, Select(policies[:3], &models.AgentTransfer{}, nil))
+}
+
+func TestMatches_AllConditions(t *testing.T) {
+	cond := models.SLAPolicyConditions{
+		WhatsAppAccounts:       []string{"support"},
+		Sources:                []models.TransferSource{models.TransferSourceFlow},
+		ContactAttribute:       "tier",
+		ContactAttributeValues: []string{"gold", "platinum"},
+	}
+	transfer := &models.AgentTransfer{WhatsAppAccount: "support", Source: models.TransferSourceFlow}
+	gold := &models.Contact{Attributes: models.JSONB{"tier": "Gold"}}
+
+	assert.True(t, Matches(cond, transfer, gold))
+	assert.False(t, Matches(cond, transfer, &models.Contact{Attributes: models.JSONB{"tier": "silver"}}))
+	assert.False(t, Matches(cond, transfer, nil))
+	assert.False(t, Matches(cond, &models.AgentTransfer{WhatsAppAccount: "support", Source: models.TransferSourceManual}, gold))
+	assert.False(t, Matches(cond, &models.AgentTransfer{WhatsAppAccount: "sales", Source: models.TransferSourceFlow}, gold))
+
+	// Attribute without values matches any value
+	assert.True(t, Matches(models.SLAPolicyConditions{ContactAttribute: "tier"}, transfer, gold))
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *models.SLAPolicy {
+		return &models.SLAPolicy{
+			Name:           "VIP",
+			Targets:        models.SLATargetSet{models.SLAPriorityNormal: {ResponseMinutes: 10}},
+			WarningPercent: 80,
+			Escalations: models.SLAEscalationChain{
+				{AfterMinutes: 10, NotifyIDs: []string{uuid.NewString()}},
+				{AfterMinutes: 30},
+			},
+		}
+	}
+	require.NoError(t, Validate(valid()))
+
+	cases := map[string]func(p *models.SLAPolicy){
+		"name is required":         func(p *models.SLAPolicy) { p.Name = " " },
+		"at least one priority":    func(p *models.SLAPolicy) { p.Targets = nil },
+		"invalid priority":         func(p *models.SLAPolicy) { p.Targets["critical"] = models.SLATargets{} },
+		"cannot be negative":       func(p *models.SLAPolicy) { p.Targets[models.SLAPriorityHigh] = models.SLATargets{ResponseMinutes: -1} },
+		"warning_percent":          func(p *models.SLAPolicy) { p.WarningPercent = 100 },
+		"after the previous level": func(p *models.SLAPolicy) { p.Escalations[1].AfterMinutes = 10 },
+		"invalid user ID":          func(p *models.SLAPolicy) { p.Escalations[0].NotifyIDs = []string{"bob"} },
+		"invalid team ID":          func(p *models.SLAPolicy) { p.Conditions.TeamIDs = []string{"billing"} },
+		"invalid source":           func(p *models.SLAPolicy) { p.Conditions.Sources = []models.TransferSource{"email"} },
+		"contact_attribute is required": func(p *models.SLAPolicy) {
+			p.Conditions.ContactAttributeValues = []string{"gold"}
+		},
+	}
+	for want, mutate := range cases {
+		p := valid()
+		mutate(p)
+		err := Validate(p)
+		require.Error(t, err, want)
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestSLATargetSet_FallsBackToNormal(t *testing.T) {
+	targets := models.SLATargetSet{
+		models.SLAPriorityNormal: {ResponseMinutes: 30},
+		models.SLAPriorityUrgent: {ResponseMinutes: 5},
+	}
+	assert.Equal(t, 5, targets.For(models.SLAPriorityUrgent).ResponseMinutes)
+	assert.Equal(t, 30, targets.For(models.SLAPriorityHigh).ResponseMinutes)
+	assert.Equal(t, 30, targets.For("").ResponseMinutes)
+}
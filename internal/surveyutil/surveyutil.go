@@ -0,0 +1,126 @@
+// Package surveyutil parses answers to customer satisfaction surveys,
+// computes NPS and validates survey settings.
+package surveyutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// ButtonIDPrefix prefixes the IDs of score buttons, e.g. "survey_score_5"
+const ButtonIDPrefix = "survey_score_"
+
+// FlowTokenPrefix prefixes the flow token of survey flows, followed by the
+// survey response ID
+const FlowTokenPrefix = "survey_"
+
+// NPS bands: scores of PromoterMin and above are promoters, scores of
+// DetractorMax and below are detractors
+const (
+	PromoterMin  = 9
+	DetractorMax = 6
+)
+
+// ScoreRange returns the lowest and highest score of a survey type
+func ScoreRange(t models.SurveyType) (lo, hi int) {
+	if t == models.SurveyTypeNPS {
+		return 0, 10
+	}
+	return 1, 5
+}
+
+// ButtonID returns the ID of the button for a score
+func ButtonID(score int) string {
+	return ButtonIDPrefix + strconv.Itoa(score)
+}
+
+// ParseScore returns the score a customer chose, either from a score button
+// or typed as a number. CSAT scores can also be given as stars.
+func ParseScore(t models.SurveyType, buttonID, text string) (int, bool) {
+	if rest, ok := strings.CutPrefix(buttonID, ButtonIDPrefix); ok {
+		return inRange(t, rest)
+	}
+
+	text = strings.TrimSpace(text)
+	if t == models.SurveyTypeCSAT && text != "" && strings.Trim(text, "⭐️ ") == "" {
+		return inRange(t, strconv.Itoa(strings.Count(text, "⭐")))
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	// "8", "8/10" and "8 - great" all count as 8
+	first, _, _ := strings.Cut(fields[0], "/")
+	return inRange(t, strings.TrimRight(first, ".,!"))
+}
+
+// FlowAnswer returns the score and comment from a survey flow's response.
+// The flow must return the score in a "score" field and may return a
+// "comment".
+func FlowAnswer(t models.SurveyType, data map[string]any) (score int, comment string, ok bool) {
+	switch v := data["score"].(type) {
+	case string:
+		score, ok = inRange(t, strings.TrimSpace(v))
+	case float64:
+		if v == float64(int(v)) {
+			score, ok = inRange(t, strconv.Itoa(int(v)))
+		}
+	}
+	if !ok {
+		return 0, "", false
+	}
+	comment, _ = data["comment"].(string)
+	return score, strings.TrimSpace(comment), true
+}
+
+// NPS returns the net promoter score, from -100 to 100, of a set of answers
+func NPS(promoters, detractors, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(promoters-detractors) * 100 / float64(total)
+}
+
+// Validate checks survey settings before they are saved
+func Validate(cfg *models.SurveyConfig) error {
+	switch cfg.Type {
+	case models.SurveyTypeCSAT, models.SurveyTypeNPS:
+	default:
+		return fmt.Errorf("invalid survey type %q", cfg.Type)
+	}
+	switch cfg.Channel {
+	case models.SurveyChannelButtons:
+	case models.SurveyChannelFlow:
+		if cfg.Enabled && strings.TrimSpace(cfg.FlowID) == "" {
+			return errors.New("survey flow ID is required for the flow channel")
+		}
+	default:
+		return fmt.Errorf("invalid survey channel %q", cfg.Channel)
+	}
+	if len(cfg.FlowCTA) > 20 {
+		return errors.New("survey flow button text must be at most 20 characters")
+	}
+	if cfg.ExpiryHours < 1 {
+		return errors.New("survey expiry must be at least 1 hour")
+	}
+	if cfg.Enabled && strings.TrimSpace(cfg.Question) == "" {
+		return errors.New("survey question is required")
+	}
+	return nil
+}
+
+func inRange(t models.SurveyType, s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	lo, hi := ScoreRange(t)
+	if n < lo || n > hi {
+		return 0, false
+	}
+	return n, true
+}
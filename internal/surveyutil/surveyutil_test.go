@@ -0,0 +1,87 @@
+package surveyutil
+
+import (
+	"testing"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		surveyType models.SurveyType
+		buttonID   string
+		text       string
+		want       int
+		wantOK     bool
+	}{
+		{"button", models.SurveyTypeCSAT, "survey_score_4", "Good", 4, true},
+		{"button out of range", models.SurveyTypeCSAT, "survey_score_9", "", 0, false},
+		{"typed number", models.SurveyTypeNPS, "", " 9 ", 9, true},
+		{"typed with scale", models.SurveyTypeNPS, "", "8/10", 8, true},
+		{"typed with words", models.SurveyTypeCSAT, "", "5. Great service!", 5, true},
+		{"zero is a valid nps", models.SurveyTypeNPS, "", "0", 0, true},
+		{"zero is not a valid csat", models.SurveyTypeCSAT, "", "0", 0, false},
+		{"stars", models.SurveyTypeCSAT, "", "⭐⭐⭐", 3, true},
+		{"other button", models.SurveyTypeCSAT, "btn_1", "Menu", 0, false},
+		{"not a score", models.SurveyTypeCSAT, "", "thanks", 0, false},
+		{"empty", models.SurveyTypeCSAT, "", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseScore(tt.surveyType, tt.buttonID, tt.text)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFlowAnswer(t *testing.T) {
+	score, comment, ok := FlowAnswer(models.SurveyTypeNPS, map[string]any{"score": "10", "comment": " Fast reply "})
+	assert.True(t, ok)
+	assert.Equal(t, 10, score)
+	assert.Equal(t, "Fast reply", comment)
+
+	score, _, ok = FlowAnswer(models.SurveyTypeCSAT, map[string]any{"score": float64(2)})
+	assert.True(t, ok)
+	assert.Equal(t, 2, score)
+
+	_, _, ok = FlowAnswer(models.SurveyTypeCSAT, map[string]any{"score": float64(2.5)})
+	assert.False(t, ok)
+	_, _, ok = FlowAnswer(models.SurveyTypeCSAT, map[string]any{"comment": "no score"})
+	assert.False(t, ok)
+}
+
+func TestNPS(t *testing.T) {
+	assert.Equal(t, 0.0, NPS(0, 0, 0))
+	assert.Equal(t, 25.0, NPS(2, 1, 4))
+	assert.Equal(t, -100.0, NPS(0, 3, 3))
+}
+
+func TestValidate(t *testing.T) {
+	valid := models.SurveyConfig{
+		Enabled:     true,
+		Type:        models.SurveyTypeCSAT,
+		Channel:     models.SurveyChannelButtons,
+		Question:    "How did we do?",
+		ExpiryHours: 24,
+	}
+	assert.NoError(t, Validate(&valid))
+
+	flow := valid
+	flow.Channel = models.SurveyChannelFlow
+	assert.EqualError(t, Validate(&flow), "survey flow ID is required for the flow channel")
+
+	badType := valid
+	badType.Type = "ces"
+	assert.EqualError(t, Validate(&badType), `invalid survey type "ces"`)
+
+	noQuestion := valid
+	noQuestion.Question = ""
+	assert.EqualError(t, Validate(&noQuestion), "survey question is required")
+
+	// Disabled surveys may be saved incomplete
+	noQuestion.Enabled = false
+	assert.NoError(t, Validate(&noQuestion))
+}
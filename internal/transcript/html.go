@@ -0,0 +1,94 @@
+package transcript
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+)
+
+// timeLayout is used for timestamps in rendered transcripts.
+const timeLayout = "2006-01-02 15:04:05"
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Format(timeLayout) },
+	"thumbnail": func(m *Media) template.URL {
+		thumb := makeThumbnail(m)
+		if thumb == nil {
+			return ""
+		}
+		// Safe: the data URI is built from an encoded JPEG, not user input
+		return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb.Data))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript - {{.Contact.Name}} {{.Contact.PhoneNumber}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; max-width: 820px; margin: 24px auto; padding: 0 16px; }
+header { border-bottom: 1px solid #d0d7de; margin-bottom: 16px; padding-bottom: 8px; }
+header h1 { font-size: 20px; margin: 0 0 4px; }
+header p { margin: 2px 0; color: #57606a; font-size: 13px; }
+.entry { margin: 10px 0; padding: 8px 12px; border-radius: 8px; max-width: 75%; font-size: 14px; }
+.incoming { background: #f6f8fa; }
+.outgoing { background: #dcf8c6; margin-left: auto; }
+.note { background: #fff8c5; border: 1px dashed #d4a72c; max-width: 100%; }
+.meta { font-size: 12px; color: #57606a; margin-bottom: 4px; }
+.text { white-space: pre-wrap; word-wrap: break-word; }
+.template { font-size: 11px; color: #57606a; }
+.media img { max-width: 240px; border-radius: 4px; display: block; margin-top: 4px; }
+.reactions { font-size: 12px; color: #57606a; margin-top: 4px; }
+</style>
+</head>
+<body>
+<header>
+<h1>Conversation transcript</h1>
+<p>{{.OrganizationName}}</p>
+<p>Contact: {{.Contact.Name}} ({{.Contact.PhoneNumber}})</p>
+<p>Period: {{formatTime .From}} to {{formatTime .To}} ({{.Timezone}})</p>
+<p>Generated: {{formatTime .GeneratedAt}}</p>
+</header>
+{{- range .Entries}}
+{{- if eq .Kind "note"}}
+<div class="entry note">
+<div class="meta">Internal note by {{.Sender}} &middot; {{formatTime .Timestamp}}</div>
+<div class="text">{{.Text}}</div>
+</div>
+{{- else}}
+<div class="entry {{.Direction}}">
+<div class="meta">{{.Sender}} &middot; {{formatTime .Timestamp}}{{if .Status}} &middot; {{.Status}}{{end}}</div>
+{{- if .TemplateName}}
+<div class="template">Template: {{.TemplateName}}</div>
+{{- end}}
+{{- with .Media}}
+<div class="media">
+{{- with thumbnail .}}<img src="{{.}}" alt="">{{end}}
+<a href="{{.URL}}">{{if .Filename}}{{.Filename}}{{else}}{{.MimeType}}{{end}}</a>
+</div>
+{{- end}}
+{{- if .Text}}
+<div class="text">{{.Text}}</div>
+{{- end}}
+{{- if .Reactions}}
+<div class="reactions">{{range $i, $r := .Reactions}}{{if $i}}, {{end}}{{$r.Emoji}} {{$r.From}}{{end}}</div>
+{{- end}}
+</div>
+{{- end}}
+{{- else}}
+<p>No messages in this period.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+func renderHTML(w io.Writer, t *Transcript) error {
+	return htmlTemplate.Execute(w, t)
+}
+
+func renderJSON(w io.Writer, t *Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
@@ -0,0 +1,350 @@
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shridarpatil/whatomate/internal/models"
+)
+
+// The PDF writer below produces a minimal PDF 1.4 document using the standard
+// Type 1 Helvetica fonts, so no font files need to be embedded. Text is
+// encoded as WinAnsi; characters outside it (e.g. emoji, non-Latin scripts)
+// are replaced with '?'. Use the HTML or JSON format for full Unicode.
+
+const (
+	pdfPageWidth  = 595.28 // A4
+	pdfPageHeight = 841.89
+	pdfMargin     = 48.0
+	pdfLineHeight = 1.3
+	pdfImageWidth = 160.0
+)
+
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota + 1
+	fontBold
+	fontItalic
+)
+
+var pdfBaseFonts = map[pdfFont]string{
+	fontRegular: "Helvetica",
+	fontBold:    "Helvetica-Bold",
+	fontItalic:  "Helvetica-Oblique",
+}
+
+// Glyph widths for WinAnsi codes 32-126 from the standard Helvetica metrics.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// winAnsiSpecial maps the Unicode characters WinAnsi places in 0x80-0x9F.
+var winAnsiSpecial = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encodeWinAnsi converts s to WinAnsi bytes, dropping control characters.
+func encodeWinAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r <= 126, r >= 160 && r <= 255:
+			out = append(out, byte(r))
+		case r < 32:
+			// Drop other control characters
+		default:
+			if b, ok := winAnsiSpecial[r]; ok {
+				out = append(out, b)
+			} else if r != '\uFE0F' && r != '\u200D' { // Skip emoji variation selectors and joiners
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+func textWidth(font pdfFont, size float64, s []byte) float64 {
+	widths := &helveticaWidths
+	if font == fontBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range s {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrapText breaks s into lines no wider than width, splitting words that do
+// not fit on a line of their own.
+func wrapText(font pdfFont, size, width float64, s []byte) [][]byte {
+	var lines [][]byte
+	for _, para := range bytes.Split(s, []byte("\n")) {
+		var line []byte
+		for _, word := range bytes.Fields(para) {
+			candidate := word
+			if len(line) > 0 {
+				candidate = append(append(append([]byte{}, line...), ' '), word...)
+			}
+			if textWidth(font, size, candidate) <= width {
+				line = candidate
+				continue
+			}
+			if len(line) > 0 {
+				lines = append(lines, line)
+			}
+			line = nil
+			for textWidth(font, size, word) > width {
+				n := 1
+				for n < len(word) && textWidth(font, size, word[:n+1]) <= width {
+					n++
+				}
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = append([]byte{}, word...)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func escapePDFString(s []byte) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c == '\\' || c == '(' || c == ')' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+type pdfColor struct{ R, G, B float64 }
+
+var (
+	colorText    = pdfColor{0.12, 0.14, 0.16}
+	colorMuted   = pdfColor{0.34, 0.38, 0.42}
+	colorInbound = pdfColor{0.13, 0.33, 0.62}
+	colorOutgo   = pdfColor{0.10, 0.50, 0.25}
+	colorNote    = pdfColor{0.62, 0.45, 0.05}
+	colorRule    = pdfColor{0.82, 0.84, 0.87}
+)
+
+// pdfDoc lays out text top to bottom, starting new pages as needed.
+type pdfDoc struct {
+	title  string
+	pages  []*bytes.Buffer
+	page   *bytes.Buffer
+	y      float64
+	images []*thumbnail
+}
+
+func (d *pdfDoc) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// ensure starts a new page unless h points of vertical space remain.
+func (d *pdfDoc) ensure(h float64) {
+	if d.page == nil || d.y-h < pdfMargin {
+		d.newPage()
+	}
+}
+
+func (d *pdfDoc) text(font pdfFont, size float64, c pdfColor, s string) {
+	width := pdfPageWidth - 2*pdfMargin
+	for _, line := range wrapText(font, size, width, encodeWinAnsi(s)) {
+		h := size * pdfLineHeight
+		d.ensure(h)
+		d.y -= h
+		fmt.Fprintf(d.page, "BT /F%d %.1f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+			font, size, c.R, c.G, c.B, pdfMargin, d.y+size*0.25, escapePDFString(line))
+	}
+}
+
+func (d *pdfDoc) rule() {
+	d.ensure(8)
+	d.y -= 4
+	fmt.Fprintf(d.page, "%.3f %.3f %.3f RG 0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		colorRule.R, colorRule.G, colorRule.B, pdfMargin, d.y, pdfPageWidth-pdfMargin, d.y)
+	d.y -= 4
+}
+
+func (d *pdfDoc) space(h float64) {
+	d.y -= h
+}
+
+func (d *pdfDoc) image(thumb *thumbnail) {
+	w := float64(thumb.Width)
+	h := float64(thumb.Height)
+	if w > pdfImageWidth {
+		h = h * pdfImageWidth / w
+		w = pdfImageWidth
+	}
+	d.ensure(h + 4)
+	d.y -= h + 4
+	d.images = append(d.images, thumb)
+	fmt.Fprintf(d.page, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, pdfMargin, d.y+2, len(d.images))
+}
+
+// writeTo serializes the document with a cross-reference table.
+func (d *pdfDoc) writeTo(w io.Writer) error {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string, stream []byte) int {
+		offsets = append(offsets, buf.Len())
+		n := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\n", n, body)
+		if stream != nil {
+			buf.WriteString("stream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream\n")
+		}
+		buf.WriteString("endobj\n")
+		return n
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Object numbers are fixed up front: catalog, page tree, fonts, images,
+	// then a page and content stream pair per page.
+	const catalogObj, pagesObj, firstFontObj = 1, 2, 3
+	firstImageObj := firstFontObj + len(pdfBaseFonts)
+	firstPageObj := firstImageObj + len(d.images)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+
+	obj(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj), nil)
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+
+	var fonts []string
+	for f := fontRegular; f <= fontItalic; f++ {
+		n := obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", pdfBaseFonts[f]), nil)
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", f, n))
+	}
+
+	var xobjects []string
+	for i, img := range d.images {
+		n := obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			img.Width, img.Height, len(img.Data)), img.Data)
+		xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i+1, n))
+	}
+
+	resources := fmt.Sprintf("<< /Font << %s >> /XObject << %s >> >>", strings.Join(fonts, " "), strings.Join(xobjects, " "))
+	for i, page := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			pagesObj, pdfPageWidth, pdfPageHeight, resources, firstPageObj+2*i+1), nil)
+		obj(fmt.Sprintf("<< /Length %d >>", page.Len()), page.Bytes())
+	}
+
+	infoObj := obj(fmt.Sprintf("<< /Title (%s) >>", escapePDFString(encodeWinAnsi(d.title))), nil)
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, catalogObj, infoObj, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func renderPDF(w io.Writer, t *Transcript) error {
+	d := &pdfDoc{title: "Transcript - " + t.Contact.Name + " " + t.Contact.PhoneNumber}
+
+	d.text(fontBold, 16, colorText, "Conversation transcript")
+	d.space(2)
+	if t.OrganizationName != "" {
+		d.text(fontRegular, 10, colorMuted, t.OrganizationName)
+	}
+	d.text(fontRegular, 10, colorMuted, fmt.Sprintf("Contact: %s (%s)", t.Contact.Name, t.Contact.PhoneNumber))
+	d.text(fontRegular, 10, colorMuted, fmt.Sprintf("Period: %s to %s (%s)", t.From.Format(timeLayout), t.To.Format(timeLayout), t.Timezone))
+	d.text(fontRegular, 10, colorMuted, "Generated: "+t.GeneratedAt.Format(timeLayout))
+	d.rule()
+
+	if len(t.Entries) == 0 {
+		d.text(fontItalic, 10, colorMuted, "No messages in this period.")
+	}
+
+	for _, e := range t.Entries {
+		d.space(6)
+		if e.Kind == EntryNote {
+			d.text(fontBold, 9, colorNote, fmt.Sprintf("Internal note by %s · %s", e.Sender, e.Timestamp.Format(timeLayout)))
+			d.text(fontItalic, 10, colorText, e.Text)
+			continue
+		}
+
+		label := fmt.Sprintf("%s · %s", e.Sender, e.Timestamp.Format(timeLayout))
+		if e.Status != "" {
+			label += " · " + e.Status
+		}
+		labelColor := colorInbound
+		if e.Direction == models.DirectionOutgoing {
+			labelColor = colorOutgo
+		}
+		d.text(fontBold, 9, labelColor, label)
+
+		if e.TemplateName != "" {
+			d.text(fontRegular, 8, colorMuted, "Template: "+e.TemplateName)
+		}
+		if e.Media != nil {
+			if thumb := makeThumbnail(e.Media); thumb != nil {
+				d.image(thumb)
+			}
+			name := e.Media.Filename
+			if name == "" {
+				name = e.Media.MimeType
+			}
+			d.text(fontRegular, 8, colorMuted, fmt.Sprintf("Attachment: %s (%s)", name, e.Media.URL))
+		}
+		if e.Text != "" {
+			d.text(fontRegular, 10, colorText, e.Text)
+		}
+		if len(e.Reactions) > 0 {
+			parts := make([]string, len(e.Reactions))
+			for i, r := range e.Reactions {
+				parts[i] = r.Emoji + " " + r.From
+			}
+			d.text(fontRegular, 8, colorMuted, "Reactions: "+strings.Join(parts, ", "))
+		}
+	}
+
+	return d.writeTo(w)
+}
@@ -0,0 +1,111 @@
+package transcript
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register decoders for thumbnails
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+)
+
+const (
+	// thumbnailSize is the longest side of an embedded thumbnail in pixels.
+	thumbnailSize = 240
+	// maxThumbnailSource skips images too large to decode for a thumbnail.
+	maxThumbnailSource = 10 << 20
+	// maxThumbnailPixels skips images whose declared dimensions would need too
+	// much memory to decode, however small the file.
+	maxThumbnailPixels = 40_000_000
+)
+
+// thumbnail is a JPEG-encoded, downscaled copy of an image attachment.
+type thumbnail struct {
+	Data          []byte
+	Width, Height int
+}
+
+// makeThumbnail reads an image attachment from storage and returns a JPEG
+// thumbnail, or nil if the file is missing or not a decodable image.
+func makeThumbnail(m *Media) *thumbnail {
+	if m == nil || !m.IsImage() || m.path == "" {
+		return nil
+	}
+	info, err := os.Stat(m.path)
+	if err != nil || info.Size() > maxThumbnailSource {
+		return nil
+	}
+	f, err := os.Open(m.path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil
+	}
+
+	dst := scale(src, thumbnailSize)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil
+	}
+	b := dst.Bounds()
+	return &thumbnail{Data: buf.Bytes(), Width: b.Dx(), Height: b.Dy()}
+}
+
+// scale downsizes src so its longest side is at most max pixels, flattening
+// transparency onto white. Sampling is nearest-neighbour, which is adequate
+// for thumbnails.
+func scale(src image.Image, max int) *image.RGBA {
+	sb := src.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+	if w > max || h > max {
+		if w >= h {
+			h = h * max / w
+			w = max
+		} else {
+			w = w * max / h
+			h = max
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	for y := 0; y < h; y++ {
+		sy := sb.Min.Y + y*sb.Dy()/h
+		for x := 0; x < w; x++ {
+			sx := sb.Min.X + x*sb.Dx()/w
+			r, g, b, a := src.At(sx, sy).RGBA()
+			if a == 0 {
+				continue
+			}
+			// Composite premultiplied colour over white
+			inv := 0xffff - a
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + inv) >> 8),
+				G: uint8((g + inv) >> 8),
+				B: uint8((b + inv) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
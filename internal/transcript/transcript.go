@@ -0,0 +1,356 @@
+// Package transcript builds conversation transcripts for a contact and renders
+// them as HTML, PDF or JSON.
+package transcript
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/contactutil"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/internal/templateutil"
+	"gorm.io/gorm"
+)
+
+// Format is a transcript output format.
+type Format string
+
+const (
+	FormatHTML Format = "html"
+	FormatPDF  Format = "pdf"
+	FormatJSON Format = "json"
+)
+
+// IsValid reports whether f is a supported format.
+func (f Format) IsValid() bool {
+	return f == FormatHTML || f == FormatPDF || f == FormatJSON
+}
+
+// ContentType returns the MIME type of the rendered format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatPDF:
+		return "application/pdf"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// ErrTooManyMessages is returned when the requested range holds more
+// messages than a transcript may contain.
+var ErrTooManyMessages = errors.New("too many messages in range")
+
+// MaxMessages caps the number of messages in one transcript.
+const MaxMessages = 5000
+
+// EntryKind distinguishes messages from internal notes.
+type EntryKind string
+
+const (
+	EntryMessage EntryKind = "message"
+	EntryNote    EntryKind = "note"
+)
+
+// Transcript is a format-agnostic view of a conversation.
+type Transcript struct {
+	OrganizationName string     `json:"organization_name"`
+	Contact          Party      `json:"contact"`
+	TransferID       *uuid.UUID `json:"transfer_id,omitempty"`
+	From             time.Time  `json:"from"`
+	To               time.Time  `json:"to"`
+	Timezone         string     `json:"timezone"`
+	GeneratedAt      time.Time  `json:"generated_at"`
+	Entries          []Entry    `json:"entries"`
+
+	mediaRoot string
+}
+
+// Party identifies the contact a transcript is about.
+type Party struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	PhoneNumber string    `json:"phone_number"`
+}
+
+// Entry is one message or internal note in a transcript.
+type Entry struct {
+	ID           uuid.UUID          `json:"id"`
+	Kind         EntryKind          `json:"kind"`
+	Timestamp    time.Time          `json:"timestamp"`
+	Direction    models.Direction   `json:"direction,omitempty"`
+	Sender       string             `json:"sender"`
+	MessageType  models.MessageType `json:"message_type,omitempty"`
+	Text         string             `json:"text"`
+	TemplateName string             `json:"template_name,omitempty"`
+	Media        *Media             `json:"media,omitempty"`
+	Reactions    []Reaction         `json:"reactions,omitempty"`
+	ReplyToID    *uuid.UUID         `json:"reply_to_id,omitempty"`
+	Status       string             `json:"status,omitempty"`
+}
+
+// Media describes an attachment. URL is the authenticated API path serving
+// the file.
+type Media struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Filename string `json:"filename,omitempty"`
+
+	path string
+}
+
+// IsImage reports whether the attachment is an image.
+func (m *Media) IsImage() bool {
+	return strings.HasPrefix(m.MimeType, "image/")
+}
+
+// Reaction is an emoji reaction on a message.
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	From  string `json:"from"`
+}
+
+// Options selects the conversation to transcribe. When TransferID is set the
+// range is taken from the transfer and From/To are ignored.
+type Options struct {
+	OrgID      uuid.UUID
+	ContactID  uuid.UUID
+	TransferID *uuid.UUID
+	From       time.Time
+	To         time.Time
+	MediaRoot  string
+	Location   *time.Location
+}
+
+// Build loads the messages and internal notes of a contact in the requested
+// range.
+func Build(db *gorm.DB, opts Options) (*Transcript, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var transfer *models.AgentTransfer
+	if opts.TransferID != nil {
+		transfer = &models.AgentTransfer{}
+		if err := db.Preload("TransferredByUser").
+			Where("id = ? AND organization_id = ?", *opts.TransferID, opts.OrgID).
+			First(transfer).Error; err != nil {
+			return nil, err
+		}
+		opts.ContactID = transfer.ContactID
+		opts.From = transfer.TransferredAt
+		opts.To = time.Now()
+		if transfer.ResumedAt != nil {
+			opts.To = *transfer.ResumedAt
+		}
+	}
+
+	var contact models.Contact
+	if err := db.Where("id = ? AND organization_id = ?", opts.ContactID, opts.OrgID).First(&contact).Error; err != nil {
+		return nil, err
+	}
+
+	var org models.Organization
+	if err := db.Select("id, name").Where("id = ?", opts.OrgID).First(&org).Error; err != nil {
+		return nil, err
+	}
+
+	t := &Transcript{
+		OrganizationName: org.Name,
+		Contact: Party{
+			ID:          contact.ID,
+			Name:        contact.ProfileName,
+			PhoneNumber: contact.PhoneNumber,
+		},
+		TransferID:  opts.TransferID,
+		From:        opts.From.In(loc),
+		To:          opts.To.In(loc),
+		Timezone:    loc.String(),
+		GeneratedAt: time.Now().In(loc),
+		Entries:     []Entry{},
+		mediaRoot:   opts.MediaRoot,
+	}
+
+	var messages []models.Message
+	if err := db.Preload("SentByUser").
+		Where("organization_id = ? AND contact_id = ? AND created_at BETWEEN ? AND ?", opts.OrgID, contact.ID, opts.From, opts.To).
+		Where("message_type <> ?", models.MessageTypeReaction).
+		Order("created_at ASC").
+		Limit(MaxMessages + 1).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	if len(messages) > MaxMessages {
+		return nil, fmt.Errorf("%w: narrow the date range to fewer than %d messages", ErrTooManyMessages, MaxMessages)
+	}
+
+	templates, err := loadTemplates(db, opts.OrgID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range messages {
+		t.Entries = append(t.Entries, t.messageEntry(m, &contact, templates, loc))
+	}
+
+	// Transfer notes are the internal notes agents leave on a conversation
+	var transfers []models.AgentTransfer
+	if transfer != nil {
+		transfers = []models.AgentTransfer{*transfer}
+	} else if err := db.Preload("TransferredByUser").
+		Where("organization_id = ? AND contact_id = ? AND transferred_at BETWEEN ? AND ? AND notes <> ''", opts.OrgID, contact.ID, opts.From, opts.To).
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	for _, tr := range transfers {
+		if tr.Notes == "" {
+			continue
+		}
+		sender := "System"
+		if tr.TransferredByUser != nil {
+			sender = tr.TransferredByUser.FullName
+		}
+		t.Entries = append(t.Entries, Entry{
+			ID:        tr.ID,
+			Kind:      EntryNote,
+			Timestamp: tr.TransferredAt.In(loc),
+			Sender:    sender,
+			Text:      tr.Notes,
+		})
+	}
+
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		return t.Entries[i].Timestamp.Before(t.Entries[j].Timestamp)
+	})
+
+	return t, nil
+}
+
+// loadTemplates returns the templates referenced by messages, keyed by
+// account and name.
+func loadTemplates(db *gorm.DB, orgID uuid.UUID, messages []models.Message) (map[string]models.Template, error) {
+	names := map[string]bool{}
+	for _, m := range messages {
+		if m.TemplateName != "" {
+			names[m.TemplateName] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+
+	var templates []models.Template
+	if err := db.Where("organization_id = ? AND name IN ?", orgID, list).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]models.Template, len(templates))
+	for _, tpl := range templates {
+		byKey[tpl.WhatsAppAccount+"/"+tpl.Name] = tpl
+	}
+	return byKey, nil
+}
+
+func (t *Transcript) messageEntry(m models.Message, contact *models.Contact, templates map[string]models.Template, loc *time.Location) Entry {
+	entry := Entry{
+		ID:           m.ID,
+		Kind:         EntryMessage,
+		Timestamp:    m.CreatedAt.In(loc),
+		Direction:    m.Direction,
+		MessageType:  m.MessageType,
+		Text:         m.Content,
+		TemplateName: m.TemplateName,
+		Status:       string(m.Status),
+	}
+	if m.IsReply {
+		entry.ReplyToID = m.ReplyToMessageID
+	}
+
+	switch {
+	case m.Direction == models.DirectionIncoming:
+		entry.Sender = contactName(contact)
+	case m.SentByUser != nil:
+		entry.Sender = m.SentByUser.FullName
+	default:
+		entry.Sender = "Automated"
+	}
+
+	if tpl, ok := templates[m.WhatsAppAccount+"/"+m.TemplateName]; ok && m.BodyPurgedAt == nil {
+		entry.Text = renderTemplate(tpl, m.TemplateParams)
+	}
+	if m.BodyPurgedAt != nil {
+		entry.Text = "[Message content removed by retention policy]"
+	}
+
+	if m.MediaURL != "" {
+		entry.Media = &Media{
+			URL:      "/api/media/" + m.ID.String(),
+			MimeType: m.MediaMimeType,
+			Filename: m.MediaFilename,
+			path:     contactutil.ResolveMediaPath(t.mediaRoot, m.MediaURL),
+		}
+	}
+
+	if reactions, ok := m.Metadata["reactions"].([]interface{}); ok {
+		for _, r := range reactions {
+			rMap, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			emoji, _ := rMap["emoji"].(string)
+			from, _ := rMap["from_user"].(string)
+			if from == "" {
+				from = contactName(contact)
+			}
+			entry.Reactions = append(entry.Reactions, Reaction{Emoji: emoji, From: from})
+		}
+	}
+
+	return entry
+}
+
+// renderTemplate returns the template's header, body and footer with the
+// message's parameters filled in.
+func renderTemplate(tpl models.Template, params models.JSONB) string {
+	var parts []string
+	if tpl.HeaderType == "TEXT" && tpl.HeaderContent != "" {
+		parts = append(parts, templateutil.ReplaceWithJSONBParams(tpl.HeaderContent, tpl.HeaderContent, params))
+	}
+	parts = append(parts, templateutil.ReplaceWithJSONBParams(tpl.BodyContent, tpl.BodyContent, params))
+	if tpl.FooterContent != "" {
+		parts = append(parts, tpl.FooterContent)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func contactName(contact *models.Contact) string {
+	if contact.ProfileName != "" {
+		return contact.ProfileName
+	}
+	return contact.PhoneNumber
+}
+
+// Render writes the transcript in the given format.
+func Render(w io.Writer, t *Transcript, format Format) error {
+	switch format {
+	case FormatHTML:
+		return renderHTML(w, t)
+	case FormatPDF:
+		return renderPDF(w, t)
+	case FormatJSON:
+		return renderJSON(w, t)
+	}
+	return fmt.Errorf("unsupported transcript format %q", format)
+}
@@ -0,0 +1,205 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/shridarpatil/whatomate/test/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleTranscript(t *testing.T) *Transcript {
+	t.Helper()
+
+	dir := t.TempDir()
+	imgPath := filepath.Join(dir, "photo.png")
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		img.Set(x, 10, color.RGBA{R: 255, A: 255})
+	}
+	f, err := os.Create(imgPath)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(f, img))
+	require.NoError(t, f.Close())
+
+	ts := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	return &Transcript{
+		OrganizationName: "Acme",
+		Contact:          Party{ID: uuid.New(), Name: "Jane", PhoneNumber: "+15550001111"},
+		From:             ts.Add(-time.Hour),
+		To:               ts.Add(time.Hour),
+		Timezone:         "UTC",
+		GeneratedAt:      ts,
+		Entries: []Entry{
+			{ID: uuid.New(), Kind: EntryMessage, Timestamp: ts, Direction: models.DirectionIncoming, Sender: "Jane", Text: "My order <b>#42</b> (broken) 👍"},
+			{ID: uuid.New(), Kind: EntryNote, Timestamp: ts.Add(time.Minute), Sender: "Sam", Text: "Escalate to billing"},
+			{
+				ID: uuid.New(), Kind: EntryMessage, Timestamp: ts.Add(2 * time.Minute), Direction: models.DirectionOutgoing,
+				Sender: "Sam", Text: "Here is the label", Media: &Media{URL: "/api/media/x", MimeType: "image/png", Filename: "photo.png", path: imgPath},
+				Reactions: []Reaction{{Emoji: "❤️", From: "Jane"}},
+			},
+		},
+	}
+}
+
+func TestRender_HTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, sampleTranscript(t), FormatHTML))
+	out := buf.String()
+
+	assert.Contains(t, out, "&lt;b&gt;#42&lt;/b&gt;", "message text must be escaped")
+	assert.Contains(t, out, "Internal note by Sam")
+	assert.Contains(t, out, `src="data:image/jpeg;base64,`)
+	assert.Contains(t, out, `href="/api/media/x"`)
+	assert.Contains(t, out, "❤️ Jane")
+}
+
+func TestRender_PDF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, sampleTranscript(t), FormatPDF))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	assert.Contains(t, out, "/BaseFont /Helvetica")
+	assert.Contains(t, out, "/Filter /DCTDecode")
+	assert.Contains(t, out, `(My order <b>#42</b> \(broken\) ?)`)
+
+	// The xref offset must point at the table
+	idx := strings.LastIndex(out, "startxref\n")
+	require.NotEqual(t, -1, idx)
+	var offset int
+	_, err := fmt.Sscan(out[idx+len("startxref\n"):], &offset)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out[offset:], "xref\n"))
+}
+
+func TestRender_PDFPagination(t *testing.T) {
+	tr := sampleTranscript(t)
+	for i := 0; i < 200; i++ {
+		tr.Entries = append(tr.Entries, Entry{Kind: EntryMessage, Direction: models.DirectionIncoming, Sender: "Jane", Text: strings.Repeat("lorem ipsum ", 20)})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, tr, FormatPDF))
+	assert.Greater(t, strings.Count(buf.String(), "/Type /Page "), 5)
+}
+
+func TestRender_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, sampleTranscript(t), FormatJSON))
+
+	var got Transcript
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Len(t, got.Entries, 3)
+	assert.Equal(t, EntryNote, got.Entries[1].Kind)
+	assert.Equal(t, "/api/media/x", got.Entries[2].Media.URL)
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText(fontRegular, 10, 50, []byte("short words here\nand averyveryverylongwordthatmustsplit"))
+	for _, l := range lines {
+		assert.LessOrEqual(t, textWidth(fontRegular, 10, l), 50.0)
+	}
+	assert.Greater(t, len(lines), 3)
+}
+
+func TestMakeThumbnail_OversizedImage(t *testing.T) {
+	// A 1x1 PNG whose header declares 20000x20000 pixels
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+	ihdr := data[8+4 : 8+4+4+13] // chunk type and data
+	binary.BigEndian.PutUint32(ihdr[4:8], 20000)
+	binary.BigEndian.PutUint32(ihdr[8:12], 20000)
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+
+	imgPath := filepath.Join(t.TempDir(), "bomb.png")
+	require.NoError(t, os.WriteFile(imgPath, data, 0644))
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 20000, cfg.Width)
+	assert.Nil(t, makeThumbnail(&Media{MimeType: "image/png", path: imgPath}))
+}
+
+func TestEncodeWinAnsi(t *testing.T) {
+	assert.Equal(t, []byte("caf\xe9 \x80 \x93ok\x94 ?"), encodeWinAnsi("café € “ok” 😀"))
+}
+
+func TestBuild(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	org := testutil.CreateTestOrganization(t, db)
+	user := testutil.CreateTestUser(t, db, org.ID)
+	contact := testutil.CreateTestContact(t, db, org.ID)
+	tpl := testutil.CreateTestTemplate(t, db, org.ID, "test")
+
+	now := time.Now()
+	require.NoError(t, db.Create(&models.Message{
+		BaseModel:       models.BaseModel{CreatedAt: now.Add(-2 * time.Hour)},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "hi",
+		Metadata:        models.JSONB{"reactions": []any{map[string]any{"emoji": "👍", "from_user": user.FullName}}},
+	}).Error)
+	require.NoError(t, db.Create(&models.Message{
+		BaseModel:       models.BaseModel{CreatedAt: now.Add(-time.Hour)},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionOutgoing,
+		MessageType:     models.MessageTypeTemplate,
+		Content:         "[Template: x]",
+		TemplateName:    tpl.Name,
+		TemplateParams:  models.JSONB{"1": "Jane"},
+		SentByUserID:    &user.ID,
+	}).Error)
+	require.NoError(t, db.Create(&models.AgentTransfer{
+		OrganizationID:      org.ID,
+		ContactID:           contact.ID,
+		WhatsAppAccount:     "test",
+		PhoneNumber:         contact.PhoneNumber,
+		Notes:               "VIP customer",
+		TransferredByUserID: &user.ID,
+	}).Error)
+	// Outside the range
+	require.NoError(t, db.Create(&models.Message{
+		BaseModel:       models.BaseModel{CreatedAt: now.AddDate(0, 0, -10)},
+		OrganizationID:  org.ID,
+		WhatsAppAccount: "test",
+		ContactID:       contact.ID,
+		Direction:       models.DirectionIncoming,
+		MessageType:     models.MessageTypeText,
+		Content:         "old",
+	}).Error)
+
+	tr, err := Build(db, Options{OrgID: org.ID, ContactID: contact.ID, From: now.AddDate(0, 0, -1), To: now.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, tr.Entries, 3)
+
+	assert.Equal(t, "hi", tr.Entries[0].Text)
+	require.Len(t, tr.Entries[0].Reactions, 1)
+	assert.Equal(t, user.FullName, tr.Entries[0].Reactions[0].From)
+
+	assert.Equal(t, "Hello Jane", tr.Entries[1].Text)
+	assert.Equal(t, user.FullName, tr.Entries[1].Sender)
+
+	assert.Equal(t, EntryNote, tr.Entries[2].Kind)
+	assert.Equal(t, "VIP customer", tr.Entries[2].Text)
+}
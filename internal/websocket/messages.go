@@ -21,12 +21,16 @@ const (
 	TypeAgentTransfer       = "agent_transfer"
 	TypeAgentTransferResume = "agent_transfer_resume"
 	TypeAgentTransferAssign = "agent_transfer_assign"
+	TypeAgentTransferHop    = "agent_transfer_hop"
 
 	// Campaign types
 	TypeCampaignStatsUpdate = "campaign_stats_update"
 
 	// Permission types
 	TypePermissionsUpdated = "permissions_updated"
+
+	// Agent availability types
+	TypeAvailabilityUpdate = "availability_update"
 )
 
 // BroadcastMessage represents a message to be broadcast to clients
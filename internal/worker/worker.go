@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/shridarpatil/whatomate/internal/bizhours"
 	"github.com/shridarpatil/whatomate/internal/config"
 	"github.com/shridarpatil/whatomate/internal/contactutil"
 	"github.com/shridarpatil/whatomate/internal/models"
@@ -80,6 +81,11 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 		return nil // Not an error, just skip
 	}
 
+	// Outside business hours the campaign is paused until they open
+	if campaign.BusinessHoursOnly && w.pauseOutsideBusinessHours(&campaign) {
+		return nil
+	}
+
 	// Get WhatsApp account
 	var account models.WhatsAppAccount
 	if err := w.DB.Where("name = ? AND organization_id = ?", campaign.WhatsAppAccount, job.OrganizationID).First(&account).Error; err != nil {
@@ -98,11 +104,32 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 		return nil // Don't retry
 	}
 
+	// Opted-out contacts are skipped and reported separately from failures
+	if contactutil.IsOptedOut(contact) {
+		w.Log.Info("Contact opted out, skipping recipient", "recipient", job.PhoneNumber, "campaign_id", job.CampaignID)
+		w.updateRecipientStatus(job.RecipientID, models.MessageStatusOptedOut, "", "Contact has opted out of messages")
+		w.incrementCampaignCount(job.CampaignID, "opted_out_count")
+		w.checkCampaignCompletion(ctx, job.CampaignID, job.OrganizationID)
+		return nil
+	}
+
+	// Contact attributes are available as named parameters; recipient params take precedence
+	templateParams := job.TemplateParams
+	if len(contact.Attributes) > 0 {
+		templateParams = models.JSONB{}
+		for k, v := range contact.Attributes {
+			templateParams[k] = contactutil.FormatAttributeValue(v)
+		}
+		for k, v := range job.TemplateParams {
+			templateParams[k] = v
+		}
+	}
+
 	// Build recipient for sending
 	recipient := &models.BulkMessageRecipient{
 		PhoneNumber:    job.PhoneNumber,
 		RecipientName:  job.RecipientName,
-		TemplateParams: job.TemplateParams,
+		TemplateParams: templateParams,
 	}
 
 	// Send template message
@@ -116,7 +143,7 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 		WhatsAppMessageID: waMessageID,
 		Direction:         models.DirectionOutgoing,
 		MessageType:       models.MessageTypeTemplate,
-		TemplateParams:    job.TemplateParams,
+		TemplateParams:    templateParams,
 		Metadata: models.JSONB{
 			"campaign_id":    job.CampaignID.String(),
 			"recipient_name": job.RecipientName,
@@ -124,7 +151,7 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 	}
 	if campaign.Template != nil {
 		message.TemplateName = campaign.Template.Name
-		content := templateutil.ReplaceWithJSONBParams(campaign.Template.BodyContent, campaign.Template.BodyContent, job.TemplateParams)
+		content := templateutil.ReplaceWithJSONBParams(campaign.Template.BodyContent, campaign.Template.BodyContent, templateParams)
 		message.Content = content
 	}
 
@@ -152,6 +179,31 @@ func (w *Worker) HandleRecipientJob(ctx context.Context, job *queue.RecipientJob
 	return nil
 }
 
+// pauseOutsideBusinessHours pauses a business-hours-only campaign while its
+// account is closed and reports whether it did. Recipients stay pending and
+// are re-enqueued when the campaign resumes.
+func (w *Worker) pauseOutsideBusinessHours(campaign *models.BulkMessageCampaign) bool {
+	schedule, err := bizhours.ForAccount(w.DB, campaign.OrganizationID, campaign.WhatsAppAccount)
+	if err != nil {
+		w.Log.Error("Failed to load business hours", "error", err, "campaign_id", campaign.ID)
+		return false
+	}
+	now := time.Now()
+	if schedule == nil || schedule.IsOpen(now) {
+		return false
+	}
+
+	var resumeAt *time.Time
+	if next := schedule.NextOpen(now); !next.IsZero() {
+		resumeAt = &next
+	}
+	w.DB.Model(&models.BulkMessageCampaign{}).
+		Where("id = ? AND status = ?", campaign.ID, models.CampaignStatusProcessing).
+		Updates(map[string]interface{}{"status": models.CampaignStatusPaused, "resume_at": resumeAt})
+	w.Log.Info("Campaign paused outside business hours", "campaign_id", campaign.ID, "resume_at", resumeAt)
+	return true
+}
+
 // updateRecipientStatus updates the recipient's status in the database
 func (w *Worker) updateRecipientStatus(recipientID uuid.UUID, status models.MessageStatus, waMessageID, errorMsg string) {
 	updates := map[string]interface{}{
@@ -189,6 +241,7 @@ func (w *Worker) publishCampaignStats(ctx context.Context, campaignID, organizat
 		DeliveredCount: campaign.DeliveredCount,
 		ReadCount:      campaign.ReadCount,
 		FailedCount:    campaign.FailedCount,
+		OptedOutCount:  campaign.OptedOutCount,
 	})
 }
 
@@ -229,6 +282,7 @@ func (w *Worker) checkCampaignCompletion(ctx context.Context, campaignID, organi
 			DeliveredCount: campaign.DeliveredCount,
 			ReadCount:      campaign.ReadCount,
 			FailedCount:    campaign.FailedCount,
+			OptedOutCount:  campaign.OptedOutCount,
 		})
 	} else {
 		// Publish current stats
@@ -245,6 +245,44 @@ func TestWorker_HandleRecipientJob_CampaignNotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to load campaign")
 }
 
+func TestWorker_HandleRecipientJob_OptedOut(t *testing.T) {
+	w := testWorker(t)
+	org, _, _, campaign, recipient := createTestCampaignData(t, w)
+
+	// Contact has opted out of messages
+	contact := &models.Contact{
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		ConsentStatus:  models.ConsentStatusOptedOut,
+	}
+	require.NoError(t, w.DB.Create(contact).Error)
+
+	job := &queue.RecipientJob{
+		CampaignID:     campaign.ID,
+		RecipientID:    recipient.ID,
+		OrganizationID: org.ID,
+		PhoneNumber:    recipient.PhoneNumber,
+		RecipientName:  recipient.RecipientName,
+	}
+
+	err := w.HandleRecipientJob(context.Background(), job)
+	require.NoError(t, err)
+
+	// Recipient is reported as opted out, not failed, and nothing was sent
+	var updatedRecipient models.BulkMessageRecipient
+	require.NoError(t, w.DB.First(&updatedRecipient, recipient.ID).Error)
+	assert.Equal(t, models.MessageStatusOptedOut, updatedRecipient.Status)
+
+	var updatedCampaign models.BulkMessageCampaign
+	require.NoError(t, w.DB.First(&updatedCampaign, campaign.ID).Error)
+	assert.Equal(t, 1, updatedCampaign.OptedOutCount)
+	assert.Equal(t, 0, updatedCampaign.FailedCount)
+
+	var messageCount int64
+	w.DB.Model(&models.Message{}).Where("contact_id = ?", contact.ID).Count(&messageCount)
+	assert.Zero(t, messageCount)
+}
+
 // createMinimalCampaignData creates the minimum data needed for campaign tests
 // Returns org, user, template, and campaign
 func createMinimalCampaignData(t *testing.T, w *Worker, status models.CampaignStatus) (*models.Organization, *models.User, *models.Template, *models.BulkMessageCampaign) {
@@ -0,0 +1,77 @@
+package whatsapp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- BlockUser ---
+
+func TestClient_BlockUser_Success(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v21.0/123456789/block_users", r.URL.Path)
+
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "whatsapp", body["messaging_product"])
+		users := body["block_users"].([]interface{})
+		assert.Equal(t, "919876543210", users[0].(map[string]interface{})["user"])
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","block_users":{"added_users":[{"input":"919876543210","wa_id":"919876543210"}]}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	account := testAccount(server.URL)
+
+	err := client.BlockUser(context.Background(), account, "919876543210")
+	require.NoError(t, err)
+}
+
+func TestClient_BlockUser_FailedUser(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","block_users":{"failed_users":[{"input":"919876543210","errors":[{"message":"Re-engagement check failed","code":139100}]}]}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	account := testAccount(server.URL)
+
+	err := client.BlockUser(context.Background(), account, "919876543210")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Re-engagement check failed")
+}
+
+// --- UnblockUser ---
+
+func TestClient_UnblockUser_Success(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/v21.0/123456789/block_users", r.URL.Path)
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","block_users":{"removed_users":[{"input":"919876543210","wa_id":"919876543210"}]}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	account := testAccount(server.URL)
+
+	err := client.UnblockUser(context.Background(), account, "919876543210")
+	require.NoError(t, err)
+}
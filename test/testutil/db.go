@@ -87,23 +87,39 @@ func runMigrations(db *gorm.DB) error {
 		&models.UserOrganization{},
 		&models.Team{},
 		&models.TeamMember{},
+		&models.Skill{},
+		&models.AgentSkill{},
 		&models.APIKey{},
 		&models.SSOProvider{},
 		&models.Webhook{},
 		&models.CustomAction{},
 		&models.UserAvailabilityLog{},
+		&models.ShiftSchedule{},
+		&models.ShiftException{},
 		// WhatsApp models
 		&models.WhatsAppAccount{},
 		&models.Contact{},
 		&models.Tag{},
+		&models.ContactAttribute{},
+		&models.Segment{},
+		&models.ContactConsent{},
+		&models.ContactActivity{},
+		&models.DataSubjectRequest{},
 		&models.Message{},
 		&models.Template{},
 		&models.WhatsAppFlow{},
 		// Chatbot models
 		&models.ChatbotSettings{},
+		&models.HolidayCalendar{},
+		&models.Holiday{},
+		&models.SLAPolicy{},
+		&models.TransferHop{},
+		&models.SurveyResponse{},
 		&models.KeywordRule{},
 		&models.ChatbotFlow{},
 		&models.ChatbotFlowStep{},
+		&models.ChatbotFlowVersion{},
+		&models.ChatbotFlowTestCase{},
 		&models.ChatbotSession{},
 		&models.ChatbotSessionMessage{},
 		&models.AIContext{},
@@ -141,14 +157,26 @@ func cleanupTables(db *gorm.DB) {
 		"chatbot_session_messages",
 		"chatbot_sessions",
 		"chatbot_flow_steps",
+		"chatbot_flow_versions",
+		"chatbot_flow_test_cases",
 		"chatbot_flows",
 		"keyword_rules",
 		"chatbot_settings",
+		"sla_policies",
+		"holidays",
+		"holiday_calendars",
 		"ai_contexts",
+		"survey_responses",
+		"transfer_hops",
 		"agent_transfers",
 		// WhatsApp tables
 		"messages",
 		"tags",
+		"contact_attributes",
+		"segments",
+		"contact_consents",
+		"contact_activities",
+		"data_subject_requests",
 		"contacts",
 		"templates",
 		"whatsapp_flows",
@@ -158,12 +186,16 @@ func cleanupTables(db *gorm.DB) {
 		"custom_roles",
 		"permissions",
 		// Core tables
+		"agent_skills",
+		"skills",
 		"team_members",
 		"teams",
 		"api_keys",
 		"sso_providers",
 		"webhooks",
 		"custom_actions",
+		"shift_exceptions",
+		"shift_schedules",
 		"user_availability_logs",
 		"user_organizations",
 		"users",
@@ -188,13 +220,25 @@ func TruncateTables(db *gorm.DB) {
 		"chatbot_session_messages",
 		"chatbot_sessions",
 		"chatbot_flow_steps",
+		"chatbot_flow_versions",
+		"chatbot_flow_test_cases",
 		"chatbot_flows",
 		"keyword_rules",
 		"chatbot_settings",
+		"sla_policies",
+		"holidays",
+		"holiday_calendars",
 		"ai_contexts",
+		"survey_responses",
+		"transfer_hops",
 		"agent_transfers",
 		"messages",
 		"tags",
+		"contact_attributes",
+		"segments",
+		"contact_consents",
+		"contact_activities",
+		"data_subject_requests",
 		"contacts",
 		"templates",
 		"whatsapp_flows",
@@ -202,12 +246,16 @@ func TruncateTables(db *gorm.DB) {
 		"role_permissions",
 		"custom_roles",
 		"permissions",
+		"agent_skills",
+		"skills",
 		"team_members",
 		"teams",
 		"api_keys",
 		"sso_providers",
 		"webhooks",
 		"custom_actions",
+		"shift_exceptions",
+		"shift_schedules",
 		"user_availability_logs",
 		"user_organizations",
 		"users",